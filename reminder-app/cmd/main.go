@@ -1,94 +1,514 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
-	"mime"
 	"net/http"
-	"path/filepath"
+	"net/mail"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"reminder-app/internal/alexa"
+	"reminder-app/internal/breaker"
+	"reminder-app/internal/condition"
+	"reminder-app/internal/config"
 	"reminder-app/internal/handlers"
+	"reminder-app/internal/i18n"
+	"reminder-app/internal/links"
+	"reminder-app/internal/notify"
+	"reminder-app/internal/pack"
+	"reminder-app/internal/pitr"
+	"reminder-app/internal/scheduler"
 	"reminder-app/internal/storage"
+	"reminder-app/internal/telemetry"
+	"reminder-app/internal/version"
+	"reminder-app/internal/webhook"
 
 	"github.com/gorilla/mux"
 )
 
+// reloadable are the flags a config file reload applies to the running
+// server. The others only take effect after a restart.
+var reloadable = map[string]bool{
+	"strict-json":        true,
+	"disable-deprecated": true,
+	"static-rate-limit":  true,
+	"matrix-homeserver":  true,
+	"matrix-token":       true,
+	"ntfy-server":        true,
+	"ntfy-token":         true,
+	"gotify-server":      true,
+	"smtp-addr":          true,
+	"smtp-from":          true,
+	"smtp-user":          true,
+	"smtp-password":      true,
+	"twilio-account-sid": true,
+	"twilio-token":       true,
+	"twilio-from":        true,
+	"twilio-api":         true,
+	"webhook-secret":     true,
+	"apprise-api":        true,
+}
+
 func main() {
+	configFile := flag.String("config", "", "file of flag settings, one name=value per line; flags on the command line take precedence. On SIGHUP, notification channel, rate limit and feature flag settings are reloaded from it")
 	staticDir := flag.String("static", "./static", "directory to serve static files from")
+	staticMaxAge := flag.Duration("static-max-age", 0, "how long browsers may cache static files other than HTML without revalidating")
+	immutableAssets := flag.Bool("immutable-assets", false, "let browsers cache fingerprinted static files (e.g. app.3f9a2c1d.js) for a year")
+	disableListing := flag.Bool("disable-dir-listing", false, "answer 404 for static directories without an index.html instead of listing them")
+	precompress := flag.Bool("precompress", false, "write gzip variants of static text files at startup; brotli variants come from the frontend build")
+	defaultLocale := flag.String("default-locale", "en", "language of the frontend served from the root of the static directory; translations live in subdirectories named by language tag")
+	staticRateLimit := flag.Int("static-rate-limit", 0, "requests per minute allowed from each client for static files; 0 disables the limit")
 	tlsCert := flag.String("tls-cert", "", "path to TLS certificate file (optional)")
 	tlsKey := flag.String("tls-key", "", "path to TLS key file (optional)")
+	disableDeprecated := flag.Bool("disable-deprecated", false, "answer calls to deprecated endpoints with 410 Gone")
+	strictJSON := flag.Bool("strict-json", false, "reject request bodies containing unknown fields")
+	baseURL := flag.String("base-url", "", "externally visible base URL used in generated links (e.g. https://reminders.example.com)")
+	linkSecret := flag.String("link-secret", "", "secret used to sign completion links (random per process if empty)")
+	adminToken := flag.String("admin-token", "", "bearer token for the /admin endpoints; empty disables them")
+	packageSecret := flag.String("package-secret", "", "secret shared with other instances to sign family export packages; empty disables export and import")
+	matrixHomeserver := flag.String("matrix-homeserver", "", "Matrix homeserver URL; enables the matrix notification channel")
+	matrixToken := flag.String("matrix-token", "", "access token of the Matrix bot account")
+	ntfyServer := flag.String("ntfy-server", "https://ntfy.sh", "default ntfy server for topic names; empty disables the ntfy channel")
+	ntfyToken := flag.String("ntfy-token", "", "access token for protected ntfy topics")
+	gotifyServer := flag.String("gotify-server", "", "default Gotify server for application tokens")
+	smtpAddr := flag.String("smtp-addr", "", "SMTP server as host:port; enables the email notification channel")
+	smtpFrom := flag.String("smtp-from", "reminders@localhost", "sender address of notification emails")
+	smtpUser := flag.String("smtp-user", "", "SMTP username (optional)")
+	smtpPassword := flag.String("smtp-password", "", "SMTP password")
+	inboundEmail := flag.String("inbound-email-address", "", "address replies to notification emails go to, with a reply token added as +token; mail for it must be posted to /inbound/email. Empty disables replies")
+	twilioSID := flag.String("twilio-account-sid", "", "Twilio account SID; enables the sms notification channel")
+	twilioToken := flag.String("twilio-token", "", "Twilio auth token")
+	twilioFrom := flag.String("twilio-from", "", "sending phone number or messaging service SID for text messages")
+	twilioAPI := flag.String("twilio-api", "https://api.twilio.com", "base URL of the Twilio-compatible SMS API")
+	webhookSecret := flag.String("webhook-secret", "", "secret used to sign outbound webhook deliveries; enables the webhook notification channel")
+	appriseAPI := flag.String("apprise-api", "", "Apprise API server used for notification URL schemes without native support")
+	weatherAPI := flag.String("weather-api", condition.DefaultWeatherAPI, "Open-Meteo compatible forecast API for reminder conditions on the weather; empty disables them")
+	airQualityAPI := flag.String("air-quality-api", condition.DefaultAirQualityAPI, "Open-Meteo compatible air quality API for reminder conditions on air quality; empty disables them")
+	templatePackKeys := flag.String("template-pack-keys", "", "publishers whose template packs may be imported from a URL, as comma-separated key_id=base64 Ed25519 public keys; empty disables imports")
+	alexaSkillID := flag.String("alexa-skill-id", "", "Alexa skill ID; enables the /alexa endpoint when set")
+	alexaFamily := flag.String("alexa-family", "", "family ID the Alexa skill acts on")
+	updateCheck := flag.Bool("update-check", false, "check GitHub twice a day for new releases, reported at /admin/update-status and in the log")
+	updateRepo := flag.String("update-repo", version.DefaultRepo, "GitHub repository whose releases -update-check looks at")
+	requestTimeout := flag.Duration("request-timeout", handlers.RequestTimeout, "deadline of each API request, including the storage calls made for it; requests running over are answered with 504. 0 disables the deadline")
+	listCacheTTL := flag.Duration("list-cache-ttl", handlers.ListCacheTTL, "how long GET /reminders answers identical queries from an earlier result; changes made through the API are seen at once. 0 only shares queries running at the same time")
+	trashRetention := flag.Duration("trash-retention", handlers.TrashRetention, "how long deleted reminders and families can be restored from the trash before being purged")
+	telemetryEndpoint := flag.String("telemetry-endpoint", "", "URL to send anonymous usage counts to once a day, previewed at /admin/telemetry; empty (the default) disables telemetry")
 
 	// Storage flags
-	storageType := flag.String("storage", "file", "storage backend to use: memory, file, sqlite, or mongo")
+	storageType := flag.String("storage", "file", "storage backend to use: memory, file, sqlite, mongo, or postgres")
 	mongoConnString := flag.String("mongo-conn", "mongodb://localhost:27017", "MongoDB connection string (used when storage=mongo)")
 	mongoDatabase := flag.String("mongo-db", "reminder_app", "MongoDB database name (used when storage=mongo)")
+	mongoConnectAttempts := flag.Int("mongo-connect-attempts", 1, "how many times to try reaching MongoDB at startup before giving up (used when storage=mongo)")
+	mongoConnectWait := flag.Duration("mongo-connect-wait", 2*time.Second, "pause after the first failed MongoDB connection attempt, doubling after each further one up to 30s (used when storage=mongo)")
 	sqliteDbPath := flag.String("sqlite-db", "reminder_app.db", "SQLite database file path (used when storage=sqlite)")
+	postgresConnString := flag.String("postgres-conn", "postgres://localhost:5432/reminder_app", "PostgreSQL connection URL or DSN (used when storage=postgres)")
+	postgresMaxConns := flag.Int("postgres-max-conns", 10, "maximum open PostgreSQL connections; 0 means unlimited (used when storage=postgres)")
+	postgresReplicaConn := flag.String("postgres-replica-conn", "", "connection URL or DSN of a read-only PostgreSQL standby to send reads to (used when storage=postgres)")
+	mongoSecondaryReads := flag.Bool("mongo-secondary-reads", false, "send reads to secondary members of the MongoDB replica set (used when storage=mongo)")
+	replicaMaxStaleness := flag.Duration("replica-max-staleness", 90*time.Second, "how far behind the primary replica reads may be; reads go to the primary for this long after each write. MongoDB requires at least 90s")
+	restorePointInterval := flag.Duration("restore-point-interval", 0, "how often to capture a restore point of sqlite or postgres storage for point-in-time restores, listed at /admin/restore-points; 0 disables them. See reminderctl restore")
+	restorePointRetention := flag.Duration("restore-point-retention", 7*24*time.Hour, "how long restore points, and the sqlite snapshots behind them, are kept")
+	sqliteArchiveDir := flag.String("sqlite-archive-dir", "", "directory to write the database snapshots of sqlite restore points to (used when storage=sqlite)")
+	dualWrite := flag.String("dual-write", "", "second storage backend to mirror every write to while migrating to it, configured by the same flags; reads stay on -storage. Compare the two at /admin/dual-write")
 
 	flag.Parse()
+	log.Printf("Reminder app %s", version.Version)
+	var cfg *config.File
+	if *configFile != "" {
+		cfg = config.New(*configFile, flag.CommandLine)
+		if _, err := cfg.Load(); err != nil {
+			log.Fatalf("Failed to read config file: %v", err)
+		}
+	}
+
+	// openStorage initializes the backend of the given type
+	openStorage := func(typ string) (storage.Storage, error) {
+		switch typ {
+		case "memory":
+			log.Println("Using memory storage")
+			return storage.NewMemoryStorage(), nil
+		case "file":
+			log.Println("Using file storage")
+			return storage.NewFileStorage("families.json", "reminders.json", "completion_events.json"), nil
+		case "sqlite":
+			log.Printf("Using SQLite storage (database: %s)", *sqliteDbPath)
+			return storage.NewSQLiteStorage(*sqliteDbPath)
+		case "mongo":
+			log.Printf("Using MongoDB storage (connection: %s, database: %s)", *mongoConnString, *mongoDatabase)
+			return storage.NewMongoStorageWithRetry(*mongoConnString, *mongoDatabase, storage.ConnectRetry{Attempts: *mongoConnectAttempts, Wait: *mongoConnectWait})
+		case "postgres":
+			// The connection string may carry a password, so it isn't logged
+			log.Println("Using PostgreSQL storage")
+			return storage.NewPostgresStorage(*postgresConnString, *postgresMaxConns)
+		}
+		return nil, fmt.Errorf("invalid storage type: %s. Valid options are: memory, file, sqlite, mongo, postgres", typ)
+	}
 
 	// Initialize storage based on type
-	var store storage.Storage
-	var err error
-
-	switch *storageType {
-	case "memory":
-		log.Println("Using memory storage")
-		store = storage.NewMemoryStorage()
-	case "file":
-		log.Println("Using file storage")
-		store = storage.NewFileStorage("families.json", "reminders.json", "completion_events.json")
-	case "sqlite":
-		log.Printf("Using SQLite storage (database: %s)", *sqliteDbPath)
-		store, err = storage.NewSQLiteStorage(*sqliteDbPath)
-		if err != nil {
-			log.Fatalf("Failed to initialize SQLite storage: %v", err)
+	store, err := openStorage(*storageType)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	var replica storage.Storage
+	switch {
+	case *postgresReplicaConn != "" && *storageType == "postgres":
+		log.Println("Reading from a PostgreSQL replica")
+		replica, err = storage.NewPostgresReplica(*postgresReplicaConn, *postgresMaxConns)
+	case *mongoSecondaryReads && *storageType == "mongo":
+		log.Println("Reading from MongoDB secondaries")
+		replica, err = store.(*storage.MongoStorage).SecondaryReads(*replicaMaxStaleness)
+	}
+	if err != nil {
+		log.Fatalf("Failed to initialize read replica: %v", err)
+	}
+	if replica != nil {
+		store = storage.NewReplicaStorage(store, replica, *replicaMaxStaleness)
+	}
+	if *dualWrite != "" {
+		if *dualWrite == *storageType {
+			log.Fatalf("-dual-write must name a different backend than -storage")
 		}
-	case "mongo":
-		log.Printf("Using MongoDB storage (connection: %s, database: %s)", *mongoConnString, *mongoDatabase)
-		store, err = storage.NewMongoStorage(*mongoConnString, *mongoDatabase)
+		log.Printf("Mirroring writes to %s storage", *dualWrite)
+		secondary, err := openStorage(*dualWrite)
 		if err != nil {
-			log.Fatalf("Failed to initialize MongoDB storage: %v", err)
+			log.Fatalf("Failed to initialize dual-write storage: %v", err)
 		}
-	default:
-		log.Fatalf("Invalid storage type: %s. Valid options are: memory, file, sqlite, mongo", *storageType)
+		handlers.DualWrite = storage.NewDualWriteStorage(store, secondary)
+		store = handlers.DualWrite
 	}
 
+	// Verify the storage before serving from it. Problems keep /readyz
+	// failing and every other request rejected rather than exiting, so the
+	// diagnostics stay visible to the orchestrator.
+	problems := storage.Verify(store)
+	for _, p := range problems {
+		log.Printf("Storage check failed: %s", p)
+	}
+	handlers.SetReadiness(problems)
+
+	// Fail fast while the database is unreachable
+	handlers.StorageBreaker = breaker.New("storage")
+	store = storage.WithBreaker(store, handlers.StorageBreaker)
 	handlers.Store = store
+	handlers.StrictJSON.Store(*strictJSON)
+	handlers.BaseURL = *baseURL
+	if *linkSecret != "" {
+		handlers.LinkSigner = links.NewSigner([]byte(*linkSecret))
+	} else {
+		log.Println("No -link-secret set; completion links will stop working after a restart")
+		handlers.LinkSigner, err = links.NewRandomSigner()
+		if err != nil {
+			log.Fatalf("Failed to initialize link signer: %v", err)
+		}
+	}
+	handlers.AdminToken = *adminToken
+	handlers.DisableDeprecated.Store(*disableDeprecated)
+	handlers.RequestTimeout = *requestTimeout
+	handlers.ListCacheTTL = *listCacheTTL
+	handlers.TrashRetention = *trashRetention
+	if *inboundEmail != "" {
+		if _, err := mail.ParseAddress(*inboundEmail); err != nil {
+			log.Fatalf("Invalid -inbound-email-address: %v", err)
+		}
+		handlers.InboundEmailAddress = *inboundEmail
+	}
+	if *packageSecret != "" {
+		handlers.PackageKey = []byte(*packageSecret)
+	}
+	if *restorePointInterval > 0 {
+		if handlers.RestorePoints, err = pitr.New(store, *sqliteArchiveDir); err != nil {
+			log.Fatalf("Invalid -restore-point-interval: %v", err)
+		}
+		handlers.RestorePoints.Interval = *restorePointInterval
+		handlers.RestorePoints.Retention = *restorePointRetention
+	}
+	if handlers.TemplatePackKeys, err = pack.ParseKeyring(*templatePackKeys); err != nil {
+		log.Fatalf("Invalid -template-pack-keys: %v", err)
+	}
+	// notifiers builds the notification channels from the flags
+	notifiers := func() map[string]notify.Notifier {
+		n := make(map[string]notify.Notifier)
+		if *matrixHomeserver != "" {
+			n["matrix"] = notify.NewMatrixNotifier(*matrixHomeserver, *matrixToken)
+		}
+		if *ntfyServer != "" {
+			n["ntfy"] = notify.NewNtfyNotifier(*ntfyServer, *ntfyToken)
+		}
+		// Gotify targets may carry their own server URL, so the channel is
+		// available even without a default server
+		n["gotify"] = notify.NewGotifyNotifier(*gotifyServer)
+		n["apprise"] = notify.NewAppriseNotifier(*appriseAPI)
+		if *webhookSecret != "" {
+			n["webhook"] = notify.NewWebhookNotifier(*webhookSecret)
+		}
+		if *smtpAddr != "" {
+			n["email"] = notify.NewEmailNotifier(*smtpAddr, *smtpFrom, *smtpUser, *smtpPassword)
+		}
+		if *twilioSID != "" {
+			n["sms"] = notify.NewSMSNotifier(*twilioAPI, *twilioSID, *twilioToken, *twilioFrom)
+		}
+		return n
+	}
+	handlers.Notifier = notify.NewDispatcher()
+	handlers.Notifier.Replace(notifiers())
+	handlers.Conditions = condition.NewRegistry()
+	if *weatherAPI != "" {
+		handlers.Conditions.Register("weather", condition.NewWeatherProvider(*weatherAPI))
+	}
+	if *airQualityAPI != "" {
+		handlers.Conditions.Register("air_quality", condition.NewAirQualityProvider(*airQualityAPI))
+	}
+	sched := scheduler.New(store, handlers.Notifier)
+	sched.Link = handlers.NotificationLink
+	sched.ReplyTo = handlers.NotificationReplyTo
+	sched.Conditions = handlers.Conditions
+	handlers.Webhooks = webhook.NewDispatcher(store)
+	handlers.Telemetry = telemetry.New(store, *storageType, *telemetryEndpoint)
+	if len(problems) == 0 {
+		go sched.Run(context.Background())
+		go handlers.Webhooks.Run(context.Background())
+		if *updateCheck {
+			handlers.Updates = version.NewChecker(*updateRepo)
+			go handlers.Updates.Run(context.Background())
+		}
+		if handlers.RestorePoints != nil {
+			go handlers.RestorePoints.Run(context.Background())
+		}
+		if *telemetryEndpoint != "" {
+			log.Printf("Sending anonymous usage telemetry to %s", *telemetryEndpoint)
+			go handlers.Telemetry.Run(context.Background())
+		}
+	} else {
+		log.Println("Not ready; the scheduler will not run")
+	}
+
+	version.Storage = *storageType
+	features := func() map[string]bool {
+		f := map[string]bool{
+			"tls":                *tlsCert != "" && *tlsKey != "",
+			"strict_json":        *strictJSON,
+			"disable_deprecated": *disableDeprecated,
+			"admin":              *adminToken != "",
+			"package_transfer":   *packageSecret != "",
+			"template_packs":     len(handlers.TemplatePackKeys) > 0,
+			"persistent_links":   *linkSecret != "",
+			"email_replies":      *inboundEmail != "",
+			"alexa":              *alexaSkillID != "",
+			"telemetry":          *telemetryEndpoint != "",
+			"update_check":       *updateCheck,
+			"immutable_assets":   *immutableAssets,
+			"precompress":        *precompress,
+			"static_rate_limit":  *staticRateLimit > 0,
+			"config_reload":      cfg != nil,
+			"dual_write":         *dualWrite != "",
+			"read_replica":       replica != nil,
+			"restore_points":     handlers.RestorePoints != nil,
+		}
+		for _, typ := range handlers.Notifier.Types() {
+			f["notify_"+typ] = true
+		}
+		for _, typ := range handlers.Conditions.Types() {
+			f["condition_"+typ] = true
+		}
+		return f
+	}
+	version.SetFeatures(features())
+
+	if *alexaSkillID != "" {
+		handlers.AlexaVerifier = &alexa.Verifier{ApplicationID: *alexaSkillID}
+		handlers.AlexaFamilyID = *alexaFamily
+	}
 
 	r := mux.NewRouter()
+	r.Use(handlers.RequireReady)
+	r.Use(handlers.TimeoutMiddleware)
+	r.Use(handlers.DeprecationMiddleware)
+	r.Use(handlers.AuthorizationMiddleware)
+	r.Use(handlers.IdempotencyMiddleware)
+	r.HandleFunc("/healthz", handlers.HealthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", handlers.ReadyzHandler).Methods("GET")
+	r.HandleFunc("/version", handlers.VersionHandler).Methods("GET")
+	r.HandleFunc("/meta/timezones", handlers.TimezonesHandler).Methods("GET")
+
+	// What the caller may do, for clients to hide refused actions
+	r.HandleFunc("/me/permissions", handlers.MyPermissionsHandler).Methods("GET")
+	r.HandleFunc("/me/preferences", handlers.GetPreferencesHandler).Methods("GET")
+	r.HandleFunc("/me/preferences", handlers.PutPreferencesHandler).Methods("PUT")
 
 	// Family routes
 	r.HandleFunc("/families", handlers.CreateFamilyHandler).Methods("POST")
 	r.HandleFunc("/families", handlers.ListFamiliesHandler).Methods("GET")
 	r.HandleFunc("/families/{id}", handlers.GetFamilyHandler).Methods("GET")
 	r.HandleFunc("/families/{id}", handlers.DeleteFamilyHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}", handlers.PatchFamilyHandler).Methods("PATCH")
+	r.HandleFunc("/families/{id}/restore", handlers.RestoreFamilyHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/members", handlers.AddMemberHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/members/{name}", handlers.RemoveMemberHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}/avatar", handlers.PutAvatarHandler).Methods("PUT")
+	r.HandleFunc("/families/{id}/avatar", handlers.GetAvatarHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/avatar", handlers.DeleteAvatarHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}/members/{name}/avatar", handlers.PutAvatarHandler).Methods("PUT")
+	r.HandleFunc("/families/{id}/members/{name}/avatar", handlers.GetAvatarHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/members/{name}/avatar", handlers.DeleteAvatarHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}/settings", handlers.GetFamilySettingsHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/settings", handlers.UpdateFamilySettingsHandler).Methods("PUT")
+	r.HandleFunc("/families/{id}/workload", handlers.FamilyWorkloadHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/leaderboard", handlers.FamilyLeaderboardHandler).Methods("GET")
+	r.HandleFunc("/digest/preview", handlers.DigestPreviewHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/dashboard.png", handlers.FamilyDashboardHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/report.pdf", handlers.FamilyReportHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/stats", handlers.FamilyStatsHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/feed.atom", handlers.FamilyFeedHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/calendar.ics", handlers.FamilyCalendarHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/import/ics", handlers.ImportCalendarHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/poll/reminders", handlers.PollRemindersHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/poll/completions", handlers.PollCompletionsHandler).Methods("GET")
+	r.HandleFunc("/admin/backup.sqlite", handlers.BackupHandler).Methods("GET")
+	r.HandleFunc("/admin/fsck", handlers.FsckHandler).Methods("POST")
+	r.HandleFunc("/admin/restore-points", handlers.ListRestorePointsHandler).Methods("GET")
+	r.HandleFunc("/admin/restore-points", handlers.CreateRestorePointHandler).Methods("POST")
+	r.HandleFunc("/admin/dual-write", handlers.DualWriteHandler).Methods("GET")
+	r.HandleFunc("/admin/dual-write/sync", handlers.DualWriteSyncHandler).Methods("POST")
+	r.HandleFunc("/admin/deprecations", handlers.DeprecationsHandler).Methods("GET")
+	r.HandleFunc("/admin/timeouts", handlers.TimeoutsHandler).Methods("GET")
+	r.HandleFunc("/admin/telemetry", handlers.TelemetryHandler).Methods("GET")
+	r.HandleFunc("/admin/update-status", handlers.UpdateStatusHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/export-package", handlers.ExportPackageHandler).Methods("POST")
+	r.HandleFunc("/families/import-package", handlers.ImportPackageHandler).Methods("POST")
+	r.HandleFunc("/templates/import-url", handlers.ImportTemplatePackHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/suggestions", handlers.ListSuggestionsHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/drift", handlers.FamilyDriftHandler).Methods("GET")
+	r.HandleFunc("/suggestions/{id}/accept", handlers.AcceptSuggestionHandler).Methods("POST")
+	r.HandleFunc("/suggestions/{id}/dismiss", handlers.DismissSuggestionHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/notifications/test", handlers.TestNotificationHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/deliveries", handlers.DeliveriesHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/webhooks", handlers.CreateWebhookHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/webhooks", handlers.ListWebhooksHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/webhooks/{webhook}", handlers.DeleteWebhookHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}/webhooks/{webhook}/deliveries", handlers.WebhookDeliveriesHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/guests", handlers.CreateGuestHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/guests", handlers.ListGuestsHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/guests/{guest}", handlers.RevokeGuestHandler).Methods("DELETE")
 
 	// Reminder routes
 	r.HandleFunc("/reminders", handlers.CreateReminderHandler).Methods("POST")
 	r.HandleFunc("/reminders", handlers.ListRemindersHandler).Methods("GET")
+	r.HandleFunc("/tags", handlers.ListTagsHandler).Methods("GET")
+	r.HandleFunc("/series/{id}", handlers.SeriesHandler).Methods("GET")
+	r.HandleFunc("/reminders/reorder", handlers.ReorderRemindersHandler).Methods("POST")
+	r.HandleFunc("/reminders/batch", handlers.CreateRemindersBatchHandler).Methods("POST")
+	r.HandleFunc("/reminders/batch", handlers.UpdateRemindersBatchHandler).Methods("PATCH")
 	r.HandleFunc("/reminders/{id}", handlers.GetReminderHandler).Methods("GET")
 	r.HandleFunc("/reminders/{id}", handlers.DeleteReminderHandler).Methods("DELETE")
 	r.HandleFunc("/reminders/{id}", handlers.UpdateReminderHandler).Methods("PATCH")
+	r.HandleFunc("/reminders/{id}", handlers.ReplaceReminderHandler).Methods("PUT")
+	r.HandleFunc("/reminders/{id}/restore", handlers.RestoreReminderHandler).Methods("POST")
+	r.HandleFunc("/trash", handlers.ListTrashHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/complete-link", handlers.CompletionLinkHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/qr.png", handlers.ReminderQRHandler).Methods("GET")
+
+	// Signed one-tap completion links
+	r.HandleFunc("/c/{token}", handlers.ConfirmViaLinkHandler).Methods("GET")
+	r.HandleFunc("/c/{token}", handlers.CompleteViaLinkHandler).Methods("POST")
+	r.HandleFunc("/inbound/email", handlers.InboundEmailHandler).Methods("POST")
+	r.HandleFunc("/shares", handlers.CreateShareHandler).Methods("POST")
+	r.HandleFunc("/shares", handlers.ListSharesHandler).Methods("GET")
+	r.HandleFunc("/shares/{id}", handlers.DeleteShareHandler).Methods("DELETE")
+	r.HandleFunc("/shared/{token}", handlers.SharedViewHandler).Methods("GET")
+	r.HandleFunc("/hooks", handlers.CreateHookHandler).Methods("POST")
+	r.HandleFunc("/hooks", handlers.ListHooksHandler).Methods("GET")
+	r.HandleFunc("/hooks/{id}", handlers.DeleteHookHandler).Methods("DELETE")
+	r.HandleFunc("/hooks/{token}", handlers.HookDeliveryHandler).Methods("POST")
+	r.HandleFunc("/widget/{token}", handlers.WidgetHandler).Methods("GET")
 
 	// CompletionEvent routes
 	r.HandleFunc("/completion-events", handlers.CreateCompletionEventHandler).Methods("POST")
+	r.HandleFunc("/smart-lists", handlers.CreateSmartListHandler).Methods("POST")
+	r.HandleFunc("/smart-lists", handlers.ListSmartListsHandler).Methods("GET")
+	r.HandleFunc("/smart-lists/{id}", handlers.GetSmartListHandler).Methods("GET")
+	r.HandleFunc("/smart-lists/{id}", handlers.UpdateSmartListHandler).Methods("PUT")
+	r.HandleFunc("/smart-lists/{id}", handlers.DeleteSmartListHandler).Methods("DELETE")
+	r.HandleFunc("/smart-lists/{id}/reminders", handlers.SmartListRemindersHandler).Methods("GET")
+	r.HandleFunc("/projects", handlers.CreateProjectHandler).Methods("POST")
+	r.HandleFunc("/projects", handlers.ListProjectsHandler).Methods("GET")
+	r.HandleFunc("/projects/{id}", handlers.GetProjectHandler).Methods("GET")
+	r.HandleFunc("/projects/{id}", handlers.UpdateProjectHandler).Methods("PUT")
+	r.HandleFunc("/projects/{id}", handlers.DeleteProjectHandler).Methods("DELETE")
+	r.HandleFunc("/projects/{id}/reminders", handlers.ProjectRemindersHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/history", handlers.ReminderHistoryHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/status", handlers.SetReminderStatusHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/snooze", handlers.SnoozeReminderHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/ack", handlers.AckReminderHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/adjust-schedule", handlers.AdjustScheduleHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/status-events", handlers.ListStatusEventsHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/usage", handlers.ReportUsageHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/usage", handlers.ListUsageHandler).Methods("GET")
 	r.HandleFunc("/reminders/{id}/completion-events", handlers.ListCompletionEventsHandler).Methods("GET")
 	r.HandleFunc("/completion-events/{id}", handlers.GetCompletionEventHandler).Methods("GET")
 	r.HandleFunc("/completion-events/{id}", handlers.DeleteCompletionEventHandler).Methods("DELETE")
 
+	// Languages of the frontend
+	r.HandleFunc("/locales", handlers.LocalesHandler).Methods("GET")
+
+	// Live updates over WebSocket
+	r.HandleFunc("/ws", handlers.LiveHandler).Methods("GET")
+
+	// Voice assistant routes
+	r.HandleFunc("/alexa", handlers.AlexaHandler).Methods("POST")
+
 	// Static file server for frontend at "/"
-	staticFs := http.FileServer(http.Dir(*staticDir))
-	r.PathPrefix("/").Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		path := req.URL.Path
-		ext := filepath.Ext(path)
-		if ext != "" {
-			if ctype := mime.TypeByExtension(ext); ctype != "" {
-				w.Header().Set("Content-Type", ctype)
-			}
+	if *precompress {
+		if n, err := handlers.Precompress(*staticDir); err != nil {
+			log.Printf("Failed to precompress static files: %v", err)
+		} else {
+			log.Printf("Precompressed %d static files", n)
 		}
-		staticFs.ServeHTTP(w, req)
+	}
+	locales, err := i18n.Discover(*staticDir, *defaultLocale)
+	if err != nil {
+		log.Printf("Failed to look for frontend translations: %v", err)
+	}
+	handlers.Locales = locales
+	staticLimiter := handlers.NewClientLimiter(*staticRateLimit)
+	r.PathPrefix("/").Handler(handlers.StaticHandler(*staticDir, handlers.StaticOptions{
+		MaxAge:          *staticMaxAge,
+		ImmutableAssets: *immutableAssets,
+		DisableListing:  *disableListing,
+		Limiter:         staticLimiter,
+		Locales:         locales,
 	}))
 
+	// Reload the config file on SIGHUP. Connections, including live
+	// update WebSockets, stay open.
+	if cfg != nil {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				changed, pending, err := cfg.Reload(reloadable)
+				if err != nil {
+					log.Printf("Failed to reload config file: %v", err)
+					continue
+				}
+				handlers.StrictJSON.Store(*strictJSON)
+				handlers.DisableDeprecated.Store(*disableDeprecated)
+				staticLimiter.SetLimit(*staticRateLimit)
+				handlers.Notifier.Replace(notifiers())
+				version.SetFeatures(features())
+				log.Printf("Reloaded %s; changed: %s", cfg.Path, listOrNone(changed))
+				if len(pending) > 0 {
+					log.Printf("Restart to apply: %s", strings.Join(pending, ", "))
+				}
+			}
+		}()
+	}
+
 	if *tlsCert != "" && *tlsKey != "" {
 		addr := ":443"
 		log.Println("Starting reminder app with HTTPS on", addr, "serving static files from", *staticDir)
@@ -103,3 +523,11 @@ func main() {
 		}
 	}
 }
+
+// listOrNone joins names for the log
+func listOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+)
+
+// runFamily runs the family subcommands
+func runFamily(c *client, args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "usage: family list [-json]")
+		return 2
+	}
+	fs := flag.NewFlagSet("family list", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the families as JSON")
+	fs.Parse(args[1:])
+
+	var families []*family.Family
+	if err := c.doJSON("GET", "/families", "", nil, &families); err != nil {
+		log.Printf("family list failed: %v", err)
+		return 1
+	}
+	if *asJSON {
+		printJSON(families)
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tMEMBERS")
+	for _, f := range families {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.ID, f.Name, strings.Join(f.Members, ", "))
+	}
+	tw.Flush()
+	return 0
+}
+
+// runReminder runs the reminder subcommands
+func runReminder(c *client, args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "create":
+			return runReminderCreate(c, args[1:])
+		case "complete":
+			return runReminderComplete(c, args[1:])
+		}
+	}
+	fmt.Fprintln(os.Stderr, "usage: reminder create -family ID -title TITLE [flags] | reminder complete [-force] ID")
+	return 2
+}
+
+// runReminderCreate creates a reminder and prints its ID
+func runReminderCreate(c *client, args []string) int {
+	fs := flag.NewFlagSet("reminder create", flag.ExitOnError)
+	familyID := fs.String("family", "", "ID of the family the reminder belongs to")
+	title := fs.String("title", "", "title of the reminder")
+	description := fs.String("description", "", "description of the reminder")
+	member := fs.String("member", "", "family member the reminder is assigned to")
+	due := fs.String("due", "", "due date in RFC 3339 format, e.g. 2024-05-01T09:00:00Z")
+	recurrence := fs.String("recurrence", "once", "how often the reminder recurs: once, hourly, daily, weekly, monthly, or yearly")
+	days := fs.String("days", "", "comma-separated days a weekly reminder recurs on")
+	asJSON := fs.Bool("json", false, "print the created reminder as JSON")
+	fs.Parse(args)
+	if *familyID == "" || *title == "" {
+		fmt.Fprintln(os.Stderr, "reminder create: -family and -title are required")
+		fs.Usage()
+		return 2
+	}
+
+	req := map[string]any{
+		"title":         *title,
+		"description":   *description,
+		"family_id":     *familyID,
+		"family_member": *member,
+		"due_date":      *due,
+		"recurrence":    recurrencePattern(*recurrence, *days),
+	}
+	var r reminder.Reminder
+	if err := c.doJSON("POST", "/reminders", "application/json", req, &r); err != nil {
+		log.Printf("reminder create failed: %v", err)
+		return 1
+	}
+	if *asJSON {
+		printJSON(r)
+	} else {
+		fmt.Println(r.ID)
+	}
+	return 0
+}
+
+// recurrencePattern builds the pattern for the -recurrence and -days flags
+func recurrencePattern(typ, days string) reminder.RecurrencePattern {
+	p := reminder.RecurrencePattern{Type: typ}
+	for _, d := range strings.Split(days, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			p.Days = append(p.Days, strings.ToLower(d))
+		}
+	}
+	return p
+}
+
+// runReminderComplete marks reminders as completed
+func runReminderComplete(c *client, args []string) int {
+	fs := flag.NewFlagSet("reminder complete", flag.ExitOnError)
+	force := fs.Bool("force", false, "record a completion even if the occurrence was already completed")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "reminder complete: at least one reminder ID is required")
+		return 2
+	}
+	status := 0
+	for _, id := range fs.Args() {
+		path := "/reminders/" + url.PathEscape(id)
+		if *force {
+			path += "?force=true"
+		}
+		if err := c.doJSON("PATCH", path, "application/merge-patch+json", map[string]bool{"completed": true}, nil); err != nil {
+			log.Printf("reminder complete %s failed: %v", id, err)
+			status = 1
+			continue
+		}
+		fmt.Printf("completed %s\n", id)
+	}
+	return status
+}
+
+// runExport writes a family's signed export package to a file or stdout,
+// encrypted if a passphrase file is given
+func runExport(c *client, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	familyID := fs.String("family", "", "ID of the family to export")
+	out := fs.String("o", "", "file to write the package to (default stdout)")
+	passphraseFile := fs.String("passphrase-file", "", "file whose first line is the passphrase to encrypt the package with")
+	fs.Parse(args)
+	if *familyID == "" {
+		fmt.Fprintln(os.Stderr, "export: -family is required")
+		fs.Usage()
+		return 2
+	}
+	if err := readPassphrase(c, *passphraseFile); err != nil {
+		log.Printf("export failed: %v", err)
+		return 1
+	}
+
+	data, err := c.do("POST", "/families/"+url.PathEscape(*familyID)+"/export-package", "", nil)
+	if err != nil {
+		log.Printf("export failed: %v", err)
+		return 1
+	}
+	if *out == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*out, data, 0o600); err != nil {
+		log.Printf("export failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// runImport imports a package written by export, from a file or stdin,
+// and prints the new family's ID
+func runImport(c *client, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	passphraseFile := fs.String("passphrase-file", "", "file whose first line is the passphrase the package was encrypted with")
+	fs.Parse(args)
+	if err := readPassphrase(c, *passphraseFile); err != nil {
+		log.Printf("import failed: %v", err)
+		return 1
+	}
+	var data []byte
+	var err error
+	switch fs.NArg() {
+	case 0:
+		data, err = io.ReadAll(os.Stdin)
+	case 1:
+		data, err = os.ReadFile(fs.Arg(0))
+	default:
+		fmt.Fprintln(os.Stderr, "usage: import [FILE]")
+		return 2
+	}
+	if err != nil {
+		log.Printf("import failed: %v", err)
+		return 1
+	}
+
+	var f family.Family
+	body, err := c.do("POST", "/families/import-package", "application/gzip", bytes.NewReader(data))
+	if err == nil {
+		err = json.Unmarshal(body, &f)
+	}
+	if err != nil {
+		log.Printf("import failed: %v", err)
+		return 1
+	}
+	fmt.Println(f.ID)
+	return 0
+}
+
+// readPassphrase sets the client's package passphrase to the first line of
+// path, if given. The passphrase is read from a file rather than a flag to
+// keep it out of shell history and process listings.
+func readPassphrase(c *client, path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	c.passphrase = strings.TrimRight(line, "\r")
+	if c.passphrase == "" {
+		return fmt.Errorf("%s holds no passphrase", path)
+	}
+	return nil
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
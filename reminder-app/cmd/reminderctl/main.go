@@ -0,0 +1,237 @@
+// Command reminderctl administers the reminder app from scripts. Commands
+// talk to a running server's API when -server is set, and otherwise serve
+// the API in-process from the storage backend the flags select, which
+// keeps them working while the server is down. fsck and restore always
+// work on the storage directly.
+//
+//	reminderctl [storage flags] fsck [-repair] [-json]
+//	reminderctl [storage flags] restore [-archive DIR] [-list] -to TIME
+//	reminderctl [flags] family list [-json]
+//	reminderctl [flags] reminder create -family ID -title TITLE [-member NAME] [-due TIME] [-recurrence TYPE]
+//	reminderctl [flags] reminder complete [-force] ID...
+//	reminderctl [flags] export -family ID [-o FILE] [-passphrase-file FILE]
+//	reminderctl [flags] import [-passphrase-file FILE] [FILE]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"reminder-app/internal/fsck"
+	"reminder-app/internal/pitr"
+	"reminder-app/internal/storage"
+)
+
+func main() {
+	storageType := flag.String("storage", "file", "storage backend to use: memory, file, sqlite, mongo, or postgres")
+	mongoConnString := flag.String("mongo-conn", "mongodb://localhost:27017", "MongoDB connection string (used when storage=mongo)")
+	mongoDatabase := flag.String("mongo-db", "reminder_app", "MongoDB database name (used when storage=mongo)")
+	sqliteDbPath := flag.String("sqlite-db", "reminder_app.db", "SQLite database file path (used when storage=sqlite)")
+	postgresConnString := flag.String("postgres-conn", "postgres://localhost:5432/reminder_app", "PostgreSQL connection URL or DSN (used when storage=postgres)")
+	server := flag.String("server", "", "base URL of the server to send commands to, e.g. http://localhost:8080; commands use the storage directly when empty")
+	token := flag.String("token", "", "admin token to send to the server")
+	actor := flag.String("actor", "", "family member to act as, for families that assign roles")
+	packageSecret := flag.String("package-secret", "", "secret that signs export packages (used without -server)")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "usage: %s [flags] command [args]\n\ncommands:\n", os.Args[0])
+		fmt.Fprintln(out, "  fsck [-repair] [-json]")
+		fmt.Fprintln(out, "  restore [-archive DIR] [-list] -to TIME")
+		fmt.Fprintln(out, "  family list [-json]")
+		fmt.Fprintln(out, "  reminder create -family ID -title TITLE [-member NAME] [-due TIME] [-recurrence TYPE] [-days DAYS]")
+		fmt.Fprintln(out, "  reminder complete [-force] ID...")
+		fmt.Fprintln(out, "  export -family ID [-o FILE] [-passphrase-file FILE]")
+		fmt.Fprintln(out, "  import [-passphrase-file FILE] [FILE]")
+		fmt.Fprintln(out, "\nflags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+
+	commands := map[string]func(*client, []string) int{
+		"family":   runFamily,
+		"reminder": runReminder,
+		"export":   runExport,
+		"import":   runImport,
+	}
+	run, ok := commands[cmd]
+	if cmd != "fsck" && cmd != "restore" && !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if ok && *server != "" {
+		os.Exit(run(newServerClient(*server, *token, *actor), args))
+	}
+
+	// A SQLite database is restored by replacing its file, so it must not
+	// be open
+	if cmd == "restore" && *storageType == "sqlite" {
+		os.Exit(runRestoreSQLite(*sqliteDbPath, args))
+	}
+
+	var store storage.Storage
+	var err error
+	switch *storageType {
+	case "memory":
+		store = storage.NewMemoryStorage()
+	case "file":
+		store = storage.NewFileStorage("families.json", "reminders.json", "completion_events.json")
+	case "sqlite":
+		store, err = storage.NewSQLiteStorage(*sqliteDbPath)
+	case "mongo":
+		store, err = storage.NewMongoStorage(*mongoConnString, *mongoDatabase)
+	case "postgres":
+		store, err = storage.NewPostgresStorage(*postgresConnString, 1)
+	default:
+		log.Fatalf("Invalid storage type: %s. Valid options are: memory, file, sqlite, mongo, postgres", *storageType)
+	}
+	if err != nil {
+		log.Fatalf("Failed to initialize %s storage: %v", *storageType, err)
+	}
+
+	switch cmd {
+	case "fsck":
+		os.Exit(runFsck(store, args))
+	case "restore":
+		os.Exit(runRestore(store, args))
+	}
+	var packageKey []byte
+	if *packageSecret != "" {
+		packageKey = []byte(*packageSecret)
+	}
+	os.Exit(run(newStorageClient(store, packageKey, *actor), args))
+}
+
+// runFsck checks storage and returns the exit status: 1 if any issue is
+// left unrepaired
+func runFsck(store storage.Storage, args []string) int {
+	fs := flag.NewFlagSet("fsck", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "fix the issues found")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	report, err := fsck.Check(store, *repair)
+	if err != nil {
+		log.Printf("fsck failed: %v", err)
+		return 1
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, is := range report.Issues {
+			status := ""
+			switch {
+			case is.Repaired:
+				status = " (repaired)"
+			case is.RepairError != "":
+				status = fmt.Sprintf(" (repair failed: %s)", is.RepairError)
+			}
+			fmt.Printf("%s %s %s: %s%s\n", is.Kind, is.Entity, is.ID, is.Detail, status)
+		}
+		fmt.Printf("%d issues, %d unrepaired\n", len(report.Issues), report.Unrepaired())
+	}
+	if report.Unrepaired() > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runRestoreSQLite restores the SQLite database at dbPath from the snapshot
+// archive of its restore points; see package pitr. The server must be
+// stopped first.
+func runRestoreSQLite(dbPath string, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	archive := fs.String("archive", "", "directory the server writes snapshots to (its -sqlite-archive-dir)")
+	list := fs.Bool("list", false, "list the snapshots instead of restoring")
+	to := fs.String("to", "", "time to restore to, as RFC 3339 (e.g. 2025-03-04T09:30:00Z)")
+	fs.Parse(args)
+	if *archive == "" {
+		fmt.Fprintln(os.Stderr, "restore: -archive is required with sqlite storage")
+		fs.Usage()
+		return 2
+	}
+	if *list {
+		snapshots, err := pitr.Snapshots(*archive)
+		if err != nil {
+			log.Printf("restore failed: %v", err)
+			return 1
+		}
+		for _, p := range snapshots {
+			fmt.Printf("%s %s\n", p.CreatedAt.Format(time.RFC3339Nano), p.Snapshot)
+		}
+		return 0
+	}
+	at, ok := restoreTime(fs, *to)
+	if !ok {
+		return 2
+	}
+	p, err := pitr.RestoreSQLite(dbPath, *archive, at)
+	if err != nil {
+		log.Printf("restore failed: %v", err)
+		return 1
+	}
+	fmt.Printf("restored %s from the snapshot of %s (%s); the previous database is %s.before-restore\n",
+		dbPath, p.CreatedAt.Format(time.RFC3339), p.Snapshot, dbPath)
+	return 0
+}
+
+// runRestore prints how to restore a PostgreSQL database to a restore
+// point; see package pitr. Other backends have none.
+func runRestore(store storage.Storage, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	list := fs.Bool("list", false, "list the restore points instead")
+	to := fs.String("to", "", "time to restore to, as RFC 3339 (e.g. 2025-03-04T09:30:00Z)")
+	fs.Parse(args)
+	if _, ok := store.(*storage.PostgresStorage); !ok {
+		log.Printf("restore failed: %v", pitr.ErrUnsupported)
+		return 1
+	}
+	points, err := pitr.List(store)
+	if err != nil {
+		log.Printf("restore failed: %v", err)
+		return 1
+	}
+	if *list {
+		for _, p := range points {
+			fmt.Printf("%s %s %s %s\n", p.CreatedAt.Format(time.RFC3339Nano), p.ID, p.LSN, p.Reason)
+		}
+		return 0
+	}
+	at, ok := restoreTime(fs, *to)
+	if !ok {
+		return 2
+	}
+	p, err := pitr.Latest(points, at)
+	if err != nil {
+		log.Printf("restore failed: %v", err)
+		return 1
+	}
+	fmt.Print(pitr.RecoverySettings(p))
+	return 0
+}
+
+// restoreTime parses the -to flag of restore
+func restoreTime(fs *flag.FlagSet, to string) (time.Time, bool) {
+	if to == "" {
+		fmt.Fprintln(os.Stderr, "restore: -to or -list is required")
+		fs.Usage()
+		return time.Time{}, false
+	}
+	at, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: -to must be an RFC 3339 time: %v\n", err)
+		return time.Time{}, false
+	}
+	return at, true
+}
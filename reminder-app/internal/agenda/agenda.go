@@ -0,0 +1,70 @@
+// Package agenda answers "what is due on a given day" for a family or
+// member. It is shared by the voice assistant, scheduled notifications and
+// dashboard views so they all agree on what counts as due and done.
+package agenda
+
+import (
+	"sort"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// Item is one occurrence of a reminder on the agenda
+type Item struct {
+	Reminder *reminder.Reminder `json:"reminder"`
+	At       time.Time          `json:"at"`
+	Done     bool               `json:"done"`
+}
+
+// ForDay returns the occurrences of the given reminders on the day containing
+// day (in day's location), sorted by time. Empty familyID or member match
+// everything. Occurrences of rotating reminders match the member whose turn
+// they are.
+func ForDay(list []*reminder.Reminder, day time.Time, familyID, member string) []Item {
+	start := reminder.StartOfDay(day)
+	end := reminder.NextDay(start)
+	var items []Item
+	for _, r := range list {
+		if familyID != "" && r.FamilyID != familyID {
+			continue
+		}
+		for _, at := range r.Occurrences(start, end) {
+			if member != "" && r.AssigneeAt(at) != member {
+				continue
+			}
+			items = append(items, Item{Reminder: r, At: at, Done: IsDone(r, at)})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].At.Equal(items[j].At) {
+			return items[i].Reminder.ID < items[j].Reminder.ID
+		}
+		return items[i].At.Before(items[j].At)
+	})
+	return items
+}
+
+// Pending filters items down to those not yet done
+func Pending(items []Item) []Item {
+	var pending []Item
+	for _, it := range items {
+		if !it.Done {
+			pending = append(pending, it)
+		}
+	}
+	return pending
+}
+
+// IsDone reports whether the occurrence of r at the given time has been
+// completed. One-off reminders use their Completed flag; recurring reminders
+// count as done when they were completed on the same day as the occurrence.
+func IsDone(r *reminder.Reminder, at time.Time) bool {
+	if !r.IsRecurring() {
+		return r.Completed
+	}
+	if r.CompletedAt == nil {
+		return false
+	}
+	return !r.CompletedAt.Before(reminder.StartOfDay(at))
+}
@@ -0,0 +1,93 @@
+package agenda
+
+import (
+	"fmt"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// Load is the projected effort of one family member over a period
+type Load struct {
+	Member      string `json:"member"`
+	Minutes     int    `json:"minutes"`
+	Occurrences int    `json:"occurrences"`
+}
+
+// Workload sums the effort of every occurrence in [from, to) of the family's
+// reminders per member. Every member is listed, in the given order, even if
+// nothing is assigned to them; reminders assigned to anyone else are ignored.
+func Workload(list []*reminder.Reminder, from, to time.Time, familyID string, members []string) []Load {
+	loads := make([]Load, len(members))
+	index := make(map[string]int, len(members))
+	for i, m := range members {
+		loads[i].Member = m
+		index[m] = i
+	}
+	for _, r := range list {
+		if r.FamilyID != familyID {
+			continue
+		}
+		// Each occurrence of a rotating reminder counts for whoever's
+		// turn it is
+		for _, at := range r.Occurrences(from, to) {
+			if i, ok := index[r.AssigneeAt(at)]; ok {
+				loads[i].Occurrences++
+				loads[i].Minutes += r.Effort
+			}
+		}
+	}
+	return loads
+}
+
+// WeekStart returns midnight on the Monday of the week containing t, in t's
+// location
+func WeekStart(t time.Time) time.Time {
+	day := reminder.StartOfDay(t)
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+	return day.AddDate(0, 0, -offset)
+}
+
+// ParseWeek resolves an ISO week such as "2025-W10", or any date within the
+// week as "2006-01-02", to the Monday starting it in loc
+func ParseWeek(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return WeekStart(t), nil
+	}
+	var year, week int
+	if n, err := fmt.Sscanf(s, "%d-W%d", &year, &week); err != nil || n != 2 || week < 1 || week > 53 {
+		return time.Time{}, fmt.Errorf("week must be YYYY-Www or YYYY-MM-DD: %q", s)
+	}
+	// January 4th is always in week 1
+	start := WeekStart(time.Date(year, time.January, 4, 0, 0, 0, 0, loc)).AddDate(0, 0, 7*(week-1))
+	if _, w := start.ISOWeek(); w != week {
+		return time.Time{}, fmt.Errorf("%d has no week %d", year, week)
+	}
+	return start, nil
+}
+
+// LeastLoaded returns the member with the least projected effort in the week
+// containing at, in at's location. The reminder skip is left out of the
+// count since it is the one being assigned. Ties go to the member with fewer
+// occurrences, then to the earlier member in the list.
+func LeastLoaded(list []*reminder.Reminder, at time.Time, familyID string, members []string, skip string) string {
+	others := make([]*reminder.Reminder, 0, len(list))
+	for _, r := range list {
+		if r.ID != skip {
+			others = append(others, r)
+		}
+	}
+	from := WeekStart(at)
+	best := -1
+	loads := Workload(others, from, from.AddDate(0, 0, 7), familyID, members)
+	for i, l := range loads {
+		if best < 0 || l.Minutes < loads[best].Minutes ||
+			(l.Minutes == loads[best].Minutes && l.Occurrences < loads[best].Occurrences) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return loads[best].Member
+}
@@ -0,0 +1,301 @@
+// Package alexa implements the parts of the Alexa Skills Kit HTTPS endpoint
+// contract that don't depend on the reminder domain: the request and
+// response envelopes and Amazon's request signature verification.
+package alexa
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"reminder-app/pkg/client"
+)
+
+// Request types sent by Alexa
+const (
+	LaunchRequest       = "LaunchRequest"
+	IntentRequest       = "IntentRequest"
+	SessionEndedRequest = "SessionEndedRequest"
+)
+
+// Intent confirmation states
+const (
+	ConfirmationNone      = "NONE"
+	ConfirmationConfirmed = "CONFIRMED"
+	ConfirmationDenied    = "DENIED"
+)
+
+// Slot is a single intent slot value
+type Slot struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	ConfirmationStatus string `json:"confirmationStatus,omitempty"`
+}
+
+// Intent is the intent of an IntentRequest
+type Intent struct {
+	Name               string          `json:"name"`
+	ConfirmationStatus string          `json:"confirmationStatus,omitempty"`
+	Slots              map[string]Slot `json:"slots,omitempty"`
+}
+
+// SlotValue returns the value of the named slot, or "" if it is unset
+func (i Intent) SlotValue(name string) string {
+	return strings.TrimSpace(i.Slots[name].Value)
+}
+
+type application struct {
+	ApplicationID string `json:"applicationId"`
+}
+
+// RequestEnvelope is the JSON body Alexa posts to the skill endpoint
+type RequestEnvelope struct {
+	Version string `json:"version"`
+	Session struct {
+		SessionID   string      `json:"sessionId"`
+		Application application `json:"application"`
+		User        struct {
+			UserID      string `json:"userId"`
+			AccessToken string `json:"accessToken,omitempty"`
+		} `json:"user"`
+	} `json:"session"`
+	Context struct {
+		System struct {
+			Application application `json:"application"`
+		} `json:"System"`
+	} `json:"context"`
+	Request struct {
+		Type      string    `json:"type"`
+		RequestID string    `json:"requestId"`
+		Timestamp time.Time `json:"timestamp"`
+		Locale    string    `json:"locale"`
+		Intent    Intent    `json:"intent"`
+	} `json:"request"`
+}
+
+// ApplicationID returns the skill ID the request was sent for
+func (e *RequestEnvelope) ApplicationID() string {
+	if id := e.Context.System.Application.ApplicationID; id != "" {
+		return id
+	}
+	return e.Session.Application.ApplicationID
+}
+
+// OutputSpeech is plain text spoken back to the user
+type OutputSpeech struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Directive is a dialog directive such as Dialog.ConfirmIntent
+type Directive struct {
+	Type          string  `json:"type"`
+	UpdatedIntent *Intent `json:"updatedIntent,omitempty"`
+}
+
+// ResponseEnvelope is the JSON body returned to Alexa
+type ResponseEnvelope struct {
+	Version  string `json:"version"`
+	Response struct {
+		OutputSpeech     *OutputSpeech `json:"outputSpeech,omitempty"`
+		Directives       []Directive   `json:"directives,omitempty"`
+		ShouldEndSession bool          `json:"shouldEndSession"`
+	} `json:"response"`
+}
+
+// Say builds a response that speaks text and ends the session
+func Say(text string) *ResponseEnvelope {
+	resp := &ResponseEnvelope{Version: "1.0"}
+	resp.Response.OutputSpeech = &OutputSpeech{Type: "PlainText", Text: text}
+	resp.Response.ShouldEndSession = true
+	return resp
+}
+
+// Ask builds a response that speaks text and keeps the session open
+func Ask(text string) *ResponseEnvelope {
+	resp := Say(text)
+	resp.Response.ShouldEndSession = false
+	return resp
+}
+
+// ConfirmIntent asks the user to confirm the intent before it is fulfilled
+func ConfirmIntent(prompt string, intent Intent) *ResponseEnvelope {
+	resp := Ask(prompt)
+	resp.Response.Directives = []Directive{{Type: "Dialog.ConfirmIntent", UpdatedIntent: &intent}}
+	return resp
+}
+
+// MaxClockSkew is the maximum age of a request timestamp Amazon allows
+const MaxClockSkew = 150 * time.Second
+
+// certSubject is the name Amazon's signing certificate must be issued for
+const certSubject = "echo-api.amazon.com"
+
+// Verifier checks that requests really come from Alexa for the configured
+// skill, following Amazon's "verify the request was sent by Alexa" rules.
+type Verifier struct {
+	// ApplicationID is the skill ID requests must be addressed to
+	ApplicationID string
+	// Roots overrides the system trust store; used by tests
+	Roots *x509.CertPool
+	// Fetch downloads the certificate chain; defaults to http.Get
+	Fetch func(url string) ([]byte, error)
+	// Now returns the current time; defaults to time.Now
+	Now func() time.Time
+
+	mu       sync.Mutex
+	certs    map[string]*x509.Certificate
+	requests *client.NonceCache
+}
+
+// Verify validates the signature headers of r against its raw body and
+// checks the skill ID and timestamp of the decoded envelope. Request IDs are
+// remembered while their timestamp is valid, so a replayed request fails.
+func (v *Verifier) Verify(r *http.Request, body []byte, env *RequestEnvelope) error {
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if v.ApplicationID != "" && env.ApplicationID() != v.ApplicationID {
+		return fmt.Errorf("unexpected application id %q", env.ApplicationID())
+	}
+	if d := now().Sub(env.Request.Timestamp); d > MaxClockSkew || d < -MaxClockSkew {
+		return fmt.Errorf("request timestamp %s outside allowed window", env.Request.Timestamp)
+	}
+
+	chainURL := r.Header.Get("SignatureCertChainUrl")
+	if err := ValidateCertURL(chainURL); err != nil {
+		return err
+	}
+	cert, err := v.certificate(chainURL, now())
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Header.Get("Signature-256"))
+	if err != nil || len(sig) == 0 {
+		return errors.New("missing or malformed Signature-256 header")
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("signing certificate does not hold an RSA key")
+	}
+	digest := sha256.Sum256(body)
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		return fmt.Errorf("signature mismatch: %w", err)
+	}
+
+	if env.Request.RequestID == "" {
+		return errors.New("missing request id")
+	}
+	v.mu.Lock()
+	if v.requests == nil {
+		v.requests = client.NewNonceCache()
+	}
+	v.mu.Unlock()
+	if v.requests.Seen(env.Request.RequestID, now(), env.Request.Timestamp.Add(MaxClockSkew)) {
+		return fmt.Errorf("request %s already received", env.Request.RequestID)
+	}
+	return nil
+}
+
+// ValidateCertURL checks that the signature chain URL points at Amazon's
+// certificate bucket as required by the Alexa documentation
+func ValidateCertURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return errors.New("missing or malformed SignatureCertChainUrl")
+	}
+	if !strings.EqualFold(u.Scheme, "https") {
+		return errors.New("certificate URL must use https")
+	}
+	if !strings.EqualFold(u.Hostname(), "s3.amazonaws.com") {
+		return errors.New("certificate URL must be hosted on s3.amazonaws.com")
+	}
+	if p := u.Port(); p != "" && p != "443" {
+		return errors.New("certificate URL must use port 443")
+	}
+	if !strings.HasPrefix(path.Clean(u.Path), "/echo.api/") {
+		return errors.New("certificate URL path must start with /echo.api/")
+	}
+	return nil
+}
+
+// certificate returns the verified leaf certificate for the chain URL,
+// downloading and caching it on first use
+func (v *Verifier) certificate(chainURL string, now time.Time) (*x509.Certificate, error) {
+	v.mu.Lock()
+	cert := v.certs[chainURL]
+	v.mu.Unlock()
+
+	if cert == nil {
+		fetch := v.Fetch
+		if fetch == nil {
+			fetch = httpFetch
+		}
+		data, err := fetch(chainURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download certificate chain: %w", err)
+		}
+		var chain []*x509.Certificate
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			c, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate: %w", err)
+			}
+			chain = append(chain, c)
+		}
+		if len(chain) == 0 {
+			return nil, errors.New("certificate chain is empty")
+		}
+		cert = chain[0]
+		intermediates := x509.NewCertPool()
+		for _, c := range chain[1:] {
+			intermediates.AddCert(c)
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{
+			DNSName:       certSubject,
+			Roots:         v.Roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+		}); err != nil {
+			return nil, fmt.Errorf("untrusted signing certificate: %w", err)
+		}
+
+		v.mu.Lock()
+		if v.certs == nil {
+			v.certs = make(map[string]*x509.Certificate)
+		}
+		v.certs[chainURL] = cert
+		v.mu.Unlock()
+	}
+
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, errors.New("signing certificate is expired or not yet valid")
+	}
+	return cert, nil
+}
+
+func httpFetch(u string) ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
@@ -0,0 +1,138 @@
+package alexa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateCertURL(t *testing.T) {
+	valid := []string{
+		"https://s3.amazonaws.com/echo.api/echo-api-cert.pem",
+		"https://s3.amazonaws.com:443/echo.api/echo-api-cert.pem",
+		"https://s3.amazonaws.com/echo.api/../echo.api/echo-api-cert.pem",
+		"HTTPS://S3.AMAZONAWS.COM/echo.api/echo-api-cert.pem",
+	}
+	invalid := []string{
+		"",
+		"http://s3.amazonaws.com/echo.api/echo-api-cert.pem",
+		"https://notamazon.com/echo.api/echo-api-cert.pem",
+		"https://s3.amazonaws.com/EcHo.aPi/echo-api-cert.pem",
+		"https://s3.amazonaws.com/invalid.path/echo-api-cert.pem",
+		"https://s3.amazonaws.com:563/echo.api/echo-api-cert.pem",
+	}
+	for _, u := range valid {
+		if err := ValidateCertURL(u); err != nil {
+			t.Errorf("ValidateCertURL(%q) = %v, want nil", u, err)
+		}
+	}
+	for _, u := range invalid {
+		if err := ValidateCertURL(u); err == nil {
+			t.Errorf("ValidateCertURL(%q) = nil, want error", u)
+		}
+	}
+}
+
+// testChain creates a root CA and a leaf certificate for echo-api.amazon.com
+func testChain(t *testing.T) (*x509.CertPool, []byte, *rsa.PrivateKey) {
+	t.Helper()
+	rootKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+
+	leafKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: certSubject},
+		DNSNames:     []string{certSubject},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, root, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(root)
+	return pool, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}), leafKey
+}
+
+func TestVerify(t *testing.T) {
+	roots, chain, key := testChain(t)
+	const certURL = "https://s3.amazonaws.com/echo.api/echo-api-cert.pem"
+	v := &Verifier{
+		ApplicationID: "amzn1.ask.skill.test",
+		Roots:         roots,
+		Fetch:         func(string) ([]byte, error) { return chain, nil },
+	}
+
+	var env RequestEnvelope
+	env.Context.System.Application.ApplicationID = "amzn1.ask.skill.test"
+	env.Request.Type = LaunchRequest
+	env.Request.RequestID = "amzn1.echo-api.request.1"
+	env.Request.Timestamp = time.Now().UTC()
+	body, _ := json.Marshal(env)
+
+	sign := func(b []byte) string {
+		digest := sha256.Sum256(b)
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+	request := func(b []byte, sig string) error {
+		r := httptest.NewRequest("POST", "/alexa", bytes.NewReader(b))
+		r.Header.Set("SignatureCertChainUrl", certURL)
+		r.Header.Set("Signature-256", sig)
+		var e RequestEnvelope
+		json.Unmarshal(b, &e)
+		return v.Verify(r, b, &e)
+	}
+
+	if err := request(body, sign(body)); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if err := request(body, sign(body)); err == nil {
+		t.Error("expected replayed request to fail verification")
+	}
+
+	tampered := bytes.Replace(body, []byte(LaunchRequest), []byte(IntentRequest), 1)
+	if err := request(tampered, sign(body)); err == nil {
+		t.Error("expected tampered body to fail verification")
+	}
+
+	env.Request.Timestamp = time.Now().Add(-5 * time.Minute)
+	stale, _ := json.Marshal(env)
+	if err := request(stale, sign(stale)); err == nil {
+		t.Error("expected stale timestamp to fail verification")
+	}
+
+	env.Request.Timestamp = time.Now()
+	env.Context.System.Application.ApplicationID = "amzn1.ask.skill.other"
+	other, _ := json.Marshal(env)
+	if err := request(other, sign(other)); err == nil {
+		t.Error("expected wrong application id to fail verification")
+	}
+}
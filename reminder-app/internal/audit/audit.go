@@ -0,0 +1,136 @@
+// Package audit keeps a change log of reminders and other entities: who
+// did what, when, and which fields changed. Entries are stored as storage
+// records so every backend supports them without schema changes.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of audit entries
+const Kind = "audit"
+
+// Actions
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionComplete = "complete"
+)
+
+// Change is one field whose value changed. Old and New are JSON values;
+// null stands for absent.
+type Change struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// Entry is one recorded change to an entity
+type Entry struct {
+	ID       string `json:"id"`
+	Entity   string `json:"entity"` // e.g. "reminder"
+	EntityID string `json:"entity_id"`
+	FamilyID string `json:"family_id,omitempty"`
+	Actor    string `json:"actor,omitempty"`
+	// Impersonator is the admin who made the change on Actor's behalf, if
+	// any
+	Impersonator string    `json:"impersonator,omitempty"`
+	Action       string    `json:"action"`
+	At           time.Time `json:"at"`
+	Changes      []Change  `json:"changes,omitempty"`
+}
+
+// Snapshot captures the JSON form of v for a later Diff. Take it before
+// mutating an entity, since storage may hand out shared pointers.
+func Snapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Diff compares two snapshots of a JSON object field by field. Fields listed
+// in ignore, such as bookkeeping counters, are skipped.
+func Diff(before, after json.RawMessage, ignore ...string) []Change {
+	var old, cur map[string]json.RawMessage
+	if len(before) > 0 {
+		json.Unmarshal(before, &old)
+	}
+	if len(after) > 0 {
+		json.Unmarshal(after, &cur)
+	}
+	skip := make(map[string]bool, len(ignore))
+	for _, f := range ignore {
+		skip[f] = true
+	}
+
+	fields := make(map[string]bool)
+	for f := range old {
+		fields[f] = true
+	}
+	for f := range cur {
+		fields[f] = true
+	}
+	var changes []Change
+	for f := range fields {
+		if skip[f] || jsonEqual(old[f], cur[f]) {
+			continue
+		}
+		changes = append(changes, Change{Field: f, Old: old[f], New: cur[f]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// jsonEqual compares JSON values, treating absent and null alike
+func jsonEqual(a, b json.RawMessage) bool {
+	if isNull(a) || isNull(b) {
+		return isNull(a) && isNull(b)
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+func isNull(v json.RawMessage) bool {
+	return len(v) == 0 || string(v) == "null"
+}
+
+// Record stores an entry, filling in its ID and time when unset
+func Record(s storage.Storage, e Entry) error {
+	if e.ID == "" {
+		e.ID = storage.NewRecordID("aud")
+	}
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	rec := storage.Record{Kind: Kind, ID: e.ID, FamilyID: e.FamilyID, Ref: e.EntityID, CreatedAt: e.At}
+	return storage.PutJSON(s, rec, e)
+}
+
+// History returns the entries of one entity, oldest first
+func History(s storage.Storage, entity, entityID string) ([]*Entry, error) {
+	entries, err := storage.ListJSON[Entry](s, storage.RecordQuery{Kind: Kind, Ref: entityID})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.Entity == entity {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
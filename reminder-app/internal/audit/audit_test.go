@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+func TestDiff(t *testing.T) {
+	before := Snapshot(map[string]any{"title": "Trash", "due_date": "2025-01-01T08:00:00Z", "version": 1, "tags": []string{"a"}})
+	after := Snapshot(map[string]any{"title": "Trash", "due_date": "2025-01-02T08:00:00Z", "version": 2, "tags": []string{"a"}, "notes": "x"})
+	changes := Diff(before, after, "version")
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	if changes[0].Field != "due_date" || string(changes[0].Old) != `"2025-01-01T08:00:00Z"` || string(changes[0].New) != `"2025-01-02T08:00:00Z"` {
+		t.Errorf("unexpected due date change: %+v", changes[0])
+	}
+	if changes[1].Field != "notes" || changes[1].Old != nil {
+		t.Errorf("unexpected added field: %+v", changes[1])
+	}
+
+	created := Diff(nil, Snapshot(map[string]any{"title": "New", "description": nil}))
+	if len(created) != 1 || created[0].Field != "title" {
+		t.Errorf("expected only non-null fields on create, got %+v", created)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	at := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	for i, e := range []Entry{
+		{Entity: "reminder", EntityID: "rem1", Action: ActionCreate, Actor: "Alice"},
+		{Entity: "reminder", EntityID: "rem2", Action: ActionCreate},
+		{Entity: "reminder", EntityID: "rem1", Action: ActionUpdate, Actor: "Bob"},
+	} {
+		e.At = at.Add(time.Duration(i) * time.Minute)
+		if err := Record(s, e); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	entries, err := History(s, "reminder", "rem1")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != ActionCreate || entries[1].Actor != "Bob" {
+		t.Errorf("unexpected history: %+v", entries)
+	}
+}
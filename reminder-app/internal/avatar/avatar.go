@@ -0,0 +1,59 @@
+// Package avatar turns uploaded pictures into the square PNG avatars shown
+// for families and their members
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register the GIF decoder
+	_ "image/jpeg" // register the JPEG decoder
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// Size is the width and height of avatars in pixels
+const Size = 256
+
+// ContentType is the media type of avatars
+const ContentType = "image/png"
+
+// maxPixels bounds the dimensions of an upload, so a small file can't
+// decode into an enormous image
+const maxPixels = 40_000_000
+
+// ErrTooLarge is returned for pictures with more than maxPixels pixels
+var ErrTooLarge = errors.New("picture dimensions are too large")
+
+// Make decodes a GIF, JPEG, PNG or WebP picture, crops it to a centered
+// square and scales it to Size×Size. It returns the avatar encoded as PNG.
+func Make(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	cfg, format, err := image.DecodeConfig(io.TeeReader(r, &buf))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported picture: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(io.MultiReader(&buf, r))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s picture: %w", format, err)
+	}
+
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+	dst := image.NewRGBA(image.Rect(0, 0, Size, Size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, dst); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
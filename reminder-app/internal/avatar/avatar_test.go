@@ -0,0 +1,56 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	// A wide picture, red on the left third, blue in the middle and green on
+	// the right, so the crop is visible
+	src := image.NewRGBA(image.Rect(0, 0, 900, 300))
+	for x := 0; x < 900; x++ {
+		c := color.RGBA{0, 0, 255, 255}
+		if x < 300 {
+			c = color.RGBA{255, 0, 0, 255}
+		} else if x >= 600 {
+			c = color.RGBA{0, 255, 0, 255}
+		}
+		for y := 0; y < 300; y++ {
+			src.Set(x, y, c)
+		}
+	}
+	var in bytes.Buffer
+	jpeg.Encode(&in, src, &jpeg.Options{Quality: 95})
+
+	data, err := Make(&in)
+	if err != nil {
+		t.Fatalf("Make failed: %v", err)
+	}
+	out, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("expected a PNG: %v", err)
+	}
+	if b := out.Bounds(); b.Dx() != Size || b.Dy() != Size {
+		t.Errorf("expected %dx%d, got %v", Size, Size, b)
+	}
+	if r, g, b, _ := out.At(Size/2, Size/2).RGBA(); b>>8 < 200 || r>>8 > 50 || g>>8 > 50 {
+		t.Errorf("expected the blue middle to be kept, got %v", out.At(Size/2, Size/2))
+	}
+}
+
+func TestMakeRejects(t *testing.T) {
+	if _, err := Make(bytes.NewReader([]byte("not a picture"))); err == nil {
+		t.Error("expected garbage to be rejected")
+	}
+	var huge bytes.Buffer
+	png.Encode(&huge, image.NewGray(image.Rect(0, 0, 10000, 5000)))
+	if _, err := Make(&huge); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package breaker implements circuit breakers, which stop calling a
+// dependency that keeps failing. While a breaker is open, calls fail at once
+// with ErrOpen instead of piling up behind timeouts. After a cooldown one
+// probe call is let through: its success closes the breaker, its failure
+// opens it for another cooldown.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned for calls refused by an open breaker
+var ErrOpen = errors.New("circuit breaker is open")
+
+// DefaultThreshold and DefaultCooldown are the settings of New
+const (
+	DefaultThreshold = 5
+	DefaultCooldown  = 30 * time.Second
+)
+
+// State is the state of a breaker
+type State int
+
+const (
+	// Closed lets every call through
+	Closed State = iota
+	// Open refuses calls until the cooldown has passed
+	Open
+	// HalfOpen lets a single probe call through
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker guards calls to one dependency
+type Breaker struct {
+	Name string
+	// Threshold is how many failures in a row open the breaker
+	Threshold int
+	// Cooldown is how long the breaker stays open before probing
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// New creates a closed breaker with the default threshold and cooldown
+func New(name string) *Breaker {
+	return &Breaker{Name: name, Threshold: DefaultThreshold, Cooldown: DefaultCooldown, now: time.Now}
+}
+
+// Allow reports whether a call may go ahead, returning an error wrapping
+// ErrOpen if not. Every allowed call must be followed by Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.Cooldown {
+			return fmt.Errorf("%s: %w", b.Name, ErrOpen)
+		}
+		b.state = HalfOpen
+	case HalfOpen:
+	default:
+		return nil
+	}
+	if b.probing {
+		return fmt.Errorf("%s: %w", b.Name, ErrOpen)
+	}
+	b.probing = true
+	return nil
+}
+
+// Record reports the outcome of an allowed call
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case HalfOpen:
+		b.probing = false
+		if failed {
+			b.state, b.openedAt = Open, b.now()
+			log.Printf("circuit breaker %s: probe failed, open for %s", b.Name, b.Cooldown)
+		} else {
+			b.state, b.failures = Closed, 0
+			log.Printf("circuit breaker %s: closed", b.Name)
+		}
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.Threshold {
+			b.state, b.openedAt = Open, b.now()
+			log.Printf("circuit breaker %s: opened after %d failures, open for %s", b.Name, b.failures, b.Cooldown)
+		}
+	}
+}
+
+// Do runs fn if the breaker allows it, recording a failure when failed
+// reports true for its error
+func (b *Breaker) Do(fn func() error, failed func(error) bool) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.Record(err != nil && failed(err))
+	return err
+}
+
+// State returns the current state. An open breaker whose cooldown has
+// passed is reported as half-open, as the next call will probe.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.Cooldown {
+		return HalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,69 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := New("test")
+	b.Threshold, b.Cooldown = 3, time.Minute
+	b.now = func() time.Time { return now }
+	down := errors.New("down")
+	fail := func() error { return down }
+	ok := func() error { return nil }
+	always := func(error) bool { return true }
+
+	// Failures below the threshold, or broken up by a success, keep it closed
+	for _, fn := range []func() error{fail, fail, ok, fail, fail} {
+		b.Do(fn, always)
+	}
+	if b.State() != Closed {
+		t.Fatalf("state = %s, want closed", b.State())
+	}
+	// Errors that aren't failures show the dependency answered
+	b.Do(fail, func(error) bool { return false })
+	b.Do(fail, always)
+	b.Do(fail, always)
+	if b.State() != Closed {
+		t.Fatalf("state = %s, want closed", b.State())
+	}
+
+	if err := b.Do(fail, always); err != down {
+		t.Fatalf("Do = %v, want %v", err, down)
+	}
+	if b.State() != Open {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+	called := false
+	if err := b.Do(func() error { called = true; return nil }, always); !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("open breaker: Do = %v, called %v", err, called)
+	}
+
+	// After the cooldown a single probe goes through; its failure reopens
+	now = now.Add(time.Minute)
+	if b.State() != HalfOpen {
+		t.Fatalf("state = %s, want half-open", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("second call during probe allowed: %v", err)
+	}
+	b.Record(true)
+	if b.State() != Open {
+		t.Fatalf("state = %s, want open after failed probe", b.State())
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	if err := b.Do(ok, always); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("state = %s, want closed after successful probe", b.State())
+	}
+}
@@ -0,0 +1,109 @@
+// Package condition evaluates the conditions that gate reminder
+// notifications on external data. Each data source (weather, air quality,
+// ...) is a Provider; a Registry routes a reminder.Condition to the provider
+// it names.
+package condition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// Provider reports a metric of some external data source
+type Provider interface {
+	// Measure returns the value of metric at location over [from, to]. How
+	// the values in the window are combined, such as summing rainfall, is up
+	// to the provider; when from equals to it returns the value at that time.
+	Measure(ctx context.Context, lat, lon float64, metric string, from, to time.Time) (float64, error)
+}
+
+// Validator is implemented by providers that can check a metric up front, so
+// conditions the provider can't evaluate are rejected when they are saved
+type Validator interface {
+	Validate(metric string) error
+}
+
+// ErrUnknownProvider is returned when no provider is registered for a
+// condition
+var ErrUnknownProvider = errors.New("unknown condition provider")
+
+// Registry routes conditions to the provider registered under their name
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewRegistry creates a registry with no providers
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register makes a provider available under the given name
+func (r *Registry) Register(name string, p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = p
+}
+
+// Types returns the registered provider names in sorted order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// provider returns the provider a condition names
+func (r *Registry) provider(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Validate checks that c is complete, that its provider is registered and,
+// when the provider supports it, that the metric is known
+func (r *Registry) Validate(c reminder.Condition) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	p, err := r.provider(c.Provider)
+	if err != nil {
+		return err
+	}
+	if v, ok := p.(Validator); ok {
+		return v.Validate(c.Metric)
+	}
+	return nil
+}
+
+// Evaluate reports whether c holds for an occurrence at the given time,
+// measuring over the condition's window before it
+func (r *Registry) Evaluate(ctx context.Context, c reminder.Condition, at time.Time) (bool, error) {
+	p, err := r.provider(c.Provider)
+	if err != nil {
+		return false, err
+	}
+	lat, lon, err := reminder.ParseLocation(c.Location)
+	if err != nil {
+		return false, err
+	}
+	v, err := p.Measure(ctx, lat, lon, c.Metric, at.Add(-c.Lookback()), at)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", c.Provider, err)
+	}
+	return c.Holds(v), nil
+}
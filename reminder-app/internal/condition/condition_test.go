@@ -0,0 +1,118 @@
+package condition
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// openMeteoServer answers with 1 mm of rain at 06:00 and 2 mm at 18:00 on
+// 2024-06-01 and none otherwise
+func openMeteoServer(t *testing.T, gotQuery *string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotQuery = r.URL.RawQuery
+		w.Write([]byte(`{"hourly": {
+			"time": ["2024-06-01T06:00", "2024-06-01T18:00", "2024-06-02T06:00", "2024-06-02T07:00"],
+			"precipitation": [1.0, 2.0, 0.0, null]
+		}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenMeteoMeasure(t *testing.T) {
+	var query string
+	p := NewWeatherProvider(openMeteoServer(t, &query).URL)
+	to := time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	sum, err := p.Measure(context.Background(), 52.52, 13.41, "precipitation", to.Add(-48*time.Hour), to)
+	if err != nil {
+		t.Fatalf("Measure failed: %v", err)
+	}
+	if sum != 3 {
+		t.Errorf("expected 3 mm over 48h, got %v", sum)
+	}
+	if query != "end_date=2024-06-02&hourly=precipitation&latitude=52.52&longitude=13.41&start_date=2024-05-31&timezone=GMT" {
+		t.Errorf("unexpected query: %s", query)
+	}
+
+	// 18:00 is outside (18:00, 09:00 next day]
+	sum, _ = p.Measure(context.Background(), 52.52, 13.41, "precipitation", to.Add(-15*time.Hour), to)
+	if sum != 0 {
+		t.Errorf("expected no rain in the last 15h, got %v", sum)
+	}
+
+	// Without a window the latest non-null value counts
+	at := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	latest, _ := p.Measure(context.Background(), 52.52, 13.41, "precipitation", at, at)
+	if latest != 2 {
+		t.Errorf("expected the 18:00 value, got %v", latest)
+	}
+
+	if _, err := p.Measure(context.Background(), 52.52, 13.41, "sunshine", at, at); err == nil {
+		t.Error("expected an unknown metric to fail")
+	}
+}
+
+func TestOpenMeteoError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": true, "reason": "Latitude must be in range"}`, http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	at := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	if _, err := NewAirQualityProvider(srv.URL).Measure(context.Background(), 0, 0, "us_aqi", at, at); err == nil {
+		t.Error("expected an error response to fail")
+	}
+}
+
+type fixedProvider float64
+
+func (f fixedProvider) Measure(ctx context.Context, lat, lon float64, metric string, from, to time.Time) (float64, error) {
+	return float64(f), nil
+}
+
+func TestRegistry(t *testing.T) {
+	var query string
+	r := NewRegistry()
+	r.Register("weather", NewWeatherProvider(openMeteoServer(t, &query).URL))
+	r.Register("fixed", fixedProvider(42))
+
+	noRain := reminder.Condition{Provider: "weather", Location: "52.52,13.41", Metric: "precipitation", Operator: "lte", Value: 0, Window: "48h"}
+	if err := r.Validate(noRain); err != nil {
+		t.Errorf("expected a valid condition, got %v", err)
+	}
+	ok, err := r.Evaluate(context.Background(), noRain, time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC))
+	if err != nil || ok {
+		t.Errorf("expected the rain to fail the condition, got %v, %v", ok, err)
+	}
+	ok, err = r.Evaluate(context.Background(), noRain, time.Date(2024, 6, 4, 9, 0, 0, 0, time.UTC))
+	if err != nil || !ok {
+		t.Errorf("expected two dry days to meet the condition, got %v, %v", ok, err)
+	}
+
+	invalid := noRain
+	invalid.Metric = "sunshine"
+	if err := r.Validate(invalid); err == nil {
+		t.Error("expected an unknown metric to be rejected")
+	}
+	invalid = noRain
+	invalid.Provider = "pollen"
+	if err := r.Validate(invalid); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+
+	// Providers without a Validator accept any metric
+	hot := reminder.Condition{Provider: "fixed", Location: "0,0", Metric: "anything", Operator: "gt", Value: 40}
+	if err := r.Validate(hot); err != nil {
+		t.Errorf("expected a valid condition, got %v", err)
+	}
+	if ok, _ := r.Evaluate(context.Background(), hot, time.Now()); !ok {
+		t.Error("expected 42 > 40 to hold")
+	}
+}
@@ -0,0 +1,171 @@
+package condition
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default Open-Meteo endpoints, which need no API key
+const (
+	DefaultWeatherAPI    = "https://api.open-meteo.com/v1/forecast"
+	DefaultAirQualityAPI = "https://air-quality-api.open-meteo.com/v1/air-quality"
+)
+
+// Aggregate says how the hourly values in a condition's window are combined
+type Aggregate int
+
+const (
+	// Sum adds the values up, for amounts such as precipitation
+	Sum Aggregate = iota
+	// Max takes the highest value, for levels such as an air quality index
+	Max
+)
+
+// WeatherMetrics are the hourly Open-Meteo weather variables conditions may
+// use. Amounts are in mm (snowfall in cm), temperatures in °C and wind speeds
+// in km/h.
+var WeatherMetrics = map[string]Aggregate{
+	"precipitation":        Sum,
+	"rain":                 Sum,
+	"snowfall":             Sum,
+	"temperature_2m":       Max,
+	"relative_humidity_2m": Max,
+	"wind_speed_10m":       Max,
+}
+
+// AirQualityMetrics are the hourly Open-Meteo air quality variables
+// conditions may use
+var AirQualityMetrics = map[string]Aggregate{
+	"us_aqi":       Max,
+	"european_aqi": Max,
+	"pm2_5":        Max,
+	"pm10":         Max,
+	"ozone":        Max,
+}
+
+// OpenMeteo reads hourly data from an Open-Meteo compatible API
+type OpenMeteo struct {
+	// Endpoint is the URL of the forecast or air quality API
+	Endpoint string
+	// Metrics are the variables the endpoint offers and how each is
+	// combined over a window
+	Metrics map[string]Aggregate
+	Client  *http.Client
+}
+
+// NewWeatherProvider creates a provider of WeatherMetrics from endpoint,
+// such as DefaultWeatherAPI
+func NewWeatherProvider(endpoint string) *OpenMeteo {
+	return &OpenMeteo{Endpoint: endpoint, Metrics: WeatherMetrics, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// NewAirQualityProvider creates a provider of AirQualityMetrics from
+// endpoint, such as DefaultAirQualityAPI
+func NewAirQualityProvider(endpoint string) *OpenMeteo {
+	return &OpenMeteo{Endpoint: endpoint, Metrics: AirQualityMetrics, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Validate checks that the endpoint offers metric
+func (o *OpenMeteo) Validate(metric string) error {
+	if _, ok := o.Metrics[metric]; !ok {
+		names := make([]string, 0, len(o.Metrics))
+		for name := range o.Metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown metric %q; supported: %s", metric, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// hourlyTimeFormat is the format of Open-Meteo's hourly timestamps
+const hourlyTimeFormat = "2006-01-02T15:04"
+
+// Measure combines the hourly values stamped in (from, to]. Each value
+// covers the hour up to its timestamp. Without any in the window, such as
+// when from equals to, the latest value at or before to is returned.
+func (o *OpenMeteo) Measure(ctx context.Context, lat, lon float64, metric string, from, to time.Time) (float64, error) {
+	if err := o.Validate(metric); err != nil {
+		return 0, err
+	}
+	from, to = from.UTC(), to.UTC()
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(lat, 'f', -1, 64))
+	q.Set("longitude", strconv.FormatFloat(lon, 'f', -1, 64))
+	q.Set("hourly", metric)
+	q.Set("timezone", "GMT")
+	// The hour ending at from's hour may be needed as the latest value
+	q.Set("start_date", from.Add(-time.Hour).Format("2006-01-02"))
+	q.Set("end_date", to.Format("2006-01-02"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.Endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var data struct {
+		Hourly map[string]json.RawMessage `json:"hourly"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, fmt.Errorf("invalid response: %w", err)
+	}
+	var times []string
+	var values []*float64
+	if err := json.Unmarshal(data.Hourly["time"], &times); err != nil {
+		return 0, fmt.Errorf("invalid hourly times: %w", err)
+	}
+	if err := json.Unmarshal(data.Hourly[metric], &values); err != nil {
+		return 0, fmt.Errorf("invalid hourly %s: %w", metric, err)
+	}
+
+	var window []float64
+	var latest *float64
+	for i, ts := range times {
+		t, err := time.Parse(hourlyTimeFormat, ts)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hourly time %q: %w", ts, err)
+		}
+		if i >= len(values) || values[i] == nil || t.After(to) {
+			continue
+		}
+		latest = values[i]
+		if t.After(from) {
+			window = append(window, *values[i])
+		}
+	}
+	if len(window) == 0 {
+		if latest == nil {
+			return 0, fmt.Errorf("no %s data up to %s", metric, to.Format(time.RFC3339))
+		}
+		return *latest, nil
+	}
+	result := window[0]
+	for _, v := range window[1:] {
+		switch o.Metrics[metric] {
+		case Sum:
+			result += v
+		case Max:
+			result = max(result, v)
+		}
+	}
+	return result, nil
+}
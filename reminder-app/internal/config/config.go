@@ -0,0 +1,138 @@
+// Package config reads flag values from a file, so settings can be changed
+// on a running server. The file holds one flag per line as name=value,
+// named like on the command line without the dash; blank lines and lines
+// starting with # are ignored. Flags given on the command line take
+// precedence over the file.
+package config
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// File applies a config file to a flag set
+type File struct {
+	Path  string
+	flags *flag.FlagSet
+	// cmdline holds the flags set on the command line
+	cmdline map[string]bool
+	// applied holds the values last taken from the file
+	applied map[string]string
+}
+
+// New creates a File applying path to fs, which must already be parsed
+func New(path string, fs *flag.FlagSet) *File {
+	f := &File{Path: path, flags: fs, cmdline: make(map[string]bool), applied: make(map[string]string)}
+	fs.Visit(func(fl *flag.Flag) {
+		f.cmdline[fl.Name] = true
+	})
+	return f
+}
+
+// Read parses a config file into flag names and values
+func Read(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected name=value", path, n)
+		}
+		name = strings.TrimPrefix(strings.TrimSpace(name), "-")
+		values[name] = strings.TrimSpace(value)
+	}
+	return values, scanner.Err()
+}
+
+// Load reads the file and sets the flags it names, except those set on the
+// command line. Flags removed from the file since the last Load go back to
+// their defaults. It returns the names of the flags whose value changed.
+// Nothing is changed if the file names an unknown flag or holds a value the
+// flag refuses.
+func (f *File) Load() ([]string, error) {
+	changed, _, err := f.load(nil)
+	return changed, err
+}
+
+// Reload is Load for a running server: only the flags in reloadable are
+// set. The others the file would change are returned as pending, as they
+// only take effect after a restart.
+func (f *File) Reload(reloadable map[string]bool) (changed, pending []string, err error) {
+	return f.load(reloadable)
+}
+
+func (f *File) load(reloadable map[string]bool) (changed, pending []string, err error) {
+	values, err := Read(f.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name := range values {
+		if f.flags.Lookup(name) == nil {
+			return nil, nil, fmt.Errorf("%s: unknown flag %q", f.Path, name)
+		}
+	}
+
+	want := make(map[string]string)
+	for name, value := range values {
+		if !f.cmdline[name] {
+			want[name] = value
+		}
+	}
+	for name := range f.applied {
+		if _, ok := want[name]; !ok {
+			want[name] = f.flags.Lookup(name).DefValue
+		}
+	}
+
+	previous := make(map[string]string)
+	var setErr error
+	f.flags.VisitAll(func(fl *flag.Flag) {
+		value, ok := want[fl.Name]
+		if !ok || setErr != nil {
+			return
+		}
+		old := fl.Value.String()
+		if reloadable != nil && !reloadable[fl.Name] {
+			if value != old {
+				pending = append(pending, fl.Name)
+			}
+			return
+		}
+		if err := fl.Value.Set(value); err != nil {
+			setErr = fmt.Errorf("%s: invalid value %q for flag %s: %w", f.Path, value, fl.Name, err)
+			return
+		}
+		if fl.Value.String() != old {
+			previous[fl.Name] = old
+			changed = append(changed, fl.Name)
+		}
+	})
+	if setErr != nil {
+		for name, old := range previous {
+			f.flags.Set(name, old)
+		}
+		return nil, nil, setErr
+	}
+
+	for name, value := range want {
+		if reloadable == nil || reloadable[name] {
+			delete(f.applied, name)
+			if _, ok := values[name]; ok {
+				f.applied[name] = value
+			}
+		}
+	}
+	return changed, pending, nil
+}
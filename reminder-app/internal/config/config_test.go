@@ -0,0 +1,74 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reminder-app.conf")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	limit := fs.Int("rate-limit", 0, "")
+	server := fs.String("server", "default", "")
+	strict := fs.Bool("strict", false, "")
+	storage := fs.String("storage", "file", "")
+	if err := fs.Parse([]string{"-server=cmdline"}); err != nil {
+		t.Fatal(err)
+	}
+
+	write("# settings\nrate-limit = 60\n-strict=true\nserver=file\nstorage=sqlite\n")
+	f := New(path, fs)
+	changed, err := f.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	sort.Strings(changed)
+	if want := []string{"rate-limit", "storage", "strict"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if *limit != 60 || !*strict || *storage != "sqlite" {
+		t.Errorf("flags after Load: rate-limit=%d strict=%v storage=%s", *limit, *strict, *storage)
+	}
+	if *server != "cmdline" {
+		t.Errorf("command line flag overridden by file: %s", *server)
+	}
+
+	// Only reloadable flags change; removed ones go back to their defaults
+	write("rate-limit=120\nstorage=postgres\n")
+	changed, pending, err := f.Reload(map[string]bool{"rate-limit": true, "strict": true})
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	sort.Strings(changed)
+	if want := []string{"rate-limit", "strict"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"storage"}; !reflect.DeepEqual(pending, want) {
+		t.Errorf("pending = %v, want %v", pending, want)
+	}
+	if *limit != 120 || *strict || *storage != "sqlite" {
+		t.Errorf("flags after Reload: rate-limit=%d strict=%v storage=%s", *limit, *strict, *storage)
+	}
+
+	// A bad file changes nothing
+	for _, content := range []string{"rate-limit=10\nstrict=maybe\n", "nope=1\n", "rate-limit\n"} {
+		write(content)
+		if _, _, err := f.Reload(map[string]bool{"rate-limit": true, "strict": true}); err == nil {
+			t.Errorf("Reload of %q succeeded", content)
+		}
+		if *limit != 120 || *strict {
+			t.Errorf("flags changed by failed reload of %q: rate-limit=%d strict=%v", content, *limit, *strict)
+		}
+	}
+}
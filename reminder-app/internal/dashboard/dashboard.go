@@ -0,0 +1,138 @@
+// Package dashboard renders a family's day as a grayscale image for e-ink
+// kitchen displays and kiosks that can't run JavaScript. The image is plain
+// black on white with no anti-aliasing-dependent detail, so it survives
+// being reduced to one bit per pixel.
+package dashboard
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"reminder-app/internal/agenda"
+)
+
+// Size limits for rendered images
+const (
+	MinSize = 100
+	MaxSize = 4000
+)
+
+var (
+	regular, bold *opentype.Font
+	black         = color.Gray{Y: 0}
+	white         = color.Gray{Y: 255}
+)
+
+func init() {
+	var err error
+	if regular, err = opentype.Parse(goregular.TTF); err != nil {
+		panic(err)
+	}
+	if bold, err = opentype.Parse(gobold.TTF); err != nil {
+		panic(err)
+	}
+}
+
+// Render draws the title and the day's items into a width x height image.
+// Times are shown in loc. Items that don't fit are summarized as "+N more".
+func Render(title string, day time.Time, items []agenda.Item, loc *time.Location, width, height int) (*image.Gray, error) {
+	if width < MinSize || height < MinSize || width > MaxSize || height > MaxSize {
+		return nil, fmt.Errorf("width and height must be between %d and %d", MinSize, MaxSize)
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+
+	margin := width / 30
+	headSize := float64(height) / 12
+	itemSize := float64(height) / 18
+	head, err := face(bold, headSize)
+	if err != nil {
+		return nil, err
+	}
+	defer head.Close()
+	body, err := face(regular, itemSize)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	y := margin + int(headSize)
+	text(img, head, margin, y, fit(head, title, width-2*margin))
+	y += int(itemSize * 1.3)
+	text(img, body, margin, y, day.In(loc).Format("Monday, January 2"))
+	y += int(itemSize * 0.6)
+	fill(img, image.Rect(margin, y, width-margin, y+max(1, height/240)))
+
+	line := int(itemSize * 1.6)
+	box := int(itemSize * 0.8)
+	if len(items) == 0 {
+		text(img, body, margin, y+line, "Nothing due today")
+		return img, nil
+	}
+	rows := (height - margin - y) / line
+	for i, it := range items {
+		y += line
+		if i == rows-1 && len(items) > rows {
+			text(img, body, margin, y, fmt.Sprintf("+%d more", len(items)-i))
+			break
+		}
+		checkbox(img, margin, y-box, box, it.Done)
+		x := margin + box + box/2
+		label := it.At.In(loc).Format("15:04") + "  " + it.Reminder.Title
+		if it.Reminder.FamilyMember != "" {
+			label += " (" + it.Reminder.FamilyMember + ")"
+		}
+		label = fit(body, label, width-margin-x)
+		end := text(img, body, x, y, label)
+		if it.Done {
+			mid := y - box/2
+			fill(img, image.Rect(x, mid, end, mid+max(1, box/12)))
+		}
+	}
+	return img, nil
+}
+
+func face(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// text draws s with its baseline at y and returns the x it ends at
+func text(img draw.Image, f font.Face, x, y int, s string) int {
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(black), Face: f, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+	return d.Dot.X.Round()
+}
+
+// fit shortens s with an ellipsis until it is at most width pixels wide
+func fit(f font.Face, s string, width int) string {
+	if font.MeasureString(f, s).Round() <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && font.MeasureString(f, string(r)+"…").Round() > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
+
+func fill(img draw.Image, r image.Rectangle) {
+	draw.Draw(img, r, image.NewUniform(black), image.Point{}, draw.Src)
+}
+
+// checkbox draws a size x size box at x, y, filled in when checked
+func checkbox(img draw.Image, x, y, size int, checked bool) {
+	t := max(1, size/10)
+	fill(img, image.Rect(x, y, x+size, y+size))
+	if !checked {
+		draw.Draw(img, image.Rect(x+t, y+t, x+size-t, y+size-t), image.NewUniform(white), image.Point{}, draw.Src)
+	}
+}
@@ -0,0 +1,40 @@
+package dashboard
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/reminder"
+)
+
+func TestRender(t *testing.T) {
+	day := time.Date(2025, 3, 4, 7, 0, 0, 0, time.UTC)
+	var items []agenda.Item
+	for i := 0; i < 20; i++ {
+		r := reminder.NewReminder(fmt.Sprintf("r%d", i), "Chore with a rather long name that will not fit", "", &day, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+		items = append(items, agenda.Item{Reminder: r, At: day, Done: i%2 == 0})
+	}
+
+	img, err := Render("Smith family", day, items, time.UTC, 800, 480)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 800 || b.Dy() != 480 {
+		t.Errorf("unexpected size %v", b)
+	}
+	dark := 0
+	for _, p := range img.Pix {
+		if p < 128 {
+			dark++
+		}
+	}
+	if dark == 0 || dark > len(img.Pix)/2 {
+		t.Errorf("expected some but not mostly dark pixels, got %d of %d", dark, len(img.Pix))
+	}
+
+	if _, err := Render("x", day, nil, time.UTC, 10, 480); err == nil {
+		t.Error("expected an error for a tiny image")
+	}
+}
@@ -0,0 +1,100 @@
+// Package delivery logs the notifications sent when reminders fall due, one
+// entry per channel tried, and queues the failovers of members who rank
+// their channels: such a member is sent a due reminder on their first
+// channel that takes it and, if the occurrence isn't acknowledged in time,
+// on the next one. The log and the queue are storage records, so pending
+// failovers survive a restart.
+package delivery
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/storage"
+)
+
+// Storage record kinds
+const (
+	Kind = "notification_delivery"
+	// QueueKind holds a copy of each delivery with a failover pending
+	QueueKind = "notification_failover"
+)
+
+// Delivery statuses
+const (
+	StatusSent   = "sent"
+	StatusFailed = "failed"
+)
+
+// Retention is how long deliveries stay in the log
+const Retention = 30 * 24 * time.Hour
+
+// Delivery is one notification of a reminder occurrence sent to one of a
+// member's channels
+type Delivery struct {
+	ID         string         `json:"id"`
+	FamilyID   string         `json:"family_id"`
+	ReminderID string         `json:"reminder_id"`
+	Member     string         `json:"member"`
+	Occurrence time.Time      `json:"occurrence"`
+	Channel    family.Channel `json:"channel"`
+	// Step is the position of Channel in the member's channels
+	Step   int       `json:"step"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	SentAt time.Time `json:"sent_at"`
+	// FailoverAt is when the occurrence goes on to the next channel unless
+	// it has been acknowledged by then
+	FailoverAt *time.Time `json:"failover_at,omitempty"`
+	// Acknowledged is set when the occurrence was acknowledged before
+	// FailoverAt, and FailedOver when it wasn't and went on to the next
+	// channel
+	Acknowledged bool `json:"acknowledged,omitempty"`
+	FailedOver   bool `json:"failed_over,omitempty"`
+}
+
+// Pending reports whether the delivery has a failover still to happen
+func (d *Delivery) Pending() bool {
+	return d.FailoverAt != nil && !d.Acknowledged && !d.FailedOver
+}
+
+// Save writes a delivery to the log and, while its failover is pending,
+// the queue
+func Save(s storage.Storage, d *Delivery) error {
+	expires := d.SentAt.Add(Retention)
+	rec := storage.Record{Kind: Kind, ID: d.ID, FamilyID: d.FamilyID, Ref: d.ReminderID, CreatedAt: d.SentAt, ExpiresAt: &expires}
+	if err := storage.PutJSON(s, rec, d); err != nil {
+		return err
+	}
+	if !d.Pending() {
+		if err := s.DeleteRecord(QueueKind, d.ID); err != nil && !errors.Is(err, storage.ErrRecordNotFound) {
+			return err
+		}
+		return nil
+	}
+	rec.Kind = QueueKind
+	return storage.PutJSON(s, rec, d)
+}
+
+// List returns the logged deliveries of a family, newest first. A non-empty
+// reminderID narrows them to that reminder's.
+func List(s storage.Storage, familyID, reminderID string) ([]*Delivery, error) {
+	list, err := storage.ListJSON[Delivery](s, storage.RecordQuery{Kind: Kind, FamilyID: familyID, Ref: reminderID})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(list)
+	return list, nil
+}
+
+// Due returns the queued deliveries whose failover is due at now, oldest
+// first
+func Due(s storage.Storage, now time.Time) ([]*Delivery, error) {
+	queue, err := storage.ListJSON[Delivery](s, storage.RecordQuery{Kind: QueueKind})
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(queue, func(d *Delivery) bool { return d.FailoverAt.After(now) }), nil
+}
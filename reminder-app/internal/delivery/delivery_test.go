@@ -0,0 +1,43 @@
+package delivery
+
+import (
+	"testing"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/storage"
+)
+
+func TestQueue(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	now := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	deadline := now.Add(10 * time.Minute)
+	d := &Delivery{ID: "ntf1", FamilyID: "fam1", ReminderID: "rem1", Member: "Bob", Occurrence: now,
+		Channel: family.Channel{Type: "email", Target: "bob@example.com"}, Status: StatusSent, SentAt: now, FailoverAt: &deadline}
+	if err := Save(s, d); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	_ = Save(s, &Delivery{ID: "ntf2", FamilyID: "fam1", ReminderID: "rem2", Status: StatusSent, SentAt: now})
+
+	if due, _ := Due(s, now.Add(5*time.Minute)); len(due) != 0 {
+		t.Errorf("expected nothing due before the deadline, got %+v", due)
+	}
+	due, err := Due(s, deadline)
+	if err != nil || len(due) != 1 || due[0].ID != "ntf1" {
+		t.Fatalf("expected ntf1 due at the deadline, got %+v %v", due, err)
+	}
+
+	d.FailedOver = true
+	_ = Save(s, d)
+	if due, _ := Due(s, deadline); len(due) != 0 {
+		t.Errorf("expected a delivery that failed over to leave the queue, got %+v", due)
+	}
+	list, _ := List(s, "fam1", "")
+	if len(list) != 2 {
+		t.Fatalf("expected both deliveries in the log, got %+v", list)
+	}
+	list, _ = List(s, "fam1", "rem1")
+	if len(list) != 1 || !list[0].FailedOver {
+		t.Errorf("expected the logged delivery of rem1 to have failed over, got %+v", list)
+	}
+}
@@ -0,0 +1,166 @@
+// Package digest builds a member's digest: the reminders they have coming
+// up over the next day or week and those that are overdue. The scheduler
+// emails it to members who opt in and GET /digest/preview shows it, both
+// rendered from the same templates.
+package digest
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/family"
+	"reminder-app/internal/notify"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/templates"
+)
+
+// Item is one reminder occurrence in a digest
+type Item struct {
+	ReminderID  string `json:"reminder_id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Priority    string `json:"priority,omitempty"`
+	// At is when the occurrence is due, or for overdue ones when they
+	// became overdue
+	At time.Time `json:"at"`
+}
+
+// Digest lists a member's reminders overdue at From and those due in
+// [From, To)
+type Digest struct {
+	FamilyID  string    `json:"family_id"`
+	Family    string    `json:"family"`
+	Member    string    `json:"member"`
+	Frequency string    `json:"frequency"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Overdue   []Item    `json:"overdue"`
+	Upcoming  []Item    `json:"upcoming"`
+}
+
+// Build returns the digest of member in family f at now, covering the next
+// day for family.DigestDaily and the next week for family.DigestWeekly.
+// Times are in the member's time zone. Occurrences count for whoever's turn
+// they are, those already done are left out and those postponed by a snooze
+// are listed at its end.
+func Build(s storage.Storage, f *family.Family, member, frequency string, now time.Time) (*Digest, error) {
+	days := 1
+	switch frequency {
+	case family.DigestDaily:
+	case family.DigestWeekly:
+		days = 7
+	default:
+		return nil, fmt.Errorf("unknown digest frequency: %s", frequency)
+	}
+	loc, err := f.LocationFor(member)
+	if err != nil {
+		return nil, err
+	}
+	now = now.In(loc)
+	d := &Digest{FamilyID: f.ID, Family: f.Name, Member: member, Frequency: frequency, From: now, To: now.AddDate(0, 0, days), Overdue: []Item{}, Upcoming: []Item{}}
+
+	list, err := s.QueryReminders(storage.ReminderFilter{FamilyID: f.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		item := Item{ReminderID: r.ID, Title: r.Title, Description: r.Description, Priority: r.Priority}
+		if since := r.OverdueSince(now); since != nil && r.AssigneeAt(*since) == member {
+			item.At = since.In(loc)
+			d.Overdue = append(d.Overdue, item)
+		}
+		times := r.Occurrences(d.From, d.To)
+		if until := r.SnoozedUntil; until != nil && until.After(now) {
+			times = slices.DeleteFunc(times, func(at time.Time) bool { return !at.After(*until) })
+			if until.Before(d.To) {
+				times = append([]time.Time{until.In(loc)}, times...)
+			}
+		}
+		for _, at := range times {
+			if r.AssigneeAt(at) != member || agenda.IsDone(r, at) {
+				continue
+			}
+			item.At = at.In(loc)
+			d.Upcoming = append(d.Upcoming, item)
+		}
+	}
+	byTime := func(a, b Item) int {
+		return cmp.Or(a.At.Compare(b.At), strings.Compare(a.Title, b.Title))
+	}
+	slices.SortFunc(d.Overdue, byTime)
+	slices.SortFunc(d.Upcoming, byTime)
+	return d, nil
+}
+
+// Title is the subject of the digest, e.g. "Alice's weekly digest"
+func (d *Digest) Title() string {
+	return fmt.Sprintf("%s's %s digest", d.Member, d.Frequency)
+}
+
+// Text renders the digest as plain text, for channels without HTML and the
+// text part of the email
+func (d *Digest) Text() string {
+	var sb strings.Builder
+	if err := textPage.Execute(&sb, d); err != nil {
+		return d.Title()
+	}
+	return sb.String()
+}
+
+// HTML renders the digest as an HTML email body
+func (d *Digest) HTML() (string, error) {
+	var sb strings.Builder
+	if err := htmlPage.Execute(&sb, d); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Message renders the digest as a notification carrying both the text and
+// the HTML
+func (d *Digest) Message() (notify.Message, error) {
+	html, err := d.HTML()
+	if err != nil {
+		return notify.Message{}, err
+	}
+	return notify.Message{Subject: d.Title(), Body: d.Text(), HTML: html}, nil
+}
+
+// funcs format the times of the templates like other notifications
+var funcs = template.FuncMap{
+	"due": func(t time.Time) string { return t.Format(templates.DueLayout) },
+}
+
+var textPage = texttemplate.Must(texttemplate.New("digest.txt").Funcs(texttemplate.FuncMap(funcs)).Parse(`
+{{- if .Overdue}}Overdue:
+{{range .Overdue}}- {{.Title}}, due {{due .At}}
+{{end}}
+{{end -}}
+{{if .Upcoming}}Coming up:
+{{range .Upcoming}}- {{.Title}} at {{due .At}}
+{{end}}{{else}}Nothing coming up{{if eq .Frequency "weekly"}} this week{{else}} today{{end}}.
+{{end}}`))
+
+var htmlPage = template.Must(template.New("digest.html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Member}}'s {{.Frequency}} digest</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em">
+<h1>{{.Member}}'s {{.Frequency}} digest</h1>
+<p style="color: #666">{{.Family}} &middot; {{due .From}} to {{due .To}}</p>
+{{if .Overdue}}<h2 style="color: #b00020">Overdue</h2>
+<ul style="list-style: none; padding: 0">
+{{range .Overdue}}<li style="margin: 0.5em 0">&#9888; <b>{{.Title}}</b> &middot; due {{due .At}}{{if .Description}}<br><small>{{.Description}}</small>{{end}}</li>
+{{end}}</ul>
+{{end}}<h2>Coming up</h2>
+{{if .Upcoming}}<ul style="list-style: none; padding: 0">
+{{range .Upcoming}}<li style="margin: 0.5em 0">&#9744; {{.Title}}{{if or (eq .Priority "high") (eq .Priority "urgent")}} <b>({{.Priority}})</b>{{end}} &middot; {{due .At}}{{if .Description}}<br><small>{{.Description}}</small>{{end}}</li>
+{{end}}</ul>{{else}}<p>Nothing coming up{{if eq .Frequency "weekly"}} this week{{else}} today{{end}}.</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,48 @@
+package digest
+
+import (
+	"testing"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+func TestBuild(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Bob"}, Settings: family.Settings{Timezone: "UTC"}}
+	_ = store.CreateFamily(f)
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	// Done today, so only tomorrow's occurrence is coming up
+	morning := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	daily := reminder.NewReminder("daily", "Feed the cat", "", &morning, "fam1", "Bob", reminder.RecurrencePattern{Type: "daily"})
+	done := now.Add(-time.Hour)
+	daily.CompletedAt = &done
+	_ = store.CreateReminder(daily)
+	// Snoozed past its due time, so it is listed when the snooze ends
+	due := now.Add(time.Hour)
+	until := now.Add(3 * time.Hour)
+	snoozed := reminder.NewReminder("snoozed", "Dishes", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"})
+	snoozed.SnoozedUntil = &until
+	_ = store.CreateReminder(snoozed)
+	past := now.Add(-24 * time.Hour)
+	_ = store.CreateReminder(reminder.NewReminder("overdue", "Trash", "", &past, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+
+	d, err := Build(store, f, "Bob", family.DigestDaily, now)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(d.Overdue) != 1 || d.Overdue[0].ReminderID != "overdue" || !d.Overdue[0].At.Equal(past) {
+		t.Errorf("expected Trash to be overdue, got %+v", d.Overdue)
+	}
+	if len(d.Upcoming) != 2 || d.Upcoming[0].ReminderID != "snoozed" || !d.Upcoming[0].At.Equal(until) ||
+		d.Upcoming[1].ReminderID != "daily" || !d.Upcoming[1].At.Equal(morning.AddDate(0, 0, 4)) {
+		t.Errorf("unexpected upcoming reminders: %+v", d.Upcoming)
+	}
+
+	if _, err := Build(store, f, "Bob", "hourly", now); err == nil {
+		t.Error("expected an unknown frequency to fail")
+	}
+}
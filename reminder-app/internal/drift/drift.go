@@ -0,0 +1,185 @@
+// Package drift looks for recurring reminders that are consistently
+// completed well before or after their scheduled time, such as a Saturday
+// chore that is always done on Tuesday, and proposes shifting the schedule
+// to match.
+package drift
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// MinCompletions is how many completions a reminder needs before its drift
+// is reported
+const MinCompletions = 4
+
+// MinDrift is the smallest median drift that is reported
+const MinDrift = time.Hour
+
+// window is how many of the most recent completions are analyzed
+const window = 8
+
+// consistency is the share of completions that must drift the same way as
+// the median, by at least half of MinDrift
+const consistency = 0.75
+
+// Report describes how a reminder's completions drift from its schedule and
+// the schedule that would remove the drift
+type Report struct {
+	ReminderID  string `json:"reminder_id"`
+	FamilyID    string `json:"family_id"`
+	Title       string `json:"title"`
+	Completions int    `json:"completions"` // how many completions were analyzed
+	// DriftHours is the median time from each scheduled occurrence to its
+	// completion; negative when the reminder is done early
+	DriftHours      float64                    `json:"drift_hours"`
+	Reason          string                     `json:"reason"`
+	CurrentDueDate  time.Time                  `json:"current_due_date"`
+	Current         reminder.RecurrencePattern `json:"current"`
+	ProposedDueDate time.Time                  `json:"proposed_due_date"`
+	Proposed        reminder.RecurrencePattern `json:"proposed"`
+}
+
+// Analyze measures each completion of r against the scheduled occurrence
+// nearest to it and returns a report if they consistently drift, or nil if
+// they don't or there isn't enough history
+func Analyze(r *reminder.Reminder, events []*reminder.CompletionEvent) *Report {
+	if !r.IsRecurring() || r.DueDate == nil || len(events) < MinCompletions {
+		return nil
+	}
+	events = append([]*reminder.CompletionEvent(nil), events...)
+	sort.Slice(events, func(i, j int) bool { return events[i].CompletedAt.Before(events[j].CompletedAt) })
+	if len(events) > window {
+		events = events[len(events)-window:]
+	}
+	var offsets []time.Duration
+	for _, e := range events {
+		if at, ok := nearestOccurrence(r, e.CompletedAt.In(r.DueDate.Location())); ok {
+			offsets = append(offsets, e.CompletedAt.Sub(at))
+		}
+	}
+	if len(offsets) < MinCompletions {
+		return nil
+	}
+	d := round(median(offsets))
+	if d.Abs() < MinDrift {
+		return nil
+	}
+	same := 0
+	for _, o := range offsets {
+		if (o > 0) == (d > 0) && o.Abs() >= MinDrift/2 {
+			same++
+		}
+	}
+	if float64(same) < consistency*float64(len(offsets)) {
+		return nil
+	}
+	due, pattern := Adjust(r, d)
+	direction := "late"
+	if d < 0 {
+		direction = "early"
+	}
+	return &Report{
+		ReminderID:      r.ID,
+		FamilyID:        r.FamilyID,
+		Title:           r.Title,
+		Completions:     len(offsets),
+		DriftHours:      math.Round(d.Hours()*100) / 100,
+		Reason:          fmt.Sprintf("%q is usually completed %s %s", r.Title, describe(d.Abs()), direction),
+		CurrentDueDate:  *r.DueDate,
+		Current:         r.Recurrence,
+		ProposedDueDate: due,
+		Proposed:        pattern,
+	}
+}
+
+// Adjust returns the due date and recurrence of r shifted by d, moving the
+// weekdays of weekly reminders and the date of monthly ones along with it.
+// Monthly dates are kept between 1 and 28 so every month has them.
+func Adjust(r *reminder.Reminder, d time.Duration) (time.Time, reminder.RecurrencePattern) {
+	due := r.DueDate.Add(d)
+	p := r.Recurrence
+	shift := daysBetween(*r.DueDate, due)
+	switch p.Type {
+	case "weekly":
+		days := make([]string, 0, len(p.Days))
+		for _, day := range p.Days {
+			if wd, ok := weekday(day); ok {
+				day = strings.ToLower(time.Weekday((int(wd) + shift%7 + 7) % 7).String())
+			}
+			days = append(days, day)
+		}
+		p.Days = days
+	case "monthly":
+		p.Date = min(max(p.Date+shift, 1), 28)
+	}
+	return due, p
+}
+
+// nearestOccurrence returns the scheduled occurrence of r closest to at,
+// searching the occurrence period containing at and the periods around it
+func nearestOccurrence(r *reminder.Reminder, at time.Time) (time.Time, bool) {
+	start, end := r.Period(at)
+	span := end.Sub(start)
+	var best time.Time
+	found := false
+	for _, o := range r.Occurrences(start.Add(-span), end.Add(span)) {
+		if !found || at.Sub(o).Abs() < at.Sub(best).Abs() {
+			best, found = o, true
+		}
+	}
+	return best, found
+}
+
+// round rounds drifts of most of a day or more to whole days, so the time
+// of day is kept, and shorter ones to quarter hours
+func round(d time.Duration) time.Duration {
+	day := 24 * time.Hour
+	if d.Abs() >= 20*time.Hour {
+		return d.Round(day)
+	}
+	return d.Round(15 * time.Minute)
+}
+
+func median(values []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// daysBetween returns the number of calendar days from a to b
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+// weekday parses a weekday name in any case
+func weekday(name string) (time.Weekday, bool) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(wd.String(), name) {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+// describe formats a drift for a reason, e.g. "3 days" or "2h30m"
+func describe(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		if n := int(d.Hours() / 24); n != 1 {
+			return fmt.Sprintf("%d days", n)
+		}
+		return "a day"
+	}
+	return strings.TrimSuffix(d.String(), "0s")
+}
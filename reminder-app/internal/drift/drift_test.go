@@ -0,0 +1,86 @@
+package drift
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// completions returns a completion event for each time
+func completions(id string, times ...time.Time) []*reminder.CompletionEvent {
+	var events []*reminder.CompletionEvent
+	for i, at := range times {
+		events = append(events, &reminder.CompletionEvent{ID: fmt.Sprintf("%s-%d", id, i), ReminderID: id, CompletedAt: at})
+	}
+	return events
+}
+
+func TestAnalyze(t *testing.T) {
+	saturday := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	weekly := reminder.NewReminder("r1", "Mow the lawn", "", &saturday, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"saturday"}})
+
+	var onTime, late, mixed []time.Time
+	for i := 0; i < 6; i++ {
+		week := saturday.AddDate(0, 0, 7*i)
+		onTime = append(onTime, week.Add(20*time.Minute))
+		late = append(late, week.AddDate(0, 0, 3).Add(time.Duration(i%3-1)*time.Hour))
+		mixed = append(mixed, week.AddDate(0, 0, 2*(i%2)))
+	}
+	if rep := Analyze(weekly, completions("r1", onTime...)); rep != nil {
+		t.Errorf("expected no report for completions on time, got %+v", rep)
+	}
+	if rep := Analyze(weekly, completions("r1", late[:MinCompletions-1]...)); rep != nil {
+		t.Errorf("expected no report with too little history, got %+v", rep)
+	}
+	if rep := Analyze(weekly, completions("r1", mixed...)); rep != nil {
+		t.Errorf("expected no report for inconsistent completions, got %+v", rep)
+	}
+
+	rep := Analyze(weekly, completions("r1", late...))
+	if rep == nil {
+		t.Fatal("expected a report for completions 3 days late")
+	}
+	if rep.DriftHours != 72 || rep.Completions != 6 || rep.Reason != `"Mow the lawn" is usually completed 3 days late` {
+		t.Errorf("unexpected report: %+v", rep)
+	}
+	if !rep.ProposedDueDate.Equal(saturday.AddDate(0, 0, 3)) || len(rep.Proposed.Days) != 1 || rep.Proposed.Days[0] != "tuesday" {
+		t.Errorf("expected the schedule moved to tuesday at 10:00, got %s %+v", rep.ProposedDueDate, rep.Proposed)
+	}
+
+	// Once adjusted, the same completions no longer drift
+	weekly.DueDate, weekly.Recurrence = &rep.ProposedDueDate, rep.Proposed
+	if rep := Analyze(weekly, completions("r1", late...)); rep != nil {
+		t.Errorf("expected no report after adjusting, got %+v", rep)
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	due := time.Date(2025, 3, 2, 7, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name    string
+		pattern reminder.RecurrencePattern
+		drift   time.Duration
+		want    reminder.RecurrencePattern
+	}{
+		{"daily time of day", reminder.RecurrencePattern{Type: "daily"}, 90 * time.Minute, reminder.RecurrencePattern{Type: "daily"}},
+		{"weekly wraps around", reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday", "Saturday"}}, 48 * time.Hour,
+			reminder.RecurrencePattern{Type: "weekly", Days: []string{"wednesday", "monday"}}},
+		{"weekly early", reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday"}}, -24 * time.Hour,
+			reminder.RecurrencePattern{Type: "weekly", Days: []string{"sunday"}}},
+		{"monthly", reminder.RecurrencePattern{Type: "monthly", Date: 2}, 72 * time.Hour, reminder.RecurrencePattern{Type: "monthly", Date: 5}},
+		{"monthly clamps", reminder.RecurrencePattern{Type: "monthly", Date: 2}, -72 * time.Hour, reminder.RecurrencePattern{Type: "monthly", Date: 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := reminder.NewReminder("r1", "Test", "", &due, "fam1", "Alice", tt.pattern)
+			gotDue, got := Adjust(r, tt.drift)
+			if !gotDue.Equal(due.Add(tt.drift)) {
+				t.Errorf("expected due date %s, got %s", due.Add(tt.drift), gotDue)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package events is the in-process bus that handlers publish domain events
+// to after every change to families, reminders and completions. Audit
+// logging, notifications, live updates and other consumers subscribe to it
+// rather than being called by each handler.
+package events
+
+import (
+	"encoding/json"
+	"log"
+	"runtime/debug"
+	"slices"
+	"sync"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+)
+
+// Event is something that happened to a family's data. Subscribers switch
+// on the concrete type.
+type Event interface {
+	// Type names the event, e.g. "reminder.created"
+	Type() string
+	// FamilyID returns the ID of the family the event belongs to
+	FamilyID() string
+}
+
+// FamilyCreated is published when a family is created
+type FamilyCreated struct {
+	Family *family.Family
+	Actor  string
+}
+
+// FamilyUpdated is published when a family's name, members or settings
+// change
+type FamilyUpdated struct {
+	Family *family.Family
+	Actor  string
+}
+
+// FamilyDeleted is published when a family is deleted
+type FamilyDeleted struct {
+	ID    string
+	Actor string
+}
+
+// ReminderCreated is published when a reminder is created. In this and the
+// other reminder events, Impersonator is the admin who acted as Actor, if
+// any.
+type ReminderCreated struct {
+	Reminder     *reminder.Reminder
+	Actor        string
+	Impersonator string
+}
+
+// ReminderUpdated is published when a reminder changes other than by being
+// completed. Before is its audit.Snapshot prior to the change.
+type ReminderUpdated struct {
+	Reminder     *reminder.Reminder
+	Before       json.RawMessage
+	Actor        string
+	Impersonator string
+}
+
+// ReminderCompleted is published when a reminder is completed and saved,
+// with the completion event recorded for it. Before is the reminder's
+// snapshot prior to completion.
+type ReminderCompleted struct {
+	Reminder     *reminder.Reminder
+	Before       json.RawMessage
+	Completion   *reminder.CompletionEvent
+	Actor        string
+	Impersonator string
+}
+
+// ReminderDeleted is published when a reminder is deleted. Before is its
+// last snapshot.
+type ReminderDeleted struct {
+	Reminder     *reminder.Reminder
+	Before       json.RawMessage
+	Actor        string
+	Impersonator string
+}
+
+// CompletionRecorded is published when a completion event is recorded
+// directly rather than by completing the reminder, such as when importing
+// history. The reminder's completion has been brought in line with it.
+type CompletionRecorded struct {
+	Reminder   *reminder.Reminder
+	Completion *reminder.CompletionEvent
+}
+
+// CompletionDeleted is published when a completion event of an existing
+// reminder is deleted
+type CompletionDeleted struct {
+	Reminder   *reminder.Reminder
+	Completion *reminder.CompletionEvent
+	Actor      string
+}
+
+func (FamilyCreated) Type() string      { return "family.created" }
+func (FamilyUpdated) Type() string      { return "family.updated" }
+func (FamilyDeleted) Type() string      { return "family.deleted" }
+func (ReminderCreated) Type() string    { return "reminder.created" }
+func (ReminderUpdated) Type() string    { return "reminder.updated" }
+func (ReminderCompleted) Type() string  { return "reminder.completed" }
+func (ReminderDeleted) Type() string    { return "reminder.deleted" }
+func (CompletionRecorded) Type() string { return "completion.recorded" }
+func (CompletionDeleted) Type() string  { return "completion.deleted" }
+
+func (e FamilyCreated) FamilyID() string      { return e.Family.ID }
+func (e FamilyUpdated) FamilyID() string      { return e.Family.ID }
+func (e FamilyDeleted) FamilyID() string      { return e.ID }
+func (e ReminderCreated) FamilyID() string    { return e.Reminder.FamilyID }
+func (e ReminderUpdated) FamilyID() string    { return e.Reminder.FamilyID }
+func (e ReminderCompleted) FamilyID() string  { return e.Reminder.FamilyID }
+func (e ReminderDeleted) FamilyID() string    { return e.Reminder.FamilyID }
+func (e CompletionRecorded) FamilyID() string { return e.Reminder.FamilyID }
+func (e CompletionDeleted) FamilyID() string  { return e.Reminder.FamilyID }
+
+// Handler consumes events
+type Handler func(Event)
+
+// Bus delivers published events to its subscribers
+type Bus struct {
+	mu   sync.RWMutex
+	subs []*subscriber
+}
+
+type subscriber struct {
+	h Handler
+}
+
+// NewBus returns a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds h to the subscribers and returns a function removing it
+func (b *Bus) Subscribe(h Handler) (unsubscribe func()) {
+	sub := &subscriber{h}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, sub)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.subs = slices.DeleteFunc(slices.Clone(b.subs), func(s *subscriber) bool { return s == sub })
+	}
+}
+
+// Publish delivers e to every subscriber in the order they subscribed,
+// before returning. Publishing happens on the request path, so subscribers
+// must hand slow work such as network calls to a goroutine. A subscriber
+// that panics is logged and doesn't affect the others.
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	for _, s := range subs {
+		deliver(s.h, e)
+	}
+}
+
+func deliver(h Handler, e Event) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("events: subscriber panicked handling %s: %v\n%s", e.Type(), p, debug.Stack())
+		}
+	}()
+	h(e)
+}
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+
+	"reminder-app/internal/reminder"
+)
+
+func TestBus(t *testing.T) {
+	b := NewBus()
+	var got []string
+	b.Subscribe(func(e Event) { got = append(got, "first "+e.Type()) })
+	b.Subscribe(func(e Event) { panic("boom") })
+	unsubscribe := b.Subscribe(func(e Event) { got = append(got, "third "+e.FamilyID()) })
+
+	rem := &reminder.Reminder{ID: "rem1", FamilyID: "fam1"}
+	b.Publish(ReminderCreated{Reminder: rem})
+	if want := []string{"first reminder.created", "third fam1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got = nil
+	unsubscribe()
+	unsubscribe()
+	b.Publish(FamilyDeleted{ID: "fam1"})
+	if want := []string{"first family.deleted"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v after unsubscribing, got %v", want, got)
+	}
+}
@@ -1,24 +1,188 @@
 package family
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type Family struct {
-    ID      string   `json:"id"`
-    Name    string   `json:"name"`
-    Members []string `json:"members"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Members  []string `json:"members"`
+	Settings Settings `json:"settings"`
+	// DeletedAt is set on families in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deletedat,omitempty"`
+}
+
+// Settings holds per-family configuration
+type Settings struct {
+	// Channels are the notification destinations for the whole family
+	Channels []Channel `json:"channels,omitempty"`
+	// Members holds per-member overrides, keyed by member name
+	Members map[string]MemberSettings `json:"members,omitempty"`
+	// Templates override the default notification text, keyed by
+	// notification kind (for example "reminder")
+	Templates map[string]Template `json:"templates,omitempty"`
+	// Timezone is the family's IANA time zone, used for members without
+	// their own. The server's local zone is used when empty.
+	Timezone string `json:"timezone,omitempty"`
+	// Nag enables an evening summary of today's unfinished reminders
+	Nag *ScheduleSettings `json:"nag,omitempty"`
+	// MonthlyReport sends the previous month's report on the first of each
+	// month, attached as a PDF on email channels
+	MonthlyReport *ScheduleSettings `json:"monthly_report,omitempty"`
+	// CompletionNotices tells the family channels whenever a reminder is
+	// completed
+	CompletionNotices bool `json:"completion_notices,omitempty"`
+	// CompletionRetentionDays deletes completion events older than this many
+	// days once their days are kept as daily stats; zero keeps them forever
+	CompletionRetentionDays int `json:"completion_retention_days,omitempty"`
+	// Workflow customizes the statuses reminders move through; see
+	// DefaultWorkflow
+	Workflow *Workflow `json:"workflow,omitempty"`
+}
+
+// Template is a customized notification text. Subject and Body are Go
+// text/template strings such as "{{.Title}} is due {{.Due}}".
+type Template struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body,omitempty"`
+}
+
+// MemberSettings holds configuration for a single family member
+type MemberSettings struct {
+	// Channels are the member's personal notification destinations, such
+	// as their own ntfy topic
+	Channels []Channel `json:"channels,omitempty"`
+	// Timezone is the member's IANA time zone, e.g. "Europe/Berlin". The
+	// family's time zone is used when empty.
+	Timezone string `json:"timezone,omitempty"`
+	// Agenda enables a daily summary of the member's reminders
+	Agenda *ScheduleSettings `json:"agenda,omitempty"`
+	// Role limits what the member may do; see Role
+	Role Role `json:"role,omitempty"`
+	// Failover ranks the member's channels, in order, instead of sending
+	// due reminders to all of them; see Failover
+	Failover *Failover `json:"failover,omitempty"`
+	// Digest opts the member in to an email digest of their upcoming and
+	// overdue reminders
+	Digest *DigestSettings `json:"digest,omitempty"`
+}
+
+// Failover makes a member's due reminders go to their first channel that
+// takes them, such as push, then email, then SMS, rather than to every
+// channel. A channel whose delivery fails is passed over at once.
+type Failover struct {
+	// AckMinutes also moves an occurrence on to the next channel when it
+	// hasn't been acknowledged, completed or snoozed this many minutes
+	// after it was delivered. Zero only fails over on errors.
+	AckMinutes int `json:"ack_minutes,omitempty"`
+}
+
+// ScheduleSettings configures a daily notification such as the agenda
+type ScheduleSettings struct {
+	// Time is the local time of day to send the notification, as "HH:MM"
+	Time string `json:"time"`
+	// Channel overrides where the notification is sent; the member's or
+	// family's channels are used when nil
+	Channel *Channel `json:"channel,omitempty"`
+}
+
+// Digest frequencies
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSettings schedules a member's digest: every day at Time, or once a
+// week on Weekday
+type DigestSettings struct {
+	ScheduleSettings
+	// Frequency is DigestDaily, the default, or DigestWeekly
+	Frequency string `json:"frequency,omitempty"`
+	// Weekday is the day weekly digests are sent, e.g. "sunday"; Monday
+	// when empty
+	Weekday string `json:"weekday,omitempty"`
+}
+
+// Period returns the frequency, defaulting to daily
+func (d DigestSettings) Period() string {
+	if d.Frequency == "" {
+		return DigestDaily
+	}
+	return d.Frequency
+}
+
+// Day parses the weekday weekly digests are sent on
+func (d DigestSettings) Day() (time.Weekday, error) {
+	if d.Weekday == "" {
+		return time.Monday, nil
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(wd.String(), d.Weekday) {
+			return wd, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday: %s", d.Weekday)
+}
+
+// Clock parses the scheduled time into hour and minute
+func (s ScheduleSettings) Clock() (hour, min int, err error) {
+	t, err := time.Parse("15:04", s.Time)
+	if err != nil {
+		return 0, 0, fmt.Errorf("time must be HH:MM: %q", s.Time)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// loadLocation resolves an IANA zone name, treating empty as the server's
+// local zone
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// Channel is a notification destination. Type selects the notifier (for
+// example "matrix") and Target is the address within it, such as a room ID.
+type Channel struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
 }
 
 func (f *Family) AddMember(member string) {
-    f.Members = append(f.Members, member)
+	f.Members = append(f.Members, member)
 }
 
 func (f *Family) RemoveMember(member string) {
-    for i, m := range f.Members {
-        if m == member {
-            f.Members = append(f.Members[:i], f.Members[i+1:]...)
-            break
-        }
-    }
+	for i, m := range f.Members {
+		if m == member {
+			f.Members = append(f.Members[:i], f.Members[i+1:]...)
+			break
+		}
+	}
 }
 
 func (f *Family) GetMembers() []string {
-    return f.Members
-}
\ No newline at end of file
+	return f.Members
+}
+
+// LocationFor returns the time zone of a member, falling back to the
+// family's time zone. An empty member returns the family's time zone.
+func (f *Family) LocationFor(member string) (*time.Location, error) {
+	if ms, ok := f.Settings.Members[member]; ok && ms.Timezone != "" {
+		return loadLocation(ms.Timezone)
+	}
+	return loadLocation(f.Settings.Timezone)
+}
+
+// ChannelsFor returns the notification channels for a member: their personal
+// channels if they have any, otherwise the family's channels
+func (f *Family) ChannelsFor(member string) []Channel {
+	if ms, ok := f.Settings.Members[member]; ok && len(ms.Channels) > 0 {
+		return ms.Channels
+	}
+	return f.Settings.Channels
+}
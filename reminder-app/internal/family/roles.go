@@ -0,0 +1,86 @@
+package family
+
+// Role is a member's level of access to their family. Roles are set in the
+// member's settings; a family that assigns none is open to every member.
+type Role string
+
+const (
+	// RoleOwner may do anything, including deleting the family and changing
+	// its settings and roles
+	RoleOwner Role = "owner"
+	// RoleAdult manages reminders but not the family itself. Members without
+	// a role are adults.
+	RoleAdult Role = "adult"
+	// RoleChild may only complete the reminders assigned to them
+	RoleChild Role = "child"
+)
+
+// Permission is an action that roles grant. Its value reads as a verb
+// phrase for error messages.
+type Permission string
+
+const (
+	// PermManage covers deleting the family, changing its settings and
+	// granting outside access such as shares and inbound hooks
+	PermManage Permission = "manage the family"
+	// PermEdit covers creating, changing and deleting reminders and the
+	// lists and projects that organize them
+	PermEdit Permission = "edit reminders"
+	// PermComplete covers completing reminders and moving them through the
+	// workflow
+	PermComplete Permission = "complete reminders"
+)
+
+// Permissions lists every permission, broadest first
+var Permissions = []Permission{PermManage, PermEdit, PermComplete}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	switch r {
+	case RoleOwner, RoleAdult, RoleChild:
+		return true
+	}
+	return false
+}
+
+// Can reports whether the role grants p
+func (r Role) Can(p Permission) bool {
+	switch r {
+	case RoleOwner:
+		return true
+	case RoleAdult:
+		return p != PermManage
+	case RoleChild:
+		return p == PermComplete
+	}
+	return false
+}
+
+// HasRoles reports whether any member has been given a role, which turns on
+// authorization for the family
+func (f *Family) HasRoles() bool {
+	for _, ms := range f.Settings.Members {
+		if ms.Role != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleOf returns a member's role, or "" if they don't belong to the family
+func (f *Family) RoleOf(member string) Role {
+	found := false
+	for _, m := range f.Members {
+		if m == member {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ""
+	}
+	if ms, ok := f.Settings.Members[member]; ok && ms.Role != "" {
+		return ms.Role
+	}
+	return RoleAdult
+}
@@ -0,0 +1,103 @@
+package family
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Built-in reminder statuses. StatusDone is the only status that means a
+// reminder is completed, and every workflow must include it.
+const (
+	StatusTodo    = "todo"
+	StatusDoing   = "doing"
+	StatusBlocked = "blocked"
+	StatusDone    = "done"
+)
+
+// Workflow lists the statuses a family's reminders move through and which
+// moves between them are allowed. The first status is where new reminders
+// start and where recurring reminders return after each completion.
+type Workflow struct {
+	Statuses []string `json:"statuses"`
+	// Transitions maps a status to the statuses it may move to
+	Transitions map[string][]string `json:"transitions"`
+}
+
+// DefaultWorkflow is used by families that haven't configured their own
+var DefaultWorkflow = Workflow{
+	Statuses: []string{StatusTodo, StatusDoing, StatusBlocked, StatusDone},
+	Transitions: map[string][]string{
+		StatusTodo:    {StatusDoing, StatusDone},
+		StatusDoing:   {StatusTodo, StatusBlocked, StatusDone},
+		StatusBlocked: {StatusTodo, StatusDoing},
+		StatusDone:    {StatusTodo},
+	},
+}
+
+// Initial returns the status new reminders start in
+func (w Workflow) Initial() string {
+	return w.Statuses[0]
+}
+
+// Has reports whether status is part of the workflow
+func (w Workflow) Has(status string) bool {
+	for _, s := range w.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether a reminder may move from one status to another
+func (w Workflow) Allows(from, to string) bool {
+	for _, s := range w.Transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the workflow is well formed
+func (w Workflow) Validate() error {
+	if len(w.Statuses) == 0 {
+		return errors.New("workflow requires at least one status")
+	}
+	seen := make(map[string]bool, len(w.Statuses))
+	for _, s := range w.Statuses {
+		if s == "" {
+			return errors.New("workflow statuses must not be empty")
+		}
+		if seen[s] {
+			return fmt.Errorf("duplicate workflow status: %s", s)
+		}
+		seen[s] = true
+	}
+	if !seen[StatusDone] {
+		return fmt.Errorf("workflow must include the %q status", StatusDone)
+	}
+	if w.Initial() == StatusDone {
+		return fmt.Errorf("workflow must not start in the %q status", StatusDone)
+	}
+	for from, targets := range w.Transitions {
+		if !seen[from] {
+			return fmt.Errorf("transition from unknown status: %s", from)
+		}
+		for _, to := range targets {
+			if !seen[to] {
+				return fmt.Errorf("transition to unknown status: %s", to)
+			}
+		}
+	}
+	return nil
+}
+
+// Workflow returns the family's workflow, or DefaultWorkflow if it hasn't
+// configured one
+func (f *Family) Workflow() Workflow {
+	if f.Settings.Workflow != nil {
+		return *f.Settings.Workflow
+	}
+	return DefaultWorkflow
+}
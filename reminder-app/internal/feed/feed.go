@@ -0,0 +1,164 @@
+// Package feed builds Atom feeds of a family's reminders for people who
+// follow them in a feed reader: what was recently completed or seen and
+// what is coming up.
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// Atom is an Atom 1.0 feed document
+type Atom struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    *Link    `xml:"link,omitempty"`
+	Entries []Entry  `xml:"entry"`
+}
+
+// Link is an Atom link element
+type Link struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+// Entry is one item of the feed
+type Entry struct {
+	ID      string  `xml:"id"`
+	Title   string  `xml:"title"`
+	Updated string  `xml:"updated"`
+	Author  *Author `xml:"author,omitempty"`
+	Summary string  `xml:"summary,omitempty"`
+}
+
+// Author names who an entry is about
+type Author struct {
+	Name string `xml:"name"`
+}
+
+// Source is the data a feed is built from
+type Source struct {
+	FamilyID  string
+	Title     string
+	Self      string // URL of the feed itself
+	Reminders []*reminder.Reminder
+	// Completions are the recent completion events of Reminders
+	Completions []*reminder.CompletionEvent
+	// Acks are the recent acknowledgements of Reminders
+	Acks []*reminder.Ack
+	// Upcoming is how far ahead due occurrences are listed
+	Upcoming time.Duration
+}
+
+// Build returns the feed at the given time, newest entries first. Upcoming
+// occurrences are dated by when they are due so readers sort them ahead of
+// past activity.
+func Build(src Source, now time.Time) *Atom {
+	byID := make(map[string]*reminder.Reminder, len(src.Reminders))
+	for _, r := range src.Reminders {
+		byID[r.ID] = r
+	}
+	type dated struct {
+		at    time.Time
+		entry Entry
+	}
+	var items []dated
+	for _, e := range src.Completions {
+		r, ok := byID[e.ReminderID]
+		if !ok {
+			continue
+		}
+		items = append(items, dated{e.CompletedAt, Entry{
+			ID:      fmt.Sprintf("urn:reminder-app:completion:%s", e.ID),
+			Title:   fmt.Sprintf("Done: %s", r.Title),
+			Updated: e.CompletedAt.UTC().Format(time.RFC3339),
+			Author:  author(e.CompletedBy),
+			Summary: fmt.Sprintf("%s completed %q.", nonEmpty(e.CompletedBy, "Someone"), r.Title),
+		}})
+	}
+	for _, a := range src.Acks {
+		r, ok := byID[a.ReminderID]
+		if !ok || completedSince(src.Completions, a) {
+			continue
+		}
+		items = append(items, dated{a.AckedAt, Entry{
+			ID:      fmt.Sprintf("urn:reminder-app:ack:%s", a.ID),
+			Title:   fmt.Sprintf("Seen: %s", r.Title),
+			Updated: a.AckedAt.UTC().Format(time.RFC3339),
+			Author:  author(a.AckedBy),
+			Summary: fmt.Sprintf("%s saw %q %s ago, not done.", nonEmpty(a.AckedBy, "Someone"), r.Title, ago(now.Sub(a.AckedAt))),
+		}})
+	}
+	for _, r := range src.Reminders {
+		for _, at := range r.Occurrences(now, now.Add(src.Upcoming)) {
+			summary := r.Description
+			if summary == "" {
+				summary = fmt.Sprintf("%q is due %s.", r.Title, at.Format("Mon Jan 2 15:04"))
+			}
+			items = append(items, dated{at, Entry{
+				ID:      fmt.Sprintf("urn:reminder-app:due:%s:%d", r.ID, at.Unix()),
+				Title:   fmt.Sprintf("Due %s: %s", at.Format("Mon Jan 2 15:04"), r.Title),
+				Updated: at.UTC().Format(time.RFC3339),
+				Author:  author(r.FamilyMember),
+				Summary: summary,
+			}})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].at.After(items[j].at) })
+
+	a := &Atom{
+		ID:      "urn:reminder-app:family:" + src.FamilyID,
+		Title:   src.Title,
+		Updated: now.UTC().Format(time.RFC3339),
+		Entries: make([]Entry, 0, len(items)),
+	}
+	if src.Self != "" {
+		a.Link = &Link{Href: src.Self, Rel: "self"}
+	}
+	for _, it := range items {
+		a.Entries = append(a.Entries, it.entry)
+	}
+	return a
+}
+
+// completedSince reports whether the acknowledged reminder was completed
+// after the acknowledgement, which the completion's entry then supersedes
+func completedSince(completions []*reminder.CompletionEvent, a *reminder.Ack) bool {
+	for _, e := range completions {
+		if e.ReminderID == a.ReminderID && !e.CompletedAt.Before(a.AckedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// ago formats how long ago something happened as "10m", "3h" or "2d"
+func ago(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(max(d, 0)/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+}
+
+func author(name string) *Author {
+	if name == "" {
+		return nil
+	}
+	return &Author{Name: name}
+}
+
+func nonEmpty(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
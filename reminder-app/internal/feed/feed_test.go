@@ -0,0 +1,68 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 3, 1, 18, 0, 0, 0, time.UTC)
+	trash := reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"wednesday"}})
+	src := Source{
+		FamilyID:    "fam1",
+		Title:       "Smith family",
+		Self:        "https://example.com/families/fam1/feed.atom",
+		Reminders:   []*reminder.Reminder{trash},
+		Completions: []*reminder.CompletionEvent{{ID: "cev1", ReminderID: "rem1", CompletedBy: "Bob", CompletedAt: now.Add(-24 * time.Hour)}},
+		Upcoming:    7 * 24 * time.Hour,
+	}
+
+	a := Build(src, now)
+	if len(a.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", a.Entries)
+	}
+	if !strings.HasPrefix(a.Entries[0].Title, "Due Wed Mar 5") || a.Entries[1].Title != "Done: Trash" || a.Entries[1].Author.Name != "Bob" {
+		t.Errorf("unexpected entries: %+v", a.Entries)
+	}
+
+	out, err := xml.Marshal(a)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.HasPrefix(string(out), `<feed xmlns="http://www.w3.org/2005/Atom">`) {
+		t.Errorf("unexpected document: %s", out)
+	}
+}
+
+func TestBuildAcks(t *testing.T) {
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 3, 4, 11, 0, 0, 0, time.UTC)
+	trash := reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	dishes := reminder.NewReminder("rem2", "Dishes", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"})
+	src := Source{
+		FamilyID:  "fam1",
+		Reminders: []*reminder.Reminder{trash, dishes},
+		Acks: []*reminder.Ack{
+			{ID: "ack1", ReminderID: "rem1", AckedBy: "Alice", AckedAt: now.Add(-10 * time.Minute)},
+			{ID: "ack2", ReminderID: "rem2", AckedBy: "Bob", AckedAt: now.Add(-30 * time.Minute)},
+		},
+		Completions: []*reminder.CompletionEvent{{ID: "cev1", ReminderID: "rem2", CompletedBy: "Bob", CompletedAt: now.Add(-20 * time.Minute)}},
+	}
+
+	a := Build(src, now)
+	if len(a.Entries) != 2 {
+		t.Fatalf("expected the completion and one acknowledgement, got %+v", a.Entries)
+	}
+	seen := a.Entries[0]
+	if seen.Title != "Seen: Trash" || seen.Summary != `Alice saw "Trash" 10m ago, not done.` {
+		t.Errorf("unexpected acknowledgement entry: %+v", seen)
+	}
+	if a.Entries[1].Title != "Done: Dishes" {
+		t.Errorf("expected the completion to supersede Bob's acknowledgement, got %+v", a.Entries[1])
+	}
+}
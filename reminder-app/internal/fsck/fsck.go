@@ -0,0 +1,210 @@
+// Package fsck finds, and optionally repairs, inconsistencies in stored data
+// that the API never creates itself: reminders left behind by a family
+// removed outside the server, documents duplicated by a partial restore, or
+// recurrence patterns edited by hand into something invalid.
+package fsck
+
+import (
+	"fmt"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// Kinds of issue
+const (
+	OrphanedReminder        = "orphaned_reminder"
+	OrphanedCompletionEvent = "orphaned_completion_event"
+	DuplicateID             = "duplicate_id"
+	InvalidRecurrence       = "invalid_recurrence"
+)
+
+// Issue is one inconsistency found by Check
+type Issue struct {
+	Kind string `json:"kind"`
+	// Entity is "family", "reminder" or "completion_event"
+	Entity   string `json:"entity"`
+	ID       string `json:"id"`
+	Detail   string `json:"detail"`
+	Repaired bool   `json:"repaired,omitempty"`
+	// RepairError explains why a repair was attempted but failed
+	RepairError string `json:"repair_error,omitempty"`
+}
+
+// Report lists every issue found by Check
+type Report struct {
+	Issues []Issue `json:"issues"`
+}
+
+// Unrepaired returns the number of issues still present in storage
+func (r *Report) Unrepaired() int {
+	n := 0
+	for _, is := range r.Issues {
+		if !is.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// checker accumulates issues and applies repairs when enabled
+type checker struct {
+	s      storage.Storage
+	repair bool
+	report Report
+}
+
+// add records an issue, running fix first when repairing
+func (c *checker) add(is Issue, fix func() error) {
+	if c.repair && fix != nil {
+		if err := fix(); err != nil {
+			is.RepairError = err.Error()
+		} else {
+			is.Repaired = true
+		}
+	}
+	c.report.Issues = append(c.report.Issues, is)
+}
+
+// Check scans s for inconsistencies. With repair set it also fixes them:
+//   - duplicated entities are collapsed into a single copy, keeping the
+//     reminder with the highest version or else the first copy read
+//   - orphaned reminders are deleted together with their completion events
+//   - orphaned completion events are deleted
+//   - reminders with an invalid recurrence become one-off reminders
+//
+// Duplicates are handled first so the other checks see one copy of each.
+func Check(s storage.Storage, repair bool) (*Report, error) {
+	c := &checker{s: s, repair: repair, report: Report{Issues: []Issue{}}}
+
+	families, err := s.ListFamilies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list families: %w", err)
+	}
+	families = dedupe(c, "family", families, func(f *family.Family) string { return f.ID },
+		func(a, b *family.Family) bool { return false },
+		func(id string) error { return s.DeleteFamily(id) },
+		func(f *family.Family) error { return s.CreateFamily(f) })
+
+	reminders, err := s.ListReminders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	reminders = dedupe(c, "reminder", reminders, func(r *reminder.Reminder) string { return r.ID },
+		func(a, b *reminder.Reminder) bool { return a.Version > b.Version },
+		s.DeleteReminder, s.CreateReminder)
+
+	events, err := s.ListAllCompletionEvents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list completion events: %w", err)
+	}
+	events = dedupe(c, "completion_event", events, func(e *reminder.CompletionEvent) string { return e.ID },
+		func(a, b *reminder.CompletionEvent) bool { return false },
+		s.DeleteCompletionEvent, s.CreateCompletionEvent)
+
+	familyIDs := make(map[string]bool, len(families))
+	for _, f := range families {
+		familyIDs[f.ID] = true
+	}
+	eventsOf := make(map[string][]*reminder.CompletionEvent)
+	for _, e := range events {
+		eventsOf[e.ReminderID] = append(eventsOf[e.ReminderID], e)
+	}
+
+	live := make(map[string]bool, len(reminders))
+	orphaned := make(map[string]bool)
+	for _, r := range reminders {
+		if !familyIDs[r.FamilyID] {
+			orphaned[r.ID] = true
+			c.add(Issue{
+				Kind:   OrphanedReminder,
+				Entity: "reminder",
+				ID:     r.ID,
+				Detail: fmt.Sprintf("family %s does not exist; %d completion events", r.FamilyID, len(eventsOf[r.ID])),
+			}, func() error { return deleteReminder(s, r, eventsOf[r.ID]) })
+			continue
+		}
+		live[r.ID] = true
+		if err := r.Recurrence.Validate(); err != nil {
+			c.add(Issue{
+				Kind:   InvalidRecurrence,
+				Entity: "reminder",
+				ID:     r.ID,
+				Detail: fmt.Sprintf("%v: %+v", err, r.Recurrence),
+			}, func() error {
+				r.Recurrence = reminder.RecurrencePattern{Type: "once"}
+				r.Version++
+				return s.CreateReminder(r)
+			})
+		}
+	}
+
+	for _, e := range events {
+		// Events of orphaned reminders are handled with their reminder
+		if live[e.ReminderID] || orphaned[e.ReminderID] {
+			continue
+		}
+		c.add(Issue{
+			Kind:   OrphanedCompletionEvent,
+			Entity: "completion_event",
+			ID:     e.ID,
+			Detail: fmt.Sprintf("reminder %s does not exist", e.ReminderID),
+		}, func() error { return s.DeleteCompletionEvent(e.ID) })
+	}
+	return &c.report, nil
+}
+
+// deleteReminder removes a reminder and its completion events
+func deleteReminder(s storage.Storage, r *reminder.Reminder, events []*reminder.CompletionEvent) error {
+	for _, e := range events {
+		if err := s.DeleteCompletionEvent(e.ID); err != nil {
+			return err
+		}
+	}
+	return s.DeleteReminder(r.ID)
+}
+
+// dedupe reports entities that share an ID and returns the list with one
+// copy of each. better reports whether a copy should be kept over the one
+// kept so far. Repairs delete every copy, one per call as document stores
+// do, and then write the kept copy back.
+func dedupe[T any](c *checker, entity string, list []T, id func(T) string, better func(a, b T) bool,
+	del func(string) error, put func(T) error) []T {
+	kept := make(map[string]T)
+	count := make(map[string]int)
+	var order []string
+	for _, v := range list {
+		k := id(v)
+		if prev, ok := kept[k]; !ok {
+			order = append(order, k)
+			kept[k] = v
+		} else if better(v, prev) {
+			kept[k] = v
+		}
+		count[k]++
+	}
+	out := make([]T, 0, len(order))
+	for _, k := range order {
+		v := kept[k]
+		out = append(out, v)
+		if count[k] == 1 {
+			continue
+		}
+		n := count[k]
+		c.add(Issue{
+			Kind:   DuplicateID,
+			Entity: entity,
+			ID:     k,
+			Detail: fmt.Sprintf("%d copies", n),
+		}, func() error {
+			for i := 0; i < n; i++ {
+				if err := del(k); err != nil {
+					return err
+				}
+			}
+			return put(v)
+		})
+	}
+	return out
+}
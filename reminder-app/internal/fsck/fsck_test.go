@@ -0,0 +1,113 @@
+package fsck
+
+import (
+	"testing"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// duplicatingStorage lists a stale second copy of one reminder, as a
+// document store without a unique index can after a partial restore
+type duplicatingStorage struct {
+	*storage.MemoryStorage
+	stale *reminder.Reminder
+}
+
+func (d *duplicatingStorage) ListReminders() ([]*reminder.Reminder, error) {
+	list, err := d.MemoryStorage.ListReminders()
+	if err != nil || d.stale == nil {
+		return list, err
+	}
+	return append(list, d.stale), nil
+}
+
+func (d *duplicatingStorage) DeleteReminder(id string) error {
+	if d.stale != nil && d.stale.ID == id {
+		d.stale = nil
+		return nil
+	}
+	return d.MemoryStorage.DeleteReminder(id)
+}
+
+func seed(t *testing.T) *duplicatingStorage {
+	t.Helper()
+	s := &duplicatingStorage{MemoryStorage: storage.NewMemoryStorage()}
+	due := time.Now().Add(time.Hour)
+	_ = s.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	live := reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	live.Version = 2
+	_ = s.CreateReminder(live)
+	// The family of rem2 was removed behind the server's back
+	_ = s.CreateReminder(reminder.NewReminder("rem2", "Dishes", "", &due, "fam9", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = s.CreateReminder(reminder.NewReminder("rem3", "Plants", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"someday"}}))
+	_ = s.CreateCompletionEvent(&reminder.CompletionEvent{ID: "cev1", ReminderID: "rem1", CompletedAt: time.Now()})
+	_ = s.CreateCompletionEvent(&reminder.CompletionEvent{ID: "cev2", ReminderID: "rem2", CompletedAt: time.Now()})
+	_ = s.CreateCompletionEvent(&reminder.CompletionEvent{ID: "cev3", ReminderID: "rem7", CompletedAt: time.Now()})
+
+	s.stale = reminder.NewReminder("rem1", "Trash (old)", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	return s
+}
+
+func TestCheck(t *testing.T) {
+	s := seed(t)
+	report, err := Check(s, false)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := map[string]string{
+		DuplicateID + " rem1":             "reminder",
+		OrphanedReminder + " rem2":        "reminder",
+		InvalidRecurrence + " rem3":       "reminder",
+		OrphanedCompletionEvent + " cev3": "completion_event",
+	}
+	if len(report.Issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), report.Issues)
+	}
+	for _, is := range report.Issues {
+		if want[is.Kind+" "+is.ID] != is.Entity {
+			t.Errorf("unexpected issue %+v", is)
+		}
+		if is.Repaired {
+			t.Errorf("issue %s %s repaired without repair mode", is.Kind, is.ID)
+		}
+	}
+	if report.Unrepaired() != len(want) {
+		t.Errorf("expected %d unrepaired issues, got %d", len(want), report.Unrepaired())
+	}
+	if _, err := s.GetReminder("rem2"); err != nil {
+		t.Errorf("check without repair changed storage: %v", err)
+	}
+}
+
+func TestCheckRepair(t *testing.T) {
+	s := seed(t)
+	report, err := Check(s, true)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if report.Unrepaired() != 0 {
+		t.Fatalf("expected every issue repaired, got %+v", report.Issues)
+	}
+
+	if r, err := s.GetReminder("rem1"); err != nil || r.Title != "Trash" {
+		t.Errorf("expected the newest copy of rem1 to be kept, got %+v, %v", r, err)
+	}
+	if _, err := s.GetReminder("rem2"); err == nil {
+		t.Error("expected orphaned reminder rem2 to be deleted")
+	}
+	if r, err := s.GetReminder("rem3"); err != nil || r.Recurrence.Type != "once" || r.Version != 2 {
+		t.Errorf("expected rem3 reset to a one-off reminder, got %+v, %v", r, err)
+	}
+	events, _ := s.ListAllCompletionEvents()
+	if len(events) != 1 || events[0].ID != "cev1" {
+		t.Errorf("expected only cev1 to remain, got %+v", events)
+	}
+
+	report, err = Check(s, false)
+	if err != nil || len(report.Issues) != 0 {
+		t.Errorf("expected a clean check after repair, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package guest implements guest tokens: temporary API credentials for
+// someone outside the family, such as a house sitter, that can see and
+// complete only the reminders in a limited scope. Tokens expire on their
+// own and can be revoked before then; revoked tokens are kept on a
+// revocation list until they would have expired.
+package guest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// Record kinds of guests and of the revocation list
+const (
+	Kind           = "guest"
+	RevocationKind = "guest-revocation"
+)
+
+var (
+	ErrNotFound = errors.New("guest token not found")
+	ErrExpired  = errors.New("guest token expired")
+	ErrRevoked  = errors.New("guest token revoked")
+)
+
+// Scope limits the reminders a guest can see. Zero-valued fields match
+// everything; reminders without a due date never match a due date bound.
+type Scope struct {
+	// ProjectIDs are the projects whose reminders are visible
+	ProjectIDs []string `json:"project_ids,omitempty"`
+	// Tags make reminders with any of them visible
+	Tags      []string   `json:"tags,omitempty"`
+	DueAfter  *time.Time `json:"due_after,omitempty"`  // inclusive
+	DueBefore *time.Time `json:"due_before,omitempty"` // exclusive
+}
+
+// Guest is a token granting access to the reminders of a family within a
+// scope. Only a hash of the token is stored, so it can't be recovered from
+// the database.
+type Guest struct {
+	ID        string    `json:"id"`
+	FamilyID  string    `json:"family_id"`
+	Name      string    `json:"name"`
+	Scope     Scope     `json:"scope"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	// RevokedAt is filled in by List for tokens on the revocation list
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// revocation is an entry on the revocation list
+type revocation struct {
+	ID        string    `json:"id"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// Actor is how the guest is named in the audit log and completion events
+func (g *Guest) Actor() string {
+	return "guest:" + g.Name
+}
+
+// Can reports whether the guest may take action p on reminders in scope.
+// Guests can only complete them.
+func (g *Guest) Can(p family.Permission) bool {
+	return p == family.PermComplete
+}
+
+// Allows reports whether r is within the guest's scope
+func (g *Guest) Allows(r *reminder.Reminder) bool {
+	if r.FamilyID != g.FamilyID {
+		return false
+	}
+	if len(g.Scope.ProjectIDs) > 0 && !slices.Contains(g.Scope.ProjectIDs, r.ProjectID) {
+		return false
+	}
+	if len(g.Scope.Tags) > 0 && !slices.ContainsFunc(g.Scope.Tags, r.HasTag) {
+		return false
+	}
+	return storage.ReminderFilter{DueAfter: g.Scope.DueAfter, DueBefore: g.Scope.DueBefore}.Matches(r)
+}
+
+// Filter returns the reminders of list within the guest's scope
+func (g *Guest) Filter(list []*reminder.Reminder) []*reminder.Reminder {
+	var result []*reminder.Reminder
+	for _, r := range list {
+		if g.Allows(r) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Create stores a new guest and returns its token, which is only available
+// now
+func Create(s storage.Storage, g *Guest, now time.Time) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	g.ID = storage.NewRecordID("gst")
+	g.CreatedAt = now
+	rec := storage.Record{Kind: Kind, ID: g.ID, FamilyID: g.FamilyID, Ref: hashToken(token), CreatedAt: now, ExpiresAt: &g.ExpiresAt}
+	if err := storage.PutJSON(s, rec, g); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Lookup returns the guest a token belongs to
+func Lookup(s storage.Storage, token string, now time.Time) (*Guest, error) {
+	if token == "" {
+		return nil, ErrNotFound
+	}
+	list, err := storage.ListJSON[Guest](s, storage.RecordQuery{Kind: Kind, Ref: hashToken(token)})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	g := list[0]
+	if !now.Before(g.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	if _, err := s.GetRecord(RevocationKind, g.ID); err == nil {
+		return nil, ErrRevoked
+	} else if !errors.Is(err, storage.ErrRecordNotFound) {
+		return nil, err
+	}
+	return g, nil
+}
+
+// List returns the guests of a family with the revoked ones marked.
+// Expired guests remain until expired records are purged.
+func List(s storage.Storage, familyID string) ([]*Guest, error) {
+	list, err := storage.ListJSON[Guest](s, storage.RecordQuery{Kind: Kind, FamilyID: familyID})
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := storage.ListJSON[revocation](s, storage.RecordQuery{Kind: RevocationKind, FamilyID: familyID})
+	if err != nil {
+		return nil, err
+	}
+	for _, rv := range revoked {
+		for _, g := range list {
+			if g.ID == rv.ID {
+				g.RevokedAt = &rv.RevokedAt
+			}
+		}
+	}
+	return list, nil
+}
+
+// Revoke puts a guest on the revocation list. The entry expires with the
+// token, after which neither is needed.
+func Revoke(s storage.Storage, familyID, id string, now time.Time) error {
+	g, err := storage.GetJSON[Guest](s, Kind, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if g.FamilyID != familyID {
+		return ErrNotFound
+	}
+	rec := storage.Record{Kind: RevocationKind, ID: id, FamilyID: familyID, Ref: id, CreatedAt: now, ExpiresAt: &g.ExpiresAt}
+	return storage.PutJSON(s, rec, revocation{ID: id, RevokedAt: now})
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
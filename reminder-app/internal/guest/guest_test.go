@@ -0,0 +1,92 @@
+package guest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+func TestLookupAndRevoke(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	now := time.Now()
+	g := &Guest{FamilyID: "fam1", Name: "Sitter", ExpiresAt: now.Add(time.Hour)}
+	token, err := Create(store, g, now)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := Lookup(store, token, now)
+	if err != nil || got.ID != g.ID {
+		t.Fatalf("Lookup = %+v, %v; want guest %s", got, err, g.ID)
+	}
+	if rec, _ := store.GetRecord(Kind, g.ID); rec.Ref == token {
+		t.Error("token stored in plain text")
+	}
+	if _, err := Lookup(store, token, g.ExpiresAt); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+	if _, err := Lookup(store, "bogus", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := Revoke(store, "fam2", g.ID, now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound revoking from another family, got %v", err)
+	}
+	if err := Revoke(store, "fam1", g.ID, now); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := Lookup(store, token, now); !errors.Is(err, ErrRevoked) {
+		t.Errorf("expected ErrRevoked, got %v", err)
+	}
+	list, _ := List(store, "fam1")
+	if len(list) != 1 || list[0].RevokedAt == nil {
+		t.Errorf("expected the guest to be listed as revoked, got %+v", list)
+	}
+
+	// The revocation list is purged along with the token
+	if n, _ := store.PurgeExpiredRecords(g.ExpiresAt); n != 2 {
+		t.Errorf("expected the token and its revocation to be purged, got %d records", n)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 14)
+	g := &Guest{FamilyID: "fam1", Scope: Scope{ProjectIDs: []string{"prj1"}, DueAfter: &from, DueBefore: &to}}
+	in := from.AddDate(0, 0, 3)
+	out := to.AddDate(0, 0, 1)
+	for _, tc := range []struct {
+		name string
+		r    *reminder.Reminder
+		want bool
+	}{
+		{"in scope", &reminder.Reminder{FamilyID: "fam1", ProjectID: "prj1", DueDate: &in}, true},
+		{"other family", &reminder.Reminder{FamilyID: "fam2", ProjectID: "prj1", DueDate: &in}, false},
+		{"other project", &reminder.Reminder{FamilyID: "fam1", ProjectID: "prj2", DueDate: &in}, false},
+		{"after the range", &reminder.Reminder{FamilyID: "fam1", ProjectID: "prj1", DueDate: &out}, false},
+		{"no due date", &reminder.Reminder{FamilyID: "fam1", ProjectID: "prj1"}, false},
+	} {
+		if got := g.Allows(tc.r); got != tc.want {
+			t.Errorf("%s: Allows = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+
+	g = &Guest{FamilyID: "fam1", Scope: Scope{Tags: []string{"pets", "plants"}}}
+	for _, tc := range []struct {
+		name string
+		tags []string
+		want bool
+	}{
+		{"one of the tags", []string{"kitchen", "plants"}, true},
+		{"other tags", []string{"kitchen"}, false},
+		{"untagged", nil, false},
+	} {
+		r := &reminder.Reminder{FamilyID: "fam1", Tags: tc.tags}
+		if got := g.Allows(r); got != tc.want {
+			t.Errorf("%s: Allows = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// AckReminderHandler handles POST /reminders/{id}/ack, recording that the
+// caller, or else the assignee, saw the reminder's notification. The
+// reminder itself is unchanged, but its notification no longer fails over
+// to the member's next channel, and the family's feed shows it as seen but
+// not done.
+func AckReminderHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	rem, err := requestStore(r).GetReminder(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("reminder not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	if rem.Completed {
+		errorHandler(w, r, fmt.Sprintf("reminder %s is already done", id), http.StatusConflict, nil)
+		return
+	}
+	by := requestActor(r)
+	if by == "" {
+		by = rem.FamilyMember
+	}
+	now := time.Now()
+	ack := reminder.Ack{ID: storage.NewRecordID("ack"), ReminderID: rem.ID, AckedBy: by, AckedAt: now}
+	if rem.DueDate != nil {
+		at := currentOccurrence(requestStore(r), rem)
+		ack.Occurrence = &at
+	}
+	rec := storage.Record{Kind: reminder.AckKind, ID: ack.ID, FamilyID: rem.FamilyID, Ref: rem.ID, CreatedAt: now}
+	if err := storage.PutJSON(requestStore(r), rec, ack); err != nil {
+		errorHandler(w, r, "failed to record acknowledgement", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ack)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
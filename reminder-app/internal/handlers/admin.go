@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/delivery"
+	"reminder-app/internal/fsck"
+	"reminder-app/internal/guest"
+	"reminder-app/internal/hook"
+	"reminder-app/internal/ical"
+	"reminder-app/internal/pitr"
+	"reminder-app/internal/project"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/share"
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/stats"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/suggest"
+	"reminder-app/internal/telemetry"
+	"reminder-app/internal/version"
+	"reminder-app/internal/webhook"
+)
+
+// AdminToken is the bearer token required by the /admin endpoints. Empty
+// disables them.
+var AdminToken string
+
+// authorizeAdmin reports whether r carries AdminToken, writing an error
+// response if it doesn't
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if AdminToken == "" {
+		errorHandler(w, r, "admin endpoints are not configured", http.StatusNotImplemented, nil)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(AdminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		errorHandler(w, r, "admin token required", http.StatusUnauthorized, nil)
+		return false
+	}
+	return true
+}
+
+// BackupHandler handles GET /admin/backup.sqlite, streaming a consistent
+// copy of the live SQLite database
+func BackupHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	db, ok := storage.Unwrap(Store).(*storage.SQLiteStorage)
+	if !ok {
+		errorHandler(w, r, "backups are only available with sqlite storage", http.StatusNotImplemented, nil)
+		return
+	}
+	name := fmt.Sprintf("reminders-%s.sqlite", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Cache-Control", "no-store")
+	// Once the copy starts streaming the status can't change, so a failure
+	// part way through only shows up as a truncated download and in the log
+	if err := db.Backup(w); err != nil {
+		errorHandler(w, r, "failed to back up database", http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// RestorePoints captures restore points of SQL storage for point-in-time
+// restores; nil unless configured
+var RestorePoints *pitr.Archiver
+
+// ListRestorePointsHandler handles GET /admin/restore-points, listing the
+// restore points kept, oldest first
+func ListRestorePointsHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if RestorePoints == nil {
+		errorHandler(w, r, "restore points are not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	points, err := pitr.List(requestStore(r))
+	if err != nil {
+		errorHandler(w, r, "failed to list restore points", http.StatusInternalServerError, err)
+		return
+	}
+	if points == nil {
+		points = []*pitr.Point{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(points)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// CreateRestorePointHandler handles POST /admin/restore-points?reason=,
+// capturing a restore point now, say before a risky maintenance task
+func CreateRestorePointHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if RestorePoints == nil {
+		errorHandler(w, r, "restore points are not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "requested"
+	}
+	p, err := RestorePoints.Capture(reason, time.Now())
+	if err != nil {
+		errorHandler(w, r, "failed to capture restore point", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
+
+// FsckHandler handles POST /admin/fsck, reporting inconsistencies in
+// storage. With ?repair=true they are also fixed.
+func FsckHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	repair := r.URL.Query().Get("repair") == "true"
+	report, err := fsck.Check(requestStore(r), repair)
+	if err != nil {
+		errorHandler(w, r, "failed to check storage", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// Telemetry collects the anonymous usage reports; they are only sent when
+// its Endpoint is set
+var Telemetry *telemetry.Reporter
+
+// TelemetryHandler handles GET /admin/telemetry, showing the report that is
+// or would be sent so operators can see exactly what telemetry shares
+func TelemetryHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	t := Telemetry
+	if t == nil {
+		t = telemetry.New(requestStore(r), "", "")
+	}
+	rep, err := t.Collect(time.Now())
+	if err != nil {
+		errorHandler(w, r, "failed to collect telemetry", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Enabled  bool              `json:"enabled"`
+		Endpoint string            `json:"endpoint,omitempty"`
+		Report   *telemetry.Report `json:"report"`
+	}{t.Endpoint != "", t.Endpoint, rep})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// Updates checks GitHub for new releases; nil when checks are disabled
+var Updates *version.Checker
+
+// UpdateStatusHandler handles GET /admin/update-status, reporting whether a
+// newer release is available as of the latest background check
+func UpdateStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	status := version.Status{Current: version.Version}
+	if Updates != nil {
+		status = Updates.Status()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Enabled bool `json:"enabled"`
+		version.Status
+	}{Updates != nil, status})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DualWrite mirrors writes to a second backend during a migration; nil
+// unless one is in progress
+var DualWrite *storage.DualWriteStorage
+
+// dualWriteKinds are the record kinds compared and synced between the
+// backends of a dual-write migration
+var dualWriteKinds = []string{
+	audit.Kind, stats.Kind, suggest.Kind, share.Kind, smartlist.Kind, project.Kind,
+	guest.Kind, guest.RevocationKind, hook.Kind, telemetry.Kind, ical.ImportKind,
+	webhook.Kind, webhook.DeliveryKind, webhook.QueueKind, reminder.StatusEventKind, IdempotencyKind,
+	delivery.Kind, delivery.QueueKind, reminder.AckKind,
+}
+
+// DualWriteHandler handles GET /admin/dual-write, comparing the primary
+// and secondary backends of a migration and listing the mirrored writes
+// that recently failed
+func DualWriteHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if DualWrite == nil {
+		errorHandler(w, r, "no dual-write migration is configured", http.StatusNotImplemented, nil)
+		return
+	}
+	report, err := DualWrite.Compare(dualWriteKinds)
+	if err != nil {
+		errorHandler(w, r, "failed to compare storage backends", http.StatusInternalServerError, err)
+		return
+	}
+	failures, mirrored := DualWrite.Failures()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		*storage.DualWriteReport
+		Mirrored int                     `json:"mirrored"`
+		Failures []storage.MirrorFailure `json:"failures"`
+	}{report, mirrored, failures})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DualWriteSyncHandler handles POST /admin/dual-write/sync, copying to the
+// secondary backend whatever it is missing, such as the data written
+// before the migration started
+func DualWriteSyncHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if DualWrite == nil {
+		errorHandler(w, r, "no dual-write migration is configured", http.StatusNotImplemented, nil)
+		return
+	}
+	repaired, err := DualWrite.Sync(dualWriteKinds)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("failed to sync storage backends after %d repairs", len(repaired)), http.StatusInternalServerError, err)
+		return
+	}
+	if repaired == nil {
+		repaired = []storage.Divergence{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Repaired []storage.Divergence `json:"repaired"`
+	}{repaired})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
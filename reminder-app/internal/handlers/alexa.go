@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/alexa"
+	"reminder-app/internal/audit"
+	"reminder-app/internal/events"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+var (
+	// AlexaVerifier validates incoming Alexa requests. Nil disables the skill
+	// endpoint.
+	AlexaVerifier *alexa.Verifier
+	// AlexaFamilyID is the family the skill acts on
+	AlexaFamilyID string
+)
+
+// Intent names defined in the skill's interaction model
+const (
+	intentListToday = "ListTodayIntent"
+	intentCreate    = "CreateReminderIntent"
+	intentComplete  = "CompleteReminderIntent"
+)
+
+// AlexaHandler handles POST /alexa, the HTTPS endpoint of the Alexa skill.
+// Every request is verified against Amazon's signature before any intent is
+// routed.
+func AlexaHandler(w http.ResponseWriter, r *http.Request) {
+	if AlexaVerifier == nil {
+		errorHandler(w, r, "alexa skill is not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		errorHandler(w, r, "failed to read request body", http.StatusBadRequest, err)
+		return
+	}
+	var env alexa.RequestEnvelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if err := AlexaVerifier.Verify(r, body, &env); err != nil {
+		errorHandler(w, r, "request verification failed", http.StatusBadRequest, err)
+		return
+	}
+
+	var resp *alexa.ResponseEnvelope
+	switch env.Request.Type {
+	case alexa.LaunchRequest:
+		resp = alexa.Ask("Welcome to family reminders. You can ask what's due today, add a reminder, or mark one as done.")
+	case alexa.IntentRequest:
+		resp = routeAlexaIntent(requestStore(r), env.Request.Intent, time.Now())
+	case alexa.SessionEndedRequest:
+		resp = &alexa.ResponseEnvelope{Version: "1.0"}
+	default:
+		resp = alexa.Say("Sorry, I can't handle that request.")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+	log.Printf("%s %s %s %d - alexa %s %s", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK, env.Request.Type, env.Request.Intent.Name)
+}
+
+// routeAlexaIntent dispatches an intent to its fulfillment function
+func routeAlexaIntent(s storage.Storage, intent alexa.Intent, now time.Time) *alexa.ResponseEnvelope {
+	switch intent.Name {
+	case intentListToday:
+		return alexaListToday(s, intent, now)
+	case intentCreate:
+		return alexaCreate(s, intent, now)
+	case intentComplete:
+		return alexaComplete(s, intent, now)
+	case "AMAZON.HelpIntent":
+		return alexa.Ask("Try saying: what's due today, remind Alice to feed the cat tomorrow at 5 pm, or mark feed the cat as done.")
+	case "AMAZON.CancelIntent", "AMAZON.StopIntent":
+		return alexa.Say("Goodbye.")
+	default:
+		return alexa.Ask("Sorry, I didn't get that. You can ask what's due today.")
+	}
+}
+
+func alexaListToday(s storage.Storage, intent alexa.Intent, now time.Time) *alexa.ResponseEnvelope {
+	member := intent.SlotValue("member")
+	list, err := s.ListReminders()
+	if err != nil {
+		log.Printf("alexa: failed to list reminders: %v", err)
+		return alexa.Say("Sorry, I couldn't load your reminders right now.")
+	}
+	pending := agenda.Pending(agenda.ForDay(list, now, AlexaFamilyID, matchMember(s, member)))
+	if len(pending) == 0 {
+		if member != "" {
+			return alexa.Say(fmt.Sprintf("%s has nothing left to do today.", member))
+		}
+		return alexa.Say("There's nothing left to do today.")
+	}
+	parts := make([]string, 0, len(pending))
+	for _, it := range pending {
+		part := fmt.Sprintf("%s at %s", it.Reminder.Title, it.At.Format("3:04 PM"))
+		if member == "" {
+			part += " for " + it.Reminder.FamilyMember
+		}
+		parts = append(parts, part)
+	}
+	noun := "reminders"
+	if len(pending) == 1 {
+		noun = "reminder"
+	}
+	return alexa.Say(fmt.Sprintf("You have %d %s left today: %s.", len(pending), noun, strings.Join(parts, "; ")))
+}
+
+func alexaCreate(s storage.Storage, intent alexa.Intent, now time.Time) *alexa.ResponseEnvelope {
+	title := intent.SlotValue("title")
+	if title == "" {
+		return alexa.Ask("What should I remind you about?")
+	}
+	family, err := s.GetFamily(AlexaFamilyID)
+	if err != nil {
+		log.Printf("alexa: family %s: %v", AlexaFamilyID, err)
+		return alexa.Say("Sorry, this skill isn't linked to a family yet.")
+	}
+	member := matchMember(s, intent.SlotValue("member"))
+	if member == "" && len(family.Members) == 1 {
+		member = family.Members[0]
+	}
+	if !hasMember(family, member) {
+		return alexa.Ask("Who is this reminder for?")
+	}
+
+	var due *time.Time
+	if d := intent.SlotValue("date"); d != "" {
+		day, err := time.ParseInLocation("2006-01-02", d, now.Location())
+		if err != nil {
+			return alexa.Ask("Sorry, I didn't understand the date. When is it due?")
+		}
+		at := time.Date(day.Year(), day.Month(), day.Day(), 9, 0, 0, 0, now.Location())
+		if tm := intent.SlotValue("time"); tm != "" {
+			clock, err := time.Parse("15:04", tm)
+			if err != nil {
+				return alexa.Ask("Sorry, I didn't understand the time. When is it due?")
+			}
+			at = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		}
+		due = &at
+	}
+
+	id, err := storage.GenerateReminderID(s)
+	if err != nil {
+		log.Printf("alexa: failed to create reminder: %v", err)
+		return alexa.Say("Sorry, I couldn't save that reminder.")
+	}
+	re := reminder.NewReminder(id, title, "", due, family.ID, member, reminder.RecurrencePattern{Type: "once"})
+	if err := s.CreateReminder(re); err != nil {
+		log.Printf("alexa: failed to create reminder: %v", err)
+		return alexa.Say("Sorry, I couldn't save that reminder.")
+	}
+	Events.Publish(events.ReminderCreated{Reminder: re, Actor: "alexa"})
+	if due != nil {
+		return alexa.Say(fmt.Sprintf("Okay, I'll remind %s to %s on %s.", member, title, due.Format("Monday, January 2 at 3:04 PM")))
+	}
+	return alexa.Say(fmt.Sprintf("Okay, I added %s for %s.", title, member))
+}
+
+func alexaComplete(s storage.Storage, intent alexa.Intent, now time.Time) *alexa.ResponseEnvelope {
+	title := intent.SlotValue("title")
+	if title == "" {
+		return alexa.Ask("Which reminder is done?")
+	}
+	list, err := s.ListReminders()
+	if err != nil {
+		log.Printf("alexa: failed to list reminders: %v", err)
+		return alexa.Say("Sorry, I couldn't load your reminders right now.")
+	}
+	rem := findReminderByTitle(list, title, matchMember(s, intent.SlotValue("member")), now)
+	if rem == nil {
+		return alexa.Say(fmt.Sprintf("I couldn't find an open reminder called %s.", title))
+	}
+
+	switch intent.ConfirmationStatus {
+	case alexa.ConfirmationDenied:
+		return alexa.Say("Okay, I'll leave it open.")
+	case alexa.ConfirmationConfirmed:
+		var (
+			before     json.RawMessage
+			completion *reminder.CompletionEvent
+			reply      *alexa.ResponseEnvelope
+			err        error
+		)
+		rem, err = updateReminder(s, rem.ID, func(s storage.Storage, rem *reminder.Reminder) error {
+			if existing, err := existingCompletion(s, rem, now); err != nil {
+				return fmt.Errorf("failed to check completions: %w", err)
+			} else if existing != nil {
+				reply = alexa.Say(fmt.Sprintf("%s was already marked done by %s.", rem.Title, existing.CompletedBy))
+				return errEditAbandoned
+			}
+			before = audit.Snapshot(rem)
+			var err error
+			completion, err = completeReminder(s, rem, rem.FamilyMember, "", now)
+			if errors.Is(err, errSameConfirmer) {
+				reply = alexa.Say(fmt.Sprintf("%s needs to be confirmed by someone other than %s.", rem.Title, rem.FamilyMember))
+				return errEditAbandoned
+			}
+			return err
+		})
+		if reply != nil {
+			return reply
+		} else if err != nil {
+			log.Printf("alexa: failed to complete %s: %v", title, err)
+			return alexa.Say("Sorry, I couldn't mark that as done.")
+		}
+		Events.Publish(reminderSaved(rem, before, completion, "alexa", ""))
+		if completion.Awaiting() {
+			return alexa.Say(fmt.Sprintf("Okay. %s now needs a second person to confirm it.", rem.Title))
+		}
+		return alexa.Say(fmt.Sprintf("Done. I marked %s as complete.", rem.Title))
+	default:
+		return alexa.ConfirmIntent(fmt.Sprintf("Mark %s for %s as done?", rem.Title, rem.FamilyMember), intent)
+	}
+}
+
+// matchMember maps a spoken member name onto the family's spelling
+func matchMember(s storage.Storage, spoken string) string {
+	if spoken == "" {
+		return ""
+	}
+	if family, err := s.GetFamily(AlexaFamilyID); err == nil {
+		for _, m := range family.Members {
+			if strings.EqualFold(m, spoken) {
+				return m
+			}
+		}
+	}
+	return spoken
+}
+
+// findReminderByTitle picks the open reminder best matching a spoken title,
+// preferring exact matches and reminders due today
+func findReminderByTitle(list []*reminder.Reminder, title, member string, now time.Time) *reminder.Reminder {
+	title = strings.ToLower(title)
+	today := agenda.Pending(agenda.ForDay(list, now, AlexaFamilyID, member))
+	var candidates []*reminder.Reminder
+	for _, it := range today {
+		candidates = append(candidates, it.Reminder)
+	}
+	for _, r := range list {
+		if r.FamilyID == AlexaFamilyID && !r.Completed && (member == "" || r.FamilyMember == member) {
+			candidates = append(candidates, r)
+		}
+	}
+	for _, r := range candidates {
+		if strings.ToLower(r.Title) == title {
+			return r
+		}
+	}
+	for _, r := range candidates {
+		if strings.Contains(strings.ToLower(r.Title), title) {
+			return r
+		}
+	}
+	return nil
+}
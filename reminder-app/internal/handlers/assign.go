@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// validateAssignment returns an error message if the strategy is unknown
+func validateAssignment(strategy string) string {
+	switch strategy {
+	case "", reminder.AssignRoundRobin, reminder.AssignLeastLoaded:
+		return ""
+	}
+	return fmt.Sprintf("invalid assignment strategy: %s", strategy)
+}
+
+// validateRotation returns an error message if an optional rotation is
+// invalid for a reminder with the given family, pattern, due date and
+// assignment strategy. Rotations take turns by occurrence, so they need a
+// recurring reminder with a due date, and replace assignment strategies.
+func validateRotation(s storage.Storage, familyID string, rot *reminder.Rotation, rp reminder.RecurrencePattern, dueDate *time.Time, strategy string) (string, error) {
+	if rot == nil {
+		return "", nil
+	}
+	if err := rot.Validate(); err != nil {
+		return fmt.Sprintf("invalid rotation: %v", err), err
+	}
+	if rp.Type == "once" || dueDate == nil {
+		return "rotation requires a recurring reminder with a due date", nil
+	}
+	if strategy != "" {
+		return "rotation and assignment are mutually exclusive", nil
+	}
+	f, err := s.GetFamily(familyID)
+	if err != nil {
+		return fmt.Sprintf("family not found: %s", familyID), err
+	}
+	for _, m := range rot.Members {
+		if !hasMember(f, m) {
+			return fmt.Sprintf("rotation member not found: %s", m), nil
+		}
+	}
+	return "", nil
+}
+
+// currentOccurrence returns when r's current occurrence is due: the one
+// after its last completion for recurring reminders, otherwise its due date,
+// or now if it has neither
+func currentOccurrence(s storage.Storage, r *reminder.Reminder) time.Time {
+	if r.CompletedAt != nil && r.IsRecurring() {
+		if next := r.NextOccurrence(scheduleTime(s, r, *r.CompletedAt)); next != nil {
+			return *next
+		}
+	}
+	if r.DueDate != nil {
+		return *r.DueDate
+	}
+	return time.Now()
+}
+
+// assignMember sets the assignee of r for its occurrence at the given time
+// according to r's rotation or assignment strategy. The caller is
+// responsible for saving r.
+func assignMember(s storage.Storage, r *reminder.Reminder, at time.Time) error {
+	if r.Rotation != nil {
+		r.FamilyMember = r.AssigneeAt(at)
+		return nil
+	}
+	if r.Assignment == "" {
+		return nil
+	}
+	f, err := s.GetFamily(r.FamilyID)
+	if err != nil {
+		return err
+	}
+	if len(f.Members) == 0 {
+		return nil
+	}
+	switch r.Assignment {
+	case reminder.AssignRoundRobin:
+		r.FamilyMember = nextMember(f.Members, r.FamilyMember)
+	case reminder.AssignLeastLoaded:
+		list, err := s.ListReminders()
+		if err != nil {
+			return err
+		}
+		loc, err := f.LocationFor("")
+		if err != nil {
+			return err
+		}
+		r.FamilyMember = agenda.LeastLoaded(list, at.In(loc), f.ID, f.Members, r.ID)
+	}
+	return nil
+}
+
+// nextMember returns the member after current, wrapping around. The first
+// member is returned if current isn't in the list.
+func nextMember(members []string, current string) string {
+	for i, m := range members {
+		if m == current {
+			return members[(i+1)%len(members)]
+		}
+	}
+	return members[0]
+}
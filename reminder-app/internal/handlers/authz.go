@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+)
+
+// authorize reports whether the member named by ActorHeader may take action
+// p in family f, writing a 401 or 403 if not. rem is the reminder being
+// acted on, if any, since children may only complete their own. Families
+// that haven't assigned any roles are open to every caller. Callers acting
+// as another member are checked with their own role, and must be able to
+// manage the family. Guests, whose scope has already been checked, may only
+// complete reminders.
+func authorize(w http.ResponseWriter, r *http.Request, f *fam.Family, p fam.Permission, rem *reminder.Reminder) bool {
+	if g := requestGuest(r); g != nil {
+		if !g.Can(p) || g.FamilyID != f.ID {
+			errorHandler(w, r, fmt.Sprintf("guests cannot %s", p), http.StatusForbidden, nil)
+			return false
+		}
+		return true
+	}
+	if r.Header.Get(ActAsHeader) != "" && !authorizeActAs(w, r, f) {
+		return false
+	}
+	if !f.HasRoles() {
+		return true
+	}
+	actor := r.Header.Get(ActorHeader)
+	if actor == "" {
+		errorHandler(w, r, fmt.Sprintf("%s header is required", ActorHeader), http.StatusUnauthorized, nil)
+		return false
+	}
+	role := f.RoleOf(actor)
+	if role == "" {
+		errorHandler(w, r, fmt.Sprintf("%s is not a member of family %s", actor, f.ID), http.StatusForbidden, nil)
+		return false
+	}
+	if !role.Can(p) {
+		errorHandler(w, r, fmt.Sprintf("%s role cannot %s", role, p), http.StatusForbidden, nil)
+		return false
+	}
+	if role == fam.RoleChild && rem != nil && rem.FamilyMember != actor {
+		errorHandler(w, r, "children can only complete their own reminders", http.StatusForbidden, nil)
+		return false
+	}
+	return true
+}
+
+// authorizeActAs checks that the caller may act as the member named by
+// ActAsHeader in family f, writing an error if not
+func authorizeActAs(w http.ResponseWriter, r *http.Request, f *fam.Family) bool {
+	member, admin := r.Header.Get(ActAsHeader), r.Header.Get(ActorHeader)
+	if admin == "" {
+		errorHandler(w, r, fmt.Sprintf("%s header is required with %s", ActorHeader, ActAsHeader), http.StatusUnauthorized, nil)
+		return false
+	}
+	if !slices.Contains(f.Members, member) {
+		errorHandler(w, r, fmt.Sprintf("cannot act as %s: not a member of family %s", member, f.ID), http.StatusBadRequest, nil)
+		return false
+	}
+	if !permitted(f, admin, fam.PermManage) {
+		errorHandler(w, r, fmt.Sprintf("%s cannot act as other members", admin), http.StatusForbidden, nil)
+		return false
+	}
+	return true
+}
+
+// permitted reports whether actor may take action p in family f, like
+// authorize without the reminder check
+func permitted(f *fam.Family, actor string, p fam.Permission) bool {
+	return !f.HasRoles() || f.RoleOf(actor).Can(p)
+}
+
+// authorizeFamily is authorize for a family given by ID. A family that
+// doesn't exist is left for the handler to report.
+func authorizeFamily(w http.ResponseWriter, r *http.Request, familyID string, p fam.Permission) bool {
+	f, err := requestStore(r).GetFamily(familyID)
+	if err != nil {
+		return true
+	}
+	return authorize(w, r, f, p, nil)
+}
+
+// authorizeReminder is authorize for an action on an existing reminder
+func authorizeReminder(w http.ResponseWriter, r *http.Request, rem *reminder.Reminder, p fam.Permission) bool {
+	f, err := requestStore(r).GetFamily(rem.FamilyID)
+	if err != nil {
+		return true
+	}
+	return authorize(w, r, f, p, rem)
+}
+
+// authorizeRecord is authorize for the family owning a stored record, such
+// as a share or a smart list. A missing record is left for the handler to
+// report.
+func authorizeRecord(w http.ResponseWriter, r *http.Request, kind, id string, p fam.Permission) bool {
+	rec, err := requestStore(r).GetRecord(kind, id)
+	if err != nil {
+		return true
+	}
+	return authorizeFamily(w, r, rec.FamilyID, p)
+}
+
+// validateRoles checks the roles in a family's new settings. Once roles are
+// in use someone must remain able to manage the family.
+func validateRoles(settings fam.Settings) string {
+	owners, roles := 0, 0
+	for member, ms := range settings.Members {
+		if ms.Role == "" {
+			continue
+		}
+		if !ms.Role.Valid() {
+			return fmt.Sprintf("unknown role for %s: %q", member, ms.Role)
+		}
+		roles++
+		if ms.Role == fam.RoleOwner {
+			owners++
+		}
+	}
+	if roles > 0 && owners == 0 {
+		return "at least one member must be an owner"
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/avatar"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// maxAvatarUpload bounds the size of an uploaded picture
+const maxAvatarUpload = 10 << 20
+
+// avatarID returns the blob ID of the avatar of a family, or of one of its
+// members if member isn't empty
+func avatarID(familyID, member string) string {
+	if member == "" {
+		return "avatar/" + familyID
+	}
+	return "avatar/" + familyID + "/" + member
+}
+
+// avatarTarget returns the blob ID an avatar request acts on, writing an
+// error response if the family or member doesn't exist
+func avatarTarget(w http.ResponseWriter, r *http.Request) (string, bool) {
+	vars := mux.Vars(r)
+	f, err := requestStore(r).GetFamily(vars["id"])
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", vars["id"]), http.StatusNotFound, err)
+		return "", false
+	}
+	if name, ok := vars["name"]; ok && !hasMember(f, name) {
+		errorHandler(w, r, fmt.Sprintf("family member not found: %s", name), http.StatusNotFound, nil)
+		return "", false
+	}
+	return avatarID(f.ID, vars["name"]), true
+}
+
+// PutAvatarHandler handles PUT /families/{id}/avatar and PUT
+// /families/{id}/members/{name}/avatar. The picture is sent as the request
+// body or as the "file" field of a multipart form, and stored cropped and
+// scaled to a square PNG.
+func PutAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := avatarTarget(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarUpload)
+	var body io.Reader = r.Body
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			errorHandler(w, r, "file is required", http.StatusBadRequest, err)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	data, err := avatar.Make(body)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		errorHandler(w, r, err.Error(), status, err)
+		return
+	}
+	b, err := storage.PutBlob(requestStore(r), id, mux.Vars(r)["id"], avatar.ContentType, data, time.Now())
+	if err != nil {
+		errorHandler(w, r, "failed to store avatar", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("ETag", b.ETag)
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
+
+// GetAvatarHandler handles GET /families/{id}/avatar and GET
+// /families/{id}/members/{name}/avatar. Avatars carry an ETag and must be
+// revalidated, so a changed picture shows up at once while unchanged ones
+// are answered with 304 Not Modified.
+func GetAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := avatarTarget(w, r)
+	if !ok {
+		return
+	}
+	b, err := storage.GetBlob(requestStore(r), id)
+	if errors.Is(err, storage.ErrRecordNotFound) {
+		errorHandler(w, r, "no avatar", http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		errorHandler(w, r, "failed to load avatar", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", b.ContentType)
+	w.Header().Set("ETag", b.ETag)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "", b.UpdatedAt, bytes.NewReader(b.Data))
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DeleteAvatarHandler handles DELETE /families/{id}/avatar and DELETE
+// /families/{id}/members/{name}/avatar
+func DeleteAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := avatarTarget(w, r)
+	if !ok {
+		return
+	}
+	if err := storage.DeleteBlob(requestStore(r), id); errors.Is(err, storage.ErrRecordNotFound) {
+		errorHandler(w, r, "no avatar", http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		errorHandler(w, r, "failed to delete avatar", http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
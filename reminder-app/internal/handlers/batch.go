@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/events"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// maxBatchSize is the most reminders one batch request may contain
+const maxBatchSize = 500
+
+// batchCreateRequest is the body accepted by POST /reminders/batch. Every
+// reminder belongs to the batch's family; their family_id may be left out.
+type batchCreateRequest struct {
+	FamilyID  string            `json:"family_id"`
+	Reminders []reminderRequest `json:"reminders"`
+}
+
+// batchUpdateRequest is the body accepted by PATCH /reminders/batch. Each
+// entry is a JSON merge patch naming the reminder it applies to by id and the
+// version it was edited from.
+type batchUpdateRequest struct {
+	FamilyID  string            `json:"family_id"`
+	Reminders []json.RawMessage `json:"reminders"`
+}
+
+// checkBatch validates the size of a batch and returns an error message if
+// it is unacceptable
+func checkBatch(familyID string, n int) string {
+	switch {
+	case familyID == "":
+		return "family_id is required"
+	case n == 0:
+		return "reminders is required"
+	case n > maxBatchSize:
+		return fmt.Sprintf("a batch may contain at most %d reminders", maxBatchSize)
+	}
+	return ""
+}
+
+// CreateRemindersBatchHandler handles POST /reminders/batch, creating many
+// reminders in one family at once. Every reminder is validated before any is
+// saved, and they are saved in one operation where the storage backend
+// supports it, so a seed of a new school year's chores either fully lands or
+// doesn't. New reminders go to the end of the family in the order given.
+func CreateRemindersBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var req batchCreateRequest
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest, err)
+		return
+	}
+	if msg := checkBatch(req.FamilyID, len(req.Reminders)); msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, nil)
+		return
+	}
+
+	s := requestStore(r)
+	position, err := nextPosition(s, req.FamilyID)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	list := make([]*reminder.Reminder, 0, len(req.Reminders))
+	for i := range req.Reminders {
+		item := &req.Reminders[i]
+		if item.FamilyID == "" {
+			item.FamilyID = req.FamilyID
+		} else if item.FamilyID != req.FamilyID {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: family_id must be %s", i, req.FamilyID), http.StatusBadRequest, nil)
+			return
+		}
+		dueDate, msg, err := item.validate(s)
+		if msg != "" {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: %s", i, msg), http.StatusBadRequest, err)
+			return
+		}
+		re, msg, err := newReminder(s, item, dueDate)
+		if msg != "" {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: %s", i, msg), http.StatusInternalServerError, err)
+			return
+		}
+		re.Position = position + i
+		list = append(list, re)
+	}
+
+	n, err := storage.SaveReminders(s, list)
+	actor, impersonator := requestActor(r), requestImpersonator(r)
+	for _, re := range list[:n] {
+		Events.Publish(events.ReminderCreated{Reminder: re, Actor: actor, Impersonator: impersonator})
+	}
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("failed to create reminders; %d of %d were created", n, len(list)), http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d - created %d reminders", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated, n)
+}
+
+// UpdateRemindersBatchHandler handles PATCH /reminders/batch, applying a
+// merge patch to each of many reminders in one family. Each patch carries the
+// version of the reminder it was made against, and the batch is refused with
+// 409 if any reminder has moved on since. All patches are validated before
+// any reminder is saved, as by PATCH /reminders/{id}. Completing or reopening
+// reminders isn't supported here, since completions record events one
+// reminder at a time.
+func UpdateRemindersBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var req batchUpdateRequest
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest, err)
+		return
+	}
+	if msg := checkBatch(req.FamilyID, len(req.Reminders)); msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, nil)
+		return
+	}
+
+	type target struct {
+		ID      string `json:"id"`
+		Version *int   `json:"version"`
+	}
+	targets := make([]target, len(req.Reminders))
+	ids := make([]string, len(req.Reminders))
+	seen := make(map[string]bool, len(req.Reminders))
+	for i, patch := range req.Reminders {
+		if err := json.Unmarshal(patch, &targets[i]); err != nil || targets[i].ID == "" {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: an object with an id is required", i), http.StatusBadRequest, err)
+			return
+		}
+		if targets[i].Version == nil {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: version is required", i), http.StatusBadRequest, nil)
+			return
+		}
+		id := targets[i].ID
+		if seen[id] {
+			errorHandler(w, r, fmt.Sprintf("duplicate reminder id: %s", id), http.StatusBadRequest, nil)
+			return
+		}
+		seen[id] = true
+		ids[i] = id
+	}
+
+	defer lockReminders(ids)()
+	s := storage.ReadPrimary(requestStore(r))
+	list := make([]*reminder.Reminder, 0, len(req.Reminders))
+	befores := make([]json.RawMessage, 0, len(req.Reminders))
+	for i, patch := range req.Reminders {
+		rem, err := s.GetReminder(targets[i].ID)
+		if err != nil || rem.FamilyID != req.FamilyID {
+			errorHandler(w, r, fmt.Sprintf("reminder %s not found in family %s", targets[i].ID, req.FamilyID), http.StatusBadRequest, err)
+			return
+		}
+		if *targets[i].Version != rem.Version {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: version conflict: reminder %s is at version %d", i, rem.ID, rem.Version), http.StatusConflict, nil)
+			return
+		}
+		patched, err := applyReminderPatch(rem, mergePatchContentType, patch)
+		if err != nil {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: invalid patch", i), http.StatusBadRequest, err)
+			return
+		}
+		var doc reminderDocument
+		if err := decodeJSON(r, bytes.NewReader(patched), &doc); err != nil {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: invalid patch: %v", i, err), http.StatusBadRequest, err)
+			return
+		}
+		if doc.Completed != rem.Completed {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: completed can't be changed in a batch; use PATCH /reminders/%s", i, rem.ID), http.StatusBadRequest, nil)
+			return
+		}
+		dueDate, msg, err := validateDocument(s, rem, &doc)
+		if msg != "" {
+			errorHandler(w, r, fmt.Sprintf("reminders[%d]: %s", i, msg), http.StatusBadRequest, err)
+			return
+		}
+		befores = append(befores, audit.Snapshot(rem))
+		// Some backends hand out the stored reminder itself, which must stay
+		// untouched if a later patch is rejected
+		updated := rem.Clone()
+		applyDocument(s, updated, doc, dueDate)
+		updated.Version++
+		list = append(list, updated)
+	}
+
+	n, err := storage.UpdateReminders(s, list...)
+	actor, impersonator := requestActor(r), requestImpersonator(r)
+	for i, rem := range list[:n] {
+		Events.Publish(events.ReminderUpdated{Reminder: rem, Before: befores[i], Actor: actor, Impersonator: impersonator})
+	}
+	if errors.Is(err, storage.ErrVersionConflict) {
+		errorHandler(w, r, fmt.Sprintf("reminders were changed by another request; %d of %d were updated", n, len(list)), http.StatusConflict, err)
+		return
+	} else if err != nil {
+		errorHandler(w, r, fmt.Sprintf("failed to update reminders; %d of %d were updated", n, len(list)), http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d - updated %d reminders", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK, n)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/ical"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// maxCalendarSize bounds the size of an uploaded calendar
+const maxCalendarSize = 8 << 20
+
+// skippedEntry is a calendar entry that wasn't imported
+type skippedEntry struct {
+	UID     string `json:"uid,omitempty"`
+	Summary string `json:"summary"`
+	Reason  string `json:"reason"`
+}
+
+// calendarImport is the response of POST /families/{id}/import/ics
+type calendarImport struct {
+	Created []*reminder.Reminder `json:"created"`
+	Skipped []skippedEntry       `json:"skipped"`
+}
+
+// ImportCalendarHandler handles POST /families/{id}/import/ics, creating a
+// reminder from each event and to-do of an uploaded .ics file, sent either
+// as the request body or as the "file" field of a multipart form. Reminders
+// go to ?member= or, with ?assignment=, are assigned by that strategy.
+// Entries imported before, cancelled or completed entries, and recurrences
+// that RecurrencePattern can't express are skipped and listed with the
+// reason.
+func ImportCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	q := r.URL.Query()
+	member, assignment := q.Get("member"), q.Get("assignment")
+	if member == "" && assignment == "" {
+		errorHandler(w, r, "member or assignment is required", http.StatusBadRequest, nil)
+		return
+	}
+	loc, err := f.LocationFor(member)
+	if err != nil {
+		loc = time.Local
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxCalendarSize)
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "multipart/form-data" {
+		r.Body = http.MaxBytesReader(w, r.Body, maxCalendarSize)
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			errorHandler(w, r, "file is required", http.StatusBadRequest, err)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	entries, err := ical.Parse(body, loc)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("invalid calendar: %v", err), http.StatusBadRequest, err)
+		return
+	}
+
+	actor := requestActor(r)
+	result := calendarImport{Created: []*reminder.Reminder{}, Skipped: []skippedEntry{}}
+	for _, e := range entries {
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, skippedEntry{UID: e.UID, Summary: e.Summary, Reason: reason})
+		}
+		if e.Status == "CANCELLED" || e.Status == "COMPLETED" {
+			skip(fmt.Sprintf("%s is %s", e.Kind, e.Status))
+			continue
+		}
+		if e.Unsupported != "" {
+			skip(e.Unsupported)
+			continue
+		}
+		if e.UID != "" {
+			rec, err := requestStore(r).GetRecord(ical.ImportKind, ical.ImportID(id, e.UID))
+			if err == nil {
+				if _, err := requestStore(r).GetReminder(rec.Ref); err == nil {
+					skip(fmt.Sprintf("already imported as %s", rec.Ref))
+					continue
+				}
+			} else if !errors.Is(err, storage.ErrRecordNotFound) {
+				errorHandler(w, r, "failed to look up imported entries", http.StatusInternalServerError, err)
+				return
+			}
+		}
+
+		req := reminderRequest{
+			Title:        e.Summary,
+			Description:  e.Description,
+			FamilyID:     id,
+			FamilyMember: member,
+			Recurrence:   e.Recurrence,
+			Assignment:   assignment,
+		}
+		if req.Title == "" {
+			req.Title = "Untitled"
+		}
+		if e.Due != nil {
+			req.DueDate = e.Due.Format(time.RFC3339)
+		}
+		dueDate, msg, err := req.validate(requestStore(r))
+		if msg != "" {
+			// The options are the same for every entry, so a bad member or
+			// strategy fails the whole import
+			errorHandler(w, r, msg, http.StatusBadRequest, err)
+			return
+		}
+		re, msg, err := insertReminder(requestStore(r), &req, dueDate, actor, requestImpersonator(r))
+		if msg != "" {
+			errorHandler(w, r, msg, http.StatusInternalServerError, err)
+			return
+		}
+		if e.UID != "" {
+			rec := storage.Record{Kind: ical.ImportKind, ID: ical.ImportID(id, e.UID), FamilyID: id, Ref: re.ID}
+			if err := storage.PutJSON(requestStore(r), rec, map[string]string{"uid": e.UID}); err != nil {
+				errorHandler(w, r, "failed to record imported entry", http.StatusInternalServerError, err)
+				return
+			}
+		}
+		result.Created = append(result.Created, re)
+	}
+
+	status := http.StatusOK
+	if len(result.Created) > 0 {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), status)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"reminder-app/internal/events"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// ListCacheTTL is how long GET /reminders answers identical queries from
+// the result of an earlier one. Zero only coalesces queries that run at
+// the same time.
+var ListCacheTTL = time.Second
+
+// listCall is one storage query shared by every identical GET /reminders
+// made while it runs and, once it finished, until it expires
+type listCall struct {
+	done     chan struct{}
+	familyID string
+	list     []*reminder.Reminder
+	err      error
+	// finished and expires are guarded by listCalls
+	finished bool
+	expires  time.Time
+}
+
+// listCalls holds the queries of GET /reminders by filter. A family's
+// entries are dropped by invalidateReminderLists whenever any of its data
+// changes, so devices polling in sync share one query without seeing
+// stale lists after an edit.
+var listCalls = struct {
+	sync.Mutex
+	calls map[string]*listCall
+}{calls: make(map[string]*listCall)}
+
+// queryReminders returns the reminders selected by f, joining an identical
+// query that is running or was answered within ListCacheTTL
+func queryReminders(r *http.Request, f storage.ReminderFilter) ([]*reminder.Reminder, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	key := string(data)
+	now := time.Now()
+
+	listCalls.Lock()
+	if c := listCalls.calls[key]; c != nil && (!c.finished || now.Before(c.expires)) {
+		listCalls.Unlock()
+		select {
+		case <-c.done:
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+		// A query cut short by its own request's deadline says nothing
+		// about this one's
+		if errors.Is(c.err, context.DeadlineExceeded) || errors.Is(c.err, context.Canceled) {
+			return requestStore(r).QueryReminders(f)
+		}
+		return c.list, c.err
+	}
+	for k, c := range listCalls.calls {
+		if c.finished && !now.Before(c.expires) {
+			delete(listCalls.calls, k)
+		}
+	}
+	c := &listCall{done: make(chan struct{}), familyID: f.FamilyID}
+	listCalls.calls[key] = c
+	listCalls.Unlock()
+
+	c.list, c.err = requestStore(r).QueryReminders(f)
+
+	listCalls.Lock()
+	c.finished = true
+	c.expires = time.Now().Add(ListCacheTTL)
+	if c.err != nil && listCalls.calls[key] == c {
+		delete(listCalls.calls, key)
+	}
+	listCalls.Unlock()
+	close(c.done)
+	return c.list, c.err
+}
+
+// invalidateReminderLists subscribes to Events to drop the queries that
+// may include the family an event belongs to. Queries still running
+// answer the requests already waiting for them but aren't reused.
+func invalidateReminderLists(e events.Event) {
+	listCalls.Lock()
+	defer listCalls.Unlock()
+	for k, c := range listCalls.calls {
+		if c.familyID == "" || c.familyID == e.FamilyID() {
+			delete(listCalls.calls, k)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/dashboard"
+
+	"github.com/gorilla/mux"
+)
+
+// Default dashboard size, matching common 7.5" e-ink panels
+const (
+	defaultDashboardWidth  = 800
+	defaultDashboardHeight = 480
+)
+
+// dashboardSize reads the width and height query parameters
+func dashboardSize(r *http.Request) (int, int, error) {
+	width, height := defaultDashboardWidth, defaultDashboardHeight
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"width", &width}, {"height", &height}} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < dashboard.MinSize || n > dashboard.MaxSize {
+			return 0, 0, fmt.Errorf("%s must be between %d and %d", p.name, dashboard.MinSize, dashboard.MaxSize)
+		}
+		*p.dst = n
+	}
+	return width, height, nil
+}
+
+// FamilyDashboardHandler handles GET /families/{id}/dashboard.png, rendering
+// today's reminders as a grayscale PNG for e-ink displays. Optional
+// parameters are width and height in pixels and member to show one member's
+// day in their time zone.
+func FamilyDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	width, height, err := dashboardSize(r)
+	if err != nil {
+		errorHandler(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	member := r.URL.Query().Get("member")
+	if member != "" && !hasMember(f, member) {
+		errorHandler(w, r, fmt.Sprintf("family member not found: %s", member), http.StatusBadRequest, nil)
+		return
+	}
+	loc, err := f.LocationFor(member)
+	if err != nil {
+		errorHandler(w, r, "invalid time zone", http.StatusInternalServerError, err)
+		return
+	}
+	list, err := requestStore(r).ListReminders()
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now().In(loc)
+	title := f.Name
+	if member != "" {
+		title = member
+	}
+	img, err := dashboard.Render(title, now, agenda.ForDay(list, now, f.ID, member), loc, width, height)
+	if err != nil {
+		errorHandler(w, r, "failed to render dashboard", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	png.Encode(w, img)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
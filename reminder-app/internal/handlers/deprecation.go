@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Deprecation describes an endpoint that is being phased out
+type Deprecation struct {
+	// Since is when the endpoint was deprecated
+	Since time.Time
+	// Sunset is when the endpoint will be removed; zero until scheduled
+	Sunset time.Time
+	// Successor is the route replacing the endpoint. Variables such as
+	// {id} are filled in from the request.
+	Successor string
+	// Message is sent to callers in the Warning header
+	Message string
+}
+
+var (
+	// Deprecations lists deprecated endpoints keyed by method and route
+	// template, e.g. "GET /reminders/{id}". Calls to them are answered with
+	// Deprecation, Sunset, Link and Warning headers and counted.
+	Deprecations = map[string]Deprecation{}
+
+	// DisableDeprecated answers calls to deprecated endpoints with 410 Gone,
+	// to find remaining callers before a sunset or to enforce it. It can be
+	// changed while serving.
+	DisableDeprecated atomic.Bool
+)
+
+// deprecationUsage counts the calls to one deprecated endpoint
+type deprecationUsage struct {
+	Calls         int64     `json:"calls"`
+	Rejected      int64     `json:"rejected"`
+	LastUsed      time.Time `json:"last_used"`
+	LastUserAgent string    `json:"last_user_agent,omitempty"`
+}
+
+var (
+	deprecationMu   sync.Mutex
+	deprecatedCalls = map[string]*deprecationUsage{}
+)
+
+// recordDeprecatedCall counts a call to the deprecated endpoint key
+func recordDeprecatedCall(key string, r *http.Request, rejected bool) {
+	deprecationMu.Lock()
+	defer deprecationMu.Unlock()
+	u := deprecatedCalls[key]
+	if u == nil {
+		u = &deprecationUsage{}
+		deprecatedCalls[key] = u
+	}
+	u.Calls++
+	if rejected {
+		u.Rejected++
+	}
+	u.LastUsed = time.Now().UTC()
+	u.LastUserAgent = r.UserAgent()
+}
+
+// DeprecationMiddleware marks responses of deprecated endpoints as such and
+// refuses them when DisableDeprecated is set. It must run after routing.
+func DeprecationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil || len(Deprecations) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := r.Method + " " + tmpl
+		d, ok := Deprecations[key]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		// Structured date as in RFC 9745
+		h.Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+		if !d.Sunset.IsZero() {
+			h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != "" {
+			h.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, expandRoute(d.Successor, mux.Vars(r))))
+		}
+		msg := d.Message
+		if msg == "" {
+			msg = fmt.Sprintf("%s is deprecated", key)
+		}
+		h.Set("Warning", fmt.Sprintf(`299 - %q`, msg))
+		recordDeprecatedCall(key, r, DisableDeprecated.Load())
+		if DisableDeprecated.Load() {
+			errorHandler(w, r, fmt.Sprintf("%s has been removed", key), http.StatusGone, nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// expandRoute fills the variables of a route template
+func expandRoute(tmpl string, vars map[string]string) string {
+	for k, v := range vars {
+		tmpl = strings.ReplaceAll(tmpl, "{"+k+"}", v)
+	}
+	return tmpl
+}
+
+// deprecationReport is one entry of GET /admin/deprecations
+type deprecationReport struct {
+	Endpoint  string           `json:"endpoint"`
+	Since     time.Time        `json:"since"`
+	Sunset    *time.Time       `json:"sunset,omitempty"`
+	Successor string           `json:"successor,omitempty"`
+	Usage     deprecationUsage `json:"usage"`
+}
+
+// DeprecationsHandler handles GET /admin/deprecations, listing deprecated
+// endpoints with how often and how recently they were called since the
+// server started, to tell when a sunset can go ahead
+func DeprecationsHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	list := make([]deprecationReport, 0, len(Deprecations))
+	deprecationMu.Lock()
+	for key, d := range Deprecations {
+		e := deprecationReport{Endpoint: key, Since: d.Since, Successor: d.Successor}
+		if !d.Sunset.IsZero() {
+			e.Sunset = &d.Sunset
+		}
+		if u := deprecatedCalls[key]; u != nil {
+			e.Usage = *u
+		}
+		list = append(list, e)
+	}
+	deprecationMu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Endpoint < list[j].Endpoint })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/digest"
+	fam "reminder-app/internal/family"
+)
+
+// DigestPreviewHandler handles GET /digest/preview?family_id=&member=,
+// rendering the digest the member would be sent right now so it can be
+// checked before opting in. The member defaults to the caller and the
+// frequency= to the member's digest settings, or daily. The email's HTML is
+// returned unless format= asks for its text or the digest as json.
+func DigestPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	id := q.Get("family_id")
+	if id == "" {
+		errorHandler(w, r, "family_id is required", http.StatusBadRequest, nil)
+		return
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "text" && format != "json" {
+		errorHandler(w, r, "format must be html, text or json", http.StatusBadRequest, nil)
+		return
+	}
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	member := q.Get("member")
+	if member == "" {
+		member = requestActor(r)
+	}
+	if member == "" {
+		errorHandler(w, r, "member is required", http.StatusBadRequest, nil)
+		return
+	}
+	if !hasMember(f, member) {
+		errorHandler(w, r, fmt.Sprintf("member not found: %s", member), http.StatusBadRequest, nil)
+		return
+	}
+	frequency := q.Get("frequency")
+	if frequency == "" {
+		frequency = fam.DigestDaily
+		if d := f.Settings.Members[member].Digest; d != nil {
+			frequency = d.Period()
+		}
+	}
+	if frequency != fam.DigestDaily && frequency != fam.DigestWeekly {
+		errorHandler(w, r, "frequency must be daily or weekly", http.StatusBadRequest, nil)
+		return
+	}
+
+	d, err := digest.Build(requestStore(r), f, member, frequency, time.Now())
+	if err != nil {
+		errorHandler(w, r, "failed to build digest", http.StatusInternalServerError, err)
+		return
+	}
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, d.Text())
+	default:
+		html, err := d.HTML()
+		if err != nil {
+			errorHandler(w, r, "failed to render digest", http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, html)
+	}
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
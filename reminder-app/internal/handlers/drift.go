@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/drift"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// reminderDrift analyzes the completions of a reminder for drift
+func reminderDrift(s storage.Storage, r *reminder.Reminder) (*drift.Report, error) {
+	events, err := s.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: r.ID})
+	if err != nil {
+		return nil, err
+	}
+	return drift.Analyze(r, events), nil
+}
+
+// FamilyDriftHandler handles GET /families/{id}/drift, reporting the
+// recurring reminders of the family that are consistently completed early or
+// late along with the schedule that would fit them
+func FamilyDriftHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	list, err := requestStore(r).QueryReminders(storage.ReminderFilter{FamilyID: id})
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	result := []*drift.Report{}
+	for _, rem := range list {
+		rep, err := reminderDrift(requestStore(r), rem)
+		if err != nil {
+			errorHandler(w, r, "failed to query completion events", http.StatusInternalServerError, err)
+			return
+		}
+		if rep != nil {
+			result = append(result, rep)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// AdjustScheduleHandler handles POST /reminders/{id}/adjust-schedule,
+// shifting the schedule of a drifting reminder to the one proposed by its
+// drift report. Reminders that don't drift are left alone with a 409.
+func AdjustScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var before json.RawMessage
+	rem := editReminder(w, r, id, func(s storage.Storage, rem *reminder.Reminder) bool {
+		rep, err := reminderDrift(s, rem)
+		if err != nil {
+			errorHandler(w, r, "failed to query completion events", http.StatusInternalServerError, err)
+			return false
+		}
+		if rep == nil {
+			errorHandler(w, r, fmt.Sprintf("reminder %s doesn't drift from its schedule", id), http.StatusConflict, nil)
+			return false
+		}
+		before = audit.Snapshot(rem)
+		rem.DueDate = &rep.ProposedDueDate
+		rem.Recurrence = rep.Proposed
+		return true
+	})
+	if rem == nil {
+		return
+	}
+	Events.Publish(reminderSaved(rem, before, nil, requestActor(r), requestImpersonator(r)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rem)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// reminderETag returns the entity tag of a reminder, which changes with its
+// version
+func reminderETag(rem *reminder.Reminder) string {
+	return strconv.Quote(strconv.Itoa(rem.Version))
+}
+
+// etagMatches reports whether an If-Match header value matches etag. Weak
+// tags never match, as If-Match uses the strong comparison.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch checks the If-Match header of a request changing rem,
+// writing a 412 with the current ETag if it's stale. A request without the
+// header passes unless required, in which case it's answered with 428.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, rem *reminder.Reminder, required bool) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if required {
+			errorHandler(w, r, fmt.Sprintf("If-Match is required; get the reminder's ETag from GET /reminders/%s", rem.ID), http.StatusPreconditionRequired, nil)
+		}
+		return !required
+	}
+	if !etagMatches(header, reminderETag(rem)) {
+		w.Header().Set("ETag", reminderETag(rem))
+		errorHandler(w, r, fmt.Sprintf("reminder %s has changed; it is at version %d", rem.ID, rem.Version), http.StatusPreconditionFailed, nil)
+		return false
+	}
+	return true
+}
+
+// reminderLocks serializes the read-modify-write of edits to the same
+// reminder within this process, so two edits checked against the same
+// version can't both be saved. Edits on other replicas are caught by
+// storage.UpdateReminders instead.
+var reminderLocks = struct {
+	sync.Mutex
+	locks map[string]*reminderLock
+}{locks: make(map[string]*reminderLock)}
+
+// reminderLock is the lock of one reminder and the number of requests
+// holding or waiting for it
+type reminderLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lockReminder locks the reminder with the given ID against concurrent
+// edits, returning the function that unlocks it
+func lockReminder(id string) func() {
+	reminderLocks.Lock()
+	l := reminderLocks.locks[id]
+	if l == nil {
+		l = &reminderLock{}
+		reminderLocks.locks[id] = l
+	}
+	l.refs++
+	reminderLocks.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		reminderLocks.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(reminderLocks.locks, id)
+		}
+		reminderLocks.Unlock()
+	}
+}
+
+// lockReminders locks several reminders as lockReminder does, in the order
+// of their IDs so two requests locking overlapping sets can't deadlock
+func lockReminders(ids []string) func() {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	unlocks := make([]func(), len(ids))
+	for i, id := range ids {
+		unlocks[i] = lockReminder(id)
+	}
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
+
+var (
+	// errReminderNotFound is returned by updateReminder when the reminder
+	// can't be read
+	errReminderNotFound = errors.New("reminder not found")
+	// errEditAbandoned is returned by an edit function that has written its
+	// own response
+	errEditAbandoned = errors.New("edit abandoned")
+)
+
+// updateReminder makes the read-modify-write every change to a stored
+// reminder goes through. Under the reminder's lock it reads the reminder
+// from the primary and passes a copy to edit, along with the store to use
+// for any other reads and writes. Unless edit fails, the copy is saved at
+// the next version, provided nobody, on any replica, saved the reminder in
+// the meantime; otherwise it fails with storage.ErrVersionConflict. It
+// returns the saved reminder.
+func updateReminder(s storage.Storage, id string, edit func(s storage.Storage, rem *reminder.Reminder) error) (*reminder.Reminder, error) {
+	defer lockReminder(id)()
+	s = storage.ReadPrimary(s)
+	stored, err := s.GetReminder(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errReminderNotFound, err)
+	}
+	rem := stored.Clone()
+	if err := edit(s, rem); err != nil {
+		return nil, err
+	}
+	rem.Version = stored.Version + 1
+	if _, err := storage.UpdateReminders(s, rem); err != nil {
+		return nil, err
+	}
+	return rem, nil
+}
+
+// editReminder runs updateReminder for a request, answering a missing
+// reminder with 404 and a concurrent edit with 409. edit writes its own
+// response and returns false to abandon the edit. It returns the saved
+// reminder, or nil once a response has been written.
+func editReminder(w http.ResponseWriter, r *http.Request, id string, edit func(s storage.Storage, rem *reminder.Reminder) bool) *reminder.Reminder {
+	rem, err := updateReminder(requestStore(r), id, func(s storage.Storage, rem *reminder.Reminder) error {
+		if !edit(s, rem) {
+			return errEditAbandoned
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		return rem
+	case errors.Is(err, errEditAbandoned):
+	case errors.Is(err, errReminderNotFound):
+		errorHandler(w, r, fmt.Sprintf("reminder not found: %s", id), http.StatusNotFound, err)
+	case errors.Is(err, storage.ErrVersionConflict):
+		errorHandler(w, r, fmt.Sprintf("reminder %s was changed by another request; retry the change", id), http.StatusConflict, err)
+	default:
+		errorHandler(w, r, "failed to update reminder", http.StatusInternalServerError, err)
+	}
+	return nil
+}
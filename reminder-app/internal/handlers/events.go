@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"reminder-app/internal/events"
+	"reminder-app/internal/reminder"
+)
+
+// Events carries the domain events handlers publish after every change
+// they save. The audit log, completion notices, live updates, outbound
+// webhooks, the report cache and the reminder list cache are its built-in
+// subscribers.
+var Events = newEventBus()
+
+func newEventBus() *events.Bus {
+	b := events.NewBus()
+	b.Subscribe(auditEvent)
+	b.Subscribe(notifyEvent)
+	b.Subscribe(liveEvent)
+	b.Subscribe(webhookEvent)
+	b.Subscribe(invalidateReports)
+	b.Subscribe(invalidateReminderLists)
+	return b
+}
+
+// reminderSaved returns the event for a saved change to rem: a completion
+// if completion was recorded with the change, otherwise an update. A first
+// sign-off awaiting confirmation doesn't complete rem, so it is only
+// recorded. before is the snapshot of rem prior to the change.
+func reminderSaved(rem *reminder.Reminder, before json.RawMessage, completion *reminder.CompletionEvent, actor, impersonator string) events.Event {
+	if completion != nil && completion.Awaiting() {
+		return events.CompletionRecorded{Reminder: rem, Completion: completion}
+	}
+	if completion != nil {
+		return events.ReminderCompleted{Reminder: rem, Before: before, Completion: completion, Actor: actor, Impersonator: impersonator}
+	}
+	return events.ReminderUpdated{Reminder: rem, Before: before, Actor: actor, Impersonator: impersonator}
+}
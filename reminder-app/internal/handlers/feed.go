@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/feed"
+	"reminder-app/internal/ical"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/share"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// Feed windows
+var (
+	// FeedHistory is how far back completions appear in feeds
+	FeedHistory = 14 * 24 * time.Hour
+	// FeedUpcoming is how far ahead due reminders appear in feeds
+	FeedUpcoming = 7 * 24 * time.Hour
+)
+
+// feedShare returns the share whose token is in r's query string, writing a
+// 404 if it isn't a live share of the family
+func feedShare(w http.ResponseWriter, r *http.Request, familyID string, now time.Time) (*share.Share, bool) {
+	sh, err := share.Lookup(requestStore(r), r.URL.Query().Get("token"), now)
+	if err == nil && sh.FamilyID != familyID {
+		err = share.ErrNotFound
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, share.ErrNotFound) || errors.Is(err, share.ErrExpired) {
+			status = http.StatusNotFound
+		}
+		// The token is a credential, so the query string is left out of the log
+		log.Printf("%s %s %s %d - %v", r.Method, r.URL.Path, r.UserAgent(), status, err)
+		http.Error(w, "feed token is invalid, expired or revoked", status)
+		return nil, false
+	}
+	return sh, true
+}
+
+// FamilyFeedHandler handles GET /families/{id}/feed.atom?token=, an Atom
+// feed of recent completions and acknowledgements and upcoming reminders. Feed readers can't send
+// credentials, so the token is a share link token (see POST /shares) for the
+// family; a share limited to one assignee yields a feed of their reminders.
+func FamilyFeedHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	now := time.Now()
+	sh, ok := feedShare(w, r, id, now)
+	if !ok {
+		return
+	}
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, "family not found", http.StatusNotFound, err)
+		return
+	}
+	list, err := requestStore(r).QueryReminders(storage.ReminderFilter{FamilyID: id, FamilyMember: sh.Query.Assignee})
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+
+	src := feed.Source{
+		FamilyID:  id,
+		Title:     f.Name,
+		Self:      externalBaseURL(r) + r.URL.RequestURI(),
+		Reminders: list,
+		Upcoming:  FeedUpcoming,
+	}
+	for _, rem := range list {
+		events, err := requestStore(r).QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: rem.ID, From: now.Add(-FeedHistory)})
+		if err != nil {
+			errorHandler(w, r, "failed to list completion events", http.StatusInternalServerError, err)
+			return
+		}
+		src.Completions = append(src.Completions, events...)
+		acks, err := storage.ListJSON[reminder.Ack](requestStore(r), storage.RecordQuery{Kind: reminder.AckKind, FamilyID: id, Ref: rem.ID})
+		if err != nil {
+			errorHandler(w, r, "failed to list acknowledgements", http.StatusInternalServerError, err)
+			return
+		}
+		for _, a := range acks {
+			if !a.AckedAt.Before(now.Add(-FeedHistory)) {
+				src.Acks = append(src.Acks, a)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(feed.Build(src, now))
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// FamilyCalendarHandler handles GET /families/{id}/calendar.ics?token=, an
+// iCalendar feed of the family's reminders to subscribe to from a calendar
+// app. Like the Atom feed it is authorized by a share link token.
+func FamilyCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	now := time.Now()
+	sh, ok := feedShare(w, r, id, now)
+	if !ok {
+		return
+	}
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, "family not found", http.StatusNotFound, err)
+		return
+	}
+	list, err := requestStore(r).QueryReminders(storage.ReminderFilter{FamilyID: id, FamilyMember: sh.Query.Assignee})
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+
+	src := ical.Source{
+		FamilyID:  id,
+		Name:      f.Name,
+		Reminders: list,
+		Location: func(member string) *time.Location {
+			loc, err := f.LocationFor(member)
+			if err != nil {
+				return nil
+			}
+			return loc
+		},
+	}
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Write(ical.Build(src, now))
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
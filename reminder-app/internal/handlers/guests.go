@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/guest"
+	"reminder-app/internal/reminder"
+
+	"github.com/gorilla/mux"
+)
+
+// GuestHeader carries a guest token in place of ActorHeader
+const GuestHeader = "X-Guest-Token"
+
+// GuestTTL is how long a guest token stays valid when no ttl is given
+var GuestTTL = 7 * 24 * time.Hour
+
+// guestRoutes are the endpoints open to guests, keyed like Policies. Every
+// {id} in them is a reminder, which must be in the guest's scope.
+var guestRoutes = map[string]bool{
+	"GET /reminders":              true,
+	"GET /reminders/{id}":         true,
+	"PATCH /reminders/{id}":       true,
+	"POST /reminders/{id}/status": true,
+}
+
+type guestContextKey struct{}
+
+// requestGuest returns the guest a request was authorized for by
+// scopeGuest, or nil if it wasn't made with a guest token
+func requestGuest(r *http.Request) *guest.Guest {
+	g, _ := r.Context().Value(guestContextKey{}).(*guest.Guest)
+	return g
+}
+
+// scopeGuest authenticates a request made with GuestHeader and confines it
+// to guestRoutes and the guest's scope, writing an error if it falls
+// outside them. Reminders out of scope are reported as not found. The
+// returned request carries the guest for requestGuest.
+func scopeGuest(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if r.Header.Get(ActorHeader) != "" || r.Header.Get(ActAsHeader) != "" {
+		errorHandler(w, r, fmt.Sprintf("%s cannot be combined with %s or %s", GuestHeader, ActorHeader, ActAsHeader), http.StatusBadRequest, nil)
+		return nil, false
+	}
+	g, err := guest.Lookup(requestStore(r), r.Header.Get(GuestHeader), time.Now())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, guest.ErrNotFound) || errors.Is(err, guest.ErrExpired) || errors.Is(err, guest.ErrRevoked) {
+			status = http.StatusUnauthorized
+		}
+		errorHandler(w, r, "guest token is invalid, expired or revoked", status, err)
+		return nil, false
+	}
+	if !guestRoutes[routeKey(r)] {
+		errorHandler(w, r, "guests cannot use this endpoint", http.StatusForbidden, nil)
+		return nil, false
+	}
+	if id, ok := mux.Vars(r)["id"]; ok {
+		if rem, err := requestStore(r).GetReminder(id); err == nil && !g.Allows(rem) {
+			errorHandler(w, r, fmt.Sprintf("reminder not found: %s", id), http.StatusNotFound, nil)
+			return nil, false
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), guestContextKey{}, g)), true
+}
+
+// guestRequest is the body accepted by POST /families/{id}/guests
+type guestRequest struct {
+	Name  string      `json:"name"`
+	Scope guest.Scope `json:"scope"`
+	// TTL is a Go duration such as "72h"; GuestTTL is used when empty
+	TTL string `json:"ttl,omitempty"`
+}
+
+// guestResponse is a newly created guest with its token
+type guestResponse struct {
+	*guest.Guest
+	Token string `json:"token"`
+}
+
+// CreateGuestHandler handles POST /families/{id}/guests, issuing a token
+// that lets someone outside the family, such as a house sitter, see and
+// complete the reminders in a scope until it expires. The token is only
+// returned here and is sent in GuestHeader.
+func CreateGuestHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	var req guestRequest
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if req.Name == "" {
+		errorHandler(w, r, "name is required", http.StatusBadRequest, nil)
+		return
+	}
+	for _, p := range req.Scope.ProjectIDs {
+		if msg, err := validateProjectID(requestStore(r), id, p); msg != "" {
+			errorHandler(w, r, msg, http.StatusBadRequest, err)
+			return
+		}
+	}
+	tags, err := reminder.NormalizeTags(req.Scope.Tags)
+	if err != nil {
+		errorHandler(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	req.Scope.Tags = tags
+	if s := req.Scope; s.DueAfter != nil && s.DueBefore != nil && !s.DueAfter.Before(*s.DueBefore) {
+		errorHandler(w, r, "due_after must be before due_before", http.StatusBadRequest, nil)
+		return
+	}
+	ttl := GuestTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			errorHandler(w, r, "ttl must be a positive duration", http.StatusBadRequest, err)
+			return
+		}
+		ttl = d
+	}
+	now := time.Now()
+	g := &guest.Guest{FamilyID: id, Name: req.Name, Scope: req.Scope, ExpiresAt: now.Add(ttl), CreatedBy: requestActor(r)}
+	token, err := guest.Create(requestStore(r), g, now)
+	if err != nil {
+		errorHandler(w, r, "failed to create guest", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(guestResponse{Guest: g, Token: token})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
+
+// ListGuestsHandler handles GET /families/{id}/guests, including revoked
+// guests until their tokens expire. Tokens are not included.
+func ListGuestsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := guest.List(requestStore(r), mux.Vars(r)["id"])
+	if err != nil {
+		errorHandler(w, r, "failed to list guests", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// RevokeGuestHandler handles DELETE /families/{id}/guests/{guest}, putting
+// the guest's token on the revocation list
+func RevokeGuestHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := guest.Revoke(requestStore(r), vars["id"], vars["guest"], time.Now()); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, guest.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		errorHandler(w, r, fmt.Sprintf("failed to revoke guest: %s", vars["guest"]), status, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
@@ -2,28 +2,80 @@ package handlers
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"reminder-app/internal/audit"
+	"reminder-app/internal/breaker"
+	"reminder-app/internal/condition"
+	"reminder-app/internal/events"
 	fam "reminder-app/internal/family"
+	"reminder-app/internal/query"
 	"reminder-app/internal/reminder"
 	"reminder-app/internal/storage"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/gorilla/mux"
 )
 
 var (
 	// Remove old maps, use storage instead
 	Store storage.Storage
+
+	// StrictJSON rejects request bodies containing unknown fields. Clients
+	// can also opt in per request with a "Prefer: handling=strict" header.
+	// It can be changed while serving.
+	StrictJSON atomic.Bool
+
+	// Conditions evaluates the conditions reminders may have on external
+	// data. Nil rejects reminders with a condition.
+	Conditions *condition.Registry
 )
 
+// strictRequested reports whether unknown fields should be rejected for r
+func strictRequested(r *http.Request) bool {
+	if StrictJSON.Load() {
+		return true
+	}
+	for _, pref := range r.Header.Values("Prefer") {
+		for _, p := range strings.Split(pref, ",") {
+			if strings.EqualFold(strings.TrimSpace(p), "handling=strict") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeJSON decodes the request body into v, honoring strict mode
+func decodeJSON(r *http.Request, body io.Reader, v interface{}) error {
+	dec := json.NewDecoder(body)
+	if strictRequested(r) {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
 // errorHandler provides consistent error handling and logging
 func errorHandler(w http.ResponseWriter, r *http.Request, message string, statusCode int, err error) {
+	if timedOut(r, err) {
+		message, statusCode = "request timed out", http.StatusGatewayTimeout
+	} else if errors.Is(err, breaker.ErrOpen) {
+		message, statusCode = "service temporarily unavailable", http.StatusServiceUnavailable
+	}
 	if err != nil {
 		log.Printf("%s %s %s %d - %s: %v", r.Method, r.URL.Path, r.UserAgent(), statusCode, message, err)
 	} else {
@@ -42,16 +94,20 @@ func CreateFamilyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	r.Body = io.NopCloser(bytes.NewBuffer(body)) // Reset body for further reading
 
-	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+	if err := decodeJSON(r, r.Body, &f); err != nil {
 		errorHandler(w, r, fmt.Sprintf("invalid JSON: %v, Body: %s", err, string(body)), http.StatusBadRequest, err)
 		return
 	}
-	f.ID = storage.GenerateFamilyID(Store)
-	err = Store.CreateFamily(&f)
+	if f.ID, err = storage.GenerateFamilyID(requestStore(r)); err != nil {
+		errorHandler(w, r, "failed to create family", http.StatusInternalServerError, err)
+		return
+	}
+	err = requestStore(r).CreateFamily(&f)
 	if err != nil {
 		errorHandler(w, r, "failed to create family", http.StatusInternalServerError, err)
 		return
 	}
+	Events.Publish(events.FamilyCreated{Family: &f, Actor: requestActor(r)})
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(f)
@@ -60,7 +116,7 @@ func CreateFamilyHandler(w http.ResponseWriter, r *http.Request) {
 
 func GetFamilyHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	f, err := Store.GetFamily(id)
+	f, err := requestStore(r).GetFamily(id)
 	if err != nil {
 		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
 		return
@@ -71,7 +127,7 @@ func GetFamilyHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func ListFamiliesHandler(w http.ResponseWriter, r *http.Request) {
-	list, err := Store.ListFamilies()
+	list, err := requestStore(r).ListFamilies()
 	if err != nil {
 		errorHandler(w, r, "failed to list families", http.StatusInternalServerError, err)
 		return
@@ -81,115 +137,242 @@ func ListFamiliesHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
 }
 
+// boolParam parses an optional boolean query parameter, writing an error
+// response if it's invalid
+func boolParam(w http.ResponseWriter, r *http.Request, name string) (value, ok bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, true
+	}
+	value, err := strconv.ParseBool(v)
+	if err != nil {
+		errorHandler(w, r, name+" must be true or false", http.StatusBadRequest, err)
+		return false, false
+	}
+	return value, true
+}
+
+// cascadeParam parses the ?cascade= parameter of deletes, writing an error
+// response if it's invalid
+func cascadeParam(w http.ResponseWriter, r *http.Request) (cascade, ok bool) {
+	return boolParam(w, r, "cascade")
+}
+
+// DeleteFamilyHandler handles DELETE /families/{id}. A family with reminders
+// is only deleted with ?cascade=true, which deletes the reminders too. The
+// family and its reminders go to the trash, from which POST
+// /families/{id}/restore brings them back until TrashRetention has passed;
+// ?permanent=true deletes them and their events at once, after capturing a
+// restore point if RestorePoints is configured.
 func DeleteFamilyHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	err := Store.DeleteFamily(id)
+	cascade, ok := cascadeParam(w, r)
+	if !ok {
+		return
+	}
+	permanent, ok := permanentParam(w, r)
+	if !ok {
+		return
+	}
+	list, err := requestStore(r).QueryReminders(storage.ReminderFilter{FamilyID: id})
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	if len(list) > 0 && !cascade {
+		errorHandler(w, r, fmt.Sprintf("family %s has %d reminders; delete them first or pass cascade=true", id, len(list)), http.StatusConflict, nil)
+		return
+	}
+	if permanent && len(list) > 0 && RestorePoints != nil {
+		if _, err := RestorePoints.Capture("before permanently deleting family "+id, time.Now()); err != nil {
+			errorHandler(w, r, "failed to capture a restore point before deleting", http.StatusInternalServerError, err)
+			return
+		}
+	}
+	var deleted []*reminder.Reminder
+	if permanent {
+		deleted, err = storage.DeleteFamilyCascade(requestStore(r), id)
+	} else {
+		now := time.Now()
+		deleted, err = storage.TrashFamily(requestStore(r), id, requestActor(r), now, now.Add(TrashRetention))
+	}
+	for _, rem := range deleted {
+		Events.Publish(events.ReminderDeleted{Reminder: rem, Actor: requestActor(r), Impersonator: requestImpersonator(r)})
+	}
 	if err != nil {
 		errorHandler(w, r, "failed to delete family", http.StatusInternalServerError, err)
 		return
 	}
+	Events.Publish(events.FamilyDeleted{ID: id, Actor: requestActor(r)})
 	w.WriteHeader(http.StatusNoContent)
 	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
 }
 
 // Reminder Handlers
-func CreateReminderHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Title        string                     `json:"title"`
-		Description  string                     `json:"description"`
-		DueDate      string                     `json:"due_date"`
-		FamilyID     string                     `json:"family_id"`
-		FamilyMember string                     `json:"family_member"`
-		Recurrence   reminder.RecurrencePattern `json:"recurrence"`
-	}
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		errorHandler(w, r, "failed to read request body", http.StatusBadRequest, err)
-		return
-	}
-	r.Body = io.NopCloser(bytes.NewBuffer(body)) // Reset body for further reading
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorHandler(w, r, fmt.Sprintf("invalid JSON: %v, Body: %s", err, string(body)), http.StatusBadRequest, err)
-		return
-	}
+// reminderRequest is the body accepted when creating or replacing a reminder
+type reminderRequest struct {
+	Title        string                     `json:"title"`
+	Description  string                     `json:"description"`
+	DueDate      string                     `json:"due_date"`
+	FamilyID     string                     `json:"family_id"`
+	FamilyMember string                     `json:"family_member"`
+	Recurrence   reminder.RecurrencePattern `json:"recurrence"`
+	ProjectID    string                     `json:"project_id"`
+	Effort       int                        `json:"effort"`
+	Assignment   string                     `json:"assignment"`
+	Version      *int                       `json:"version,omitempty"`
+	// RequiresConfirmation makes completions need two members' sign-offs
+	RequiresConfirmation bool `json:"requires_confirmation"`
+	// Timezone is the IANA time zone the reminder recurs in; the
+	// assignee's or family's time zone is used when empty
+	Timezone string `json:"timezone"`
+	// Condition gates notifications on external data such as the weather
+	Condition *reminder.Condition `json:"condition"`
+	// Usage makes the reminder fall due by a counter such as an odometer
+	Usage *reminder.Usage `json:"usage"`
+	// Rotation makes the assignee take turns among members each occurrence
+	Rotation *reminder.Rotation `json:"rotation"`
+	// Tags are free-form labels, normalized by validate
+	Tags []string `json:"tags"`
+	// Priority is low, normal, high or urgent; empty means normal
+	Priority string `json:"priority"`
+	// Points are earned by each completion, up to reminder.MaxPoints
+	Points int `json:"points"`
+	// Escalation re-notifies the assignee and notifies other members while
+	// the reminder is overdue
+	Escalation *reminder.Escalation `json:"escalation"`
+}
 
+// validate checks the request against the stored family and normalizes the
+// recurrence pattern. It returns the parsed due date, or an error message
+// suitable for a 400 response. The family member may be left empty when an
+// assignment strategy or rotation is set, so the server picks one.
+func (req *reminderRequest) validate(s storage.Storage) (*time.Time, string, error) {
 	var dueDate *time.Time
 	if req.DueDate != "" {
 		due, err := time.Parse(time.RFC3339, req.DueDate)
 		if err != nil {
-			errorHandler(w, r, "invalid due_date format", http.StatusBadRequest, err)
-			return
+			return nil, "invalid due_date format", err
 		}
 		dueDate = &due
 	}
 
-	if req.FamilyID == "" || req.FamilyMember == "" {
-		errorHandler(w, r, "family_id and family_member are required", http.StatusBadRequest, nil)
-		return
+	if msg := validateAssignment(req.Assignment); msg != "" {
+		return nil, msg, nil
+	}
+	if req.FamilyID == "" || (req.FamilyMember == "" && req.Assignment == "" && req.Rotation == nil) {
+		return nil, "family_id and family_member are required", nil
 	}
 
-	family, err := Store.GetFamily(req.FamilyID)
+	family, err := s.GetFamily(req.FamilyID)
 	if err != nil {
-		errorHandler(w, r, fmt.Sprintf("family not found: %s", req.FamilyID), http.StatusBadRequest, err)
-		return
+		return nil, fmt.Sprintf("family not found: %s", req.FamilyID), err
 	}
 
-	memberExists := false
-	for _, member := range family.Members {
-		if member == req.FamilyMember {
-			memberExists = true
-			break
-		}
+	if req.FamilyMember != "" && !hasMember(family, req.FamilyMember) {
+		return nil, fmt.Sprintf("family member not found: %s", req.FamilyMember), nil
 	}
-	if !memberExists {
-		errorHandler(w, r, fmt.Sprintf("family member not found: %s", req.FamilyMember), http.StatusBadRequest, nil)
-		return
+	if req.Effort < 0 {
+		return nil, "effort must not be negative", nil
+	}
+	if msg := validateTimezone(req.Timezone); msg != "" {
+		return nil, msg, nil
 	}
 	if req.Recurrence.Type == "" {
 		req.Recurrence.Type = "once"
 	}
+	if msg, err := validateRecurrence(req.Recurrence); msg != "" {
+		return nil, msg, err
+	}
+	if msg, err := validateCondition(req.Condition); msg != "" {
+		return nil, msg, err
+	}
+	if msg, err := validateUsage(req.Usage, req.Recurrence); msg != "" {
+		return nil, msg, err
+	}
+	if msg, err := validateRotation(s, req.FamilyID, req.Rotation, req.Recurrence, dueDate, req.Assignment); msg != "" {
+		return nil, msg, err
+	}
+	if msg, err := validateEscalation(s, req.FamilyID, req.Escalation); msg != "" {
+		return nil, msg, err
+	}
+	if msg, err := validateProjectID(s, req.FamilyID, req.ProjectID); msg != "" {
+		return nil, msg, err
+	}
+	if req.Tags, err = reminder.NormalizeTags(req.Tags); err != nil {
+		return nil, err.Error(), err
+	}
+	if err := reminder.ValidatePriority(req.Priority); err != nil {
+		return nil, err.Error(), err
+	}
+	if err := reminder.ValidatePoints(req.Points); err != nil {
+		return nil, err.Error(), err
+	}
 
-	// Validate recurrence pattern
-	switch req.Recurrence.Type {
-	case "once":
-		// No additional validation needed
-	case "daily":
-		// No additional validation needed for daily recurrence
-	case "weekly":
-		if len(req.Recurrence.Days) == 0 {
-			errorHandler(w, r, "weekly recurrence requires at least one day", http.StatusBadRequest, nil)
-			return
-		}
-		for _, day := range req.Recurrence.Days {
-			if !isValidWeekday(day) {
-				errorHandler(w, r, "invalid weekday in recurrence pattern", http.StatusBadRequest, nil)
-				return
-			}
-		}
-	case "monthly":
-		if req.Recurrence.Date < 1 || req.Recurrence.Date > 31 {
-			errorHandler(w, r, "monthly recurrence requires a date between 1 and 31", http.StatusBadRequest, nil)
-			return
-		}
-	default:
-		errorHandler(w, r, "invalid recurrence type", http.StatusBadRequest, nil)
-		return
+	return dueDate, "", nil
+}
+
+// validateRecurrence returns an error message if the pattern is invalid
+func validateRecurrence(rp reminder.RecurrencePattern) (string, error) {
+	if err := rp.Validate(); err != nil {
+		return err.Error(), err
 	}
+	return "", nil
+}
 
-	if req.Recurrence.EndDate != "" {
-		_, err = time.Parse(time.RFC3339, req.Recurrence.EndDate)
-		if err != nil {
-			errorHandler(w, r, "invalid end_date format", http.StatusBadRequest, err)
-			return
-		}
+// validateCondition returns an error message if an optional condition is
+// invalid or can't be evaluated by this server
+func validateCondition(c *reminder.Condition) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	if Conditions == nil {
+		return "conditions are not enabled on this server", nil
 	}
+	if err := Conditions.Validate(*c); err != nil {
+		return fmt.Sprintf("invalid condition: %v", err), err
+	}
+	return "", nil
+}
 
-	id := storage.GenerateReminderID(Store)
-	re := reminder.NewReminderWithNullableDueDate(id, req.Title, req.Description, dueDate, req.FamilyID, req.FamilyMember, req.Recurrence)
-	err = Store.CreateReminder(re)
+// validateUsage returns an error message if an optional usage trigger is
+// invalid. Usage-based reminders recur by their counter, not by date.
+func validateUsage(u *reminder.Usage, rp reminder.RecurrencePattern) (string, error) {
+	if u == nil {
+		return "", nil
+	}
+	if err := u.Validate(); err != nil {
+		return fmt.Sprintf("invalid usage: %v", err), err
+	}
+	if rp.Type != "once" {
+		return "usage-based reminders can't also recur by date", nil
+	}
+	return "", nil
+}
+
+func CreateReminderHandler(w http.ResponseWriter, r *http.Request) {
+	var req reminderRequest
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		errorHandler(w, r, "failed to create reminder", http.StatusInternalServerError, err)
+		errorHandler(w, r, "failed to read request body", http.StatusBadRequest, err)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewBuffer(body)) // Reset body for further reading
+
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, fmt.Sprintf("invalid JSON: %v, Body: %s", err, string(body)), http.StatusBadRequest, err)
+		return
+	}
+
+	dueDate, msg, err := req.validate(requestStore(r))
+	if msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	re, msg, err := insertReminder(requestStore(r), &req, dueDate, requestActor(r), requestImpersonator(r))
+	if msg != "" {
+		errorHandler(w, r, msg, http.StatusInternalServerError, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -198,139 +381,805 @@ func CreateReminderHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
 }
 
+// insertReminder creates and publishes a reminder from a validated request,
+// assigning a member if none was given. On failure it returns an error
+// message.
+func insertReminder(s storage.Storage, req *reminderRequest, dueDate *time.Time, actor, impersonator string) (*reminder.Reminder, string, error) {
+	re, msg, err := newReminder(s, req, dueDate)
+	if msg != "" {
+		return nil, msg, err
+	}
+	if re.Position, err = nextPosition(s, req.FamilyID); err != nil {
+		return nil, "failed to list reminders", err
+	}
+	if err := s.CreateReminder(re); err != nil {
+		return nil, "failed to create reminder", err
+	}
+	Events.Publish(events.ReminderCreated{Reminder: re, Actor: actor, Impersonator: impersonator})
+	return re, "", nil
+}
+
+// newReminder builds a reminder with a new ID from a validated request,
+// assigning a member if none was given. It isn't saved. On failure it
+// returns an error message.
+func newReminder(s storage.Storage, req *reminderRequest, dueDate *time.Time) (*reminder.Reminder, string, error) {
+	id, err := storage.GenerateReminderID(s)
+	if err != nil {
+		return nil, "failed to create reminder", err
+	}
+	re := reminder.NewReminder(id, req.Title, req.Description, dueDate, req.FamilyID, req.FamilyMember, req.Recurrence)
+	re.ProjectID = req.ProjectID
+	re.Effort = req.Effort
+	re.Assignment = req.Assignment
+	re.RequiresConfirmation = req.RequiresConfirmation
+	re.Timezone = req.Timezone
+	re.Condition = req.Condition
+	re.Usage = req.Usage
+	re.Rotation = req.Rotation
+	re.Tags = req.Tags
+	re.Priority = req.Priority
+	re.Points = req.Points
+	re.Escalation = req.Escalation
+	if re.Usage != nil && re.Usage.Due() && re.DueDate == nil {
+		now := time.Now()
+		re.DueDate = &now
+	}
+	if re.FamilyMember == "" || re.Rotation != nil {
+		if err := assignMember(s, re, currentOccurrence(s, re)); err != nil {
+			return nil, "failed to assign reminder", err
+		}
+	}
+	return re, "", nil
+}
+
+// ReplaceReminderHandler handles PUT /reminders/{id}. The request must say
+// which version it was based on, either as the reminder's ETag in If-Match
+// or as the version in the body, so concurrent editors don't overwrite each
+// other: a stale If-Match is rejected with 412, a stale version with 409.
+// Completion state is not part of the replacement and is left untouched.
+func ReplaceReminderHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var before json.RawMessage
+	existing := editReminder(w, r, id, func(s storage.Storage, existing *reminder.Reminder) bool {
+		if !checkIfMatch(w, r, existing, false) {
+			return false
+		}
+
+		var req reminderRequest
+		if err := decodeJSON(r, r.Body, &req); err != nil {
+			errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+			return false
+		}
+		if r.Header.Get("If-Match") == "" {
+			if req.Version == nil {
+				errorHandler(w, r, "version or If-Match is required", http.StatusBadRequest, nil)
+				return false
+			}
+			if *req.Version != existing.Version {
+				errorHandler(w, r, fmt.Sprintf("version conflict: reminder %s is at version %d", id, existing.Version), http.StatusConflict, nil)
+				return false
+			}
+		}
+
+		dueDate, msg, err := req.validate(s)
+		if msg != "" {
+			errorHandler(w, r, msg, http.StatusBadRequest, err)
+			return false
+		}
+
+		if req.FamilyID != existing.FamilyID {
+			if !authorizeFamily(w, r, req.FamilyID, fam.PermEdit) {
+				return false
+			}
+			if existing.Position, err = nextPosition(s, req.FamilyID); err != nil {
+				errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+				return false
+			}
+		}
+
+		before = audit.Snapshot(existing)
+		existing.Update(req.Title, req.Description, dueDate)
+		existing.Recurrence = req.Recurrence
+		existing.FamilyID = req.FamilyID
+		existing.FamilyMember = req.FamilyMember
+		existing.ProjectID = req.ProjectID
+		existing.Effort = req.Effort
+		existing.Assignment = req.Assignment
+		existing.RequiresConfirmation = req.RequiresConfirmation
+		existing.Timezone = req.Timezone
+		existing.Condition = req.Condition
+		existing.Usage = req.Usage
+		existing.Rotation = req.Rotation
+		existing.Tags = req.Tags
+		existing.Priority = req.Priority
+		existing.Points = req.Points
+		existing.Escalation = req.Escalation
+		if existing.FamilyMember == "" || existing.Rotation != nil {
+			if err := assignMember(s, existing, currentOccurrence(s, existing)); err != nil {
+				errorHandler(w, r, "failed to assign reminder", http.StatusInternalServerError, err)
+				return false
+			}
+		}
+		return true
+	})
+	if existing == nil {
+		return
+	}
+	Events.Publish(events.ReminderUpdated{Reminder: existing, Before: before, Actor: requestActor(r), Impersonator: requestImpersonator(r)})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", reminderETag(existing))
+	json.NewEncoder(w).Encode(existing)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
 func GetReminderHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	reminder, err := Store.GetReminder(id)
+	reminder, err := requestStore(r).GetReminder(id)
 	if err != nil {
 		errorHandler(w, r, fmt.Sprintf("reminder not found: %s", id), http.StatusNotFound, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", reminderETag(reminder))
 	json.NewEncoder(w).Encode(reminder)
 	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
 }
 
+// reminderFilter builds a storage filter from the query parameters of
+// GET /reminders. It returns an error message for malformed values.
+func reminderFilter(params url.Values) (storage.ReminderFilter, string, error) {
+	f := storage.ReminderFilter{
+		FamilyID:       params.Get("family_id"),
+		FamilyMember:   params.Get("family_member"),
+		RecurrenceType: params.Get("recurrence_type"),
+		ProjectID:      params.Get("project_id"),
+		Tag:            reminder.NormalizeTag(params.Get("tag")),
+	}
+	if v := params.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, "completed must be true or false", err
+		}
+		f.Completed = &completed
+	}
+	for name, dst := range map[string]**time.Time{"due_after": &f.DueAfter, "due_before": &f.DueBefore} {
+		if v := params.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Sprintf("invalid %s format", name), err
+			}
+			*dst = &t
+		}
+	}
+	expr, err := query.Parse(params.Get("q"))
+	if err != nil {
+		return f, fmt.Sprintf("invalid q: %s", err), err
+	}
+	if err := expr.Apply(&f, time.Now()); err != nil {
+		return f, fmt.Sprintf("invalid q: %s", err), err
+	}
+	return f, "", nil
+}
+
+// ListRemindersHandler handles GET /reminders. The optional family_id,
+// family_member, completed, due_after (inclusive), due_before (exclusive),
+// recurrence_type, project_id, tag and overdue parameters narrow the result,
+// as does q, a filter expression such as q=assignee:"Alice" AND tag:school
+// AND due<2025-01-01 (see package query); reminders without a due date never
+// match a due date bound. sort orders the
+// result, such as sort=priority,due_date for the most pressing first and then
+// the earliest due. Each reminder is listed with whether it is overdue, and
+// since when. Guests only see the reminders in their scope. Identical queries
+// share one storage query while it runs and for ListCacheTTL after.
 func ListRemindersHandler(w http.ResponseWriter, r *http.Request) {
-	list, err := Store.ListReminders()
+	f, msg, err := reminderFilter(r.URL.Query())
+	if msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	var overdue *bool
+	if v := r.URL.Query().Get("overdue"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errorHandler(w, r, "overdue must be true or false", http.StatusBadRequest, err)
+			return
+		}
+		overdue = &b
+	}
+	order, err := reminder.ParseSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		errorHandler(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	g := requestGuest(r)
+	if g != nil {
+		f.FamilyID = g.FamilyID
+	}
+	list, err := queryReminders(r, f)
 	if err != nil {
 		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
 		return
 	}
+	if g != nil {
+		list = g.Filter(list)
+	}
+	// The list may be shared with coalesced requests, so it is sorted as a
+	// copy
+	list = slices.Clone(list)
+	reminder.SortReminders(list, order)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(list)
+	json.NewEncoder(w).Encode(withOverdue(r, list, time.Now(), overdue))
 	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
 }
 
+// DeleteReminderHandler handles DELETE /reminders/{id}, moving the reminder
+// to the trash, from which POST /reminders/{id}/restore brings it back until
+// TrashRetention has passed. ?permanent=true deletes it at once instead; a
+// reminder that has been completed is then only deleted with ?cascade=true,
+// which deletes its completion and status events too, and otherwise refused
+// with a 409.
 func DeleteReminderHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	err := Store.DeleteReminder(id)
+	cascade, ok := cascadeParam(w, r)
+	if !ok {
+		return
+	}
+	permanent, ok := permanentParam(w, r)
+	if !ok {
+		return
+	}
+	if !permanent {
+		if _, err := requestStore(r).GetReminder(id); err != nil {
+			errorHandler(w, r, fmt.Sprintf("reminder not found: %s", id), http.StatusNotFound, err)
+			return
+		}
+		now := time.Now()
+		existing, err := storage.TrashReminder(requestStore(r), id, requestActor(r), now, now.Add(TrashRetention))
+		if err != nil {
+			errorHandler(w, r, "failed to delete reminder", http.StatusInternalServerError, err)
+			return
+		}
+		Events.Publish(events.ReminderDeleted{Reminder: existing, Actor: requestActor(r), Impersonator: requestImpersonator(r)})
+		w.WriteHeader(http.StatusNoContent)
+		log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+		return
+	}
+	completions, err := requestStore(r).ListCompletionEvents(id)
+	if err != nil {
+		errorHandler(w, r, "failed to list completion events", http.StatusInternalServerError, err)
+		return
+	}
+	if len(completions) > 0 && !cascade {
+		errorHandler(w, r, fmt.Sprintf("reminder %s has %d completion events; delete them first or pass cascade=true", id, len(completions)), http.StatusConflict, nil)
+		return
+	}
+	existing, _ := requestStore(r).GetReminder(id)
+	err = storage.DeleteReminderCascade(requestStore(r), id)
 	if err != nil {
 		errorHandler(w, r, "failed to delete reminder", http.StatusInternalServerError, err)
 		return
 	}
+	if existing != nil {
+		Events.Publish(events.ReminderDeleted{Reminder: existing, Actor: requestActor(r), Impersonator: requestImpersonator(r)})
+	}
 	w.WriteHeader(http.StatusNoContent)
 	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
 }
 
-func UpdateReminderHandler(w http.ResponseWriter, req *http.Request) {
-	id := mux.Vars(req)["id"]
-	r, err := Store.GetReminder(id)
+// Content types accepted by PATCH /reminders/{id}
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// reminderDocument is the JSON view of a reminder that patches are applied
+// to. The due date stays a string so an empty value can still clear it.
+type reminderDocument struct {
+	ID           string                     `json:"id"`
+	Title        string                     `json:"title"`
+	Description  string                     `json:"description"`
+	DueDate      *string                    `json:"due_date"`
+	Recurrence   reminder.RecurrencePattern `json:"recurrence"`
+	Completed    bool                       `json:"completed"`
+	CompletedAt  *time.Time                 `json:"completed_at"`
+	FamilyID     string                     `json:"family_id"`
+	FamilyMember string                     `json:"family_member"`
+	Version      int                        `json:"version"`
+	Position     int                        `json:"position"`
+	Status       string                     `json:"status"`
+	ProjectID    string                     `json:"project_id"`
+	Effort       int                        `json:"effort"`
+	Assignment   string                     `json:"assignment"`
+	SnoozedUntil *time.Time                 `json:"snoozed_until"`
+	// RequiresConfirmation is editable like the other settings
+	RequiresConfirmation bool                 `json:"requires_confirmation"`
+	Timezone             string               `json:"timezone"`
+	Condition            *reminder.Condition  `json:"condition"`
+	Usage                *reminder.Usage      `json:"usage"`
+	Rotation             *reminder.Rotation   `json:"rotation"`
+	Tags                 []string             `json:"tags"`
+	Priority             string               `json:"priority"`
+	Exceptions           []time.Time          `json:"exceptions"`
+	SeriesID             string               `json:"series_id"`
+	Points               int                  `json:"points"`
+	Escalation           *reminder.Escalation `json:"escalation"`
+}
+
+// applyReminderPatch applies a JSON Patch (RFC 6902) or JSON Merge Patch
+// (RFC 7396) to the JSON form of r. Plain application/json bodies are
+// treated as merge patches.
+func applyReminderPatch(r *reminder.Reminder, contentType string, patch []byte) ([]byte, error) {
+	doc, err := json.Marshal(r)
 	if err != nil {
-		errorHandler(w, req, fmt.Sprintf("reminder not found: %s", id), http.StatusNotFound, err)
-		return
+		return nil, err
 	}
-	// Read and decode partial update
-	var patch map[string]interface{}
-	if err := json.NewDecoder(req.Body).Decode(&patch); err != nil {
-		errorHandler(w, req, "invalid JSON", http.StatusBadRequest, err)
-		return
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == jsonPatchContentType {
+		p, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, err
+		}
+		return p.Apply(doc)
 	}
-	updated := false
-	for k, v := range patch {
-		switch k {
-		case "title":
-			if s, ok := v.(string); ok {
-				r.Title = s
-				updated = true
+	return jsonpatch.MergePatch(doc, patch)
+}
+
+// UpdateReminderHandler handles PATCH /reminders/{id}. Patches that change
+// anything besides the completed flag must send the reminder's ETag in
+// If-Match, and are rejected with 412 if it has changed since. On recurring
+// reminders, ?scope=occurrence&occurrence= edits that occurrence only and
+// ?scope=following&occurrence= it and the ones after; see
+// updateOccurrences.
+func UpdateReminderHandler(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	var (
+		before     json.RawMessage
+		completion *reminder.CompletionEvent
+		reopened   []*reminder.CompletionEvent
+	)
+	r := editReminder(w, req, id, func(s storage.Storage, r *reminder.Reminder) bool {
+		contentType := req.Header.Get("Content-Type")
+		switch mediaType, _, _ := mime.ParseMediaType(contentType); mediaType {
+		case "", "application/json", mergePatchContentType, jsonPatchContentType:
+		default:
+			errorHandler(w, req, fmt.Sprintf("unsupported content type: %s", contentType), http.StatusUnsupportedMediaType, nil)
+			return false
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			errorHandler(w, req, "failed to read request body", http.StatusBadRequest, err)
+			return false
+		}
+		scope, at, ok := editScope(w, req, r)
+		if !ok {
+			return false
+		}
+		if scope != editAll {
+			updateOccurrences(w, req, s, r, scope, at, contentType, body)
+			return false
+		}
+		patched, err := applyReminderPatch(r, contentType, body)
+		if err != nil {
+			errorHandler(w, req, "invalid patch", http.StatusBadRequest, err)
+			return false
+		}
+		var doc reminderDocument
+		if err := decodeJSON(req, bytes.NewReader(patched), &doc); err != nil {
+			errorHandler(w, req, fmt.Sprintf("invalid patch: %v", err), http.StatusBadRequest, err)
+			return false
+		}
+		// The route's policy only requires PermComplete
+		completionOnly := onlyCompletion(r, doc)
+		if !completionOnly && !authorizeReminder(w, req, r, fam.PermEdit) {
+			return false
+		}
+
+		dueDate, msg, err := validateDocument(s, r, &doc)
+		if msg != "" {
+			errorHandler(w, req, msg, http.StatusBadRequest, err)
+			return false
+		}
+		// Completing twice is caught by the occurrence check below, so only
+		// edits must name the version they were based on
+		if !checkIfMatch(w, req, r, !completionOnly) {
+			return false
+		}
+
+		before = audit.Snapshot(r)
+		wasCompleted := r.Completed
+		applyDocument(s, r, doc, dueDate)
+		if doc.Completed && !wasCompleted {
+			now := time.Now()
+			if req.URL.Query().Get("force") != "true" {
+				existing, err := existingCompletion(s, r, now)
+				if err != nil {
+					errorHandler(w, req, "failed to check completion events", http.StatusInternalServerError, err)
+					return false
+				}
+				if existing != nil {
+					errorHandler(w, req, fmt.Sprintf("reminder already completed for this occurrence by %s (event %s); use ?force=true to record another completion", existing.CompletedBy, existing.ID), http.StatusConflict, nil)
+					return false
+				}
 			}
-		case "description":
-			if s, ok := v.(string); ok {
-				r.Description = s
-				updated = true
+			// Completions are credited to the assignee unless an admin is
+			// acting as someone. Sign-offs are credited to whoever makes them.
+			by := r.FamilyMember
+			if req.Header.Get(ActAsHeader) != "" || (r.RequiresConfirmation && requestActor(req) != "") {
+				by = requestActor(req)
 			}
-		case "due_date":
-			if s, ok := v.(string); ok {
-				if s == "" {
-					// Empty string means null due date
-					r.DueDate = nil
-					updated = true
-				} else if t, err := time.Parse(time.RFC3339, s); err == nil {
-					r.DueDate = &t
-					updated = true
-				}
+			var err error
+			if completion, err = completeReminder(s, r, by, requestImpersonator(req), now); errors.Is(err, errSameConfirmer) {
+				errorHandler(w, req, err.Error(), http.StatusConflict, nil)
+				return false
+			} else if err != nil {
+				errorHandler(w, req, "failed to create completion event", http.StatusInternalServerError, err)
+				return false
 			}
-		case "completed":
-			if b, ok := v.(bool); ok {
-				now := time.Now()
-				if r.IsRecurring() {
-					// For recurring reminders, never set Completed=true, just set CompletedAt
-					if b {
-						r.CompletedAt = &now
-					} else {
-						r.CompletedAt = nil
-					}
-					r.Completed = false
-					updated = true
-				} else {
-					if b && !r.Completed {
-						r.MarkCompleted()
-						updated = true
-					} else if !b && r.Completed {
-						r.Completed = false
-						r.CompletedAt = nil
-						updated = true
-					}
-				}
-				// Create a completion event
-				completionEvent := &reminder.CompletionEvent{
-					ID:          fmt.Sprintf("cev%d", Store.GetCompletionEventIDCounter()+1),
-					ReminderID:  r.ID,
-					CompletedBy: r.FamilyMember, // Assuming the assigned member completed it
-					CompletedAt: now,
-				}
+		} else if !doc.Completed && wasCompleted {
+			var err error
+			if reopened, err = reopenReminder(s, r); err != nil {
+				errorHandler(w, req, "failed to delete completion events", http.StatusInternalServerError, err)
+				return false
+			}
+		}
+		return true
+	})
+	if r == nil {
+		return
+	}
+	Events.Publish(reminderSaved(r, before, completion, requestActor(req), requestImpersonator(req)))
+	for _, e := range reopened {
+		Events.Publish(events.CompletionDeleted{Reminder: r, Completion: e, Actor: requestActor(req)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", reminderETag(r))
+	json.NewEncoder(w).Encode(r)
+	log.Printf("%s %s %s %d - PATCH reminder %s", req.Method, req.URL.Path, req.UserAgent(), http.StatusOK, id)
+}
 
-				if err := Store.CreateCompletionEvent(completionEvent); err != nil {
-					errorHandler(w, req, "failed to create completion event", http.StatusInternalServerError, err)
-					return
-				}
+// onlyCompletion reports whether the patched document doc differs from r in
+// nothing but its completed flag, so the patch only needs PermComplete
+func onlyCompletion(r *reminder.Reminder, doc reminderDocument) bool {
+	orig, err := json.Marshal(r)
+	if err != nil {
+		return false
+	}
+	var before reminderDocument
+	if err := json.Unmarshal(orig, &before); err != nil {
+		return false
+	}
+	doc.Completed = before.Completed
+	return reflect.DeepEqual(before, doc)
+}
+
+// validateDocument checks a patched reminderDocument against the reminder r
+// it came from, filling in defaults, and returns its parsed due date. On
+// failure it returns an error message.
+func validateDocument(s storage.Storage, r *reminder.Reminder, doc *reminderDocument) (*time.Time, string, error) {
+	// Identity and bookkeeping fields are owned by the server
+	if doc.ID != r.ID || doc.FamilyID != r.FamilyID || doc.Version != r.Version ||
+		doc.Position != r.Position || doc.Status != r.Status || !timesEqual(doc.CompletedAt, r.CompletedAt) ||
+		!timesEqual(doc.SnoozedUntil, r.SnoozedUntil) || !slices.EqualFunc(doc.Exceptions, r.Exceptions, time.Time.Equal) ||
+		doc.SeriesID != r.SeriesID {
+		return nil, "id, family_id, version, position, status, completed_at, snoozed_until, exceptions and series_id are read-only", nil
+	}
+
+	var dueDate *time.Time
+	if doc.DueDate != nil && *doc.DueDate != "" {
+		t, err := time.Parse(time.RFC3339, *doc.DueDate)
+		if err != nil {
+			return nil, "invalid due_date format", err
+		}
+		dueDate = &t
+	}
+	if doc.Recurrence.Type == "" {
+		doc.Recurrence.Type = "once"
+	}
+	if msg, err := validateRecurrence(doc.Recurrence); msg != "" {
+		return nil, msg, err
+	}
+	if doc.FamilyMember != r.FamilyMember {
+		family, err := s.GetFamily(r.FamilyID)
+		if err != nil {
+			return nil, fmt.Sprintf("family not found: %s", r.FamilyID), err
+		}
+		if !hasMember(family, doc.FamilyMember) {
+			return nil, fmt.Sprintf("family member not found: %s", doc.FamilyMember), nil
+		}
+	}
+
+	if doc.Effort < 0 {
+		return nil, "effort must not be negative", nil
+	}
+	if msg := validateAssignment(doc.Assignment); msg != "" {
+		return nil, msg, nil
+	}
+	if msg := validateTimezone(doc.Timezone); msg != "" {
+		return nil, msg, nil
+	}
+	if msg, err := validateCondition(doc.Condition); msg != "" {
+		return nil, msg, err
+	}
+	if msg, err := validateUsage(doc.Usage, doc.Recurrence); msg != "" {
+		return nil, msg, err
+	}
+	if msg, err := validateRotation(s, r.FamilyID, doc.Rotation, doc.Recurrence, dueDate, doc.Assignment); msg != "" {
+		return nil, msg, err
+	}
+	if msg, err := validateEscalation(s, r.FamilyID, doc.Escalation); msg != "" {
+		return nil, msg, err
+	}
+	if doc.ProjectID != r.ProjectID {
+		if msg, err := validateProjectID(s, r.FamilyID, doc.ProjectID); msg != "" {
+			return nil, msg, err
+		}
+	}
+	tags, err := reminder.NormalizeTags(doc.Tags)
+	if err != nil {
+		return nil, err.Error(), err
+	}
+	doc.Tags = tags
+	if err := reminder.ValidatePriority(doc.Priority); err != nil {
+		return nil, err.Error(), err
+	}
+	if err := reminder.ValidatePoints(doc.Points); err != nil {
+		return nil, err.Error(), err
+	}
+	return dueDate, "", nil
+}
+
+// applyDocument sets r's editable fields from a validated reminderDocument.
+// A rotating reminder is reassigned to whoever's turn its current occurrence
+// is. Completion is left to the caller.
+func applyDocument(s storage.Storage, r *reminder.Reminder, doc reminderDocument, dueDate *time.Time) {
+	r.Update(doc.Title, doc.Description, dueDate)
+	r.Recurrence = doc.Recurrence
+	r.FamilyMember = doc.FamilyMember
+	r.ProjectID = doc.ProjectID
+	r.Effort = doc.Effort
+	r.Assignment = doc.Assignment
+	r.RequiresConfirmation = doc.RequiresConfirmation
+	r.Timezone = doc.Timezone
+	r.Condition = doc.Condition
+	r.Usage = doc.Usage
+	r.Rotation = doc.Rotation
+	r.Tags = doc.Tags
+	r.Priority = doc.Priority
+	r.Points = doc.Points
+	r.Escalation = doc.Escalation
+	if r.Rotation != nil {
+		r.FamilyMember = r.AssigneeAt(currentOccurrence(s, r))
+	}
+}
+
+// completeReminder marks r as completed at the given time and records a
+// completion event for it. Recurring reminders never become Completed; only
+// CompletedAt advances so the next occurrence stays active, and is assigned
+// according to the reminder's assignment strategy. Neither do usage-based
+// reminders, whose next interval starts from the latest reading and which
+// have no due date until it is reached. Either way the workflow
+// status is reset, so the next occurrence or a later reopen starts from the
+// initial status, and any snooze ends. impersonator is the admin recording the completion on
+// by's behalf, if any. The caller is responsible for saving r and then
+// publishing events.ReminderCompleted, which sends the family's completion
+// notice.
+//
+// Reminders that require confirmation take two sign-offs. The first only
+// records an event awaiting confirmation and leaves r as it is; the second,
+// which must be by another member or fails with errSameConfirmer, records a
+// paired event and completes r.
+func completeReminder(s storage.Storage, r *reminder.Reminder, by, impersonator string, at time.Time) (*reminder.CompletionEvent, error) {
+	var pending *reminder.CompletionEvent
+	if r.RequiresConfirmation {
+		last, err := lastCompletion(s, r, at)
+		if err != nil {
+			return nil, err
+		}
+		if last == nil || !last.Awaiting() {
+			id, err := storage.GenerateCompletionEventID(s)
+			if err != nil {
+				return nil, err
 			}
-		case "recurrence":
-			if rec, ok := v.(map[string]interface{}); ok {
-				var rp reminder.RecurrencePattern
-				b, _ := json.Marshal(rec)
-				if err := json.Unmarshal(b, &rp); err == nil {
-					r.Recurrence = rp
-					updated = true
-				}
+			e := &reminder.CompletionEvent{
+				ID:           id,
+				ReminderID:   r.ID,
+				CompletedBy:  by,
+				Impersonator: impersonator,
+				CompletedAt:  at,
+				State:        reminder.StateAwaitingConfirmation,
+				Points:       r.Points,
 			}
-		case "family_member":
-			if s, ok := v.(string); ok {
-				r.FamilyMember = s
-				updated = true
+			if err := s.CreateCompletionEvent(e); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+		if last.CompletedBy == by {
+			return nil, errSameConfirmer
+		}
+		pending = last
+	}
+	r.Status = ""
+	r.SnoozedUntil = nil
+	if r.Usage != nil {
+		// The next interval counts from the latest reading, and the
+		// reminder waits for the counter to reach it
+		r.Usage.Baseline = r.Usage.Reading
+		r.DueDate = nil
+		r.Completed = false
+		r.CompletedAt = &at
+	} else if r.IsRecurring() {
+		r.Completed = false
+		r.CompletedAt = &at
+		if next := r.NextOccurrence(scheduleTime(s, r, at)); next != nil {
+			if err := assignMember(s, r, *next); err != nil {
+				log.Printf("failed to assign next occurrence of reminder %s: %v", r.ID, err)
 			}
 		}
+	} else {
+		r.Completed = true
+		r.CompletedAt = &at
+	}
+	id, err := storage.GenerateCompletionEventID(s)
+	if err != nil {
+		return nil, err
+	}
+	e := &reminder.CompletionEvent{
+		ID:           id,
+		ReminderID:   r.ID,
+		CompletedBy:  by,
+		Impersonator: impersonator,
+		CompletedAt:  at,
 	}
+	if pending != nil {
+		// The first sign-off earned the points; they count once it's
+		// confirmed
+		e.State, e.PairID = reminder.StateConfirmed, pending.ID
+	} else {
+		e.Points = r.Points
+	}
+	if err := s.CreateCompletionEvent(e); err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		pending.State, pending.PairID = reminder.StateConfirmed, e.ID
+		if err := s.CreateCompletionEvent(pending); err != nil { // Overwrite existing
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// errSameConfirmer is returned by completeReminder when the member who made
+// the first sign-off tries to confirm it
+var errSameConfirmer = errors.New("completion must be confirmed by a different member")
 
-	if updated {
-		err = Store.CreateReminder(r) // Overwrite existing
+// existingCompletion returns the completion event already recorded for the
+// occurrence period of r containing at, or nil if there is none. A sign-off
+// still awaiting confirmation doesn't count. A usage-based reminder's
+// period lasts from its last completion until more usage is reported.
+func existingCompletion(s storage.Storage, r *reminder.Reminder, at time.Time) (*reminder.CompletionEvent, error) {
+	if r.Usage != nil && r.Usage.Reading > r.Usage.Baseline {
+		return nil, nil
 	}
+	e, err := lastCompletion(s, r, at)
+	if err != nil || e == nil || e.Awaiting() {
+		return nil, err
+	}
+	return e, nil
+}
+
+// reopenReminder marks a completed one-off reminder as not done. Its
+// completion events are deleted, as they would otherwise count as completing
+// its only occurrence and block completing it again, and returned so their
+// deletion can be published. The caller is responsible for saving r.
+func reopenReminder(s storage.Storage, r *reminder.Reminder) ([]*reminder.CompletionEvent, error) {
+	r.Completed = false
+	r.CompletedAt = nil
+	events, err := s.ListCompletionEvents(r.ID)
 	if err != nil {
-		errorHandler(w, req, "failed to update reminder", http.StatusInternalServerError, err)
-		return
+		return nil, err
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(r)
-	log.Printf("%s %s %s %d - PATCH reminder %s", req.Method, req.URL.Path, req.UserAgent(), http.StatusOK, id)
+	for _, e := range events {
+		if err := s.DeleteCompletionEvent(e.ID); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// lastCompletion returns the last completion event recorded for the
+// occurrence period of r containing at, or nil if there is none
+func lastCompletion(s storage.Storage, r *reminder.Reminder, at time.Time) (*reminder.CompletionEvent, error) {
+	if !r.IsRecurring() {
+		// A one-off reminder has a single period holding all its events
+		return s.GetLatestCompletionEvent(r.ID)
+	}
+	from, to := r.Period(scheduleTime(s, r, at))
+	events, err := s.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: r.ID, From: from, To: to})
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return events[len(events)-1], nil
+}
+
+// latestCompletion returns the most recent completion event of a reminder
+// that isn't awaiting confirmation, or nil if there is none
+func latestCompletion(s storage.Storage, id string) (*reminder.CompletionEvent, error) {
+	e, err := s.GetLatestCompletionEvent(id)
+	if err != nil || e == nil || !e.Awaiting() {
+		return e, err
+	}
+	events, err := s.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: id})
+	if err != nil {
+		return nil, err
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if !events[i].Awaiting() {
+			return events[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// syncCompletion brings the completion recorded on a reminder in line with
+// its completion events after one was created or deleted on its own, so its
+// overdue state and next occurrence follow the events. It returns the
+// reminder, saved only if it changed.
+func syncCompletion(s storage.Storage, id string) (*reminder.Reminder, error) {
+	rem, err := updateReminder(s, id, func(s storage.Storage, rem *reminder.Reminder) error {
+		latest, err := latestCompletion(s, rem.ID)
+		if err != nil {
+			return err
+		}
+		var at *time.Time
+		if latest != nil {
+			at = &latest.CompletedAt
+		}
+		completed := at != nil && !rem.IsRecurring() && rem.Usage == nil
+		if completed == rem.Completed && (at == nil) == (rem.CompletedAt == nil) && (at == nil || at.Equal(*rem.CompletedAt)) {
+			return errEditAbandoned
+		}
+		rem.Completed, rem.CompletedAt = completed, at
+		return nil
+	})
+	if errors.Is(err, errEditAbandoned) {
+		return s.GetReminder(id)
+	}
+	return rem, err
+}
+
+// scheduleTime returns t in the time zone of the member r is assigned to,
+// which its schedule follows unless it has a Timezone of its own. The server's
+// zone is kept if the family can't be loaded.
+func scheduleTime(s storage.Storage, r *reminder.Reminder, t time.Time) time.Time {
+	f, err := s.GetFamily(r.FamilyID)
+	if err != nil {
+		return t
+	}
+	loc, err := f.LocationFor(r.FamilyMember)
+	if err != nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// hasMember reports whether member belongs to the family
+func hasMember(f *fam.Family, member string) bool {
+	for _, m := range f.Members {
+		if m == member {
+			return true
+		}
+	}
+	return false
+}
+
+// timesEqual compares two optional timestamps
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
 }
 
 // --- CompletionEvent Handlers ---
@@ -342,12 +1191,15 @@ func CreateCompletionEventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	r.Body = io.NopCloser(bytes.NewBuffer(body))
-	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+	if err := decodeJSON(r, r.Body, &e); err != nil {
 		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
 		return
 	}
-	if e.ID == "" {
-		e.ID = storage.GenerateCompletionEventID(Store)
+	// Identity and pairing are the server's, so an event can neither
+	// overwrite another nor pass for a confirmed sign-off
+	if e.ID != "" || e.State != "" || e.PairID != "" {
+		errorHandler(w, r, "id, state and pair_id are read-only", http.StatusBadRequest, nil)
+		return
 	}
 	if e.ReminderID == "" || e.CompletedBy == "" {
 		errorHandler(w, r, "reminder_id and completed_by are required", http.StatusBadRequest, nil)
@@ -356,19 +1208,80 @@ func CreateCompletionEventHandler(w http.ResponseWriter, r *http.Request) {
 	if e.CompletedAt.IsZero() {
 		e.CompletedAt = time.Now()
 	}
-	err = Store.CreateCompletionEvent(&e)
+	rem, err := requestStore(r).GetReminder(e.ReminderID)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("reminder not found: %s", e.ReminderID), http.StatusNotFound, err)
+		return
+	}
+	// Points are the reminder's to award, not the caller's
+	e.Points = rem.Points
+	if r.URL.Query().Get("force") != "true" {
+		existing, err := existingCompletion(requestStore(r), rem, e.CompletedAt)
+		if err != nil {
+			errorHandler(w, r, "failed to check completion events", http.StatusInternalServerError, err)
+			return
+		}
+		if existing != nil {
+			errorHandler(w, r, fmt.Sprintf("reminder already completed for this occurrence by %s (event %s); use ?force=true to record another completion", existing.CompletedBy, existing.ID), http.StatusConflict, nil)
+			return
+		}
+	}
+	if rem.RequiresConfirmation {
+		recordSignOff(w, r, e)
+		return
+	}
+	if e.ID, err = storage.GenerateCompletionEventID(requestStore(r)); err != nil {
+		errorHandler(w, r, "failed to create completion event", http.StatusInternalServerError, err)
+		return
+	}
+	err = requestStore(r).CreateCompletionEvent(&e)
 	if err != nil {
 		errorHandler(w, r, "failed to create completion event", http.StatusInternalServerError, err)
 		return
 	}
+	if synced, err := syncCompletion(requestStore(r), rem.ID); err != nil {
+		log.Printf("failed to sync completion of reminder %s: %v", rem.ID, err)
+	} else {
+		rem = synced
+	}
+	Events.Publish(events.CompletionRecorded{Reminder: rem, Completion: &e})
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(e)
 }
 
+// recordSignOff records e for a reminder that requires confirmation the way
+// completing it does, so it only completes the reminder as the second
+// sign-off of another member
+func recordSignOff(w http.ResponseWriter, r *http.Request, e reminder.CompletionEvent) {
+	var (
+		before     json.RawMessage
+		completion *reminder.CompletionEvent
+	)
+	rem := editReminder(w, r, e.ReminderID, func(s storage.Storage, rem *reminder.Reminder) bool {
+		before = audit.Snapshot(rem)
+		var err error
+		if completion, err = completeReminder(s, rem, e.CompletedBy, requestImpersonator(r), e.CompletedAt); errors.Is(err, errSameConfirmer) {
+			errorHandler(w, r, err.Error(), http.StatusConflict, nil)
+			return false
+		} else if err != nil {
+			errorHandler(w, r, "failed to create completion event", http.StatusInternalServerError, err)
+			return false
+		}
+		return true
+	})
+	if rem == nil {
+		return
+	}
+	Events.Publish(reminderSaved(rem, before, completion, requestActor(r), requestImpersonator(r)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(completion)
+}
+
 func GetCompletionEventHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	e, err := Store.GetCompletionEvent(id)
+	e, err := requestStore(r).GetCompletionEvent(id)
 	if err != nil {
 		errorHandler(w, r, fmt.Sprintf("completion event not found: %s", id), http.StatusNotFound, err)
 		return
@@ -377,39 +1290,112 @@ func GetCompletionEventHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(e)
 }
 
+// maxCompletionEventPage caps the limit query parameter
+const maxCompletionEventPage = 1000
+
+// ListCompletionEventsHandler handles GET /reminders/{id}/completion-events.
+// Events are returned oldest first and can be narrowed with from/to
+// (RFC 3339) and paged with limit/cursor; when more events remain, the next
+// cursor is returned in the X-Next-Cursor header and a rel="next" Link.
 func ListCompletionEventsHandler(w http.ResponseWriter, r *http.Request) {
 	reminderID := mux.Vars(r)["id"]
 	if reminderID == "" {
 		errorHandler(w, r, "reminder_id query param required", http.StatusBadRequest, nil)
 		return
 	}
-	list, err := Store.ListCompletionEvents(reminderID)
+	q := storage.CompletionEventQuery{ReminderID: reminderID}
+	params := r.URL.Query()
+	for name, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := params.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errorHandler(w, r, fmt.Sprintf("%s must be an RFC 3339 timestamp", name), http.StatusBadRequest, err)
+				return
+			}
+			*dst = t
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			errorHandler(w, r, "limit must be a positive integer", http.StatusBadRequest, err)
+			return
+		}
+		q.Limit = min(limit, maxCompletionEventPage)
+	}
+	if v := params.Get("cursor"); v != "" {
+		var err error
+		if q.AfterTime, q.AfterID, err = decodeEventCursor(v); err != nil {
+			errorHandler(w, r, "invalid cursor", http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// Fetch one extra event to learn whether another page exists
+	pageSize := q.Limit
+	if pageSize > 0 {
+		q.Limit++
+	}
+	list, err := requestStore(r).QueryCompletionEvents(q)
 	if err != nil {
 		errorHandler(w, r, "failed to list completion events", http.StatusInternalServerError, err)
 		return
 	}
+	if pageSize > 0 && len(list) > pageSize {
+		list = list[:pageSize]
+		last := list[len(list)-1]
+		cursor := encodeEventCursor(last.CompletedAt, last.ID)
+		next := *r.URL
+		nextParams := next.Query()
+		nextParams.Set("cursor", cursor)
+		next.RawQuery = nextParams.Encode()
+		w.Header().Set("X-Next-Cursor", cursor)
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(list)
 }
 
+// encodeEventCursor builds the opaque cursor resuming after an event
+func encodeEventCursor(at time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(at.UnixNano(), 10) + "|" + id))
+}
+
+// decodeEventCursor parses a cursor from encodeEventCursor
+func decodeEventCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	nanos, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", errors.New("malformed cursor")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return time.Unix(0, n), id, nil
+}
+
 func DeleteCompletionEventHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	err := Store.DeleteCompletionEvent(id)
-	if err != nil {
+	var rem *reminder.Reminder
+	e, err := requestStore(r).GetCompletionEvent(id)
+	if err == nil {
+		rem, _ = requestStore(r).GetReminder(e.ReminderID)
+	}
+	if err := requestStore(r).DeleteCompletionEvent(id); err != nil {
 		errorHandler(w, r, "failed to delete completion event", http.StatusInternalServerError, err)
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// Helper function to validate weekday strings
-func isValidWeekday(day string) bool {
-	validDays := []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
-	day = strings.ToLower(day)
-	for _, valid := range validDays {
-		if day == valid {
-			return true
+	if rem != nil {
+		if synced, err := syncCompletion(requestStore(r), rem.ID); err != nil {
+			log.Printf("failed to sync completion of reminder %s: %v", rem.ID, err)
+		} else {
+			rem = synced
 		}
+		Events.Publish(events.CompletionDeleted{Reminder: rem, Completion: e, Actor: requestActor(r)})
 	}
-	return false
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -1,13 +1,58 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"reminder-app/internal/alexa"
+	"reminder-app/internal/audit"
+	"reminder-app/internal/breaker"
+	"reminder-app/internal/condition"
+	"reminder-app/internal/delivery"
+	"reminder-app/internal/digest"
+	"reminder-app/internal/drift"
+	"reminder-app/internal/events"
 	"reminder-app/internal/family"
+	"reminder-app/internal/fsck"
+	"reminder-app/internal/guest"
+	"reminder-app/internal/hook"
+	"reminder-app/internal/i18n"
+	"reminder-app/internal/links"
+	"reminder-app/internal/notify"
+	"reminder-app/internal/pack"
+	"reminder-app/internal/pitr"
+	"reminder-app/internal/preferences"
 	"reminder-app/internal/reminder"
+	"reminder-app/internal/share"
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/stats"
 	"reminder-app/internal/storage"
+	"reminder-app/internal/suggest"
+	"reminder-app/internal/tzdb"
+	"reminder-app/internal/webhook"
+	"reminder-app/pkg/client"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,22 +61,127 @@ import (
 
 func setupRouter() *mux.Router {
 	r := mux.NewRouter()
+	r.Use(TimeoutMiddleware)
+	r.Use(DeprecationMiddleware)
+	r.Use(AuthorizationMiddleware)
+	r.Use(IdempotencyMiddleware)
+	r.HandleFunc("/healthz", HealthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", ReadyzHandler).Methods("GET")
+	r.HandleFunc("/version", VersionHandler).Methods("GET")
+	r.HandleFunc("/meta/timezones", TimezonesHandler).Methods("GET")
+	r.HandleFunc("/ws", LiveHandler).Methods("GET")
+	r.HandleFunc("/locales", LocalesHandler).Methods("GET")
+	r.HandleFunc("/me/permissions", MyPermissionsHandler).Methods("GET")
+	r.HandleFunc("/me/preferences", GetPreferencesHandler).Methods("GET")
+	r.HandleFunc("/me/preferences", PutPreferencesHandler).Methods("PUT")
 	r.HandleFunc("/families", CreateFamilyHandler).Methods("POST")
 	r.HandleFunc("/families", ListFamiliesHandler).Methods("GET")
 	r.HandleFunc("/families/{id}", GetFamilyHandler).Methods("GET")
 	r.HandleFunc("/families/{id}", DeleteFamilyHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}", PatchFamilyHandler).Methods("PATCH")
+	r.HandleFunc("/families/{id}/restore", RestoreFamilyHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/members", AddMemberHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/members/{name}", RemoveMemberHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}/avatar", PutAvatarHandler).Methods("PUT")
+	r.HandleFunc("/families/{id}/avatar", GetAvatarHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/avatar", DeleteAvatarHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}/members/{name}/avatar", PutAvatarHandler).Methods("PUT")
+	r.HandleFunc("/families/{id}/members/{name}/avatar", GetAvatarHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/members/{name}/avatar", DeleteAvatarHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}/settings", GetFamilySettingsHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/settings", UpdateFamilySettingsHandler).Methods("PUT")
+	r.HandleFunc("/families/{id}/workload", FamilyWorkloadHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/leaderboard", FamilyLeaderboardHandler).Methods("GET")
+	r.HandleFunc("/digest/preview", DigestPreviewHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/dashboard.png", FamilyDashboardHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/report.pdf", FamilyReportHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/stats", FamilyStatsHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/feed.atom", FamilyFeedHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/calendar.ics", FamilyCalendarHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/import/ics", ImportCalendarHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/poll/reminders", PollRemindersHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/poll/completions", PollCompletionsHandler).Methods("GET")
+	r.HandleFunc("/admin/backup.sqlite", BackupHandler).Methods("GET")
+	r.HandleFunc("/admin/fsck", FsckHandler).Methods("POST")
+	r.HandleFunc("/admin/restore-points", ListRestorePointsHandler).Methods("GET")
+	r.HandleFunc("/admin/restore-points", CreateRestorePointHandler).Methods("POST")
+	r.HandleFunc("/admin/dual-write", DualWriteHandler).Methods("GET")
+	r.HandleFunc("/admin/dual-write/sync", DualWriteSyncHandler).Methods("POST")
+	r.HandleFunc("/admin/deprecations", DeprecationsHandler).Methods("GET")
+	r.HandleFunc("/admin/timeouts", TimeoutsHandler).Methods("GET")
+	r.HandleFunc("/admin/telemetry", TelemetryHandler).Methods("GET")
+	r.HandleFunc("/admin/update-status", UpdateStatusHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/export-package", ExportPackageHandler).Methods("POST")
+	r.HandleFunc("/families/import-package", ImportPackageHandler).Methods("POST")
+	r.HandleFunc("/templates/import-url", ImportTemplatePackHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/suggestions", ListSuggestionsHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/drift", FamilyDriftHandler).Methods("GET")
+	r.HandleFunc("/suggestions/{id}/accept", AcceptSuggestionHandler).Methods("POST")
+	r.HandleFunc("/suggestions/{id}/dismiss", DismissSuggestionHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/notifications/test", TestNotificationHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/deliveries", DeliveriesHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/webhooks", CreateWebhookHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/webhooks", ListWebhooksHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/webhooks/{webhook}", DeleteWebhookHandler).Methods("DELETE")
+	r.HandleFunc("/families/{id}/webhooks/{webhook}/deliveries", WebhookDeliveriesHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/guests", CreateGuestHandler).Methods("POST")
+	r.HandleFunc("/families/{id}/guests", ListGuestsHandler).Methods("GET")
+	r.HandleFunc("/families/{id}/guests/{guest}", RevokeGuestHandler).Methods("DELETE")
 	r.HandleFunc("/reminders", CreateReminderHandler).Methods("POST")
 	r.HandleFunc("/reminders", ListRemindersHandler).Methods("GET")
+	r.HandleFunc("/tags", ListTagsHandler).Methods("GET")
+	r.HandleFunc("/series/{id}", SeriesHandler).Methods("GET")
+	r.HandleFunc("/reminders/reorder", ReorderRemindersHandler).Methods("POST")
+	r.HandleFunc("/reminders/batch", CreateRemindersBatchHandler).Methods("POST")
+	r.HandleFunc("/reminders/batch", UpdateRemindersBatchHandler).Methods("PATCH")
 	r.HandleFunc("/reminders/{id}", GetReminderHandler).Methods("GET")
 	r.HandleFunc("/reminders/{id}", DeleteReminderHandler).Methods("DELETE")
 	r.HandleFunc("/reminders/{id}", UpdateReminderHandler).Methods("PATCH") // Add PATCH route for testing
+	r.HandleFunc("/reminders/{id}", ReplaceReminderHandler).Methods("PUT")
+	r.HandleFunc("/reminders/{id}/restore", RestoreReminderHandler).Methods("POST")
+	r.HandleFunc("/trash", ListTrashHandler).Methods("GET")
 
 	// Add new completion event routes
 	r.HandleFunc("/completion-events", CreateCompletionEventHandler).Methods("POST")
 	r.HandleFunc("/completion-events/{id}", GetCompletionEventHandler).Methods("GET")
 	r.HandleFunc("/completion-events/{id}", DeleteCompletionEventHandler).Methods("DELETE")
+	r.HandleFunc("/smart-lists", CreateSmartListHandler).Methods("POST")
+	r.HandleFunc("/smart-lists", ListSmartListsHandler).Methods("GET")
+	r.HandleFunc("/smart-lists/{id}", GetSmartListHandler).Methods("GET")
+	r.HandleFunc("/smart-lists/{id}", UpdateSmartListHandler).Methods("PUT")
+	r.HandleFunc("/smart-lists/{id}", DeleteSmartListHandler).Methods("DELETE")
+	r.HandleFunc("/smart-lists/{id}/reminders", SmartListRemindersHandler).Methods("GET")
+	r.HandleFunc("/projects", CreateProjectHandler).Methods("POST")
+	r.HandleFunc("/projects", ListProjectsHandler).Methods("GET")
+	r.HandleFunc("/projects/{id}", GetProjectHandler).Methods("GET")
+	r.HandleFunc("/projects/{id}", UpdateProjectHandler).Methods("PUT")
+	r.HandleFunc("/projects/{id}", DeleteProjectHandler).Methods("DELETE")
+	r.HandleFunc("/projects/{id}/reminders", ProjectRemindersHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/history", ReminderHistoryHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/status", SetReminderStatusHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/snooze", SnoozeReminderHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/ack", AckReminderHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/adjust-schedule", AdjustScheduleHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/status-events", ListStatusEventsHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/usage", ReportUsageHandler).Methods("POST")
+	r.HandleFunc("/reminders/{id}/usage", ListUsageHandler).Methods("GET")
 	r.HandleFunc("/reminders/{id}/completion-events", ListCompletionEventsHandler).Methods("GET")
 
+	r.HandleFunc("/reminders/{id}/complete-link", CompletionLinkHandler).Methods("GET")
+	r.HandleFunc("/reminders/{id}/qr.png", ReminderQRHandler).Methods("GET")
+	r.HandleFunc("/c/{token}", ConfirmViaLinkHandler).Methods("GET")
+	r.HandleFunc("/c/{token}", CompleteViaLinkHandler).Methods("POST")
+	r.HandleFunc("/inbound/email", InboundEmailHandler).Methods("POST")
+	r.HandleFunc("/shares", CreateShareHandler).Methods("POST")
+	r.HandleFunc("/shares", ListSharesHandler).Methods("GET")
+	r.HandleFunc("/shares/{id}", DeleteShareHandler).Methods("DELETE")
+	r.HandleFunc("/shared/{token}", SharedViewHandler).Methods("GET")
+	r.HandleFunc("/hooks", CreateHookHandler).Methods("POST")
+	r.HandleFunc("/hooks", ListHooksHandler).Methods("GET")
+	r.HandleFunc("/hooks/{id}", DeleteHookHandler).Methods("DELETE")
+	r.HandleFunc("/hooks/{token}", HookDeliveryHandler).Methods("POST")
+	r.HandleFunc("/widget/{token}", WidgetHandler).Methods("GET")
+
 	return r
 }
 
@@ -40,6 +190,8 @@ func setupTestStorage() {
 	Store.SetFamilyIDCounter(0)
 	Store.SetReminderIDCounter(0)
 	Store.SetCompletionEventIDCounter(0)
+	reportCache.reports = make(map[string]map[string][]byte)
+	listCalls.calls = make(map[string]*listCall)
 }
 
 func TestCreateFamilyHandler(t *testing.T) {
@@ -122,6 +274,29 @@ func TestCreateReminderHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("Reminder without due date", func(t *testing.T) {
+		body := []byte(`{
+			"title": "No deadline",
+			"family_id": "fam1",
+			"family_member": "Bob"
+		}`)
+		req := httptest.NewRequest("POST", "/reminders", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		resp := w.Result()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", resp.StatusCode)
+		}
+		var raw map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if _, ok := raw["due_date"]; ok {
+			t.Errorf("expected due_date to be omitted, got %v", raw["due_date"])
+		}
+	})
+
 	t.Run("Invalid family ID", func(t *testing.T) {
 		body := []byte(`{
 			"title": "Test",
@@ -157,6 +332,71 @@ func TestCreateReminderHandler(t *testing.T) {
 			t.Fatalf("expected status 400, got %d", resp.StatusCode)
 		}
 	})
+
+	t.Run("Interval recurrence", func(t *testing.T) {
+		for recurrence, want := range map[string]int{
+			`{"type": "yearly"}`: http.StatusCreated,
+			`{"type": "weekly", "days": ["tuesday"], "interval": 2}`: http.StatusCreated,
+			`{"type": "once", "interval": 3}`:                        http.StatusBadRequest,
+			`{"type": "daily", "interval": -1}`:                      http.StatusBadRequest,
+			`{"type": "monthly", "date": 1, "interval": 25}`:         http.StatusBadRequest,
+		} {
+			body := `{"title": "Test", "due_date": "2024-01-01T10:00:00Z", "family_id": "fam1", "family_member": "Alice", "recurrence": ` + recurrence + `}`
+			req := httptest.NewRequest("POST", "/reminders", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("recurrence %s: expected status %d, got %d", recurrence, want, w.Code)
+			}
+		}
+	})
+
+	t.Run("Timezone", func(t *testing.T) {
+		for timezone, want := range map[string]int{
+			"America/New_York": http.StatusCreated,
+			"Mars/Olympus":     http.StatusBadRequest,
+		} {
+			body := `{"title": "School run", "due_date": "2024-01-01T07:00:00-05:00", "family_id": "fam1", "family_member": "Alice", "recurrence": {"type": "daily"}, "timezone": "` + timezone + `"}`
+			req := httptest.NewRequest("POST", "/reminders", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("timezone %s: expected status %d, got %d", timezone, want, w.Code)
+				continue
+			}
+			var got reminder.Reminder
+			json.NewDecoder(w.Body).Decode(&got)
+			if want == http.StatusCreated && got.Timezone != timezone {
+				t.Errorf("expected timezone %s, got %q", timezone, got.Timezone)
+			}
+		}
+	})
+
+	t.Run("Condition", func(t *testing.T) {
+		Conditions = condition.NewRegistry()
+		Conditions.Register("weather", condition.NewWeatherProvider(condition.DefaultWeatherAPI))
+		defer func() { Conditions = nil }()
+		for cond, want := range map[string]int{
+			`{"provider": "weather", "location": "52.52,13.41", "metric": "precipitation", "operator": "lte", "value": 0, "window": "48h"}`: http.StatusCreated,
+			`{"provider": "weather", "location": "52.52,13.41", "metric": "sunshine", "operator": "lte", "value": 0}`:                       http.StatusBadRequest,
+			`{"provider": "pollen", "location": "52.52,13.41", "metric": "birch", "operator": "gt", "value": 10}`:                           http.StatusBadRequest,
+			`{"provider": "weather", "location": "Berlin", "metric": "precipitation", "operator": "lte", "value": 0}`:                       http.StatusBadRequest,
+		} {
+			body := `{"title": "Water the lawn", "due_date": "2024-06-01T07:00:00Z", "family_id": "fam1", "family_member": "Alice", "recurrence": {"type": "daily"}, "condition": ` + cond + `}`
+			req := httptest.NewRequest("POST", "/reminders", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("condition %s: expected status %d, got %d: %s", cond, want, w.Code, w.Body.String())
+				continue
+			}
+			var got reminder.Reminder
+			json.NewDecoder(w.Body).Decode(&got)
+			if want == http.StatusCreated && (got.Condition == nil || got.Condition.Window != "48h") {
+				t.Errorf("expected the condition to be saved, got %+v", got.Condition)
+			}
+		}
+	})
 }
 
 func TestGetReminderHandler(t *testing.T) {
@@ -219,6 +459,7 @@ func TestUpdateReminderHandler(t *testing.T) {
 		body, _ := json.Marshal(patch)
 		req := httptest.NewRequest("PATCH", "/reminders/rem1", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 		resp := w.Result()
@@ -245,6 +486,7 @@ func TestUpdateReminderHandler(t *testing.T) {
 		body, _ := json.Marshal(patch)
 		req := httptest.NewRequest("PATCH", "/reminders/rem1", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 		resp := w.Result()
@@ -260,6 +502,26 @@ func TestUpdateReminderHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("Clear due_date with null", func(t *testing.T) {
+		body := []byte(`{"due_date": null}`)
+		req := httptest.NewRequest("PATCH", "/reminders/rem1", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		resp := w.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var updated reminder.Reminder
+		if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if updated.DueDate != nil {
+			t.Errorf("expected due_date to be cleared, got %v", updated.DueDate)
+		}
+	})
+
 	t.Run("Complete recurring reminder", func(t *testing.T) {
 		// Create a recurring reminder first
 		recurringReminder := &reminder.Reminder{
@@ -308,6 +570,264 @@ func TestUpdateReminderHandler(t *testing.T) {
 	})
 }
 
+func TestReplaceReminderHandler(t *testing.T) {
+	setupTestStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	_ = Store.CreateFamily(f)
+	due, _ := time.Parse(time.RFC3339, "2025-05-21T10:00:00Z")
+	r := reminder.NewReminder("rem1", "Old Title", "Old Desc", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	_ = Store.CreateReminder(r)
+	router := setupRouter()
+
+	put := func(body string) *http.Response {
+		req := httptest.NewRequest("PUT", "/reminders/rem1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	t.Run("Replace with current version", func(t *testing.T) {
+		resp := put(`{
+			"title": "New Title",
+			"family_id": "fam1",
+			"family_member": "Bob",
+			"recurrence": {"type": "weekly", "days": ["monday"]},
+			"version": 1
+		}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var got reminder.Reminder
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if got.Title != "New Title" || got.Description != "" || got.FamilyMember != "Bob" {
+			t.Errorf("unexpected reminder: %+v", got)
+		}
+		if got.DueDate != nil {
+			t.Errorf("expected due_date to be cleared by replacement, got %v", got.DueDate)
+		}
+		if got.Version != 2 {
+			t.Errorf("expected version 2, got %d", got.Version)
+		}
+	})
+
+	t.Run("Stale version", func(t *testing.T) {
+		resp := put(`{"title": "Stale", "family_id": "fam1", "family_member": "Alice", "version": 1}`)
+		if resp.StatusCode != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Missing version", func(t *testing.T) {
+		resp := put(`{"title": "No version", "family_id": "fam1", "family_member": "Alice"}`)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Invalid recurrence", func(t *testing.T) {
+		resp := put(`{"title": "Bad", "family_id": "fam1", "family_member": "Alice", "recurrence": {"type": "weekly"}, "version": 2}`)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Unknown reminder", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/reminders/missing", bytes.NewBufferString(`{"version": 1}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestReminderETags(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	router := setupRouter()
+
+	do := func(method, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/reminders/rem1", strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	etag := do("GET", "", "").Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", etag)
+	}
+
+	// Alice and Bob both start from the same version; Bob's edit loses
+	w := do("PATCH", `{"title": "Take out the trash"}`, etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected status 200 with ETag \"2\", got %d, %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+	w = do("PATCH", `{"title": "Trash and recycling"}`, etag)
+	if w.Code != http.StatusPreconditionFailed || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("expected status 412 with the current ETag, got %d, %q", w.Code, w.Header().Get("ETag"))
+	}
+	if rem, _ := Store.GetReminder("rem1"); rem.Title != "Take out the trash" {
+		t.Errorf("expected the first edit kept, got %q", rem.Title)
+	}
+
+	if w := do("PATCH", `{"title": "Trash and recycling"}`, ""); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("expected status 428 editing without If-Match, got %d", w.Code)
+	}
+	if w := do("PATCH", `{"completed": true}`, ""); w.Code != http.StatusOK {
+		t.Errorf("expected completing without If-Match to pass, got %d", w.Code)
+	}
+
+	body := `{"title": "Recycling", "family_id": "fam1", "family_member": "Bob", "recurrence": {"type": "daily"}}`
+	if w := do("PUT", body, `"2"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status 412 replacing a stale version, got %d", w.Code)
+	}
+	if w := do("PUT", body, `"1", "3"`); w.Code != http.StatusOK || w.Header().Get("ETag") != `"4"` {
+		t.Errorf("expected status 200 with ETag \"4\", got %d, %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+}
+
+func TestStrictJSONDecoding(t *testing.T) {
+	setupTestStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}}
+	_ = Store.CreateFamily(f)
+	due, _ := time.Parse(time.RFC3339, "2025-05-21T10:00:00Z")
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Title", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	send := func(method, path, body string, strict bool) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if strict {
+			req.Header.Set("Prefer", "handling=strict")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	t.Run("Unknown field ignored by default", func(t *testing.T) {
+		if code := send("PATCH", "/reminders/rem1", `{"duedate": "2026-01-01T00:00:00Z"}`, false); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+	})
+
+	t.Run("Unknown PATCH field rejected in strict mode", func(t *testing.T) {
+		if code := send("PATCH", "/reminders/rem1", `{"duedate": "2026-01-01T00:00:00Z"}`, true); code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", code)
+		}
+	})
+
+	t.Run("Unknown create field rejected in strict mode", func(t *testing.T) {
+		body := `{"title": "T", "family_id": "fam1", "family_member": "Alice", "duedate": "2026-01-01T00:00:00Z"}`
+		if code := send("POST", "/reminders", body, true); code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", code)
+		}
+	})
+
+	t.Run("Strict mode from config", func(t *testing.T) {
+		StrictJSON.Store(true)
+		defer StrictJSON.Store(false)
+		if code := send("POST", "/families", `{"name": "Doe", "member": ["Alice"]}`, false); code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", code)
+		}
+	})
+
+	t.Run("Wrongly typed PATCH value rejected", func(t *testing.T) {
+		if code := send("PATCH", "/reminders/rem1", `{"completed": "yes"}`, false); code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", code)
+		}
+		if code := send("PATCH", "/reminders/rem1", `{"due_date": "tomorrow"}`, false); code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", code)
+		}
+	})
+}
+
+func TestPatchContentTypes(t *testing.T) {
+	setupTestStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	_ = Store.CreateFamily(f)
+	due, _ := time.Parse(time.RFC3339, "2025-05-21T10:00:00Z")
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice",
+		reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday"}}))
+	router := setupRouter()
+
+	patch := func(contentType, body string) *http.Response {
+		req := httptest.NewRequest("PATCH", "/reminders/rem1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	t.Run("Merge patch null clears due date", func(t *testing.T) {
+		resp := patch("application/merge-patch+json", `{"due_date": null, "family_member": "Bob"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var got reminder.Reminder
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if got.DueDate != nil || got.FamilyMember != "Bob" {
+			t.Errorf("unexpected reminder: %+v", got)
+		}
+	})
+
+	t.Run("JSON patch appends to recurrence days", func(t *testing.T) {
+		resp := patch("application/json-patch+json", `[
+			{"op": "test", "path": "/title", "value": "Trash"},
+			{"op": "add", "path": "/recurrence/days/-", "value": "thursday"}
+		]`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var got reminder.Reminder
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if len(got.Recurrence.Days) != 2 || got.Recurrence.Days[1] != "thursday" {
+			t.Errorf("unexpected recurrence days: %v", got.Recurrence.Days)
+		}
+	})
+
+	t.Run("Failed JSON patch test op", func(t *testing.T) {
+		resp := patch("application/json-patch+json", `[{"op": "test", "path": "/title", "value": "Dishes"}]`)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Read-only field", func(t *testing.T) {
+		resp := patch("application/merge-patch+json", `{"id": "rem9"}`)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Unknown member", func(t *testing.T) {
+		resp := patch("application/merge-patch+json", `{"family_member": "Mallory"}`)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Unsupported content type", func(t *testing.T) {
+		resp := patch("text/plain", `title=x`)
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Fatalf("expected status 415, got %d", resp.StatusCode)
+		}
+	})
+}
+
 func TestCompletionEventHandlers(t *testing.T) {
 	setupTestStorage()
 	// Create required test data first
@@ -467,3 +987,4372 @@ func TestCompletionEventHandlers(t *testing.T) {
 		}
 	})
 }
+
+func TestDuplicateCompletions(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	due := time.Now().Add(-48 * time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Dishes", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	router := setupRouter()
+
+	post := func(url string) int {
+		body := `{"reminder_id": "rem1", "completed_by": "Alice"}`
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+	if code := post("/completion-events"); code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", code)
+	}
+	if code := post("/completion-events"); code != http.StatusConflict {
+		t.Errorf("expected status 409 for second completion today, got %d", code)
+	}
+	if code := post("/completion-events?force=true"); code != http.StatusCreated {
+		t.Errorf("expected status 201 with force, got %d", code)
+	}
+
+	// Yesterday's occurrence is a different period
+	yesterday := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
+	req := httptest.NewRequest("POST", "/completion-events", strings.NewReader(`{"reminder_id": "rem1", "completed_by": "Alice", "completed_at": "`+yesterday+`"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Errorf("expected status 201 for yesterday's occurrence, got %d", w.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest("PATCH", "/reminders/rem1", strings.NewReader(`{"completed": true}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusConflict {
+		t.Errorf("expected status 409 for PATCH completion, got %d", w.Result().StatusCode)
+	}
+
+	if code := post("/completion-events"); code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", code)
+	}
+	req = httptest.NewRequest("POST", "/completion-events", strings.NewReader(`{"reminder_id": "nope", "completed_by": "Alice"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown reminder, got %d", w.Result().StatusCode)
+	}
+
+	// Reopening a one-off reminder drops its completion, so it can be
+	// completed again
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Call the plumber", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	for _, completed := range []string{"true", "false", "true"} {
+		req = httptest.NewRequest("PATCH", "/reminders/rem2", strings.NewReader(`{"completed": `+completed+`}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200 setting completed to %s, got %d: %s", completed, w.Result().StatusCode, w.Body.String())
+		}
+	}
+	if events, _ := Store.ListCompletionEvents("rem2"); len(events) != 1 {
+		t.Errorf("expected only the completion after reopening, got %+v", events)
+	}
+}
+
+func TestCompletionEventPaging(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	due := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Dishes", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	for i := 0; i < 5; i++ {
+		_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{
+			ID:          fmt.Sprintf("cev%d", i),
+			ReminderID:  "rem1",
+			CompletedBy: "Alice",
+			CompletedAt: due.AddDate(0, 0, 4-i),
+		})
+	}
+	router := setupRouter()
+
+	list := func(url string) ([]reminder.CompletionEvent, string) {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", url, w.Result().StatusCode)
+		}
+		var events []reminder.CompletionEvent
+		json.NewDecoder(w.Result().Body).Decode(&events)
+		return events, w.Result().Header.Get("X-Next-Cursor")
+	}
+
+	var ids []string
+	url := "/reminders/rem1/completion-events?limit=2"
+	for pages := 0; pages < 5; pages++ {
+		events, cursor := list(url)
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		if cursor == "" {
+			break
+		}
+		url = "/reminders/rem1/completion-events?limit=2&cursor=" + cursor
+	}
+	if strings.Join(ids, ",") != "cev4,cev3,cev2,cev1,cev0" {
+		t.Errorf("expected all events oldest first across pages, got %v", ids)
+	}
+
+	events, cursor := list("/reminders/rem1/completion-events?from=2025-01-02T00:00:00Z&to=2025-01-04T00:00:00Z")
+	if len(events) != 2 || events[0].ID != "cev3" || cursor != "" {
+		t.Errorf("unexpected range result: %v (cursor %q)", events, cursor)
+	}
+
+	for _, bad := range []string{"limit=0", "limit=x", "from=yesterday", "cursor=!!"} {
+		req := httptest.NewRequest("GET", "/reminders/rem1/completion-events?"+bad, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", bad, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestReminderHistory(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	router := setupRouter()
+
+	body := `{"title": "Trash", "due_date": "2025-01-01T08:00:00Z", "family_id": "fam1", "family_member": "Alice", "recurrence": {"type": "once"}}`
+	req := httptest.NewRequest("POST", "/reminders", strings.NewReader(body))
+	req.Header.Set(ActorHeader, "Alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var created reminder.Reminder
+	json.NewDecoder(w.Result().Body).Decode(&created)
+
+	req = httptest.NewRequest("PATCH", "/reminders/"+created.ID, strings.NewReader(`{"due_date": "2025-01-03T08:00:00Z"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set(ActorHeader, "Bob")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest("DELETE", "/reminders/"+created.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	req = httptest.NewRequest("GET", "/reminders/"+created.ID+"/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 for deleted reminder's history, got %d", w.Result().StatusCode)
+	}
+	var entries []audit.Entry
+	if err := json.NewDecoder(w.Result().Body).Decode(&entries); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected create, update and delete entries, got %+v", entries)
+	}
+	update := entries[1]
+	if update.Actor != "Bob" || update.Action != audit.ActionUpdate || len(update.Changes) != 1 || update.Changes[0].Field != "due_date" {
+		t.Errorf("unexpected update entry: %+v", update)
+	}
+	if string(update.Changes[0].Old) != `"2025-01-01T08:00:00Z"` {
+		t.Errorf("unexpected old due date: %s", update.Changes[0].Old)
+	}
+	if entries[0].Actor != "Alice" || entries[2].Action != audit.ActionDelete {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	req = httptest.NewRequest("GET", "/reminders/nope/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestSmartLists(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	soon := time.Now().Add(24 * time.Hour)
+	later := time.Now().Add(30 * 24 * time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Soon", "", &soon, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Later", "", &later, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem3", "Bob's", "", &soon, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := do("POST", "/smart-lists", `{"family_id": "fam1", "name": "Alice this week", "query": {"assignee": "Alice", "due_from_days": 0, "due_to_days": 7}}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	var sl struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&sl)
+
+	resp = do("GET", "/smart-lists/"+sl.ID+"/reminders", "")
+	var got []reminder.Reminder
+	json.NewDecoder(resp.Body).Decode(&got)
+	if len(got) != 1 || got[0].ID != "rem1" {
+		t.Errorf("expected only rem1, got %+v", got)
+	}
+
+	resp = do("PUT", "/smart-lists/"+sl.ID, `{"name": "All of Alice's", "query": {"assignee": "Alice"}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	resp = do("GET", "/smart-lists/"+sl.ID+"/reminders", "")
+	got = nil
+	json.NewDecoder(resp.Body).Decode(&got)
+	if len(got) != 2 {
+		t.Errorf("expected both of Alice's reminders, got %+v", got)
+	}
+
+	// The same list as a filter expression
+	resp = do("POST", "/smart-lists", `{"family_id": "fam1", "name": "Alice this week", "query": {"q": "assignee:Alice AND due>=today AND due<+7d"}}`)
+	var expr struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&expr)
+	resp = do("GET", "/smart-lists/"+expr.ID+"/reminders", "")
+	got = nil
+	json.NewDecoder(resp.Body).Decode(&got)
+	if len(got) != 1 || got[0].ID != "rem1" {
+		t.Errorf("expected only rem1 from the expression, got %+v", got)
+	}
+	do("DELETE", "/smart-lists/"+expr.ID, "")
+
+	resp = do("GET", "/smart-lists?family_id=fam1", "")
+	var lists []map[string]any
+	json.NewDecoder(resp.Body).Decode(&lists)
+	if len(lists) != 1 || lists[0]["name"] != "All of Alice's" {
+		t.Errorf("unexpected smart lists: %v", lists)
+	}
+
+	for _, body := range []string{
+		`{"family_id": "fam1", "query": {}}`,
+		`{"family_id": "nope", "name": "x"}`,
+		`{"family_id": "fam1", "name": "x", "query": {"assignee": "Mallory"}}`,
+		`{"family_id": "fam1", "name": "x", "query": {"due_from_days": 3, "due_to_days": 1}}`,
+		`{"family_id": "fam1", "name": "x", "query": {"q": "assignee:Mallory"}}`,
+		`{"family_id": "fam1", "name": "x", "query": {"q": "due<soon"}}`,
+	} {
+		if resp := do("POST", "/smart-lists", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, resp.StatusCode)
+		}
+	}
+
+	if resp := do("DELETE", "/smart-lists/"+sl.ID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/smart-lists/"+sl.ID, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 after delete, got %d", resp.StatusCode)
+	}
+}
+
+func TestReorderReminders(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Carol"}})
+	for _, id := range []string{"rem1", "rem2", "rem3"} {
+		_ = Store.CreateReminder(reminder.NewReminder(id, id, "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	}
+	_ = Store.CreateReminder(reminder.NewReminder("other", "Other", "", nil, "fam2", "Carol", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := do("POST", "/reminders/reorder", `{"family_id": "fam1", "ids": ["rem3", "rem1", "rem2"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	positions := map[string]int{}
+	for _, id := range []string{"rem1", "rem2", "rem3"} {
+		r, _ := Store.GetReminder(id)
+		positions[id] = r.Position
+	}
+	if !(positions["rem3"] < positions["rem1"] && positions["rem1"] < positions["rem2"]) {
+		t.Errorf("unexpected positions after reorder: %v", positions)
+	}
+
+	// Swapping a subset keeps the other reminders where they are
+	resp = do("POST", "/reminders/reorder", `{"family_id": "fam1", "ids": ["rem2", "rem1"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	r3, _ := Store.GetReminder("rem3")
+	r2, _ := Store.GetReminder("rem2")
+	r1, _ := Store.GetReminder("rem1")
+	if r3.Position != positions["rem3"] || r2.Position != positions["rem1"] || r1.Position != positions["rem2"] {
+		t.Errorf("unexpected positions after swap: rem1=%d rem2=%d rem3=%d", r1.Position, r2.Position, r3.Position)
+	}
+
+	// New reminders go to the end of the family
+	resp = do("POST", "/reminders", `{"title": "New", "family_id": "fam1", "family_member": "Alice"}`)
+	var created reminder.Reminder
+	json.NewDecoder(resp.Body).Decode(&created)
+	if created.Position <= r1.Position {
+		t.Errorf("expected new reminder after position %d, got %d", r1.Position, created.Position)
+	}
+
+	resp = do("POST", "/reminders/reorder", `{"family_id": "fam1", "ids": ["rem1", "other"]}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for reminder from another family, got %d", resp.StatusCode)
+	}
+	resp = do("POST", "/reminders/reorder", `{"family_id": "fam1", "ids": ["rem1", "rem1"]}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for duplicate ids, got %d", resp.StatusCode)
+	}
+}
+
+func TestBatchReminders(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	_ = Store.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Carol"}})
+	_ = Store.CreateReminder(reminder.NewReminder("other", "Other", "", nil, "fam2", "Carol", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/reminders/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", `{"family_id": "fam1", "reminders": [
+		{"title": "Pack lunch", "family_member": "Alice", "due_date": "2024-09-02T07:00:00Z", "recurrence": {"type": "daily"}},
+		{"title": "Sign planner", "family_member": "Bob"},
+		{"title": "Check backpack", "family_id": "fam1", "family_member": "Alice"}
+	]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created []reminder.Reminder
+	json.NewDecoder(w.Body).Decode(&created)
+	if len(created) != 3 {
+		t.Fatalf("expected 3 reminders, got %d", len(created))
+	}
+	for i, rem := range created {
+		if rem.FamilyID != "fam1" || rem.Version != 1 {
+			t.Errorf("reminders[%d]: unexpected family or version: %+v", i, rem)
+		}
+		if i > 0 && rem.Position != created[i-1].Position+1 {
+			t.Errorf("expected consecutive positions, got %d after %d", rem.Position, created[i-1].Position)
+		}
+		if _, err := Store.GetReminder(rem.ID); err != nil {
+			t.Errorf("reminder %s wasn't saved: %v", rem.ID, err)
+		}
+	}
+
+	// Nothing is created if any reminder is invalid
+	before, _ := Store.ListReminders()
+	for _, body := range []string{
+		`{"family_id": "fam1", "reminders": [{"title": "Ok", "family_member": "Alice"}, {"title": "Bad", "family_member": "Zed"}]}`,
+		`{"family_id": "fam1", "reminders": [{"title": "Elsewhere", "family_id": "fam2", "family_member": "Carol"}]}`,
+		`{"family_id": "fam1", "reminders": []}`,
+		`{"reminders": [{"title": "No family", "family_member": "Alice"}]}`,
+	} {
+		if w := do("POST", body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+	after, _ := Store.ListReminders()
+	if len(after) != len(before) {
+		t.Errorf("expected no reminders from rejected batches, got %d more", len(after)-len(before))
+	}
+
+	t.Run("Patch", func(t *testing.T) {
+		body := fmt.Sprintf(`{"family_id": "fam1", "reminders": [
+			{"id": %q, "version": 1, "title": "Pack lunches"},
+			{"id": %q, "version": 1, "family_member": "Alice", "effort": 5}
+		]}`, created[0].ID, created[1].ID)
+		w := do("PATCH", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		first, _ := Store.GetReminder(created[0].ID)
+		second, _ := Store.GetReminder(created[1].ID)
+		if first.Title != "Pack lunches" || first.Version != 2 || first.Recurrence.Type != "daily" {
+			t.Errorf("unexpected first reminder: %+v", first)
+		}
+		if second.FamilyMember != "Alice" || second.Effort != 5 || second.Title != "Sign planner" {
+			t.Errorf("unexpected second reminder: %+v", second)
+		}
+
+		for name, body := range map[string]string{
+			"other family":    fmt.Sprintf(`{"family_id": "fam1", "reminders": [{"id": %q, "version": 1, "title": "x"}, {"id": "other", "version": 1, "title": "y"}]}`, created[2].ID),
+			"duplicate":       fmt.Sprintf(`{"family_id": "fam1", "reminders": [{"id": %q, "version": 1}, {"id": %q, "version": 1}]}`, created[2].ID, created[2].ID),
+			"missing id":      `{"family_id": "fam1", "reminders": [{"title": "x", "version": 1}]}`,
+			"missing version": fmt.Sprintf(`{"family_id": "fam1", "reminders": [{"id": %q, "title": "x"}]}`, created[2].ID),
+			"completion":      fmt.Sprintf(`{"family_id": "fam1", "reminders": [{"id": %q, "version": 1, "completed": true}]}`, created[2].ID),
+		} {
+			if w := do("PATCH", body); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", name, w.Code)
+			}
+		}
+		// A patch made against an older version would undo the first edit
+		stale := fmt.Sprintf(`{"family_id": "fam1", "reminders": [
+			{"id": %q, "version": 1, "title": "Check backpacks"},
+			{"id": %q, "version": 1, "title": "Pack lunch"}
+		]}`, created[2].ID, created[0].ID)
+		if w := do("PATCH", stale); w.Code != http.StatusConflict {
+			t.Errorf("stale version: expected status 409, got %d: %s", w.Code, w.Body.String())
+		}
+		if first, _ := Store.GetReminder(created[0].ID); first.Title != "Pack lunches" {
+			t.Errorf("expected a stale batch to leave the edited reminder alone, got %+v", first)
+		}
+		third, _ := Store.GetReminder(created[2].ID)
+		if third.Title != "Check backpack" || third.Version != 1 {
+			t.Errorf("expected rejected batches to leave the reminder alone, got %+v", third)
+		}
+	})
+}
+
+func TestUsageBasedReminders(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateReminder(reminder.NewReminder("dated", "Dated", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(ActorHeader, "Alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/reminders", `{"title": "Change the oil", "family_id": "fam1", "family_member": "Alice",
+		"usage": {"counter": "odometer", "unit": "km", "interval": 8000, "lead": 500, "baseline": 40000, "reading": 40000}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created reminder.Reminder
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.DueDate != nil {
+		t.Errorf("expected no due date before the threshold, got %v", created.DueDate)
+	}
+	url := "/reminders/" + created.ID + "/usage"
+
+	if w := do("POST", url, `{"value": 45000}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if rem, _ := Store.GetReminder(created.ID); rem.DueDate != nil || rem.Usage.Reading != 45000 {
+		t.Errorf("expected reading 45000 and no due date, got %v and %v", rem.Usage.Reading, rem.DueDate)
+	}
+	if w := do("POST", url, `{"value": 44000}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a decreasing reading, got %d", w.Code)
+	}
+	// The lead makes it due at 47500
+	if w := do("POST", url, `{"value": 47600}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if rem, _ := Store.GetReminder(created.ID); rem.DueDate == nil {
+		t.Error("expected the threshold to make the reminder due")
+	}
+
+	w = do("GET", url, "")
+	var readings []reminder.UsageReading
+	json.NewDecoder(w.Body).Decode(&readings)
+	if len(readings) != 2 || readings[0].Value != 45000 || readings[0].Triggered || !readings[1].Triggered || readings[1].ReportedBy != "Alice" {
+		t.Errorf("unexpected readings: %+v", readings)
+	}
+
+	// Completing starts the next interval from the latest reading
+	if w := do("PATCH", "/reminders/"+created.ID, `{"completed": true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	rem, _ := Store.GetReminder(created.ID)
+	if rem.Completed || rem.DueDate != nil || rem.Usage.Baseline != 47600 || rem.CompletedAt == nil {
+		t.Errorf("expected a reset usage interval, got completed=%v due=%v usage=%+v", rem.Completed, rem.DueDate, rem.Usage)
+	}
+	if w := do("PATCH", "/reminders/"+created.ID, `{"completed": true}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 completing again without new usage, got %d", w.Code)
+	}
+	do("POST", url, `{"value": 47700}`)
+	if w := do("PATCH", "/reminders/"+created.ID, `{"completed": true}`); w.Code != http.StatusOK {
+		t.Errorf("expected status 200 completing after new usage, got %d", w.Code)
+	}
+
+	if w := do("POST", "/reminders/dated/usage", `{"value": 1}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a date-based reminder, got %d", w.Code)
+	}
+	for _, body := range []string{
+		`{"title": "x", "family_id": "fam1", "family_member": "Alice", "recurrence": {"type": "daily"}, "usage": {"counter": "hours", "interval": 300}}`,
+		`{"title": "x", "family_id": "fam1", "family_member": "Alice", "usage": {"counter": "hours", "interval": 0}}`,
+		`{"title": "x", "family_id": "fam1", "family_member": "Alice", "usage": {"interval": 300}}`,
+	} {
+		if w := do("POST", "/reminders", body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	// A reading already past the threshold makes a new reminder due at once
+	w = do("POST", "/reminders", `{"title": "Replace the filter", "family_id": "fam1", "family_member": "Alice",
+		"usage": {"counter": "filter_hours", "unit": "hours", "interval": 300, "reading": 320}}`)
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.DueDate == nil {
+		t.Error("expected an overdue filter to be due")
+	}
+}
+
+func TestReminderStatusWorkflow(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Paint the shed", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(ActorHeader, "Alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := do("POST", "/reminders/rem1/status", `{"status": "blocked"}`)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected status 409 for todo -> blocked, got %d", resp.StatusCode)
+	}
+	resp = do("POST", "/reminders/rem1/status", `{"status": "someday"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown status, got %d", resp.StatusCode)
+	}
+
+	for _, status := range []string{"doing", "blocked", "doing", "done"} {
+		resp = do("POST", "/reminders/rem1/status", `{"status": "`+status+`"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200 moving to %s, got %d", status, resp.StatusCode)
+		}
+	}
+	r, _ := Store.GetReminder("rem1")
+	if !r.Completed || r.CompletedAt == nil {
+		t.Errorf("expected reminder to be completed, got %+v", r)
+	}
+	events, _ := Store.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: "rem1"})
+	if len(events) != 1 || events[0].CompletedBy != "Alice" {
+		t.Errorf("expected one completion event by Alice, got %+v", events)
+	}
+
+	resp = do("POST", "/reminders/rem1/status", `{"status": "todo"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 reopening, got %d", resp.StatusCode)
+	}
+	r, _ = Store.GetReminder("rem1")
+	if r.Completed || r.Status != "todo" {
+		t.Errorf("expected reopened reminder in todo, got %+v", r)
+	}
+	if events, _ := Store.ListCompletionEvents("rem1"); len(events) != 0 {
+		t.Errorf("expected reopening to delete the completion, got %+v", events)
+	}
+
+	resp = do("GET", "/reminders/rem1/status-events", "")
+	var sevs []reminder.StatusEvent
+	json.NewDecoder(resp.Body).Decode(&sevs)
+	if len(sevs) != 5 || sevs[0].From != "todo" || sevs[0].To != "doing" || sevs[4].To != "todo" || sevs[0].ChangedBy != "Alice" {
+		t.Errorf("unexpected status events: %+v", sevs)
+	}
+
+	// A custom workflow replaces the default statuses and transitions
+	resp = do("PUT", "/families/fam1/settings", `{"workflow": {"statuses": ["idea", "done"], "transitions": {"idea": ["done"]}}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Treehouse", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	resp = do("POST", "/reminders/rem2/status", `{"status": "doing"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for status outside custom workflow, got %d", resp.StatusCode)
+	}
+	resp = do("POST", "/reminders/rem2/status", `{"status": "done"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200 for idea -> done, got %d", resp.StatusCode)
+	}
+
+	resp = do("PUT", "/families/fam1/settings", `{"workflow": {"statuses": ["idea"], "transitions": {}}}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for workflow without done, got %d", resp.StatusCode)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Carol"}})
+	router := setupRouter()
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := do("POST", "/projects", `{"family_id": "fam1", "name": "Plan birthday party", "due_date": "2030-06-01T00:00:00Z"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	var p struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Progress struct {
+			Total     int `json:"total"`
+			Completed int `json:"completed"`
+			Percent   int `json:"percent"`
+		} `json:"progress"`
+	}
+	json.NewDecoder(resp.Body).Decode(&p)
+
+	for _, title := range []string{"Invitations", "Cake"} {
+		resp = do("POST", "/reminders", `{"title": "`+title+`", "family_id": "fam1", "family_member": "Alice", "project_id": "`+p.ID+`"}`)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", resp.StatusCode)
+		}
+	}
+	var cake reminder.Reminder
+	json.NewDecoder(resp.Body).Decode(&cake)
+	_ = Store.CreateReminder(reminder.NewReminder("rem9", "Unrelated", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+
+	resp = do("POST", "/reminders", `{"title": "Sneaky", "family_id": "fam2", "family_member": "Carol", "project_id": "`+p.ID+`"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for project of another family, got %d", resp.StatusCode)
+	}
+
+	resp = do("PATCH", "/reminders/"+cake.ID, `{"completed": true}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	resp = do("GET", "/projects/"+p.ID, "")
+	json.NewDecoder(resp.Body).Decode(&p)
+	if p.Progress.Total != 2 || p.Progress.Completed != 1 || p.Progress.Percent != 50 {
+		t.Errorf("unexpected progress: %+v", p.Progress)
+	}
+
+	resp = do("GET", "/projects/"+p.ID+"/reminders", "")
+	var got []reminder.Reminder
+	json.NewDecoder(resp.Body).Decode(&got)
+	if len(got) != 2 {
+		t.Errorf("expected 2 project reminders, got %d", len(got))
+	}
+
+	resp = do("PUT", "/projects/"+p.ID, `{"name": "Birthday party"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	resp = do("GET", "/projects?family_id=fam1", "")
+	var list []struct {
+		Name string `json:"name"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	if len(list) != 1 || list[0].Name != "Birthday party" {
+		t.Errorf("unexpected project list: %+v", list)
+	}
+
+	if resp := do("DELETE", "/projects/"+p.ID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/projects/"+p.ID, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 after delete, got %d", resp.StatusCode)
+	}
+	r, err := Store.GetReminder(cake.ID)
+	if err != nil || r.ProjectID != "" {
+		t.Errorf("expected reminder to survive and leave the project, got %+v, %v", r, err)
+	}
+}
+
+func TestFamilyWorkload(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob", "Carol"}, Settings: family.Settings{Timezone: "UTC"}})
+	router := setupRouter()
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	for _, body := range []string{
+		`{"title": "Dishes", "family_id": "fam1", "family_member": "Alice", "due_date": "2030-03-01T08:00:00Z", "effort": 10, "recurrence": {"type": "daily"}}`,
+		`{"title": "Laundry", "family_id": "fam1", "family_member": "Bob", "due_date": "2030-03-01T08:00:00Z", "effort": 30, "recurrence": {"type": "weekly", "days": ["monday", "thursday"]}}`,
+		`{"title": "Dentist", "family_id": "fam1", "family_member": "Alice", "due_date": "2030-03-06T14:00:00Z", "effort": 15}`,
+		`{"title": "Next week", "family_id": "fam1", "family_member": "Bob", "due_date": "2030-03-12T14:00:00Z", "effort": 45}`,
+	} {
+		if resp := do("POST", "/reminders", body); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", resp.StatusCode)
+		}
+	}
+	if resp := do("POST", "/reminders", `{"title": "x", "family_id": "fam1", "family_member": "Bob", "effort": -5}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for negative effort, got %d", resp.StatusCode)
+	}
+
+	for _, week := range []string{"2030-W10", "2030-03-06"} {
+		resp := do("GET", "/families/fam1/workload?week="+week, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var report struct {
+			Week         string `json:"week"`
+			TotalMinutes int    `json:"total_minutes"`
+			Members      []struct {
+				Member      string `json:"member"`
+				Minutes     int    `json:"minutes"`
+				Occurrences int    `json:"occurrences"`
+			} `json:"members"`
+		}
+		json.NewDecoder(resp.Body).Decode(&report)
+		if report.Week != "2030-W10" || report.TotalMinutes != 145 || len(report.Members) != 3 {
+			t.Fatalf("unexpected report for %s: %+v", week, report)
+		}
+		alice, bob, carol := report.Members[0], report.Members[1], report.Members[2]
+		if alice.Minutes != 85 || alice.Occurrences != 8 || bob.Minutes != 60 || bob.Occurrences != 2 || carol.Minutes != 0 {
+			t.Errorf("unexpected member loads for %s: %+v", week, report.Members)
+		}
+	}
+
+	if resp := do("GET", "/families/fam1/workload?week=soon", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid week, got %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/families/nope/workload", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown family, got %d", resp.StatusCode)
+	}
+}
+
+func TestFamilyLeaderboard(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob", "Carol"}, Settings: family.Settings{Timezone: "UTC"}})
+	router := setupRouter()
+
+	do := func(method, url, actor, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if actor != "" {
+			req.Header.Set(ActorHeader, actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+	create := func(body string) string {
+		resp := do("POST", "/reminders", "", body)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", resp.StatusCode)
+		}
+		var rem reminder.Reminder
+		json.NewDecoder(resp.Body).Decode(&rem)
+		return rem.ID
+	}
+
+	for _, points := range []string{"-1", "1001"} {
+		if resp := do("POST", "/reminders", "", `{"title": "x", "family_id": "fam1", "family_member": "Bob", "points": `+points+`}`); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s points, got %d", points, resp.StatusCode)
+		}
+	}
+	dishes := create(`{"title": "Dishes", "family_id": "fam1", "family_member": "Alice", "points": 10}`)
+	meds := create(`{"title": "Medication", "family_id": "fam1", "family_member": "Bob", "points": 20, "requires_confirmation": true}`)
+	trash := create(`{"title": "Trash", "family_id": "fam1", "family_member": "Carol", "points": 5}`)
+
+	if resp := do("PATCH", "/reminders/"+dishes, "", `{"completed": true}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	// The first sign-off earns the points once the second one confirms it
+	for _, actor := range []string{"Bob", "Alice"} {
+		if resp := do("PATCH", "/reminders/"+meds, actor, `{"completed": true}`); resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+	}
+	// Points posted with a completion event are replaced by the reminder's
+	if resp := do("POST", "/completion-events", "", `{"reminder_id": "`+trash+`", "completed_by": "Carol", "points": 500}`); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+
+	type board struct {
+		Window  string           `json:"window"`
+		From    time.Time        `json:"from"`
+		To      time.Time        `json:"to"`
+		Members []stats.Standing `json:"members"`
+	}
+	want := []stats.Standing{
+		{Rank: 1, Member: "Bob", Points: 20, Completions: 1},
+		{Rank: 2, Member: "Alice", Points: 10, Completions: 2},
+		{Rank: 3, Member: "Carol", Points: 5, Completions: 1},
+	}
+	for _, window := range []string{"", "week", "month"} {
+		resp := do("GET", "/families/fam1/leaderboard?window="+window, "", "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var b board
+		json.NewDecoder(resp.Body).Decode(&b)
+		if !slices.Equal(b.Members, want) || !b.From.Before(time.Now()) || !b.To.After(time.Now()) {
+			t.Errorf("unexpected %q leaderboard: %+v", window, b)
+		}
+	}
+
+	resp := do("GET", "/families/fam1/leaderboard?window=month&month=2020-01", "", "")
+	var past board
+	json.NewDecoder(resp.Body).Decode(&past)
+	if past.Window != "month" || !past.From.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) || !past.To.Equal(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)) || len(past.Members) != 3 || past.Members[0].Points != 0 {
+		t.Errorf("unexpected leaderboard for January 2020: %+v", past)
+	}
+	for _, url := range []string{"/families/fam1/leaderboard?window=year", "/families/fam1/leaderboard?week=soon", "/families/fam1/leaderboard?window=month&month=Jan"} {
+		if resp := do("GET", url, "", ""); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", url, resp.StatusCode)
+		}
+	}
+	if resp := do("GET", "/families/nope/leaderboard", "", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown family, got %d", resp.StatusCode)
+	}
+}
+
+func TestOverdueReminders(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}, Settings: family.Settings{Timezone: "UTC"}})
+	router := setupRouter()
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	past := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	escalation := `"escalation": {"repeat_minutes": 30, "steps": [{"after_minutes": 60, "member": "Alice"}]}`
+	for _, body := range []string{
+		`{"title": "Trash", "family_id": "fam1", "family_member": "Bob", "due_date": "` + past + `", ` + escalation + `}`,
+		`{"title": "Dishes", "family_id": "fam1", "family_member": "Bob", "due_date": "` + future + `"}`,
+	} {
+		if resp := do("POST", "/reminders", body); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", resp.StatusCode)
+		}
+	}
+	for _, e := range []string{
+		`{"repeat_minutes": 1}`,
+		`{"steps": [{"after_minutes": 60, "member": "Zed"}]}`,
+	} {
+		body := `{"title": "x", "family_id": "fam1", "family_member": "Bob", "escalation": ` + e + `}`
+		if resp := do("POST", "/reminders", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status 400 for escalation %s, got %d", e, resp.StatusCode)
+		}
+	}
+
+	type listed struct {
+		Title        string               `json:"title"`
+		Overdue      bool                 `json:"overdue"`
+		OverdueSince *time.Time           `json:"overdue_since"`
+		Escalation   *reminder.Escalation `json:"escalation"`
+	}
+	list := func(query string) []listed {
+		resp := do("GET", "/reminders?family_id=fam1&sort=title"+query, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var result []listed
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result
+	}
+	all := list("")
+	if len(all) != 2 || all[0].Title != "Dishes" || all[0].Overdue || all[0].OverdueSince != nil {
+		t.Fatalf("expected Dishes not to be overdue, got %+v", all)
+	}
+	if trash := all[1]; !trash.Overdue || trash.OverdueSince == nil || trash.OverdueSince.Format(time.RFC3339) != past ||
+		trash.Escalation == nil || trash.Escalation.RepeatMinutes != 30 || len(trash.Escalation.Steps) != 1 {
+		t.Errorf("expected Trash to be overdue with its escalation, got %+v", trash)
+	}
+	if overdue := list("&overdue=true"); len(overdue) != 1 || overdue[0].Title != "Trash" {
+		t.Errorf("expected only Trash to be overdue, got %+v", overdue)
+	}
+	if onTime := list("&overdue=false"); len(onTime) != 1 || onTime[0].Title != "Dishes" {
+		t.Errorf("expected only Dishes to be on time, got %+v", onTime)
+	}
+	if resp := do("GET", "/reminders?overdue=maybe", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid overdue, got %d", resp.StatusCode)
+	}
+
+	// Recording a completion event directly completes the reminder, and
+	// deleting it makes it overdue again
+	var trashID string
+	rems, _ := Store.ListReminders()
+	for _, rem := range rems {
+		if rem.Title == "Trash" {
+			trashID = rem.ID
+		}
+	}
+	resp := do("POST", "/completion-events", `{"reminder_id": "`+trashID+`", "completed_by": "Bob"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	var recorded reminder.CompletionEvent
+	json.NewDecoder(resp.Body).Decode(&recorded)
+	if overdue := list("&overdue=true"); len(overdue) != 0 {
+		t.Errorf("expected nothing overdue after a completion event, got %+v", overdue)
+	}
+	if resp := do("DELETE", "/completion-events/"+recorded.ID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", resp.StatusCode)
+	}
+	if overdue := list("&overdue=true"); len(overdue) != 1 || overdue[0].Title != "Trash" {
+		t.Errorf("expected Trash to be overdue again once its completion was deleted, got %+v", overdue)
+	}
+}
+
+func TestDigestPreview(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}, Settings: family.Settings{Timezone: "UTC"}})
+	past := time.Now().Add(-2 * time.Hour)
+	soon := time.Now().Add(2 * time.Hour)
+	later := time.Now().Add(3 * 24 * time.Hour)
+	once := reminder.RecurrencePattern{Type: "once"}
+	_ = Store.CreateReminder(reminder.NewReminder("r1", "Trash & recycling", "", &past, "fam1", "Bob", once))
+	_ = Store.CreateReminder(reminder.NewReminder("r2", "Dishes", "", &soon, "fam1", "Bob", once))
+	_ = Store.CreateReminder(reminder.NewReminder("r3", "Dentist", "", &later, "fam1", "Bob", once))
+	_ = Store.CreateReminder(reminder.NewReminder("r4", "Homework", "", &soon, "fam1", "Alice", once))
+	router := setupRouter()
+
+	do := func(method, url, actor, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if actor != "" {
+			req.Header.Set(ActorHeader, actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := do("GET", "/digest/preview?family_id=fam1&member=Bob", "", "")
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("expected an HTML digest, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{"Bob's daily digest", "Overdue", "Trash &amp; recycling", "Dishes"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("HTML digest missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), "Dentist") || strings.Contains(string(body), "Homework") {
+		t.Errorf("expected only Bob's reminders of the next day:\n%s", body)
+	}
+
+	// The weekly frequency comes from the member's settings
+	if resp := do("PUT", "/families/fam1/settings", "", `{"timezone": "UTC", "members": {"Bob": {"digest": {"time": "07:00", "frequency": "fortnightly"}}}}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid frequency, got %d", resp.StatusCode)
+	}
+	if resp := do("PUT", "/families/fam1/settings", "", `{"timezone": "UTC", "members": {"Bob": {"digest": {"time": "07:00", "frequency": "weekly", "weekday": "sunday"}}}}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var d digest.Digest
+	json.NewDecoder(do("GET", "/digest/preview?family_id=fam1&format=json", "Bob", "").Body).Decode(&d)
+	if d.Frequency != "weekly" || len(d.Overdue) != 1 || d.Overdue[0].ReminderID != "r1" || len(d.Upcoming) != 2 || d.Upcoming[1].ReminderID != "r3" {
+		t.Errorf("unexpected weekly digest: %+v", d)
+	}
+	resp = do("GET", "/digest/preview?family_id=fam1&member=Alice&frequency=daily&format=text", "", "")
+	if text, _ := io.ReadAll(resp.Body); !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") || !strings.Contains(string(text), "- Homework at ") {
+		t.Errorf("unexpected text digest: %s", text)
+	}
+
+	for _, url := range []string{"/digest/preview", "/digest/preview?family_id=fam1", "/digest/preview?family_id=fam1&member=Zed", "/digest/preview?family_id=fam1&member=Bob&frequency=hourly", "/digest/preview?family_id=fam1&member=Bob&format=pdf"} {
+		if resp := do("GET", url, "", ""); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", url, resp.StatusCode)
+		}
+	}
+	if resp := do("GET", "/digest/preview?family_id=nope&member=Bob", "", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown family, got %d", resp.StatusCode)
+	}
+}
+
+func TestAutoAssignment(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob", "Carol"}})
+	due := time.Now().Add(-48 * time.Hour)
+	heavy := reminder.NewReminder("heavy", "Mow the lawn", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"})
+	heavy.Effort = 60
+	_ = Store.CreateReminder(heavy)
+	light := reminder.NewReminder("light", "Feed the cat", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "daily"})
+	light.Effort = 5
+	_ = Store.CreateReminder(light)
+	router := setupRouter()
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	// Carol has nothing scheduled, so she gets the new chore
+	resp := do("POST", "/reminders", `{"title": "Dishes", "family_id": "fam1", "effort": 15, "assignment": "least_loaded", "due_date": "`+due.Format(time.RFC3339)+`", "recurrence": {"type": "daily"}}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	var dishes reminder.Reminder
+	json.NewDecoder(resp.Body).Decode(&dishes)
+	if dishes.FamilyMember != "Carol" {
+		t.Errorf("expected Carol to be assigned, got %q", dishes.FamilyMember)
+	}
+
+	// Once Carol has more to do than Bob, the next occurrence goes to Bob
+	busy := reminder.NewReminder("busy", "Homework", "", &due, "fam1", "Carol", reminder.RecurrencePattern{Type: "daily"})
+	busy.Effort = 30
+	_ = Store.CreateReminder(busy)
+	if resp := do("PATCH", "/reminders/"+dishes.ID, `{"completed": true}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	r, _ := Store.GetReminder(dishes.ID)
+	if r.FamilyMember != "Bob" {
+		t.Errorf("expected Bob to be assigned the next occurrence, got %q", r.FamilyMember)
+	}
+	events, _ := Store.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: dishes.ID})
+	if len(events) != 1 || events[0].CompletedBy != "Carol" {
+		t.Errorf("expected the completion to be credited to Carol, got %+v", events)
+	}
+
+	resp = do("POST", "/reminders", `{"title": "Trash", "family_id": "fam1", "family_member": "Carol", "assignment": "round_robin", "recurrence": {"type": "daily"}, "due_date": "`+due.Format(time.RFC3339)+`"}`)
+	var trash reminder.Reminder
+	json.NewDecoder(resp.Body).Decode(&trash)
+	for _, want := range []string{"Alice", "Bob"} {
+		if resp := do("PATCH", "/reminders/"+trash.ID+"?force=true", `{"completed": true}`); resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		r, _ := Store.GetReminder(trash.ID)
+		if r.FamilyMember != want {
+			t.Errorf("expected round robin to assign %s, got %q", want, r.FamilyMember)
+		}
+	}
+
+	if resp := do("POST", "/reminders", `{"title": "x", "family_id": "fam1", "assignment": "lottery"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown strategy, got %d", resp.StatusCode)
+	}
+	if resp := do("POST", "/reminders", `{"title": "x", "family_id": "fam1"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 without member or strategy, got %d", resp.StatusCode)
+	}
+}
+
+func TestRotatingAssignee(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob", "Carol"}})
+	// Noon yesterday, so completing now leaves tomorrow's as the next and
+	// third occurrence whatever the time of day
+	due := reminder.StartOfDay(time.Now().UTC()).Add(-12 * time.Hour)
+	router := setupRouter()
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := do("POST", "/reminders", `{"title": "Dishes", "family_id": "fam1", "rotation": {"members": ["Alice", "Bob", "Carol"]}, "recurrence": {"type": "daily"}, "due_date": "`+due.Format(time.RFC3339)+`"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	var dishes reminder.Reminder
+	json.NewDecoder(resp.Body).Decode(&dishes)
+	if dishes.FamilyMember != "Alice" {
+		t.Errorf("expected Alice to have the first turn, got %q", dishes.FamilyMember)
+	}
+
+	if resp := do("PATCH", "/reminders/"+dishes.ID, `{"completed": true}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	r, _ := Store.GetReminder(dishes.ID)
+	if r.FamilyMember != "Carol" {
+		t.Errorf("expected Carol to have the third occurrence, got %q", r.FamilyMember)
+	}
+
+	// Carol drops out of the rotation, so nothing needs handing over and
+	// the turn passes on
+	if resp := do("DELETE", "/families/fam1/members/Carol", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", resp.StatusCode)
+	}
+	r, _ = Store.GetReminder(dishes.ID)
+	if r.Rotation == nil || !slices.Equal(r.Rotation.Members, []string{"Alice", "Bob"}) || r.FamilyMember != "Alice" {
+		t.Errorf("expected Alice's turn in a rotation of Alice and Bob, got %q in %+v", r.FamilyMember, r.Rotation)
+	}
+
+	for body, why := range map[string]string{
+		`{"title": "x", "family_id": "fam1", "rotation": {"members": ["Alice"]}, "due_date": "` + due.Format(time.RFC3339) + `"}`:                                                               "a one-off reminder",
+		`{"title": "x", "family_id": "fam1", "rotation": {"members": ["Alice", "Alice"]}, "recurrence": {"type": "daily"}, "due_date": "` + due.Format(time.RFC3339) + `"}`:                     "a repeated member",
+		`{"title": "x", "family_id": "fam1", "rotation": {"members": ["Alice", "Zed"]}, "recurrence": {"type": "daily"}, "due_date": "` + due.Format(time.RFC3339) + `"}`:                       "an unknown member",
+		`{"title": "x", "family_id": "fam1", "rotation": {"members": ["Alice"]}, "assignment": "round_robin", "recurrence": {"type": "daily"}, "due_date": "` + due.Format(time.RFC3339) + `"}`: "an assignment strategy",
+	} {
+		if resp := do("POST", "/reminders", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status 400 for a rotation with %s, got %d", why, resp.StatusCode)
+		}
+	}
+}
+
+func TestReminderTags(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	_ = Store.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Carol"}})
+	router := setupRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("POST", "/reminders", `{"title": "Pack lunch", "family_id": "fam1", "family_member": "Alice", "tags": [" School", "kids", "school"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	var lunch reminder.Reminder
+	json.NewDecoder(w.Body).Decode(&lunch)
+	if !slices.Equal(lunch.Tags, []string{"school", "kids"}) {
+		t.Errorf("expected normalized tags, got %v", lunch.Tags)
+	}
+	do("POST", "/reminders", `{"title": "Permission slip", "family_id": "fam1", "family_member": "Bob", "tags": ["school"]}`)
+	do("POST", "/reminders", `{"title": "Untagged", "family_id": "fam1", "family_member": "Bob"}`)
+	do("POST", "/reminders", `{"title": "Elsewhere", "family_id": "fam2", "family_member": "Carol", "tags": ["school"]}`)
+
+	var list []reminder.Reminder
+	json.NewDecoder(do("GET", "/reminders?family_id=fam1&tag=School", "").Body).Decode(&list)
+	if len(list) != 2 {
+		t.Errorf("expected 2 reminders tagged school, got %+v", list)
+	}
+
+	var counts []reminder.TagCount
+	json.NewDecoder(do("GET", "/tags?family_id=fam1", "").Body).Decode(&counts)
+	if want := []reminder.TagCount{{Tag: "school", Count: 2}, {Tag: "kids", Count: 1}}; !slices.Equal(counts, want) {
+		t.Errorf("expected tag counts %v, got %v", want, counts)
+	}
+	if w := do("GET", "/tags", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without family_id, got %d", w.Code)
+	}
+
+	// Merge patches replace the tags
+	req := httptest.NewRequest("PATCH", "/reminders/"+lunch.ID, strings.NewReader(`{"tags": ["Lunch"]}`))
+	req.Header.Set("If-Match", reminderETag(&lunch))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 patching tags, got %d: %s", w.Code, w.Body)
+	}
+	if r, _ := Store.GetReminder(lunch.ID); !slices.Equal(r.Tags, []string{"lunch"}) {
+		t.Errorf("expected the patched tags, got %v", r.Tags)
+	}
+
+	for body, why := range map[string]string{
+		`{"title": "x", "family_id": "fam1", "family_member": "Alice", "tags": [" "]}`:                                                    "a blank tag",
+		`{"title": "x", "family_id": "fam1", "family_member": "Alice", "tags": ["` + strings.Repeat("x", reminder.MaxTagLength+1) + `"]}`: "an overlong tag",
+	} {
+		if w := do("POST", "/reminders", body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", why, w.Code)
+		}
+	}
+}
+
+func TestReminderPriority(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	router := setupRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return w
+	}
+
+	for _, body := range []string{
+		`{"title": "Later", "family_id": "fam1", "family_member": "Alice", "due_date": "2024-03-05T09:00:00Z"}`,
+		`{"title": "Sooner", "family_id": "fam1", "family_member": "Alice", "due_date": "2024-03-04T09:00:00Z"}`,
+		`{"title": "Pills", "family_id": "fam1", "family_member": "Alice", "due_date": "2024-03-06T09:00:00Z", "priority": "urgent"}`,
+		`{"title": "Someday", "family_id": "fam1", "family_member": "Alice", "priority": "low"}`,
+	} {
+		if w := do("POST", "/reminders", body); w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body)
+		}
+	}
+	var list []reminder.Reminder
+	json.NewDecoder(do("GET", "/reminders?family_id=fam1&sort=priority,due_date", "").Body).Decode(&list)
+	var titles []string
+	for _, r := range list {
+		titles = append(titles, r.Title)
+	}
+	if want := []string{"Pills", "Sooner", "Later", "Someday"}; !slices.Equal(titles, want) {
+		t.Errorf("expected %v, got %v", want, titles)
+	}
+
+	if w := do("GET", "/reminders?sort=colour", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown sort, got %d", w.Code)
+	}
+	if w := do("POST", "/reminders", `{"title": "x", "family_id": "fam1", "family_member": "Alice", "priority": "critical"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown priority, got %d", w.Code)
+	}
+}
+
+func TestEditOccurrences(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	router := setupRouter()
+	due := time.Date(2030, 1, 7, 19, 0, 0, 0, time.UTC) // a Monday
+	day := func(d int) string { return due.AddDate(0, 0, d).Format(time.RFC3339) }
+
+	do := func(method, url, etag, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w := do("POST", "/reminders", "", `{"title": "Trash", "family_id": "fam1", "family_member": "Alice", "recurrence": {"type": "daily"}, "due_date": "`+day(0)+`"}`)
+	var trash reminder.Reminder
+	json.NewDecoder(w.Body).Decode(&trash)
+
+	// This occurrence only
+	w = do("PATCH", "/reminders/"+trash.ID+"?scope=occurrence&occurrence="+day(2), reminderETag(&trash), `{"family_member": "Bob"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var edit seriesEdit
+	json.NewDecoder(w.Body).Decode(&edit)
+	if edit.Reminder.IsRecurring() || edit.Reminder.FamilyMember != "Bob" || edit.Reminder.DueDate.Format(time.RFC3339) != day(2) {
+		t.Errorf("expected a one-off occurrence for Bob, got %+v", edit.Reminder)
+	}
+	series, _ := Store.GetReminder(trash.ID)
+	if series.FamilyMember != "Alice" || len(series.Exceptions) != 1 || series.IsOccurrence(due.AddDate(0, 0, 2)) {
+		t.Errorf("expected the series to skip the edited occurrence, got %+v", series)
+	}
+
+	// This and the following occurrences
+	w = do("PATCH", "/reminders/"+trash.ID+"?scope=following&occurrence="+day(4), reminderETag(series), `{"title": "Recycling"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	edit = seriesEdit{}
+	json.NewDecoder(w.Body).Decode(&edit)
+	if edit.Reminder.Title != "Recycling" || !edit.Reminder.IsRecurring() || edit.Reminder.DueDate.Format(time.RFC3339) != day(4) {
+		t.Errorf("expected a new series of Recycling from day 4, got %+v", edit.Reminder)
+	}
+	series, _ = Store.GetReminder(trash.ID)
+	if series.Title != "Trash" || series.IsOccurrence(due.AddDate(0, 0, 4)) || !series.IsOccurrence(due.AddDate(0, 0, 3)) {
+		t.Errorf("expected Trash to end before day 4, got %+v", series)
+	}
+
+	// From the first occurrence, the whole series is edited
+	w = do("PATCH", "/reminders/"+trash.ID+"?scope=following&occurrence="+day(0), reminderETag(series), `{"description": "Bins out"}`)
+	var whole reminder.Reminder
+	json.NewDecoder(w.Body).Decode(&whole)
+	if w.Code != http.StatusOK || whole.ID != trash.ID || whole.Description != "Bins out" {
+		t.Errorf("expected the series itself to be edited, got %d %+v", w.Code, whole)
+	}
+
+	series, _ = Store.GetReminder(trash.ID)
+	for url, why := range map[string]string{
+		"?scope=sometimes":                       "an unknown scope",
+		"?scope=occurrence":                      "a missing occurrence",
+		"?scope=occurrence&occurrence=" + day(2): "an occurrence already detached",
+		"?scope=occurrence&occurrence=" + due.Add(time.Hour).Format(time.RFC3339): "a time that isn't an occurrence",
+	} {
+		if w := do("PATCH", "/reminders/"+trash.ID+url, reminderETag(series), `{"title": "x"}`); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", why, w.Code)
+		}
+	}
+	if w := do("PATCH", "/reminders/"+trash.ID+"?scope=occurrence&occurrence="+day(1), "", `{"title": "x"}`); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("expected status 428 without If-Match, got %d", w.Code)
+	}
+
+	// The series links all three, and its history spans them
+	var view seriesView
+	json.NewDecoder(do("GET", "/series/"+trash.ID, "", "").Body).Decode(&view)
+	var ids []string
+	for _, r := range view.Reminders {
+		ids = append(ids, r.ID)
+		if r.Series() != trash.ID {
+			t.Errorf("expected %s in series %s, got %q", r.ID, trash.ID, r.SeriesID)
+		}
+	}
+	if len(ids) != 3 || ids[0] != trash.ID || ids[2] != edit.Reminder.ID {
+		t.Errorf("expected the series by due date, got %v", ids)
+	}
+	if len(view.History) < 5 {
+		t.Errorf("expected the history of every part, got %d entries", len(view.History))
+	}
+	if w := do("GET", "/series/nope", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown series, got %d", w.Code)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	start := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	for _, id := range []string{"rem1", "rem2"} {
+		_ = Store.CreateReminder(reminder.NewReminder(id, "Water plants", "", &start, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+		for i := 0; i < 5; i++ {
+			_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: fmt.Sprintf("cev%s%d", id, i), ReminderID: id, CompletedBy: "Alice", CompletedAt: start.AddDate(0, 0, 7*i)})
+		}
+	}
+	if _, err := suggest.Generate(Store, start); err != nil {
+		t.Fatalf("failed to generate suggestions: %v", err)
+	}
+	router := setupRouter()
+
+	do := func(method, url string) *http.Response {
+		req := httptest.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := do("GET", "/families/fam1/suggestions")
+	var list []suggest.Suggestion
+	json.NewDecoder(resp.Body).Decode(&list)
+	if len(list) != 2 {
+		t.Fatalf("expected 2 suggestions, got %d", len(list))
+	}
+	byReminder := map[string]string{}
+	for _, s := range list {
+		byReminder[s.ReminderID] = s.ID
+	}
+
+	if resp := do("POST", "/suggestions/"+byReminder["rem1"]+"/accept"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	r, _ := Store.GetReminder("rem1")
+	if r.Recurrence.Type != "weekly" || len(r.Recurrence.Days) != 1 || r.Recurrence.Days[0] != "monday" || r.Version != 2 {
+		t.Errorf("expected accepted recurrence weekly on monday, got %+v (version %d)", r.Recurrence, r.Version)
+	}
+	if resp := do("POST", "/suggestions/"+byReminder["rem1"]+"/dismiss"); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected status 409 for an accepted suggestion, got %d", resp.StatusCode)
+	}
+
+	if resp := do("POST", "/suggestions/"+byReminder["rem2"]+"/dismiss"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	r, _ = Store.GetReminder("rem2")
+	if r.Recurrence.Type != "daily" {
+		t.Errorf("expected dismissed suggestion to leave the reminder alone, got %+v", r.Recurrence)
+	}
+
+	resp = do("GET", "/families/fam1/suggestions")
+	list = nil
+	json.NewDecoder(resp.Body).Decode(&list)
+	if len(list) != 0 {
+		t.Errorf("expected no open suggestions, got %d", len(list))
+	}
+	resp = do("GET", "/families/fam1/suggestions?status=all")
+	list = nil
+	json.NewDecoder(resp.Body).Decode(&list)
+	if len(list) != 2 {
+		t.Errorf("expected 2 suggestions in total, got %d", len(list))
+	}
+	if resp := do("POST", "/suggestions/nope/accept"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestFamilyDashboard(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	due := time.Now()
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	router := setupRouter()
+
+	get := func(url string) *http.Response {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w.Result()
+	}
+
+	resp := get("/families/fam1/dashboard.png?width=600&height=448&member=Alice")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a PNG, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 600 || b.Dy() != 448 {
+		t.Errorf("expected 600x448, got %v", b)
+	}
+
+	for _, url := range []string{
+		"/families/fam1/dashboard.png?width=abc",
+		"/families/fam1/dashboard.png?height=99999",
+		"/families/fam1/dashboard.png?member=Mallory",
+	} {
+		if resp := get(url); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", url, resp.StatusCode)
+		}
+	}
+	if resp := get("/families/nope/dashboard.png"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown family, got %d", resp.StatusCode)
+	}
+}
+
+func TestFamilyFeed(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	_ = Store.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Carol"}})
+	soon := time.Now().Add(48 * time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &soon, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Taxes", "", &soon, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "cev1", ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: time.Now().Add(-time.Hour)})
+	_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "cev2", ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: time.Now().Add(-60 * 24 * time.Hour)})
+	token, _ := share.Create(Store, &share.Share{FamilyID: "fam1", Name: "Alice", Query: smartlist.Query{Assignee: "Alice"}}, time.Now())
+	otherToken, _ := share.Create(Store, &share.Share{FamilyID: "fam2", Name: "Jones"}, time.Now())
+	router := setupRouter()
+
+	get := func(url string) *http.Response {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w.Result()
+	}
+
+	resp := get("/families/fam1/feed.atom?token=" + token)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/atom+xml") {
+		t.Fatalf("expected an Atom feed, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var doc struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			Title string `xml:"title"`
+		} `xml:"entry"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("invalid feed: %v", err)
+	}
+	if doc.Title != "Smith" || len(doc.Entries) != 2 || !strings.Contains(doc.Entries[0].Title, "Trash") || doc.Entries[1].Title != "Done: Trash" {
+		t.Errorf("unexpected feed: %+v", doc)
+	}
+
+	for _, url := range []string{
+		"/families/fam1/feed.atom",
+		"/families/fam1/feed.atom?token=bogus",
+		"/families/fam1/feed.atom?token=" + otherToken,
+	} {
+		if resp := get(url); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", url, resp.StatusCode)
+		}
+	}
+}
+
+func TestFamilyPackages(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	PackageKey = nil
+	if w := do("POST", "/families/fam1/export-package", nil); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without a package key, got %d", w.Code)
+	}
+	PackageKey = []byte("shared secret")
+	defer func() { PackageKey = nil }()
+
+	w := do("POST", "/families/fam1/export-package", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("expected a gzip archive, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	archive := w.Body.Bytes()
+
+	w = do("POST", "/families/import-package", archive)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var f family.Family
+	json.NewDecoder(w.Body).Decode(&f)
+	if f.ID == "fam1" || f.Name != "Smith" {
+		t.Errorf("expected a copy of the family under a new ID, got %+v", f)
+	}
+	list, _ := Store.ListReminders()
+	if len(list) != 2 {
+		t.Errorf("expected the reminder to be copied, got %d reminders", len(list))
+	}
+
+	PackageKey = []byte("another secret")
+	if w := do("POST", "/families/import-package", archive); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a package signed with another key, got %d", w.Code)
+	}
+	if w := do("POST", "/families/import-package", []byte("not an archive")); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for garbage, got %d", w.Code)
+	}
+	if w := do("POST", "/families/nope/export-package", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown family, got %d", w.Code)
+	}
+
+	PackageKey = []byte("shared secret")
+	withPassphrase := func(method, url string, body []byte, passphrase string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set(PassphraseHeader, passphrase)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := withPassphrase("POST", "/families/fam1/export-package", nil, "short"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a short passphrase, got %d", w.Code)
+	}
+	w = withPassphrase("POST", "/families/fam1/export-package", nil, "correct horse")
+	if w.Code != http.StatusOK || !strings.HasSuffix(w.Header().Get("Content-Disposition"), `.tar.gz.enc"`) {
+		t.Fatalf("expected an encrypted archive, got %d %s", w.Code, w.Header().Get("Content-Disposition"))
+	}
+	encrypted := w.Body.Bytes()
+	if w := do("POST", "/families/import-package", encrypted); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without the passphrase, got %d", w.Code)
+	}
+	if w := withPassphrase("POST", "/families/import-package", encrypted, "wrong horse"); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 with the wrong passphrase, got %d", w.Code)
+	}
+	if w := withPassphrase("POST", "/families/import-package", encrypted, "correct horse"); w.Code != http.StatusCreated {
+		t.Errorf("expected status 201 with the passphrase, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAvatars(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	router := setupRouter()
+
+	do := func(method, url string, body []byte, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	var picture bytes.Buffer
+	png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 640, 480)))
+
+	if w := do("GET", "/families/fam1/avatar", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 before an upload, got %d", w.Code)
+	}
+	w := do("PUT", "/families/fam1/avatar", picture.Bytes(), nil)
+	if w.Code != http.StatusNoContent || w.Header().Get("ETag") == "" {
+		t.Fatalf("expected status 204 with an ETag, got %d: %s", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+
+	w = do("GET", "/families/fam1/avatar", nil, nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || w.Header().Get("ETag") != etag {
+		t.Fatalf("expected the PNG avatar, got %d %s %s", w.Code, w.Header().Get("Content-Type"), w.Header().Get("ETag"))
+	}
+	img, err := png.Decode(w.Body)
+	if err != nil || img.Bounds().Dx() != 256 || img.Bounds().Dy() != 256 {
+		t.Errorf("expected a 256x256 avatar, got %v, %v", img, err)
+	}
+	if w := do("GET", "/families/fam1/avatar", nil, http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304 for a matching ETag, got %d", w.Code)
+	}
+
+	// Members have their own avatars, uploaded as a form too
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("file", "alice.png")
+	png.Encode(part, image.NewGray(image.Rect(0, 0, 300, 600)))
+	mw.Close()
+	if w := do("PUT", "/families/fam1/members/Alice/avatar", form.Bytes(), http.Header{"Content-Type": {mw.FormDataContentType()}}); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204 for a form upload, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/families/fam1/members/Alice/avatar", nil, nil); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("expected Alice's own avatar, got %d %s", w.Code, w.Header().Get("ETag"))
+	}
+	if w := do("GET", "/families/fam1/members/Bob/avatar", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for Bob, got %d", w.Code)
+	}
+	if w := do("PUT", "/families/fam1/members/Zed/avatar", picture.Bytes(), nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown member, got %d", w.Code)
+	}
+	if w := do("PUT", "/families/fam1/avatar", []byte("not a picture"), nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for garbage, got %d", w.Code)
+	}
+
+	// Removing a member removes their avatar
+	do("DELETE", "/families/fam1/members/Alice?orphan=true", nil, nil)
+	if _, err := storage.GetBlob(Store, avatarID("fam1", "Alice")); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Errorf("expected the removed member's avatar to be deleted, got %v", err)
+	}
+
+	if w := do("DELETE", "/families/fam1/avatar", nil, nil); w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	if w := do("GET", "/families/fam1/avatar", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 after deleting, got %d", w.Code)
+	}
+}
+
+func TestTemplatePackImport(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	router := setupRouter()
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	_, untrusted, _ := ed25519.GenerateKey(nil)
+	m := &pack.Manifest{
+		Name:      "School year",
+		Publisher: "Example PTA",
+		Templates: []pack.Template{
+			{ID: "lunch", Title: "Pack lunch", Recurrence: reminder.RecurrencePattern{Type: "daily"}, DueIn: "24h"},
+			{ID: "planner", Title: "Sign planner", Recurrence: reminder.RecurrencePattern{Type: "weekly", Days: []string{"friday"}}, Effort: 5},
+			{ID: "photos", Title: "Order school photos"},
+		},
+	}
+	signed, _ := pack.Sign(m, "pta", priv)
+	forged, _ := pack.Sign(m, "pta", untrusted)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pack.json":
+			w.Write(signed)
+		case "/forged.json":
+			w.Write(forged)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/templates/import-url", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	TemplatePackKeys = nil
+	if w := do(`{"url": "` + srv.URL + `/pack.json", "family_id": "fam1", "preview": true}`); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without trusted keys, got %d", w.Code)
+	}
+	TemplatePackKeys = pack.Keyring{"pta": pub}
+	defer func() { TemplatePackKeys = nil }()
+
+	w := do(`{"url": "` + srv.URL + `/pack.json", "family_id": "fam1", "preview": true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for a preview, got %d: %s", w.Code, w.Body.String())
+	}
+	var preview struct {
+		Name      string          `json:"name"`
+		Templates []pack.Template `json:"templates"`
+		Created   []any           `json:"created"`
+	}
+	json.NewDecoder(w.Body).Decode(&preview)
+	if preview.Name != "School year" || len(preview.Templates) != 3 || preview.Created != nil {
+		t.Errorf("unexpected preview: %+v", preview)
+	}
+	if list, _ := Store.ListReminders(); len(list) != 0 {
+		t.Errorf("expected a preview to install nothing, got %d reminders", len(list))
+	}
+
+	w = do(`{"url": "` + srv.URL + `/pack.json", "family_id": "fam1", "family_member": "Alice", "templates": ["planner", "lunch"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var installed struct {
+		Created []reminder.Reminder `json:"created"`
+	}
+	json.NewDecoder(w.Body).Decode(&installed)
+	if len(installed.Created) != 2 || installed.Created[0].Title != "Pack lunch" || installed.Created[1].Effort != 5 {
+		t.Fatalf("expected the selected templates in pack order, got %+v", installed.Created)
+	}
+	if installed.Created[0].DueDate == nil || installed.Created[0].Recurrence.Type != "daily" || installed.Created[0].FamilyMember != "Alice" {
+		t.Errorf("unexpected reminder: %+v", installed.Created[0])
+	}
+
+	for body, status := range map[string]int{
+		`{"url": "` + srv.URL + `/forged.json", "family_id": "fam1", "preview": true}`:                              http.StatusForbidden,
+		`{"url": "` + srv.URL + `/missing.json", "family_id": "fam1", "preview": true}`:                             http.StatusBadGateway,
+		`{"url": "` + srv.URL + `/pack.json", "family_id": "fam1", "family_member": "Alice", "templates": ["bus"]}`: http.StatusBadRequest,
+		`{"url": "` + srv.URL + `/pack.json", "family_id": "fam1"}`:                                                 http.StatusBadRequest,
+		`{"family_id": "fam1"}`: http.StatusBadRequest,
+	} {
+		if w := do(body); w.Code != status {
+			t.Errorf("%s: expected status %d, got %d", body, status, w.Code)
+		}
+	}
+	if list, _ := Store.ListReminders(); len(list) != 2 {
+		t.Errorf("expected failed imports to install nothing, got %d reminders", len(list))
+	}
+}
+
+func TestCompletionLinks(t *testing.T) {
+	setupTestStorage()
+	LinkSigner = links.NewSigner([]byte("test-secret"))
+	BaseURL = "https://reminders.example.com"
+	defer func() { LinkSigner, BaseURL = nil, "" }()
+
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	_ = Store.CreateFamily(f)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Feed cat", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	req := httptest.NewRequest("GET", "/reminders/rem1/complete-link?member=Bob", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	var link struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(w.Result().Body).Decode(&link); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !strings.HasPrefix(link.URL, "https://reminders.example.com/c/") {
+		t.Fatalf("unexpected link: %s", link.URL)
+	}
+
+	// Opening the link only asks to confirm, as link previews open it too
+	req = httptest.NewRequest("GET", strings.TrimPrefix(link.URL, BaseURL), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK || !strings.Contains(w.Body.String(), `<form method="post">`) {
+		t.Fatalf("expected a confirmation form, got %d: %s", w.Result().StatusCode, w.Body.String())
+	}
+	if got, _ := Store.GetReminder("rem1"); got.Completed {
+		t.Error("expected opening the link to leave the reminder alone")
+	}
+	req = httptest.NewRequest("POST", strings.TrimPrefix(link.URL, BaseURL), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	got, _ := Store.GetReminder("rem1")
+	if !got.Completed {
+		t.Error("expected reminder to be completed via link")
+	}
+	events, _ := Store.ListCompletionEvents("rem1")
+	if len(events) != 1 || events[0].CompletedBy != "Bob" {
+		t.Errorf("expected one completion event by Bob, got %+v", events)
+	}
+
+	req = httptest.NewRequest("GET", "/reminders/rem1/qr.png?size=128", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK || w.Result().Header.Get("Content-Type") != "image/png" {
+		t.Errorf("expected PNG QR code, got %d %s", w.Result().StatusCode, w.Result().Header.Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("\x89PNG")) {
+		t.Error("QR response is not a PNG image")
+	}
+
+	for _, method := range []string{"GET", "POST"} {
+		req = httptest.NewRequest(method, "/c/not-a-token", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != http.StatusForbidden {
+			t.Errorf("%s: expected status 403 for invalid token, got %d", method, w.Result().StatusCode)
+		}
+	}
+
+	// Links are only issued to members of the family, and stop working once
+	// the member leaves it
+	req = httptest.NewRequest("GET", "/reminders/rem1/complete-link?member=Mallory", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a link to a non-member, got %d", w.Result().StatusCode)
+	}
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Water plants", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	rem2, _ := Store.GetReminder("rem2")
+	url, _ := completionLink("", rem2, "Bob", time.Hour)
+	f.RemoveMember("Bob")
+	_ = Store.UpdateFamily(f)
+	req = httptest.NewRequest("POST", url, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected status 403 for a link to a removed member, got %d", w.Result().StatusCode)
+	}
+	if got, _ := Store.GetReminder("rem2"); got.Completed {
+		t.Error("expected a removed member's link not to complete the reminder")
+	}
+
+	// Links for someone other than the caller or the assignee take being
+	// able to act as them
+	f.AddMember("Bob")
+	f.AddMember("Carol")
+	f.Settings.Members = map[string]family.MemberSettings{"Alice": {Role: family.RoleOwner}, "Bob": {Role: family.RoleAdult}, "Carol": {Role: family.RoleAdult}}
+	_ = Store.UpdateFamily(f)
+	for _, tt := range []struct {
+		actor, member string
+		want          int
+	}{
+		{"Bob", "Carol", http.StatusForbidden},
+		{"Bob", "Bob", http.StatusOK},
+		{"Bob", "", http.StatusOK},
+		{"Alice", "Carol", http.StatusOK},
+	} {
+		req = httptest.NewRequest("GET", "/reminders/rem2/complete-link?member="+tt.member, nil)
+		req.Header.Set(ActorHeader, tt.actor)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s linking for %q: expected status %d, got %d: %s", tt.actor, tt.member, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestEmailReplies(t *testing.T) {
+	setupTestStorage()
+	router := setupRouter()
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/inbound/email", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := post("application/json", `{}`); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without an inbound address, got %d", w.Code)
+	}
+
+	InboundEmailAddress = "reply@reminders.example.com"
+	defer func() { InboundEmailAddress = "" }()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	due := time.Now().Add(time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Feed cat", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+
+	replyTo := NotificationReplyTo(&reminder.Reminder{ID: "rem1", FamilyID: "fam1", FamilyMember: "Bob"})
+	if !strings.HasPrefix(replyTo, "reply+") || !strings.HasSuffix(replyTo, "@reminders.example.com") {
+		t.Fatalf("unexpected reply address %q", replyTo)
+	}
+	email := func(text string) string {
+		body, _ := json.Marshal(map[string]string{"from": "bob@example.com", "to": replyTo, "text": text})
+		return string(body)
+	}
+
+	if w := post("application/json", email("what is this?")); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for an unknown command, got %d", w.Code)
+	}
+	if w := post("application/json", `{"to": "reply+bogus@reminders.example.com", "text": "done"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown token, got %d", w.Code)
+	}
+
+	w := post("application/json", email("snooze 2h\n\nOn Monday, Reminders wrote:\n> Feed cat"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 snoozing, got %d: %s", w.Code, w.Body)
+	}
+	if rem, _ := Store.GetReminder("rem1"); rem.SnoozedUntil == nil || !rem.SnoozedUntil.Equal(due.Add(2*time.Hour)) {
+		t.Errorf("expected rem1 snoozed by 2h, got %v", rem.SnoozedUntil)
+	}
+
+	raw := "From: Bob <bob@example.com>\r\nTo: " + replyTo + "\r\nSubject: Re: Feed cat\r\n" +
+		"Content-Type: multipart/alternative; boundary=b1\r\n\r\n" +
+		"--b1\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nDone=21\r\n" +
+		"--b1\r\nContent-Type: text/html\r\n\r\n<p>Done!</p>\r\n--b1--\r\n"
+	w = post("message/rfc822", raw)
+	var result inboundResult
+	json.NewDecoder(w.Body).Decode(&result)
+	if w.Code != http.StatusOK || result.Action != "done" || result.ReminderID != "rem1" {
+		t.Fatalf("expected rem1 completed, got %d: %+v", w.Code, result)
+	}
+	events, _ := Store.ListCompletionEvents("rem1")
+	if len(events) != 1 || events[0].CompletedBy != "Bob" {
+		t.Errorf("expected one completion by Bob, got %+v", events)
+	}
+	if w := post("application/json", email("done")); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "already") {
+		t.Errorf("expected replying done twice to be reported, got %d: %s", w.Code, w.Body)
+	}
+}
+
+type fakeNotifier struct {
+	sent []notify.Message
+	err  error
+}
+
+func (f *fakeNotifier) Send(ctx context.Context, target string, msg notify.Message) error {
+	f.sent = append(f.sent, msg)
+	return f.err
+}
+
+func TestShareLinks(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	tonight := time.Now().Add(2 * time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Bath time", "Use the blue towel", &tonight, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Pay taxes", "", &tonight, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, body, accept string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	resp := do("POST", "/shares", `{"family_id": "fam1", "name": "Babysitter", "query": {"assignee": "Alice"}, "ttl": "6h"}`, "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	var created struct {
+		ID        string    `json:"id"`
+		Token     string    `json:"token"`
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	if created.Token == "" || !strings.HasSuffix(created.URL, "/shared/"+created.Token) {
+		t.Fatalf("unexpected share: %+v", created)
+	}
+	if d := time.Until(created.ExpiresAt); d < 5*time.Hour || d > 6*time.Hour {
+		t.Errorf("expected expiry in 6h, got %v", d)
+	}
+
+	resp = do("GET", "/shared/"+created.Token, "", "")
+	var view struct {
+		Name      string `json:"name"`
+		Reminders []struct {
+			Title    string `json:"title"`
+			Assignee string `json:"assignee"`
+			ID       string `json:"id"`
+		} `json:"reminders"`
+	}
+	json.NewDecoder(resp.Body).Decode(&view)
+	if view.Name != "Babysitter" || len(view.Reminders) != 1 || view.Reminders[0].Title != "Bath time" || view.Reminders[0].ID != "" {
+		t.Errorf("unexpected shared view: %+v", view)
+	}
+
+	resp = do("GET", "/shared/"+created.Token, "", "text/html,application/xhtml+xml")
+	page, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || !strings.Contains(string(page), "Bath time") || strings.Contains(string(page), "Pay taxes") {
+		t.Errorf("unexpected shared page: %s", page)
+	}
+
+	resp = do("GET", "/shares?family_id=fam1", "", "")
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(body), created.Token) || !strings.Contains(string(body), created.ID) {
+		t.Errorf("expected share listing without tokens, got %s", body)
+	}
+
+	if resp := do("DELETE", "/shares/"+created.ID, "", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/shared/"+created.Token, "", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 after revocation, got %d", resp.StatusCode)
+	}
+	if resp := do("POST", "/shares", `{"family_id": "fam1", "name": "x", "ttl": "-1h"}`, ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for negative ttl, got %d", resp.StatusCode)
+	}
+}
+
+func TestWidget(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}, Settings: family.Settings{Timezone: "UTC"}})
+	now := time.Now().UTC()
+	at := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 0, 0, time.UTC)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Brush <teeth>", "", &at, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Pay taxes", "", &at, "fam1", "Bob", reminder.RecurrencePattern{Type: "daily"}))
+	tomorrow := at.AddDate(0, 0, 1)
+	_ = Store.CreateReminder(reminder.NewReminder("rem3", "Dentist", "", &tomorrow, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	token, err := share.Create(Store, &share.Share{FamilyID: "fam1", Name: "Alice today", Query: smartlist.Query{Assignee: "Alice"}}, now)
+	if err != nil {
+		t.Fatalf("failed to create share: %v", err)
+	}
+	router := setupRouter()
+
+	get := func(url string) *http.Response {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w.Result()
+	}
+
+	resp := get("/widget/" + token + "?format=json")
+	var data struct {
+		Items []struct {
+			Title string `json:"title"`
+			Time  string `json:"time"`
+		} `json:"items"`
+	}
+	json.NewDecoder(resp.Body).Decode(&data)
+	if len(data.Items) != 1 || data.Items[0].Title != "Brush <teeth>" || data.Items[0].Time != "23:59" {
+		t.Errorf("unexpected widget data: %+v", data)
+	}
+
+	resp = get("/widget/" + token)
+	page, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || !strings.Contains(string(page), "Brush &lt;teeth&gt;") || !strings.Contains(string(page), "<script>") {
+		t.Errorf("unexpected widget page: %s", page)
+	}
+
+	if resp := get("/widget/bogus"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown token, got %d", resp.StatusCode)
+	}
+}
+
+func TestFamilyNotificationSettings(t *testing.T) {
+	setupTestStorage()
+	fake := &fakeNotifier{}
+	Notifier = notify.NewDispatcher()
+	Notifier.Register("matrix", fake)
+	Notifier.Register("broken", &fakeNotifier{err: errors.New("unreachable")})
+	Notifier.Register("apprise", notify.NewAppriseNotifier(""))
+	defer func() { Notifier = nil }()
+
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	router := setupRouter()
+
+	body := `{"channels": [{"type": "sms", "target": "+15550100"}]}`
+	req := httptest.NewRequest("PUT", "/families/fam1/settings", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unsupported channel, got %d", w.Result().StatusCode)
+	}
+
+	body = `{"channels": [{"type": "apprise", "target": "slack://only-one-token"}]}`
+	req = httptest.NewRequest("PUT", "/families/fam1/settings", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for malformed notification URL, got %d", w.Result().StatusCode)
+	}
+
+	body = `{"channels": [{"type": "matrix", "target": "!room:example.org"}, {"type": "broken", "target": "x"}]}`
+	req = httptest.NewRequest("PUT", "/families/fam1/settings", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	f, _ := Store.GetFamily("fam1")
+	if len(f.Settings.Channels) != 2 || f.Settings.Channels[0].Target != "!room:example.org" {
+		t.Errorf("settings not persisted: %+v", f.Settings)
+	}
+
+	req = httptest.NewRequest("POST", "/families/fam1/notifications/test", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	var results []channelResult
+	if err := json.NewDecoder(w.Result().Body).Decode(&results); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(results) != 2 || results[0].Error != "" || results[1].Error == "" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if len(fake.sent) != 1 || fake.sent[0].Subject != "Reminder: Test notification" {
+		t.Errorf("expected one test message from the default template, got %+v", fake.sent)
+	}
+
+	body = `{"templates": {"reminder": {"subject": "{{.Bogus}}"}}}`
+	req = httptest.NewRequest("PUT", "/families/fam1/settings", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid template, got %d", w.Result().StatusCode)
+	}
+
+	body = `{"members": {"Alice": {"channels": [{"type": "matrix", "target": "@alice:example.org"}]}}, "templates": {"reminder": {"subject": "Hey {{.Assignee}}"}}}`
+	req = httptest.NewRequest("PUT", "/families/fam1/settings", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	for _, body := range []string{
+		`{"members": {"Alice": {"timezone": "Mars/Olympus"}}}`,
+		`{"members": {"Alice": {"agenda": {"time": "7am"}}}}`,
+		`{"members": {"Alice": {"failover": {"ack_minutes": -5}}}}`,
+	} {
+		req = httptest.NewRequest("PUT", "/families/fam1/settings", strings.NewReader(body))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, w.Result().StatusCode)
+		}
+	}
+	body = `{"members": {"Mallory": {"channels": [{"type": "matrix", "target": "x"}]}}}`
+	req = httptest.NewRequest("PUT", "/families/fam1/settings", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown member, got %d", w.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest("POST", "/families/fam1/notifications/test?member=Alice", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	results = nil
+	json.NewDecoder(w.Result().Body).Decode(&results)
+	if len(results) != 1 || results[0].Target != "@alice:example.org" {
+		t.Errorf("expected the member's own channel, got %+v", results)
+	}
+	if last := fake.sent[len(fake.sent)-1]; last.Subject != "Hey Alice" {
+		t.Errorf("expected the family's template, got %q", last.Subject)
+	}
+
+	now := time.Now()
+	_ = delivery.Save(Store, &delivery.Delivery{ID: "ntf1", FamilyID: "fam1", ReminderID: "rem1", Member: "Alice", Channel: family.Channel{Type: "matrix", Target: "@alice:example.org"}, Status: delivery.StatusSent, SentAt: now})
+	_ = delivery.Save(Store, &delivery.Delivery{ID: "ntf2", FamilyID: "fam1", ReminderID: "rem2", Member: "Alice", Status: delivery.StatusFailed, SentAt: now.Add(time.Minute)})
+	req = httptest.NewRequest("GET", "/families/fam1/deliveries?reminder_id=rem1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var deliveries []delivery.Delivery
+	json.NewDecoder(w.Result().Body).Decode(&deliveries)
+	if w.Result().StatusCode != http.StatusOK || len(deliveries) != 1 || deliveries[0].ID != "ntf1" {
+		t.Errorf("expected the delivery of rem1, got %d %+v", w.Result().StatusCode, deliveries)
+	}
+}
+
+func TestAlexaIntents(t *testing.T) {
+	setupTestStorage()
+	AlexaFamilyID = "fam1"
+	defer func() { AlexaFamilyID = "" }()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	now := time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)
+	at := time.Date(2025, 3, 3, 17, 0, 0, 0, time.UTC)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Feed the cat", "", &at, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	Store.SetReminderIDCounter(1)
+
+	speech := func(resp *alexa.ResponseEnvelope) string {
+		if resp.Response.OutputSpeech == nil {
+			return ""
+		}
+		return resp.Response.OutputSpeech.Text
+	}
+
+	t.Run("List today", func(t *testing.T) {
+		resp := routeAlexaIntent(Store, alexa.Intent{Name: intentListToday}, now)
+		if !strings.Contains(speech(resp), "Feed the cat at 5:00 PM for Alice") {
+			t.Errorf("unexpected speech: %q", speech(resp))
+		}
+	})
+
+	t.Run("Create reminder", func(t *testing.T) {
+		resp := routeAlexaIntent(Store, alexa.Intent{Name: intentCreate, Slots: map[string]alexa.Slot{
+			"title":  {Value: "water plants"},
+			"member": {Value: "bob"},
+			"date":   {Value: "2025-03-04"},
+			"time":   {Value: "18:30"},
+		}}, now)
+		if !strings.Contains(speech(resp), "remind Bob to water plants") {
+			t.Errorf("unexpected speech: %q", speech(resp))
+		}
+		got, err := Store.GetReminder("rem2")
+		if err != nil {
+			t.Fatalf("expected reminder to be created: %v", err)
+		}
+		if got.FamilyMember != "Bob" || got.DueDate == nil || got.DueDate.Hour() != 18 {
+			t.Errorf("unexpected reminder: %+v", got)
+		}
+	})
+
+	t.Run("Complete requires confirmation", func(t *testing.T) {
+		intent := alexa.Intent{Name: intentComplete, ConfirmationStatus: alexa.ConfirmationNone,
+			Slots: map[string]alexa.Slot{"title": {Value: "feed the cat"}}}
+		resp := routeAlexaIntent(Store, intent, now)
+		if len(resp.Response.Directives) != 1 || resp.Response.Directives[0].Type != "Dialog.ConfirmIntent" {
+			t.Fatalf("expected a confirmation directive, got %+v", resp.Response)
+		}
+
+		intent.ConfirmationStatus = alexa.ConfirmationConfirmed
+		routeAlexaIntent(Store, intent, now)
+		events, _ := Store.ListCompletionEvents("rem1")
+		if len(events) != 1 {
+			t.Errorf("expected one completion event, got %d", len(events))
+		}
+		resp = routeAlexaIntent(Store, alexa.Intent{Name: intentListToday}, now)
+		if !strings.Contains(speech(resp), "nothing left") {
+			t.Errorf("expected nothing left after completion, got %q", speech(resp))
+		}
+	})
+}
+
+func TestInboundHooks(t *testing.T) {
+	setupTestStorage()
+	HookLimiter = hook.NewLimiter()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	router := setupRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/hooks", `{"family_id": "fam1", "name": "Alerts", "defaults": {"family_member": "Carol"}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown default member, got %d", w.Code)
+	}
+	w := do("POST", "/hooks", `{"family_id": "fam1", "name": "Alerts", "defaults": {"family_member": "Alice", "due_in": "1h"}, "rate_limit": 2}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Token == "" || !strings.HasSuffix(created.URL, "/hooks/"+created.Token) {
+		t.Fatalf("unexpected hook: %+v", created)
+	}
+
+	w = do("POST", "/hooks/"+created.Token, `{"title": "Disk almost full"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var rem reminder.Reminder
+	json.NewDecoder(w.Body).Decode(&rem)
+	if rem.FamilyID != "fam1" || rem.FamilyMember != "Alice" || rem.DueDate == nil || time.Until(*rem.DueDate) < 59*time.Minute {
+		t.Errorf("expected defaults to apply, got %+v", rem)
+	}
+
+	w = do("POST", "/hooks/"+created.Token, `{"title": "Sign permission slip", "member": "Bob", "due": "2025-03-04T08:00:00Z"}`)
+	json.NewDecoder(w.Body).Decode(&rem)
+	if w.Code != http.StatusCreated || rem.FamilyMember != "Bob" || !rem.DueDate.Equal(time.Date(2025, 3, 4, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected payload to override defaults, got %d %+v", w.Code, rem)
+	}
+
+	w = do("POST", "/hooks/"+created.Token, `{"title": "One too many"}`)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After, got %d", w.Code)
+	}
+
+	w = do("GET", "/hooks?family_id=fam1", "")
+	if strings.Contains(w.Body.String(), created.Token) || !strings.Contains(w.Body.String(), created.ID) {
+		t.Errorf("expected hook listing without tokens, got %s", w.Body.String())
+	}
+	if w := do("DELETE", "/hooks/"+created.ID, ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	if w := do("POST", "/hooks/"+created.Token, `{"title": "After revocation"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for revoked hook, got %d", w.Code)
+	}
+}
+
+type chanNotifier chan notify.Message
+
+func (c chanNotifier) Send(ctx context.Context, target string, msg notify.Message) error {
+	c <- msg
+	return nil
+}
+
+func TestCompletionNotices(t *testing.T) {
+	setupTestStorage()
+	sent := make(chanNotifier, 4)
+	Notifier = notify.NewDispatcher()
+	Notifier.Register("test", sent)
+	defer func() { Notifier = nil }()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	f.Settings.Channels = []family.Channel{{Type: "test", Target: "family"}}
+	_ = Store.CreateFamily(f)
+	due := time.Now().Add(time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Dishes", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	complete := func(id string) {
+		req := httptest.NewRequest("PATCH", "/reminders/"+id, strings.NewReader(`{"completed": true}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	complete("rem1")
+	select {
+	case msg := <-sent:
+		t.Errorf("expected no notice without completion_notices, got %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	f.Settings.CompletionNotices = true
+	_ = Store.UpdateFamily(f)
+	complete("rem2")
+	select {
+	case msg := <-sent:
+		if msg.Subject != "Done: Dishes" || msg.Body != "Alice completed Dishes." {
+			t.Errorf("unexpected notice: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a completion notice")
+	}
+}
+
+func TestPollingCursors(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Carol"}})
+	router := setupRouter()
+
+	create := func(family, member, title string) string {
+		body := fmt.Sprintf(`{"title": %q, "family_id": %q, "family_member": %q}`, title, family, member)
+		req := httptest.NewRequest("POST", "/reminders", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var rem reminder.Reminder
+		json.NewDecoder(w.Body).Decode(&rem)
+		return rem.ID
+	}
+	type page struct {
+		Items []struct {
+			ID   string          `json:"id"`
+			Data json.RawMessage `json:"data"`
+		} `json:"items"`
+		Cursor string `json:"cursor"`
+		More   bool   `json:"more"`
+	}
+	poll := func(url string) page {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d", url, w.Code)
+		}
+		var p page
+		json.NewDecoder(w.Body).Decode(&p)
+		if w.Header().Get("X-Next-Cursor") != p.Cursor {
+			t.Errorf("X-Next-Cursor %q doesn't match body cursor %q", w.Header().Get("X-Next-Cursor"), p.Cursor)
+		}
+		return p
+	}
+	ids := func(p page) []string {
+		var out []string
+		for _, it := range p.Items {
+			out = append(out, it.ID)
+		}
+		return out
+	}
+
+	// An empty feed still hands out a cursor to resume from
+	empty := poll("/families/fam1/poll/reminders")
+	if len(empty.Items) != 0 || empty.Cursor == "" {
+		t.Fatalf("unexpected empty page: %+v", empty)
+	}
+
+	var want []string
+	for i := 0; i < 12; i++ {
+		want = append(want, create("fam1", "Alice", fmt.Sprintf("Chore %d", i)))
+		create("fam2", "Carol", "Other family")
+	}
+	p := poll("/families/fam1/poll/reminders?limit=10&cursor=" + empty.Cursor)
+	if !p.More || !reflect.DeepEqual(ids(p), want[:10]) {
+		t.Fatalf("first page = %v more=%v, want %v", ids(p), p.More, want[:10])
+	}
+	p = poll("/families/fam1/poll/reminders?limit=10&cursor=" + p.Cursor)
+	if p.More || !reflect.DeepEqual(ids(p), want[10:]) {
+		t.Fatalf("second page = %v, want %v", ids(p), want[10:])
+	}
+	last := p.Cursor
+	if p = poll("/families/fam1/poll/reminders?cursor=" + last); len(p.Items) != 0 || p.Cursor != last {
+		t.Errorf("expected no new items and an unchanged cursor, got %+v", p)
+	}
+	// Reordering changes positions, not creation order, so it doesn't
+	// resurface old reminders
+	body := fmt.Sprintf(`{"family_id": "fam1", "ids": [%q, %q]}`, want[11], want[0])
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/reminders/reorder", strings.NewReader(body)))
+	newest := create("fam1", "Alice", "Newest")
+	if p = poll("/families/fam1/poll/reminders?cursor=" + last); !reflect.DeepEqual(ids(p), []string{newest}) {
+		t.Errorf("expected only %s, got %v", newest, ids(p))
+	}
+
+	req := httptest.NewRequest("PATCH", "/reminders/"+want[0], strings.NewReader(`{"completed": true}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if p = poll("/families/fam1/poll/completions"); len(p.Items) != 1 || !strings.HasPrefix(p.Items[0].ID, "cev") {
+		t.Errorf("expected one completion, got %+v", p)
+	}
+	if p = poll("/families/fam1/poll/completions?cursor=" + p.Cursor); len(p.Items) != 0 {
+		t.Errorf("expected no new completions, got %+v", p)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/families/fam1/poll/reminders?cursor=garbage!", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a bad cursor, got %d", w.Code)
+	}
+}
+
+func TestAdminBackup(t *testing.T) {
+	setupTestStorage()
+	router := setupRouter()
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/backup.sqlite", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("Bearer anything"); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without an admin token configured, got %d", w.Code)
+	}
+	AdminToken = "letmein"
+	defer func() { AdminToken = "" }()
+	if w := get("Bearer wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for a wrong token, got %d", w.Code)
+	}
+	if w := get("Bearer letmein"); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 with memory storage, got %d", w.Code)
+	}
+
+	db, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "live.db"))
+	if err != nil {
+		t.Fatalf("failed to create SQLite storage: %v", err)
+	}
+	defer db.Close()
+	Store = db
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+
+	w := get("Bearer letmein")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/vnd.sqlite3" {
+		t.Fatalf("expected a SQLite download, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("SQLite format 3\x00")) {
+		t.Errorf("backup is not a SQLite database: %q", w.Body.Bytes()[:min(16, w.Body.Len())])
+	}
+}
+
+func TestAdminRestorePoints(t *testing.T) {
+	setupTestStorage()
+	router := setupRouter()
+	AdminToken = "letmein"
+	defer func() { AdminToken = "" }()
+	do := func(method, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer letmein")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := do("GET", "/admin/restore-points"); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without restore points configured, got %d", w.Code)
+	}
+
+	dir := t.TempDir()
+	db, err := storage.NewSQLiteStorage(filepath.Join(dir, "live.db"))
+	if err != nil {
+		t.Fatalf("failed to create SQLite storage: %v", err)
+	}
+	defer db.Close()
+	Store = db
+	if RestorePoints, err = pitr.New(db, filepath.Join(dir, "archive")); err != nil {
+		t.Fatalf("failed to create archiver: %v", err)
+	}
+	defer func() { RestorePoints = nil }()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+
+	w := do("POST", "/admin/restore-points?reason=before+cleanup")
+	var p pitr.Point
+	json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != http.StatusCreated || p.Reason != "before cleanup" || p.Snapshot == "" {
+		t.Fatalf("expected a restore point, got %d %+v", w.Code, p)
+	}
+	// Deleting a family for good captures one first
+	if w := do("DELETE", "/families/fam1?cascade=true&permanent=true"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	w = do("GET", "/admin/restore-points")
+	var points []pitr.Point
+	json.NewDecoder(w.Body).Decode(&points)
+	if w.Code != http.StatusOK || len(points) != 2 || points[1].Reason != "before permanently deleting family fam1" {
+		t.Errorf("expected two restore points, got %d %+v", w.Code, points)
+	}
+}
+
+func TestRoleAuthorization(t *testing.T) {
+	setupTestStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Mom", "Dad", "Kid"}}
+	_ = Store.CreateFamily(f)
+	due := time.Now().Add(time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Homework", "", &due, "fam1", "Kid", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Taxes", "", &due, "fam1", "Dad", reminder.RecurrencePattern{Type: "once"}))
+	Store.SetReminderIDCounter(2)
+	router := setupRouter()
+
+	do := func(method, path, actor, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if actor != "" {
+			req.Header.Set(ActorHeader, actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without roles anyone may change the family, which is how the first
+	// owner is appointed
+	if w := do("PUT", "/families/fam1/settings", "", `{"members": {"Kid": {"role": "child"}}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("roles without an owner: expected status 400, got %d", w.Code)
+	}
+	if w := do("PUT", "/families/fam1/settings", "", `{"members": {"Mom": {"role": "boss"}}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown role: expected status 400, got %d", w.Code)
+	}
+	roles := `{"members": {"Mom": {"role": "owner"}, "Kid": {"role": "child"}}}`
+	if w := do("PUT", "/families/fam1/settings", "", roles); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name                string
+		method, path, actor string
+		body                string
+		want                int
+	}{
+		{"anonymous", "PUT", "/families/fam1/settings", "", roles, http.StatusUnauthorized},
+		{"outsider", "PUT", "/families/fam1/settings", "Stranger", roles, http.StatusForbidden},
+		{"adult changes settings", "PUT", "/families/fam1/settings", "Dad", roles, http.StatusForbidden},
+		{"owner changes settings", "PUT", "/families/fam1/settings", "Mom", roles, http.StatusOK},
+		{"child creates reminder", "POST", "/reminders", "Kid", `{"title": "Candy", "family_id": "fam1", "family_member": "Kid"}`, http.StatusForbidden},
+		{"adult creates reminder", "POST", "/reminders", "Dad", `{"title": "Groceries", "family_id": "fam1", "family_member": "Dad"}`, http.StatusCreated},
+		{"child edits own reminder", "PATCH", "/reminders/rem1", "Kid", `{"title": "No homework"}`, http.StatusForbidden},
+		{"child completes other's reminder", "PATCH", "/reminders/rem2", "Kid", `{"completed": true}`, http.StatusForbidden},
+		{"child completes own reminder", "PATCH", "/reminders/rem1", "Kid", `{"completed": true}`, http.StatusOK},
+		{"child deletes reminder", "DELETE", "/reminders/rem1", "Kid", "", http.StatusForbidden},
+		{"anonymous links reminder", "GET", "/reminders/rem1/complete-link", "", "", http.StatusUnauthorized},
+		{"child links other's reminder", "GET", "/reminders/rem2/complete-link", "Kid", "", http.StatusForbidden},
+		{"child prints other's QR code", "GET", "/reminders/rem2/qr.png", "Kid", "", http.StatusForbidden},
+		{"adult tests notifications", "POST", "/families/fam1/notifications/test", "Dad", "", http.StatusForbidden},
+		{"adult deletes family", "DELETE", "/families/fam1", "Dad", "", http.StatusForbidden},
+		{"owner deletes family", "DELETE", "/families/fam1?cascade=true", "Mom", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if w := do(tt.method, tt.path, tt.actor, tt.body); w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestListRemindersFilters(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	due := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	later := due.Add(72 * time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Lawn", "", &later, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem3", "Call", "", nil, "fam2", "Carol", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"rem1", "rem2", "rem3"}},
+		{"family_id=fam1", []string{"rem1", "rem2"}},
+		{"family_id=fam1&family_member=Bob", []string{"rem2"}},
+		{"completed=false&due_before=2025-06-02T00:00:00Z", []string{"rem1"}},
+		{"due_after=2025-06-04T09:00:00Z", []string{"rem2"}},
+		{"recurrence_type=weekly", nil},
+		{"q=assignee:Bob", []string{"rem2"}},
+		{"q=assignee:%22Alice%22+AND+completed:false+AND+due%3C2025-06-02", []string{"rem1"}},
+		{"family_id=fam1&q=due%3E%3D2025-06-02", []string{"rem2"}},
+		{"q=due:2025-06-04", []string{"rem2"}},
+		{"q=due:2025-06-01T09:00:00Z", []string{"rem1"}},
+		{"q=due%3C%3D2025-06-01T09:00:00Z", []string{"rem1"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/reminders?"+tt.query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d: %s", tt.query, w.Code, w.Body.String())
+		}
+		var list []*reminder.Reminder
+		json.NewDecoder(w.Body).Decode(&list)
+		var got []string
+		for _, r := range list {
+			got = append(got, r.ID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"completed=maybe", "due_before=tomorrow", "q=owner:Bob", "q=assignee:Alice+OR+assignee:Bob", "family_member=Alice&q=assignee:Bob"} {
+		req := httptest.NewRequest("GET", "/reminders?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestTimezones(t *testing.T) {
+	if _, err := tzdb.Load(); err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	router := setupRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/meta/timezones", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp timezonesResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	i := slices.IndexFunc(resp.Zones, func(z tzdb.Zone) bool { return z.Name == "Asia/Kolkata" })
+	if resp.Source == "" || i < 0 || resp.Zones[i].Offset != "+05:30" || resp.Zones[i].DST {
+		t.Errorf("expected Asia/Kolkata at +05:30, got %+v", resp)
+	}
+	// Every zone listed is accepted in settings
+	for _, z := range resp.Zones {
+		if msg := validateTimezone(z.Name); msg != "" {
+			t.Errorf("listed zone rejected: %s", msg)
+		}
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	setupTestStorage()
+	router := setupRouter()
+	router.Use(RequireReady)
+	defer SetReadiness(nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	SetReadiness([]string{"reminder ID counter is 1 but rem7 exists"})
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("healthz: expected status 200, got %d", w.Code)
+	}
+	w := get("/readyz")
+	var resp readyResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusServiceUnavailable || resp.Ready || len(resp.Problems) != 1 {
+		t.Errorf("readyz: got %d %+v, want 503 with the problem", w.Code, resp)
+	}
+	if w := get("/families"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("API while not ready: expected status 503, got %d", w.Code)
+	}
+	if w := get("/version"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"version":"dev"`) {
+		t.Errorf("version while not ready: expected status 200, got %d %s", w.Code, w.Body.String())
+	}
+
+	SetReadiness(nil)
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Errorf("readyz: expected status 200, got %d", w.Code)
+	}
+	if w := get("/families"); w.Code != http.StatusOK {
+		t.Errorf("API when ready: expected status 200, got %d", w.Code)
+	}
+
+	Store = &unpingableStorage{Store}
+	w = get("/readyz")
+	resp = readyResponse{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusServiceUnavailable || len(resp.Problems) != 1 || !strings.Contains(resp.Problems[0], "connection refused") {
+		t.Errorf("readyz with storage down: got %d %+v, want 503 with the ping error", w.Code, resp)
+	}
+}
+
+// unpingableStorage fails pings as if the database were down
+type unpingableStorage struct {
+	storage.Storage
+}
+
+func (s *unpingableStorage) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+// slowQueryStorage counts reminder queries, holding each until release is
+// closed
+type slowQueryStorage struct {
+	storage.Storage
+	queries atomic.Int32
+	release chan struct{}
+}
+
+func (s *slowQueryStorage) QueryReminders(f storage.ReminderFilter) ([]*reminder.Reminder, error) {
+	s.queries.Add(1)
+	<-s.release
+	return s.Storage.QueryReminders(f)
+}
+
+func TestListRemindersCoalescing(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Dishes", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	Store.SetReminderIDCounter(1)
+	slow := &slowQueryStorage{Storage: Store, release: make(chan struct{})}
+	Store = slow
+	router := setupRouter()
+	get := func() []reminder.Reminder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/reminders?family_id=fam1", nil))
+		var list []reminder.Reminder
+		json.NewDecoder(w.Body).Decode(&list)
+		return list
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if list := get(); len(list) != 1 {
+				t.Errorf("expected 1 reminder, got %d", len(list))
+			}
+		}()
+	}
+	close(slow.release)
+	wg.Wait()
+	if n := slow.queries.Load(); n != 1 {
+		t.Errorf("expected one storage query for identical requests, got %d", n)
+	}
+
+	body := `{"title": "Trash", "family_id": "fam1", "family_member": "Alice", "recurrence": {"type": "once"}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/reminders", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	before := slow.queries.Load()
+	if list := get(); len(list) != 2 || slow.queries.Load() != before+1 {
+		t.Errorf("after a change: got %d reminders from %d new queries, want 2 from 1", len(list), slow.queries.Load()-before)
+	}
+}
+
+func TestStorageBreaker(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith"})
+	StorageBreaker = breaker.New("storage")
+	StorageBreaker.Threshold = 1
+	Store = storage.WithBreaker(Store, StorageBreaker)
+	defer func() { StorageBreaker = nil }()
+	router := setupRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	if w := get("/families/fam1"); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	StorageBreaker.Allow()
+	StorageBreaker.Record(true)
+	if w := get("/families/fam1"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 while the breaker is open, got %d", w.Code)
+	}
+	w := get("/readyz")
+	var resp readyResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusServiceUnavailable || resp.Breakers["storage"] != "open" {
+		t.Errorf("readyz: got %d %+v, want 503 with the open breaker", w.Code, resp)
+	}
+}
+
+func TestAdminFsck(t *testing.T) {
+	setupTestStorage()
+	due := time.Now().Add(time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Dishes", "", &due, "fam9", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+	AdminToken = "letmein"
+	defer func() { AdminToken = "" }()
+	post := func(path string) (*httptest.ResponseRecorder, fsck.Report) {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("Authorization", "Bearer letmein")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var report fsck.Report
+		_ = json.NewDecoder(w.Body).Decode(&report)
+		return w, report
+	}
+
+	w, report := post("/admin/fsck")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Kind != fsck.OrphanedReminder || report.Issues[0].Repaired {
+		t.Fatalf("expected one unrepaired orphaned reminder, got %+v", report.Issues)
+	}
+	if _, report = post("/admin/fsck?repair=true"); report.Unrepaired() != 0 {
+		t.Errorf("expected the orphan repaired, got %+v", report.Issues)
+	}
+	if _, err := Store.GetReminder("rem1"); err == nil {
+		t.Error("expected rem1 to be deleted by repair")
+	}
+}
+
+func TestAdminDualWrite(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	secondary := storage.NewMemoryStorage()
+	DualWrite = storage.NewDualWriteStorage(Store, secondary)
+	Store = DualWrite
+	defer func() { DualWrite = nil }()
+	router := setupRouter()
+	AdminToken = "letmein"
+	defer func() { AdminToken = "" }()
+	do := func(method, path string, v any) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer letmein")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		_ = json.NewDecoder(w.Body).Decode(v)
+		return w.Code
+	}
+
+	body := `{"title": "Dishes", "family_id": "fam1", "family_member": "Alice", "recurrence": {"type": "once"}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/reminders", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var report storage.DualWriteReport
+	if code := do("GET", "/admin/dual-write", &report); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	// Only the family predates the migration
+	if len(report.Divergences) != 1 || report.Divergences[0].ID != "fam1" || report.Checked["reminder"] != 1 {
+		t.Fatalf("expected fam1 missing from the secondary, got %+v", report)
+	}
+	var synced struct{ Repaired []storage.Divergence }
+	if code := do("POST", "/admin/dual-write/sync", &synced); code != http.StatusOK || len(synced.Repaired) != 1 {
+		t.Fatalf("sync: got %d, %+v", code, synced)
+	}
+	if _, err := secondary.GetFamily("fam1"); err != nil {
+		t.Errorf("expected fam1 copied to the secondary: %v", err)
+	}
+}
+
+func TestFamilyCalendar(t *testing.T) {
+	setupTestStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	f.Settings.Timezone = "UTC"
+	_ = Store.CreateFamily(f)
+	due := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday"}}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Taxes", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem3", "Someday", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	token, _ := share.Create(Store, &share.Share{FamilyID: "fam1", Name: "Alice", Query: smartlist.Query{Assignee: "Alice"}}, time.Now())
+	router := setupRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/families/fam1/calendar.ics?token="+token, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Fatalf("expected a calendar, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, want := range []string{"BEGIN:VCALENDAR\r\n", "X-WR-CALNAME:Smith\r\n", "SUMMARY:Trash\r\n", "DTSTART:20250303T180000Z\r\n", "RRULE:FREQ=WEEKLY;BYDAY=MO\r\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Taxes") || strings.Contains(body, "Someday") {
+		t.Errorf("calendar includes reminders outside the share or without a due date:\n%s", body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/families/fam1/calendar.ics?token=bogus", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a bad token, got %d", w.Code)
+	}
+}
+
+func TestImportCalendar(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	router := setupRouter()
+	ics := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nUID:trash@example.com\r\nDTSTART:20250303T180000Z\r\nRRULE:FREQ=WEEKLY;BYDAY=MO\r\nSUMMARY:Trash\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:party@example.com\r\nDTSTART:20250303T180000Z\r\nSUMMARY:Party\r\nSTATUS:CANCELLED\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:gym@example.com\r\nDTSTART:20250303T180000Z\r\nRRULE:FREQ=MINUTELY\r\nSUMMARY:Gym\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	post := func(url, contentType string, body io.Reader) (*httptest.ResponseRecorder, calendarImport) {
+		req := httptest.NewRequest("POST", url, body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var res calendarImport
+		_ = json.NewDecoder(w.Body).Decode(&res)
+		return w, res
+	}
+
+	w, res := post("/families/fam1/import/ics?member=Bob", "text/calendar", strings.NewReader(ics))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	if len(res.Created) != 1 || res.Created[0].Title != "Trash" || res.Created[0].FamilyMember != "Bob" ||
+		res.Created[0].Recurrence.Type != "weekly" || len(res.Skipped) != 2 {
+		t.Fatalf("unexpected import: %+v", res)
+	}
+
+	// Importing the same file again, this time as an upload, creates nothing
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, _ := mw.CreateFormFile("file", "calendar.ics")
+	fw.Write([]byte(ics))
+	mw.Close()
+	w, res = post("/families/fam1/import/ics?member=Bob", mw.FormDataContentType(), &form)
+	if w.Code != http.StatusOK || len(res.Created) != 0 || len(res.Skipped) != 3 || !strings.Contains(res.Skipped[0].Reason, "already imported") {
+		t.Errorf("expected every entry skipped on reimport, got %d %+v", w.Code, res)
+	}
+
+	for url, want := range map[string]int{
+		"/families/fam1/import/ics":                http.StatusBadRequest,
+		"/families/fam1/import/ics?member=Mallory": http.StatusBadRequest,
+		"/families/nope/import/ics?member=Bob":     http.StatusNotFound,
+	} {
+		if w, _ := post(url, "text/calendar", strings.NewReader(strings.Replace(ics, "trash@", "other@", 1))); w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", url, want, w.Code)
+		}
+	}
+	if w, _ := post("/families/fam1/import/ics?member=Bob", "text/calendar", strings.NewReader("not a calendar")); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid file, got %d", w.Code)
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"index.html":      "<html></html>",
+		"app.3f9a2c1d.js": "console.log('fingerprinted')",
+		"main.js":         "console.log('plain')",
+		"assets/logo.svg": "<svg></svg>",
+	} {
+		_ = os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(h http.Handler, path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	h := StaticHandler(dir, StaticOptions{MaxAge: time.Hour, ImmutableAssets: true})
+	for path, want := range map[string]string{
+		"/":                "no-cache",
+		"/index.html":      "no-cache",
+		"/main.js":         "public, max-age=3600",
+		"/app.3f9a2c1d.js": "public, max-age=31536000, immutable",
+	} {
+		if got := get(h, path).Header().Get("Cache-Control"); got != want {
+			t.Errorf("%s: expected Cache-Control %q, got %q", path, want, got)
+		}
+	}
+	if got := get(StaticHandler(dir, StaticOptions{}), "/main.js").Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("expected assets to be revalidated by default, got %q", got)
+	}
+
+	w := get(h, "/main.js", "Range", "bytes=0-6")
+	if w.Code != http.StatusPartialContent || w.Body.String() != "console" || w.Header().Get("Content-Range") != "bytes 0-6/20" {
+		t.Errorf("expected a partial response, got %d %q %q", w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+	}
+	lastModified := get(h, "/main.js").Header().Get("Last-Modified")
+	if w := get(h, "/main.js", "If-Modified-Since", lastModified); w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304 for a cached file, got %d", w.Code)
+	}
+
+	if w := get(h, "/assets/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "logo.svg") {
+		t.Errorf("expected a directory listing by default, got %d", w.Code)
+	}
+	noListing := StaticHandler(dir, StaticOptions{DisableListing: true})
+	if w := get(noListing, "/assets/"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a directory with listing disabled, got %d", w.Code)
+	}
+	if w := get(noListing, "/"); w.Code != http.StatusOK || w.Body.String() != "<html></html>" {
+		t.Errorf("expected the index page with listing disabled, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get(noListing, "/assets/logo.svg"); w.Code != http.StatusOK {
+		t.Errorf("expected files in unlisted directories to be served, got %d", w.Code)
+	}
+
+	limited := StaticHandler(dir, StaticOptions{RateLimit: 2})
+	for i := 0; i < 2; i++ {
+		if w := get(limited, "/main.js"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i, w.Code)
+		}
+	}
+	if w := get(limited, "/main.js"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected status 429 with Retry-After 30, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"index.html":    "<html></html>",
+		"index.html.gz": "gzipped index",
+		"app.js":        "console.log('plain')",
+		"app.js.br":     "brotli app",
+		"app.js.gz":     "gzipped app",
+		"other.css":     "body {}",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := StaticHandler(dir, StaticOptions{})
+	for _, tc := range []struct {
+		path, accept, body, encoding, vary, ctype string
+	}{
+		{"/app.js", "gzip, deflate, br", "brotli app", "br", "Accept-Encoding", "text/javascript"},
+		{"/app.js", "gzip", "gzipped app", "gzip", "Accept-Encoding", "text/javascript"},
+		{"/app.js", "br;q=0, *", "gzipped app", "gzip", "Accept-Encoding", "text/javascript"},
+		{"/app.js", "", "console.log('plain')", "", "Accept-Encoding", "text/javascript"},
+		{"/", "gzip", "gzipped index", "gzip", "Accept-Encoding", "text/html"},
+		{"/other.css", "br, gzip", "body {}", "", "", "text/css"},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.Header.Set("Accept-Encoding", tc.accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Body.String() != tc.body || w.Header().Get("Content-Encoding") != tc.encoding || w.Header().Get("Vary") != tc.vary {
+			t.Errorf("%s with %q: got %q encoded %q varying by %q", tc.path, tc.accept, w.Body.String(), w.Header().Get("Content-Encoding"), w.Header().Get("Vary"))
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), tc.ctype) {
+			t.Errorf("%s: expected Content-Type %s, got %q", tc.path, tc.ctype, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestPrecompress(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("console.log('hello');\n", 100)
+	_ = os.WriteFile(filepath.Join(dir, "app.js"), []byte(big), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "tiny.js"), []byte("x"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "logo.png"), []byte(big), 0o644)
+
+	if n, err := Precompress(dir); err != nil || n != 1 {
+		t.Fatalf("expected 1 file compressed, got %d, %v", n, err)
+	}
+	f, err := os.Open(filepath.Join(dir, "app.js.gz"))
+	if err != nil {
+		t.Fatalf("missing gzip variant: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("invalid gzip variant: %v", err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != big {
+		t.Errorf("gzip variant doesn't match the original")
+	}
+	if n, err := Precompress(dir); err != nil || n != 0 {
+		t.Errorf("expected up-to-date variants to be kept, got %d, %v", n, err)
+	}
+}
+
+func TestLiveUpdates(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	router := setupRouter()
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ws?family=fam1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a handshake, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ws?family=fam9", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown family, got %d", w.Code)
+	}
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintf(conn, "GET /ws?family=fam1 HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n", host)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %v, %v", resp, err)
+	}
+	// readEvent reads one text frame; server frames are unmasked
+	readEvent := func() map[string]any {
+		t.Helper()
+		head := make([]byte, 2)
+		if _, err := io.ReadFull(br, head); err != nil {
+			t.Fatal(err)
+		}
+		n := int(head[1])
+		if n == 126 {
+			ext := make([]byte, 2)
+			io.ReadFull(br, ext)
+			n = int(ext[0])<<8 | int(ext[1])
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			t.Fatal(err)
+		}
+		var e map[string]any
+		if err := json.Unmarshal(payload, &e); err != nil {
+			t.Fatalf("invalid event %q: %v", payload, err)
+		}
+		return e
+	}
+
+	req := httptest.NewRequest("POST", "/reminders", strings.NewReader(`{"title":"Trash","family_id":"fam1","family_member":"Alice"}`))
+	req.Header.Set(ActorHeader, "Alice")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	e := readEvent()
+	if e["type"] != "reminder.created" || e["reminder_id"] != "rem1" || e["actor"] != "Alice" || e["reminder"].(map[string]any)["title"] != "Trash" {
+		t.Errorf("unexpected event %v", e)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/completion-events", strings.NewReader(`{"reminder_id":"rem1","completed_by":"Alice"}`)))
+	if e := readEvent(); e["type"] != "completion.recorded" || e["completion_event"] == nil {
+		t.Errorf("unexpected event %v", e)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/reminders/rem1?cascade=true", nil))
+	if e := readEvent(); e["type"] != "reminder.deleted" || e["reminder_id"] != "rem1" || e["reminder"] != nil {
+		t.Errorf("unexpected event %v", e)
+	}
+}
+
+func TestStaticLocales(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"index.html":     "home",
+		"member.html":    "member",
+		"app.js":         "shared script",
+		"es/index.html":  "inicio",
+		"es/member.html": "miembro",
+		"de/index.html":  "Startseite",
+	} {
+		_ = os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	locales, err := i18n.Discover(dir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := StaticHandler(dir, StaticOptions{Locales: locales})
+
+	for _, tc := range []struct {
+		path, language, cookie string
+		code                   int
+		location, body         string
+	}{
+		{"/", "es-MX,es;q=0.9", "", http.StatusFound, "/es/", ""},
+		{"/member.html?x=1", "es", "", http.StatusFound, "/es/member.html?x=1", ""},
+		// German has no member page, so the default one is served
+		{"/member.html", "de", "", http.StatusOK, "", "member"},
+		{"/", "fr, en;q=0.5", "", http.StatusOK, "", "home"},
+		{"/", "es", "en", http.StatusOK, "", "home"},
+		{"/", "en", "de", http.StatusFound, "/de/", ""},
+		{"/es/", "de", "", http.StatusOK, "", "inicio"},
+		{"/es/app.js", "", "", http.StatusOK, "", "shared script"},
+		{"/app.js", "es", "", http.StatusOK, "", "shared script"},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.Header.Set("Accept-Language", tc.language)
+		if tc.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: i18n.Cookie, Value: tc.cookie})
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.code || w.Header().Get("Location") != tc.location {
+			t.Errorf("%s (%s, cookie %q): expected %d %q, got %d %q", tc.path, tc.language, tc.cookie, tc.code, tc.location, w.Code, w.Header().Get("Location"))
+			continue
+		}
+		if tc.body != "" && w.Body.String() != tc.body {
+			t.Errorf("%s (%s): expected %q, got %q", tc.path, tc.language, tc.body, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if !strings.Contains(w.Header().Get("Vary"), "Accept-Language") {
+		t.Errorf("expected negotiated pages to vary by Accept-Language, got %q", w.Header().Get("Vary"))
+	}
+}
+
+func TestLocales(t *testing.T) {
+	saved := Locales
+	defer func() { Locales = saved }()
+	Locales = i18n.Locales{Default: "en", Supported: []string{"de", "en", "es"}}
+	router := setupRouter()
+
+	req := httptest.NewRequest("GET", "/locales", nil)
+	req.Header.Set("Accept-Language", "es-ES, en;q=0.5")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		Default   string   `json:"default"`
+		Supported []string `json:"supported"`
+		Preferred string   `json:"preferred"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected locales, got %d, %v", w.Code, err)
+	}
+	if resp.Default != "en" || len(resp.Supported) != 3 || resp.Preferred != "es" {
+		t.Errorf("unexpected locales %+v", resp)
+	}
+}
+
+func TestDeprecation(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith"})
+	AdminToken = "letmein"
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	Deprecations = map[string]Deprecation{
+		"GET /families/{id}": {Since: since, Sunset: since.AddDate(0, 6, 0), Successor: "/v2/families/{id}", Message: "use /v2/families/{id}"},
+	}
+	defer func() {
+		AdminToken, Deprecations = "", map[string]Deprecation{}
+		DisableDeprecated.Store(false)
+		deprecatedCalls = map[string]*deprecationUsage{}
+	}()
+	router := setupRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/families/fam1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"Deprecation": "@1735689600",
+		"Sunset":      "Tue, 01 Jul 2025 00:00:00 GMT",
+		"Link":        `</v2/families/fam1>; rel="successor-version"`,
+		"Warning":     `299 - "use /v2/families/{id}"`,
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("expected %s %q, got %q", header, want, got)
+		}
+	}
+
+	// Other methods on the same path aren't deprecated
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/families", nil))
+	if w.Header().Get("Deprecation") != "" {
+		t.Errorf("expected no Deprecation header on GET /families")
+	}
+
+	DisableDeprecated.Store(true)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/families/fam1", nil))
+	if w.Code != http.StatusGone || w.Header().Get("Link") == "" {
+		t.Errorf("expected status 410 with the successor, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/admin/deprecations", nil)
+	req.Header.Set("Authorization", "Bearer letmein")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var report []struct {
+		Endpoint string `json:"endpoint"`
+		Usage    struct {
+			Calls    int `json:"calls"`
+			Rejected int `json:"rejected"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected a report, got %d, %v", w.Code, err)
+	}
+	if len(report) != 1 || report[0].Endpoint != "GET /families/{id}" || report[0].Usage.Calls != 2 || report[0].Usage.Rejected != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	db, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "timeout.db"))
+	if err != nil {
+		t.Fatalf("failed to create SQLite storage: %v", err)
+	}
+	defer db.Close()
+	Store = db
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	AdminToken = "letmein"
+	Timeouts["GET /families/{id}"] = time.Nanosecond
+	defer func() {
+		AdminToken = ""
+		delete(Timeouts, "GET /families/{id}")
+		timedOutCalls = map[string]*timeoutUsage{}
+	}()
+	router := setupRouter()
+
+	// The storage call runs out of time
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/families/fam1", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d: %s", w.Code, w.Body.String())
+	}
+	// Other endpoints keep the default budget
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/families", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/admin/timeouts", nil)
+	req.Header.Set("Authorization", "Bearer letmein")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var report []struct {
+		Endpoint string `json:"endpoint"`
+		Timeout  string `json:"timeout"`
+		Count    int    `json:"count"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected a report, got %d, %v", w.Code, err)
+	}
+	if len(report) != 1 || report[0].Endpoint != "GET /families/{id}" || report[0].Timeout != "1ns" || report[0].Count != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestDomainEvents(t *testing.T) {
+	setupTestStorage()
+	router := setupRouter()
+	var got []string
+	unsubscribe := Events.Subscribe(func(e events.Event) { got = append(got, e.Type()+" "+e.FamilyID()) })
+	defer unsubscribe()
+
+	do := func(method, path, body string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(ActorHeader, "Alice")
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code >= 300 {
+			t.Fatalf("%s %s: unexpected status %d: %s", method, path, w.Code, w.Body.String())
+		}
+	}
+	do("POST", "/families", `{"name":"Smith","members":["Alice"]}`)
+	do("PUT", "/families/fam1/settings", `{}`)
+	do("POST", "/reminders", `{"title":"Trash","family_id":"fam1","family_member":"Alice"}`)
+	do("PATCH", "/reminders/rem1", `{"title":"Take out the trash"}`)
+	do("PATCH", "/reminders/rem1", `{"completed":true}`)
+	do("DELETE", "/completion-events/cev1", "")
+	do("DELETE", "/reminders/rem1", "")
+	do("DELETE", "/families/fam1", "")
+
+	want := []string{
+		"family.created fam1",
+		"family.updated fam1",
+		"reminder.created fam1",
+		"reminder.updated fam1",
+		"reminder.completed fam1",
+		"completion.deleted fam1",
+		"reminder.deleted fam1",
+		"family.deleted fam1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected events %v, got %v", want, got)
+	}
+
+	// The audit log is a subscriber and records the completion as such
+	entries, _ := audit.History(Store, "reminder", "rem1")
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	if want := []string{audit.ActionCreate, audit.ActionUpdate, audit.ActionComplete, audit.ActionDelete}; !reflect.DeepEqual(actions, want) {
+		t.Errorf("expected audit actions %v, got %v", want, actions)
+	}
+}
+
+func TestFamilyWebhooks(t *testing.T) {
+	setupTestStorage()
+	Webhooks = webhook.NewDispatcher(Store)
+	defer func() { Webhooks = nil }()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Bob"}})
+	router := setupRouter()
+
+	var received []client.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e client.Event
+		json.NewDecoder(r.Body).Decode(&e)
+		received = append(received, e)
+	}))
+	defer srv.Close()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/families/fam1/webhooks", `{"url": "ftp://example.com"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-http url, got %d", w.Code)
+	}
+	if w := do("POST", "/families/fam1/webhooks", `{"url": "`+srv.URL+`", "events": ["reminder.exploded"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown event type, got %d", w.Code)
+	}
+	w := do("POST", "/families/fam1/webhooks", `{"url": "`+srv.URL+`", "events": ["reminder.created"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.ID == "" || created.Secret == "" {
+		t.Fatalf("expected an ID and secret, got %+v", created)
+	}
+
+	do("POST", "/reminders", `{"title": "Trash", "family_id": "fam1", "family_member": "Alice"}`)
+	do("POST", "/reminders", `{"title": "Dishes", "family_id": "fam2", "family_member": "Bob"}`)
+	Webhooks.Process(context.Background(), time.Now())
+	if len(received) != 1 || received[0].Type != "reminder.created" || received[0].FamilyID != "fam1" {
+		t.Fatalf("expected one reminder.created event for fam1, got %+v", received)
+	}
+
+	w = do("GET", "/families/fam1/webhooks", "")
+	if strings.Contains(w.Body.String(), created.Secret) || !strings.Contains(w.Body.String(), created.ID) {
+		t.Errorf("expected webhook listing without secrets, got %s", w.Body.String())
+	}
+	w = do("GET", "/families/fam1/webhooks/"+created.ID+"/deliveries", "")
+	var deliveries []webhook.Delivery
+	json.NewDecoder(w.Body).Decode(&deliveries)
+	if len(deliveries) != 1 || deliveries[0].Status != webhook.StatusDelivered || deliveries[0].ResponseStatus != http.StatusOK {
+		t.Errorf("expected one successful delivery, got %+v", deliveries)
+	}
+	if w := do("GET", "/families/fam2/webhooks/"+created.ID+"/deliveries", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another family's webhook, got %d", w.Code)
+	}
+	if w := do("DELETE", "/families/fam1/webhooks/"+created.ID, ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	if w := do("GET", "/families/fam1/webhooks/"+created.ID+"/deliveries", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted webhook, got %d", w.Code)
+	}
+}
+
+func TestPoliciesMatchRoutes(t *testing.T) {
+	routes := map[string]bool{}
+	setupRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tmpl, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		for _, m := range methods {
+			routes[m+" "+tmpl] = true
+		}
+		return nil
+	})
+	for key := range Policies {
+		if !routes[key] {
+			t.Errorf("policy for unknown endpoint %s", key)
+		}
+	}
+}
+
+func TestMyPermissions(t *testing.T) {
+	setupTestStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Mom", "Kid"}}
+	_ = Store.CreateFamily(f)
+	router := setupRouter()
+
+	get := func(path, actor string) (*httptest.ResponseRecorder, permissionsResponse) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(ActorHeader, actor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp permissionsResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp
+	}
+
+	if w, _ := get("/me/permissions", "Kid"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without family_id, got %d", w.Code)
+	}
+	if w, _ := get("/me/permissions?family_id=fam9", "Kid"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown family, got %d", w.Code)
+	}
+	_, resp := get("/me/permissions?family_id=fam1", "Kid")
+	if len(resp.Permissions) != len(family.Permissions) || !resp.Endpoints["DELETE /families/{id}"] {
+		t.Errorf("expected every permission without roles, got %+v", resp)
+	}
+
+	f.Settings.Members = map[string]family.MemberSettings{"Mom": {Role: family.RoleOwner}, "Kid": {Role: family.RoleChild}}
+	_ = Store.UpdateFamily(f)
+	_, resp = get("/me/permissions?family_id=fam1", "Kid")
+	if resp.Role != family.RoleChild || !reflect.DeepEqual(resp.Permissions, []family.Permission{family.PermComplete}) {
+		t.Errorf("unexpected child permissions: %+v", resp)
+	}
+	if resp.Endpoints["POST /reminders"] || !resp.Endpoints["PATCH /reminders/{id}"] || len(resp.Endpoints) != len(Policies) {
+		t.Errorf("unexpected child endpoints: %v", resp.Endpoints)
+	}
+	_, resp = get("/me/permissions?family_id=fam1", "Stranger")
+	if resp.Role != "" || len(resp.Permissions) != 0 {
+		t.Errorf("expected no permissions for an outsider, got %+v", resp)
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	router := setupRouter()
+
+	do := func(method, url, actor, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if actor != "" {
+			req.Header.Set(ActorHeader, actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tt := range []struct {
+		url, actor string
+		want       int
+	}{
+		{"/me/preferences", "Alice", http.StatusBadRequest},
+		{"/me/preferences?family_id=fam1", "", http.StatusUnauthorized},
+		{"/me/preferences?family_id=fam9", "Alice", http.StatusNotFound},
+		{"/me/preferences?family_id=fam1", "Mallory", http.StatusForbidden},
+	} {
+		if w := do("GET", tt.url, tt.actor, ""); w.Code != tt.want {
+			t.Errorf("GET %s as %q: expected status %d, got %d", tt.url, tt.actor, tt.want, w.Code)
+		}
+	}
+
+	body := `{"filter": {"assignee": "Alice", "completed": false}, "sort": "-due_date", "theme": "dark", "start_page": "/today"}`
+	if w := do("PUT", "/me/preferences?family_id=fam1", "Alice", body); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 saving preferences, got %d: %s", w.Code, w.Body)
+	}
+	if w := do("PUT", "/me/preferences?family_id=fam1", "Alice", `{"theme": "neon"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown theme, got %d", w.Code)
+	}
+
+	var got preferences.Preferences
+	json.NewDecoder(do("GET", "/me/preferences?family_id=fam1", "Alice", "").Body).Decode(&got)
+	if got.Sort != "-due_date" || got.Theme != "dark" || got.StartPage != "/today" || got.Filter.Assignee != "Alice" || got.UpdatedAt == nil {
+		t.Errorf("expected Alice's preferences, got %+v", got)
+	}
+	got = preferences.Preferences{}
+	json.NewDecoder(do("GET", "/me/preferences?family_id=fam1", "Bob", "").Body).Decode(&got)
+	if got.Theme != "" || got.UpdatedAt != nil {
+		t.Errorf("expected Bob to have no preferences yet, got %+v", got)
+	}
+}
+
+func TestActAs(t *testing.T) {
+	setupTestStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Mom", "Dad", "Toddler"}}
+	f.Settings.Members = map[string]family.MemberSettings{"Mom": {Role: family.RoleOwner}, "Toddler": {Role: family.RoleChild}}
+	_ = Store.CreateFamily(f)
+	due := time.Now().Add(time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Tidy toys", "", &due, "fam1", "Toddler", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, path, actor, actAs, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(ActorHeader, actor)
+		if actAs != "" {
+			req.Header.Set(ActAsHeader, actAs)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("PATCH", "/reminders/rem1", "Dad", "Toddler", `{"completed": true}`); w.Code != http.StatusForbidden {
+		t.Errorf("adult acting as a member: expected 403, got %d", w.Code)
+	}
+	if w := do("PATCH", "/reminders/rem1", "Mom", "Grandma", `{"completed": true}`); w.Code != http.StatusBadRequest {
+		t.Errorf("acting as an outsider: expected 400, got %d", w.Code)
+	}
+	if w := do("GET", "/families", "Mom", "Toddler", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("acting as a member outside a family endpoint: expected 400, got %d", w.Code)
+	}
+	if w := do("PATCH", "/reminders/rem1", "Mom", "Toddler", `{"completed": true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	events, _ := Store.ListCompletionEvents("rem1")
+	if len(events) != 1 || events[0].CompletedBy != "Toddler" || events[0].Impersonator != "Mom" {
+		t.Errorf("expected a completion by Toddler recorded by Mom, got %+v", events)
+	}
+	entries, _ := audit.History(Store, "reminder", "rem1")
+	if len(entries) != 1 || entries[0].Actor != "Toddler" || entries[0].Impersonator != "Mom" {
+		t.Errorf("expected an audit entry for Toddler recorded by Mom, got %+v", entries)
+	}
+
+	// Acting as themselves, callers aren't recorded as impersonators
+	do("PATCH", "/reminders/rem1", "Mom", "", `{"completed": false}`)
+	entries, _ = audit.History(Store, "reminder", "rem1")
+	if last := entries[len(entries)-1]; last.Actor != "Mom" || last.Impersonator != "" {
+		t.Errorf("unexpected audit entry: %+v", last)
+	}
+}
+
+func TestAckReminder(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	due := time.Now().Add(-time.Hour).Truncate(time.Second)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Call the vet", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, actor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		if actor != "" {
+			req.Header.Set(ActorHeader, actor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/reminders/rem1/ack", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var ack reminder.Ack
+	json.NewDecoder(w.Body).Decode(&ack)
+	if ack.AckedBy != "Alice" || ack.Occurrence == nil || !ack.Occurrence.Equal(due) {
+		t.Errorf("expected the assignee to acknowledge the due occurrence, got %+v", ack)
+	}
+	do("POST", "/reminders/rem1/ack", "Bob")
+	acks, _ := storage.ListJSON[reminder.Ack](Store, storage.RecordQuery{Kind: reminder.AckKind, Ref: "rem1"})
+	if len(acks) != 2 || acks[1].AckedBy != "Bob" {
+		t.Errorf("expected acknowledgements by Alice and Bob, got %+v", acks)
+	}
+	if r, _ := Store.GetReminder("rem1"); r.Version != 1 || r.Completed {
+		t.Errorf("expected acknowledging to leave the reminder alone, got %+v", r)
+	}
+
+	if w := do("POST", "/reminders/nope/ack", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	r, _ := Store.GetReminder("rem1")
+	r.Completed = true
+	_ = Store.CreateReminder(r)
+	if w := do("POST", "/reminders/rem1/ack", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a done reminder, got %d", w.Code)
+	}
+
+	// Acknowledgements go with their reminder
+	if err := storage.DeleteReminderCascade(Store, "rem1"); err != nil {
+		t.Fatalf("failed to delete reminder: %v", err)
+	}
+	if acks, _ := storage.ListJSON[reminder.Ack](Store, storage.RecordQuery{Kind: reminder.AckKind, Ref: "rem1"}); len(acks) != 0 {
+		t.Errorf("expected acknowledgements to be deleted with the reminder, got %+v", acks)
+	}
+}
+
+func TestSnoozeReminder(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	due := time.Now().Add(time.Hour).Truncate(time.Second)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Call the vet", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Someday", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(ActorHeader, "Alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for body, want := range map[string]int{
+		`{"duration": "soon"}`: http.StatusBadRequest,
+		`{"duration": "-1h"}`:  http.StatusBadRequest,
+	} {
+		if w := do("POST", "/reminders/rem1/snooze", body); w.Code != want {
+			t.Errorf("snooze %s: expected status %d, got %d", body, want, w.Code)
+		}
+	}
+	if w := do("POST", "/reminders/rem2/snooze", `{"duration": "1h"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 without a due date, got %d", w.Code)
+	}
+
+	// Snoozes postpone from the due date and stack
+	do("POST", "/reminders/rem1/snooze", `{"duration": "30m"}`)
+	w := do("POST", "/reminders/rem1/snooze", `{"duration": "1h"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got reminder.Reminder
+	json.NewDecoder(w.Body).Decode(&got)
+	until := due.Add(90 * time.Minute)
+	if got.SnoozedUntil == nil || !got.SnoozedUntil.Equal(until) || !got.DueDate.Equal(due) || got.Version != 3 {
+		t.Fatalf("expected snoozed until %s with the due date kept, got %+v", until, got)
+	}
+
+	// List filters use the end of the snooze
+	for query, want := range map[string]int{
+		"due_before=" + url.QueryEscape(due.Add(time.Minute).Format(time.RFC3339)): 0,
+		"due_after=" + url.QueryEscape(until.Format(time.RFC3339)):                 1,
+	} {
+		var list []reminder.Reminder
+		json.NewDecoder(do("GET", "/reminders?family_id=fam1&"+query, "").Body).Decode(&list)
+		if len(list) != want {
+			t.Errorf("GET /reminders?%s: expected %d reminders, got %d", query, want, len(list))
+		}
+	}
+
+	// The snooze is read-only to patches and ends on completion
+	if w := do("PATCH", "/reminders/rem1", `{"snoozed_until": null}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 patching snoozed_until, got %d", w.Code)
+	}
+	if w := do("PATCH", "/reminders/rem1", `{"completed": true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 completing, got %d", w.Code)
+	}
+	if r, _ := Store.GetReminder("rem1"); r.SnoozedUntil != nil {
+		t.Errorf("expected completion to end the snooze, got %s", r.SnoozedUntil)
+	}
+	if w := do("POST", "/reminders/rem1/snooze", `{"duration": "1h"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 snoozing a completed reminder, got %d", w.Code)
+	}
+}
+
+func TestGuestAccess(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	soon, later := time.Now().Add(48*time.Hour), time.Now().Add(30*24*time.Hour)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Feed the cat", "", &soon, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Taxes", "", &later, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	alice := map[string]string{ActorHeader: "Alice"}
+
+	before := time.Now().Add(7 * 24 * time.Hour).Format(time.RFC3339)
+	if w := do("POST", "/families/fam1/guests", `{"scope": {"due_before": "`+before+`"}}`, alice); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a name, got %d", w.Code)
+	}
+	w := do("POST", "/families/fam1/guests", `{"name": "Sitter", "scope": {"due_before": "`+before+`"}, "ttl": "72h"}`, alice)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created guestResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Token == "" || created.CreatedBy != "Alice" {
+		t.Fatalf("unexpected guest: %+v", created)
+	}
+	sitter := map[string]string{GuestHeader: created.Token}
+
+	var list []reminder.Reminder
+	json.NewDecoder(do("GET", "/reminders", "", sitter).Body).Decode(&list)
+	if len(list) != 1 || list[0].ID != "rem1" {
+		t.Errorf("expected the guest to see only rem1, got %+v", list)
+	}
+	for _, tc := range []struct {
+		method, url, body string
+		headers           map[string]string
+		want              int
+	}{
+		{"GET", "/reminders/rem2", "", sitter, http.StatusNotFound},
+		{"GET", "/families/fam1", "", sitter, http.StatusForbidden},
+		{"PATCH", "/reminders/rem1", `{"title": "Feed the dog"}`, sitter, http.StatusForbidden},
+		{"GET", "/reminders", "", map[string]string{GuestHeader: created.Token, ActorHeader: "Alice"}, http.StatusBadRequest},
+		{"GET", "/reminders", "", map[string]string{GuestHeader: "bogus"}, http.StatusUnauthorized},
+		{"PATCH", "/reminders/rem1", `{"completed": true}`, sitter, http.StatusOK},
+	} {
+		if w := do(tc.method, tc.url, tc.body, tc.headers); w.Code != tc.want {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.url, tc.want, w.Code)
+		}
+	}
+	if r, _ := Store.GetReminder("rem1"); !r.Completed {
+		t.Error("expected the guest to complete rem1")
+	}
+	var entries []audit.Entry
+	json.NewDecoder(do("GET", "/reminders/rem1/history", "", alice).Body).Decode(&entries)
+	if len(entries) == 0 || entries[len(entries)-1].Actor != "guest:Sitter" {
+		t.Errorf("expected the completion to be audited as the guest, got %+v", entries)
+	}
+
+	if w := do("DELETE", "/families/fam1/guests/"+created.ID, "", alice); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204 revoking, got %d", w.Code)
+	}
+	if w := do("GET", "/reminders", "", sitter); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 after revocation, got %d", w.Code)
+	}
+	var guests []guest.Guest
+	json.NewDecoder(do("GET", "/families/fam1/guests", "", alice).Body).Decode(&guests)
+	if len(guests) != 1 || guests[0].RevokedAt == nil {
+		t.Errorf("expected the revoked guest to be listed, got %+v", guests)
+	}
+}
+
+func TestTwoPersonCompletion(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}})
+	rem := reminder.NewReminder("rem1", "Give medication", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	rem.RequiresConfirmation = true
+	_ = Store.CreateReminder(rem)
+	router := setupRouter()
+
+	complete := func(actor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/reminders/rem1", strings.NewReader(`{"completed": true}`))
+		req.Header.Set(ActorHeader, actor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := complete("Alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for the first sign-off, got %d: %s", w.Code, w.Body.String())
+	}
+	events, _ := Store.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: "rem1"})
+	if r, _ := Store.GetReminder("rem1"); r.Completed || len(events) != 1 || !events[0].Awaiting() || events[0].CompletedBy != "Alice" {
+		t.Fatalf("expected an open reminder awaiting confirmation, got completed=%v and events %+v", r.Completed, events)
+	}
+	if w := complete("Alice"); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 confirming one's own sign-off, got %d", w.Code)
+	}
+
+	if w := complete("Bob"); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for the second sign-off, got %d: %s", w.Code, w.Body.String())
+	}
+	if r, _ := Store.GetReminder("rem1"); !r.Completed {
+		t.Error("expected the reminder to be completed after the second sign-off")
+	}
+	events, _ = Store.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: "rem1"})
+	if len(events) != 2 {
+		t.Fatalf("expected 2 completion events, got %d", len(events))
+	}
+	first, second := events[0], events[1]
+	if first.State != reminder.StateConfirmed || second.State != reminder.StateConfirmed ||
+		first.PairID != second.ID || second.PairID != first.ID || second.CompletedBy != "Bob" {
+		t.Errorf("expected paired confirmed events, got %+v and %+v", first, second)
+	}
+
+	// Completion events recorded directly need the same two sign-offs
+	rem2 := reminder.NewReminder("rem2", "Give medication", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	rem2.RequiresConfirmation = true
+	_ = Store.CreateReminder(rem2)
+	record := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/completion-events", strings.NewReader(body)))
+		return w
+	}
+	for _, body := range []string{
+		`{"reminder_id": "rem2", "completed_by": "Alice", "state": "confirmed"}`,
+		`{"reminder_id": "rem2", "completed_by": "Alice", "pair_id": "` + first.ID + `"}`,
+		`{"reminder_id": "rem2", "completed_by": "Alice", "id": "` + first.ID + `"}`,
+	} {
+		if w := record(body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+	if e, _ := Store.GetCompletionEvent(first.ID); e.ReminderID != "rem1" || e.PairID != second.ID {
+		t.Errorf("expected the existing event to be left alone, got %+v", e)
+	}
+	if w := record(`{"reminder_id": "rem2", "completed_by": "Alice"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 for the first sign-off, got %d: %s", w.Code, w.Body.String())
+	}
+	if r, _ := Store.GetReminder("rem2"); r.Completed {
+		t.Error("expected a single completion event not to complete the reminder")
+	}
+	if w := record(`{"reminder_id": "rem2", "completed_by": "Alice"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 confirming one's own sign-off, got %d", w.Code)
+	}
+	w := record(`{"reminder_id": "rem2", "completed_by": "Bob"}`)
+	var confirmed reminder.CompletionEvent
+	json.NewDecoder(w.Body).Decode(&confirmed)
+	if w.Code != http.StatusCreated || confirmed.State != reminder.StateConfirmed {
+		t.Fatalf("expected the second sign-off to be confirmed, got %d %+v", w.Code, confirmed)
+	}
+	if r, _ := Store.GetReminder("rem2"); !r.Completed {
+		t.Error("expected the reminder to be completed after the second sign-off")
+	}
+}
+
+func TestScheduleDrift(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	saturday := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Mow the lawn", "", &saturday, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"saturday"}}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Take out trash", "", &saturday, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"saturday"}}))
+	for i := 0; i < 5; i++ {
+		week := saturday.AddDate(0, 0, 7*i)
+		_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: fmt.Sprintf("cev1%d", i), ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: week.AddDate(0, 0, 3)})
+		_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: fmt.Sprintf("cev2%d", i), ReminderID: "rem2", CompletedBy: "Alice", CompletedAt: week})
+	}
+	router := setupRouter()
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var reports []drift.Report
+	json.NewDecoder(do("GET", "/families/fam1/drift").Body).Decode(&reports)
+	if len(reports) != 1 || reports[0].ReminderID != "rem1" || reports[0].DriftHours != 72 {
+		t.Fatalf("expected rem1 to drift by 72 hours, got %+v", reports)
+	}
+
+	if w := do("POST", "/reminders/rem2/adjust-schedule"); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 adjusting a reminder without drift, got %d", w.Code)
+	}
+	if w := do("POST", "/reminders/rem1/adjust-schedule"); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	r, _ := Store.GetReminder("rem1")
+	if !r.DueDate.Equal(saturday.AddDate(0, 0, 3)) || len(r.Recurrence.Days) != 1 || r.Recurrence.Days[0] != "tuesday" || r.Version != 2 {
+		t.Errorf("expected the schedule moved to tuesday, got %s %+v (version %d)", r.DueDate, r.Recurrence, r.Version)
+	}
+
+	reports = nil
+	json.NewDecoder(do("GET", "/families/fam1/drift").Body).Decode(&reports)
+	if len(reports) != 0 {
+		t.Errorf("expected no drift after adjusting, got %+v", reports)
+	}
+	if w := do("GET", "/families/nope/drift"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown family, got %d", w.Code)
+	}
+}
+
+func TestFamilyReport(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	due := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/families/fam1/report.pdf?month=2025-03")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("expected a PDF, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte("(0 of 1 reminders completed \\(0%\\))")) {
+		t.Errorf("expected nothing completed yet")
+	}
+
+	// Completing the reminder invalidates the cached report
+	req := httptest.NewRequest("PATCH", "/reminders/rem1", strings.NewReader(`{"completed": true}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	w = get("/families/fam1/report.pdf?month=2025-03")
+	if !bytes.Contains(w.Body.Bytes(), []byte("(1 of 1 reminders completed \\(100%\\))")) {
+		t.Errorf("expected the report to be rebuilt after a completion")
+	}
+
+	for url, want := range map[string]int{
+		"/families/fam1/report.pdf?month=March":   http.StatusBadRequest,
+		"/families/fam1/report.pdf?month=2999-01": http.StatusBadRequest,
+		"/families/fam1/report.pdf":               http.StatusOK,
+		"/families/nope/report.pdf":               http.StatusNotFound,
+	} {
+		if w := get(url); w.Code != want {
+			t.Errorf("GET %s: expected status %d, got %d", url, want, w.Code)
+		}
+	}
+}
+
+func TestFamilyStats(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}, Settings: family.Settings{Timezone: "UTC"}})
+	due := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "e1", ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: due})
+	router := setupRouter()
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/families/fam1/stats?from=2025-03-03&to=2025-03-04")
+	var days []stats.Day
+	json.NewDecoder(w.Body).Decode(&days)
+	if w.Code != http.StatusOK || len(days) != 2 {
+		t.Fatalf("expected 2 days, got %d %+v", w.Code, days)
+	}
+	if days[0].Date != "2025-03-03" || days[0].Due != 1 || days[0].Done != 1 || days[0].Members["Alice"].Completions != 1 {
+		t.Errorf("unexpected first day %+v", days[0])
+	}
+	if days[1].Due != 1 || days[1].Done != 0 || days[1].Overdue != 1 {
+		t.Errorf("unexpected second day %+v", days[1])
+	}
+
+	w = get("/families/fam1/stats?from=2025-03-03&to=2025-03-04&view=summary")
+	var sum stats.Summary
+	json.NewDecoder(w.Body).Decode(&sum)
+	if w.Code != http.StatusOK || sum.Due != 2 || sum.Done != 1 || sum.Members["Alice"].CompletionRate != 0.5 {
+		t.Fatalf("expected half of 2 occurrences done, got %d %+v", w.Code, sum)
+	}
+	if len(sum.Streaks) != 1 || sum.Streaks[0].SeriesID != "rem1" || sum.Streaks[0].Current != 0 || sum.Streaks[0].Longest != 1 {
+		t.Errorf("unexpected streaks %+v", sum.Streaks)
+	}
+
+	// Retention shorter than the stats cover is refused
+	req := httptest.NewRequest("PUT", "/families/fam1/settings", strings.NewReader(`{"completion_retention_days": 7}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a 7 day retention, got %d", w.Code)
+	}
+
+	for url, want := range map[string]int{
+		"/families/fam1/stats":                               http.StatusOK,
+		"/families/fam1/stats?from=March":                    http.StatusBadRequest,
+		"/families/fam1/stats?view=weekly":                   http.StatusBadRequest,
+		"/families/fam1/stats?from=2025-03-05&to=2025-03-04": http.StatusBadRequest,
+		"/families/fam1/stats?from=2020-01-01&to=2025-03-04": http.StatusBadRequest,
+		"/families/nope/stats":                               http.StatusNotFound,
+	} {
+		if w := get(url); w.Code != want {
+			t.Errorf("GET %s: expected status %d, got %d", url, want, w.Code)
+		}
+	}
+}
+
+func TestFamilyMembers(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}, Settings: family.Settings{
+		Members: map[string]family.MemberSettings{"Alice": {Role: family.RoleOwner}, "Bob": {Role: family.RoleAdult}},
+	}})
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", nil, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Dishes", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	router := setupRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set(ActorHeader, "Alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/families/fam1/members", `{"name": "Carol"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 adding Carol, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/families/fam1/members", `{"name": "Carol"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 adding Carol twice, got %d", w.Code)
+	}
+
+	// Bob's reminder must go somewhere before he can leave
+	if w := do("DELETE", "/families/fam1/members/Bob", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 removing Bob without reassigning, got %d", w.Code)
+	}
+	if w := do("DELETE", "/families/fam1/members/Bob?reassign_to=Bob", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 reassigning to Bob himself, got %d", w.Code)
+	}
+	if w := do("DELETE", "/families/fam1/members/Bob?reassign_to=Carol", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204 removing Bob, got %d: %s", w.Code, w.Body.String())
+	}
+	f, _ := Store.GetFamily("fam1")
+	if hasMember(f, "Bob") || !hasMember(f, "Carol") {
+		t.Errorf("unexpected members %v", f.Members)
+	}
+	if _, ok := f.Settings.Members["Bob"]; ok {
+		t.Errorf("expected Bob's settings removed")
+	}
+	if rem, _ := Store.GetReminder("rem1"); rem.FamilyMember != "Carol" {
+		t.Errorf("expected rem1 reassigned to Carol, got %q", rem.FamilyMember)
+	}
+	if w := do("DELETE", "/families/fam1/members/Bob", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 removing Bob again, got %d", w.Code)
+	}
+
+	// The last owner can't be removed
+	if w := do("PATCH", "/families/fam1", `{"remove_members": ["Alice"], "orphan": true}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 removing the only owner, got %d", w.Code)
+	}
+
+	w := do("PATCH", "/families/fam1", `{"name": "Smith-Jones", "add_members": ["Dave"], "remove_members": ["Carol"], "orphan": true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 patching the family, got %d: %s", w.Code, w.Body.String())
+	}
+	f = &family.Family{}
+	json.NewDecoder(w.Body).Decode(f)
+	if f.Name != "Smith-Jones" || len(f.Members) != 2 || f.Members[1] != "Dave" {
+		t.Errorf("unexpected family %+v", f)
+	}
+	if rem, _ := Store.GetReminder("rem1"); rem.FamilyMember != "" {
+		t.Errorf("expected rem1 orphaned, got %q", rem.FamilyMember)
+	}
+	for body, want := range map[string]int{
+		`{"name": " "}`:               http.StatusBadRequest,
+		`{"remove_members": ["Zed"]}`: http.StatusBadRequest,
+		`{"remove_members": ["Dave"], "reassign_to": "Alice", "orphan": true}`: http.StatusBadRequest,
+	} {
+		if w := do("PATCH", "/families/fam1", body); w.Code != want {
+			t.Errorf("PATCH %s: expected status %d, got %d", body, want, w.Code)
+		}
+	}
+}
+
+func TestCascadeDelete(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Dishes", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem3", "Laundry", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "e1", ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: time.Now()})
+	_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "e2", ReminderID: "rem2", CompletedBy: "Alice", CompletedAt: time.Now()})
+	_ = storage.PutJSON(Store, storage.Record{Kind: reminder.StatusEventKind, ID: "s1", FamilyID: "fam1", Ref: "rem1", CreatedAt: time.Now()}, reminder.StatusEvent{ID: "s1"})
+	_, _ = storage.PutBlob(Store, avatarID("fam1", ""), "fam1", "image/png", []byte("png"), time.Now())
+	router := setupRouter()
+
+	del := func(url string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", url, nil))
+		return w.Code
+	}
+
+	for _, tt := range []struct {
+		url  string
+		want int
+	}{
+		{"/reminders/rem1?permanent=true", http.StatusConflict},
+		{"/reminders/rem1?permanent=true&cascade=maybe", http.StatusBadRequest},
+		{"/reminders/rem1?permanent=maybe", http.StatusBadRequest},
+		{"/families/fam1?permanent=true", http.StatusConflict},
+		{"/reminders/rem3?permanent=true", http.StatusNoContent},
+		{"/reminders/rem1?permanent=true&cascade=true", http.StatusNoContent},
+	} {
+		if got := del(tt.url); got != tt.want {
+			t.Errorf("DELETE %s: expected status %d, got %d", tt.url, tt.want, got)
+		}
+	}
+	if _, err := Store.GetCompletionEvent("e1"); err == nil {
+		t.Errorf("expected rem1's completion event deleted")
+	}
+	if recs, _ := Store.ListRecords(storage.RecordQuery{Kind: reminder.StatusEventKind}); len(recs) != 0 {
+		t.Errorf("expected rem1's status events deleted, got %d", len(recs))
+	}
+
+	if got := del("/families/fam1?permanent=true&cascade=true"); got != http.StatusNoContent {
+		t.Fatalf("expected status 204 deleting the family, got %d", got)
+	}
+	if _, err := Store.GetReminder("rem2"); err == nil {
+		t.Errorf("expected rem2 deleted with the family")
+	}
+	if _, err := Store.GetCompletionEvent("e2"); err == nil {
+		t.Errorf("expected rem2's completion event deleted with the family")
+	}
+	if _, err := storage.GetBlob(Store, avatarID("fam1", "")); !errors.Is(err, storage.ErrRecordNotFound) {
+		t.Errorf("expected the family's avatar deleted with it, got %v", err)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = Store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	_ = Store.CreateReminder(reminder.NewReminder("rem2", "Dishes", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	_ = Store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "e1", ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: time.Now()})
+	router := setupRouter()
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w
+	}
+
+	// Completed reminders go to the trash without cascade, keeping their
+	// history
+	if w := do("DELETE", "/reminders/rem1"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204 deleting rem1, got %d: %s", w.Code, w.Body)
+	}
+	if _, err := Store.GetReminder("rem1"); err == nil {
+		t.Errorf("expected rem1 gone from the reminders")
+	}
+	w := do("GET", "/trash?family_id=fam1")
+	var trash []storage.Trashed
+	json.NewDecoder(w.Body).Decode(&trash)
+	if len(trash) != 1 || trash[0].Reminder == nil || trash[0].Reminder.ID != "rem1" || trash[0].Reminder.DeletedAt == nil {
+		t.Fatalf("expected rem1 in the trash, got %+v", trash)
+	}
+	if w := do("GET", "/trash"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without family_id, got %d", w.Code)
+	}
+
+	w = do("POST", "/reminders/rem1/restore")
+	var restored reminder.Reminder
+	json.NewDecoder(w.Body).Decode(&restored)
+	if w.Code != http.StatusOK || restored.ID != "rem1" || restored.DeletedAt != nil {
+		t.Fatalf("expected rem1 restored, got %d: %+v", w.Code, restored)
+	}
+	if _, err := Store.GetCompletionEvent("e1"); err != nil {
+		t.Errorf("expected rem1's completion event kept: %v", err)
+	}
+	if w := do("POST", "/reminders/rem1/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 restoring twice, got %d", w.Code)
+	}
+
+	// A reminder deleted before its family can only come back after it
+	do("DELETE", "/reminders/rem2")
+	if w := do("DELETE", "/families/fam1?cascade=true"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204 deleting the family, got %d: %s", w.Code, w.Body)
+	}
+	if _, err := Store.GetFamily("fam1"); err == nil {
+		t.Errorf("expected fam1 gone from the families")
+	}
+	if w := do("POST", "/reminders/rem2/restore"); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 restoring into a trashed family, got %d", w.Code)
+	}
+	if w := do("POST", "/families/fam1/restore"); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 restoring the family, got %d: %s", w.Code, w.Body)
+	}
+	if rem, err := Store.GetReminder("rem1"); err != nil || rem.DeletedAt != nil {
+		t.Errorf("expected rem1 restored with the family, got %+v, %v", rem, err)
+	}
+	if w := do("POST", "/reminders/rem2/restore"); w.Code != http.StatusOK {
+		t.Errorf("expected status 200 restoring rem2, got %d", w.Code)
+	}
+
+	// Purging removes the trashed reminder's events for good
+	do("DELETE", "/reminders/rem1")
+	if n, err := storage.PurgeTrash(Store, time.Now()); err != nil || n != 0 {
+		t.Errorf("expected nothing due for purging yet, got %d, %v", n, err)
+	}
+	if n, err := storage.PurgeTrash(Store, time.Now().Add(TrashRetention+time.Minute)); err != nil || n != 1 {
+		t.Errorf("expected rem1 purged, got %d, %v", n, err)
+	}
+	if _, err := Store.GetCompletionEvent("e1"); err == nil {
+		t.Errorf("expected rem1's completion event purged")
+	}
+	if w := do("POST", "/reminders/rem1/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 restoring a purged reminder, got %d", w.Code)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	setupTestStorage()
+	_ = Store.CreateFamily(&family.Family{ID: "smith", Name: "Smith", Members: []string{"Alice"}})
+	router := setupRouter()
+
+	post := func(url, key, actor, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyHeader, key)
+		}
+		req.Header.Set(ActorHeader, actor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	count := func() int {
+		list, _ := Store.ListReminders()
+		return len(list)
+	}
+
+	body := `{"title": "Trash", "family_id": "smith", "family_member": "Alice"}`
+	first := post("/reminders", "k1", "Alice", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", first.Code, first.Body.String())
+	}
+	retry := post("/reminders", "k1", "Alice", body)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("expected the first response replayed, got %d %s", retry.Code, retry.Body.String())
+	}
+	if n := count(); n != 1 {
+		t.Errorf("expected 1 reminder after a retry, got %d", n)
+	}
+
+	if w := post("/reminders", "k1", "Alice", `{"title": "Dishes", "family_id": "smith"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 reusing a key for another body, got %d", w.Code)
+	}
+	// Keys are per caller, and requests without one always create
+	post("/reminders", "k1", "Bob", body)
+	post("/reminders", "", "Alice", body)
+	post("/reminders", "", "Alice", body)
+	if n := count(); n != 4 {
+		t.Errorf("expected 4 reminders, got %d", n)
+	}
+
+	// Failed requests aren't remembered
+	if w := post("/reminders", "k2", "Alice", `{"title": "Dishes", "family_id": "nope"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an unknown family, got %d", w.Code)
+	}
+	if w := post("/reminders", "k2", "Alice", `{"title": "Dishes", "family_id": "smith", "family_member": "Alice"}`); w.Code != http.StatusCreated {
+		t.Errorf("expected status 201 retrying a failed key with a fixed body, got %d", w.Code)
+	}
+
+	w := post("/families", "k3", "Alice", `{"name": "Jones"}`)
+	post("/families", "k3", "Alice", `{"name": "Jones"}`)
+	if families, _ := Store.ListFamilies(); w.Code != http.StatusCreated || len(families) != 2 {
+		t.Errorf("expected 1 family created, got %d families", len(families))
+	}
+
+	// Remembered responses expire with the record
+	if n, _ := Store.PurgeExpiredRecords(time.Now().Add(IdempotencyTTL)); n != 4 {
+		t.Errorf("expected 4 remembered responses purged, got %d", n)
+	}
+}
+
+func TestIdempotencyKeyInProgress(t *testing.T) {
+	setupTestStorage()
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	router := mux.NewRouter()
+	router.Use(IdempotencyMiddleware)
+	router.HandleFunc("/reminders", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "rem1"}`))
+	}).Methods("POST")
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/reminders", strings.NewReader(`{"title": "Trash"}`))
+		req.Header.Set(IdempotencyHeader, "k1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post() }()
+	<-started
+	// A retry while the first request is running neither waits for it nor
+	// runs the handler again
+	if w := post(); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 while the first request is in progress, got %d", w.Code)
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	if w := post(); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("expected the first response replayed, got %d", w.Code)
+	}
+	if calls != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", calls)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"reminder-app/internal/breaker"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/version"
+)
+
+// StorageBreaker is the circuit breaker guarding Store, if any. While it is
+// open the server reports not ready.
+var StorageBreaker *breaker.Breaker
+
+// readiness is the outcome of the startup checks. The server isn't ready
+// until SetReadiness reports no problems.
+var readiness struct {
+	sync.RWMutex
+	checked  bool
+	problems []string
+}
+
+// SetReadiness records the problems found by the startup checks; none
+// marks the server ready
+func SetReadiness(problems []string) {
+	readiness.Lock()
+	defer readiness.Unlock()
+	readiness.checked = true
+	readiness.problems = problems
+}
+
+// ready reports whether the startup checks passed and, if not, why
+func ready() (bool, []string) {
+	readiness.RLock()
+	defer readiness.RUnlock()
+	if !readiness.checked {
+		return false, []string{"startup checks have not finished"}
+	}
+	return len(readiness.problems) == 0, readiness.problems
+}
+
+// readyResponse is the body of GET /readyz
+type readyResponse struct {
+	Ready    bool     `json:"ready"`
+	Problems []string `json:"problems,omitempty"`
+	// Breakers holds the state of each circuit breaker, e.g. "storage" or
+	// "notify_ntfy"
+	Breakers map[string]string `json:"breakers,omitempty"`
+}
+
+// breakerStates returns the state of the storage and notifier breakers
+func breakerStates() map[string]string {
+	states := make(map[string]string)
+	if StorageBreaker != nil {
+		states["storage"] = StorageBreaker.State().String()
+	}
+	if Notifier != nil {
+		for typ, state := range Notifier.Breakers() {
+			states["notify_"+typ] = state.String()
+		}
+	}
+	return states
+}
+
+// HealthzHandler handles GET /healthz. It only reports that the process is
+// up, so orchestrators don't restart a server that is merely not ready.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// VersionHandler handles GET /version, identifying the running build. Like
+// the probes it answers even when the server isn't ready.
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version.Current())
+}
+
+// PingTimeout bounds how long /readyz waits for the storage backend to
+// answer
+var PingTimeout = 2 * time.Second
+
+// ReadyzHandler handles GET /readyz, returning 503 with the problems found
+// at startup until the server can safely serve requests, while the storage
+// backend doesn't answer a ping, and while the storage circuit breaker is
+// open. Once its cooldown has passed the server reports ready again, so
+// traffic returns and the breaker can probe.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ok, problems := ready()
+	ctx, cancel := context.WithTimeout(r.Context(), PingTimeout)
+	defer cancel()
+	if err := storage.Ping(ctx, Store); err != nil {
+		ok, problems = false, append(slices.Clip(problems), fmt.Sprintf("storage is unreachable: %v", err))
+	}
+	if StorageBreaker != nil && StorageBreaker.State() == breaker.Open {
+		ok, problems = false, append(slices.Clip(problems), "storage circuit breaker is open")
+	}
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(readyResponse{Ready: ok, Problems: problems, Breakers: breakerStates()})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), status)
+}
+
+// RequireReady rejects every request other than the health checks and
+// /version with 503
+// until the server is ready, so nothing is read from or written to storage
+// that failed its startup checks
+func RequireReady(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" && r.URL.Path != "/readyz" && r.URL.Path != "/version" {
+			if ok, _ := ready(); !ok {
+				errorHandler(w, r, "server is not ready; see /readyz", http.StatusServiceUnavailable, nil)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"reminder-app/internal/hook"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// HookLimiter enforces the rate limits of inbound hooks
+var HookLimiter = hook.NewLimiter()
+
+// hookRequest is the body accepted by POST /hooks
+type hookRequest struct {
+	FamilyID  string        `json:"family_id"`
+	Name      string        `json:"name"`
+	Defaults  hook.Defaults `json:"defaults"`
+	RateLimit int           `json:"rate_limit,omitempty"`
+}
+
+// hookResponse is a newly created hook with its token and URL
+type hookResponse struct {
+	*hook.Hook
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// hookPayload is the body external systems post to /hooks/{token}
+type hookPayload struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// Due is an RFC 3339 time or a Go duration from now such as "2h"
+	Due    string `json:"due,omitempty"`
+	Member string `json:"member,omitempty"`
+}
+
+// validateHook returns an error message if the hook's settings are invalid
+func validateHook(s storage.Storage, req *hookRequest) (string, error) {
+	if req.FamilyID == "" || req.Name == "" {
+		return "family_id and name are required", nil
+	}
+	f, err := s.GetFamily(req.FamilyID)
+	if err != nil {
+		return fmt.Sprintf("family not found: %s", req.FamilyID), err
+	}
+	d := req.Defaults
+	if d.FamilyMember != "" && !hasMember(f, d.FamilyMember) {
+		return fmt.Sprintf("family member not found: %s", d.FamilyMember), nil
+	}
+	if msg := validateAssignment(d.Assignment); msg != "" {
+		return msg, nil
+	}
+	if d.Effort < 0 {
+		return "effort must not be negative", nil
+	}
+	if d.DueIn != "" {
+		if due, err := time.ParseDuration(d.DueIn); err != nil || due < 0 {
+			return "due_in must be a non-negative duration", err
+		}
+	}
+	if req.RateLimit < 0 {
+		return "rate_limit must not be negative", nil
+	}
+	return validateProjectID(s, req.FamilyID, d.ProjectID)
+}
+
+// CreateHookHandler handles POST /hooks, creating an inbound webhook for a
+// family. The token is only returned here.
+func CreateHookHandler(w http.ResponseWriter, r *http.Request) {
+	var req hookRequest
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if msg, err := validateHook(requestStore(r), &req); msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	h := &hook.Hook{FamilyID: req.FamilyID, Name: req.Name, Defaults: req.Defaults, RateLimit: req.RateLimit}
+	token, err := hook.Create(requestStore(r), h, time.Now())
+	if err != nil {
+		errorHandler(w, r, "failed to create hook", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hookResponse{Hook: h, Token: token, URL: externalBaseURL(r) + "/hooks/" + token})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
+
+// ListHooksHandler handles GET /hooks?family_id=. Tokens are not included.
+func ListHooksHandler(w http.ResponseWriter, r *http.Request) {
+	q := storage.RecordQuery{Kind: hook.Kind, FamilyID: r.URL.Query().Get("family_id")}
+	list, err := storage.ListJSON[hook.Hook](requestStore(r), q)
+	if err != nil {
+		errorHandler(w, r, "failed to list hooks", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DeleteHookHandler handles DELETE /hooks/{id}, revoking the hook
+func DeleteHookHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := requestStore(r).DeleteRecord(hook.Kind, id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		errorHandler(w, r, fmt.Sprintf("failed to delete hook: %s", id), status, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
+
+// hookFail logs and reports a failed delivery. The token is a credential,
+// so the path is left out of the log.
+func hookFail(w http.ResponseWriter, r *http.Request, msg string, status int, err error) {
+	log.Printf("%s /hooks/ %s %d - %s: %v", r.Method, r.UserAgent(), status, msg, err)
+	http.Error(w, msg, status)
+}
+
+// HookDeliveryHandler handles POST /hooks/{token}, creating a reminder from
+// an external system's payload. Fields the payload leaves out come from the
+// hook's defaults. It is unauthenticated: the token is the credential.
+func HookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	h, err := hook.Lookup(requestStore(r), mux.Vars(r)["token"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, hook.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		hookFail(w, r, "hook is invalid or revoked", status, err)
+		return
+	}
+	now := time.Now()
+	if ok, wait := HookLimiter.Allow(h.ID, h.Limit(), now); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		hookFail(w, r, "rate limit exceeded", http.StatusTooManyRequests, nil)
+		return
+	}
+
+	var p hookPayload
+	if err := decodeJSON(r, http.MaxBytesReader(w, r.Body, 64<<10), &p); err != nil {
+		hookFail(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if p.Title == "" {
+		hookFail(w, r, "title is required", http.StatusBadRequest, nil)
+		return
+	}
+	req := reminderRequest{
+		Title:        p.Title,
+		Description:  p.Description,
+		FamilyID:     h.FamilyID,
+		FamilyMember: h.Defaults.FamilyMember,
+		ProjectID:    h.Defaults.ProjectID,
+		Effort:       h.Defaults.Effort,
+		Assignment:   h.Defaults.Assignment,
+	}
+	if p.Member != "" {
+		req.FamilyMember, req.Assignment = p.Member, ""
+	}
+	due := p.Due
+	if due == "" {
+		due = h.Defaults.DueIn
+	}
+	if due != "" {
+		if d, err := time.ParseDuration(due); err == nil {
+			req.DueDate = now.Add(d).Format(time.RFC3339)
+		} else {
+			req.DueDate = due
+		}
+	}
+
+	dueDate, msg, err := req.validate(requestStore(r))
+	if msg != "" {
+		hookFail(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	re, msg, err := insertReminder(requestStore(r), &req, dueDate, "hook:"+h.Name, "")
+	if msg != "" {
+		hookFail(w, r, msg, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(re)
+	log.Printf("%s /hooks/ %s %d", r.Method, r.UserAgent(), http.StatusCreated)
+}
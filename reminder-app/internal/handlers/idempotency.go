@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+// IdempotencyHeader carries a client-chosen key that makes retries of a
+// creation return the original response instead of creating a duplicate
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed for a repeated key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// IdempotencyKind is the storage record kind of remembered responses
+const IdempotencyKind = "idempotency"
+
+// IdempotencyTTL is how long a key's response is remembered
+const IdempotencyTTL = 24 * time.Hour
+
+// idempotencyLease is how long a key is reserved for a request still being
+// handled, after which a retry may proceed in case the first one died
+const idempotencyLease = time.Minute
+
+// maxIdempotencyKey is the longest key accepted
+const maxIdempotencyKey = 255
+
+// Idempotent lists the endpoints honoring IdempotencyHeader, keyed by method
+// and route template like Policies
+var Idempotent = map[string]bool{
+	"POST /families":        true,
+	"POST /reminders":       true,
+	"POST /reminders/batch": true,
+}
+
+// idempotentResponse is a remembered response along with a hash of the
+// request body it answered, so a key reused for another request is caught.
+// A zero Status marks a key reserved by a request still being handled.
+type idempotentResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// responseCapture passes a response through while keeping a copy of it
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware remembers the successful responses of Idempotent
+// endpoints by the caller's Idempotency-Key for IdempotencyTTL and replays
+// them when the key is sent again. The key is reserved before the request is
+// handled, so a retry arriving while the first attempt is still running is
+// refused with a 409 rather than creating a duplicate, on any replica.
+// Reusing a key for a different request body is refused with a 422. Requests
+// without a key are unaffected. It must run after routing and authorization.
+func IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		route := routeKey(r)
+		if key == "" || !Idempotent[route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			errorHandler(w, r, "Idempotency-Key is too long", http.StatusBadRequest, nil)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			errorHandler(w, r, "failed to read request body", http.StatusBadRequest, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+		// Keys are scoped to the caller and endpoint
+		id := sha256.Sum256([]byte(requestActor(r) + "\n" + route + "\n" + key))
+		recID := hex.EncodeToString(id[:])
+
+		s := requestStore(r)
+		now := time.Now()
+		lease := now.Add(idempotencyLease)
+		rec := storage.Record{Kind: IdempotencyKind, ID: recID, CreatedAt: now, ExpiresAt: &lease}
+		err = storage.CreateJSON(s, rec, idempotentResponse{Fingerprint: fingerprint}, now)
+		if errors.Is(err, storage.ErrRecordExists) {
+			replayIdempotent(w, r, recID, fingerprint)
+			return
+		} else if err != nil {
+			errorHandler(w, r, "failed to reserve Idempotency-Key", http.StatusInternalServerError, err)
+			return
+		}
+
+		c := &responseCapture{ResponseWriter: w}
+		next.ServeHTTP(c, r)
+		// Failures aren't remembered, so a corrected retry can succeed
+		if c.status < 200 || c.status >= 300 {
+			if err := s.DeleteRecord(IdempotencyKind, recID); err != nil {
+				log.Printf("failed to release Idempotency-Key: %v", err)
+			}
+			return
+		}
+		expires := now.Add(IdempotencyTTL)
+		rec.ExpiresAt = &expires
+		resp := idempotentResponse{Fingerprint: fingerprint, Status: c.status, ContentType: w.Header().Get("Content-Type"), Body: c.body.Bytes()}
+		if err := storage.PutJSON(s, rec, resp); err != nil {
+			log.Printf("failed to remember Idempotency-Key response: %v", err)
+		}
+	})
+}
+
+// replayIdempotent answers a request whose Idempotency-Key is already
+// reserved with the response remembered for it, or 409 while the request
+// that reserved it is still being handled
+func replayIdempotent(w http.ResponseWriter, r *http.Request, recID, fingerprint string) {
+	prev, err := storage.GetJSON[idempotentResponse](requestStore(r), IdempotencyKind, recID)
+	if errors.Is(err, storage.ErrRecordNotFound) {
+		// Released by a failed first attempt in the meantime
+		errorHandler(w, r, "a request with this Idempotency-Key just failed; retry it", http.StatusConflict, nil)
+		return
+	} else if err != nil {
+		errorHandler(w, r, "failed to look up Idempotency-Key", http.StatusInternalServerError, err)
+		return
+	}
+	if prev.Fingerprint != fingerprint {
+		errorHandler(w, r, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity, nil)
+		return
+	}
+	if prev.Status == 0 {
+		errorHandler(w, r, "a request with this Idempotency-Key is still in progress", http.StatusConflict, nil)
+		return
+	}
+	if prev.ContentType != "" {
+		w.Header().Set("Content-Type", prev.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(prev.Status)
+	w.Write(prev.Body)
+	log.Printf("%s %s %s %d - replayed", r.Method, r.URL.Path, r.UserAgent(), prev.Status)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/reply"
+	"reminder-app/internal/storage"
+)
+
+var (
+	// InboundEmailAddress is the address replies to notification emails go
+	// to, with a reply token added to its local part. The inbound email
+	// integration must deliver mail for it to POST /inbound/email. Empty
+	// disables replies.
+	InboundEmailAddress string
+	// ReplyTokenTTL is how long replying to a notification keeps working
+	ReplyTokenTTL = 7 * 24 * time.Hour
+)
+
+// maxInboundEmail bounds the size of an inbound email
+const maxInboundEmail = 1 << 20
+
+// NotificationReplyTo issues a reply token for a due-reminder notification
+// and returns its reply address, or "" when InboundEmailAddress is unset
+func NotificationReplyTo(r *reminder.Reminder) string {
+	if InboundEmailAddress == "" {
+		return ""
+	}
+	token, err := reply.Issue(Store, r.ID, r.FamilyID, r.FamilyMember, time.Now(), ReplyTokenTTL)
+	if err != nil {
+		log.Printf("failed to issue reply token for %s: %v", r.ID, err)
+		return ""
+	}
+	return reply.Address(InboundEmailAddress, token)
+}
+
+// inboundEmail is an email posted to POST /inbound/email as JSON
+type inboundEmail struct {
+	From string `json:"from"`
+	// To is the recipient list, which includes the reply address
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+// readInboundEmail reads the email posted to POST /inbound/email, either as
+// JSON or as a raw message with the message/rfc822 content type
+func readInboundEmail(r *http.Request) (*inboundEmail, error) {
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "message/rfc822" {
+		var e inboundEmail
+		if err := decodeJSON(r, r.Body, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	}
+	msg, err := mail.ReadMessage(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	text, err := plainText(msg.Header.Get, msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &inboundEmail{From: msg.Header.Get("From"), To: msg.Header.Get("To"), Text: text}, nil
+}
+
+// plainText returns the text/plain content of a message part, looking into
+// multipart bodies, or "" if there is none
+func plainText(header func(string) string, body io.Reader) (string, error) {
+	ct, params, err := mime.ParseMediaType(header("Content-Type"))
+	if err != nil {
+		ct = "text/plain"
+	}
+	if strings.HasPrefix(ct, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return "", nil
+			} else if err != nil {
+				return "", err
+			}
+			text, err := plainText(p.Header.Get, p)
+			if err != nil || text != "" {
+				return text, err
+			}
+		}
+	}
+	if ct != "text/plain" {
+		return "", nil
+	}
+	switch strings.ToLower(header("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, err := io.ReadAll(body)
+	return string(data), err
+}
+
+// inboundResult is the response of POST /inbound/email
+type inboundResult struct {
+	Action     string `json:"action"`
+	ReminderID string `json:"reminder_id"`
+	Message    string `json:"message"`
+}
+
+// InboundEmailHandler handles POST /inbound/email, where the inbound email
+// integration delivers replies to notification emails. A reply of "done"
+// completes the reminder and "snooze 1h" snoozes it, on behalf of the
+// member the notification went to. It is unauthenticated: the reply token
+// in the recipient address is the credential.
+func InboundEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if InboundEmailAddress == "" {
+		errorHandler(w, r, "email replies are not configured on this server", http.StatusNotImplemented, nil)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxInboundEmail)
+	e, err := readInboundEmail(r)
+	if err != nil {
+		errorHandler(w, r, "invalid email", http.StatusBadRequest, err)
+		return
+	}
+	t, err := reply.Lookup(requestStore(r), reply.TokenFrom(InboundEmailAddress, e.To), time.Now())
+	if errors.Is(err, reply.ErrNotFound) {
+		errorHandler(w, r, "not a reply to a current notification", http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		errorHandler(w, r, "failed to look up reply token", http.StatusInternalServerError, err)
+		return
+	}
+	cmd, err := reply.Parse(e.Text)
+	if err != nil {
+		errorHandler(w, r, err.Error(), http.StatusUnprocessableEntity, nil)
+		return
+	}
+
+	now := time.Now()
+	var (
+		result     inboundResult
+		before     json.RawMessage
+		completion *reminder.CompletionEvent
+	)
+	rem := editReminder(w, r, t.ReminderID, func(s storage.Storage, rem *reminder.Reminder) bool {
+		result = inboundResult{Action: cmd.Action, ReminderID: rem.ID}
+		before = audit.Snapshot(rem)
+		switch cmd.Action {
+		case "done":
+			existing, err := existingCompletion(s, rem, now)
+			if err != nil {
+				errorHandler(w, r, "failed to check completion events", http.StatusInternalServerError, err)
+				return false
+			}
+			if rem.Completed || existing != nil {
+				result.Message = fmt.Sprintf("%q was already marked done", rem.Title)
+				writeInboundResult(w, r, result)
+				return false
+			}
+			if completion, err = completeReminder(s, rem, t.Member, "", now); errors.Is(err, errSameConfirmer) {
+				errorHandler(w, r, err.Error(), http.StatusConflict, nil)
+				return false
+			} else if err != nil {
+				errorHandler(w, r, "failed to create completion event", http.StatusInternalServerError, err)
+				return false
+			}
+			result.Message = fmt.Sprintf("%q has been marked done", rem.Title)
+			if completion.Awaiting() {
+				result.Message = fmt.Sprintf("%q now needs a second person to confirm it", rem.Title)
+			}
+		case "snooze":
+			if msg := snoozeReminder(rem, cmd.Snooze, now); msg != "" {
+				errorHandler(w, r, msg, http.StatusConflict, nil)
+				return false
+			}
+			result.Message = fmt.Sprintf("%q is snoozed until %s", rem.Title, rem.SnoozedUntil.Format(time.RFC3339))
+		}
+		return true
+	})
+	if rem == nil {
+		return
+	}
+	Events.Publish(reminderSaved(rem, before, completion, t.Member, ""))
+	writeInboundResult(w, r, result)
+}
+
+// writeInboundResult writes the response of POST /inbound/email
+func writeInboundResult(w http.ResponseWriter, r *http.Request, result inboundResult) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+	log.Printf("%s %s %s %d - %s %s via email reply", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK, result.Action, result.ReminderID)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/stats"
+
+	"github.com/gorilla/mux"
+)
+
+// FamilyLeaderboardHandler handles GET /families/{id}/leaderboard?window=,
+// ranking the family's members by the points they earned completing
+// reminders. The window is week (the default) or month, in the family's time
+// zone. A past week is picked with week=, an ISO week ("2025-W10") or any
+// date within it, and a past month with month=YYYY-MM.
+func FamilyLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	window := q.Get("window")
+	if window == "" {
+		window = stats.WindowWeek
+	}
+	if window != stats.WindowWeek && window != stats.WindowMonth {
+		errorHandler(w, r, "window must be week or month", http.StatusBadRequest, nil)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	loc, err := f.LocationFor("")
+	if err != nil {
+		errorHandler(w, r, "invalid family time zone", http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now().In(loc)
+	var from, to time.Time
+	if window == stats.WindowWeek {
+		from = agenda.WeekStart(now)
+		if week := q.Get("week"); week != "" {
+			if from, err = agenda.ParseWeek(week, loc); err != nil {
+				errorHandler(w, r, err.Error(), http.StatusBadRequest, err)
+				return
+			}
+		}
+		to = from.AddDate(0, 0, 7)
+	} else {
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		if month := q.Get("month"); month != "" {
+			if from, err = time.ParseInLocation("2006-01", month, loc); err != nil {
+				errorHandler(w, r, "month must be YYYY-MM", http.StatusBadRequest, err)
+				return
+			}
+		}
+		to = from.AddDate(0, 1, 0)
+	}
+
+	board, err := stats.Rank(requestStore(r), f, window, from, to)
+	if err != nil {
+		errorHandler(w, r, "failed to compute leaderboard", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(board)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
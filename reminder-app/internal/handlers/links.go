@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"reminder-app/internal/audit"
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/links"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+var (
+	// LinkSigner signs one-tap completion links. Nil disables the feature.
+	LinkSigner *links.Signer
+	// BaseURL is the externally visible URL of the server, used to build
+	// absolute links. When empty, links are relative.
+	BaseURL string
+	// CompletionLinkTTL is how long a completion link stays valid by default
+	CompletionLinkTTL = 7 * 24 * time.Hour
+	// QRLinkTTL is the default lifetime of links printed as QR codes, which
+	// tend to stay stuck to a wall for a long time
+	QRLinkTTL = 365 * 24 * time.Hour
+)
+
+// externalBaseURL returns BaseURL, falling back to the scheme and host the
+// request was made to
+func externalBaseURL(r *http.Request) string {
+	if BaseURL != "" {
+		return strings.TrimSuffix(BaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// completionLink returns a signed URL that completes r on behalf of member
+func completionLink(base string, r *reminder.Reminder, member string, ttl time.Duration) (string, time.Time) {
+	expires := time.Now().Add(ttl)
+	token := LinkSigner.Sign(links.Claims{ReminderID: r.ID, Member: member, ExpiresAt: expires})
+	return base + "/c/" + token, expires
+}
+
+// linkParams reads the member and ttl query parameters shared by the link
+// endpoints
+func linkParams(r *http.Request, rem *reminder.Reminder, defaultTTL time.Duration) (string, time.Duration, error) {
+	member := r.URL.Query().Get("member")
+	if member == "" {
+		member = rem.FamilyMember
+	}
+	ttl := defaultTTL
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return "", 0, err
+		}
+		if d <= 0 {
+			return "", 0, fmt.Errorf("ttl must be positive")
+		}
+		ttl = d
+	}
+	return member, ttl, nil
+}
+
+// checkLinkMember checks that the member a link is issued to belongs to the
+// reminder's family and that the caller may complete the reminder in their
+// name, writing the error response if not. Anyone who may complete it can
+// issue links for themselves or its assignee, as completing it does;
+// naming anyone else takes being able to act as them.
+func checkLinkMember(w http.ResponseWriter, r *http.Request, rem *reminder.Reminder, member string) bool {
+	f, err := requestStore(r).GetFamily(rem.FamilyID)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", rem.FamilyID), http.StatusInternalServerError, err)
+		return false
+	}
+	if !hasMember(f, member) {
+		errorHandler(w, r, fmt.Sprintf("family member not found: %q", member), http.StatusBadRequest, nil)
+		return false
+	}
+	if actor := requestActor(r); member != actor && member != rem.FamilyMember && !permitted(f, actor, fam.PermManage) {
+		errorHandler(w, r, fmt.Sprintf("%s cannot issue links for %s", actor, member), http.StatusForbidden, nil)
+		return false
+	}
+	return true
+}
+
+// CompletionLinkHandler handles GET /reminders/{id}/complete-link and returns
+// a signed link that marks the reminder done without logging in. The optional
+// member query parameter records who completes it (default: the assignee) and
+// ttl overrides the link lifetime.
+func CompletionLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if LinkSigner == nil {
+		errorHandler(w, r, "completion links are not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	rem, err := requestStore(r).GetReminder(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("reminder not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	member, ttl, err := linkParams(r, rem, CompletionLinkTTL)
+	if err != nil {
+		errorHandler(w, r, "invalid ttl", http.StatusBadRequest, err)
+		return
+	}
+	if !checkLinkMember(w, r, rem, member) {
+		return
+	}
+	url, expires := completionLink(externalBaseURL(r), rem, member, ttl)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":        url,
+		"expires_at": expires.UTC().Format(time.RFC3339),
+	})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// ReminderQRHandler handles GET /reminders/{id}/qr.png. The PNG encodes a
+// signed completion link so a printed sticker lets anyone scan-and-complete.
+// Accepts the same member and ttl parameters as the complete-link endpoint
+// plus size (pixels, default 256).
+func ReminderQRHandler(w http.ResponseWriter, r *http.Request) {
+	if LinkSigner == nil {
+		errorHandler(w, r, "completion links are not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	rem, err := requestStore(r).GetReminder(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("reminder not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	member, ttl, err := linkParams(r, rem, QRLinkTTL)
+	if err != nil {
+		errorHandler(w, r, "invalid ttl", http.StatusBadRequest, err)
+		return
+	}
+	if !checkLinkMember(w, r, rem, member) {
+		return
+	}
+	size := 256
+	if v := r.URL.Query().Get("size"); v != "" {
+		size, err = strconv.Atoi(v)
+		if err != nil || size < 64 || size > 2048 {
+			errorHandler(w, r, "size must be between 64 and 2048", http.StatusBadRequest, err)
+			return
+		}
+	}
+	url, _ := completionLink(externalBaseURL(r), rem, member, ttl)
+	png, err := qrcode.Encode(url, qrcode.Medium, size)
+	if err != nil {
+		errorHandler(w, r, "failed to encode QR code", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+var completedPage = template.Must(template.New("completed").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Heading}}</title></head>
+<body style="font-family: sans-serif; text-align: center; padding-top: 3em">
+<h1>{{.Heading}}</h1>
+<p>{{.Message}}</p>
+{{- if .Button}}
+<form method="post"><button type="submit" style="font-size: 1.2em; padding: 0.5em 1.5em">{{.Button}}</button></form>
+{{- end}}
+</body>
+</html>
+`))
+
+// renderLinkPage writes a minimal human-readable page for link endpoints
+func renderLinkPage(w http.ResponseWriter, status int, heading, message string) {
+	renderLinkForm(w, status, heading, message, "")
+}
+
+// renderLinkForm writes a link page with a button that posts back to the
+// link, or none if button is empty
+func renderLinkForm(w http.ResponseWriter, status int, heading, message, button string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	completedPage.Execute(w, map[string]string{"Heading": heading, "Message": message, "Button": button})
+}
+
+// verifyCompletionLink checks the token of a completion link and that the
+// member it completes for still belongs to the reminder's family, rendering
+// a page explaining why not otherwise. It returns the token's claims and the
+// reminder, or false if a page has been written.
+func verifyCompletionLink(w http.ResponseWriter, r *http.Request) (*links.Claims, *reminder.Reminder, bool) {
+	if LinkSigner == nil {
+		renderLinkPage(w, http.StatusNotImplemented, "Unavailable", "Completion links are not configured on this server.")
+		return nil, nil, false
+	}
+	claims, err := LinkSigner.Verify(mux.Vars(r)["token"], time.Now())
+	if err != nil {
+		log.Printf("%s %s %s %d - invalid completion link: %v", r.Method, r.URL.Path, r.UserAgent(), http.StatusForbidden, err)
+		renderLinkPage(w, http.StatusForbidden, "Link not valid", "This link is invalid or has expired.")
+		return nil, nil, false
+	}
+	rem, err := requestStore(r).GetReminder(claims.ReminderID)
+	if err != nil {
+		log.Printf("%s /c/ %s %d - reminder %s: %v", r.Method, r.UserAgent(), http.StatusNotFound, claims.ReminderID, err)
+		renderLinkPage(w, http.StatusNotFound, "Not found", "This reminder no longer exists.")
+		return nil, nil, false
+	}
+	f, err := requestStore(r).GetFamily(rem.FamilyID)
+	if err != nil || !hasMember(f, claims.Member) {
+		log.Printf("%s /c/ %s %d - %q is not a member of family %s", r.Method, r.UserAgent(), http.StatusForbidden, claims.Member, rem.FamilyID)
+		renderLinkPage(w, http.StatusForbidden, "Link not valid", "This link was issued to someone who is no longer in the family.")
+		return nil, nil, false
+	}
+	return claims, rem, true
+}
+
+// ConfirmViaLinkHandler handles GET /c/{token}, the page a completion link
+// opens. It only asks to confirm: link previews and mail scanners fetch
+// links on their own, so the reminder is completed by the page's POST to
+// CompleteViaLinkHandler instead.
+func ConfirmViaLinkHandler(w http.ResponseWriter, r *http.Request) {
+	claims, rem, ok := verifyCompletionLink(w, r)
+	if !ok {
+		return
+	}
+	existing, err := existingCompletion(requestStore(r), rem, time.Now())
+	if err != nil {
+		log.Printf("%s /c/ %s %d - failed to check completion events: %v", r.Method, r.UserAgent(), http.StatusInternalServerError, err)
+		renderLinkPage(w, http.StatusInternalServerError, "Something went wrong", "The reminder could not be loaded. Please try again.")
+		return
+	}
+	if rem.Completed || existing != nil {
+		renderLinkPage(w, http.StatusOK, "Already done", fmt.Sprintf("%q was already marked done.", rem.Title))
+		return
+	}
+	renderLinkForm(w, http.StatusOK, rem.Title, fmt.Sprintf("Mark %q done as %s?", rem.Title, claims.Member), "Mark done")
+	log.Printf("%s /c/ %s %d", r.Method, r.UserAgent(), http.StatusOK)
+}
+
+// CompleteViaLinkHandler handles POST /c/{token}. It is unauthenticated: the
+// signed token is the credential. The response is an HTML page because the
+// link is meant to be tapped from an email or chat notification.
+func CompleteViaLinkHandler(w http.ResponseWriter, r *http.Request) {
+	claims, _, ok := verifyCompletionLink(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	var (
+		before     json.RawMessage
+		completion *reminder.CompletionEvent
+	)
+	rem, err := updateReminder(requestStore(r), claims.ReminderID, func(s storage.Storage, rem *reminder.Reminder) error {
+		existing, err := existingCompletion(s, rem, now)
+		if err != nil {
+			return fmt.Errorf("failed to check completion events: %w", err)
+		}
+		if rem.Completed || existing != nil {
+			renderLinkPage(w, http.StatusOK, "Already done", fmt.Sprintf("%q was already marked done.", rem.Title))
+			return errEditAbandoned
+		}
+		before = audit.Snapshot(rem)
+		completion, err = completeReminder(s, rem, claims.Member, "", now)
+		if errors.Is(err, errSameConfirmer) {
+			renderLinkPage(w, http.StatusConflict, "Needs a second person", fmt.Sprintf("You already signed off %q; someone else has to confirm it.", rem.Title))
+			return errEditAbandoned
+		} else if err != nil {
+			return fmt.Errorf("failed to create completion event: %w", err)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errEditAbandoned):
+		return
+	case errors.Is(err, errReminderNotFound):
+		log.Printf("%s %s %s %d - reminder %s: %v", r.Method, r.URL.Path, r.UserAgent(), http.StatusNotFound, claims.ReminderID, err)
+		renderLinkPage(w, http.StatusNotFound, "Not found", "This reminder no longer exists.")
+		return
+	case errors.Is(err, storage.ErrVersionConflict):
+		log.Printf("%s /c/ %s %d - reminder %s: %v", r.Method, r.UserAgent(), http.StatusConflict, claims.ReminderID, err)
+		renderLinkPage(w, http.StatusConflict, "Please try again", "Someone changed this reminder at the same time.")
+		return
+	case err != nil:
+		log.Printf("%s %s %s %d - failed to complete reminder: %v", r.Method, r.URL.Path, r.UserAgent(), http.StatusInternalServerError, err)
+		renderLinkPage(w, http.StatusInternalServerError, "Something went wrong", "The reminder could not be completed. Please try again.")
+		return
+	}
+	Events.Publish(reminderSaved(rem, before, completion, claims.Member, ""))
+	if completion.Awaiting() {
+		renderLinkPage(w, http.StatusOK, "Awaiting confirmation", fmt.Sprintf("%q now needs a second person to confirm it.", rem.Title))
+		return
+	}
+	renderLinkPage(w, http.StatusOK, "Done!", fmt.Sprintf("%q has been marked done.", rem.Title))
+	// The token is a credential, so it is deliberately left out of the log
+	log.Printf("%s /c/ %s %d - completed %s via link", r.Method, r.UserAgent(), http.StatusOK, rem.ID)
+}
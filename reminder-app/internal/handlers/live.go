@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/events"
+	"reminder-app/internal/live"
+	"reminder-app/internal/reminder"
+)
+
+// Live delivers reminder changes to clients connected to /ws
+var Live = live.NewHub()
+
+// livePingInterval is how often idle connections are pinged. A client that
+// doesn't answer within two intervals is disconnected.
+const livePingInterval = 30 * time.Second
+
+// liveEvent pushes changes to live clients
+func liveEvent(e events.Event) {
+	le := live.Event{Type: e.Type(), FamilyID: e.FamilyID(), At: time.Now().UTC()}
+	// Reminders are copied since events are encoded after the handler has
+	// moved on
+	setReminder := func(rem *reminder.Reminder) {
+		c := *rem
+		le.ReminderID, le.Reminder = rem.ID, &c
+	}
+	switch e := e.(type) {
+	case events.FamilyUpdated:
+		le.Actor = e.Actor
+	case events.FamilyDeleted:
+		le.Actor = e.Actor
+	case events.ReminderCreated:
+		setReminder(e.Reminder)
+		le.Actor = e.Actor
+	case events.ReminderUpdated:
+		setReminder(e.Reminder)
+		le.Actor = e.Actor
+	case events.ReminderCompleted:
+		setReminder(e.Reminder)
+		le.CompletionEvent, le.Actor = e.Completion, e.Actor
+	case events.ReminderDeleted:
+		le.ReminderID, le.Actor = e.Reminder.ID, e.Actor
+	case events.CompletionRecorded:
+		setReminder(e.Reminder)
+		le.CompletionEvent, le.Actor = e.Completion, e.Completion.CompletedBy
+	case events.CompletionDeleted:
+		le.ReminderID, le.CompletionEvent, le.Actor = e.Reminder.ID, e.Completion, e.Actor
+	default:
+		return
+	}
+	Live.Publish(le)
+}
+
+// LiveHandler handles GET /ws?family=, upgrading to a WebSocket that
+// streams a JSON live.Event for every change to the family and its
+// reminders and completions. Clients that fall behind are disconnected
+// with close code 1013 and should reload their reminders.
+func LiveHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("family")
+	if id == "" {
+		errorHandler(w, r, "family is required", http.StatusBadRequest, nil)
+		return
+	}
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	sub := Live.Subscribe(id)
+	defer sub.Cancel()
+	conn, err := live.Upgrade(w, r)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, live.ErrCrossOrigin) {
+			status = http.StatusForbidden
+		}
+		errorHandler(w, r, err.Error(), status, err)
+		return
+	}
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusSwitchingProtocols)
+
+	closed := make(chan error, 1)
+	go func() { closed <- conn.ReadControl(2 * livePingInterval) }()
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				conn.Close(live.CloseTryAgainLater, "too many pending events")
+				return
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				conn.Close(live.CloseGoingAway, "")
+				return
+			}
+		case <-ping.C:
+			if err := conn.Ping(); err != nil {
+				conn.Close(live.CloseGoingAway, "")
+				return
+			}
+		case <-closed:
+			conn.Close(live.CloseNormal, "")
+			return
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"reminder-app/internal/i18n"
+)
+
+// Locales are the languages the frontend is served in
+var Locales = i18n.Locales{Default: "en", Supported: []string{"en"}}
+
+// localesResponse is the body of GET /locales
+type localesResponse struct {
+	i18n.Locales
+	// Preferred is the language the caller's pages are served in
+	Preferred string `json:"preferred"`
+}
+
+// LocalesHandler handles GET /locales, listing the languages the frontend
+// is available in and which of them the caller gets, so pages can offer a
+// language switcher. Choosing a language sets the i18n.Cookie cookie.
+func LocalesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Vary", "Accept-Language, Cookie")
+	json.NewEncoder(w).Encode(localesResponse{Locales: Locales, Preferred: Locales.Preferred(r)})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
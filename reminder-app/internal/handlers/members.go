@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/events"
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/preferences"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// familyPatch is the body accepted by PATCH /families/{id}. Omitted fields
+// are left alone. The reminders of removed members must be handed over
+// explicitly, either to ReassignTo or, with Orphan, to nobody.
+type familyPatch struct {
+	Name          *string  `json:"name"`
+	AddMembers    []string `json:"add_members"`
+	RemoveMembers []string `json:"remove_members"`
+	ReassignTo    string   `json:"reassign_to"`
+	Orphan        bool     `json:"orphan"`
+}
+
+// memberChange is a validated change to a family's members along with the
+// reminders it moves off removed members. Removed members also drop out of
+// rotations, which only need handing over once nobody is left in them.
+type memberChange struct {
+	family    *fam.Family
+	reminders []*reminder.Reminder
+	removed   []string
+	// reassignTo receives the reminders, or "" to leave them unassigned
+	reassignTo string
+}
+
+// changeMembers validates adding and removing members of f and applies it to
+// a copy of f. It returns an error message and status for the response when
+// the change is refused, such as removing a member who still has reminders
+// without saying where they go.
+func changeMembers(s storage.Storage, f *fam.Family, add, remove []string, reassignTo string, orphan bool) (*memberChange, string, int) {
+	if reassignTo != "" && orphan {
+		return nil, "reassign_to and orphan are mutually exclusive", http.StatusBadRequest
+	}
+	c := &memberChange{family: &fam.Family{}, removed: remove, reassignTo: reassignTo}
+	*c.family = *f
+	c.family.Members = slices.Clone(f.Members)
+	for _, m := range add {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			return nil, "member name must not be empty", http.StatusBadRequest
+		}
+		if hasMember(c.family, m) {
+			return nil, fmt.Sprintf("member already in family: %s", m), http.StatusConflict
+		}
+		c.family.AddMember(m)
+	}
+	if len(remove) == 0 {
+		return c, "", 0
+	}
+	for _, m := range remove {
+		if !hasMember(c.family, m) {
+			return nil, fmt.Sprintf("member not in family: %s", m), http.StatusNotFound
+		}
+		c.family.RemoveMember(m)
+	}
+	if reassignTo != "" && !hasMember(c.family, reassignTo) {
+		return nil, fmt.Sprintf("reassign_to must be a remaining member: %s", reassignTo), http.StatusBadRequest
+	}
+
+	// Read from the primary so a reminder just assigned to a removed
+	// member isn't missed
+	list, err := storage.ReadPrimary(s).QueryReminders(storage.ReminderFilter{FamilyID: f.ID})
+	if err != nil {
+		return nil, "failed to list reminders", http.StatusInternalServerError
+	}
+	handover := 0
+	for _, rem := range list {
+		if rem.Rotation != nil && slices.ContainsFunc(rem.Rotation.Members, c.isRemoved) {
+			c.reminders = append(c.reminders, rem)
+			if !slices.ContainsFunc(rem.Rotation.Members, func(m string) bool { return !c.isRemoved(m) }) {
+				handover++
+			}
+		} else if slices.Contains(remove, rem.FamilyMember) {
+			c.reminders = append(c.reminders, rem)
+			handover++
+		}
+	}
+	if handover > 0 && reassignTo == "" && !orphan {
+		return nil, fmt.Sprintf("%d reminders are assigned to %s; set reassign_to or orphan", handover, strings.Join(remove, ", ")), http.StatusConflict
+	}
+
+	if len(f.Settings.Members) > 0 {
+		c.family.Settings.Members = make(map[string]fam.MemberSettings, len(f.Settings.Members))
+		for m, ms := range f.Settings.Members {
+			if !slices.Contains(remove, m) {
+				c.family.Settings.Members[m] = ms
+			}
+		}
+		// Removing the last member with a role would open the family up
+		msg := validateRoles(c.family.Settings)
+		if msg == "" && f.HasRoles() && !c.family.HasRoles() {
+			msg = "at least one member must be an owner"
+		}
+		if msg != "" {
+			return nil, msg, http.StatusConflict
+		}
+	}
+	return c, "", 0
+}
+
+// isRemoved reports whether the change removes member
+func (c *memberChange) isRemoved(member string) bool {
+	return slices.Contains(c.removed, member)
+}
+
+// save stores the changed family and then hands the removed members'
+// reminders over, publishing an event for each
+func (c *memberChange) save(r *http.Request) error {
+	if err := requestStore(r).UpdateFamily(c.family); err != nil {
+		return err
+	}
+	Events.Publish(events.FamilyUpdated{Family: c.family, Actor: requestActor(r)})
+	for _, rem := range c.reminders {
+		var before json.RawMessage
+		saved, err := updateReminder(requestStore(r), rem.ID, func(s storage.Storage, rem *reminder.Reminder) error {
+			// The plan was made before the lock; skip a reminder that has
+			// since been handed to someone staying
+			if rem.Rotation == nil && !c.isRemoved(rem.FamilyMember) ||
+				rem.Rotation != nil && !slices.ContainsFunc(rem.Rotation.Members, c.isRemoved) {
+				return errEditAbandoned
+			}
+			before = audit.Snapshot(rem)
+			rem.FamilyMember = c.reassignTo
+			if rem.Rotation != nil {
+				members := slices.DeleteFunc(slices.Clone(rem.Rotation.Members), c.isRemoved)
+				if len(members) == 0 {
+					rem.Rotation = nil
+				} else {
+					rem.Rotation = &reminder.Rotation{Members: members}
+					rem.FamilyMember = rem.AssigneeAt(currentOccurrence(s, rem))
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errReminderNotFound) || errors.Is(err, errEditAbandoned) {
+			continue // Deleted or reassigned since it was listed
+		} else if err != nil {
+			return err
+		}
+		Events.Publish(reminderSaved(saved, before, nil, requestActor(r), requestImpersonator(r)))
+	}
+	return nil
+}
+
+// PatchFamilyHandler handles PATCH /families/{id}, renaming the family and
+// adding or removing members
+func PatchFamilyHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	var req familyPatch
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		errorHandler(w, r, "name must not be empty", http.StatusBadRequest, nil)
+		return
+	}
+	c, msg, status := changeMembers(requestStore(r), f, req.AddMembers, req.RemoveMembers, req.ReassignTo, req.Orphan)
+	if msg != "" {
+		if status == http.StatusNotFound {
+			status = http.StatusBadRequest
+		}
+		errorHandler(w, r, msg, status, nil)
+		return
+	}
+	if req.Name != nil {
+		c.family.Name = strings.TrimSpace(*req.Name)
+	}
+	if err := c.save(r); err != nil {
+		errorHandler(w, r, "failed to update family", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.family)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// AddMemberHandler handles POST /families/{id}/members with a body like
+// {"name": "Alice"}
+func AddMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	c, msg, status := changeMembers(requestStore(r), f, []string{req.Name}, nil, "", false)
+	if msg != "" {
+		errorHandler(w, r, msg, status, nil)
+		return
+	}
+	if err := c.save(r); err != nil {
+		errorHandler(w, r, "failed to update family", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c.family)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
+
+// RemoveMemberHandler handles DELETE /families/{id}/members/{name}. A member
+// with reminders can only be removed with ?reassign_to=<member>, handing
+// them over, or ?orphan=true, leaving them unassigned.
+func RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	q := r.URL.Query()
+	orphan := false
+	if v := q.Get("orphan"); v != "" {
+		if orphan, err = strconv.ParseBool(v); err != nil {
+			errorHandler(w, r, "orphan must be true or false", http.StatusBadRequest, err)
+			return
+		}
+	}
+	c, msg, status := changeMembers(requestStore(r), f, nil, []string{vars["name"]}, q.Get("reassign_to"), orphan)
+	if msg != "" {
+		errorHandler(w, r, msg, status, nil)
+		return
+	}
+	if err := c.save(r); err != nil {
+		errorHandler(w, r, "failed to update family", http.StatusInternalServerError, err)
+		return
+	}
+	if err := storage.DeleteBlob(requestStore(r), avatarID(id, vars["name"])); err != nil && !errors.Is(err, storage.ErrRecordNotFound) {
+		log.Printf("failed to delete avatar of removed member %s: %v", vars["name"], err)
+	}
+	if err := preferences.Delete(requestStore(r), id, vars["name"]); err != nil {
+		log.Printf("failed to delete preferences of removed member %s: %v", vars["name"], err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
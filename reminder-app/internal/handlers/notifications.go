@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"reminder-app/internal/delivery"
+	"reminder-app/internal/events"
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/notify"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/stats"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/templates"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	// Notifier delivers notifications to configured channels. Nil disables
+	// notifications entirely.
+	Notifier *notify.Dispatcher
+)
+
+// validateChannels checks that every channel has a well-formed target and a
+// notifier that can deliver to it
+func validateChannels(channels []fam.Channel) string {
+	for _, ch := range channels {
+		if ch.Type == "" || ch.Target == "" {
+			return "notification channels require a type and a target"
+		}
+		if Notifier == nil || !Notifier.Supports(ch.Type) {
+			return fmt.Sprintf("notification channel type not enabled on this server: %s", ch.Type)
+		}
+		if err := Notifier.Validate(ch); err != nil {
+			return fmt.Sprintf("invalid %s notification target: %v", ch.Type, err)
+		}
+	}
+	return ""
+}
+
+// validateMemberSettings checks a member's channels, failover, time zone
+// and agenda
+func validateMemberSettings(ms fam.MemberSettings) string {
+	if msg := validateChannels(ms.Channels); msg != "" {
+		return msg
+	}
+	if ms.Failover != nil && ms.Failover.AckMinutes < 0 {
+		return "failover ack_minutes must not be negative"
+	}
+	if msg := validateTimezone(ms.Timezone); msg != "" {
+		return msg
+	}
+	if msg := validateSchedule(ms.Agenda); msg != "" {
+		return msg
+	}
+	return validateDigest(ms.Digest)
+}
+
+// validateDigest checks the schedule, frequency and weekday of an optional
+// digest
+func validateDigest(d *fam.DigestSettings) string {
+	if d == nil {
+		return ""
+	}
+	if msg := validateSchedule(&d.ScheduleSettings); msg != "" {
+		return msg
+	}
+	if p := d.Period(); p != fam.DigestDaily && p != fam.DigestWeekly {
+		return "digest frequency must be daily or weekly"
+	}
+	if _, err := d.Day(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func validateTimezone(name string) string {
+	if name == "" {
+		return ""
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Sprintf("unknown time zone: %s", name)
+	}
+	return ""
+}
+
+// validateSchedule checks the time and channel of an optional daily
+// notification
+func validateSchedule(s *fam.ScheduleSettings) string {
+	if s == nil {
+		return ""
+	}
+	if _, _, err := s.Clock(); err != nil {
+		return err.Error()
+	}
+	if s.Channel != nil {
+		return validateChannels([]fam.Channel{*s.Channel})
+	}
+	return ""
+}
+
+// validateRetention checks the completion retention of a family, which must
+// be long enough for the nightly stats to cover the events it purges
+func validateRetention(days int) string {
+	if days != 0 && days < stats.MinRetentionDays {
+		return fmt.Sprintf("completion_retention_days must be 0 or at least %d", stats.MinRetentionDays)
+	}
+	return ""
+}
+
+// GetFamilySettingsHandler handles GET /families/{id}/settings
+func GetFamilySettingsHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f.Settings)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// UpdateFamilySettingsHandler handles PUT /families/{id}/settings, replacing
+// the family's settings wholesale
+func UpdateFamilySettingsHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	var settings fam.Settings
+	if err := decodeJSON(r, r.Body, &settings); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	for _, msg := range []string{
+		validateChannels(settings.Channels),
+		validateTimezone(settings.Timezone),
+		validateSchedule(settings.Nag),
+		validateSchedule(settings.MonthlyReport),
+		validateRetention(settings.CompletionRetentionDays),
+		validateRoles(settings),
+	} {
+		if msg != "" {
+			errorHandler(w, r, msg, http.StatusBadRequest, nil)
+			return
+		}
+	}
+	for member, ms := range settings.Members {
+		if !hasMember(f, member) {
+			errorHandler(w, r, fmt.Sprintf("member not in family: %s", member), http.StatusBadRequest, nil)
+			return
+		}
+		if msg := validateMemberSettings(ms); msg != "" {
+			errorHandler(w, r, msg, http.StatusBadRequest, nil)
+			return
+		}
+	}
+	if err := templates.Validate(settings.Templates); err != nil {
+		errorHandler(w, r, fmt.Sprintf("invalid notification template: %v", err), http.StatusBadRequest, err)
+		return
+	}
+	if settings.Workflow != nil {
+		if err := settings.Workflow.Validate(); err != nil {
+			errorHandler(w, r, fmt.Sprintf("invalid workflow: %v", err), http.StatusBadRequest, err)
+			return
+		}
+	}
+	f.Settings = settings
+	if err := requestStore(r).UpdateFamily(f); err != nil {
+		errorHandler(w, r, "failed to update family settings", http.StatusInternalServerError, err)
+		return
+	}
+	Events.Publish(events.FamilyUpdated{Family: f, Actor: requestActor(r)})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f.Settings)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// channelResult reports the outcome of delivering to one channel
+type channelResult struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TestNotificationHandler handles POST /families/{id}/notifications/test. It
+// sends a test message, rendered with the family's reminder template, to
+// every channel of the family or of one member when ?member= is given, and
+// reports the per-channel results so configuration mistakes surface
+// immediately.
+func TestNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	if Notifier == nil {
+		errorHandler(w, r, "notifications are not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	channels := f.Settings.Channels
+	member := r.URL.Query().Get("member")
+	if member != "" {
+		if !hasMember(f, member) {
+			errorHandler(w, r, fmt.Sprintf("member not in family: %s", member), http.StatusBadRequest, nil)
+			return
+		}
+		channels = f.ChannelsFor(member)
+	}
+	if len(channels) == 0 {
+		errorHandler(w, r, "no notification channels configured", http.StatusBadRequest, nil)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if member == "" && len(f.Members) > 0 {
+		member = f.Members[0]
+	}
+	due := time.Now().Add(time.Hour)
+	sample := reminder.NewReminder("", "Test notification", fmt.Sprintf("Notifications for %s are working.", f.Name), &due, f.ID, member, reminder.RecurrencePattern{Type: "once"})
+	msg, err := templates.Render(f.Settings.Templates, templates.KindReminder, templates.ForReminder(f, sample, time.Local))
+	if err != nil {
+		errorHandler(w, r, "failed to render notification", http.StatusInternalServerError, err)
+		return
+	}
+	results := make([]channelResult, 0, len(channels))
+	for _, ch := range channels {
+		res := channelResult{Type: ch.Type, Target: ch.Target}
+		if err := Notifier.Send(ctx, ch, msg); err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DeliveriesHandler handles GET /families/{id}/deliveries, listing the
+// due-reminder notifications sent to the family's channels in the last 30
+// days, newest first, with their outcome and any failover. ?reminder_id=
+// narrows the list to one reminder's.
+func DeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	list, err := delivery.List(requestStore(r), id, r.URL.Query().Get("reminder_id"))
+	if err != nil {
+		errorHandler(w, r, "failed to list deliveries", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// NotificationLink returns a signed completion link for a due-reminder
+// notification, or "" when links can't be built because LinkSigner or
+// BaseURL is unset
+func NotificationLink(r *reminder.Reminder) string {
+	if LinkSigner == nil || BaseURL == "" {
+		return ""
+	}
+	link, _ := completionLink(strings.TrimSuffix(BaseURL, "/"), r, r.FamilyMember, CompletionLinkTTL)
+	return link
+}
+
+// notifyEvent sends completion notices
+func notifyEvent(e events.Event) {
+	if c, ok := e.(events.ReminderCompleted); ok {
+		notifyCompletion(c.Reminder, c.Completion.CompletedBy)
+	}
+}
+
+// notifyCompletion tells the family channels that r was completed, if the
+// family asked for completion notices. Events carry no request, so the
+// family is read in the background under the send's deadline; delivery
+// failures are only logged.
+func notifyCompletion(r *reminder.Reminder, by string) {
+	if Notifier == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		f, err := storage.WithContext(Store, ctx).GetFamily(r.FamilyID)
+		if err != nil || !f.Settings.CompletionNotices || len(f.Settings.Channels) == 0 {
+			return
+		}
+		data := templates.ForReminder(f, r, time.Local)
+		data.CompletedBy = by
+		msg, err := templates.Render(f.Settings.Templates, templates.KindCompleted, data)
+		if err != nil {
+			log.Printf("failed to render completion notice for %s: %v", r.ID, err)
+			return
+		}
+		if err := Notifier.SendAll(ctx, f.Settings.Channels, msg); err != nil {
+			log.Printf("failed to send completion notice for %s: %v", r.ID, err)
+		}
+	}()
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// listedReminder is a reminder as listed by GET /reminders, along with
+// whether it is overdue at the time of the request
+type listedReminder struct {
+	*reminder.Reminder
+	Overdue      bool       `json:"overdue"`
+	OverdueSince *time.Time `json:"overdue_since,omitempty"`
+}
+
+// withOverdue returns list with the overdue state of each reminder at now,
+// which follows the days of its assignee's time zone. When overdue is set,
+// only the reminders whose state matches it are returned.
+func withOverdue(r *http.Request, list []*reminder.Reminder, now time.Time, overdue *bool) []listedReminder {
+	families := make(map[string]*fam.Family)
+	result := make([]listedReminder, 0, len(list))
+	for _, rem := range list {
+		f, ok := families[rem.FamilyID]
+		if !ok {
+			f, _ = requestStore(r).GetFamily(rem.FamilyID)
+			families[rem.FamilyID] = f
+		}
+		at := now
+		if f != nil {
+			if loc, err := f.LocationFor(rem.FamilyMember); err == nil {
+				at = now.In(loc)
+			}
+		}
+		lr := listedReminder{Reminder: rem, OverdueSince: rem.OverdueSince(at)}
+		lr.Overdue = lr.OverdueSince != nil
+		if overdue == nil || *overdue == lr.Overdue {
+			result = append(result, lr)
+		}
+	}
+	return result
+}
+
+// validateEscalation returns an error message if an optional escalation is
+// invalid or notifies someone outside the family
+func validateEscalation(s storage.Storage, familyID string, e *reminder.Escalation) (string, error) {
+	if e == nil {
+		return "", nil
+	}
+	if err := e.Validate(); err != nil {
+		return fmt.Sprintf("invalid escalation: %v", err), err
+	}
+	f, err := s.GetFamily(familyID)
+	if err != nil {
+		return fmt.Sprintf("family not found: %s", familyID), err
+	}
+	for _, step := range e.Steps {
+		if !hasMember(f, step.Member) {
+			return fmt.Sprintf("escalation member not found: %s", step.Member), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/events"
+	"reminder-app/internal/pack"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// TemplatePackKeys are the publishers whose template packs may be imported.
+// Empty disables the feature.
+var TemplatePackKeys pack.Keyring
+
+// TemplatePackClient fetches template packs
+var TemplatePackClient = &http.Client{Timeout: 10 * time.Second}
+
+// packImportRequest is the body accepted by POST /templates/import-url
+type packImportRequest struct {
+	URL      string `json:"url"`
+	FamilyID string `json:"family_id"`
+	// FamilyMember or Assignment decides who the installed reminders go to
+	FamilyMember string `json:"family_member"`
+	Assignment   string `json:"assignment"`
+	// Templates selects the templates to install by ID; all when empty
+	Templates []string `json:"templates"`
+	// Preview returns the pack without installing anything
+	Preview bool `json:"preview"`
+}
+
+// packImport is the response of POST /templates/import-url. Created is
+// omitted from previews.
+type packImport struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Publisher   string               `json:"publisher"`
+	Version     string               `json:"version,omitempty"`
+	Templates   []pack.Template      `json:"templates"`
+	Created     []*reminder.Reminder `json:"created,omitempty"`
+}
+
+// ImportTemplatePackHandler handles POST /templates/import-url, fetching a
+// signed template pack and installing its templates as reminders of a
+// family. With "preview" the verified pack is returned without installing
+// anything, so the family can pick templates for a second request. The
+// selected templates are installed together like POST /reminders/batch.
+func ImportTemplatePackHandler(w http.ResponseWriter, r *http.Request) {
+	if len(TemplatePackKeys) == 0 {
+		errorHandler(w, r, "template packs are not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	var req packImportRequest
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if req.URL == "" || req.FamilyID == "" {
+		errorHandler(w, r, "url and family_id are required", http.StatusBadRequest, nil)
+		return
+	}
+
+	data, err := pack.Fetch(r.Context(), TemplatePackClient, req.URL)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("failed to fetch pack: %v", err), http.StatusBadGateway, err)
+		return
+	}
+	m, err := pack.Verify(data, TemplatePackKeys)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, pack.ErrSignature) || errors.Is(err, pack.ErrUntrusted) {
+			status = http.StatusForbidden
+		}
+		errorHandler(w, r, fmt.Sprintf("invalid pack: %v", err), status, err)
+		return
+	}
+	selected, err := m.Select(req.Templates)
+	if err != nil {
+		errorHandler(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	result := packImport{Name: m.Name, Description: m.Description, Publisher: m.Publisher, Version: m.Version, Templates: selected}
+	if req.Preview {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		log.Printf("%s %s %s %d - previewed pack %q", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK, m.Name)
+		return
+	}
+
+	now := time.Now()
+	s := requestStore(r)
+	position, err := nextPosition(s, req.FamilyID)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	list := make([]*reminder.Reminder, 0, len(selected))
+	for i, t := range selected {
+		item := reminderRequest{
+			Title:        t.Title,
+			Description:  t.Description,
+			FamilyID:     req.FamilyID,
+			FamilyMember: req.FamilyMember,
+			Assignment:   req.Assignment,
+			Recurrence:   t.Recurrence,
+			Effort:       t.Effort,
+		}
+		if due := t.DueDate(now); due != nil {
+			item.DueDate = due.Format(time.RFC3339)
+		}
+		dueDate, msg, err := item.validate(s)
+		if msg != "" {
+			errorHandler(w, r, fmt.Sprintf("template %s: %s", t.ID, msg), http.StatusBadRequest, err)
+			return
+		}
+		re, msg, err := newReminder(s, &item, dueDate)
+		if msg != "" {
+			errorHandler(w, r, fmt.Sprintf("template %s: %s", t.ID, msg), http.StatusInternalServerError, err)
+			return
+		}
+		re.Position = position + i
+		list = append(list, re)
+	}
+
+	n, err := storage.SaveReminders(s, list)
+	actor, impersonator := requestActor(r), requestImpersonator(r)
+	for _, re := range list[:n] {
+		Events.Publish(events.ReminderCreated{Reminder: re, Actor: actor, Impersonator: impersonator})
+	}
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("failed to create reminders; %d of %d were created", n, len(list)), http.StatusInternalServerError, err)
+		return
+	}
+	result.Created = list
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+	log.Printf("%s %s %s %d - installed %d templates from pack %q", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated, n, m.Name)
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/hook"
+	"reminder-app/internal/project"
+	"reminder-app/internal/share"
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/suggest"
+
+	"github.com/gorilla/mux"
+)
+
+// Scope says where a policy finds the family, and possibly the reminder, a
+// request acts on
+type Scope int
+
+const (
+	// ScopeFamily is a family given by the {id} route variable
+	ScopeFamily Scope = iota
+	// ScopeReminder is the family of the reminder given by {id}. Children
+	// are limited to their own reminders.
+	ScopeReminder
+	// ScopeCompletion is the family of the reminder the completion event
+	// given by {id} belongs to
+	ScopeCompletion
+	// ScopeRecord is the family of the stored record of Policy.Kind given
+	// by {id}
+	ScopeRecord
+	// ScopeBody is the family_id field of the JSON body or, failing that,
+	// the family of the reminder in its reminder_id field
+	ScopeBody
+)
+
+// Policy is the permission an endpoint requires
+type Policy struct {
+	Permission fam.Permission
+	Scope      Scope
+	// Kind is the record kind of ScopeRecord
+	Kind string
+}
+
+// Policies lists the endpoints that require a permission, keyed by method
+// and route template like Deprecations. They are enforced by
+// AuthorizationMiddleware before the handler runs; handlers only check
+// what depends on the request itself, such as the family a reminder is
+// moved to. Endpoints not listed are open to every caller.
+var Policies = map[string]Policy{
+	"PATCH /families/{id}":                             {fam.PermManage, ScopeFamily, ""},
+	"DELETE /families/{id}":                            {fam.PermManage, ScopeFamily, ""},
+	"POST /families/{id}/members":                      {fam.PermManage, ScopeFamily, ""},
+	"DELETE /families/{id}/members/{name}":             {fam.PermManage, ScopeFamily, ""},
+	"PUT /families/{id}/avatar":                        {fam.PermManage, ScopeFamily, ""},
+	"DELETE /families/{id}/avatar":                     {fam.PermManage, ScopeFamily, ""},
+	"PUT /families/{id}/members/{name}/avatar":         {fam.PermManage, ScopeFamily, ""},
+	"DELETE /families/{id}/members/{name}/avatar":      {fam.PermManage, ScopeFamily, ""},
+	"PUT /families/{id}/settings":                      {fam.PermManage, ScopeFamily, ""},
+	"POST /families/{id}/import/ics":                   {fam.PermEdit, ScopeFamily, ""},
+	"POST /families/{id}/export-package":               {fam.PermManage, ScopeFamily, ""},
+	"POST /families/{id}/webhooks":                     {fam.PermManage, ScopeFamily, ""},
+	"GET /families/{id}/webhooks":                      {fam.PermManage, ScopeFamily, ""},
+	"DELETE /families/{id}/webhooks/{webhook}":         {fam.PermManage, ScopeFamily, ""},
+	"GET /families/{id}/webhooks/{webhook}/deliveries": {fam.PermManage, ScopeFamily, ""},
+	"GET /families/{id}/deliveries":                    {fam.PermManage, ScopeFamily, ""},
+	"POST /families/{id}/guests":                       {fam.PermManage, ScopeFamily, ""},
+	"GET /families/{id}/guests":                        {fam.PermManage, ScopeFamily, ""},
+	"DELETE /families/{id}/guests/{guest}":             {fam.PermManage, ScopeFamily, ""},
+	"POST /families/{id}/notifications/test":           {fam.PermManage, ScopeFamily, ""},
+	"POST /reminders":                                  {fam.PermEdit, ScopeBody, ""},
+	"POST /reminders/reorder":                          {fam.PermEdit, ScopeBody, ""},
+	"POST /reminders/batch":                            {fam.PermEdit, ScopeBody, ""},
+	"POST /templates/import-url":                       {fam.PermEdit, ScopeBody, ""},
+	"PATCH /reminders/batch":                           {fam.PermEdit, ScopeBody, ""},
+	"PUT /reminders/{id}":                              {fam.PermEdit, ScopeReminder, ""},
+	"DELETE /reminders/{id}":                           {fam.PermEdit, ScopeReminder, ""},
+	"POST /reminders/{id}/restore":                     {fam.PermEdit, ScopeRecord, storage.TrashKind},
+	"PATCH /reminders/{id}":                            {fam.PermComplete, ScopeReminder, ""},
+	"POST /reminders/{id}/status":                      {fam.PermComplete, ScopeReminder, ""},
+	"POST /reminders/{id}/snooze":                      {fam.PermComplete, ScopeReminder, ""},
+	"POST /reminders/{id}/ack":                         {fam.PermComplete, ScopeReminder, ""},
+	"POST /reminders/{id}/usage":                       {fam.PermComplete, ScopeReminder, ""},
+	"POST /reminders/{id}/adjust-schedule":             {fam.PermEdit, ScopeReminder, ""},
+	"GET /reminders/{id}/complete-link":                {fam.PermComplete, ScopeReminder, ""},
+	"GET /reminders/{id}/qr.png":                       {fam.PermComplete, ScopeReminder, ""},
+	"POST /completion-events":                          {fam.PermComplete, ScopeBody, ""},
+	"DELETE /completion-events/{id}":                   {fam.PermEdit, ScopeCompletion, ""},
+	"POST /smart-lists":                                {fam.PermEdit, ScopeBody, ""},
+	"PUT /smart-lists/{id}":                            {fam.PermEdit, ScopeRecord, smartlist.Kind},
+	"DELETE /smart-lists/{id}":                         {fam.PermEdit, ScopeRecord, smartlist.Kind},
+	"POST /projects":                                   {fam.PermEdit, ScopeBody, ""},
+	"PUT /projects/{id}":                               {fam.PermEdit, ScopeRecord, project.Kind},
+	"DELETE /projects/{id}":                            {fam.PermEdit, ScopeRecord, project.Kind},
+	"POST /suggestions/{id}/accept":                    {fam.PermEdit, ScopeRecord, suggest.Kind},
+	"POST /suggestions/{id}/dismiss":                   {fam.PermEdit, ScopeRecord, suggest.Kind},
+	"POST /shares":                                     {fam.PermManage, ScopeBody, ""},
+	"DELETE /shares/{id}":                              {fam.PermManage, ScopeRecord, share.Kind},
+	"POST /hooks":                                      {fam.PermManage, ScopeBody, ""},
+	"DELETE /hooks/{id}":                               {fam.PermManage, ScopeRecord, hook.Kind},
+}
+
+// routeKey returns the method and template of the route a request matched,
+// as Policies is keyed, or "" if it didn't match one
+func routeKey(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	tmpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return r.Method + " " + tmpl
+}
+
+// routePolicy returns the policy of the route a request matched
+func routePolicy(r *http.Request) (Policy, bool) {
+	p, ok := Policies[routeKey(r)]
+	return p, ok
+}
+
+// AuthorizationMiddleware enforces Policies, answering requests the caller
+// may not make with 401 or 403. Entities that don't exist are left for the
+// handler to report. ActAsHeader is refused on endpoints without a policy,
+// which have no family to check it against. Requests with GuestHeader are
+// confined to the guest's scope first. It must run after routing.
+func AuthorizationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(GuestHeader) != "" {
+			var ok bool
+			if r, ok = scopeGuest(w, r); !ok {
+				return
+			}
+		}
+		p, ok := routePolicy(r)
+		if !ok && r.Header.Get(ActAsHeader) != "" {
+			errorHandler(w, r, fmt.Sprintf("%s is not accepted by this endpoint", ActAsHeader), http.StatusBadRequest, nil)
+			return
+		}
+		if ok && !p.authorize(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize checks the policy for a request
+func (p Policy) authorize(w http.ResponseWriter, r *http.Request) bool {
+	id := mux.Vars(r)["id"]
+	switch p.Scope {
+	case ScopeFamily:
+		return authorizeFamily(w, r, id, p.Permission)
+	case ScopeReminder:
+		rem, err := requestStore(r).GetReminder(id)
+		if err != nil {
+			return true
+		}
+		return authorizeReminder(w, r, rem, p.Permission)
+	case ScopeCompletion:
+		e, err := requestStore(r).GetCompletionEvent(id)
+		if err != nil {
+			return true
+		}
+		rem, err := requestStore(r).GetReminder(e.ReminderID)
+		if err != nil {
+			return true
+		}
+		return authorizeReminder(w, r, rem, p.Permission)
+	case ScopeRecord:
+		return authorizeRecord(w, r, p.Kind, id, p.Permission)
+	case ScopeBody:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			errorHandler(w, r, "failed to read request body", http.StatusBadRequest, err)
+			return false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		// Malformed bodies are the handler's to reject
+		var ref struct {
+			FamilyID   string `json:"family_id"`
+			ReminderID string `json:"reminder_id"`
+		}
+		json.Unmarshal(body, &ref)
+		if ref.FamilyID == "" && ref.ReminderID != "" {
+			rem, err := requestStore(r).GetReminder(ref.ReminderID)
+			if err != nil {
+				return true
+			}
+			return authorizeReminder(w, r, rem, p.Permission)
+		}
+		return authorizeFamily(w, r, ref.FamilyID, p.Permission)
+	}
+	return true
+}
+
+// permissionsResponse is the body of GET /me/permissions
+type permissionsResponse struct {
+	Member   string   `json:"member"`
+	FamilyID string   `json:"family_id"`
+	Role     fam.Role `json:"role,omitempty"`
+	// Permissions are those the member holds in the family
+	Permissions []fam.Permission `json:"permissions"`
+	// Endpoints maps each endpoint in Policies to whether the member may
+	// call it. Children may only complete their own reminders.
+	Endpoints map[string]bool `json:"endpoints"`
+}
+
+// MyPermissionsHandler handles GET /me/permissions?family_id=, reporting
+// what the member named by ActorHeader may do in the family so clients can
+// hide actions that would be refused
+func MyPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("family_id")
+	if id == "" {
+		errorHandler(w, r, "family_id is required", http.StatusBadRequest, nil)
+		return
+	}
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	actor := requestActor(r)
+	resp := permissionsResponse{
+		Member:      actor,
+		FamilyID:    id,
+		Role:        f.RoleOf(actor),
+		Permissions: []fam.Permission{},
+		Endpoints:   make(map[string]bool, len(Policies)),
+	}
+	for _, p := range fam.Permissions {
+		if permitted(f, actor, p) {
+			resp.Permissions = append(resp.Permissions, p)
+		}
+	}
+	for key, p := range Policies {
+		resp.Endpoints[key] = permitted(f, actor, p.Permission)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultPollPage and maxPollPage bound the limit of polling endpoints
+const (
+	defaultPollPage = 100
+	maxPollPage     = 1000
+)
+
+// pollItem is one entry of a polling response. ID is the deduplication key:
+// it is the same every time the entity is returned, so a poller that sees
+// an item twice, e.g. after retrying a request, can drop the repeat.
+type pollItem struct {
+	ID   string `json:"id"`
+	Data any    `json:"data"`
+}
+
+// pollResponse is the body of the polling endpoints. Cursor resumes after
+// the last item and is returned even when there are no items, so it can be
+// stored and sent unchanged on the next poll.
+type pollResponse struct {
+	Items  []pollItem `json:"items"`
+	Cursor string     `json:"cursor"`
+	More   bool       `json:"more"`
+}
+
+// sequence returns the counter value embedded in a generated ID such as
+// "rem12". IDs come from monotonically increasing counters, so unlike
+// timestamps they order entities by creation regardless of clock skew
+// between the server, the database and the poller.
+func sequence(id string) int {
+	n, err := strconv.Atoi(strings.TrimLeft(id, "abcdefghijklmnopqrstuvwxyz"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// encodePollCursor builds the opaque cursor resuming after sequence seq
+func encodePollCursor(seq int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("seq:" + strconv.Itoa(seq)))
+}
+
+// decodePollCursor parses a cursor from encodePollCursor. An empty cursor
+// starts from the beginning.
+func decodePollCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := strings.CutPrefix(string(raw), "seq:")
+	if !ok {
+		return 0, errors.New("malformed cursor")
+	}
+	return strconv.Atoi(v)
+}
+
+// pollParams reads the cursor and limit query parameters
+func pollParams(r *http.Request) (after, limit int, msg string, err error) {
+	params := r.URL.Query()
+	if after, err = decodePollCursor(params.Get("cursor")); err != nil {
+		return 0, 0, "invalid cursor", err
+	}
+	limit = defaultPollPage
+	if v := params.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return 0, 0, "limit must be a positive integer", err
+		}
+		limit = min(limit, maxPollPage)
+	}
+	return after, limit, "", nil
+}
+
+// writePoll pages items, which must be sorted by sequence, and writes the
+// response. The next cursor is also sent in the X-Next-Cursor header.
+func writePoll(w http.ResponseWriter, r *http.Request, items []pollItem, after, limit int) {
+	resp := pollResponse{Items: items, Cursor: encodePollCursor(after)}
+	if len(items) > limit {
+		resp.Items, resp.More = items[:limit], true
+	}
+	if n := len(resp.Items); n > 0 {
+		resp.Cursor = encodePollCursor(sequence(resp.Items[n-1].ID))
+	}
+	if resp.Items == nil {
+		resp.Items = []pollItem{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Next-Cursor", resp.Cursor)
+	json.NewEncoder(w).Encode(resp)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// PollRemindersHandler handles GET /families/{id}/poll/reminders, returning
+// reminders created after the cursor, oldest first, for integrations that
+// poll for new items. Each item's id is the reminder ID.
+func PollRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	after, limit, msg, err := pollParams(r)
+	if msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	list, err := familyReminders(requestStore(r), id)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	var items []pollItem
+	for _, rem := range list {
+		if sequence(rem.ID) > after {
+			items = append(items, pollItem{ID: rem.ID, Data: rem})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return sequence(items[i].ID) < sequence(items[j].ID) })
+	writePoll(w, r, items, after, limit)
+}
+
+// PollCompletionsHandler handles GET /families/{id}/poll/completions,
+// returning completion events recorded after the cursor, oldest first.
+// Each item's id is the completion event ID.
+func PollCompletionsHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	after, limit, msg, err := pollParams(r)
+	if msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	list, err := familyReminders(requestStore(r), id)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	var items []pollItem
+	for _, rem := range list {
+		events, err := requestStore(r).ListCompletionEvents(rem.ID)
+		if err != nil {
+			errorHandler(w, r, "failed to list completion events", http.StatusInternalServerError, err)
+			return
+		}
+		for _, e := range events {
+			if sequence(e.ID) > after {
+				items = append(items, pollItem{ID: e.ID, Data: e})
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return sequence(items[i].ID) < sequence(items[j].ID) })
+	writePoll(w, r, items, after, limit)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/preferences"
+)
+
+// preferencesMember returns the family and member whose preferences a
+// request to /me/preferences?family_id= is for, writing an error response
+// if the caller isn't a member of the family
+func preferencesMember(w http.ResponseWriter, r *http.Request) (familyID, member string, ok bool) {
+	familyID = r.URL.Query().Get("family_id")
+	if familyID == "" {
+		errorHandler(w, r, "family_id is required", http.StatusBadRequest, nil)
+		return "", "", false
+	}
+	member = requestActor(r)
+	if member == "" {
+		errorHandler(w, r, fmt.Sprintf("%s header is required", ActorHeader), http.StatusUnauthorized, nil)
+		return "", "", false
+	}
+	f, err := requestStore(r).GetFamily(familyID)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", familyID), http.StatusNotFound, err)
+		return "", "", false
+	}
+	if !hasMember(f, member) {
+		errorHandler(w, r, fmt.Sprintf("%s is not a member of family %s", member, familyID), http.StatusForbidden, nil)
+		return "", "", false
+	}
+	return familyID, member, true
+}
+
+// GetPreferencesHandler handles GET /me/preferences?family_id=, returning
+// the calling member's UI preferences
+func GetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	familyID, member, ok := preferencesMember(w, r)
+	if !ok {
+		return
+	}
+	p, err := preferences.Get(requestStore(r), familyID, member)
+	if err != nil {
+		errorHandler(w, r, "failed to load preferences", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// PutPreferencesHandler handles PUT /me/preferences?family_id=, replacing
+// the calling member's UI preferences
+func PutPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	familyID, member, ok := preferencesMember(w, r)
+	if !ok {
+		return
+	}
+	var p preferences.Preferences
+	if err := decodeJSON(r, r.Body, &p); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if err := p.Validate(); err != nil {
+		errorHandler(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	if err := preferences.Put(requestStore(r), familyID, member, &p, time.Now()); err != nil {
+		errorHandler(w, r, "failed to save preferences", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
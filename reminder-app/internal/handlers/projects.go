@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/events"
+	"reminder-app/internal/project"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// projectView is a project as returned by the API, with its progress
+type projectView struct {
+	*project.Project
+	Progress project.Progress `json:"progress"`
+}
+
+// validateProjectID checks that a reminder may join the project. An empty ID
+// means the reminder is not part of any project.
+func validateProjectID(s storage.Storage, familyID, projectID string) (string, error) {
+	if projectID == "" {
+		return "", nil
+	}
+	p, err := storage.GetJSON[project.Project](s, project.Kind, projectID)
+	if err != nil {
+		return fmt.Sprintf("project not found: %s", projectID), err
+	}
+	if p.FamilyID != familyID {
+		return fmt.Sprintf("project %s belongs to another family", projectID), nil
+	}
+	return "", nil
+}
+
+// validateProject checks a project against its family
+func validateProject(s storage.Storage, p *project.Project) (string, error) {
+	if p.Name == "" || p.FamilyID == "" {
+		return "name and family_id are required", nil
+	}
+	if _, err := s.GetFamily(p.FamilyID); err != nil {
+		return fmt.Sprintf("family not found: %s", p.FamilyID), err
+	}
+	return "", nil
+}
+
+// putProject saves a project as a storage record
+func putProject(s storage.Storage, p *project.Project) error {
+	return storage.PutJSON(s, storage.Record{Kind: project.Kind, ID: p.ID, FamilyID: p.FamilyID}, p)
+}
+
+// getProject loads a project, writing a 404 if it doesn't exist
+func getProject(w http.ResponseWriter, r *http.Request) *project.Project {
+	id := mux.Vars(r)["id"]
+	p, err := storage.GetJSON[project.Project](requestStore(r), project.Kind, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		errorHandler(w, r, fmt.Sprintf("project not found: %s", id), status, err)
+		return nil
+	}
+	return p
+}
+
+// projectReminders returns the reminders belonging to a project
+func projectReminders(s storage.Storage, p *project.Project) ([]*reminder.Reminder, error) {
+	return s.QueryReminders(storage.ReminderFilter{FamilyID: p.FamilyID, ProjectID: p.ID})
+}
+
+// viewProject attaches the current progress to a project
+func viewProject(s storage.Storage, p *project.Project) (*projectView, error) {
+	list, err := projectReminders(s, p)
+	if err != nil {
+		return nil, err
+	}
+	return &projectView{Project: p, Progress: project.Summarize(list, time.Now())}, nil
+}
+
+// CreateProjectHandler handles POST /projects
+func CreateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	var p project.Project
+	if err := decodeJSON(r, r.Body, &p); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if msg, err := validateProject(requestStore(r), &p); msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	p.ID = storage.NewRecordID("prj")
+	if err := putProject(requestStore(r), &p); err != nil {
+		errorHandler(w, r, "failed to create project", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(projectView{Project: &p})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
+
+// ListProjectsHandler handles GET /projects?family_id=
+func ListProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	q := storage.RecordQuery{Kind: project.Kind, FamilyID: r.URL.Query().Get("family_id")}
+	list, err := storage.ListJSON[project.Project](requestStore(r), q)
+	if err != nil {
+		errorHandler(w, r, "failed to list projects", http.StatusInternalServerError, err)
+		return
+	}
+	reminders, err := requestStore(r).ListReminders()
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now()
+	views := make([]projectView, 0, len(list))
+	for _, p := range list {
+		members := storage.FilterReminders(reminders, storage.ReminderFilter{FamilyID: p.FamilyID, ProjectID: p.ID})
+		views = append(views, projectView{Project: p, Progress: project.Summarize(members, now)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// GetProjectHandler handles GET /projects/{id}
+func GetProjectHandler(w http.ResponseWriter, r *http.Request) {
+	p := getProject(w, r)
+	if p == nil {
+		return
+	}
+	view, err := viewProject(requestStore(r), p)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// UpdateProjectHandler handles PUT /projects/{id}, replacing the name,
+// description and due date. A project cannot move to another family.
+func UpdateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	existing := getProject(w, r)
+	if existing == nil {
+		return
+	}
+	var p project.Project
+	if err := decodeJSON(r, r.Body, &p); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	p.ID, p.FamilyID = existing.ID, existing.FamilyID
+	if msg, err := validateProject(requestStore(r), &p); msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	if err := putProject(requestStore(r), &p); err != nil {
+		errorHandler(w, r, "failed to update project", http.StatusInternalServerError, err)
+		return
+	}
+	view, err := viewProject(requestStore(r), &p)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DeleteProjectHandler handles DELETE /projects/{id}. The project's
+// reminders are kept and simply leave the project.
+func DeleteProjectHandler(w http.ResponseWriter, r *http.Request) {
+	p := getProject(w, r)
+	if p == nil {
+		return
+	}
+	list, err := projectReminders(requestStore(r), p)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	actor, impersonator := requestActor(r), requestImpersonator(r)
+	for _, rem := range list {
+		var before json.RawMessage
+		saved, err := updateReminder(requestStore(r), rem.ID, func(_ storage.Storage, rem *reminder.Reminder) error {
+			if rem.ProjectID != p.ID {
+				return errEditAbandoned
+			}
+			before = audit.Snapshot(rem)
+			rem.ProjectID = ""
+			return nil
+		})
+		if errors.Is(err, errReminderNotFound) || errors.Is(err, errEditAbandoned) {
+			continue // Deleted or moved since it was listed
+		} else if err != nil {
+			errorHandler(w, r, "failed to update reminder", http.StatusInternalServerError, err)
+			return
+		}
+		Events.Publish(events.ReminderUpdated{Reminder: saved, Before: before, Actor: actor, Impersonator: impersonator})
+	}
+	if err := requestStore(r).DeleteRecord(project.Kind, p.ID); err != nil {
+		errorHandler(w, r, fmt.Sprintf("failed to delete project: %s", p.ID), http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
+
+// ProjectRemindersHandler handles GET /projects/{id}/reminders
+func ProjectRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	p := getProject(w, r)
+	if p == nil {
+		return
+	}
+	list, err := projectReminders(requestStore(r), p)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/events"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// reorderRequest is the body accepted by POST /reminders/reorder
+type reorderRequest struct {
+	FamilyID string   `json:"family_id"`
+	IDs      []string `json:"ids"`
+}
+
+// familyReminders returns the family's reminders in display order
+func familyReminders(s storage.Storage, familyID string) ([]*reminder.Reminder, error) {
+	list, err := s.QueryReminders(storage.ReminderFilter{FamilyID: familyID})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Position != list[j].Position {
+			return list[i].Position < list[j].Position
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// nextPosition returns the position that places a new reminder last in its
+// family
+func nextPosition(s storage.Storage, familyID string) (int, error) {
+	list, err := familyReminders(s, familyID)
+	if err != nil {
+		return 0, err
+	}
+	if len(list) == 0 {
+		return 1, nil
+	}
+	return list[len(list)-1].Position + 1, nil
+}
+
+// ReorderRemindersHandler handles POST /reminders/reorder. The listed
+// reminders are rearranged among the positions they already occupy, so a
+// client can reorder a single day without sending the whole family. Reminders
+// left over from before positions existed are numbered first.
+func ReorderRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	var req reorderRequest
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if req.FamilyID == "" || len(req.IDs) == 0 {
+		errorHandler(w, r, "family_id and ids are required", http.StatusBadRequest, nil)
+		return
+	}
+
+	s := storage.ReadPrimary(requestStore(r))
+	list, err := familyReminders(s, req.FamilyID)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	for i, rem := range list {
+		list[i] = rem.Clone()
+	}
+	byID := make(map[string]*reminder.Reminder, len(list))
+	original := make(map[string]int, len(list))
+	unique := true
+	for i, rem := range list {
+		byID[rem.ID] = rem
+		original[rem.ID] = rem.Position
+		if i > 0 && rem.Position == list[i-1].Position {
+			unique = false
+		}
+	}
+	if !unique {
+		for i, rem := range list {
+			rem.Position = i + 1
+		}
+	}
+
+	seen := make(map[string]bool, len(req.IDs))
+	slots := make([]int, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		rem, ok := byID[id]
+		if !ok {
+			errorHandler(w, r, fmt.Sprintf("reminder %s not found in family %s", id, req.FamilyID), http.StatusBadRequest, nil)
+			return
+		}
+		if seen[id] {
+			errorHandler(w, r, fmt.Sprintf("duplicate reminder id: %s", id), http.StatusBadRequest, nil)
+			return
+		}
+		seen[id] = true
+		slots = append(slots, rem.Position)
+	}
+	sort.Ints(slots)
+	for i, id := range req.IDs {
+		byID[id].Position = slots[i]
+	}
+
+	var changed []*reminder.Reminder
+	var befores []json.RawMessage
+	for _, rem := range list {
+		if rem.Position == original[rem.ID] {
+			continue
+		}
+		moved := *rem
+		moved.Position = original[rem.ID]
+		befores = append(befores, audit.Snapshot(&moved))
+		rem.Version++
+		changed = append(changed, rem)
+	}
+	ids := make([]string, len(changed))
+	for i, rem := range changed {
+		ids[i] = rem.ID
+	}
+	// The reminders were listed before they were locked, so a reminder
+	// edited in between is a conflict rather than overwritten
+	defer lockReminders(ids)()
+	n, err := storage.UpdateReminders(s, changed...)
+	actor, impersonator := requestActor(r), requestImpersonator(r)
+	for i, rem := range changed[:n] {
+		Events.Publish(events.ReminderUpdated{Reminder: rem, Before: befores[i], Actor: actor, Impersonator: impersonator})
+	}
+	if errors.Is(err, storage.ErrVersionConflict) {
+		errorHandler(w, r, "reminders were changed by another request; retry the reorder", http.StatusConflict, err)
+		return
+	} else if err != nil {
+		errorHandler(w, r, "failed to update reminders", http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"reminder-app/internal/events"
+	"reminder-app/internal/report"
+	"reminder-app/internal/stats"
+
+	"github.com/gorilla/mux"
+)
+
+// reportCache holds rendered reports of past months by family and month.
+// A family's entries are dropped by invalidateReports whenever any of its
+// data changes, since late completions and edits can change past months.
+var reportCache = struct {
+	sync.Mutex
+	reports map[string]map[string][]byte
+}{reports: make(map[string]map[string][]byte)}
+
+// invalidateReports subscribes to Events to drop the cached reports of the
+// family an event belongs to
+func invalidateReports(e events.Event) {
+	reportCache.Lock()
+	defer reportCache.Unlock()
+	delete(reportCache.reports, e.FamilyID())
+}
+
+// FamilyReportHandler handles GET /families/{id}/report.pdf?month=YYYY-MM,
+// rendering the family's completion rates and per-member charts for a month
+// as a PDF. The month defaults to the previous one; the current month is
+// reported up to now. Reports of past months are cached.
+func FamilyReportHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	loc, err := f.LocationFor("")
+	if err != nil {
+		errorHandler(w, r, "invalid time zone", http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now().In(loc)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	month := thisMonth.AddDate(0, -1, 0)
+	if v := r.URL.Query().Get("month"); v != "" {
+		if month, err = time.ParseInLocation("2006-01", v, loc); err != nil {
+			errorHandler(w, r, "month must be YYYY-MM", http.StatusBadRequest, err)
+			return
+		}
+		if month.After(thisMonth) {
+			errorHandler(w, r, "month must not be in the future", http.StatusBadRequest, nil)
+			return
+		}
+	}
+
+	key := month.Format("2006-01")
+	reportCache.Lock()
+	pdf, cached := reportCache.reports[id][key]
+	reportCache.Unlock()
+	if !cached {
+		sum, err := report.Build(requestStore(r), f, month, now)
+		if err != nil {
+			errorHandler(w, r, "failed to build report", http.StatusInternalServerError, err)
+			return
+		}
+		pdf = report.PDF(sum, now)
+		if !sum.Partial {
+			reportCache.Lock()
+			if reportCache.reports[id] == nil {
+				reportCache.reports[id] = make(map[string][]byte)
+			}
+			reportCache.reports[id][key] = pdf
+			reportCache.Unlock()
+		}
+	}
+	w.Header().Set("Content-Type", report.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=report-%s.pdf", key))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.Write(pdf)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// maxStatsDays is the longest range FamilyStatsHandler reports at once
+const maxStatsDays = 366
+
+// FamilyStatsHandler handles GET /families/{id}/stats?from=YYYY-MM-DD&to=YYYY-MM-DD,
+// listing the family's daily stats for the days from from through to. The
+// range defaults to the last 30 days including today, which is reported up
+// to now. With view=summary the range is summed up instead: completion
+// rates and overdue counts per member and the streaks of recurring
+// reminders.
+func FamilyStatsHandler(w http.ResponseWriter, r *http.Request) {
+	view := r.URL.Query().Get("view")
+	if view != "" && view != "days" && view != "summary" {
+		errorHandler(w, r, "view must be days or summary", http.StatusBadRequest, nil)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	loc, err := f.LocationFor("")
+	if err != nil {
+		errorHandler(w, r, "invalid time zone", http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.ParseInLocation(stats.DateLayout, v, loc); err != nil {
+			errorHandler(w, r, "to must be YYYY-MM-DD", http.StatusBadRequest, err)
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.ParseInLocation(stats.DateLayout, v, loc); err != nil {
+			errorHandler(w, r, "from must be YYYY-MM-DD", http.StatusBadRequest, err)
+			return
+		}
+	}
+	end := to.AddDate(0, 0, 1)
+	if from.After(to) || end.After(from.AddDate(0, 0, maxStatsDays)) {
+		errorHandler(w, r, fmt.Sprintf("from must be before to and at most %d days apart", maxStatsDays), http.StatusBadRequest, nil)
+		return
+	}
+	if end.After(now) {
+		end = now
+	}
+	if view == "summary" {
+		sum, err := stats.Summarize(requestStore(r), f, from, end)
+		if err != nil {
+			errorHandler(w, r, "failed to compute stats", http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sum)
+		log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+		return
+	}
+	days := []*stats.Day{}
+	if from.Before(end) {
+		if days, err = stats.Range(requestStore(r), f, from, end); err != nil {
+			errorHandler(w, r, "failed to compute stats", http.StatusInternalServerError, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(days)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
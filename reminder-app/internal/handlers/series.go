@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/events"
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// Edit scopes of PATCH /reminders/{id}?scope=, saying which occurrences of
+// a recurring reminder a patch applies to
+const (
+	editAll        = "all"
+	editOccurrence = "occurrence"
+	editFollowing  = "following"
+)
+
+// seriesEdit is the response to a patch of some occurrences of a series
+type seriesEdit struct {
+	// Series is the recurring reminder the occurrences were taken from
+	Series *reminder.Reminder `json:"series"`
+	// Reminder holds the edited occurrences
+	Reminder *reminder.Reminder `json:"reminder"`
+}
+
+// editScope reads the scope and occurrence parameters of a patch of r,
+// writing a 400 if they are invalid. Editing the following occurrences from
+// the first one is editing all of them.
+func editScope(w http.ResponseWriter, req *http.Request, r *reminder.Reminder) (string, time.Time, bool) {
+	scope := req.URL.Query().Get("scope")
+	switch scope {
+	case "", editAll:
+		return editAll, time.Time{}, true
+	case editOccurrence, editFollowing:
+	default:
+		errorHandler(w, req, fmt.Sprintf("scope must be %s, %s or %s", editAll, editOccurrence, editFollowing), http.StatusBadRequest, nil)
+		return "", time.Time{}, false
+	}
+	if !r.IsRecurring() {
+		errorHandler(w, req, fmt.Sprintf("scope=%s requires a recurring reminder", scope), http.StatusBadRequest, nil)
+		return "", time.Time{}, false
+	}
+	at, err := time.Parse(time.RFC3339, req.URL.Query().Get("occurrence"))
+	if err != nil {
+		errorHandler(w, req, fmt.Sprintf("scope=%s requires an occurrence in RFC 3339 format", scope), http.StatusBadRequest, err)
+		return "", time.Time{}, false
+	}
+	if !r.IsOccurrence(at) {
+		errorHandler(w, req, fmt.Sprintf("%s is not an occurrence of reminder %s", at.Format(time.RFC3339), r.ID), http.StatusBadRequest, nil)
+		return "", time.Time{}, false
+	}
+	if scope == editFollowing && at.Equal(*r.DueDate) {
+		return editAll, time.Time{}, true
+	}
+	return scope, at, true
+}
+
+// updateOccurrences applies a patch to the occurrence of r at the given
+// time alone, or to it and the ones after, the way calendar apps do. The
+// occurrence is detached from the series as a one-off reminder, or the
+// series is split into one ending before it and a new one starting with it,
+// and the patch is applied to the new reminder. Both are saved together,
+// and the series keeps its completion history.
+func updateOccurrences(w http.ResponseWriter, req *http.Request, s storage.Storage, r *reminder.Reminder, scope string, at time.Time, contentType string, body []byte) {
+	if !authorizeReminder(w, req, r, fam.PermEdit) || !checkIfMatch(w, req, r, true) {
+		return
+	}
+	id, err := storage.GenerateReminderID(s)
+	if err != nil {
+		errorHandler(w, req, "failed to create reminder", http.StatusInternalServerError, err)
+		return
+	}
+	before := audit.Snapshot(r)
+	series := *r
+	series.Exceptions = slices.Clone(r.Exceptions)
+	var edited *reminder.Reminder
+	if scope == editOccurrence {
+		edited = series.Detach(at, id)
+	} else {
+		edited = series.SplitAt(at, id)
+	}
+
+	patched, err := applyReminderPatch(edited, contentType, body)
+	if err != nil {
+		errorHandler(w, req, "invalid patch", http.StatusBadRequest, err)
+		return
+	}
+	var doc reminderDocument
+	if err := decodeJSON(req, bytes.NewReader(patched), &doc); err != nil {
+		errorHandler(w, req, fmt.Sprintf("invalid patch: %v", err), http.StatusBadRequest, err)
+		return
+	}
+	if doc.Completed {
+		errorHandler(w, req, fmt.Sprintf("occurrences are completed without scope=%s", scope), http.StatusBadRequest, nil)
+		return
+	}
+	dueDate, msg, err := validateDocument(s, edited, &doc)
+	if msg != "" {
+		errorHandler(w, req, msg, http.StatusBadRequest, err)
+		return
+	}
+	applyDocument(s, edited, doc, dueDate)
+
+	series.Version++
+	if _, err := storage.UpdateReminders(s, &series, edited); errors.Is(err, storage.ErrVersionConflict) {
+		errorHandler(w, req, fmt.Sprintf("reminder %s was changed by another request; retry the change", r.ID), http.StatusConflict, err)
+		return
+	} else if err != nil {
+		errorHandler(w, req, "failed to update reminder", http.StatusInternalServerError, err)
+		return
+	}
+	actor, impersonator := requestActor(req), requestImpersonator(req)
+	Events.Publish(events.ReminderUpdated{Reminder: &series, Before: before, Actor: actor, Impersonator: impersonator})
+	Events.Publish(events.ReminderCreated{Reminder: edited, Actor: actor, Impersonator: impersonator})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(seriesEdit{Series: &series, Reminder: edited})
+	log.Printf("%s %s %s %d - PATCH %s of reminder %s", req.Method, req.URL.Path, req.UserAgent(), http.StatusOK, scope, r.ID)
+}
+
+// seriesView is a series as returned by GET /series/{id}
+type seriesView struct {
+	ID string `json:"id"`
+	// Reminders are the parts of the series by due date
+	Reminders []*reminder.Reminder `json:"reminders"`
+	// Completions and History are those of every part, oldest first
+	Completions []*reminder.CompletionEvent `json:"completions"`
+	History     []*audit.Entry              `json:"history"`
+}
+
+// SeriesHandler handles GET /series/{id}, returning the reminders a
+// recurring series was split into, with the completions and recorded
+// changes of all of them, so its history can be followed across splits
+// and changes of recurrence. The series ID is that of the first reminder.
+func SeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	s := requestStore(r)
+	list, err := s.QueryReminders(storage.ReminderFilter{SeriesID: id})
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	if len(list) == 0 {
+		errorHandler(w, r, fmt.Sprintf("series not found: %s", id), http.StatusNotFound, nil)
+		return
+	}
+	reminder.SortReminders(list, []reminder.SortKey{{Field: reminder.SortDueDate}})
+	view := seriesView{ID: id, Reminders: list, Completions: []*reminder.CompletionEvent{}, History: []*audit.Entry{}}
+	for _, rem := range list {
+		events, err := s.ListCompletionEvents(rem.ID)
+		if err != nil {
+			errorHandler(w, r, "failed to list completion events", http.StatusInternalServerError, err)
+			return
+		}
+		view.Completions = append(view.Completions, events...)
+		entries, err := audit.History(s, "reminder", rem.ID)
+		if err != nil {
+			errorHandler(w, r, "failed to load reminder history", http.StatusInternalServerError, err)
+			return
+		}
+		view.History = append(view.History, entries...)
+	}
+	sort.SliceStable(view.Completions, func(i, j int) bool {
+		return view.Completions[i].CompletedAt.Before(view.Completions[j].CompletedAt)
+	})
+	sort.SliceStable(view.History, func(i, j int) bool { return view.History[i].At.Before(view.History[j].At) })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
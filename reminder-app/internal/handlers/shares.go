@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/share"
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// ShareTTL is how long a share link stays valid when no ttl is given
+var ShareTTL = 30 * 24 * time.Hour
+
+// shareRequest is the body accepted by POST /shares
+type shareRequest struct {
+	FamilyID string          `json:"family_id"`
+	Name     string          `json:"name"`
+	Query    smartlist.Query `json:"query"`
+	// TTL is a Go duration such as "12h"; ShareTTL is used when empty
+	TTL string `json:"ttl,omitempty"`
+}
+
+// shareResponse is a newly created share with its token and link
+type shareResponse struct {
+	*share.Share
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// sharedReminder is the public view of a reminder, leaving out IDs and
+// other internals
+type sharedReminder struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Assignee    string     `json:"assignee"`
+	Done        bool       `json:"done"`
+}
+
+// sharedView is the response of GET /shared/{token}
+type sharedView struct {
+	Name      string           `json:"name"`
+	ExpiresAt *time.Time       `json:"expires_at,omitempty"`
+	Reminders []sharedReminder `json:"reminders"`
+}
+
+// CreateShareHandler handles POST /shares, creating a read-only link to the
+// reminders matched by a query. The token is only returned here.
+func CreateShareHandler(w http.ResponseWriter, r *http.Request) {
+	var req shareRequest
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if msg, err := validateSmartList(requestStore(r), &smartlist.SmartList{FamilyID: req.FamilyID, Name: req.Name, Query: req.Query}); msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	ttl := ShareTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			errorHandler(w, r, "ttl must be a positive duration", http.StatusBadRequest, err)
+			return
+		}
+		ttl = d
+	}
+	now := time.Now()
+	expires := now.Add(ttl)
+	sh := &share.Share{FamilyID: req.FamilyID, Name: req.Name, Query: req.Query, ExpiresAt: &expires}
+	token, err := share.Create(requestStore(r), sh, now)
+	if err != nil {
+		errorHandler(w, r, "failed to create share", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shareResponse{Share: sh, Token: token, URL: externalBaseURL(r) + "/shared/" + token})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
+
+// ListSharesHandler handles GET /shares?family_id=. Tokens are not included.
+func ListSharesHandler(w http.ResponseWriter, r *http.Request) {
+	q := storage.RecordQuery{Kind: share.Kind, FamilyID: r.URL.Query().Get("family_id")}
+	list, err := storage.ListJSON[share.Share](requestStore(r), q)
+	if err != nil {
+		errorHandler(w, r, "failed to list shares", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DeleteShareHandler handles DELETE /shares/{id}, revoking the link
+func DeleteShareHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := requestStore(r).DeleteRecord(share.Kind, id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		errorHandler(w, r, fmt.Sprintf("failed to delete share: %s", id), status, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
+
+// sharedReminders evaluates a share's query, returning the public view of
+// the matching reminders ordered by due date
+func sharedReminders(s storage.Storage, sh *share.Share, now time.Time) ([]sharedReminder, error) {
+	f, err := sh.Query.Filter(sh.FamilyID, now)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.QueryReminders(f)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].DueDate, list[j].DueDate
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Before(*b)
+	})
+	result := make([]sharedReminder, 0, len(list))
+	for _, rem := range list {
+		result = append(result, sharedReminder{
+			Title:       rem.Title,
+			Description: rem.Description,
+			DueDate:     rem.DueDate,
+			Assignee:    rem.FamilyMember,
+			Done:        agenda.IsDone(rem, now),
+		})
+	}
+	return result, nil
+}
+
+var sharedPage = template.Must(template.New("shared").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Name}}</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em">
+<h1>{{.Name}}</h1>
+{{if .Reminders}}<ul style="list-style: none; padding: 0">
+{{range .Reminders}}<li style="margin: 0.5em 0">{{if .Done}}&#9745; <s>{{.Title}}</s>{{else}}&#9744; {{.Title}}{{end}}{{if .DueDate}} &middot; {{.DueDate.Format "Mon Jan 2 15:04"}}{{end}}{{if .Assignee}} &middot; {{.Assignee}}{{end}}{{if .Description}}<br><small>{{.Description}}</small>{{end}}</li>
+{{end}}</ul>{{else}}<p>Nothing to do.</p>{{end}}
+</body>
+</html>
+`))
+
+// wantsHTML reports whether the client asked for a web page rather than
+// JSON, either with ?format=html or a browser Accept header
+func wantsHTML(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "html"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// SharedViewHandler handles GET /shared/{token}. It is unauthenticated: the
+// token is the credential. Browsers get a simple HTML page and other clients
+// JSON.
+func SharedViewHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	sh, err := share.Lookup(requestStore(r), mux.Vars(r)["token"], now)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, share.ErrNotFound) || errors.Is(err, share.ErrExpired) {
+			status = http.StatusNotFound
+		}
+		// The token is a credential, so it is deliberately left out of the log
+		log.Printf("%s /shared/ %s %d - %v", r.Method, r.UserAgent(), status, err)
+		if wantsHTML(r) {
+			renderLinkPage(w, status, "Link not valid", "This link is invalid, has expired or was revoked.")
+		} else {
+			http.Error(w, "share link is invalid, expired or revoked", status)
+		}
+		return
+	}
+	reminders, err := sharedReminders(requestStore(r), sh, now)
+	if err != nil {
+		log.Printf("%s /shared/ %s %d - failed to list reminders: %v", r.Method, r.UserAgent(), http.StatusInternalServerError, err)
+		http.Error(w, "failed to list reminders", http.StatusInternalServerError)
+		return
+	}
+	view := sharedView{Name: sh.Name, ExpiresAt: sh.ExpiresAt, Reminders: reminders}
+	w.Header().Set("Cache-Control", "no-store")
+	if wantsHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		sharedPage.Execute(w, view)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(view)
+	}
+	log.Printf("%s /shared/ %s %d", r.Method, r.UserAgent(), http.StatusOK)
+}
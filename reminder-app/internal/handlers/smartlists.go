@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// validateSmartList checks a smart list against its family
+func validateSmartList(s storage.Storage, sl *smartlist.SmartList) (string, error) {
+	if sl.Name == "" || sl.FamilyID == "" {
+		return "name and family_id are required", nil
+	}
+	f, err := s.GetFamily(sl.FamilyID)
+	if err != nil {
+		return fmt.Sprintf("family not found: %s", sl.FamilyID), err
+	}
+	if err := sl.Query.Validate(); err != nil {
+		return err.Error(), err
+	}
+	// The assignee may be set by the query's q as well as its own field
+	filter, _ := sl.Query.Filter(sl.FamilyID, time.Now())
+	if filter.FamilyMember != "" && !hasMember(f, filter.FamilyMember) {
+		return fmt.Sprintf("family member not found: %s", filter.FamilyMember), nil
+	}
+	return "", nil
+}
+
+// putSmartList saves a smart list as a storage record
+func putSmartList(s storage.Storage, sl *smartlist.SmartList) error {
+	return storage.PutJSON(s, storage.Record{Kind: smartlist.Kind, ID: sl.ID, FamilyID: sl.FamilyID}, sl)
+}
+
+// getSmartList loads a smart list, writing a 404 if it doesn't exist
+func getSmartList(w http.ResponseWriter, r *http.Request) *smartlist.SmartList {
+	id := mux.Vars(r)["id"]
+	sl, err := storage.GetJSON[smartlist.SmartList](requestStore(r), smartlist.Kind, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		errorHandler(w, r, fmt.Sprintf("smart list not found: %s", id), status, err)
+		return nil
+	}
+	return sl
+}
+
+// CreateSmartListHandler handles POST /smart-lists
+func CreateSmartListHandler(w http.ResponseWriter, r *http.Request) {
+	var sl smartlist.SmartList
+	if err := decodeJSON(r, r.Body, &sl); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if msg, err := validateSmartList(requestStore(r), &sl); msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	sl.ID = storage.NewRecordID("sl")
+	if err := putSmartList(requestStore(r), &sl); err != nil {
+		errorHandler(w, r, "failed to create smart list", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sl)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
+
+// ListSmartListsHandler handles GET /smart-lists?family_id=
+func ListSmartListsHandler(w http.ResponseWriter, r *http.Request) {
+	q := storage.RecordQuery{Kind: smartlist.Kind, FamilyID: r.URL.Query().Get("family_id")}
+	list, err := storage.ListJSON[smartlist.SmartList](requestStore(r), q)
+	if err != nil {
+		errorHandler(w, r, "failed to list smart lists", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// GetSmartListHandler handles GET /smart-lists/{id}
+func GetSmartListHandler(w http.ResponseWriter, r *http.Request) {
+	sl := getSmartList(w, r)
+	if sl == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sl)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// UpdateSmartListHandler handles PUT /smart-lists/{id}, replacing the name
+// and query. A smart list cannot move to another family.
+func UpdateSmartListHandler(w http.ResponseWriter, r *http.Request) {
+	existing := getSmartList(w, r)
+	if existing == nil {
+		return
+	}
+	var sl smartlist.SmartList
+	if err := decodeJSON(r, r.Body, &sl); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	sl.ID, sl.FamilyID = existing.ID, existing.FamilyID
+	if msg, err := validateSmartList(requestStore(r), &sl); msg != "" {
+		errorHandler(w, r, msg, http.StatusBadRequest, err)
+		return
+	}
+	if err := putSmartList(requestStore(r), &sl); err != nil {
+		errorHandler(w, r, "failed to update smart list", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sl)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DeleteSmartListHandler handles DELETE /smart-lists/{id}
+func DeleteSmartListHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := requestStore(r).DeleteRecord(smartlist.Kind, id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		errorHandler(w, r, fmt.Sprintf("failed to delete smart list: %s", id), status, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
+
+// SmartListRemindersHandler handles GET /smart-lists/{id}/reminders,
+// evaluating the saved query against the current time
+func SmartListRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	sl := getSmartList(w, r)
+	if sl == nil {
+		return
+	}
+	f, err := sl.Query.Filter(sl.FamilyID, time.Now())
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("invalid smart list query: %s", err), http.StatusUnprocessableEntity, err)
+		return
+	}
+	result, err := requestStore(r).QueryReminders(f)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
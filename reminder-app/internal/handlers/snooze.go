@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// snoozeRequest is the body accepted by POST /reminders/{id}/snooze
+type snoozeRequest struct {
+	// Duration is a Go duration such as "30m" or "2h"
+	Duration string `json:"duration"`
+}
+
+// SnoozeReminderHandler handles POST /reminders/{id}/snooze, postponing the
+// effective due date of a reminder by the requested duration. Snoozing a
+// reminder that is already overdue postpones it from now, and snoozing again
+// extends the snooze. The due date itself is kept, and completing the
+// reminder ends the snooze.
+func SnoozeReminderHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var before json.RawMessage
+	rem := editReminder(w, r, id, func(_ storage.Storage, rem *reminder.Reminder) bool {
+		var req snoozeRequest
+		if err := decodeJSON(r, r.Body, &req); err != nil {
+			errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+			return false
+		}
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			errorHandler(w, r, "invalid duration format", http.StatusBadRequest, err)
+			return false
+		}
+		if d <= 0 {
+			errorHandler(w, r, "duration must be positive", http.StatusBadRequest, nil)
+			return false
+		}
+
+		before = audit.Snapshot(rem)
+		if msg := snoozeReminder(rem, d, time.Now()); msg != "" {
+			errorHandler(w, r, msg, http.StatusConflict, nil)
+			return false
+		}
+		return true
+	})
+	if rem == nil {
+		return
+	}
+	Events.Publish(reminderSaved(rem, before, nil, requestActor(r), requestImpersonator(r)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rem)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// snoozeReminder postpones rem by d from its effective due date, or from now
+// if that has passed. It returns an error message if rem can't be snoozed.
+func snoozeReminder(rem *reminder.Reminder, d time.Duration, now time.Time) string {
+	if rem.DueDate == nil {
+		return fmt.Sprintf("reminder %s has no due date to snooze", rem.ID)
+	}
+	if rem.Completed {
+		return fmt.Sprintf("reminder %s is already completed", rem.ID)
+	}
+	from := now
+	if due := rem.EffectiveDueDate(); due.After(from) {
+		from = *due
+	}
+	until := from.Add(d)
+	rem.SnoozedUntil = &until
+	return ""
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"log"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"reminder-app/internal/i18n"
+)
+
+// StaticOptions configures StaticHandler
+type StaticOptions struct {
+	// MaxAge is how long browsers may cache assets without revalidating.
+	// HTML is always revalidated so new releases are picked up.
+	MaxAge time.Duration
+	// ImmutableAssets lets browsers cache fingerprinted files, such as
+	// app.3f9a2c1d.js, for a year without revalidating
+	ImmutableAssets bool
+	// DisableListing serves 404 for directories without an index.html
+	// instead of listing their contents
+	DisableListing bool
+	// RateLimit is the number of requests per minute allowed from each
+	// client address; zero disables the limit
+	RateLimit int
+	// Limiter, when set, enforces the limit instead of RateLimit, so the
+	// caller can change it while serving
+	Limiter *ClientLimiter
+	// Locales are the translations of the frontend. Pages requested without
+	// a language prefix are redirected to the caller's preferred
+	// translation when it has them.
+	Locales i18n.Locales
+}
+
+// immutableMaxAge is the lifetime of fingerprinted files
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// StaticHandler serves the frontend from dir. Files are served by
+// http.FileServer, which handles conditional and range requests.
+func StaticHandler(dir string, opts StaticOptions) http.Handler {
+	var root http.FileSystem = http.Dir(dir)
+	if opts.DisableListing {
+		root = noListingFS{root}
+	}
+	files := http.FileServer(root)
+	limiter := opts.Limiter
+	if limiter == nil && opts.RateLimit > 0 {
+		limiter = NewClientLimiter(opts.RateLimit)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limiter != nil {
+			if ok, wait := limiter.Allow(clientAddr(r), time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusTooManyRequests)
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+		}
+		if opts.Locales.Enabled() {
+			if navigation(r.URL.Path) {
+				if _, _, ok := opts.Locales.Prefix(r.URL.Path); !ok {
+					w.Header().Add("Vary", "Accept-Language, Cookie")
+					if target := localeRedirect(r, opts.Locales, root); target != "" {
+						w.Header().Set("Cache-Control", "no-cache")
+						log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusFound)
+						http.Redirect(w, r, target, http.StatusFound)
+						return
+					}
+				}
+			}
+			r = localeFallback(r, opts.Locales, root)
+		}
+		ext := filepath.Ext(r.URL.Path)
+		if ext != "" {
+			if ctype := mime.TypeByExtension(ext); ctype != "" {
+				w.Header().Set("Content-Type", ctype)
+			}
+		}
+		w.Header().Set("Cache-Control", cacheControl(r.URL.Path, opts))
+		if servePrecompressed(w, r, root) {
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// navigation reports whether a path names a page rather than an asset
+func navigation(p string) bool {
+	return strings.HasSuffix(p, "/") || path.Ext(p) == ".html"
+}
+
+// localeRedirect returns the translated page to send a request for an
+// unprefixed page to, or "" if the caller prefers the default language or
+// the page hasn't been translated into theirs
+func localeRedirect(r *http.Request, l i18n.Locales, root http.FileSystem) string {
+	loc := l.Preferred(r)
+	if loc == l.Default {
+		return ""
+	}
+	target := "/" + loc + r.URL.Path
+	name := target
+	if strings.HasSuffix(name, "/") {
+		name += "index.html"
+	}
+	f, err := root.Open(path.Clean(name))
+	if err != nil {
+		return ""
+	}
+	f.Close()
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	return target
+}
+
+// localeFallback serves files a translation doesn't have, such as shared
+// scripts and images, from the default tree
+func localeFallback(r *http.Request, l i18n.Locales, root http.FileSystem) *http.Request {
+	_, rest, ok := l.Prefix(r.URL.Path)
+	if !ok || strings.HasSuffix(r.URL.Path, "/") {
+		return r
+	}
+	if f, err := root.Open(path.Clean(r.URL.Path)); err == nil {
+		f.Close()
+		return r
+	}
+	fallback := r.Clone(r.Context())
+	fallback.URL.Path = rest
+	return fallback
+}
+
+// encodings are the pre-compressed variants looked for next to a static
+// file, in order of preference, with their file suffixes
+var encodings = []struct{ name, suffix string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// servePrecompressed serves the best pre-compressed variant of the requested
+// file that the client accepts, reporting whether it did. Responses for files
+// with variants vary by Accept-Encoding whichever representation is sent.
+func servePrecompressed(w http.ResponseWriter, r *http.Request, root http.FileSystem) bool {
+	name := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, "index.html")
+	}
+	for _, enc := range encodings {
+		f, err := root.Open(name + enc.suffix)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			continue
+		}
+		w.Header().Set("Vary", "Accept-Encoding")
+		if !acceptsEncoding(r.Header.Get("Accept-Encoding"), enc.name) {
+			continue
+		}
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+		w.Header().Set("Content-Encoding", enc.name)
+		http.ServeContent(w, r, name, info.ModTime(), f)
+		return true
+	}
+	return false
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows enc,
+// either by name or through "*", with a non-zero quality
+func acceptsEncoding(header, enc string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case enc:
+			return q > 0
+		case "*":
+			wildcard = q > 0
+		}
+	}
+	return wildcard
+}
+
+// compressible lists the extensions of text assets worth compressing
+var compressible = map[string]bool{
+	".html": true, ".css": true, ".js": true, ".mjs": true, ".json": true, ".map": true,
+	".svg": true, ".txt": true, ".xml": true, ".webmanifest": true, ".wasm": true,
+}
+
+// minCompressSize is the smallest file Precompress compresses; below it the
+// saving doesn't outweigh the extra request handling
+const minCompressSize = 1024
+
+// Precompress writes a gzip variant next to every compressible file in dir
+// that lacks an up-to-date one, so StaticHandler can serve it without
+// compressing per request. Brotli variants are produced by the frontend
+// build. It returns the number of files written.
+func Precompress(dir string) (int, error) {
+	n := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !compressible[filepath.Ext(p)] {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() < minCompressSize {
+			return err
+		}
+		if gz, err := os.Stat(p + ".gz"); err == nil && !gz.ModTime().Before(info.ModTime()) {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		if buf.Len() >= len(data) {
+			return nil
+		}
+		if err := os.WriteFile(p+".gz", buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// cacheControl returns the Cache-Control header for a static path
+func cacheControl(p string, opts StaticOptions) string {
+	ext := path.Ext(p)
+	switch {
+	case ext == "" || ext == ".html" || strings.HasSuffix(p, "/"):
+		return "no-cache"
+	case opts.ImmutableAssets && fingerprinted(p):
+		return "public, max-age=" + strconv.Itoa(int(immutableMaxAge.Seconds())) + ", immutable"
+	case opts.MaxAge > 0:
+		return "public, max-age=" + strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return "no-cache"
+}
+
+// fingerprinted reports whether a file name carries a content hash as
+// bundlers write them: a segment of at least 8 letters, digits or
+// underscores, including a digit, just before the extension, as in
+// app.3f9a2c1d.js or index-BxZ3k9aQ.css
+func fingerprinted(p string) bool {
+	name := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	i := strings.LastIndexAny(name, ".-")
+	if i < 0 {
+		return false
+	}
+	hash := name[i+1:]
+	if len(hash) < 8 || !strings.ContainsAny(hash, "0123456789") {
+		return false
+	}
+	for _, c := range hash {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// noListingFS hides directories that have no index.html, so FileServer
+// answers 404 rather than listing them
+type noListingFS struct {
+	fs http.FileSystem
+}
+
+func (n noListingFS) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		index, err := n.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			f.Close()
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fs.ErrNotExist
+			}
+			return nil, err
+		}
+		index.Close()
+	}
+	return f, nil
+}
+
+// clientAddr returns the IP address of the client. Forwarding headers are
+// ignored since any client can set them.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ClientLimiter allows each client a number of requests per minute with a
+// token bucket that refills continuously. Buckets that have refilled are
+// dropped, so memory is bounded by the number of recently active clients.
+type ClientLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	buckets map[string]*clientBucket
+	pruned  time.Time
+}
+
+type clientBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewClientLimiter creates a limiter; a limit of zero allows everything
+func NewClientLimiter(perMinute int) *ClientLimiter {
+	return &ClientLimiter{limit: perMinute, buckets: make(map[string]*clientBucket)}
+}
+
+// SetLimit changes the requests per minute allowed from each client.
+// Clients keep their buckets, capped at the new limit.
+func (l *ClientLimiter) SetLimit(perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = perMinute
+}
+
+// Allow takes a token from the client's bucket. When the bucket is empty it
+// returns false and how long until the next token is available.
+func (l *ClientLimiter) Allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit <= 0 {
+		return true, 0
+	}
+	perToken := float64(time.Minute) / float64(l.limit)
+	if now.Sub(l.pruned) > time.Minute {
+		for c, b := range l.buckets {
+			if now.Sub(b.last) >= time.Minute {
+				delete(l.buckets, c)
+			}
+		}
+		l.pruned = now
+	}
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &clientBucket{tokens: float64(l.limit), last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(float64(l.limit), b.tokens+float64(now.Sub(b.last))/perToken)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration(math.Ceil((1 - b.tokens) * perToken))
+	}
+	b.tokens--
+	return true, 0
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/events"
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// reminderStatus returns the effective workflow status of r
+func reminderStatus(r *reminder.Reminder, wf fam.Workflow) string {
+	if r.Completed {
+		return fam.StatusDone
+	}
+	if r.Status == "" {
+		return wf.Initial()
+	}
+	return r.Status
+}
+
+// statusRequest is the body accepted by POST /reminders/{id}/status
+type statusRequest struct {
+	Status string `json:"status"`
+}
+
+// SetReminderStatusHandler handles POST /reminders/{id}/status, moving a
+// reminder to another status in its family's workflow. Moving to done
+// completes the reminder exactly like PATCH does, including the duplicate
+// check that ?force=true overrides; moving out of done reopens it.
+func SetReminderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	actor := requestActor(r)
+	now := time.Now()
+	var (
+		req        statusRequest
+		from       string
+		before     json.RawMessage
+		completion *reminder.CompletionEvent
+		reopened   []*reminder.CompletionEvent
+	)
+	rem := editReminder(w, r, id, func(s storage.Storage, rem *reminder.Reminder) bool {
+		if err := decodeJSON(r, r.Body, &req); err != nil {
+			errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+			return false
+		}
+		f, err := s.GetFamily(rem.FamilyID)
+		if err != nil {
+			errorHandler(w, r, fmt.Sprintf("family not found: %s", rem.FamilyID), http.StatusInternalServerError, err)
+			return false
+		}
+		wf := f.Workflow()
+		if !wf.Has(req.Status) {
+			errorHandler(w, r, fmt.Sprintf("unknown status: %q", req.Status), http.StatusBadRequest, nil)
+			return false
+		}
+		from = reminderStatus(rem, wf)
+		if from == req.Status {
+			errorHandler(w, r, fmt.Sprintf("reminder %s is already %s", id, from), http.StatusConflict, nil)
+			return false
+		}
+		if !wf.Allows(from, req.Status) {
+			errorHandler(w, r, fmt.Sprintf("cannot move reminder from %s to %s", from, req.Status), http.StatusConflict, nil)
+			return false
+		}
+
+		before = audit.Snapshot(rem)
+		switch {
+		case req.Status == fam.StatusDone:
+			if r.URL.Query().Get("force") != "true" {
+				existing, err := existingCompletion(s, rem, now)
+				if err != nil {
+					errorHandler(w, r, "failed to check completion events", http.StatusInternalServerError, err)
+					return false
+				}
+				if existing != nil {
+					errorHandler(w, r, fmt.Sprintf("reminder already completed for this occurrence by %s (event %s); use ?force=true to record another completion", existing.CompletedBy, existing.ID), http.StatusConflict, nil)
+					return false
+				}
+			}
+			by := actor
+			if by == "" {
+				by = rem.FamilyMember
+			}
+			var err error
+			if completion, err = completeReminder(s, rem, by, requestImpersonator(r), now); errors.Is(err, errSameConfirmer) {
+				errorHandler(w, r, err.Error(), http.StatusConflict, nil)
+				return false
+			} else if err != nil {
+				errorHandler(w, r, "failed to create completion event", http.StatusInternalServerError, err)
+				return false
+			}
+		case from == fam.StatusDone:
+			var err error
+			if reopened, err = reopenReminder(s, rem); err != nil {
+				errorHandler(w, r, "failed to delete completion events", http.StatusInternalServerError, err)
+				return false
+			}
+			rem.Status = req.Status
+		default:
+			rem.Status = req.Status
+		}
+		return true
+	})
+	if rem == nil {
+		return
+	}
+	Events.Publish(reminderSaved(rem, before, completion, actor, requestImpersonator(r)))
+	for _, e := range reopened {
+		Events.Publish(events.CompletionDeleted{Reminder: rem, Completion: e, Actor: actor})
+	}
+	e := reminder.StatusEvent{
+		ID:         storage.NewRecordID("sev"),
+		ReminderID: rem.ID,
+		From:       from,
+		To:         req.Status,
+		ChangedBy:  actor,
+		ChangedAt:  now,
+	}
+	rec := storage.Record{Kind: reminder.StatusEventKind, ID: e.ID, FamilyID: rem.FamilyID, Ref: rem.ID, CreatedAt: now}
+	if err := storage.PutJSON(requestStore(r), rec, e); err != nil {
+		log.Printf("failed to record status event for reminder %s: %v", rem.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rem)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// ListStatusEventsHandler handles GET /reminders/{id}/status-events, oldest
+// first
+func ListStatusEventsHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	events, err := storage.ListJSON[reminder.StatusEvent](requestStore(r), storage.RecordQuery{Kind: reminder.StatusEventKind, Ref: id})
+	if err != nil {
+		errorHandler(w, r, "failed to list status events", http.StatusInternalServerError, err)
+		return
+	}
+	if events == nil {
+		events = []*reminder.StatusEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/events"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/suggest"
+
+	"github.com/gorilla/mux"
+)
+
+// ListSuggestionsHandler handles GET /families/{id}/suggestions. Only open
+// suggestions are returned unless ?status= asks for another status or "all".
+func ListSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = suggest.StatusOpen
+	}
+	list, err := storage.ListJSON[suggest.Suggestion](requestStore(r), storage.RecordQuery{Kind: suggest.Kind, FamilyID: id})
+	if err != nil {
+		errorHandler(w, r, "failed to list suggestions", http.StatusInternalServerError, err)
+		return
+	}
+	result := make([]*suggest.Suggestion, 0, len(list))
+	for _, s := range list {
+		if status == "all" || s.Status == status {
+			result = append(result, s)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// getOpenSuggestion loads a suggestion that hasn't been acted on yet,
+// writing an error response if there is none
+func getOpenSuggestion(w http.ResponseWriter, r *http.Request) *suggest.Suggestion {
+	id := mux.Vars(r)["id"]
+	s, err := storage.GetJSON[suggest.Suggestion](requestStore(r), suggest.Kind, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		errorHandler(w, r, fmt.Sprintf("suggestion not found: %s", id), status, err)
+		return nil
+	}
+	if s.Status != suggest.StatusOpen {
+		errorHandler(w, r, fmt.Sprintf("suggestion %s is already %s", id, s.Status), http.StatusConflict, nil)
+		return nil
+	}
+	return s
+}
+
+// AcceptSuggestionHandler handles POST /suggestions/{id}/accept, applying
+// the proposed recurrence to the reminder
+func AcceptSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	s := getOpenSuggestion(w, r)
+	if s == nil {
+		return
+	}
+	var before json.RawMessage
+	rem := editReminder(w, r, s.ReminderID, func(_ storage.Storage, rem *reminder.Reminder) bool {
+		before = audit.Snapshot(rem)
+		rem.Recurrence = s.Proposed
+		return true
+	})
+	if rem == nil {
+		return
+	}
+	Events.Publish(events.ReminderUpdated{Reminder: rem, Before: before, Actor: requestActor(r), Impersonator: requestImpersonator(r)})
+	s.Status = suggest.StatusAccepted
+	if err := suggest.Put(requestStore(r), s); err != nil {
+		errorHandler(w, r, "failed to update suggestion", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rem)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// DismissSuggestionHandler handles POST /suggestions/{id}/dismiss. The same
+// proposal won't be suggested again for the reminder.
+func DismissSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	s := getOpenSuggestion(w, r)
+	if s == nil {
+		return
+	}
+	s.Status = suggest.StatusDismissed
+	if err := suggest.Put(requestStore(r), s); err != nil {
+		errorHandler(w, r, "failed to update suggestion", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// ListTagsHandler handles GET /tags?family_id=, returning the tags in use
+// on the family's reminders with how many reminders carry each, most used
+// first. Guests only count the reminders in their scope.
+func ListTagsHandler(w http.ResponseWriter, r *http.Request) {
+	f := storage.ReminderFilter{FamilyID: r.URL.Query().Get("family_id")}
+	g := requestGuest(r)
+	if g != nil {
+		f.FamilyID = g.FamilyID
+	}
+	if f.FamilyID == "" {
+		errorHandler(w, r, "family_id is required", http.StatusBadRequest, nil)
+		return
+	}
+	list, err := queryReminders(r, f)
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	if g != nil {
+		list = g.Filter(list)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reminder.CountTags(list))
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+var (
+	// RequestTimeout is the deadline given to each request; zero leaves
+	// requests without one. Storage calls made for a request give up when
+	// it passes, and the request is answered with 504.
+	RequestTimeout = 30 * time.Second
+
+	// Timeouts overrides RequestTimeout for endpoints keyed by method and
+	// route template like Policies. Zero exempts an endpoint, such as the
+	// long-lived live updates connection.
+	Timeouts = map[string]time.Duration{
+		"GET /ws":                    0,
+		"GET /admin/backup.sqlite":   0,
+		"POST /admin/fsck":           0,
+		"POST /admin/restore-points": 0,
+	}
+)
+
+// timeoutUsage counts the requests to one endpoint that ran out of time
+type timeoutUsage struct {
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+var (
+	timeoutMu     sync.Mutex
+	timedOutCalls = map[string]*timeoutUsage{}
+)
+
+// requestTimeout returns the deadline budget of the endpoint r matched
+func requestTimeout(r *http.Request) time.Duration {
+	if d, ok := Timeouts[routeKey(r)]; ok {
+		return d
+	}
+	return RequestTimeout
+}
+
+// TimeoutMiddleware gives each request a context deadline from
+// RequestTimeout and Timeouts. It must run after routing and before the
+// middleware and handlers that use storage.
+func TimeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := requestTimeout(r)
+		if d <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestStore returns Store bound to the context of r, so the storage calls
+// made for a request are cancelled when its deadline passes
+func requestStore(r *http.Request) storage.Storage {
+	return storage.WithContext(Store, r.Context())
+}
+
+// timedOut reports whether err comes from r running out of time, counting
+// the timeout if so
+func timedOut(r *http.Request, err error) bool {
+	if err == nil || r.Context().Err() != context.DeadlineExceeded || !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	key := routeKey(r)
+	timeoutMu.Lock()
+	defer timeoutMu.Unlock()
+	u := timedOutCalls[key]
+	if u == nil {
+		u = &timeoutUsage{}
+		timedOutCalls[key] = u
+	}
+	u.Count++
+	u.LastSeen = time.Now()
+	return true
+}
+
+// timeoutReport is one entry of GET /admin/timeouts
+type timeoutReport struct {
+	Endpoint string `json:"endpoint"`
+	Timeout  string `json:"timeout"`
+	timeoutUsage
+}
+
+// TimeoutsHandler handles GET /admin/timeouts, listing the endpoints whose
+// requests ran out of time since the server started and how often
+func TimeoutsHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	timeoutMu.Lock()
+	list := make([]timeoutReport, 0, len(timedOutCalls))
+	for key, u := range timedOutCalls {
+		d, ok := Timeouts[key]
+		if !ok {
+			d = RequestTimeout
+		}
+		list = append(list, timeoutReport{Endpoint: key, Timeout: d.String(), timeoutUsage: *u})
+	}
+	timeoutMu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Endpoint < list[j].Endpoint })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
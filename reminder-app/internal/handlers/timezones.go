@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/tzdb"
+)
+
+// timezonesResponse is the body of GET /meta/timezones
+type timezonesResponse struct {
+	// Source is the time zone database the zones were read from
+	Source string      `json:"source"`
+	Zones  []tzdb.Zone `json:"zones"`
+}
+
+// TimezonesHandler handles GET /meta/timezones, listing the IANA time zones
+// the server can load, and so accepts in family, member and reminder
+// settings, with their current offsets from UTC
+func TimezonesHandler(w http.ResponseWriter, r *http.Request) {
+	db, err := tzdb.Load()
+	if err != nil {
+		errorHandler(w, r, "time zone database not available", http.StatusServiceUnavailable, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timezonesResponse{Source: db.Source, Zones: db.Zones(time.Now())})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
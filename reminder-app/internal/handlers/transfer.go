@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/events"
+	"reminder-app/internal/transfer"
+
+	"github.com/gorilla/mux"
+)
+
+// PackageKey signs and verifies family export packages. Instances that move
+// families between each other must share it. Nil disables the feature.
+var PackageKey []byte
+
+// PassphraseHeader carries the passphrase an export package is encrypted
+// with, or an imported one was. Packages exported without it are only
+// signed.
+const PassphraseHeader = "X-Package-Passphrase"
+
+// maxImportSize bounds the size of an uploaded package
+const maxImportSize = 64 << 20
+
+// ExportPackageHandler handles POST /families/{id}/export-package, returning
+// the family as a signed archive for POST /families/import-package on
+// another instance. The archive is encrypted if a passphrase is given in
+// PassphraseHeader.
+func ExportPackageHandler(w http.ResponseWriter, r *http.Request) {
+	if PackageKey == nil {
+		errorHandler(w, r, "export packages are not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	passphrase := r.Header.Get(PassphraseHeader)
+	if passphrase != "" {
+		if err := transfer.ValidatePassphrase(passphrase); err != nil {
+			errorHandler(w, r, err.Error(), http.StatusBadRequest, err)
+			return
+		}
+	}
+	id := mux.Vars(r)["id"]
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	p, err := transfer.Build(requestStore(r), id, time.Now())
+	if err != nil {
+		errorHandler(w, r, "failed to export family", http.StatusInternalServerError, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := transfer.Write(&buf, p, PackageKey, passphrase); err != nil {
+		errorHandler(w, r, "failed to write package", http.StatusInternalServerError, err)
+		return
+	}
+	contentType, filename := "application/gzip", "family-"+id+".tar.gz"
+	if passphrase != "" {
+		contentType, filename = "application/octet-stream", filename+".enc"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(buf.Bytes())
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// ImportPackageHandler handles POST /families/import-package. The family is
+// created under new IDs and returned. Encrypted packages need their
+// passphrase in PassphraseHeader.
+func ImportPackageHandler(w http.ResponseWriter, r *http.Request) {
+	if PackageKey == nil {
+		errorHandler(w, r, "export packages are not configured", http.StatusNotImplemented, nil)
+		return
+	}
+	p, err := transfer.Read(http.MaxBytesReader(w, r.Body, maxImportSize), PackageKey, r.Header.Get(PassphraseHeader))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, transfer.ErrSignature) || errors.Is(err, transfer.ErrPassphrase) {
+			status = http.StatusForbidden
+		}
+		errorHandler(w, r, fmt.Sprintf("invalid package: %v", err), status, err)
+		return
+	}
+	f, err := transfer.Import(requestStore(r), p)
+	if err != nil {
+		errorHandler(w, r, "failed to import family", http.StatusInternalServerError, err)
+		return
+	}
+	// The family is new, so nobody can be following its reminders yet
+	Events.Publish(events.FamilyCreated{Family: f, Actor: requestActor(r)})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(f)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/events"
+	fam "reminder-app/internal/family"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// TrashRetention is how long deleted reminders and families stay in the
+// trash before the scheduler purges them
+var TrashRetention = 30 * 24 * time.Hour
+
+// permanentParam parses the ?permanent= parameter of deletes, which skips
+// the trash, writing an error response if it's invalid
+func permanentParam(w http.ResponseWriter, r *http.Request) (permanent, ok bool) {
+	return boolParam(w, r, "permanent")
+}
+
+// ListTrashHandler handles GET /trash?family_id=, listing the family's
+// deleted reminders and families oldest first
+func ListTrashHandler(w http.ResponseWriter, r *http.Request) {
+	familyID := r.URL.Query().Get("family_id")
+	if familyID == "" {
+		errorHandler(w, r, "family_id is required", http.StatusBadRequest, nil)
+		return
+	}
+	list, err := storage.ListTrash(requestStore(r), familyID)
+	if err != nil {
+		errorHandler(w, r, "failed to list trash", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// restoreError writes the error response for a failed restore
+func restoreError(w http.ResponseWriter, r *http.Request, id string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrRecordNotFound):
+		errorHandler(w, r, fmt.Sprintf("not in the trash: %s", id), http.StatusNotFound, err)
+	case errors.Is(err, storage.ErrTrashedFamily):
+		errorHandler(w, r, "restore the family first", http.StatusConflict, err)
+	default:
+		errorHandler(w, r, "failed to restore", http.StatusInternalServerError, err)
+	}
+}
+
+// RestoreReminderHandler handles POST /reminders/{id}/restore, putting a
+// deleted reminder back with its completion history
+func RestoreReminderHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	t, err := storage.GetJSON[storage.Trashed](requestStore(r), storage.TrashKind, id)
+	if err == nil && t.Reminder == nil {
+		err = storage.ErrRecordNotFound
+	}
+	if err == nil {
+		t, err = storage.Restore(requestStore(r), id)
+	}
+	if err != nil {
+		restoreError(w, r, id, err)
+		return
+	}
+	Events.Publish(events.ReminderCreated{Reminder: t.Reminder, Actor: requestActor(r), Impersonator: requestImpersonator(r)})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.Reminder)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// RestoreFamilyHandler handles POST /families/{id}/restore, putting a
+// deleted family back along with the reminders deleted with it. The family
+// doesn't exist until then, so the caller is checked against the family as
+// it was deleted rather than through Policies.
+func RestoreFamilyHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	t, err := storage.GetJSON[storage.Trashed](requestStore(r), storage.TrashKind, id)
+	if err == nil && t.Family == nil {
+		err = storage.ErrRecordNotFound
+	}
+	if err != nil {
+		restoreError(w, r, id, err)
+		return
+	}
+	if !authorize(w, r, t.Family, fam.PermManage, nil) {
+		return
+	}
+	if t, err = storage.Restore(requestStore(r), id); err != nil {
+		restoreError(w, r, id, err)
+		return
+	}
+	Events.Publish(events.FamilyCreated{Family: t.Family, Actor: requestActor(r)})
+	for _, rem := range t.Reminders {
+		Events.Publish(events.ReminderCreated{Reminder: rem, Actor: requestActor(r), Impersonator: requestImpersonator(r)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.Family)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// usageRequest is the body accepted by POST /reminders/{id}/usage
+type usageRequest struct {
+	// Value is the counter's current reading, such as the odometer
+	Value float64 `json:"value"`
+}
+
+// ReportUsageHandler handles POST /reminders/{id}/usage, recording the
+// current reading of a usage-based reminder's counter. The reading that
+// reaches the reminder's threshold makes it fall due, which notifies the
+// assignee like a due date would. Counters only go up; a lower reading than
+// the last one is rejected, and a new counter, such as after replacing a
+// meter, is set up by editing the reminder's usage.
+func ReportUsageHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	now := time.Now()
+	var (
+		req       usageRequest
+		before    json.RawMessage
+		triggered bool
+	)
+	rem := editReminder(w, r, id, func(_ storage.Storage, rem *reminder.Reminder) bool {
+		if rem.Usage == nil {
+			errorHandler(w, r, fmt.Sprintf("reminder %s isn't usage-based", id), http.StatusConflict, nil)
+			return false
+		}
+		if err := decodeJSON(r, r.Body, &req); err != nil {
+			errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+			return false
+		}
+		if math.IsNaN(req.Value) || math.IsInf(req.Value, 0) || req.Value < rem.Usage.Reading {
+			errorHandler(w, r, fmt.Sprintf("value must not be less than the last reading of %v", rem.Usage.Reading), http.StatusBadRequest, nil)
+			return false
+		}
+		before = audit.Snapshot(rem)
+		triggered = rem.RecordUsage(req.Value, now)
+		return true
+	})
+	if rem == nil {
+		return
+	}
+	Events.Publish(reminderSaved(rem, before, nil, requestActor(r), requestImpersonator(r)))
+	reading := reminder.UsageReading{
+		ID:         storage.NewRecordID("use"),
+		ReminderID: rem.ID,
+		Counter:    rem.Usage.Counter,
+		Value:      req.Value,
+		ReportedBy: requestActor(r),
+		ReportedAt: now,
+		Triggered:  triggered,
+	}
+	rec := storage.Record{Kind: reminder.UsageReadingKind, ID: reading.ID, FamilyID: rem.FamilyID, Ref: rem.ID, CreatedAt: now}
+	if err := storage.PutJSON(requestStore(r), rec, reading); err != nil {
+		log.Printf("failed to record usage reading for reminder %s: %v", rem.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rem)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// ListUsageHandler handles GET /reminders/{id}/usage, listing the readings
+// reported for a reminder oldest first
+func ListUsageHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	readings, err := storage.ListJSON[reminder.UsageReading](requestStore(r), storage.RecordQuery{Kind: reminder.UsageReadingKind, Ref: id})
+	if err != nil {
+		errorHandler(w, r, "failed to list usage readings", http.StatusInternalServerError, err)
+		return
+	}
+	if readings == nil {
+		readings = []*reminder.UsageReading{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(readings)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
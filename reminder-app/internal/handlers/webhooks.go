@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"reminder-app/internal/events"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/webhook"
+
+	"github.com/gorilla/mux"
+)
+
+// Webhooks delivers events to the webhooks families register. Events are
+// not delivered while it is nil.
+var Webhooks *webhook.Dispatcher
+
+// webhookEvents are the event types webhooks can subscribe to
+var webhookEvents = []string{
+	"reminder.created", "reminder.updated", "reminder.completed", "reminder.deleted",
+	"completion.recorded", "completion.deleted",
+}
+
+// webhookData is the data of an event delivered to a webhook
+type webhookData struct {
+	// Reminder is the reminder after the change, or as it was when deleted
+	Reminder        *reminder.Reminder        `json:"reminder"`
+	CompletionEvent *reminder.CompletionEvent `json:"completion_event,omitempty"`
+	Actor           string                    `json:"actor,omitempty"`
+}
+
+// webhookEvent queues reminder events for the family's webhooks
+func webhookEvent(e events.Event) {
+	if Webhooks == nil {
+		return
+	}
+	var data webhookData
+	switch e := e.(type) {
+	case events.ReminderCreated:
+		data = webhookData{Reminder: e.Reminder, Actor: e.Actor}
+	case events.ReminderUpdated:
+		data = webhookData{Reminder: e.Reminder, Actor: e.Actor}
+	case events.ReminderCompleted:
+		data = webhookData{Reminder: e.Reminder, CompletionEvent: e.Completion, Actor: e.Actor}
+	case events.ReminderDeleted:
+		data = webhookData{Reminder: e.Reminder, Actor: e.Actor}
+	case events.CompletionRecorded:
+		data = webhookData{Reminder: e.Reminder, CompletionEvent: e.Completion, Actor: e.Completion.CompletedBy}
+	case events.CompletionDeleted:
+		data = webhookData{Reminder: e.Reminder, CompletionEvent: e.Completion, Actor: e.Actor}
+	default:
+		return
+	}
+	if err := Webhooks.Enqueue(e.FamilyID(), e.Type(), data, time.Now()); err != nil {
+		log.Printf("Failed to queue %s for webhooks of %s: %v", e.Type(), e.FamilyID(), err)
+	}
+}
+
+// webhookRequest is the body accepted by POST /families/{id}/webhooks
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+}
+
+// webhookResponse is a newly created webhook with its signing secret
+type webhookResponse struct {
+	*webhook.Webhook
+	Secret string `json:"secret"`
+}
+
+// CreateWebhookHandler handles POST /families/{id}/webhooks, registering a
+// URL to receive the family's reminder events. The secret deliveries are
+// signed with is only returned here.
+func CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := requestStore(r).GetFamily(id); err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	var req webhookRequest
+	if err := decodeJSON(r, r.Body, &req); err != nil {
+		errorHandler(w, r, "invalid JSON", http.StatusBadRequest, err)
+		return
+	}
+	if err := webhook.ValidateURL(req.URL); err != nil {
+		errorHandler(w, r, "url must be an absolute http or https URL", http.StatusBadRequest, err)
+		return
+	}
+	for _, typ := range req.Events {
+		if !slices.Contains(webhookEvents, typ) {
+			errorHandler(w, r, fmt.Sprintf("unknown event type: %q", typ), http.StatusBadRequest, nil)
+			return
+		}
+	}
+	wh := &webhook.Webhook{FamilyID: id, URL: req.URL, Events: req.Events}
+	secret, err := webhook.Create(requestStore(r), wh, time.Now())
+	if err != nil {
+		errorHandler(w, r, "failed to create webhook", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhookResponse{Webhook: wh, Secret: secret})
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusCreated)
+}
+
+// ListWebhooksHandler handles GET /families/{id}/webhooks. Secrets are not
+// included.
+func ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	list, err := webhook.List(requestStore(r), id)
+	if err != nil {
+		errorHandler(w, r, "failed to list webhooks", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
+
+// familyWebhook looks up the webhook named by the request and checks that it
+// belongs to the family in its path, reporting an error if not
+func familyWebhook(w http.ResponseWriter, r *http.Request) (*webhook.Webhook, bool) {
+	vars := mux.Vars(r)
+	wh, err := webhook.Get(requestStore(r), vars["webhook"])
+	if err == nil && wh.FamilyID != vars["id"] {
+		err = webhook.ErrNotFound
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, webhook.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		errorHandler(w, r, fmt.Sprintf("webhook not found: %s", vars["webhook"]), status, err)
+		return nil, false
+	}
+	return wh, true
+}
+
+// DeleteWebhookHandler handles DELETE /families/{id}/webhooks/{webhook}.
+// Deliveries still being retried are abandoned.
+func DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	wh, ok := familyWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := webhook.Delete(requestStore(r), wh.ID); err != nil {
+		errorHandler(w, r, fmt.Sprintf("failed to delete webhook: %s", wh.ID), http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusNoContent)
+}
+
+// WebhookDeliveriesHandler handles
+// GET /families/{id}/webhooks/{webhook}/deliveries, listing the webhook's
+// deliveries of the last 30 days, newest first, with the outcome of their
+// latest attempt
+func WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	wh, ok := familyWebhook(w, r)
+	if !ok {
+		return
+	}
+	list, err := webhook.Deliveries(requestStore(r), wh.ID)
+	if err != nil {
+		errorHandler(w, r, "failed to list deliveries", http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
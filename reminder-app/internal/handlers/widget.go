@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/share"
+	"reminder-app/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// widgetItem is one occurrence shown on the widget
+type widgetItem struct {
+	Title    string `json:"title"`
+	Time     string `json:"time"`
+	Assignee string `json:"assignee"`
+	Done     bool   `json:"done"`
+}
+
+// widgetData is the JSON form of the widget, which the embedded script
+// polls to refresh itself
+type widgetData struct {
+	Name  string       `json:"name"`
+	Date  string       `json:"date"`
+	Items []widgetItem `json:"items"`
+}
+
+// widgetRefresh is how often the embedded widget reloads its data
+const widgetRefresh = 5 * time.Minute
+
+var widgetPage = template.Must(template.New("widget").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Data.Name}}</title>
+<style>
+body { margin: 0; padding: 0.5em; font-family: sans-serif; background: transparent; color: {{if eq .Theme "light"}}#222{{else}}#eee{{end}}; }
+h1 { font-size: 1.1em; margin: 0 0 0.4em; }
+ul { list-style: none; margin: 0; padding: 0; }
+li { padding: 0.15em 0; }
+li.done { opacity: 0.5; text-decoration: line-through; }
+.time { display: inline-block; min-width: 3.5em; opacity: 0.7; }
+.who { opacity: 0.7; }
+</style>
+</head>
+<body>
+<h1 id="name">{{.Data.Name}}</h1>
+<ul id="items">{{range .Data.Items}}<li{{if .Done}} class="done"{{end}}><span class="time">{{.Time}}</span> {{.Title}}{{if .Assignee}} <span class="who">({{.Assignee}})</span>{{end}}</li>{{else}}<li>Nothing due today</li>{{end}}</ul>
+<script>
+(function () {
+  function render(data) {
+    var list = document.getElementById("items");
+    list.textContent = "";
+    if (!data.items || data.items.length === 0) {
+      var empty = document.createElement("li");
+      empty.textContent = "Nothing due today";
+      list.appendChild(empty);
+      return;
+    }
+    data.items.forEach(function (it) {
+      var li = document.createElement("li");
+      if (it.done) li.className = "done";
+      var time = document.createElement("span");
+      time.className = "time";
+      time.textContent = it.time;
+      li.appendChild(time);
+      li.appendChild(document.createTextNode(" " + it.title));
+      if (it.assignee) {
+        var who = document.createElement("span");
+        who.className = "who";
+        who.textContent = " (" + it.assignee + ")";
+        li.appendChild(who);
+      }
+      list.appendChild(li);
+    });
+  }
+  setInterval(function () {
+    fetch(location.pathname + "?format=json", {cache: "no-store"})
+      .then(function (r) { return r.ok ? r.json() : null; })
+      .then(function (data) { if (data) render(data); })
+      .catch(function () {});
+  }, {{.RefreshMillis}});
+})();
+</script>
+</body>
+</html>
+`))
+
+// widgetItems returns today's occurrences of the reminders a share exposes,
+// in the family's time zone
+func widgetItems(s storage.Storage, sh *share.Share, now time.Time) (*widgetData, error) {
+	f, err := s.GetFamily(sh.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := f.LocationFor(sh.Query.Assignee)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.QueryReminders(storage.ReminderFilter{FamilyID: sh.FamilyID})
+	if err != nil {
+		return nil, err
+	}
+	today := now.In(loc)
+	data := &widgetData{Name: sh.Name, Date: today.Format("2006-01-02"), Items: []widgetItem{}}
+	for _, it := range agenda.ForDay(list, today, sh.FamilyID, sh.Query.Assignee) {
+		data.Items = append(data.Items, widgetItem{
+			Title:    it.Reminder.Title,
+			Time:     it.At.In(loc).Format("15:04"),
+			Assignee: it.Reminder.FamilyMember,
+			Done:     it.Done,
+		})
+	}
+	return data, nil
+}
+
+// WidgetHandler handles GET /widget/{token}, a self-contained page of
+// today's reminders for embedding in dashboards such as DAKboard or
+// MagicMirror. It uses share link tokens, and shows the occurrences due
+// today for the share's assignee (or the whole family). The page refreshes
+// itself; ?format=json returns the data alone and ?theme=light switches to
+// dark text for light backgrounds.
+func WidgetHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	sh, err := share.Lookup(requestStore(r), mux.Vars(r)["token"], now)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, share.ErrNotFound) || errors.Is(err, share.ErrExpired) {
+			status = http.StatusNotFound
+		}
+		// The token is a credential, so it is deliberately left out of the log
+		log.Printf("%s /widget/ %s %d - %v", r.Method, r.UserAgent(), status, err)
+		http.Error(w, "widget link is invalid, expired or revoked", status)
+		return
+	}
+	data, err := widgetItems(requestStore(r), sh, now)
+	if err != nil {
+		log.Printf("%s /widget/ %s %d - %v", r.Method, r.UserAgent(), http.StatusInternalServerError, err)
+		http.Error(w, "failed to load reminders", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+	} else {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		widgetPage.Execute(w, map[string]any{
+			"Data":          data,
+			"Theme":         r.URL.Query().Get("theme"),
+			"RefreshMillis": widgetRefresh.Milliseconds(),
+		})
+	}
+	log.Printf("%s /widget/ %s %d", r.Method, r.UserAgent(), http.StatusOK)
+}
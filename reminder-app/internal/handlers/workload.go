@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/agenda"
+
+	"github.com/gorilla/mux"
+)
+
+// workloadReport is the response of GET /families/{id}/workload
+type workloadReport struct {
+	FamilyID     string        `json:"family_id"`
+	Week         string        `json:"week"`
+	From         time.Time     `json:"from"`
+	To           time.Time     `json:"to"`
+	TotalMinutes int           `json:"total_minutes"`
+	Members      []agenda.Load `json:"members"`
+}
+
+// FamilyWorkloadHandler handles GET /families/{id}/workload?week=, summing
+// the estimated effort of every occurrence due in the week per member. The
+// week is an ISO week ("2025-W10") or any date within it, in the family's
+// time zone, and defaults to the current week.
+func FamilyWorkloadHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	f, err := requestStore(r).GetFamily(id)
+	if err != nil {
+		errorHandler(w, r, fmt.Sprintf("family not found: %s", id), http.StatusNotFound, err)
+		return
+	}
+	loc, err := f.LocationFor("")
+	if err != nil {
+		errorHandler(w, r, "invalid family time zone", http.StatusInternalServerError, err)
+		return
+	}
+	from := agenda.WeekStart(time.Now().In(loc))
+	if week := r.URL.Query().Get("week"); week != "" {
+		if from, err = agenda.ParseWeek(week, loc); err != nil {
+			errorHandler(w, r, err.Error(), http.StatusBadRequest, err)
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 7)
+
+	list, err := requestStore(r).ListReminders()
+	if err != nil {
+		errorHandler(w, r, "failed to list reminders", http.StatusInternalServerError, err)
+		return
+	}
+	year, week := from.ISOWeek()
+	report := workloadReport{
+		FamilyID: f.ID,
+		Week:     fmt.Sprintf("%d-W%02d", year, week),
+		From:     from,
+		To:       to,
+		Members:  agenda.Workload(list, from, to, f.ID, f.Members),
+	}
+	for _, l := range report.Members {
+		report.TotalMinutes += l.Minutes
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+	log.Printf("%s %s %s %d", r.Method, r.URL.Path, r.UserAgent(), http.StatusOK)
+}
@@ -0,0 +1,135 @@
+// Package hook implements inbound webhooks: a secret URL that external
+// systems, such as monitoring alerts or a school portal, post to in order to
+// create reminders in a family. Each hook carries defaults for the fields
+// the caller leaves out and a rate limit.
+package hook
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of hooks
+const Kind = "hook"
+
+// DefaultRateLimit is the number of reminders per hour a hook may create
+// when it doesn't set its own limit
+const DefaultRateLimit = 60
+
+var ErrNotFound = errors.New("hook not found")
+
+// Defaults fill in reminder fields the payload doesn't set
+type Defaults struct {
+	FamilyMember string `json:"family_member,omitempty"`
+	Assignment   string `json:"assignment,omitempty"`
+	ProjectID    string `json:"project_id,omitempty"`
+	Effort       int    `json:"effort,omitempty"`
+	// DueIn is a Go duration added to the delivery time when the payload has
+	// no due date; reminders are undated if it is empty too
+	DueIn string `json:"due_in,omitempty"`
+}
+
+// Hook is an inbound webhook. As with shares, only a hash of the token is
+// stored; deleting the hook revokes it.
+type Hook struct {
+	ID       string   `json:"id"`
+	FamilyID string   `json:"family_id"`
+	Name     string   `json:"name"`
+	Defaults Defaults `json:"defaults"`
+	// RateLimit is the maximum number of reminders per hour; zero means
+	// DefaultRateLimit
+	RateLimit int       `json:"rate_limit,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Limit returns the hook's effective hourly rate limit
+func (h *Hook) Limit() int {
+	if h.RateLimit > 0 {
+		return h.RateLimit
+	}
+	return DefaultRateLimit
+}
+
+// Create stores a new hook and returns its token, which is only available
+// now
+func Create(s storage.Storage, h *Hook, now time.Time) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	h.ID = storage.NewRecordID("hok")
+	h.CreatedAt = now
+	rec := storage.Record{Kind: Kind, ID: h.ID, FamilyID: h.FamilyID, Ref: hashToken(token), CreatedAt: now}
+	if err := storage.PutJSON(s, rec, h); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Lookup returns the hook a token belongs to
+func Lookup(s storage.Storage, token string) (*Hook, error) {
+	if token == "" {
+		return nil, ErrNotFound
+	}
+	list, err := storage.ListJSON[Hook](s, storage.RecordQuery{Kind: Kind, Ref: hashToken(token)})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	return list[0], nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Limiter enforces hourly rate limits per hook with a token bucket that
+// refills continuously. State is kept in memory, so limits reset when the
+// server restarts.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter with every bucket full
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from the bucket of hook id, which holds at most limit
+// tokens and refills at limit per hour. When the bucket is empty it returns
+// false and how long until the next token is available.
+func (l *Limiter) Allow(id string, limit int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{tokens: float64(limit), last: now}
+		l.buckets[id] = b
+	}
+	perToken := float64(time.Hour) / float64(limit)
+	b.tokens = min(float64(limit), b.tokens+float64(now.Sub(b.last))/perToken)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration(math.Ceil((1 - b.tokens) * perToken))
+	}
+	b.tokens--
+	return true, 0
+}
@@ -0,0 +1,50 @@
+package hook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+func TestLookup(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	h := &Hook{FamilyID: "fam1", Name: "Monitoring"}
+	token, err := Create(store, h, time.Now())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := Lookup(store, token)
+	if err != nil || got.ID != h.ID {
+		t.Fatalf("Lookup = %+v, %v; want hook %s", got, err, h.ID)
+	}
+	rec, _ := store.GetRecord(Kind, h.ID)
+	if rec.Ref == token {
+		t.Error("token stored in plain text")
+	}
+	if _, err := Lookup(store, "bogus"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("hok1", 3, now); !ok {
+			t.Fatalf("request %d rejected within the limit", i+1)
+		}
+	}
+	ok, wait := l.Allow("hok1", 3, now)
+	if ok || wait != 20*time.Minute {
+		t.Errorf("Allow over the limit = %v, %s; want false, 20m", ok, wait)
+	}
+	if ok, _ := l.Allow("hok2", 3, now); !ok {
+		t.Error("limit shared between hooks")
+	}
+	if ok, _ := l.Allow("hok1", 3, now.Add(20*time.Minute)); !ok {
+		t.Error("expected a token after the refill interval")
+	}
+}
@@ -0,0 +1,154 @@
+// Package i18n tracks which languages the frontend is translated into and
+// picks the best of them for a request. Translations are whole static
+// trees: the default language is served from the root of the static
+// directory and every other one from a subdirectory named by its language
+// tag, such as es/ or pt-BR/.
+package i18n
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Cookie is the name of the cookie holding a language chosen explicitly,
+// which takes precedence over Accept-Language
+const Cookie = "lang"
+
+// tagPattern matches the language tags locale directories may be named
+// after, e.g. "de", "es-419" or "zh-Hant"
+var tagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// Locales are the languages the frontend is available in
+type Locales struct {
+	Default string `json:"default"`
+	// Supported lists every language, including Default, in sorted order
+	Supported []string `json:"supported"`
+}
+
+// Discover finds the translations in a static directory: subdirectories
+// named by a language tag that contain an index.html. def is the language
+// of the root tree.
+func Discover(dir, def string) (Locales, error) {
+	l := Locales{Default: def, Supported: []string{def}}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return l, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || !tagPattern.MatchString(name) || strings.EqualFold(name, def) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, name, "index.html")); err != nil {
+			continue
+		}
+		l.Supported = append(l.Supported, name)
+	}
+	sort.Strings(l.Supported)
+	return l, nil
+}
+
+// Enabled reports whether there is more than one language to choose from
+func (l Locales) Enabled() bool {
+	return len(l.Supported) > 1
+}
+
+// Lookup returns the supported language equal to tag, ignoring case
+func (l Locales) Lookup(tag string) (string, bool) {
+	for _, s := range l.Supported {
+		if strings.EqualFold(s, tag) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// Prefix splits a URL path starting with the directory of a language other
+// than the default into the language and the rest of the path, e.g.
+// "/es/member.html" into "es" and "/member.html"
+func (l Locales) Prefix(p string) (string, string, bool) {
+	first, rest, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	loc, ok := l.Lookup(first)
+	if !ok || loc == l.Default {
+		return "", p, false
+	}
+	return loc, "/" + rest, true
+}
+
+// Match returns the supported language that best satisfies an
+// Accept-Language header, or the default if none does. Each range, in
+// order of preference, is tried as is, then shortened as in RFC 4647
+// lookup ("es-MX" matches "es"), then against the regional variants of the
+// language ("pt" matches "pt-BR").
+func (l Locales) Match(header string) string {
+	for _, r := range languageRanges(header) {
+		if r == "*" {
+			return l.Default
+		}
+		for tag := r; tag != ""; {
+			if loc, ok := l.Lookup(tag); ok {
+				return loc
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+		for _, s := range l.Supported {
+			if len(s) > len(r) && strings.EqualFold(s[:len(r)+1], r+"-") {
+				return s
+			}
+		}
+	}
+	return l.Default
+}
+
+// Preferred returns the language to serve a request in: the one chosen in
+// the Cookie if it is supported, or else the best match for its
+// Accept-Language header
+func (l Locales) Preferred(r *http.Request) string {
+	if c, err := r.Cookie(Cookie); err == nil {
+		if loc, ok := l.Lookup(c.Value); ok {
+			return loc
+		}
+	}
+	return l.Match(r.Header.Get("Accept-Language"))
+}
+
+// languageRanges returns the ranges of an Accept-Language header with a
+// non-zero quality, most preferred first
+func languageRanges(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
@@ -0,0 +1,68 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"es", "pt-BR", "assets", "fr"} {
+		_ = os.Mkdir(filepath.Join(dir, d), 0o755)
+	}
+	for _, d := range []string{"es", "pt-BR", "assets"} {
+		_ = os.WriteFile(filepath.Join(dir, d, "index.html"), []byte("<html>"), 0o644)
+	}
+	l, err := Discover(dir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// assets isn't a language tag and fr has no index.html
+	if want := []string{"en", "es", "pt-BR"}; !reflect.DeepEqual(l.Supported, want) {
+		t.Errorf("expected %v, got %v", want, l.Supported)
+	}
+	if !l.Enabled() {
+		t.Error("expected locales to be enabled")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	l := Locales{Default: "en", Supported: []string{"de", "en", "es", "pt-BR"}}
+	for _, tc := range []struct{ header, want string }{
+		{"", "en"},
+		{"de", "de"},
+		{"es-MX,es;q=0.9,en;q=0.8", "es"},
+		{"fr-FR, fr;q=0.9, de;q=0.5", "de"},
+		{"en;q=0.5, de", "de"},
+		{"pt", "pt-BR"},
+		{"PT-br", "pt-BR"},
+		{"de;q=0, es", "es"},
+		{"fr, *;q=0.5", "en"},
+		{"ja", "en"},
+	} {
+		if got := l.Match(tc.header); got != tc.want {
+			t.Errorf("Match(%q) = %s, want %s", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	l := Locales{Default: "en", Supported: []string{"en", "es"}}
+	for _, tc := range []struct {
+		path, loc, rest string
+		ok              bool
+	}{
+		{"/es/member.html", "es", "/member.html", true},
+		{"/es/", "es", "/", true},
+		{"/es", "es", "/", true},
+		{"/en/member.html", "", "/en/member.html", false},
+		{"/assets/app.js", "", "/assets/app.js", false},
+	} {
+		loc, rest, ok := l.Prefix(tc.path)
+		if loc != tc.loc || rest != tc.rest || ok != tc.ok {
+			t.Errorf("Prefix(%q) = %q, %q, %v", tc.path, loc, rest, ok)
+		}
+	}
+}
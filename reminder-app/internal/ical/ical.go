@@ -0,0 +1,308 @@
+// Package ical renders a family's reminders as an iCalendar (RFC 5545) feed
+// that calendar apps such as Google Calendar and Apple Calendar can
+// subscribe to. Each reminder with a due date becomes an event, with
+// recurring reminders expressed as an RRULE in the assignee's time zone,
+// less the occurrences edited on their own.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// ContentType is the media type of a rendered calendar
+const ContentType = "text/calendar; charset=utf-8"
+
+// Layouts of DATE-TIME values
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+)
+
+// zoneYears is how far past the build time time zone definitions extend.
+// Clients that know the zone by name use their own rules beyond that.
+const zoneYears = 5
+
+// Source is the data a calendar is built from
+type Source struct {
+	FamilyID  string
+	Name      string
+	Reminders []*reminder.Reminder
+	// Location returns the time zone a member's reminders recur in unless
+	// they have a Timezone of their own; nil means UTC
+	Location func(member string) *time.Location
+}
+
+// Build returns the calendar at the given time. Reminders without a due
+// date can't be placed on a calendar and are left out.
+func Build(src Source, now time.Time) []byte {
+	var events writer
+	zones := make(map[string]*time.Location)
+	var earliest time.Time
+	rems := append([]*reminder.Reminder(nil), src.Reminders...)
+	sort.Slice(rems, func(i, j int) bool { return rems[i].ID < rems[j].ID })
+	for _, r := range rems {
+		if r.DueDate == nil {
+			continue
+		}
+		loc := time.UTC
+		if src.Location != nil {
+			if l := src.Location(r.FamilyMember); l != nil {
+				loc = l
+			}
+		}
+		loc = r.Location(loc)
+		start, ok := firstOccurrence(r, loc)
+		if !ok {
+			continue
+		}
+		if !isUTC(loc) {
+			zones[loc.String()] = loc
+		}
+		if earliest.IsZero() || start.Before(earliest) {
+			earliest = start
+		}
+
+		events.line("BEGIN:VEVENT")
+		events.prop("UID", fmt.Sprintf("%s.%s@reminder-app", r.ID, src.FamilyID))
+		events.prop("DTSTAMP", now.UTC().Format(utcLayout))
+		events.dateTime("DTSTART", start, loc)
+		if rule := rrule(r.Recurrence); rule != "" {
+			events.prop("RRULE", rule)
+			for _, ex := range r.Exceptions {
+				events.dateTime("EXDATE", ex, loc)
+			}
+		}
+		events.text("SUMMARY", r.Title)
+		if r.Description != "" {
+			events.text("DESCRIPTION", r.Description)
+		}
+		if r.FamilyMember != "" {
+			events.text("CATEGORIES", r.FamilyMember)
+		}
+		events.prop("SEQUENCE", fmt.Sprint(max(r.Version-1, 0)))
+		events.line("BEGIN:VALARM")
+		events.prop("ACTION", "DISPLAY")
+		events.prop("TRIGGER", "PT0S")
+		events.text("DESCRIPTION", r.Title)
+		events.line("END:VALARM")
+		events.line("END:VEVENT")
+	}
+
+	var w writer
+	w.line("BEGIN:VCALENDAR")
+	w.prop("VERSION", "2.0")
+	w.prop("PRODID", "-//reminder-app//Reminders//EN")
+	w.prop("CALSCALE", "GREGORIAN")
+	w.prop("METHOD", "PUBLISH")
+	w.text("NAME", src.Name)
+	w.text("X-WR-CALNAME", src.Name)
+	w.prop("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	w.prop("X-PUBLISHED-TTL", "PT1H")
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w.timezone(zones[name], earliest, now.AddDate(zoneYears, 0, 0))
+	}
+	w.Write(events.Bytes())
+	w.line("END:VCALENDAR")
+	return w.Bytes()
+}
+
+// firstOccurrence returns when the reminder is first due in loc. Recurring
+// reminders start on the first day their pattern selects, since DTSTART
+// always counts as an occurrence.
+func firstOccurrence(r *reminder.Reminder, loc *time.Location) (time.Time, bool) {
+	due := r.DueDate.In(loc)
+	if !r.IsRecurring() {
+		return due, true
+	}
+	local := *r
+	local.DueDate = &due
+	occ := local.Occurrences(due, due.AddDate(1, 0, 1))
+	if len(occ) == 0 {
+		return time.Time{}, false
+	}
+	return occ[0], true
+}
+
+// rrule returns the recurrence rule of a pattern, or "" for one-off
+// reminders
+func rrule(p reminder.RecurrencePattern) string {
+	var parts []string
+	switch p.Type {
+	case "hourly":
+		parts = append(parts, "FREQ=HOURLY")
+	case "daily":
+		parts = append(parts, "FREQ=DAILY")
+	case "weekly":
+		var days []string
+		for _, d := range p.Days {
+			if reminder.IsWeekday(d) {
+				// The first two letters of every English weekday are its
+				// iCalendar abbreviation
+				days = append(days, strings.ToUpper(d[:2]))
+			}
+		}
+		if len(days) == 0 {
+			return ""
+		}
+		parts = append(parts, "FREQ=WEEKLY", "BYDAY="+strings.Join(days, ","))
+	case "monthly":
+		parts = append(parts, "FREQ=MONTHLY", fmt.Sprintf("BYMONTHDAY=%d", p.Date))
+	case "yearly":
+		parts = append(parts, "FREQ=YEARLY")
+	default:
+		return ""
+	}
+	if p.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", p.Interval))
+	}
+	if p.EndDate != "" {
+		if end, err := time.Parse(time.RFC3339, p.EndDate); err == nil {
+			// UNTIL must be UTC when DTSTART has a time zone
+			parts = append(parts, "UNTIL="+end.UTC().Format(utcLayout))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// isUTC reports whether loc is UTC, which needs no VTIMEZONE
+func isUTC(loc *time.Location) bool {
+	return loc == time.UTC || loc.String() == "UTC"
+}
+
+// writer accumulates content lines, folded and terminated as RFC 5545
+// requires
+type writer struct {
+	bytes.Buffer
+}
+
+// line writes a content line, folding it at 75 octets without splitting
+// UTF-8 sequences
+func (w *writer) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		n := limit
+		for s[n]&0xC0 == 0x80 {
+			n--
+		}
+		w.WriteString(s[:n])
+		w.WriteString("\r\n ")
+		s = s[n:]
+		// Continuation lines lose one octet to the leading space
+		limit = 74
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// prop writes a property whose value needs no escaping
+func (w *writer) prop(name, value string) {
+	w.line(name + ":" + value)
+}
+
+// text writes a TEXT property, escaping its value
+func (w *writer) text(name, value string) {
+	w.prop(name, escaper.Replace(value))
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+// dateTime writes a DATE-TIME property in UTC or, for other zones, as local
+// time referencing the zone's VTIMEZONE
+func (w *writer) dateTime(name string, t time.Time, loc *time.Location) {
+	if isUTC(loc) {
+		w.prop(name, t.UTC().Format(utcLayout))
+		return
+	}
+	w.prop(name+";TZID="+loc.String(), t.In(loc).Format(localLayout))
+}
+
+// observance is one STANDARD or DAYLIGHT component, with the onsets of every
+// period that shares its offsets and abbreviation
+type observance struct {
+	daylight bool
+	from, to int
+	name     string
+	onsets   []string
+}
+
+// timezone writes a VTIMEZONE for loc covering [from, to] from Go's zone
+// data, listing each transition as an RDATE rather than deriving rules
+func (w *writer) timezone(loc *time.Location, from, to time.Time) {
+	var obs []*observance
+	find := func(daylight bool, off, prev int, name string) *observance {
+		for _, o := range obs {
+			if o.daylight == daylight && o.to == off && o.from == prev && o.name == name {
+				return o
+			}
+		}
+		o := &observance{daylight: daylight, from: prev, to: off, name: name}
+		obs = append(obs, o)
+		return o
+	}
+
+	t := from.In(loc)
+	for {
+		name, off := t.Zone()
+		start, end := t.ZoneBounds()
+		prev := off
+		onset := start
+		if start.IsZero() || start.Before(from.AddDate(-1, 0, 0)) {
+			// The zone was in effect long before the first event; its true
+			// onset doesn't matter, only that one precedes every event
+			onset = from.AddDate(-1, 0, 0)
+		} else {
+			_, prev = start.Add(-time.Second).Zone()
+		}
+		o := find(t.IsDST(), off, prev, name)
+		o.onsets = append(o.onsets, onset.In(time.FixedZone("", prev)).Format(localLayout))
+		if end.IsZero() || end.After(to) {
+			break
+		}
+		t = end.In(loc)
+	}
+
+	w.line("BEGIN:VTIMEZONE")
+	w.prop("TZID", loc.String())
+	for _, o := range obs {
+		kind := "STANDARD"
+		if o.daylight {
+			kind = "DAYLIGHT"
+		}
+		w.line("BEGIN:" + kind)
+		w.prop("DTSTART", o.onsets[0])
+		if len(o.onsets) > 1 {
+			w.prop("RDATE", strings.Join(o.onsets[1:], ","))
+		}
+		w.prop("TZOFFSETFROM", offset(o.from))
+		w.prop("TZOFFSETTO", offset(o.to))
+		if o.name != "" {
+			w.text("TZNAME", o.name)
+		}
+		w.line("END:" + kind)
+	}
+	w.line("END:VTIMEZONE")
+}
+
+// offset formats a UTC offset in seconds as a UTC-OFFSET value
+func offset(secs int) string {
+	sign := "+"
+	if secs < 0 {
+		sign, secs = "-", -secs
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, secs/3600, secs/60%60)
+	if secs%60 != 0 {
+		s += fmt.Sprintf("%02d", secs%60)
+	}
+	return s
+}
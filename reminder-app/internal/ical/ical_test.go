@@ -0,0 +1,166 @@
+package ical
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+func TestBuild(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	// Saturday evening; the first Monday or Wednesday after it is the 3rd
+	due := time.Date(2025, 3, 1, 17, 0, 0, 0, time.UTC)
+	end := "2025-06-30T00:00:00+02:00"
+	trash := reminder.NewReminder("rem1", "Trash", "Bins, then recycling;\nmind the lid", &due, "fam1", "Alice",
+		reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday", "Wednesday"}, EndDate: end})
+	rent := reminder.NewReminder("rem2", "Rent", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "monthly", Date: 5})
+	dentist := reminder.NewReminder("rem3", "Dentist", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"})
+	undated := reminder.NewReminder("rem4", "Someday", "", nil, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"})
+
+	out := string(Build(Source{
+		FamilyID:  "fam1",
+		Name:      "Smith family",
+		Reminders: []*reminder.Reminder{dentist, trash, rent, undated},
+		Location: func(member string) *time.Location {
+			if member == "Alice" {
+				return berlin
+			}
+			return nil
+		},
+	}, now))
+
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Fatalf("not a calendar:\n%s", out)
+	}
+	if strings.Count(out, "BEGIN:VEVENT") != 3 || strings.Contains(out, "Someday") {
+		t.Errorf("expected 3 events without the undated reminder:\n%s", out)
+	}
+	for _, want := range []string{
+		"UID:rem1.fam1@reminder-app\r\n",
+		"DTSTART;TZID=Europe/Berlin:20250303T180000\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20250629T220000Z\r\n",
+		`DESCRIPTION:Bins\, then recycling\;\nmind the lid` + "\r\n",
+		"DTSTART:20250305T170000Z\r\nRRULE:FREQ=MONTHLY;BYMONTHDAY=5\r\n",
+		"UID:rem3.fam1@reminder-app\r\nDTSTAMP:20250304T120000Z\r\nDTSTART:20250301T170000Z\r\nSUMMARY:Dentist\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\n",
+		// The switch to summer time on 30 March 2025
+		"TZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, "DTSTART:20250330T020000\r\nRDATE:20260329T020000") {
+		t.Errorf("expected summer time transitions as RDATEs:\n%s", out)
+	}
+}
+
+func TestFolding(t *testing.T) {
+	var w writer
+	w.text("SUMMARY", strings.Repeat("ä", 60))
+	lines := strings.Split(strings.TrimSuffix(w.String(), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected a folded line, got %q", w.String())
+	}
+	var joined strings.Builder
+	for i, l := range lines {
+		if len(l) > 75 {
+			t.Errorf("line %d is %d octets long", i, len(l))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(l, " ") {
+				t.Errorf("continuation line %d doesn't start with a space: %q", i, l)
+			}
+			l = l[1:]
+		}
+		joined.WriteString(l)
+	}
+	if joined.String() != "SUMMARY:"+strings.Repeat("ä", 60) {
+		t.Errorf("unfolding gave %q", joined.String())
+	}
+}
+
+const sample = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Example//EN\r\n" +
+	"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\nBEGIN:STANDARD\r\nDTSTART:19701025T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\nUID:trash@example.com\r\nDTSTART;TZID=Europe/Berlin:20250303T180000\r\nRRULE:FREQ=WEEKLY;BYDAY=MO,TH;UNTIL=20250630T000000Z\r\n" +
+	"SUMMARY:Take out\\, the trash\r\nDESCRIPTION:Bins first\\nthen recycl\r\n ing\r\nBEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:Alarm\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:rent@example.com\r\nDTSTART;VALUE=DATE:20250101\r\nRRULE:FREQ=MONTHLY;COUNT=3\r\nSUMMARY:Rent\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:gym@example.com\r\nDTSTART:20250101T070000Z\r\nRRULE:FREQ=WEEKLY;INTERVAL=2\r\nSUMMARY:Gym\r\nEND:VEVENT\r\n" +
+	"BEGIN:VTODO\r\nUID:taxes@example.com\r\nDTSTART:20250101T090000Z\r\nDUE:20250415T170000Z\r\nSUMMARY:Taxes\r\nSTATUS:NEEDS-ACTION\r\nEND:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	entries, err := Parse(strings.NewReader(sample), berlin)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %+v", entries)
+	}
+
+	trash := entries[0]
+	if trash.Summary != "Take out, the trash" || trash.Description != "Bins first\nthen recycling" {
+		t.Errorf("unexpected text: %q %q", trash.Summary, trash.Description)
+	}
+	if trash.Due == nil || !trash.Due.Equal(time.Date(2025, 3, 3, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected start: %v", trash.Due)
+	}
+	want := reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday", "thursday"}, EndDate: "2025-06-30T00:00:00Z"}
+	if !reflect.DeepEqual(trash.Recurrence, want) || trash.Unsupported != "" {
+		t.Errorf("unexpected recurrence: %+v %q", trash.Recurrence, trash.Unsupported)
+	}
+
+	rent := entries[1]
+	if rent.Due == nil || !rent.Due.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, berlin)) {
+		t.Errorf("expected an all-day date at midnight, got %v", rent.Due)
+	}
+	if rent.Recurrence.Type != "monthly" || rent.Recurrence.Date != 1 || rent.Recurrence.EndDate != "2025-02-28T23:00:00Z" {
+		t.Errorf("expected the third occurrence to end the recurrence, got %+v", rent.Recurrence)
+	}
+
+	gymWant := reminder.RecurrencePattern{Type: "weekly", Days: []string{"wednesday"}, Interval: 2}
+	if gym := entries[2]; !reflect.DeepEqual(gym.Recurrence, gymWant) || gym.Unsupported != "" {
+		t.Errorf("expected every other wednesday, got %+v", gym)
+	}
+	if taxes := entries[3]; taxes.Kind != "VTODO" || taxes.Due == nil || taxes.Due.Month() != time.April {
+		t.Errorf("expected a to-do due on its DUE date, got %+v", taxes)
+	}
+
+	for _, bad := range []string{"", "hello world", "BEGIN:VCARD\r\nEND:VCARD\r\n", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n"} {
+		if _, err := Parse(strings.NewReader(bad), time.UTC); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	due := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	r := reminder.NewReminder("rem1", "Plants; water, then feed", "", &due, "fam1", "Alice",
+		reminder.RecurrencePattern{Type: "monthly", Date: 3, EndDate: "2025-12-31T00:00:00Z", Interval: 2})
+	entries, err := Parse(bytes.NewReader(Build(Source{FamilyID: "fam1", Name: "Smith", Reminders: []*reminder.Reminder{r}}, due)), time.UTC)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Parse failed: %+v, %v", entries, err)
+	}
+	e := entries[0]
+	if e.Summary != r.Title || !e.Due.Equal(due) || !reflect.DeepEqual(e.Recurrence, r.Recurrence) {
+		t.Errorf("round trip changed the reminder: %+v", e)
+	}
+
+	r.Recurrence = reminder.RecurrencePattern{Type: "hourly", Interval: 6}
+	entries, err = Parse(bytes.NewReader(Build(Source{FamilyID: "fam1", Name: "Smith", Reminders: []*reminder.Reminder{r}}, due)), time.UTC)
+	if err != nil || len(entries) != 1 || !reflect.DeepEqual(entries[0].Recurrence, r.Recurrence) {
+		t.Errorf("round trip changed the hourly recurrence: %+v, %v", entries, err)
+	}
+}
@@ -0,0 +1,326 @@
+package ical
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// ErrNotCalendar is returned by Parse for input that isn't an iCalendar
+// stream
+var ErrNotCalendar = errors.New("not an iCalendar file")
+
+// Entry is a VEVENT or VTODO read from a calendar
+type Entry struct {
+	// Kind is "VEVENT" or "VTODO"
+	Kind        string
+	UID         string
+	Summary     string
+	Description string
+	// Due is the start of an event, or the due time of a to-do falling back
+	// to its start; nil if it has neither
+	Due        *time.Time
+	Recurrence reminder.RecurrencePattern
+	// Unsupported explains why the entry's RRULE can't be expressed as a
+	// RecurrencePattern; Recurrence is then "once"
+	Unsupported string
+	// Status is the STATUS property, such as "CANCELLED" or "COMPLETED"
+	Status string
+}
+
+// property is one content line
+type property struct {
+	params map[string]string
+	value  string
+}
+
+// Parse reads the events and to-dos of a calendar. Times in a zone that
+// isn't an IANA name, floating times and all-day dates are read in loc.
+// Exceptions to recurrences (EXDATE, RDATE and RECURRENCE-ID overrides) are
+// ignored.
+func Parse(r io.Reader, loc *time.Location) ([]Entry, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	var stack []string
+	var props map[string]property
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+		name, p, err := parseLine(l)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "BEGIN":
+			if len(stack) == 0 && p.value != "VCALENDAR" {
+				return nil, ErrNotCalendar
+			}
+			stack = append(stack, p.value)
+			if len(stack) == 2 && (p.value == "VEVENT" || p.value == "VTODO") {
+				props = make(map[string]property)
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != p.value {
+				return nil, fmt.Errorf("unexpected END:%s", p.value)
+			}
+			if len(stack) == 2 && props != nil {
+				entries = append(entries, entry(p.value, props, loc))
+				props = nil
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if len(stack) == 0 {
+			return nil, ErrNotCalendar
+		}
+		// Properties of nested components such as VALARM are skipped
+		if len(stack) == 2 && props != nil {
+			if _, seen := props[name]; !seen {
+				props[name] = p
+			}
+		}
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("unterminated %s", stack[len(stack)-1])
+	}
+	if lines == nil {
+		return nil, ErrNotCalendar
+	}
+	return entries, nil
+}
+
+// unfold reads content lines, joining folded continuation lines. Bare line
+// feeds are accepted as well as CRLF.
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	var lines []string
+	for sc.Scan() {
+		l := strings.TrimSuffix(sc.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, sc.Err()
+}
+
+// parseLine splits a content line into its upper-cased name, parameters and
+// value. Parameter values may be quoted to contain ':' and ';'.
+func parseLine(l string) (string, property, error) {
+	p := property{params: make(map[string]string)}
+	colon, quoted := -1, false
+	for i := 0; i < len(l) && colon < 0; i++ {
+		switch l[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return "", p, fmt.Errorf("invalid content line: %q", l)
+	}
+	p.value = l[colon+1:]
+	parts := strings.Split(l[:colon], ";")
+	for _, param := range parts[1:] {
+		k, v, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return strings.ToUpper(parts[0]), p, nil
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// entry builds an Entry from the properties of a component
+func entry(kind string, props map[string]property, loc *time.Location) Entry {
+	e := Entry{
+		Kind:        kind,
+		UID:         props["UID"].value,
+		Summary:     unescaper.Replace(props["SUMMARY"].value),
+		Description: unescaper.Replace(props["DESCRIPTION"].value),
+		Status:      strings.ToUpper(props["STATUS"].value),
+		Recurrence:  reminder.RecurrencePattern{Type: "once"},
+	}
+	start, hasStart := props["DTSTART"]
+	due, hasDue := props["DUE"]
+	switch {
+	case kind == "VTODO" && hasDue:
+		e.Due = parseTime(due, loc)
+	case hasStart:
+		e.Due = parseTime(start, loc)
+	}
+	if rule, ok := props["RRULE"]; ok && e.Due != nil {
+		e.Recurrence, e.Unsupported = recurrence(rule.value, *e.Due, loc)
+	}
+	return e
+}
+
+// parseTime parses a DATE or DATE-TIME value, returning nil if it's invalid
+func parseTime(p property, loc *time.Location) *time.Time {
+	v := p.value
+	if tzid := p.params["TZID"]; tzid != "" {
+		// Some producers prefix globally unique zone names with a slash
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+	var t time.Time
+	var err error
+	switch {
+	case p.params["VALUE"] == "DATE" || len(v) == 8:
+		t, err = time.ParseInLocation("20060102", v, loc)
+	case strings.HasSuffix(v, "Z"):
+		t, err = time.Parse(utcLayout, v)
+	default:
+		t, err = time.ParseInLocation(localLayout, v, loc)
+	}
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// icalWeekdays maps iCalendar weekday abbreviations to reminder day names
+var icalWeekdays = map[string]string{
+	"MO": "monday", "TU": "tuesday", "WE": "wednesday", "TH": "thursday",
+	"FR": "friday", "SA": "saturday", "SU": "sunday",
+}
+
+// recurrence maps an RRULE onto a RecurrencePattern. Rules the pattern can't
+// express yield a one-off pattern and the reason.
+func recurrence(rule string, start time.Time, loc *time.Location) (reminder.RecurrencePattern, string) {
+	once := reminder.RecurrencePattern{Type: "once"}
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(k)] = strings.ToUpper(v)
+	}
+	for k, v := range parts {
+		switch k {
+		case "FREQ", "BYDAY", "BYMONTHDAY", "UNTIL", "COUNT", "WKST":
+		case "INTERVAL":
+			if n, err := strconv.Atoi(v); err != nil || n < 1 {
+				return once, fmt.Sprintf("invalid INTERVAL: %s", v)
+			}
+		default:
+			return once, fmt.Sprintf("%s is not supported", k)
+		}
+	}
+
+	var days []string
+	if byDay := parts["BYDAY"]; byDay != "" {
+		for _, d := range strings.Split(byDay, ",") {
+			day, ok := icalWeekdays[d]
+			if !ok {
+				return once, fmt.Sprintf("BYDAY=%s is not supported", d)
+			}
+			days = append(days, day)
+		}
+	}
+
+	var p reminder.RecurrencePattern
+	switch parts["FREQ"] {
+	case "HOURLY":
+		if len(days) > 0 {
+			return once, "hourly repetition on weekdays is not supported"
+		}
+		p = reminder.RecurrencePattern{Type: "hourly"}
+	case "DAILY":
+		// Daily on selected weekdays is weekly on those days
+		if len(days) > 0 {
+			p = reminder.RecurrencePattern{Type: "weekly", Days: days}
+		} else {
+			p = reminder.RecurrencePattern{Type: "daily"}
+		}
+	case "WEEKLY":
+		if len(days) == 0 {
+			days = []string{strings.ToLower(start.In(loc).Weekday().String())}
+		}
+		p = reminder.RecurrencePattern{Type: "weekly", Days: days}
+	case "MONTHLY":
+		if len(days) > 0 {
+			return once, "monthly repetition on weekdays is not supported"
+		}
+		date := start.In(loc).Day()
+		if v := parts["BYMONTHDAY"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 31 {
+				return once, fmt.Sprintf("BYMONTHDAY=%s is not supported", v)
+			}
+			date = n
+		}
+		p = reminder.RecurrencePattern{Type: "monthly", Date: date}
+	case "YEARLY":
+		if len(days) > 0 {
+			return once, "yearly repetition on weekdays is not supported"
+		}
+		p = reminder.RecurrencePattern{Type: "yearly"}
+	default:
+		return once, fmt.Sprintf("FREQ=%s is not supported", parts["FREQ"])
+	}
+	if parts["BYMONTHDAY"] != "" && p.Type != "monthly" {
+		return once, "BYMONTHDAY is only supported for monthly repetition"
+	}
+	if n, _ := strconv.Atoi(parts["INTERVAL"]); n > 1 {
+		if len(days) > 0 && parts["FREQ"] == "DAILY" {
+			return once, "daily repetition on weekdays every few days is not supported"
+		}
+		p.Interval = n
+		if err := p.Validate(); err != nil {
+			return once, fmt.Sprintf("repeating every %d periods is not supported", n)
+		}
+	}
+
+	switch {
+	case parts["UNTIL"] != "":
+		until := parseTime(property{value: parts["UNTIL"]}, loc)
+		if until == nil {
+			return once, fmt.Sprintf("invalid UNTIL: %s", parts["UNTIL"])
+		}
+		end := *until
+		if len(parts["UNTIL"]) == 8 {
+			// A date includes the whole day
+			end = end.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		p.EndDate = end.UTC().Format(time.RFC3339)
+	case parts["COUNT"] != "":
+		n, err := strconv.Atoi(parts["COUNT"])
+		if err != nil || n < 1 {
+			return once, fmt.Sprintf("invalid COUNT: %s", parts["COUNT"])
+		}
+		r := reminder.Reminder{DueDate: &start, Recurrence: p}
+		from := start.In(loc)
+		if occ := r.Occurrences(from, from.AddDate(10, 0, 0)); len(occ) >= n {
+			p.EndDate = occ[n-1].UTC().Format(time.RFC3339)
+		}
+	}
+	return p, ""
+}
+
+// ImportKind is the storage record kind remembering which reminder each
+// imported calendar entry became, so importing a file again doesn't
+// duplicate it. Records are keyed by ImportID.
+const ImportKind = "ical_import"
+
+// ImportID returns the record ID of an entry imported into a family
+func ImportID(familyID, uid string) string {
+	sum := sha256.Sum256([]byte(familyID + "\x00" + uid))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,94 @@
+package links
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed = errors.New("malformed link token")
+	ErrSignature = errors.New("invalid link signature")
+	ErrExpired   = errors.New("link token expired")
+)
+
+// Claims are the facts a signed link vouches for
+type Claims struct {
+	ReminderID string
+	Member     string
+	ExpiresAt  time.Time
+}
+
+// payload is the compact JSON encoding of Claims inside a token
+type payload struct {
+	ReminderID string `json:"r"`
+	Member     string `json:"m,omitempty"`
+	Expiry     int64  `json:"exp"`
+}
+
+// Signer issues and verifies HMAC-SHA256 signed link tokens. Tokens are
+// stateless: anyone holding one can act on the reminder until it expires.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates a signer using the given secret key
+func NewSigner(key []byte) *Signer {
+	return &Signer{key: key}
+}
+
+// NewRandomSigner creates a signer with a random key. Tokens it issues do not
+// survive a restart.
+func NewRandomSigner() (*Signer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return NewSigner(key), nil
+}
+
+// Sign returns a URL-safe token for the claims
+func (s *Signer) Sign(c Claims) string {
+	data, _ := json.Marshal(payload{ReminderID: c.ReminderID, Member: c.Member, Expiry: c.ExpiresAt.Unix()})
+	enc := base64.RawURLEncoding.EncodeToString(data)
+	return enc + "." + base64.RawURLEncoding.EncodeToString(s.mac(enc))
+}
+
+// Verify checks the token signature and expiry and returns its claims
+func (s *Signer) Verify(token string, now time.Time) (*Claims, error) {
+	enc, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrMalformed
+	}
+	gotMAC, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if !hmac.Equal(gotMAC, s.mac(enc)) {
+		return nil, ErrSignature
+	}
+	data, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, ErrMalformed
+	}
+	c := &Claims{ReminderID: p.ReminderID, Member: p.Member, ExpiresAt: time.Unix(p.Expiry, 0)}
+	if !now.Before(c.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	return c, nil
+}
+
+func (s *Signer) mac(data string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,39 @@
+package links
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	now := time.Now()
+	token := s.Sign(Claims{ReminderID: "rem1", Member: "Alice", ExpiresAt: now.Add(time.Hour)})
+
+	c, err := s.Verify(token, now)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if c.ReminderID != "rem1" || c.Member != "Alice" {
+		t.Errorf("unexpected claims: %+v", c)
+	}
+
+	if _, err := s.Verify(token, now.Add(2*time.Hour)); err != ErrExpired {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+
+	if _, err := NewSigner([]byte("other")).Verify(token, now); err != ErrSignature {
+		t.Errorf("expected ErrSignature for wrong key, got %v", err)
+	}
+
+	payload, sig, _ := strings.Cut(token, ".")
+	tampered := payload[:len(payload)-1] + "A." + sig
+	if _, err := s.Verify(tampered, now); err != ErrSignature && err != ErrMalformed {
+		t.Errorf("expected tampered token to be rejected, got %v", err)
+	}
+
+	if _, err := s.Verify("garbage", now); err != ErrMalformed {
+		t.Errorf("expected ErrMalformed, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Package live pushes changes to a family's reminders to connected clients
+// as they happen, so the frontend can stay current without polling. A Hub
+// fans events out to per-family subscribers, and Conn is a minimal server
+// side WebSocket (RFC 6455) connection to deliver them over.
+package live
+
+import (
+	"sync"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// Event is one change pushed to subscribers
+type Event struct {
+	// Type is the type of the domain event, e.g. "reminder.created"
+	Type       string `json:"type"`
+	FamilyID   string `json:"family_id"`
+	ReminderID string `json:"reminder_id,omitempty"`
+	// Reminder is the reminder after the change; nil for deletions
+	Reminder *reminder.Reminder `json:"reminder,omitempty"`
+	// CompletionEvent is set for completions
+	CompletionEvent *reminder.CompletionEvent `json:"completion_event,omitempty"`
+	Actor           string                    `json:"actor,omitempty"`
+	At              time.Time                 `json:"at"`
+}
+
+// bufferSize is how many events a subscriber may fall behind by before it
+// is dropped
+const bufferSize = 64
+
+// Subscription receives the events of one family. C is closed when the
+// subscription is cancelled or the subscriber falls too far behind; a
+// client that is dropped should reload its reminders.
+type Subscription struct {
+	C        <-chan Event
+	c        chan Event
+	familyID string
+	hub      *Hub
+}
+
+// Cancel stops delivery to the subscription
+func (s *Subscription) Cancel() {
+	s.hub.remove(s)
+}
+
+// Hub fans events out to subscribers. Publishing never blocks.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]bool
+}
+
+// NewHub returns a hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[*Subscription]bool)}
+}
+
+// Subscribe starts delivering the events of a family
+func (h *Hub) Subscribe(familyID string) *Subscription {
+	c := make(chan Event, bufferSize)
+	s := &Subscription{C: c, c: c, familyID: familyID, hub: h}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[familyID] == nil {
+		h.subs[familyID] = make(map[*Subscription]bool)
+	}
+	h.subs[familyID][s] = true
+	return s
+}
+
+// Publish delivers e to the subscribers of its family. Subscribers whose
+// buffer is full are dropped rather than holding up the request that made
+// the change.
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs[e.FamilyID] {
+		select {
+		case s.c <- e:
+		default:
+			h.removeLocked(s)
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers of a family
+func (h *Hub) Subscribers(familyID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[familyID])
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(s)
+}
+
+func (h *Hub) removeLocked(s *Subscription) {
+	subs := h.subs[s.familyID]
+	if !subs[s] {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subs, s.familyID)
+	}
+	close(s.c)
+}
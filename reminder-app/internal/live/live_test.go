@@ -0,0 +1,211 @@
+package live
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHub(t *testing.T) {
+	h := NewHub()
+	a := h.Subscribe("fam1")
+	b := h.Subscribe("fam2")
+
+	h.Publish(Event{Type: "reminder.created", FamilyID: "fam1", ReminderID: "rem1"})
+	select {
+	case e := <-a.C:
+		if e.ReminderID != "rem1" {
+			t.Errorf("expected rem1, got %+v", e)
+		}
+	default:
+		t.Fatal("expected an event for fam1")
+	}
+	select {
+	case e := <-b.C:
+		t.Errorf("fam2 received an event of fam1: %+v", e)
+	default:
+	}
+
+	a.Cancel()
+	if _, ok := <-a.C; ok {
+		t.Error("expected the channel to be closed after Cancel")
+	}
+	a.Cancel()
+	if n := h.Subscribers("fam1"); n != 0 {
+		t.Errorf("expected no subscribers left, got %d", n)
+	}
+
+	// A subscriber that doesn't keep up is dropped
+	for i := 0; i <= bufferSize; i++ {
+		h.Publish(Event{Type: "reminder.updated", FamilyID: "fam2"})
+	}
+	n := 0
+	for range b.C {
+		n++
+	}
+	if n != bufferSize {
+		t.Errorf("expected %d buffered events before dropping, got %d", bufferSize, n)
+	}
+	if h.Subscribers("fam2") != 0 {
+		t.Error("expected the slow subscriber to be removed")
+	}
+}
+
+// dial performs a WebSocket handshake with the server and returns the
+// connection and a reader positioned after the response headers
+func dial(t *testing.T, srv *httptest.Server, header string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	req := "GET / HTTP/1.1\r\nHost: " + strings.TrimPrefix(srv.URL, "http://") + "\r\n" +
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" + header + "\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp
+}
+
+// writeClientFrame writes a masked frame as clients must
+func writeClientFrame(w io.Writer, op byte, payload []byte) error {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// readServerFrame reads an unmasked frame
+func readServerFrame(r io.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(head[1] & 0x7F)
+	if n == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	_, err := io.ReadFull(r, payload)
+	return head[0] & 0x0F, payload, err
+}
+
+func TestConn(t *testing.T) {
+	done := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.WriteJSON(Event{Type: "reminder.deleted", FamilyID: "fam1", ReminderID: "rem1"})
+		done <- c.ReadControl(time.Second)
+	}))
+	defer srv.Close()
+
+	conn, br, resp := dial(t, srv, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	// Accept key from the example handshake in RFC 6455
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected accept key %q", got)
+	}
+
+	op, payload, err := readServerFrame(br)
+	if err != nil || op != opText {
+		t.Fatalf("expected a text frame, got %d, %v", op, err)
+	}
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil || e.Type != "reminder.deleted" || e.ReminderID != "rem1" {
+		t.Errorf("unexpected event %s: %v", payload, err)
+	}
+
+	if err := writeClientFrame(conn, opPing, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if op, payload, err := readServerFrame(br); err != nil || op != opPong || string(payload) != "hi" {
+		t.Errorf("expected pong echoing the ping, got %d %q, %v", op, payload, err)
+	}
+
+	if err := writeClientFrame(conn, opClose, binary.BigEndian.AppendUint16(nil, CloseNormal)); err != nil {
+		t.Fatal(err)
+	}
+	if op, payload, err := readServerFrame(br); err != nil || op != opClose || binary.BigEndian.Uint16(payload) != CloseNormal {
+		t.Errorf("expected a close frame in reply, got %d %v, %v", op, payload, err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean close, got %v", err)
+	}
+}
+
+func TestConnRejectsUnmasked(t *testing.T) {
+	done := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		done <- c.ReadControl(time.Second)
+	}))
+	defer srv.Close()
+
+	conn, br, _ := dial(t, srv, "")
+	conn.Write([]byte{0x80 | opText, 2, 'h', 'i'})
+	if op, payload, err := readServerFrame(br); err != nil || op != opClose || binary.BigEndian.Uint16(payload) != CloseProtocolError {
+		t.Errorf("expected a protocol error close, got %d %v, %v", op, payload, err)
+	}
+	if err := <-done; err == nil {
+		t.Error("expected an error for an unmasked frame")
+	}
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := Upgrade(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a plain request, got %d", resp.StatusCode)
+	}
+
+	_, _, resp = dial(t, srv, "Origin: https://evil.example\r\n")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a cross-origin handshake to be refused, got %d", resp.StatusCode)
+	}
+	_, _, resp = dial(t, srv, "Origin: "+srv.URL+"\r\n")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected a same-origin handshake to succeed, got %d", resp.StatusCode)
+	}
+}
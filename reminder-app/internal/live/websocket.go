@@ -0,0 +1,264 @@
+package live
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotWebSocket is returned by Upgrade for requests that aren't a
+	// WebSocket handshake
+	ErrNotWebSocket = errors.New("not a WebSocket handshake")
+	// ErrCrossOrigin is returned by Upgrade for handshakes from pages served
+	// by another site, which browsers allow without CORS
+	ErrCrossOrigin = errors.New("cross-origin WebSocket handshake")
+)
+
+// Close codes
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseTooBig        = 1009
+	CloseTryAgainLater = 1013
+)
+
+// Opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// handshakeGUID is appended to the client's key to compute the accept key
+const handshakeGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize bounds messages read from clients, which only ever send
+// control frames
+const maxMessageSize = 4096
+
+// writeTimeout bounds each frame written, so a stalled client can't hold
+// a handler forever
+const writeTimeout = 10 * time.Second
+
+// Conn is the server side of a WebSocket connection. Writes are safe for
+// concurrent use; reads happen in ReadControl.
+type Conn struct {
+	conn      net.Conn
+	br        *bufio.Reader
+	mu        sync.Mutex
+	closeOnce sync.Once
+}
+
+// Upgrade completes a WebSocket handshake and takes over the connection.
+// Handshakes whose Origin differs from the request's host are refused. On
+// error nothing has been written, so the caller can still respond.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !headerHas(r.Header, "Connection", "upgrade") ||
+		!headerHas(r.Header, "Upgrade", "websocket") || key == "" {
+		return nil, ErrNotWebSocket
+	}
+	if v := r.Header.Get("Sec-WebSocket-Version"); v != "13" {
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrNotWebSocket, v)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			return nil, ErrCrossOrigin
+		}
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection cannot be taken over")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + handshakeGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: rw.Reader}, nil
+}
+
+// headerHas reports whether a comma-separated header contains token
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteJSON sends v as a text message
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, data)
+}
+
+// Ping sends a ping, which the client answers with a pong
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close sends a close frame with the given code and reason, then closes
+// the connection. Only the first call has an effect.
+func (c *Conn) Close(code int, reason string) error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		c.writeFrame(opClose, closePayload(code, reason))
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func closePayload(code int, reason string) []byte {
+	p := binary.BigEndian.AppendUint16(nil, uint16(code))
+	// Control frames carry at most 125 bytes
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	return append(p, reason...)
+}
+
+// writeFrame writes an unfragmented, unmasked frame as servers must
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	header := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = binary.BigEndian.AppendUint16(append(header, 126), uint16(n))
+	default:
+		header = binary.BigEndian.AppendUint64(append(header, 127), uint64(n))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadControl reads from the client until the connection ends, answering
+// pings and closing in response to a close frame. Data messages are
+// discarded since clients only listen. The connection is considered dead
+// if nothing, not even a pong, arrives within timeout. It returns nil when
+// the client closed the connection cleanly.
+func (c *Conn) ReadControl(timeout time.Duration) error {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+		op, payload, err := c.readFrame()
+		if err != nil {
+			var pe protocolError
+			if errors.As(err, &pe) {
+				c.Close(pe.code, pe.msg)
+			}
+			return err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return err
+			}
+		case opClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code, "")
+			return nil
+		}
+	}
+}
+
+// protocolError is a violation by the client, answered with a close frame
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e protocolError) Error() string {
+	return fmt.Sprintf("websocket: %s", e.msg)
+}
+
+// readFrame reads one frame, unmasking its payload
+func (c *Conn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return 0, nil, err
+	}
+	fin, op := head[0]&0x80 != 0, head[0]&0x0F
+	masked, n := head[1]&0x80 != 0, uint64(head[1]&0x7F)
+	if !masked {
+		return 0, nil, protocolError{CloseProtocolError, "client frames must be masked"}
+	}
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	switch op {
+	case opClose, opPing, opPong:
+		if !fin || n > 125 {
+			return 0, nil, protocolError{CloseProtocolError, "invalid control frame"}
+		}
+	case opContinuation, opText, opBinary:
+		if n > maxMessageSize {
+			return 0, nil, protocolError{CloseTooBig, "message too big"}
+		}
+	default:
+		return 0, nil, protocolError{CloseProtocolError, fmt.Sprintf("unknown opcode %d", op)}
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
@@ -0,0 +1,246 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedScheme is returned for Apprise URLs that are neither handled
+// natively nor forwardable to an Apprise API server
+var ErrUnsupportedScheme = errors.New("unsupported notification URL scheme")
+
+// AppriseNotifier delivers to targets written as Apprise-style URLs, such as
+// "ntfys://ntfy.example.org/chores" or "discord://webhook_id/webhook_token".
+// Common schemes are handled natively; any other scheme is forwarded to an
+// Apprise API server when APIURL is set, which covers the remaining services
+// Apprise supports without a dedicated integration here.
+type AppriseNotifier struct {
+	// APIURL is the base URL of an Apprise API server, e.g.
+	// "http://apprise:8000". Empty disables forwarding.
+	APIURL string
+	Client *http.Client
+}
+
+// NewAppriseNotifier creates a notifier, optionally backed by an Apprise API
+// server for schemes without native support
+func NewAppriseNotifier(apiURL string) *AppriseNotifier {
+	return &AppriseNotifier{
+		APIURL: strings.TrimSuffix(apiURL, "/"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// appriseSender delivers a message to an already parsed target
+type appriseSender func(ctx context.Context, msg Message) error
+
+// Validate checks that target is a URL this notifier can deliver to
+func (a *AppriseNotifier) Validate(target string) error {
+	_, err := a.parse(target)
+	return err
+}
+
+func (a *AppriseNotifier) Send(ctx context.Context, target string, msg Message) error {
+	send, err := a.parse(target)
+	if err != nil {
+		return err
+	}
+	return send(ctx, msg)
+}
+
+func (a *AppriseNotifier) parse(target string) (appriseSender, error) {
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok || scheme == "" {
+		return nil, fmt.Errorf("invalid notification URL: %q", target)
+	}
+	scheme = strings.ToLower(scheme)
+	rest, _, _ = strings.Cut(rest, "?")
+	secure := strings.HasSuffix(scheme, "s")
+
+	switch scheme {
+	case "json", "jsons":
+		return a.parseJSON(target, secure)
+	case "ntfy", "ntfys":
+		return a.parseNtfy(target, secure)
+	case "gotify", "gotifys":
+		return a.parseGotify(target, secure)
+	case "discord":
+		parts := pathParts(rest)
+		if len(parts) < 2 {
+			return nil, errors.New("discord URL needs a webhook ID and token")
+		}
+		endpoint := "https://discord.com/api/webhooks/" + url.PathEscape(parts[0]) + "/" + url.PathEscape(parts[1])
+		return func(ctx context.Context, msg Message) error {
+			return a.postJSON(ctx, endpoint, map[string]string{"content": msg.Text()}, nil)
+		}, nil
+	case "slack":
+		parts := pathParts(rest)
+		if len(parts) < 3 {
+			return nil, errors.New("slack URL needs three webhook tokens")
+		}
+		endpoint := "https://hooks.slack.com/services/" + strings.Join(parts[:3], "/")
+		return func(ctx context.Context, msg Message) error {
+			return a.postJSON(ctx, endpoint, map[string]string{"text": msg.Text()}, nil)
+		}, nil
+	case "tgram":
+		// Bot tokens contain a colon, so the URL cannot go through url.Parse
+		parts := pathParts(rest)
+		if len(parts) < 2 {
+			return nil, errors.New("telegram URL needs a bot token and chat ID")
+		}
+		endpoint := "https://api.telegram.org/bot" + parts[0] + "/sendMessage"
+		chats := parts[1:]
+		return func(ctx context.Context, msg Message) error {
+			var errs []error
+			for _, chat := range chats {
+				errs = append(errs, a.postJSON(ctx, endpoint, map[string]string{"chat_id": chat, "text": msg.Text()}, nil))
+			}
+			return errors.Join(errs...)
+		}, nil
+	}
+
+	if a.APIURL == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, scheme)
+	}
+	return func(ctx context.Context, msg Message) error {
+		body := msg.Body
+		if msg.Link != "" {
+			body += "\n" + msg.Link
+		}
+		payload := map[string]string{"urls": target, "title": msg.Subject, "body": body}
+		return a.postJSON(ctx, a.APIURL+"/notify/", payload, nil)
+	}, nil
+}
+
+// parseJSON handles json:// and jsons:// which POST a JSON document to an
+// arbitrary webhook. Query parameters prefixed with "+" become headers.
+func (a *AppriseNotifier) parseJSON(target string, secure bool) (appriseSender, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("json URL needs a host")
+	}
+	// The raw query is split by hand because "+" would decode to a space
+	headers := http.Header{}
+	var query []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		k, v, _ := strings.Cut(pair, "=")
+		if !strings.HasPrefix(k, "+") {
+			if pair != "" {
+				query = append(query, pair)
+			}
+			continue
+		}
+		name, err1 := url.QueryUnescape(k[1:])
+		value, err2 := url.QueryUnescape(v)
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, err
+		}
+		headers.Add(name, value)
+	}
+	endpoint := url.URL{Scheme: httpScheme(secure), User: u.User, Host: u.Host, Path: u.Path, RawQuery: strings.Join(query, "&")}
+	return func(ctx context.Context, msg Message) error {
+		payload := map[string]string{
+			"version": "1.0",
+			"title":   msg.Subject,
+			"message": msg.Body,
+			"type":    "info",
+		}
+		if msg.Link != "" {
+			payload["url"] = msg.Link
+		}
+		return a.postJSON(ctx, endpoint.String(), payload, headers)
+	}, nil
+}
+
+// parseNtfy handles ntfy://topic (on ntfy.sh) and ntfy[s]://host/topic
+func (a *AppriseNotifier) parseNtfy(target string, secure bool) (appriseSender, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	parts := pathParts(u.Path)
+	server, topic := httpScheme(secure)+"://"+u.Host, ""
+	switch {
+	case len(parts) == 0 && u.Host != "":
+		server, topic = "https://ntfy.sh", u.Host
+	case len(parts) > 0:
+		topic = parts[len(parts)-1]
+	}
+	if topic == "" {
+		return nil, errors.New("ntfy URL needs a topic")
+	}
+	n := NewNtfyNotifier(server, u.Query().Get("token"))
+	n.Client = a.Client
+	return func(ctx context.Context, msg Message) error {
+		return n.Send(ctx, topic, msg)
+	}, nil
+}
+
+// parseGotify handles gotify[s]://host[/path]/token
+func (a *AppriseNotifier) parseGotify(target string, secure bool) (appriseSender, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	parts := pathParts(u.Path)
+	if u.Host == "" || len(parts) == 0 {
+		return nil, errors.New("gotify URL needs a host and application token")
+	}
+	token := parts[len(parts)-1]
+	server := httpScheme(secure) + "://" + u.Host
+	if len(parts) > 1 {
+		server += "/" + strings.Join(parts[:len(parts)-1], "/")
+	}
+	g := NewGotifyNotifier(server)
+	g.Client = a.Client
+	if p, err := strconv.Atoi(u.Query().Get("priority")); err == nil {
+		g.Priority = p
+	}
+	return func(ctx context.Context, msg Message) error {
+		return g.Send(ctx, token, msg)
+	}, nil
+}
+
+func (a *AppriseNotifier) postJSON(ctx context.Context, endpoint string, payload any, headers http.Header) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(a.Client, req)
+}
+
+// pathParts splits a URL path into its non-empty segments
+func pathParts(path string) []string {
+	var parts []string
+	for _, p := range strings.Split(path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+func httpScheme(secure bool) string {
+	if secure {
+		return "https"
+	}
+	return "http"
+}
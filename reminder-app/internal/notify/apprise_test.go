@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureTransport records requests instead of sending them, so URLs for
+// hosted services can be checked without network access
+type captureTransport struct {
+	reqs []*http.Request
+	body []string
+}
+
+func (c *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b, _ := io.ReadAll(req.Body)
+	c.reqs = append(c.reqs, req)
+	c.body = append(c.body, string(b))
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+}
+
+func TestAppriseHostedServices(t *testing.T) {
+	tests := []struct {
+		target   string
+		endpoint string
+		contains string
+	}{
+		{"discord://123/abc", "https://discord.com/api/webhooks/123/abc", `"content"`},
+		{"slack://T1/B2/C3/#chores", "https://hooks.slack.com/services/T1/B2/C3", `"text"`},
+		{"tgram://123456:ABC-def/987", "https://api.telegram.org/bot123456:ABC-def/sendMessage", `"chat_id":"987"`},
+		{"ntfys://chores", "https://ntfy.sh/chores", "Take out the trash"},
+		{"gotifys://push.example.org/sub/apptoken?priority=8", "https://push.example.org/sub/message", `"priority":8`},
+	}
+	for _, tt := range tests {
+		ct := &captureTransport{}
+		a := NewAppriseNotifier("")
+		a.Client = &http.Client{Transport: ct}
+		if err := a.Send(context.Background(), tt.target, Message{Subject: "Trash", Body: "Take out the trash"}); err != nil {
+			t.Errorf("%s: Send failed: %v", tt.target, err)
+			continue
+		}
+		if len(ct.reqs) != 1 || ct.reqs[0].URL.String() != tt.endpoint {
+			t.Errorf("%s: unexpected requests %v", tt.target, ct.reqs)
+			continue
+		}
+		if !strings.Contains(ct.body[0], tt.contains) {
+			t.Errorf("%s: body %q does not contain %q", tt.target, ct.body[0], tt.contains)
+		}
+	}
+}
+
+func TestAppriseJSON(t *testing.T) {
+	var got map[string]string
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	target := "json://" + strings.TrimPrefix(srv.URL, "http://") + "/hook?+X-Api-Key=secret"
+	if err := NewAppriseNotifier("").Send(context.Background(), target, Message{Subject: "Trash", Body: "Now"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if got["title"] != "Trash" || got["message"] != "Now" {
+		t.Errorf("unexpected payload: %v", got)
+	}
+	if gotHeader.Get("X-Api-Key") != "secret" {
+		t.Errorf("expected custom header, got %v", gotHeader)
+	}
+}
+
+func TestAppriseAPIFallback(t *testing.T) {
+	var got map[string]string
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	target := "pover://user@token"
+	if err := NewAppriseNotifier("").Validate(target); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("expected unsupported scheme without API server, got %v", err)
+	}
+	a := NewAppriseNotifier(srv.URL)
+	if err := a.Send(context.Background(), target, Message{Subject: "Trash", Body: "Now"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotPath != "/notify/" || got["urls"] != target || got["title"] != "Trash" {
+		t.Errorf("unexpected forwarded request: %s %v", gotPath, got)
+	}
+}
+
+func TestAppriseValidate(t *testing.T) {
+	a := NewAppriseNotifier("")
+	for _, target := range []string{"not a url", "discord://only-id", "slack://a/b", "gotify://host", "json://"} {
+		if err := a.Validate(target); err == nil {
+			t.Errorf("expected %q to be rejected", target)
+		}
+	}
+}
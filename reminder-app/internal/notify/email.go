@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// EmailNotifier sends messages over SMTP. The target is an email address.
+type EmailNotifier struct {
+	// Addr is the SMTP server as host:port
+	Addr string
+	From string
+	// Username and Password enable PLAIN authentication when set
+	Username string
+	Password string
+	// SendMail delivers the message; defaults to smtp.SendMail
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailNotifier creates a notifier that relays through the SMTP server at
+// addr
+func NewEmailNotifier(addr, from, username, password string) *EmailNotifier {
+	return &EmailNotifier{Addr: addr, From: from, Username: username, Password: password}
+}
+
+// Validate checks that the target is a single email address
+func (n *EmailNotifier) Validate(target string) error {
+	addr, err := mail.ParseAddress(target)
+	if err != nil {
+		return err
+	}
+	if addr.Name != "" {
+		return fmt.Errorf("email target must be a bare address: %s", target)
+	}
+	return nil
+}
+
+func (n *EmailNotifier) Send(ctx context.Context, target string, msg Message) error {
+	if err := n.Validate(target); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if n.Username != "" {
+		host, _, err := net.SplitHostPort(n.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+	send := n.SendMail
+	if send == nil {
+		send = smtp.SendMail
+	}
+	// smtp.SendMail doesn't take a context, so cancellation only applies
+	// before the connection is made
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return send(n.Addr, auth, n.From, []string{target}, n.compose(target, msg))
+}
+
+// compose builds an RFC 5322 plain text message, or a multipart one with
+// the text first when there are attachments. An HTML body is sent as an
+// alternative to the text.
+func (n *EmailNotifier) compose(to string, msg Message) []byte {
+	body := msg.Body
+	if msg.Link != "" {
+		body += "\n\n" + msg.Link
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", n.From)
+	fmt.Fprintf(&sb, "To: %s\r\n", to)
+	if addr, err := mail.ParseAddress(msg.ReplyTo); err == nil {
+		fmt.Fprintf(&sb, "Reply-To: %s\r\n", addr.Address)
+	}
+	fmt.Fprintf(&sb, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&sb, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	if len(msg.Attachments) == 0 && msg.HTML == "" {
+		sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		sb.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		sb.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+		sb.WriteString("\r\n")
+		return []byte(sb.String())
+	}
+	if len(msg.Attachments) == 0 {
+		mw := multipart.NewWriter(&sb)
+		fmt.Fprintf(&sb, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+		writeAlternatives(mw, body, msg.HTML)
+		return []byte(sb.String())
+	}
+
+	mw := multipart.NewWriter(&sb)
+	fmt.Fprintf(&sb, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	if msg.HTML == "" {
+		part, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"text/plain; charset=utf-8"},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		io.WriteString(part, strings.ReplaceAll(body, "\n", "\r\n")+"\r\n")
+	} else {
+		boundary := multipart.NewWriter(nil).Boundary()
+		part, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"multipart/alternative; boundary=" + boundary},
+		})
+		alt := multipart.NewWriter(part)
+		alt.SetBoundary(boundary)
+		writeAlternatives(alt, body, msg.HTML)
+	}
+	for _, a := range msg.Attachments {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", a.ContentType)
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+		h.Set("Content-Transfer-Encoding", "base64")
+		part, _ := mw.CreatePart(h)
+		enc := base64.StdEncoding.EncodeToString(a.Data)
+		for len(enc) > 76 {
+			io.WriteString(part, enc[:76]+"\r\n")
+			enc = enc[76:]
+		}
+		io.WriteString(part, enc+"\r\n")
+	}
+	mw.Close()
+	return []byte(sb.String())
+}
+
+// writeAlternatives writes the text and HTML parts of a
+// multipart/alternative body, least preferred first, and closes it. The HTML
+// is quoted-printable so long lines stay within SMTP's limit.
+func writeAlternatives(mw *multipart.Writer, text, html string) {
+	part, _ := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	io.WriteString(part, strings.ReplaceAll(text, "\n", "\r\n")+"\r\n")
+	part, _ = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	qp := quotedprintable.NewWriter(part)
+	io.WriteString(qp, html)
+	qp.Close()
+	mw.Close()
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GotifyNotifier pushes messages to a Gotify server. The target is an
+// application token on the configured server, or a full URL of the form
+// "https://gotify.example.org?token=APPTOKEN" for a member's own server.
+type GotifyNotifier struct {
+	Server   string
+	Priority int
+	Client   *http.Client
+}
+
+// NewGotifyNotifier creates a notifier for the given Gotify server. The
+// server may be empty if every target is a full URL.
+func NewGotifyNotifier(server string) *GotifyNotifier {
+	return &GotifyNotifier{
+		Server:   strings.TrimSuffix(server, "/"),
+		Priority: 5,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type gotifyMessage struct {
+	Title    string         `json:"title,omitempty"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+func (g *GotifyNotifier) Send(ctx context.Context, target string, msg Message) error {
+	server, token := g.Server, target
+	if isURL(target) {
+		u, err := url.Parse(target)
+		if err != nil {
+			return err
+		}
+		token = u.Query().Get("token")
+		u.RawQuery = ""
+		server = strings.TrimSuffix(u.String(), "/")
+	}
+	if server == "" {
+		return errors.New("gotify target needs a server URL")
+	}
+	if token == "" {
+		return errors.New("gotify target needs an application token")
+	}
+
+	m := gotifyMessage{Title: msg.Subject, Message: msg.Body, Priority: g.Priority}
+	if msg.Link != "" {
+		m.Message += "\n" + msg.Link
+		m.Extras = map[string]any{
+			"client::notification": map[string]any{"click": map[string]string{"url": msg.Link}},
+		}
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+"/message", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", token)
+	return doRequest(g.Client, req)
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MatrixNotifier posts messages to Matrix rooms through the client-server
+// API using a bot account's access token. The target is a room ID such as
+// "!abc123:example.org"; the bot must already be joined to the room.
+type MatrixNotifier struct {
+	Homeserver  string
+	AccessToken string
+	Client      *http.Client
+
+	txn atomic.Int64
+}
+
+// NewMatrixNotifier creates a notifier for the given homeserver base URL
+func NewMatrixNotifier(homeserver, accessToken string) *MatrixNotifier {
+	return &MatrixNotifier{
+		Homeserver:  strings.TrimSuffix(homeserver, "/"),
+		AccessToken: accessToken,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+func (m *MatrixNotifier) Send(ctx context.Context, roomID string, msg Message) error {
+	formatted := html.EscapeString(msg.Body)
+	if msg.Subject != "" {
+		formatted = "<strong>" + html.EscapeString(msg.Subject) + "</strong><br>" + formatted
+	}
+	if msg.Link != "" {
+		formatted += `<br><a href="` + html.EscapeString(msg.Link) + `">Mark done</a>`
+	}
+	payload, err := json.Marshal(matrixMessage{
+		MsgType:       "m.text",
+		Body:          msg.Text(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The transaction ID makes retries of the same request idempotent
+	txnID := fmt.Sprintf("reminder-%d-%d", time.Now().UnixNano(), m.txn.Add(1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.Homeserver, url.PathEscape(roomID), url.PathEscape(txnID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(m.Client, req)
+}
@@ -0,0 +1,187 @@
+// Package notify delivers reminder notifications over pluggable channels.
+// Each channel type (Matrix, ntfy, email, ...) is a Notifier; a Dispatcher
+// routes messages to the notifier matching a configured family.Channel.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"reminder-app/internal/breaker"
+	"reminder-app/internal/family"
+)
+
+// Message is a notification to deliver
+type Message struct {
+	Subject string
+	Body    string
+	// HTML is an optional rich version of Body. Only email sends it, as
+	// an alternative to the text.
+	HTML string
+	// Link is an optional action URL, such as a signed completion link
+	Link string
+	// ReplyTo is an optional address replies go to, such as one that
+	// accepts "done" in reply to a reminder. Only email uses it.
+	ReplyTo string
+	// Attachments are only delivered by channels that can carry files,
+	// such as email; others send the text alone
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Text renders the message as plain text for channels without formatting
+func (m Message) Text() string {
+	text := m.Body
+	if m.Subject != "" {
+		text = m.Subject + "\n" + text
+	}
+	if m.Link != "" {
+		text += "\n" + m.Link
+	}
+	return text
+}
+
+// Notifier delivers messages over one channel type. The target is the
+// channel-specific address, e.g. a Matrix room ID.
+type Notifier interface {
+	Send(ctx context.Context, target string, msg Message) error
+}
+
+// Validator is implemented by notifiers that can check a target address
+// up front, so bad configuration is rejected when it is saved
+type Validator interface {
+	Validate(target string) error
+}
+
+// ErrUnknownChannel is returned when no notifier handles a channel type
+var ErrUnknownChannel = errors.New("unknown notification channel type")
+
+// Dispatcher routes messages to the notifier registered for a channel type.
+// Each channel type has a circuit breaker, so a service that is down fails
+// deliveries at once rather than tying up the scheduler with timeouts.
+type Dispatcher struct {
+	mu        sync.RWMutex
+	notifiers map[string]Notifier
+	breakers  map[string]*breaker.Breaker
+}
+
+// NewDispatcher creates a dispatcher with no channels registered
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{notifiers: make(map[string]Notifier), breakers: make(map[string]*breaker.Breaker)}
+}
+
+// breaker returns the circuit breaker of a channel type
+func (d *Dispatcher) breaker(channelType string) *breaker.Breaker {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b := d.breakers[channelType]
+	if b == nil {
+		b = breaker.New("notify " + channelType)
+		d.breakers[channelType] = b
+	}
+	return b
+}
+
+// Breakers returns the state of the breaker of each channel type that has
+// been sent to
+func (d *Dispatcher) Breakers() map[string]breaker.State {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	states := make(map[string]breaker.State, len(d.breakers))
+	for t, b := range d.breakers {
+		states[t] = b.State()
+	}
+	return states
+}
+
+// Register makes a notifier available under the given channel type
+func (d *Dispatcher) Register(channelType string, n Notifier) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifiers[channelType] = n
+}
+
+// Replace swaps every registered notifier for the given ones at once, so
+// channels can be reconfigured while messages are being sent
+func (d *Dispatcher) Replace(notifiers map[string]Notifier) {
+	m := make(map[string]Notifier, len(notifiers))
+	for t, n := range notifiers {
+		m[t] = n
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifiers = m
+}
+
+// Supports reports whether a notifier is registered for the channel type
+func (d *Dispatcher) Supports(channelType string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.notifiers[channelType]
+	return ok
+}
+
+// Types returns the registered channel types in sorted order
+func (d *Dispatcher) Types() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	types := make([]string, 0, len(d.notifiers))
+	for t := range d.notifiers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks that a channel has a registered notifier and, when the
+// notifier supports it, that the target is well formed
+func (d *Dispatcher) Validate(ch family.Channel) error {
+	d.mu.RLock()
+	n, ok := d.notifiers[ch.Type]
+	d.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownChannel, ch.Type)
+	}
+	if v, ok := n.(Validator); ok {
+		return v.Validate(ch.Target)
+	}
+	return nil
+}
+
+// Send delivers msg to a single channel
+func (d *Dispatcher) Send(ctx context.Context, ch family.Channel, msg Message) error {
+	d.mu.RLock()
+	n, ok := d.notifiers[ch.Type]
+	d.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownChannel, ch.Type)
+	}
+	// A caller giving up says nothing about the service
+	failed := func(error) bool { return ctx.Err() == nil }
+	err := d.breaker(ch.Type).Do(func() error { return n.Send(ctx, ch.Target, msg) }, failed)
+	if err != nil && !errors.Is(err, breaker.ErrOpen) {
+		return fmt.Errorf("%s: %w", ch.Type, err)
+	}
+	return err
+}
+
+// SendAll delivers msg to every channel, returning the joined errors of the
+// deliveries that failed
+func (d *Dispatcher) SendAll(ctx context.Context, channels []family.Channel, msg Message) error {
+	var errs []error
+	for _, ch := range channels {
+		if err := d.Send(ctx, ch, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
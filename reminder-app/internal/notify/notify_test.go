@@ -0,0 +1,294 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"reminder-app/internal/breaker"
+	"reminder-app/internal/family"
+	"reminder-app/pkg/client"
+)
+
+func TestMatrixNotifier(t *testing.T) {
+	var gotPath, gotAuth string
+	var got matrixMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.EscapedPath(), r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"event_id": "$1"}`))
+	}))
+	defer srv.Close()
+
+	n := NewMatrixNotifier(srv.URL+"/", "token123")
+	err := n.Send(context.Background(), "!room:example.org", Message{Subject: "Trash", Body: "Take out the <trash>", Link: "https://x/c/t"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if !strings.HasPrefix(gotPath, "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/") {
+		t.Errorf("unexpected path: %s", gotPath)
+	}
+	if gotAuth != "Bearer token123" {
+		t.Errorf("unexpected authorization header: %s", gotAuth)
+	}
+	if got.MsgType != "m.text" || !strings.Contains(got.Body, "Take out the <trash>") {
+		t.Errorf("unexpected message: %+v", got)
+	}
+	if !strings.Contains(got.FormattedBody, "&lt;trash&gt;") {
+		t.Errorf("formatted body is not escaped: %s", got.FormattedBody)
+	}
+}
+
+func TestMatrixNotifierError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errcode": "M_FORBIDDEN"}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := NewMatrixNotifier(srv.URL, "bad").Send(context.Background(), "!room:example.org", Message{Body: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "M_FORBIDDEN") {
+		t.Errorf("expected forbidden error, got %v", err)
+	}
+}
+
+type recordingNotifier struct {
+	targets []string
+	err     error
+}
+
+func (r *recordingNotifier) Send(ctx context.Context, target string, msg Message) error {
+	r.targets = append(r.targets, target)
+	return r.err
+}
+
+func TestDispatcher(t *testing.T) {
+	d := NewDispatcher()
+	ok := &recordingNotifier{}
+	failing := &recordingNotifier{err: errors.New("boom")}
+	d.Register("ok", ok)
+	d.Register("failing", failing)
+
+	err := d.SendAll(context.Background(), []family.Channel{
+		{Type: "ok", Target: "a"},
+		{Type: "failing", Target: "b"},
+		{Type: "missing", Target: "c"},
+	}, Message{Body: "hi"})
+	if len(ok.targets) != 1 || len(failing.targets) != 1 {
+		t.Errorf("expected every known channel to be attempted, got %v %v", ok.targets, failing.targets)
+	}
+	if !errors.Is(err, ErrUnknownChannel) || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected joined errors, got %v", err)
+	}
+	if got := d.Types(); len(got) != 2 || got[0] != "failing" {
+		t.Errorf("unexpected types: %v", got)
+	}
+}
+
+func TestDispatcherBreaker(t *testing.T) {
+	d := NewDispatcher()
+	failing := &recordingNotifier{err: errors.New("boom")}
+	d.Register("failing", failing)
+	ch := family.Channel{Type: "failing", Target: "b"}
+	for i := 0; i < breaker.DefaultThreshold; i++ {
+		d.Send(context.Background(), ch, Message{Body: "hi"})
+	}
+	if got := d.Breakers()["failing"]; got != breaker.Open {
+		t.Fatalf("breaker is %s, want open", got)
+	}
+	if err := d.Send(context.Background(), ch, Message{Body: "hi"}); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("expected ErrOpen, got %v", err)
+	}
+	if len(failing.targets) != breaker.DefaultThreshold {
+		t.Errorf("open breaker still sent: %d attempts", len(failing.targets))
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	var gotPath, gotBody string
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotPath, gotBody, gotHeader = r.URL.Path, string(b), r.Header
+	}))
+	defer srv.Close()
+
+	n := NewNtfyNotifier(srv.URL, "tk_secret")
+	msg := Message{Subject: "Trash", Body: "Take out the trash", Link: "https://x/c/t"}
+	if err := n.Send(context.Background(), "smith-family", msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotPath != "/smith-family" || gotBody != "Take out the trash" {
+		t.Errorf("unexpected request: %s %q", gotPath, gotBody)
+	}
+	if gotHeader.Get("Title") != "Trash" || gotHeader.Get("Click") != "https://x/c/t" {
+		t.Errorf("unexpected headers: %v", gotHeader)
+	}
+	if gotHeader.Get("Authorization") != "Bearer tk_secret" {
+		t.Errorf("expected token on configured server, got %q", gotHeader.Get("Authorization"))
+	}
+
+	// A full topic URL goes to that server without the default token
+	if err := n.Send(context.Background(), srv.URL+"/alice", msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotPath != "/alice" || gotHeader.Get("Authorization") != "" {
+		t.Errorf("unexpected request for topic URL: %s %q", gotPath, gotHeader.Get("Authorization"))
+	}
+}
+
+func TestGotifyNotifier(t *testing.T) {
+	var gotPath, gotKey string
+	var got gotifyMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotKey = r.URL.Path, r.Header.Get("X-Gotify-Key")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	msg := Message{Subject: "Trash", Body: "Take out the trash"}
+	if err := NewGotifyNotifier(srv.URL).Send(context.Background(), "apptoken", msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotPath != "/message" || gotKey != "apptoken" || got.Title != "Trash" || got.Message != "Take out the trash" {
+		t.Errorf("unexpected request: %s %s %+v", gotPath, gotKey, got)
+	}
+
+	if err := NewGotifyNotifier("").Send(context.Background(), srv.URL+"?token=other", msg); err != nil {
+		t.Fatalf("Send with URL target failed: %v", err)
+	}
+	if gotKey != "other" {
+		t.Errorf("expected token from target URL, got %s", gotKey)
+	}
+
+	if err := NewGotifyNotifier("").Send(context.Background(), "apptoken", msg); err == nil {
+		t.Error("expected error without a server")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got client.Notification
+	var hits int
+	srv := httptest.NewServer(client.NewVerifier([]byte("s3cret")).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		json.NewDecoder(r.Body).Decode(&got)
+	})))
+	defer srv.Close()
+
+	msg := Message{Subject: "Trash", Body: "Take out the trash", Link: "https://example.com/done"}
+	if err := NewWebhookNotifier("s3cret").Send(context.Background(), srv.URL, msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if hits != 1 || got.Subject != "Trash" || got.Body != "Take out the trash" || got.Link != msg.Link || got.SentAt.IsZero() {
+		t.Errorf("unexpected delivery: %+v", got)
+	}
+
+	if err := NewWebhookNotifier("wrong").Send(context.Background(), srv.URL, msg); err == nil {
+		t.Error("expected delivery signed with the wrong secret to be rejected")
+	}
+	if err := NewWebhookNotifier("s3cret").Validate("ftp://example.com"); err == nil {
+		t.Error("expected non-http target to be invalid")
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg string
+	n := NewEmailNotifier("smtp.example.com:587", "reminders@example.com", "bot", "pw")
+	n.SendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, string(msg)
+		if a == nil {
+			t.Error("expected authentication with a username set")
+		}
+		return nil
+	}
+
+	msg := Message{Subject: "Trash night", Body: "Take out the trash", Link: "https://example.com/c/x"}
+	if err := n.Send(context.Background(), "alice@example.com", msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotAddr != "smtp.example.com:587" || gotFrom != "reminders@example.com" || len(gotTo) != 1 || gotTo[0] != "alice@example.com" {
+		t.Errorf("unexpected envelope: %s %s %v", gotAddr, gotFrom, gotTo)
+	}
+	for _, want := range []string{"To: alice@example.com\r\n", "Subject: Trash night\r\n", "\r\n\r\nTake out the trash\r\n\r\nhttps://example.com/c/x"} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("message missing %q:\n%s", want, gotMsg)
+		}
+	}
+
+	msg.Attachments = []Attachment{{Name: "report.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")}}
+	if err := n.Send(context.Background(), "alice@example.com", msg); err != nil {
+		t.Fatalf("Send with attachment failed: %v", err)
+	}
+	for _, want := range []string{"Content-Type: multipart/mixed; boundary=", "Content-Disposition: attachment; filename=report.pdf", "JVBERi0xLjQ=", "Take out the trash"} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("message with attachment missing %q:\n%s", want, gotMsg)
+		}
+	}
+
+	msg.HTML = "<p>Take out the <b>trash</b></p>"
+	if err := n.Send(context.Background(), "alice@example.com", msg); err != nil {
+		t.Fatalf("Send with HTML failed: %v", err)
+	}
+	for _, want := range []string{"Content-Type: multipart/mixed; boundary=", "Content-Type: multipart/alternative; boundary=", "Content-Type: text/html; charset=utf-8", "<p>Take out the <b>trash</b></p>", "JVBERi0xLjQ="} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("message with HTML missing %q:\n%s", want, gotMsg)
+		}
+	}
+	if strings.Index(gotMsg, "text/plain") > strings.Index(gotMsg, "text/html") {
+		t.Errorf("expected the text before the HTML:\n%s", gotMsg)
+	}
+	msg.Attachments = nil
+	n.Send(context.Background(), "alice@example.com", msg)
+	if !strings.Contains(gotMsg, "MIME-Version: 1.0\r\nContent-Type: multipart/alternative; boundary=") || strings.Contains(gotMsg, "multipart/mixed") {
+		t.Errorf("expected an alternative message without attachments:\n%s", gotMsg)
+	}
+
+	if err := n.Send(context.Background(), "Alice <alice@example.com>", msg); err == nil {
+		t.Error("expected display-name target to be rejected")
+	}
+	injected := Message{Subject: "Hi\r\nBcc: eve@example.com", Body: "x", ReplyTo: "a@example.com\r\nBcc: eve@example.com"}
+	n.Send(context.Background(), "alice@example.com", injected)
+	if strings.Contains(gotMsg, "\r\nBcc:") {
+		t.Errorf("subject or reply-to allowed header injection:\n%s", gotMsg)
+	}
+
+	msg = Message{Subject: "Trash night", Body: "Take out the trash", ReplyTo: "reply+abc@example.com"}
+	n.Send(context.Background(), "alice@example.com", msg)
+	if !strings.Contains(gotMsg, "Reply-To: reply+abc@example.com\r\n") {
+		t.Errorf("message missing Reply-To:\n%s", gotMsg)
+	}
+}
+
+func TestSMSNotifier(t *testing.T) {
+	var gotPath, gotUser, gotPass string
+	var gotForm map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotUser, gotPass, _ = r.BasicAuth()
+		r.ParseForm()
+		gotForm = r.PostForm
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	n := NewSMSNotifier(srv.URL, "AC123", "secret", "+15005550006")
+	if err := n.Send(context.Background(), "+14155550123", Message{Subject: "Trash", Body: "Take out the trash"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotPath != "/2010-04-01/Accounts/AC123/Messages.json" || gotUser != "AC123" || gotPass != "secret" {
+		t.Errorf("unexpected request: %s %s:%s", gotPath, gotUser, gotPass)
+	}
+	if gotForm["To"][0] != "+14155550123" || gotForm["From"][0] != "+15005550006" || gotForm["Body"][0] != "Trash\nTake out the trash" {
+		t.Errorf("unexpected form: %v", gotForm)
+	}
+	if err := n.Validate("555-0123"); err == nil {
+		t.Error("expected non-E.164 number to be rejected")
+	}
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NtfyNotifier publishes messages to ntfy topics. The target is either a
+// topic name on the configured server or a full topic URL, which lets a
+// member use their own self-hosted ntfy instance.
+type NtfyNotifier struct {
+	Server string
+	// Token is an optional access token for protected topics
+	Token  string
+	Client *http.Client
+}
+
+// NewNtfyNotifier creates a notifier for the given ntfy server, such as
+// "https://ntfy.sh"
+func NewNtfyNotifier(server, token string) *NtfyNotifier {
+	return &NtfyNotifier{
+		Server: strings.TrimSuffix(server, "/"),
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *NtfyNotifier) Send(ctx context.Context, topic string, msg Message) error {
+	endpoint := topic
+	if !isURL(topic) {
+		endpoint = n.Server + "/" + strings.TrimPrefix(topic, "/")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(msg.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if msg.Subject != "" {
+		req.Header.Set("Title", msg.Subject)
+	}
+	if msg.Link != "" {
+		req.Header.Set("Click", msg.Link)
+		req.Header.Set("Actions", "view, Mark done, "+msg.Link+", clear=true")
+	}
+	if n.Token != "" && !isURL(topic) {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+	return doRequest(n.Client, req)
+}
+
+// isURL reports whether a channel target is an absolute http(s) URL rather
+// than a name on the configured server
+func isURL(target string) bool {
+	return strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "http://")
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// e164 matches international phone numbers such as "+14155550123"
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// SMSNotifier sends text messages through Twilio's Messages API, or any
+// provider exposing the same interface. The target is a phone number in
+// E.164 format.
+type SMSNotifier struct {
+	// APIURL is the API base, "https://api.twilio.com" for Twilio
+	APIURL     string
+	AccountSID string
+	AuthToken  string
+	// From is the sending phone number or messaging service SID
+	From   string
+	Client *http.Client
+}
+
+// NewSMSNotifier creates a notifier for the given Twilio account
+func NewSMSNotifier(apiURL, accountSID, authToken, from string) *SMSNotifier {
+	return &SMSNotifier{
+		APIURL:     strings.TrimSuffix(apiURL, "/"),
+		AccountSID: accountSID,
+		AuthToken:  authToken,
+		From:       from,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Validate checks that the target is an E.164 phone number
+func (n *SMSNotifier) Validate(target string) error {
+	if !e164.MatchString(target) {
+		return errors.New("sms target must be a phone number like +14155550123")
+	}
+	return nil
+}
+
+func (n *SMSNotifier) Send(ctx context.Context, target string, msg Message) error {
+	if err := n.Validate(target); err != nil {
+		return err
+	}
+	// Text messages have no subject line, so it leads the body
+	form := url.Values{"To": {target}, "Body": {msg.Text()}}
+	if strings.HasPrefix(n.From, "MG") {
+		form.Set("MessagingServiceSid", n.From)
+	} else {
+		form.Set("From", n.From)
+	}
+	endpoint := n.APIURL + "/2010-04-01/Accounts/" + url.PathEscape(n.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(n.AccountSID, n.AuthToken)
+	return doRequest(n.Client, req)
+}
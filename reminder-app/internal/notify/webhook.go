@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"reminder-app/pkg/client"
+)
+
+// WebhookNotifier POSTs a JSON client.Notification to the target URL. Each
+// delivery is signed with the shared secret so receivers can check it with
+// client.Verifier.
+type WebhookNotifier struct {
+	Secret []byte
+	Client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier that signs deliveries with secret
+func NewWebhookNotifier(secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		Secret: []byte(secret),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Validate checks that the target is an absolute http(s) URL
+func (n *WebhookNotifier) Validate(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook target must be an http or https URL")
+	}
+	return nil
+}
+
+func (n *WebhookNotifier) Send(ctx context.Context, target string, msg Message) error {
+	if err := n.Validate(target); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(client.Notification{
+		Subject: msg.Subject,
+		Body:    msg.Body,
+		Link:    msg.Link,
+		SentAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client.SignRequest(req, n.Secret, payload)
+	return doRequest(n.Client, req)
+}
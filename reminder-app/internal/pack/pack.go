@@ -0,0 +1,216 @@
+// Package pack reads community-shared reminder template packs. A pack is a
+// JSON manifest of reminder templates, such as a school year's chores,
+// published at a URL and signed with its publisher's Ed25519 key. A server
+// only accepts packs signed by a publisher it trusts.
+package pack
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// MaxSize bounds the size of a signed pack
+const MaxSize = 1 << 20
+
+var (
+	ErrSignature = errors.New("invalid pack signature")
+	ErrUntrusted = errors.New("pack publisher is not trusted")
+)
+
+// Template describes a reminder a pack can install
+type Template struct {
+	// ID identifies the template within its pack, so a subset can be
+	// selected for installation
+	ID          string                     `json:"id"`
+	Title       string                     `json:"title"`
+	Description string                     `json:"description,omitempty"`
+	Recurrence  reminder.RecurrencePattern `json:"recurrence"`
+	Effort      int                        `json:"effort,omitempty"`
+	// DueIn is when the first occurrence is due after installation, as a
+	// duration such as "168h". When empty, the reminder has no due date.
+	DueIn string `json:"due_in,omitempty"`
+}
+
+// Manifest is the content of a pack
+type Manifest struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Publisher   string     `json:"publisher"`
+	Version     string     `json:"version,omitempty"`
+	Templates   []Template `json:"templates"`
+}
+
+// Signed is the document served at a pack's URL. The signature covers the
+// manifest exactly as it appears, so it is kept as raw JSON.
+type Signed struct {
+	Manifest json.RawMessage `json:"manifest"`
+	// KeyID names the publisher key in a Keyring
+	KeyID     string `json:"key_id"`
+	Signature string `json:"signature"` // base64
+}
+
+// Keyring maps key IDs to the public keys of trusted publishers
+type Keyring map[string]ed25519.PublicKey
+
+// ParseKeyring parses a comma-separated list of key_id=key pairs, where
+// key is a base64 Ed25519 public key
+func ParseKeyring(spec string) (Keyring, error) {
+	keys := make(Keyring)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, enc, ok := strings.Cut(entry, "=")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key must be key_id=base64: %q", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %s is not a base64 Ed25519 public key", id)
+		}
+		keys[id] = ed25519.PublicKey(key)
+	}
+	return keys, nil
+}
+
+// Validate checks that every template can be installed
+func (m *Manifest) Validate() error {
+	if m.Name == "" {
+		return errors.New("pack has no name")
+	}
+	if len(m.Templates) == 0 {
+		return errors.New("pack has no templates")
+	}
+	seen := make(map[string]bool, len(m.Templates))
+	for i, t := range m.Templates {
+		if t.ID == "" || t.Title == "" {
+			return fmt.Errorf("templates[%d]: id and title are required", i)
+		}
+		if seen[t.ID] {
+			return fmt.Errorf("duplicate template id: %s", t.ID)
+		}
+		seen[t.ID] = true
+		if t.Effort < 0 {
+			return fmt.Errorf("template %s: effort must not be negative", t.ID)
+		}
+		if t.DueIn != "" {
+			if d, err := time.ParseDuration(t.DueIn); err != nil || d < 0 {
+				return fmt.Errorf("template %s: due_in must be a duration such as 168h: %q", t.ID, t.DueIn)
+			}
+		}
+	}
+	return nil
+}
+
+// Select returns the templates with the given IDs in the pack's order, or
+// all of them if ids is empty
+func (m *Manifest) Select(ids []string) ([]Template, error) {
+	if len(ids) == 0 {
+		return m.Templates, nil
+	}
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	var selected []Template
+	for _, t := range m.Templates {
+		if want[t.ID] {
+			selected = append(selected, t)
+			delete(want, t.ID)
+		}
+	}
+	for id := range want {
+		return nil, fmt.Errorf("unknown template: %s", id)
+	}
+	return selected, nil
+}
+
+// DueDate returns when the first occurrence of a template installed at now
+// is due, or nil if it has no due date
+func (t Template) DueDate(now time.Time) *time.Time {
+	d, err := time.ParseDuration(t.DueIn)
+	if t.DueIn == "" || err != nil {
+		return nil
+	}
+	due := now.Add(d)
+	return &due
+}
+
+// Sign returns the signed document of a manifest
+func Sign(m *Manifest, keyID string, key ed25519.PrivateKey) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Signed{
+		Manifest:  data,
+		KeyID:     keyID,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	})
+}
+
+// Verify checks the signature of a signed document against the trusted
+// keys and returns its validated manifest
+func Verify(data []byte, keys Keyring) (*Manifest, error) {
+	var s Signed
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid pack: %w", err)
+	}
+	key, ok := keys[s.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUntrusted, s.KeyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil || !ed25519.Verify(key, s.Manifest, sig) {
+		return nil, ErrSignature
+	}
+	var m Manifest
+	if err := json.Unmarshal(s.Manifest, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Fetch downloads the signed document at an http or https URL
+func Fetch(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("pack URL must be http or https: %q", rawURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("pack is larger than %d bytes", MaxSize)
+	}
+	return data, nil
+}
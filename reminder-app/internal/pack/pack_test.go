@@ -0,0 +1,108 @@
+package pack
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"reminder-app/internal/reminder"
+)
+
+func testManifest() *Manifest {
+	return &Manifest{
+		Name:      "School year",
+		Publisher: "Example",
+		Templates: []Template{
+			{ID: "lunch", Title: "Pack lunch", Recurrence: reminder.RecurrencePattern{Type: "daily"}, DueIn: "24h"},
+			{ID: "planner", Title: "Sign planner", Recurrence: reminder.RecurrencePattern{Type: "weekly", Days: []string{"friday"}}},
+		},
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	other, _, _ := ed25519.GenerateKey(nil)
+	keys, err := ParseKeyring("example=" + base64.StdEncoding.EncodeToString(pub))
+	if err != nil {
+		t.Fatalf("ParseKeyring failed: %v", err)
+	}
+
+	data, err := Sign(testManifest(), "example", priv)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	m, err := Verify(data, keys)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if m.Name != "School year" || len(m.Templates) != 2 {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	if _, err := Verify(data, Keyring{"example": other}); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected ErrSignature for another key, got %v", err)
+	}
+	if _, err := Verify(data, Keyring{}); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("expected ErrUntrusted, got %v", err)
+	}
+
+	// Changing the manifest breaks the signature
+	var s Signed
+	json.Unmarshal(data, &s)
+	s.Manifest = json.RawMessage(`{"name": "School year", "publisher": "Example", "templates": [{"id": "x", "title": "Send money"}]}`)
+	tampered, _ := json.Marshal(s)
+	if _, err := Verify(tampered, keys); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected ErrSignature for a changed manifest, got %v", err)
+	}
+
+	if _, err := ParseKeyring("example=notakey"); err == nil {
+		t.Error("expected an invalid key to be rejected")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	m := testManifest()
+	all, _ := m.Select(nil)
+	if len(all) != 2 {
+		t.Errorf("expected every template, got %d", len(all))
+	}
+	some, err := m.Select([]string{"planner"})
+	if err != nil || len(some) != 1 || some[0].ID != "planner" {
+		t.Errorf("unexpected selection: %+v, %v", some, err)
+	}
+	if _, err := m.Select([]string{"lunch", "recess"}); err == nil {
+		t.Error("expected an unknown template to be rejected")
+	}
+
+	m.Templates = append(m.Templates, Template{ID: "lunch", Title: "Again"})
+	if err := m.Validate(); err == nil {
+		t.Error("expected duplicate template IDs to be rejected")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pack.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"manifest": {}}`))
+	}))
+	defer srv.Close()
+
+	data, err := Fetch(context.Background(), srv.Client(), srv.URL+"/pack.json")
+	if err != nil || string(data) != `{"manifest": {}}` {
+		t.Errorf("unexpected fetch: %q, %v", data, err)
+	}
+	if _, err := Fetch(context.Background(), srv.Client(), srv.URL+"/missing.json"); err == nil {
+		t.Error("expected a 404 to fail")
+	}
+	if _, err := Fetch(context.Background(), srv.Client(), "file:///etc/passwd"); err == nil {
+		t.Error("expected a non-HTTP URL to be rejected")
+	}
+}
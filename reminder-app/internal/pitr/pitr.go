@@ -0,0 +1,287 @@
+// Package pitr captures restore points of the SQL backends, so operators
+// can bring the database back to how it was at a point in time, such as
+// just before an accidental bulk delete.
+//
+// PostgreSQL keeps a write-ahead log of its own. With WAL archiving set up
+// on the server (archive_mode = on, an archive_command, and a base backup
+// taken with pg_basebackup), a restore point is a position in that log:
+// named with pg_create_restore_point where the app's role may call it, and
+// otherwise just the LSN. SQLite has no log that can be archived, so there
+// a restore point is a snapshot of the database written to an archive
+// directory, and the database can be restored to any snapshot.
+//
+// Restore points are captured every Archiver.Interval and before permanent
+// deletes of whole families, and are listed at GET /admin/restore-points.
+// To restore, stop the server and run
+//
+//	reminderctl -storage sqlite -sqlite-db DB restore -archive DIR -to TIME
+//
+// which copies the last snapshot taken at or before TIME over DB, keeping
+// the current file as DB.before-restore, or
+//
+//	reminderctl -storage postgres -postgres-conn URL restore -to TIME
+//
+// which prints the recovery settings for the last restore point at or
+// before TIME. Restore the base backup into a fresh data directory, add the
+// settings to its postgresql.conf, create recovery.signal there and start
+// the server; it replays the archived WAL up to the restore point and
+// pauses for the result to be checked. Then run SELECT pg_wal_replay_resume()
+// to open it for writes.
+package pitr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of restore points
+const Kind = "restore_point"
+
+// Backends of restore points
+const (
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+// snapshotLayout formats the time of a SQLite snapshot into its file name,
+// so snapshots can be found without the records of the database they copy
+const snapshotLayout = "20060102T150405.000000000Z"
+
+// snapshotPrefix and snapshotSuffix surround the time in snapshot names
+const (
+	snapshotPrefix = "reminders-"
+	snapshotSuffix = ".sqlite"
+)
+
+// ErrUnsupported is returned for backends without restore points
+var ErrUnsupported = errors.New("point-in-time restore needs sqlite or postgres storage")
+
+// ErrNoPoint is returned when no restore point precedes the requested time
+var ErrNoPoint = errors.New("no restore point at or before that time")
+
+// Point is a restore point
+type Point struct {
+	ID        string    `json:"id"`
+	Reason    string    `json:"reason"`
+	Backend   string    `json:"backend"`
+	CreatedAt time.Time `json:"created_at"`
+	// LSN is the PostgreSQL WAL position, and Named is set when the point
+	// was created with pg_create_restore_point under its ID
+	LSN   string `json:"lsn,omitempty"`
+	Named bool   `json:"named,omitempty"`
+	// Snapshot is the file holding the SQLite copy
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// Archiver captures restore points of one backend
+type Archiver struct {
+	Store storage.Storage
+	// Dir is where SQLite snapshots are written
+	Dir string
+	// Interval is how often Run captures a restore point
+	Interval time.Duration
+	// Retention is how long restore points and their snapshots are kept
+	Retention time.Duration
+}
+
+// New returns an archiver for s, which must be SQLite or PostgreSQL storage,
+// keeping restore points for a week. SQLite storage needs the directory to
+// write snapshots to.
+func New(s storage.Storage, dir string) (*Archiver, error) {
+	switch storage.Unwrap(s).(type) {
+	case *storage.SQLiteStorage:
+		if dir == "" {
+			return nil, errors.New("sqlite restore points need an archive directory")
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create archive directory: %w", err)
+		}
+	case *storage.PostgresStorage:
+	default:
+		return nil, ErrUnsupported
+	}
+	return &Archiver{Store: s, Dir: dir, Interval: time.Hour, Retention: 7 * 24 * time.Hour}, nil
+}
+
+// Capture creates a restore point, recording why
+func (a *Archiver) Capture(reason string, now time.Time) (*Point, error) {
+	p := &Point{ID: storage.NewRecordID("rp"), Reason: reason, CreatedAt: now.UTC()}
+	switch db := storage.Unwrap(a.Store).(type) {
+	case *storage.SQLiteStorage:
+		p.Backend = BackendSQLite
+		p.Snapshot = filepath.Join(a.Dir, snapshotPrefix+p.CreatedAt.Format(snapshotLayout)+snapshotSuffix)
+		if err := db.Snapshot(p.Snapshot); err != nil {
+			return nil, err
+		}
+	case *storage.PostgresStorage:
+		p.Backend = BackendPostgres
+		var err error
+		if p.LSN, p.Named, p.CreatedAt, err = db.RestorePoint(p.ID); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = p.CreatedAt.UTC()
+	default:
+		return nil, ErrUnsupported
+	}
+	expires := p.CreatedAt.Add(a.Retention)
+	rec := storage.Record{Kind: Kind, ID: p.ID, CreatedAt: p.CreatedAt, ExpiresAt: &expires}
+	if err := storage.PutJSON(a.Store, rec, p); err != nil {
+		return nil, err
+	}
+	log.Printf("Restore point %s (%s): %s%s", p.ID, reason, p.LSN, p.Snapshot)
+	return p, nil
+}
+
+// Prune deletes the SQLite snapshots older than the retention. Their
+// records expire by themselves.
+func (a *Archiver) Prune(now time.Time) (int, error) {
+	if a.Dir == "" {
+		return 0, nil
+	}
+	snapshots, err := Snapshots(a.Dir)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, p := range snapshots {
+		if p.CreatedAt.Add(a.Retention).After(now) {
+			continue
+		}
+		if err := os.Remove(p.Snapshot); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Run captures a restore point every Interval and prunes old snapshots
+// until ctx is done
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := a.Capture("scheduled", now); err != nil {
+				log.Printf("restore point: %v", err)
+			}
+			if _, err := a.Prune(now); err != nil {
+				log.Printf("restore point pruning: %v", err)
+			}
+		}
+	}
+}
+
+// List returns the recorded restore points, oldest first
+func List(s storage.Storage) ([]*Point, error) {
+	points, err := storage.ListJSON[Point](s, storage.RecordQuery{Kind: Kind})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(points, func(a, b *Point) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return points, nil
+}
+
+// Snapshots returns the SQLite snapshots in dir, oldest first, read from
+// the file names
+func Snapshots(dir string) ([]*Point, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var points []*Point
+	for _, e := range entries {
+		stamp, hasPrefix := strings.CutPrefix(e.Name(), snapshotPrefix)
+		stamp, hasSuffix := strings.CutSuffix(stamp, snapshotSuffix)
+		if !hasPrefix || !hasSuffix || e.IsDir() {
+			continue
+		}
+		at, err := time.Parse(snapshotLayout, stamp)
+		if err != nil {
+			continue
+		}
+		points = append(points, &Point{Backend: BackendSQLite, CreatedAt: at, Snapshot: filepath.Join(dir, e.Name())})
+	}
+	slices.SortFunc(points, func(a, b *Point) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return points, nil
+}
+
+// Latest returns the last of points, sorted oldest first, created at or
+// before t
+func Latest(points []*Point, t time.Time) (*Point, error) {
+	for i := len(points) - 1; i >= 0; i-- {
+		if !points[i].CreatedAt.After(t) {
+			return points[i], nil
+		}
+	}
+	return nil, ErrNoPoint
+}
+
+// RestoreSQLite replaces the database at dbPath with the last snapshot in
+// dir taken at or before t, and returns the snapshot. The current database
+// and its journal files are kept with a .before-restore suffix. The server
+// must not be running.
+func RestoreSQLite(dbPath, dir string, t time.Time) (*Point, error) {
+	snapshots, err := Snapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Latest(snapshots, t)
+	if err != nil {
+		return nil, err
+	}
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		if err := os.Rename(dbPath+suffix, dbPath+suffix+".before-restore"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to keep the current database: %w", err)
+		}
+	}
+	if err := copyFile(p.Snapshot, dbPath); err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	return p, nil
+}
+
+// RecoverySettings returns the postgresql.conf settings that make a
+// restored base backup replay the archived WAL up to p and pause
+func RecoverySettings(p *Point) string {
+	target := fmt.Sprintf("recovery_target_lsn = '%s'", p.LSN)
+	if p.Named {
+		target = fmt.Sprintf("recovery_target_name = '%s'", p.ID)
+	}
+	return fmt.Sprintf(`# Restore point %s (%s) at %s
+restore_command = 'cp /path/to/wal-archive/%%f %%p'  # the inverse of archive_command
+%s
+recovery_target_inclusive = on
+recovery_target_action = 'pause'
+`, p.ID, p.Reason, p.CreatedAt.Format(time.RFC3339), target)
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,95 @@
+package pitr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/storage"
+)
+
+func TestSQLiteRestore(t *testing.T) {
+	if _, err := New(storage.NewMemoryStorage(), ""); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected memory storage to be unsupported, got %v", err)
+	}
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "live.db")
+	db, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create SQLite storage: %v", err)
+	}
+	if _, err := New(db, ""); err == nil {
+		t.Error("expected SQLite storage to need an archive directory")
+	}
+	a, err := New(db, filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	start := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	_ = db.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	first, err := a.Capture("scheduled", start)
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	_ = db.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Carol"}})
+	if _, err := a.Capture("before permanently deleting family fam1", start.Add(time.Hour)); err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	_ = db.DeleteFamily("fam1")
+
+	points, err := List(db)
+	if err != nil || len(points) != 2 || points[0].ID != first.ID || points[1].Backend != BackendSQLite {
+		t.Fatalf("expected both restore points listed, got %+v %v", points, err)
+	}
+	db.Close()
+
+	if _, err := RestoreSQLite(dbPath, a.Dir, start.Add(-time.Minute)); !errors.Is(err, ErrNoPoint) {
+		t.Errorf("expected no restore point before the first, got %v", err)
+	}
+	p, err := RestoreSQLite(dbPath, a.Dir, start.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("RestoreSQLite failed: %v", err)
+	}
+	if !p.CreatedAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the second snapshot, got %+v", p)
+	}
+	if _, err := os.Stat(dbPath + ".before-restore"); err != nil {
+		t.Errorf("expected the previous database kept: %v", err)
+	}
+	restored, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open restored database: %v", err)
+	}
+	defer restored.Close()
+	if _, err := restored.GetFamily("fam1"); err != nil {
+		t.Errorf("expected the deleted family back: %v", err)
+	}
+	if _, err := restored.GetFamily("fam2"); err != nil {
+		t.Errorf("expected the family created before the snapshot: %v", err)
+	}
+
+	a.Retention = 24 * time.Hour
+	if n, err := a.Prune(start.Add(24*time.Hour + 30*time.Minute)); err != nil || n != 1 {
+		t.Errorf("expected the first snapshot pruned, got %d %v", n, err)
+	}
+	if left, _ := Snapshots(a.Dir); len(left) != 1 {
+		t.Errorf("expected one snapshot left, got %+v", left)
+	}
+}
+
+func TestRecoverySettings(t *testing.T) {
+	at := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	p := &Point{ID: "rp1", Reason: "scheduled", Backend: BackendPostgres, CreatedAt: at, LSN: "0/16B3748"}
+	if got := RecoverySettings(p); !strings.Contains(got, "recovery_target_lsn = '0/16B3748'") {
+		t.Errorf("expected an LSN target, got\n%s", got)
+	}
+	p.Named = true
+	if got := RecoverySettings(p); !strings.Contains(got, "recovery_target_name = 'rp1'") {
+		t.Errorf("expected a named target, got\n%s", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Package preferences stores each family member's UI preferences, such as
+// the list they start on and how it's sorted, so they follow the member
+// from device to device
+package preferences
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of preferences
+const Kind = "preferences"
+
+// Themes of the UI
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Preferences are one member's UI settings. Empty fields leave the choice
+// to the client.
+type Preferences struct {
+	// Filter is the reminder list shown by default
+	Filter smartlist.Query `json:"filter"`
+	// Sort orders reminder lists, in the form reminder.ParseSort accepts
+	Sort string `json:"sort,omitempty"`
+	// Theme is "system", "light" or "dark"
+	Theme string `json:"theme,omitempty"`
+	// StartPage is the client path opened first, such as "/today"
+	StartPage string `json:"start_page,omitempty"`
+	// UpdatedAt is when the preferences were last saved
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Validate checks the preferences for unknown values
+func (p Preferences) Validate() error {
+	if err := p.Filter.Validate(); err != nil {
+		return err
+	}
+	if _, err := reminder.ParseSort(p.Sort); err != nil {
+		return err
+	}
+	switch p.Theme {
+	case "", ThemeSystem, ThemeLight, ThemeDark:
+	default:
+		return fmt.Errorf("unknown theme %q; supported: %s, %s, %s", p.Theme, ThemeSystem, ThemeLight, ThemeDark)
+	}
+	if p.StartPage != "" && (!strings.HasPrefix(p.StartPage, "/") || strings.HasPrefix(p.StartPage, "//")) {
+		return errors.New("start_page must be a path such as /today")
+	}
+	return nil
+}
+
+// id returns the record ID of a member's preferences
+func id(familyID, member string) string {
+	return familyID + "/" + member
+}
+
+// Get returns a member's preferences, which are empty until first saved
+func Get(s storage.Storage, familyID, member string) (*Preferences, error) {
+	p, err := storage.GetJSON[Preferences](s, Kind, id(familyID, member))
+	if errors.Is(err, storage.ErrRecordNotFound) {
+		return &Preferences{}, nil
+	}
+	return p, err
+}
+
+// Put replaces a member's preferences
+func Put(s storage.Storage, familyID, member string, p *Preferences, now time.Time) error {
+	p.UpdatedAt = &now
+	rec := storage.Record{Kind: Kind, ID: id(familyID, member), FamilyID: familyID, Ref: member, CreatedAt: now}
+	return storage.PutJSON(s, rec, p)
+}
+
+// Delete removes a member's preferences, if any
+func Delete(s storage.Storage, familyID, member string) error {
+	if err := s.DeleteRecord(Kind, id(familyID, member)); err != nil && !errors.Is(err, storage.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
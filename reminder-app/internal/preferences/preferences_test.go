@@ -0,0 +1,56 @@
+package preferences
+
+import (
+	"testing"
+	"time"
+
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/storage"
+)
+
+func TestValidate(t *testing.T) {
+	from, to := 7, 0
+	backwards := smartlist.Query{DueFromDays: &from, DueToDays: &to}
+	tests := []struct {
+		name string
+		p    Preferences
+		ok   bool
+	}{
+		{"empty", Preferences{}, true},
+		{"everything", Preferences{Sort: "-due_date", Theme: ThemeDark, StartPage: "/today"}, true},
+		{"unknown sort", Preferences{Sort: "color"}, false},
+		{"unknown theme", Preferences{Theme: "neon"}, false},
+		{"relative start page", Preferences{StartPage: "today"}, false},
+		{"other host", Preferences{StartPage: "//example.com"}, false},
+		{"invalid filter", Preferences{Filter: backwards}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.p.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: expected ok=%v, got %v", tt.name, tt.ok, err)
+		}
+	}
+}
+
+func TestGetPut(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	p, err := Get(s, "fam1", "Alice")
+	if err != nil || *p != (Preferences{}) {
+		t.Fatalf("expected empty preferences, got %+v, %v", p, err)
+	}
+	now := time.Date(2025, 3, 4, 15, 0, 0, 0, time.UTC)
+	if err := Put(s, "fam1", "Alice", &Preferences{Theme: ThemeDark}, now); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := Get(s, "fam1", "Alice"); p.Theme != ThemeDark || p.UpdatedAt == nil || !p.UpdatedAt.Equal(now) {
+		t.Errorf("expected the saved preferences, got %+v", p)
+	}
+	if p, _ := Get(s, "fam1", "Bob"); p.Theme != "" {
+		t.Errorf("expected Bob's preferences separate, got %+v", p)
+	}
+	if err := Delete(s, "fam1", "Alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Delete(s, "fam1", "Alice"); err != nil {
+		t.Errorf("expected deleting again to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Package project groups related reminders, such as everything needed to
+// "Plan birthday party", under a container with its own due date and
+// aggregate progress.
+package project
+
+import (
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// Kind is the storage record kind of projects
+const Kind = "project"
+
+// Project is a named group of reminders within a family. Reminders join a
+// project by setting their project_id.
+type Project struct {
+	ID          string     `json:"id"`
+	FamilyID    string     `json:"family_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+}
+
+// Progress summarizes how many of a project's reminders are done
+type Progress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Percent   int `json:"percent"`
+}
+
+// Done reports whether r counts as done at the given time. Recurring
+// reminders never become Completed, so they count as done when completed
+// during their current occurrence period.
+func Done(r *reminder.Reminder, at time.Time) bool {
+	if r.Completed {
+		return true
+	}
+	if !r.IsRecurring() || r.CompletedAt == nil {
+		return false
+	}
+	from, _ := r.Period(at)
+	return !from.IsZero() && !r.CompletedAt.Before(from)
+}
+
+// Summarize computes the progress of a project's reminders at the given time
+func Summarize(list []*reminder.Reminder, at time.Time) Progress {
+	p := Progress{Total: len(list)}
+	for _, r := range list {
+		if Done(r, at) {
+			p.Completed++
+		}
+	}
+	if p.Total > 0 {
+		p.Percent = p.Completed * 100 / p.Total
+	}
+	return p
+}
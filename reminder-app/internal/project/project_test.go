@@ -0,0 +1,32 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Date(2025, 3, 4, 15, 0, 0, 0, time.Local)
+	due := time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local)
+	yesterday := now.AddDate(0, 0, -1)
+	earlier := now.Add(-time.Hour)
+
+	open := reminder.NewReminder("r1", "Invitations", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	done := reminder.NewReminder("r2", "Cake", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	done.Completed, done.CompletedAt = true, &earlier
+	dailyToday := reminder.NewReminder("r3", "Water plants", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "daily"})
+	dailyToday.CompletedAt = &earlier
+	dailyStale := reminder.NewReminder("r4", "Feed cat", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "daily"})
+	dailyStale.CompletedAt = &yesterday
+
+	got := Summarize([]*reminder.Reminder{open, done, dailyToday, dailyStale}, now)
+	want := Progress{Total: 4, Completed: 2, Percent: 50}
+	if got != want {
+		t.Errorf("Summarize() = %+v, want %+v", got, want)
+	}
+	if got := Summarize(nil, now); got != (Progress{}) {
+		t.Errorf("Summarize(nil) = %+v, want zero progress", got)
+	}
+}
@@ -0,0 +1,288 @@
+// Package query parses filter expressions over reminders, such as
+//
+//	assignee:"Alice" AND tag:school AND due<2025-01-01
+//
+// into storage filters. GET /reminders takes one as ?q= and smart lists
+// save one, so searches and saved lists read the same way. An expression is
+// terms joined by AND, which may be left out. A term is a field, an
+// operator and a value, quoted when it contains spaces:
+//
+//	assignee:NAME      the reminder's assignee
+//	tag:TAG            one of the reminder's tags
+//	project:ID         the reminder's project
+//	series:ID          the series the reminder belongs to
+//	recurrence:TYPE    once, hourly, daily, weekly, monthly or yearly
+//	completed:BOOL     true or false
+//	due:DAY            due on that day
+//	due<DAY            due before that day, and likewise <=, > and >=
+//
+// A day is a date (2025-01-01), today, tomorrow or yesterday, or a number of
+// days from today (+7d, -1d), resolved when the expression is applied, so a
+// saved "due<+7d" always means the coming week. Due bounds also take an
+// RFC 3339 time, which compares exactly.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// MaxLength bounds the length of an expression
+const MaxLength = 1000
+
+// Fields of a term
+const (
+	FieldAssignee   = "assignee"
+	FieldTag        = "tag"
+	FieldProject    = "project"
+	FieldSeries     = "series"
+	FieldRecurrence = "recurrence"
+	FieldCompleted  = "completed"
+	FieldDue        = "due"
+)
+
+// operators are tried longest first, so "<=" isn't read as "<"
+var operators = []string{"<=", ">=", ":", "<", ">"}
+
+// Term is one condition of an expression, such as due<2025-01-01
+type Term struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// String formats the term as it would be written, quoting the value when it
+// needs it
+func (t Term) String() string {
+	value := t.Value
+	if value == "" || strings.ContainsAny(value, " \t\"") {
+		value = strconv.Quote(value)
+	}
+	return t.Field + t.Op + value
+}
+
+// Expr is a parsed expression, the terms that must all hold
+type Expr []Term
+
+// Parse reads an expression, checking its fields, operators and values. An
+// empty expression matches everything.
+func Parse(s string) (Expr, error) {
+	if len(s) > MaxLength {
+		return nil, fmt.Errorf("query must be at most %d characters", MaxLength)
+	}
+	var e Expr
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		word, _, _ := strings.Cut(rest, " ")
+		switch strings.ToUpper(word) {
+		case "AND":
+			if len(e) == 0 {
+				return nil, errors.New("query can't start with AND")
+			}
+			rest = strings.TrimSpace(rest[len(word):])
+			if rest == "" {
+				return nil, errors.New("query can't end with AND")
+			}
+			continue
+		case "OR", "NOT":
+			return nil, fmt.Errorf("%s is not supported; terms are joined by AND", strings.ToUpper(word))
+		}
+		t, n, err := parseTerm(rest)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.check(); err != nil {
+			return nil, err
+		}
+		e = append(e, t)
+		rest = strings.TrimSpace(rest[n:])
+	}
+	return e, nil
+}
+
+// parseTerm reads the term at the start of s, returning how much of s it
+// took
+func parseTerm(s string) (Term, int, error) {
+	i := strings.IndexAny(s, ":<> \t\"()")
+	if i <= 0 || strings.ContainsAny(s[i:i+1], " \t\"()") {
+		word, _, _ := strings.Cut(s, " ")
+		if strings.ContainsAny(word, "()") {
+			return Term{}, 0, errors.New("parentheses are not supported; terms are joined by AND")
+		}
+		return Term{}, 0, fmt.Errorf("expected field:value, got %q", word)
+	}
+	t := Term{Field: strings.ToLower(s[:i])}
+	for _, op := range operators {
+		if strings.HasPrefix(s[i:], op) {
+			t.Op = op
+			break
+		}
+	}
+	n := i + len(t.Op)
+	if strings.HasPrefix(s[n:], `"`) {
+		end := n + 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return Term{}, 0, fmt.Errorf("unterminated quote in %s", t.Field)
+		}
+		value, err := strconv.Unquote(s[n : end+1])
+		if err != nil {
+			return Term{}, 0, fmt.Errorf("invalid quoted value for %s", t.Field)
+		}
+		t.Value = value
+		return t, end + 1, nil
+	}
+	end := strings.IndexAny(s[n:], " \t")
+	if end < 0 {
+		end = len(s) - n
+	}
+	t.Value = s[n : n+end]
+	if t.Value == "" {
+		return Term{}, 0, fmt.Errorf("%s needs a value", t.Field)
+	}
+	return t, n + end, nil
+}
+
+// check validates a term's operator and value for its field
+func (t Term) check() error {
+	switch t.Field {
+	case FieldAssignee, FieldTag, FieldProject, FieldSeries, FieldRecurrence, FieldCompleted:
+		if t.Op != ":" {
+			return fmt.Errorf("%s only takes %s:value", t.Field, t.Field)
+		}
+	case FieldDue:
+		if _, _, err := day(t.Value, time.Now()); err != nil {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown field: %s", t.Field)
+	}
+	switch t.Field {
+	case FieldRecurrence:
+		switch t.Value {
+		case "once", "hourly", "daily", "weekly", "monthly", "yearly":
+		default:
+			return fmt.Errorf("unknown recurrence: %s", t.Value)
+		}
+	case FieldCompleted:
+		if _, err := strconv.ParseBool(t.Value); err != nil {
+			return errors.New("completed must be true or false")
+		}
+	}
+	return nil
+}
+
+// Apply narrows f by the expression, resolving days relative to now in
+// now's location. It fails when a term contradicts another or a field f
+// already has, such as two assignees.
+func (e Expr) Apply(f *storage.ReminderFilter, now time.Time) error {
+	for _, t := range e {
+		var err error
+		switch t.Field {
+		case FieldAssignee:
+			err = set(&f.FamilyMember, t)
+		case FieldTag:
+			err = set(&f.Tag, Term{Field: t.Field, Op: t.Op, Value: reminder.NormalizeTag(t.Value)})
+		case FieldProject:
+			err = set(&f.ProjectID, t)
+		case FieldSeries:
+			err = set(&f.SeriesID, t)
+		case FieldRecurrence:
+			err = set(&f.RecurrenceType, t)
+		case FieldCompleted:
+			completed, _ := strconv.ParseBool(t.Value)
+			if f.Completed != nil && *f.Completed != completed {
+				err = fmt.Errorf("%s contradicts completed:%t", t, *f.Completed)
+			}
+			f.Completed = &completed
+		case FieldDue:
+			err = narrowDue(f, t, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set fills a string field of a filter, unless it holds another value
+func set(field *string, t Term) error {
+	if *field != "" && *field != t.Value {
+		return fmt.Errorf("%s contradicts %s:%s", t, t.Field, *field)
+	}
+	*field = t.Value
+	return nil
+}
+
+// narrowDue applies a due term, keeping the tighter of each bound
+func narrowDue(f *storage.ReminderFilter, t Term, now time.Time) error {
+	start, end, err := day(t.Value, now)
+	if err != nil {
+		return err
+	}
+	var after, before *time.Time
+	switch t.Op {
+	case ":":
+		after, before = &start, &end
+	case "<":
+		before = &start
+	case "<=":
+		before = &end
+	case ">":
+		after = &end
+	case ">=":
+		after = &start
+	}
+	if after != nil && (f.DueAfter == nil || after.After(*f.DueAfter)) {
+		f.DueAfter = after
+	}
+	if before != nil && (f.DueBefore == nil || before.Before(*f.DueBefore)) {
+		f.DueBefore = before
+	}
+	return nil
+}
+
+// day resolves a day value to its bounds [start, end) in now's location.
+// An RFC 3339 time is an instant, so its bounds hold only that time and
+// the operators compare with it exactly.
+func day(value string, now time.Time) (start, end time.Time, err error) {
+	today := reminder.StartOfDay(now)
+	offset := 0
+	switch v := strings.ToLower(value); {
+	case v == "today":
+	case v == "tomorrow":
+		offset = 1
+	case v == "yesterday":
+		offset = -1
+	case strings.HasSuffix(v, "d") && (strings.HasPrefix(v, "+") || strings.HasPrefix(v, "-")):
+		if offset, err = strconv.Atoi(v[:len(v)-1]); err != nil {
+			return start, end, fmt.Errorf("invalid relative day: %s", value)
+		}
+	case strings.Contains(v, "t"):
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid due time: %s", value)
+		}
+		return t, t.Add(time.Nanosecond), nil
+	default:
+		d, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid due day: %s; use YYYY-MM-DD, today or +Nd", value)
+		}
+		start = reminder.LocalTime(d.Year(), d.Month(), d.Day(), 0, 0, 0, now.Location())
+		return start, reminder.NextDay(start), nil
+	}
+	start = reminder.LocalTime(today.Year(), today.Month(), today.Day()+offset, 0, 0, 0, now.Location())
+	return start, reminder.NextDay(start), nil
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"reminder-app/internal/storage"
+	"reminder-app/internal/tztest"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Expr
+	}{
+		{"", nil},
+		{`assignee:"Alice" AND tag:school AND due<2025-01-01`, Expr{{"assignee", ":", "Alice"}, {"tag", ":", "school"}, {"due", "<", "2025-01-01"}}},
+		{`  Tag:"after school"   and completed:false `, Expr{{"tag", ":", "after school"}, {"completed", ":", "false"}}},
+		{`due>=today due<=+7d`, Expr{{"due", ">=", "today"}, {"due", "<=", "+7d"}}},
+		{`assignee:"Ann \"Nan\" Lee"`, Expr{{"assignee", ":", `Ann "Nan" Lee`}}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.s)
+		if err != nil {
+			t.Errorf("%q: %v", tt.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.s, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{
+		"school",
+		"AND tag:school",
+		"tag:school AND",
+		"tag:school OR tag:work",
+		"NOT tag:school",
+		"(tag:school)",
+		"owner:Alice",
+		"tag<school",
+		"assignee:",
+		`assignee:"Alice`,
+		"completed:maybe",
+		"recurrence:minutely",
+		"due<soon",
+		"due>+xd",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	now := time.Date(2025, 3, 4, 15, 0, 0, 0, time.UTC)
+	day := func(d int) *time.Time {
+		t := time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	at := func(ns int) *time.Time {
+		t := time.Date(2025, 3, 10, 9, 30, 0, ns, time.UTC)
+		return &t
+	}
+	no := false
+	tests := []struct {
+		s    string
+		want storage.ReminderFilter
+	}{
+		{`assignee:Alice tag:" School "`, storage.ReminderFilter{FamilyID: "fam1", FamilyMember: "Alice", Tag: "school"}},
+		{"completed:false recurrence:weekly", storage.ReminderFilter{FamilyID: "fam1", Completed: &no, RecurrenceType: "weekly"}},
+		{"due<2025-03-10", storage.ReminderFilter{FamilyID: "fam1", DueBefore: day(10)}},
+		{"due<=2025-03-10", storage.ReminderFilter{FamilyID: "fam1", DueBefore: day(11)}},
+		{"due>2025-03-10", storage.ReminderFilter{FamilyID: "fam1", DueAfter: day(11)}},
+		{"due:today", storage.ReminderFilter{FamilyID: "fam1", DueAfter: day(4), DueBefore: day(5)}},
+		// Instants compare exactly
+		{"due:2025-03-10T09:30:00Z", storage.ReminderFilter{FamilyID: "fam1", DueAfter: at(0), DueBefore: at(1)}},
+		{"due<2025-03-10T09:30:00Z", storage.ReminderFilter{FamilyID: "fam1", DueBefore: at(0)}},
+		{"due<=2025-03-10T09:30:00Z", storage.ReminderFilter{FamilyID: "fam1", DueBefore: at(1)}},
+		{"due>2025-03-10T09:30:00Z", storage.ReminderFilter{FamilyID: "fam1", DueAfter: at(1)}},
+		{"due>=2025-03-10T09:30:00Z", storage.ReminderFilter{FamilyID: "fam1", DueAfter: at(0)}},
+		// Bounds intersect
+		{"due>=yesterday due<+7d due<tomorrow due>=-5d", storage.ReminderFilter{FamilyID: "fam1", DueAfter: day(3), DueBefore: day(5)}},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.s)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.s, err)
+		}
+		f := storage.ReminderFilter{FamilyID: "fam1"}
+		if err := e.Apply(&f, now); err != nil {
+			t.Errorf("%q: %v", tt.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(f, tt.want) {
+			t.Errorf("%q: got %+v, want %+v", tt.s, f, tt.want)
+		}
+	}
+
+	// Terms may repeat a field but not contradict it or the filter
+	for s, ok := range map[string]bool{
+		"assignee:Alice assignee:Alice":  true,
+		"assignee:Alice assignee:Bob":    false,
+		"completed:true completed:false": false,
+		"project:p1":                     false,
+	} {
+		e, _ := Parse(s)
+		f := storage.ReminderFilter{ProjectID: "p2"}
+		if err := e.Apply(&f, now); (err == nil) != ok {
+			t.Errorf("%q: got error %v", s, err)
+		}
+	}
+}
+
+func TestApplyAcrossDST(t *testing.T) {
+	// Days are calendar days of the caller's zone, however long they are
+	loc := tztest.Load(t, "America/New_York")
+	now := time.Date(2025, 3, 8, 20, 0, 0, 0, loc)
+	e, _ := Parse("due:tomorrow")
+	var f storage.ReminderFilter
+	if err := e.Apply(&f, now); err != nil {
+		t.Fatal(err)
+	}
+	start, end := time.Date(2025, 3, 9, 0, 0, 0, 0, loc), time.Date(2025, 3, 10, 0, 0, 0, 0, loc)
+	if !f.DueAfter.Equal(start) || !f.DueBefore.Equal(end) || end.Sub(start) != 23*time.Hour {
+		t.Errorf("due:tomorrow from %s: got [%s, %s)", now, f.DueAfter, f.DueBefore)
+	}
+}
@@ -7,4 +7,57 @@ type CompletionEvent struct {
 	ReminderID  string    `json:"reminder_id"`
 	CompletedAt time.Time `json:"completed_at"`
 	CompletedBy string    `json:"completed_by"`
+	// Impersonator is the admin who recorded the completion on
+	// CompletedBy's behalf, if any
+	Impersonator string `json:"impersonator,omitempty"`
+	// State is set on the completions of reminders that require
+	// confirmation by a second member
+	State string `json:"state,omitempty"`
+	// PairID is the other sign-off of a confirmed completion
+	PairID string `json:"pair_id,omitempty"`
+	// Points are what the completion earned CompletedBy, as the reminder
+	// was worth when it was made. The confirmation of a paired completion
+	// earns none; the points go to the first sign-off.
+	Points int `json:"points,omitempty"`
+}
+
+// Completion states of reminders that require confirmation. The first
+// sign-off awaits confirmation until another member confirms it, which
+// records a second, paired event and completes the reminder.
+const (
+	StateAwaitingConfirmation = "awaiting_confirmation"
+	StateConfirmed            = "confirmed"
+)
+
+// Awaiting reports whether the event is a first sign-off still awaiting
+// confirmation
+func (e *CompletionEvent) Awaiting() bool {
+	return e.State == StateAwaitingConfirmation
+}
+
+// StatusEventKind is the storage record kind of status events
+const StatusEventKind = "status_event"
+
+// StatusEvent records a reminder moving between workflow statuses
+type StatusEvent struct {
+	ID         string    `json:"id"`
+	ReminderID string    `json:"reminder_id"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	ChangedBy  string    `json:"changed_by,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// AckKind is the storage record kind of acknowledgements
+const AckKind = "ack"
+
+// Ack records that a member saw a reminder's notification, without it being
+// done yet. It stops the notification failing over to their next channel.
+type Ack struct {
+	ID         string `json:"id"`
+	ReminderID string `json:"reminder_id"`
+	// Occurrence is the occurrence that was due when it was acknowledged
+	Occurrence *time.Time `json:"occurrence,omitempty"`
+	AckedBy    string     `json:"acked_by,omitempty"`
+	AckedAt    time.Time  `json:"acked_at"`
 }
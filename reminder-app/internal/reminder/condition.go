@@ -0,0 +1,98 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition gates the notifications of a reminder on external data, such as
+// the weather. An occurrence is only notified when the value the provider
+// reports for Metric over the Window before it compares to Value as the
+// Operator says; "water the lawn only if no rain in the last 48h" is
+// {provider: weather, metric: precipitation, operator: lte, value: 0,
+// window: 48h}.
+type Condition struct {
+	Provider string  `json:"provider"` // e.g. "weather" or "air_quality"
+	Location string  `json:"location"` // "latitude,longitude"
+	Metric   string  `json:"metric"`   // e.g. "precipitation" or "us_aqi"
+	Operator string  `json:"operator"` // "lt", "lte", "gt", "gte" or "eq"
+	Value    float64 `json:"value"`
+	// Window is how far back from the occurrence the data is looked at, as
+	// a duration such as "48h". When empty, the value at the occurrence is
+	// used.
+	Window string `json:"window,omitempty"`
+}
+
+// maxConditionWindow bounds how far back a condition may look, which is
+// about what providers keep at hand
+const maxConditionWindow = 30 * 24 * time.Hour
+
+// Validate checks that the condition is complete. Whether the provider and
+// metric are available depends on the server and is checked separately.
+func (c Condition) Validate() error {
+	if c.Provider == "" || c.Metric == "" {
+		return errors.New("condition requires a provider and a metric")
+	}
+	if _, _, err := ParseLocation(c.Location); err != nil {
+		return err
+	}
+	switch c.Operator {
+	case "lt", "lte", "gt", "gte", "eq":
+	default:
+		return fmt.Errorf("invalid condition operator: %q", c.Operator)
+	}
+	if c.Window != "" {
+		d, err := time.ParseDuration(c.Window)
+		if err != nil || d < 0 {
+			return fmt.Errorf("condition window must be a duration such as 48h: %q", c.Window)
+		}
+		if d > maxConditionWindow {
+			return fmt.Errorf("condition window must be at most %s", maxConditionWindow)
+		}
+	}
+	return nil
+}
+
+// Lookback returns the window as a duration, zero if it is empty or invalid
+func (c Condition) Lookback() time.Duration {
+	d, _ := time.ParseDuration(c.Window)
+	return d
+}
+
+// Holds reports whether a measured value satisfies the condition
+func (c Condition) Holds(v float64) bool {
+	switch c.Operator {
+	case "lt":
+		return v < c.Value
+	case "lte":
+		return v <= c.Value
+	case "gt":
+		return v > c.Value
+	case "gte":
+		return v >= c.Value
+	case "eq":
+		return v == c.Value
+	}
+	return false
+}
+
+// ParseLocation parses a "latitude,longitude" pair in decimal degrees
+func ParseLocation(s string) (lat, lon float64, err error) {
+	latStr, lonStr, ok := strings.Cut(s, ",")
+	if ok {
+		lat, err = strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	}
+	if ok && err == nil {
+		lon, err = strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	}
+	if !ok || err != nil {
+		return 0, 0, fmt.Errorf("condition location must be latitude,longitude: %q", s)
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("condition location out of range: %q", s)
+	}
+	return lat, lon, nil
+}
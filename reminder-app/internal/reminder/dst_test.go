@@ -0,0 +1,59 @@
+package reminder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-app/internal/tztest"
+)
+
+func TestOccurrencesAcrossDST(t *testing.T) {
+	for _, tr := range tztest.All(t) {
+		wall := tr.Affected()
+		loc := tr.Zone
+		weekday := strings.ToLower(wall.Weekday().String())
+		// The skipped or repeated time of the change, and one it leaves be
+		for _, clock := range [][2]int{{wall.Hour(), wall.Minute()}, {9, 0}} {
+			// Due a few weeks before, stored in UTC as the storage backends
+			// return it
+			due := time.Date(wall.Year(), wall.Month(), wall.Day()-28, clock[0], clock[1], 0, 0, loc).UTC()
+			for _, pattern := range []RecurrencePattern{
+				{Type: "weekly", Days: []string{weekday}},
+				{Type: "monthly", Date: wall.Day()},
+			} {
+				r := NewReminder("rem1", "Water the plants", "", &due, "fam1", "Alice", pattern)
+				r.Timezone = loc.String()
+				name := loc.String() + " " + tr.At.Format(time.DateOnly) + " " + pattern.Type
+
+				// Noon is never skipped, so the windows start and end on
+				// the days they should
+				noon := func(months, days int) time.Time {
+					return time.Date(wall.Year(), wall.Month()+time.Month(months), wall.Day()+days, 12, 0, 0, 0, loc)
+				}
+				from, to := noon(0, -8), noon(0, 8)
+				if pattern.Type == "monthly" {
+					from, to = noon(-1, -1), noon(1, 1)
+				}
+				got := r.Occurrences(from, to)
+				tztest.CheckWallClock(t, got, loc, clock[0], clock[1])
+				onDay := 0
+				for _, at := range got {
+					if at.In(loc).YearDay() == wall.YearDay() {
+						onDay++
+					}
+				}
+				if onDay != 1 || (pattern.Type == "weekly" && len(got) != 3) {
+					t.Errorf("%s at %02d:%02d: unexpected occurrences %v", name, clock[0], clock[1], got)
+					continue
+				}
+				// Stepping from each occurrence finds the next one
+				for i := 0; i+1 < len(got); i++ {
+					if next := r.NextOccurrence(got[i]); next == nil || !next.Equal(got[i+1]) {
+						t.Errorf("%s at %02d:%02d: NextOccurrence(%s) = %v, want %s", name, clock[0], clock[1], got[i].In(loc), next, got[i+1].In(loc))
+					}
+				}
+			}
+		}
+	}
+}
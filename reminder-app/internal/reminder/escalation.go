@@ -0,0 +1,97 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limits on a reminder's escalation
+const (
+	MaxEscalationSteps = 5
+	// MinRepeatMinutes keeps re-notifications from flooding a member
+	MinRepeatMinutes = 15
+)
+
+// Escalation is what happens while a reminder is overdue: the assignee is
+// notified again every RepeatMinutes, and each step notifies its member once
+// the reminder has been overdue for the step's AfterMinutes. "Remind Bob
+// every half hour and tell Mom after two hours" is {repeat_minutes: 30,
+// steps: [{after_minutes: 120, member: Mom}]}. Both stop once the reminder
+// is completed or snoozed.
+type Escalation struct {
+	RepeatMinutes int              `json:"repeat_minutes,omitempty"`
+	Steps         []EscalationStep `json:"steps,omitempty"`
+}
+
+// EscalationStep notifies Member once a reminder has been overdue for
+// AfterMinutes
+type EscalationStep struct {
+	AfterMinutes int    `json:"after_minutes"`
+	Member       string `json:"member"`
+}
+
+// Validate checks that the escalation does something, repeats no more often
+// than MinRepeatMinutes and has at most MaxEscalationSteps steps, each
+// naming a member and a positive delay
+func (e Escalation) Validate() error {
+	if e.RepeatMinutes == 0 && len(e.Steps) == 0 {
+		return errors.New("escalation requires repeat_minutes or steps")
+	}
+	if e.RepeatMinutes != 0 && e.RepeatMinutes < MinRepeatMinutes {
+		return fmt.Errorf("repeat_minutes must be at least %d", MinRepeatMinutes)
+	}
+	if len(e.Steps) > MaxEscalationSteps {
+		return fmt.Errorf("escalation can have at most %d steps", MaxEscalationSteps)
+	}
+	for _, s := range e.Steps {
+		if s.Member == "" {
+			return errors.New("escalation steps require a member")
+		}
+		if s.AfterMinutes <= 0 {
+			return errors.New("escalation steps require a positive after_minutes")
+		}
+	}
+	return nil
+}
+
+// PendingOccurrence returns when the reminder's first occurrence that hasn't
+// been completed is due, or nil if it has none. For recurring reminders it
+// is the first occurrence on a day after the last completion, days being
+// those of the reminder's time zone or else of loc.
+func (r *Reminder) PendingOccurrence(loc *time.Location) *time.Time {
+	if r.DueDate == nil {
+		return nil
+	}
+	if !r.IsRecurring() || r.Usage != nil {
+		if r.Completed {
+			return nil
+		}
+		return r.DueDate
+	}
+	if r.CompletedAt != nil {
+		return r.NextOccurrence(r.CompletedAt.In(loc))
+	}
+	if r.IsOccurrence(*r.DueDate) {
+		return r.DueDate
+	}
+	return r.NextOccurrence(*r.DueDate)
+}
+
+// OverdueSince returns when the reminder became overdue, or nil if it isn't
+// at now: the due time of its pending occurrence, or the end of a snooze
+// that postponed it. Days are those of now's location unless the reminder
+// has a time zone.
+func (r *Reminder) OverdueSince(now time.Time) *time.Time {
+	since := r.PendingOccurrence(now.Location())
+	if since == nil {
+		return nil
+	}
+	if r.SnoozedUntil != nil && r.SnoozedUntil.After(*since) {
+		since = r.SnoozedUntil
+	}
+	if !now.After(*since) {
+		return nil
+	}
+	return since
+}
@@ -0,0 +1,57 @@
+package reminder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOverdueSince(t *testing.T) {
+	due := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	daily := NewReminder("rem1", "Dishes", "", &due, "fam1", "Alice", RecurrencePattern{Type: "daily"})
+	once := NewReminder("rem2", "Dentist", "", &due, "fam1", "Alice", RecurrencePattern{Type: "once"})
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC) }
+
+	check := func(name string, r *Reminder, now time.Time, want *time.Time) {
+		t.Helper()
+		got := r.OverdueSince(now)
+		if (got == nil) != (want == nil) || (got != nil && !got.Equal(*want)) {
+			t.Errorf("%s: expected overdue since %v at %s, got %v", name, want, now, got)
+		}
+	}
+	check("before its due time", once, at(1, 7), nil)
+	check("at its due time", once, due, nil)
+	check("past its due time", once, at(1, 9), &due)
+	check("never completed", daily, at(3, 12), &due)
+
+	completed := at(3, 9)
+	daily.CompletedAt = &completed
+	next := at(4, 8)
+	check("completed today", daily, at(3, 12), nil)
+	check("not yet due tomorrow", daily, at(4, 7), nil)
+	check("due tomorrow", daily, at(4, 9), &next)
+
+	until := at(4, 12)
+	daily.SnoozedUntil = &until
+	check("snoozed", daily, at(4, 9), nil)
+	check("past its snooze", daily, at(4, 13), &until)
+
+	once.Completed = true
+	check("completed", once, at(1, 9), nil)
+}
+
+func TestEscalationValidate(t *testing.T) {
+	for _, e := range []Escalation{
+		{},
+		{RepeatMinutes: 5},
+		{Steps: []EscalationStep{{AfterMinutes: 60}}},
+		{Steps: []EscalationStep{{Member: "Bob"}}},
+		{Steps: make([]EscalationStep, MaxEscalationSteps+1)},
+	} {
+		if err := e.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", e)
+		}
+	}
+	if err := (Escalation{RepeatMinutes: 30, Steps: []EscalationStep{{AfterMinutes: 60, Member: "Bob"}}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
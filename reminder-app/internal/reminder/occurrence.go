@@ -0,0 +1,279 @@
+package reminder
+
+import (
+	"strings"
+	"time"
+)
+
+// maxOccurrenceDays bounds how far Occurrences will walk, so a careless
+// caller can't expand a daily reminder over centuries
+const maxOccurrenceDays = 3660
+
+// maxOccurrenceHours bounds hourly reminders the same way, covering as long
+// a span as maxOccurrenceDays does for daily ones
+const maxOccurrenceHours = 24 * maxOccurrenceDays
+
+// Occurrences returns the times in [from, to) at which the reminder is due.
+// One-off reminders yield their due date if it falls in the window; recurring
+// reminders are expanded day by day in the reminder's location using the
+// time of day of the original due date. Hourly reminders are instead stepped
+// from the due date by their interval in elapsed hours. Reminders without a
+// due date have no occurrences.
+func (r *Reminder) Occurrences(from, to time.Time) []time.Time {
+	if r.DueDate == nil || !from.Before(to) {
+		return nil
+	}
+	due := *r.DueDate
+
+	if !r.IsRecurring() {
+		if !due.Before(from) && due.Before(to) {
+			return []time.Time{due}
+		}
+		return nil
+	}
+
+	var end *time.Time
+	if r.Recurrence.EndDate != "" {
+		if t, err := time.Parse(time.RFC3339, r.Recurrence.EndDate); err == nil {
+			end = &t
+		}
+	}
+
+	loc := r.Location(from.Location())
+	due = due.In(loc)
+	if r.Recurrence.Type == "hourly" {
+		return r.hourlyOccurrences(due, from, to, end)
+	}
+	day := StartOfDay(from.In(loc))
+	var result []time.Time
+	for i := 0; i < maxOccurrenceDays && day.Before(to); i++ {
+		at := LocalTime(day.Year(), day.Month(), day.Day(), due.Hour(), due.Minute(), due.Second(), loc)
+		if end != nil && at.After(*end) {
+			break
+		}
+		if !at.Before(from) && at.Before(to) && !at.Before(due) && r.occursOnDay(at) && !r.IsException(at) {
+			result = append(result, at)
+		}
+		day = NextDay(day)
+	}
+	return result
+}
+
+// hourlyOccurrences returns the occurrences of an hourly reminder in
+// [from, to), stepping from due
+func (r *Reminder) hourlyOccurrences(due, from, to time.Time, end *time.Time) []time.Time {
+	step := r.hourlyStep()
+	i := max(r.hourlyIndex(from), 0)
+	if due.Add(time.Duration(i) * step).Before(from) {
+		i++
+	}
+	var result []time.Time
+	for n := 0; n < maxOccurrenceHours; n++ {
+		at := due.Add(time.Duration(i+n) * step)
+		if !at.Before(to) || end != nil && at.After(*end) {
+			break
+		}
+		if !r.IsException(at) {
+			result = append(result, at)
+		}
+	}
+	return result
+}
+
+// hourlyStep returns the time between occurrences of an hourly reminder.
+// Hours are elapsed rather than on the clock, so a reminder every 8 hours
+// stays 8 hours apart across daylight saving changes.
+func (r *Reminder) hourlyStep() time.Duration {
+	return time.Duration(max(r.Recurrence.Interval, 1)) * time.Hour
+}
+
+// hourlyIndex returns how many steps after the due date the last occurrence
+// of an hourly reminder at or before t is, negative before the due date
+func (r *Reminder) hourlyIndex(t time.Time) int {
+	step := r.hourlyStep()
+	d := t.Sub(*r.DueDate)
+	n := d / step
+	if d%step < 0 {
+		n--
+	}
+	return int(n)
+}
+
+// OccursBetween reports whether the reminder has at least one occurrence in
+// [from, to)
+func (r *Reminder) OccursBetween(from, to time.Time) bool {
+	return len(r.Occurrences(from, to)) > 0
+}
+
+// nextOccurrenceDay returns the first occurrence after the given time,
+// walking day by day like Occurrences. NextOccurrence uses it for patterns
+// that can't be stepped directly.
+func (r *Reminder) nextOccurrenceDay(after time.Time) *time.Time {
+	after = after.In(r.Location(after.Location()))
+	due := r.DueDate.In(after.Location())
+	var end *time.Time
+	if r.Recurrence.EndDate != "" {
+		if t, err := time.Parse(time.RFC3339, r.Recurrence.EndDate); err == nil {
+			end = &t
+		}
+	}
+	day := StartOfDay(after)
+	for i := 0; i < maxOccurrenceDays; i++ {
+		at := LocalTime(day.Year(), day.Month(), day.Day(), due.Hour(), due.Minute(), due.Second(), day.Location())
+		if end != nil && at.After(*end) {
+			return nil
+		}
+		if at.After(after) && !at.Before(due) && r.occursOnDay(at) {
+			return &at
+		}
+		day = NextDay(day)
+	}
+	return nil
+}
+
+// occursOnDay reports whether the recurrence pattern selects the given day
+func (r *Reminder) occursOnDay(day time.Time) bool {
+	switch r.Recurrence.Type {
+	case "daily":
+		return r.onInterval(daysBetween(r.anchor(day), day))
+	case "weekly":
+		weekday := strings.ToLower(day.Weekday().String())
+		for _, d := range r.Recurrence.Days {
+			if strings.ToLower(d) == weekday {
+				return r.onInterval(daysBetween(startOfWeek(r.anchor(day)), startOfWeek(day)) / 7)
+			}
+		}
+	case "monthly":
+		anchor := r.anchor(day)
+		months := (day.Year()-anchor.Year())*12 + int(day.Month()) - int(anchor.Month())
+		return day.Day() == r.Recurrence.Date && r.onInterval(months)
+	case "yearly":
+		// Reminders due on February 29 only recur in leap years
+		anchor := r.anchor(day)
+		return day.Month() == anchor.Month() && day.Day() == anchor.Day() && r.onInterval(day.Year()-anchor.Year())
+	}
+	return false
+}
+
+// anchor returns the due date in day's location, from which intervals are
+// counted. Reminders without a due date are anchored at day itself.
+func (r *Reminder) anchor(day time.Time) time.Time {
+	if r.DueDate == nil {
+		return day
+	}
+	return r.DueDate.In(day.Location())
+}
+
+// onInterval reports whether n periods after the anchor is a multiple of
+// the pattern's interval
+func (r *Reminder) onInterval(n int) bool {
+	if r.Recurrence.Interval <= 1 {
+		return true
+	}
+	return n%r.Recurrence.Interval == 0
+}
+
+// daysBetween returns the number of calendar days from a to b, regardless
+// of daylight saving changes in between
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+// startOfWeek returns the Monday of t's week
+func startOfWeek(t time.Time) time.Time {
+	return t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+}
+
+// Location returns the time zone the reminder recurs in: its Timezone, or
+// fallback if it has none or it can't be loaded
+func (r *Reminder) Location(fallback *time.Location) *time.Location {
+	if r.Timezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// StartOfDay returns midnight at the beginning of t's day in t's location,
+// or the end of the gap where daylight saving time skips midnight
+func StartOfDay(t time.Time) time.Time {
+	return LocalTime(t.Year(), t.Month(), t.Day(), 0, 0, 0, t.Location())
+}
+
+// NextDay returns the start of the day after t's in t's location. Unlike
+// adding a day to the start of t's day, it never lands back on the same
+// day where daylight saving time skips midnight.
+func NextDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return LocalTime(y, m, d+1, 0, 0, 0, t.Location())
+}
+
+// LocalTime returns the instant at a wall-clock time in loc, reading the
+// times that daylight saving changes skip or repeat the way iCalendar does
+// rather than leaving them unspecified like time.Date: a skipped time is
+// taken in the offset before the change, so 02:30 is 03:30 when clocks go
+// from 02:00 to 03:00, and a repeated time is the first of the two.
+func LocalTime(year int, month time.Month, day, hour, min, sec int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, min, sec, 0, loc)
+	// Zones change their offset at most once a day, so the offsets half a
+	// day either side are the only ones that can apply
+	_, before := t.Add(-12 * time.Hour).Zone()
+	_, after := t.Add(12 * time.Hour).Zone()
+	if before == after {
+		return t
+	}
+	wall := time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	// The larger offset gives the earlier instant
+	offsets := []int{before, after}
+	if after > before {
+		offsets = []int{after, before}
+	}
+	for _, offset := range offsets {
+		at := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if y, m, d := at.Date(); y == wall.Year() && m == wall.Month() && d == wall.Day() &&
+			at.Hour() == wall.Hour() && at.Minute() == wall.Minute() && at.Second() == wall.Second() {
+			return at
+		}
+	}
+	return wall.Add(-time.Duration(before) * time.Second).In(loc)
+}
+
+// Period returns the bounds [start, end) of the occurrence period containing
+// at: from the start of the most recent scheduled day up to the start of the
+// next one, in the reminder's location. A weekly reminder on Monday and Thursday thus has one period from
+// Monday to Thursday and another from Thursday to the following Monday.
+// Hourly reminders have a period from each occurrence to the next.
+// One-off reminders have a single unbounded period, returned as zero times.
+func (r *Reminder) Period(at time.Time) (start, end time.Time) {
+	if !r.IsRecurring() {
+		return time.Time{}, time.Time{}
+	}
+	if r.Recurrence.Type == "hourly" && r.DueDate != nil {
+		loc := r.Location(at.Location())
+		start = r.DueDate.Add(time.Duration(r.hourlyIndex(at)) * r.hourlyStep()).In(loc)
+		return start, start.Add(r.hourlyStep())
+	}
+	day := StartOfDay(at.In(r.Location(at.Location())))
+	start = day
+	for i := 0; i < maxOccurrenceDays; i++ {
+		d := day.AddDate(0, 0, -i)
+		if r.occursOnDay(d) {
+			start = d
+			break
+		}
+	}
+	end = start.AddDate(0, 0, 1)
+	for i := 1; i <= maxOccurrenceDays; i++ {
+		d := start.AddDate(0, 0, i)
+		if r.occursOnDay(d) {
+			end = d
+			break
+		}
+	}
+	return start, end
+}
@@ -0,0 +1,278 @@
+package reminder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOccurrences(t *testing.T) {
+	due := time.Date(2025, 1, 6, 7, 30, 0, 0, time.UTC) // a Monday
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		recurrence RecurrencePattern
+		want       int
+	}{
+		{"once", RecurrencePattern{Type: "once"}, 1},
+		{"daily from due date", RecurrencePattern{Type: "daily"}, 26},
+		{"weekly on monday and thursday", RecurrencePattern{Type: "weekly", Days: []string{"monday", "thursday"}}, 8},
+		{"monthly on the 20th", RecurrencePattern{Type: "monthly", Date: 20}, 1},
+		{"daily with end date", RecurrencePattern{Type: "daily", EndDate: "2025-01-10T00:00:00Z"}, 4},
+		{"every 3 days", RecurrencePattern{Type: "daily", Interval: 3}, 9},
+		{"every 2 weeks on tuesday", RecurrencePattern{Type: "weekly", Days: []string{"tuesday"}, Interval: 2}, 2},
+		{"every 2 months on the 20th", RecurrencePattern{Type: "monthly", Date: 20, Interval: 2}, 1},
+		{"yearly", RecurrencePattern{Type: "yearly"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReminder("rem1", "Test", "", &due, "fam1", "Alice", tt.recurrence)
+			got := r.Occurrences(from, to)
+			if len(got) != tt.want {
+				t.Fatalf("got %d occurrences, want %d: %v", len(got), tt.want, got)
+			}
+			for _, o := range got {
+				if o.Hour() != 7 || o.Minute() != 30 {
+					t.Errorf("occurrence %v does not keep the due time of day", o)
+				}
+			}
+		})
+	}
+
+	noDue := NewReminder("rem2", "Test", "", nil, "fam1", "Alice", RecurrencePattern{Type: "daily"})
+	if got := noDue.Occurrences(from, to); got != nil {
+		t.Errorf("expected no occurrences without a due date, got %v", got)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	due := time.Date(2025, 1, 6, 7, 30, 0, 0, time.UTC) // a Monday
+	wed := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		recurrence RecurrencePattern
+		start, end time.Time
+	}{
+		{"once", RecurrencePattern{Type: "once"}, time.Time{}, time.Time{}},
+		{"daily", RecurrencePattern{Type: "daily"}, time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{"weekly", RecurrencePattern{Type: "weekly", Days: []string{"monday", "thursday"}}, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{"monthly", RecurrencePattern{Type: "monthly", Date: 20}, time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReminder("rem1", "Test", "", &due, "fam1", "Alice", tt.recurrence)
+			start, end := r.Period(wed)
+			if !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("got [%v, %v), want [%v, %v)", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestNextOccurrenceWithInterval(t *testing.T) {
+	due := time.Date(2025, 1, 6, 7, 30, 0, 0, time.UTC) // a Monday
+	tests := []struct {
+		name       string
+		recurrence RecurrencePattern
+		after      time.Time
+		want       time.Time
+	}{
+		{"every 3 days", RecurrencePattern{Type: "daily", Interval: 3}, due, time.Date(2025, 1, 9, 7, 30, 0, 0, time.UTC)},
+		{"every 2 weeks on tuesday", RecurrencePattern{Type: "weekly", Days: []string{"tuesday"}, Interval: 2}, time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 21, 7, 30, 0, 0, time.UTC)},
+		{"quarterly on the 6th", RecurrencePattern{Type: "monthly", Date: 6, Interval: 3}, due, time.Date(2025, 4, 6, 7, 30, 0, 0, time.UTC)},
+		{"yearly", RecurrencePattern{Type: "yearly"}, due, time.Date(2026, 1, 6, 7, 30, 0, 0, time.UTC)},
+		{"every 4 years", RecurrencePattern{Type: "yearly", Interval: 4}, due, time.Date(2029, 1, 6, 7, 30, 0, 0, time.UTC)},
+		{"same day before the due time", RecurrencePattern{Type: "yearly"}, due.Add(-time.Hour), due},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReminder("rem1", "Test", "", &due, "fam1", "Alice", tt.recurrence)
+			got := r.NextOccurrence(tt.after)
+			if got == nil || !got.Equal(tt.want) {
+				t.Errorf("NextOccurrence(%s) = %v, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+
+	ended := NewReminder("rem2", "Test", "", &due, "fam1", "Alice", RecurrencePattern{Type: "yearly", EndDate: "2025-12-31T00:00:00Z"})
+	if got := ended.NextOccurrence(due); got != nil {
+		t.Errorf("expected no occurrence after the end date, got %s", got)
+	}
+}
+
+func TestHourly(t *testing.T) {
+	due := time.Date(2025, 1, 6, 7, 30, 0, 0, time.UTC)
+	r := NewReminder("rem1", "Medicine", "", &due, "fam1", "Alice", RecurrencePattern{Type: "hourly", Interval: 8})
+
+	got := r.Occurrences(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC))
+	if len(got) != 6 || !got[0].Equal(due) || !got[5].Equal(due.Add(40*time.Hour)) {
+		t.Errorf("expected 6 occurrences every 8 hours from the due date, got %v", got)
+	}
+	if got := r.Occurrences(due.Add(time.Hour), due.Add(9*time.Hour)); len(got) != 1 || !got[0].Equal(due.Add(8*time.Hour)) {
+		t.Errorf("expected the occurrence 8 hours after the due date, got %v", got)
+	}
+
+	if next := r.NextOccurrence(due); next == nil || !next.Equal(due.Add(8*time.Hour)) {
+		t.Errorf("NextOccurrence(due) = %v, want 8 hours later", next)
+	}
+	if next := r.NextOccurrence(due.Add(-time.Hour)); next == nil || !next.Equal(due) {
+		t.Errorf("NextOccurrence before the due date = %v, want the due date", next)
+	}
+
+	start, end := r.Period(due.Add(20 * time.Hour))
+	if !start.Equal(due.Add(16*time.Hour)) || !end.Equal(due.Add(24*time.Hour)) {
+		t.Errorf("got period [%v, %v), want the 8 hours from the third occurrence", start, end)
+	}
+
+	r.Recurrence.EndDate = due.Add(12 * time.Hour).Format(time.RFC3339)
+	if next := r.NextOccurrence(due.Add(8 * time.Hour)); next != nil {
+		t.Errorf("expected no occurrence after the end date, got %v", next)
+	}
+
+	// Steps are elapsed hours, so the clock time moves across daylight saving
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	before := time.Date(2025, 3, 8, 20, 0, 0, 0, ny)
+	dst := NewReminder("rem2", "Medicine", "", &before, "fam1", "Alice", RecurrencePattern{Type: "hourly", Interval: 12})
+	dst.Timezone = "America/New_York"
+	if got := dst.Occurrences(before, before.Add(25*time.Hour)); len(got) != 3 || got[2].Hour() != 21 {
+		t.Errorf("expected occurrences 12 elapsed hours apart, got %v", got)
+	}
+}
+
+func TestValidateInterval(t *testing.T) {
+	for _, tt := range []struct {
+		p  RecurrencePattern
+		ok bool
+	}{
+		{RecurrencePattern{Type: "yearly"}, true},
+		{RecurrencePattern{Type: "daily", Interval: 3}, true},
+		{RecurrencePattern{Type: "weekly", Days: []string{"tuesday"}, Interval: 2}, true},
+		{RecurrencePattern{Type: "once", Interval: 2}, false},
+		{RecurrencePattern{Type: "daily", Interval: -1}, false},
+		{RecurrencePattern{Type: "yearly", Interval: 11}, false},
+		{RecurrencePattern{Type: "hourly", Interval: 8}, true},
+		{RecurrencePattern{Type: "hourly", Interval: 169}, false},
+	} {
+		if err := tt.p.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v, want ok %v", tt.p, err, tt.ok)
+		}
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// 7am in New York, stored in UTC as the storage backends return it
+	due := time.Date(2025, 3, 6, 7, 0, 0, 0, ny).UTC()
+	r := NewReminder("rem1", "School run", "", &due, "fam1", "Alice", RecurrencePattern{Type: "daily"})
+	r.Timezone = "America/New_York"
+
+	// Daylight saving starts on March 9; the reminder stays at 7am local
+	from := time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC)
+	got := r.Occurrences(from, from.AddDate(0, 0, 3))
+	want := []time.Time{
+		time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 9, 11, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 10, 11, 0, 0, 0, time.UTC),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d occurrences, got %v", len(want), got)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+	if next := r.NextOccurrence(time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC)); next == nil || !next.Equal(want[2]) {
+		t.Errorf("NextOccurrence = %v, want %s", next, want[2])
+	}
+
+	// 11pm local on the 9th is still the 9th's period, though it is the
+	// 10th in UTC
+	start, _ := r.Period(time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC))
+	if !start.Equal(time.Date(2025, 3, 9, 0, 0, 0, 0, ny)) {
+		t.Errorf("expected the period to start at midnight on the 9th in New York, got %s", start)
+	}
+
+	// Without a time zone of its own, the zone of the arguments is used
+	r.Timezone = ""
+	if got := r.Occurrences(from, from.AddDate(0, 0, 1)); len(got) != 1 || !got[0].Equal(want[0]) {
+		t.Errorf("expected the due time in UTC, got %v", got)
+	}
+	if got := r.Location(ny); got != ny {
+		t.Errorf("expected the fallback location, got %s", got)
+	}
+}
+
+func TestAssigneeAt(t *testing.T) {
+	due := time.Date(2025, 1, 6, 19, 0, 0, 0, time.UTC) // a Monday
+	r := NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", RecurrencePattern{Type: "weekly", Days: []string{"monday", "thursday"}})
+	r.Rotation = &Rotation{Members: []string{"Alice", "Bob", "Carol"}}
+
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{due, "Alice"},
+		{time.Date(2025, 1, 9, 19, 0, 0, 0, time.UTC), "Bob"},
+		{time.Date(2025, 1, 13, 19, 0, 0, 0, time.UTC), "Carol"},
+		{time.Date(2025, 1, 16, 19, 0, 0, 0, time.UTC), "Alice"},
+		// Past the span Occurrences walks at once: 2088 occurrences come
+		// before this Monday, and 2088 % 3 == 0
+		{time.Date(2045, 1, 9, 19, 0, 0, 0, time.UTC), "Alice"},
+	}
+	for _, tt := range tests {
+		if got := r.AssigneeAt(tt.at); got != tt.want {
+			t.Errorf("AssigneeAt(%v) = %s, want %s", tt.at, got, tt.want)
+		}
+	}
+
+	r.Rotation = nil
+	if got := r.AssigneeAt(due.AddDate(0, 0, 3)); got != "Alice" {
+		t.Errorf("without a rotation AssigneeAt = %s, want the family member", got)
+	}
+	if err := (Rotation{Members: []string{"Alice", "Alice"}}).Validate(); err == nil {
+		t.Error("a rotation listing a member twice was accepted")
+	}
+}
+
+func TestSplitSeries(t *testing.T) {
+	due := time.Date(2025, 1, 6, 19, 0, 0, 0, time.UTC) // a Monday
+	day := func(d int) time.Time { return due.AddDate(0, 0, d) }
+	r := NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", RecurrencePattern{Type: "daily"})
+	r.Rotation = &Rotation{Members: []string{"Alice", "Bob", "Carol"}}
+
+	one := r.Detach(day(1), "rem2")
+	if one.IsRecurring() || !one.DueDate.Equal(day(1)) || one.FamilyMember != "Bob" || one.Rotation != nil {
+		t.Errorf("expected a one-off occurrence for Bob, got %+v", one)
+	}
+	if got := r.Occurrences(due, day(3)); len(got) != 2 || !got[1].Equal(day(2)) {
+		t.Errorf("expected the detached occurrence to be skipped, got %v", got)
+	}
+	if next := r.NextOccurrence(due); next == nil || !next.Equal(day(2)) {
+		t.Errorf("expected NextOccurrence to pass over the exception, got %v", next)
+	}
+
+	r.Exceptions = append(r.Exceptions, day(5))
+	rest := r.SplitAt(day(4), "rem3")
+	if !rest.DueDate.Equal(day(4)) || rest.Version != 1 || len(rest.Exceptions) != 1 || !rest.Exceptions[0].Equal(day(5)) {
+		t.Errorf("expected the rest of the series from the split, got %+v", rest)
+	}
+	// Day 4 was Bob's turn, and day 6 Alice's as before
+	if rest.Rotation.Members[0] != "Bob" || rest.AssigneeAt(day(6)) != "Alice" {
+		t.Errorf("expected the rotation to carry on, got %v", rest.Rotation.Members)
+	}
+	if got := r.Occurrences(due, day(10)); len(got) != 3 || len(r.Exceptions) != 1 {
+		t.Errorf("expected the series to end before the split, got %v with exceptions %v", got, r.Exceptions)
+	}
+	if !r.IsOccurrence(day(3)) || r.IsOccurrence(day(4)) || r.IsOccurrence(day(1)) {
+		t.Error("IsOccurrence disagrees with the split series")
+	}
+}
@@ -0,0 +1,14 @@
+package reminder
+
+import "fmt"
+
+// MaxPoints is the most a reminder can be worth per completion
+const MaxPoints = 1000
+
+// ValidatePoints checks that p is between 0 and MaxPoints
+func ValidatePoints(p int) error {
+	if p < 0 || p > MaxPoints {
+		return fmt.Errorf("points must be between 0 and %d", MaxPoints)
+	}
+	return nil
+}
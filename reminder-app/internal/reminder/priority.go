@@ -0,0 +1,118 @@
+package reminder
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Priorities of reminders, from least to most pressing. The zero value is
+// treated as PriorityNormal.
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+var priorities = []string{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+// ValidatePriority checks that p is empty or one of the priorities
+func ValidatePriority(p string) error {
+	if p != "" && !slices.Contains(priorities, p) {
+		return fmt.Errorf("unknown priority %q; supported: %s", p, strings.Join(priorities, ", "))
+	}
+	return nil
+}
+
+// PriorityRank orders priorities, higher meaning more pressing
+func PriorityRank(p string) int {
+	if p == "" {
+		p = PriorityNormal
+	}
+	return slices.Index(priorities, p)
+}
+
+// Sort keys of reminder lists
+const (
+	SortPosition = "position"
+	SortDueDate  = "due_date"
+	SortTitle    = "title"
+	SortAssignee = "assignee"
+	SortPriority = "priority"
+)
+
+var sortKeys = []string{SortPosition, SortDueDate, SortTitle, SortAssignee, SortPriority}
+
+// SortKey is one key of a sort order
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// ParseSort parses a sort order such as "priority,-due_date": keys
+// separated by commas, each reversed by a leading "-". The priority key
+// puts the most pressing reminders first and the due date key the
+// earliest, with reminders without a due date last either way.
+func ParseSort(spec string) ([]SortKey, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	var keys []SortKey
+	for _, field := range strings.Split(spec, ",") {
+		k := SortKey{Field: strings.TrimPrefix(field, "-")}
+		k.Descending = k.Field != field
+		if !slices.Contains(sortKeys, k.Field) {
+			return nil, fmt.Errorf("unknown sort %q; supported: %s", field, strings.Join(sortKeys, ", "))
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// SortReminders orders list in place by the given keys, keeping the
+// existing order among reminders that compare equal
+func SortReminders(list []*Reminder, keys []SortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	slices.SortStableFunc(list, func(a, b *Reminder) int {
+		for _, k := range keys {
+			if c := compareBy(a, b, k); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// compareBy compares two reminders by one sort key
+func compareBy(a, b *Reminder, k SortKey) int {
+	var c int
+	switch k.Field {
+	case SortPosition:
+		c = cmp.Compare(a.Position, b.Position)
+	case SortTitle:
+		c = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case SortAssignee:
+		c = strings.Compare(a.FamilyMember, b.FamilyMember)
+	case SortPriority:
+		c = cmp.Compare(PriorityRank(b.Priority), PriorityRank(a.Priority))
+	case SortDueDate:
+		da, db := a.EffectiveDueDate(), b.EffectiveDueDate()
+		switch {
+		case da == nil && db == nil:
+			return 0
+		case da == nil:
+			return 1
+		case db == nil:
+			return -1
+		}
+		c = da.Compare(*db)
+	}
+	if k.Descending {
+		return -c
+	}
+	return c
+}
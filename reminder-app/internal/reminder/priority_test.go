@@ -0,0 +1,57 @@
+package reminder
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortReminders(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 3, d, 9, 0, 0, 0, time.UTC)
+		return &t
+	}
+	list := []*Reminder{
+		{ID: "a", Title: "Bins", DueDate: day(5)},
+		{ID: "b", Title: "vet", DueDate: day(4), Priority: PriorityHigh},
+		{ID: "c", Title: "Call", Priority: PriorityUrgent},
+		{ID: "d", Title: "Alarm", DueDate: day(3), Priority: PriorityLow},
+		{ID: "e", Title: "Dentist", DueDate: day(2), Priority: PriorityNormal},
+	}
+	ids := func() []string {
+		var ids []string
+		for _, r := range list {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	for _, tc := range []struct {
+		spec string
+		want []string
+	}{
+		{"priority,due_date", []string{"c", "b", "e", "a", "d"}},
+		{"due_date", []string{"e", "d", "b", "a", "c"}},
+		{"-due_date", []string{"a", "b", "d", "e", "c"}},
+		{"-priority,title", []string{"d", "a", "e", "b", "c"}},
+		{"title", []string{"d", "a", "c", "e", "b"}},
+	} {
+		keys, err := ParseSort(tc.spec)
+		if err != nil {
+			t.Fatalf("ParseSort(%q) failed: %v", tc.spec, err)
+		}
+		SortReminders(list, keys)
+		if got := ids(); !slices.Equal(got, tc.want) {
+			t.Errorf("sort %q: got %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+
+	for _, spec := range []string{"color", "priority,", "--title"} {
+		if _, err := ParseSort(spec); err == nil {
+			t.Errorf("expected ParseSort(%q) to fail", spec)
+		}
+	}
+	if err := ValidatePriority("critical"); err == nil {
+		t.Error("expected an unknown priority to be rejected")
+	}
+}
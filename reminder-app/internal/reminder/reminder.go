@@ -1,56 +1,133 @@
 package reminder
 
 import (
+	"slices"
 	"strings"
 	"time"
 )
 
 type RecurrencePattern struct {
-	Type    string   `json:"type"`     // "once", "weekly", "monthly"
+	Type    string   `json:"type"`     // "once", "hourly", "daily", "weekly", "monthly", "yearly"
 	Days    []string `json:"days"`     // ["monday", "wednesday", etc] for weekly
 	Date    int      `json:"date"`     // 1-31 for monthly
 	EndDate string   `json:"end_date"` // Optional end date for recurrence
+	// Interval repeats every Interval hours, days, weeks, months or years,
+	// counted from the due date; 0 and 1 both mean every one. A weekly
+	// pattern with an interval of 2 recurs on its days every other week.
+	Interval int `json:"interval,omitempty"`
 }
 
 type Reminder struct {
 	ID           string            `json:"id"`
 	Title        string            `json:"title"`
 	Description  string            `json:"description"`
-	DueDate      *time.Time        `json:"due_date,omitempty"`
+	DueDate      *time.Time        `json:"due_date,omitempty" bson:"duedate,omitempty"`
 	Recurrence   RecurrencePattern `json:"recurrence"`
 	Completed    bool              `json:"completed"`
-	CompletedAt  *time.Time        `json:"completed_at,omitempty"`
+	CompletedAt  *time.Time        `json:"completed_at,omitempty" bson:"completedat,omitempty"`
 	FamilyID     string            `json:"family_id"`
 	FamilyMember string            `json:"family_member"`
+	Version      int               `json:"version"`          // Incremented on every update
+	Position     int               `json:"position"`         // Manual sort order within the family
+	Status       string            `json:"status,omitempty"` // Workflow status; empty means the initial one
+	ProjectID    string            `json:"project_id,omitempty"`
+	Effort       int               `json:"effort,omitempty"` // Estimated minutes per occurrence
+	Assignment   string            `json:"assignment,omitempty"`
+	SnoozedUntil *time.Time        `json:"snoozed_until,omitempty" bson:"snoozeduntil,omitempty"` // Postpones the due date without moving it
+	// RequiresConfirmation makes completions take sign-offs by two
+	// different members, such as for giving medication
+	RequiresConfirmation bool `json:"requires_confirmation,omitempty"`
+	// Timezone is the IANA time zone the reminder recurs in, so it keeps
+	// its time of day across daylight saving changes. When empty, the zone
+	// of the times passed to its methods is used.
+	Timezone string `json:"timezone,omitempty"`
+	// Condition skips the notifications of occurrences for which external
+	// data, such as recent rain, doesn't meet it. Nil always notifies.
+	Condition *Condition `json:"condition,omitempty"`
+	// Usage makes the reminder fall due by a reported counter, such as an
+	// odometer, rather than by date. Nil for date-based reminders.
+	Usage *Usage `json:"usage,omitempty"`
+	// Rotation makes the assignee of a recurring reminder take turns among
+	// members each occurrence; FamilyMember then follows the current turn
+	Rotation *Rotation `json:"rotation,omitempty"`
+	// Tags are free-form labels such as "school", stored normalized by
+	// NormalizeTags
+	Tags []string `json:"tags,omitempty"`
+	// Priority is one of low, normal, high and urgent; empty means normal
+	Priority string `json:"priority,omitempty"`
+	// Exceptions are occurrences removed from a recurring reminder's
+	// series, such as ones edited on their own
+	Exceptions []time.Time `json:"exceptions,omitempty"`
+	// SeriesID links the reminders a recurring series was split into; see
+	// Series
+	SeriesID string `json:"series_id,omitempty"`
+	// Points are earned by whoever completes the reminder, each time, and
+	// add up on the family leaderboard
+	Points int `json:"points,omitempty"`
+	// Escalation notifies again, and notifies other members, while the
+	// reminder is overdue. Nil never escalates.
+	Escalation *Escalation `json:"escalation,omitempty"`
+	// DeletedAt is set on reminders in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deletedat,omitempty"`
 }
 
-func NewReminder(id, title, description string, dueDate time.Time, familyID, familyMember string, recurrence RecurrencePattern) *Reminder {
+// Assignment strategies choose who a recurring reminder's next occurrence is
+// assigned to when the current one is completed. The zero value keeps the
+// same assignee.
+const (
+	AssignRoundRobin  = "round_robin"  // the next family member in order
+	AssignLeastLoaded = "least_loaded" // the member with the least effort that week
+)
+
+// NewReminder creates a new reminder. The due date is optional; a nil due
+// date means the reminder has no deadline.
+func NewReminder(id, title, description string, dueDate *time.Time, familyID, familyMember string, recurrence RecurrencePattern) *Reminder {
 	return &Reminder{
 		ID:           id,
 		Title:        title,
 		Description:  description,
-		DueDate:      &dueDate,
+		DueDate:      dueDate,
 		Recurrence:   recurrence,
 		Completed:    false,
 		CompletedAt:  nil,
 		FamilyID:     familyID,
 		FamilyMember: familyMember,
+		Version:      1,
 	}
 }
 
-// NewReminderWithNullableDueDate creates a new reminder with nullable due date
-func NewReminderWithNullableDueDate(id, title, description string, dueDate *time.Time, familyID, familyMember string, recurrence RecurrencePattern) *Reminder {
-	return &Reminder{
-		ID:           id,
-		Title:        title,
-		Description:  description,
-		DueDate:      dueDate,
-		Recurrence:   recurrence,
-		Completed:    false,
-		CompletedAt:  nil,
-		FamilyID:     familyID,
-		FamilyMember: familyMember,
+// Clone returns a copy of r that can be changed without changing r
+func (r *Reminder) Clone() *Reminder {
+	c := *r
+	c.Recurrence.Days = slices.Clone(r.Recurrence.Days)
+	c.Tags = slices.Clone(r.Tags)
+	c.Exceptions = slices.Clone(r.Exceptions)
+	if r.Condition != nil {
+		cond := *r.Condition
+		c.Condition = &cond
+	}
+	if r.Usage != nil {
+		u := *r.Usage
+		c.Usage = &u
+	}
+	if r.Rotation != nil {
+		c.Rotation = &Rotation{Members: slices.Clone(r.Rotation.Members)}
 	}
+	if r.Escalation != nil {
+		e := *r.Escalation
+		e.Steps = slices.Clone(r.Escalation.Steps)
+		c.Escalation = &e
+	}
+	return &c
+}
+
+// EffectiveDueDate returns when the reminder is actually due: the end of its
+// snooze if that is later than the due date, otherwise the due date
+func (r *Reminder) EffectiveDueDate() *time.Time {
+	if r.DueDate == nil || r.SnoozedUntil == nil || !r.SnoozedUntil.After(*r.DueDate) {
+		return r.DueDate
+	}
+	return r.SnoozedUntil
 }
 
 // IsRecurring returns true if the reminder is a recurring reminder
@@ -58,8 +135,23 @@ func (r *Reminder) IsRecurring() bool {
 	return r.Recurrence.Type != "once"
 }
 
-// NextOccurrence returns the next occurrence of the reminder after the given time
+// NextOccurrence returns the next occurrence of the reminder after the given
+// time, passing over exceptions
 func (r *Reminder) NextOccurrence(after time.Time) *time.Time {
+	next := r.nextOccurrence(after)
+	// Each exception is passed over at most once
+	for range r.Exceptions {
+		if next == nil || !r.IsException(*next) {
+			break
+		}
+		next = r.nextOccurrence(*next)
+	}
+	return next
+}
+
+// nextOccurrence returns the next occurrence of the reminder after the
+// given time, exceptions included
+func (r *Reminder) nextOccurrence(after time.Time) *time.Time {
 	if r.DueDate == nil {
 		return nil
 	}
@@ -78,27 +170,46 @@ func (r *Reminder) NextOccurrence(after time.Time) *time.Time {
 		}
 	}
 
+	if r.Recurrence.Type == "hourly" {
+		loc := r.Location(after.Location())
+		i := max(r.hourlyIndex(after)+1, 0)
+		next := r.DueDate.Add(time.Duration(i) * r.hourlyStep()).In(loc)
+		if r.Recurrence.EndDate != "" {
+			if end, err := time.Parse(time.RFC3339, r.Recurrence.EndDate); err == nil && next.After(end) {
+				return nil
+			}
+		}
+		return &next
+	}
+
+	if r.Recurrence.Type == "yearly" || r.Recurrence.Interval > 1 {
+		return r.nextOccurrenceDay(after)
+	}
+
+	// Times of day are those of the due date in the reminder's location
+	after = after.In(r.Location(after.Location()))
+	due := r.DueDate.In(after.Location())
 	next := after
 	switch r.Recurrence.Type {
 	case "daily":
 		// Add one day to the "after" time, keeping the same time of day as the original due date
-		next = time.Date(
+		next = LocalTime(
 			after.Year(), after.Month(), after.Day()+1,
-			r.DueDate.Hour(), r.DueDate.Minute(), r.DueDate.Second(),
-			0, after.Location(),
+			due.Hour(), due.Minute(), due.Second(),
+			after.Location(),
 		)
 		return &next
 	case "weekly":
 		// Find the next matching day of the week
 		for i := 0; i < 7; i++ {
-			next = next.AddDate(0, 0, 1)
+			next = NextDay(next)
 			weekday := strings.ToLower(next.Weekday().String())
 			for _, day := range r.Recurrence.Days {
 				if day == weekday {
-					result := time.Date(
+					result := LocalTime(
 						next.Year(), next.Month(), next.Day(),
-						r.DueDate.Hour(), r.DueDate.Minute(), r.DueDate.Second(),
-						0, next.Location(),
+						due.Hour(), due.Minute(), due.Second(),
+						next.Location(),
 					)
 					return &result
 				}
@@ -106,23 +217,27 @@ func (r *Reminder) NextOccurrence(after time.Time) *time.Time {
 		}
 	case "monthly":
 		// Find the next matching date of the month
-		next = time.Date(
+		next = LocalTime(
 			after.Year(), after.Month(), r.Recurrence.Date,
-			r.DueDate.Hour(), r.DueDate.Minute(), r.DueDate.Second(),
-			0, after.Location(),
+			due.Hour(), due.Minute(), due.Second(),
+			after.Location(),
 		)
-		if next.Before(after) {
-			next = next.AddDate(0, 1, 0)
+		if !next.After(after) {
+			next = LocalTime(
+				after.Year(), after.Month()+1, r.Recurrence.Date,
+				due.Hour(), due.Minute(), due.Second(),
+				after.Location(),
+			)
 		}
 		return &next
 	}
 	return nil
 }
 
-func (r *Reminder) Update(title, description string, dueDate time.Time) {
+func (r *Reminder) Update(title, description string, dueDate *time.Time) {
 	r.Title = title
 	r.Description = description
-	r.DueDate = &dueDate
+	r.DueDate = dueDate
 }
 
 func (r *Reminder) MarkCompleted() {
@@ -0,0 +1,66 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Rotation makes a recurring reminder's assignee cycle through Members, one
+// occurrence each, starting with the first member at the due date. "Take
+// out the trash, Alice then Bob then Carol" is {members: [Alice, Bob,
+// Carol]} on a weekly reminder. The assignee of an occurrence follows from
+// its position in the series, so skipped occurrences still take their turn.
+type Rotation struct {
+	Members []string `json:"members"`
+}
+
+// Validate checks that the rotation names at least one member and none twice
+func (rot Rotation) Validate() error {
+	if len(rot.Members) == 0 {
+		return errors.New("rotation requires at least one member")
+	}
+	seen := make(map[string]bool, len(rot.Members))
+	for _, m := range rot.Members {
+		if m == "" {
+			return errors.New("rotation members must not be empty")
+		}
+		if seen[m] {
+			return fmt.Errorf("rotation lists %s more than once", m)
+		}
+		seen[m] = true
+	}
+	return nil
+}
+
+// AssigneeAt returns who the reminder's occurrence at the given time is
+// assigned to: the member whose turn it is for rotating reminders, otherwise
+// the reminder's family member
+func (r *Reminder) AssigneeAt(at time.Time) string {
+	if r.Rotation == nil || len(r.Rotation.Members) == 0 || !r.IsRecurring() || r.DueDate == nil {
+		return r.FamilyMember
+	}
+	return r.Rotation.Members[r.occurrenceIndex(at)%len(r.Rotation.Members)]
+}
+
+// occurrenceIndex returns how many occurrences the reminder has before the
+// given time, counting from its due date. Exceptions count too, as they keep
+// their turn when edited on their own. Occurrences only walks a bounded span
+// at a time, so long series are counted in steps.
+func (r *Reminder) occurrenceIndex(at time.Time) int {
+	n := 0
+	for _, ex := range r.Exceptions {
+		if ex.Before(at) {
+			n++
+		}
+	}
+	for from := *r.DueDate; from.Before(at); {
+		to := from.AddDate(0, 0, maxOccurrenceDays)
+		if to.After(at) {
+			to = at
+		}
+		n += len(r.Occurrences(from, to))
+		from = to
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package reminder
+
+import (
+	"slices"
+	"time"
+)
+
+// Series returns the ID of the series the reminder belongs to: the ID of
+// the reminder it was first split from, or its own if it never was
+func (r *Reminder) Series() string {
+	if r.SeriesID != "" {
+		return r.SeriesID
+	}
+	return r.ID
+}
+
+// IsException reports whether the occurrence at the given time was removed
+// from the series, such as by being edited on its own
+func (r *Reminder) IsException(at time.Time) bool {
+	return slices.ContainsFunc(r.Exceptions, at.Equal)
+}
+
+// IsOccurrence reports whether the reminder occurs at exactly the given time
+func (r *Reminder) IsOccurrence(at time.Time) bool {
+	return len(r.Occurrences(at, at.Add(time.Nanosecond))) > 0
+}
+
+// Detach removes the occurrence at the given time from the series and
+// returns it as a one-off reminder with the given ID in the same series,
+// assigned to whoever's turn it was, to be edited on its own. Neither
+// reminder is saved.
+func (r *Reminder) Detach(at time.Time, id string) *Reminder {
+	r.SeriesID = r.Series()
+	r.Exceptions = append(r.Exceptions, at)
+	one := r.copyFrom(at, id)
+	one.Recurrence = RecurrencePattern{Type: "once"}
+	one.FamilyMember = r.AssigneeAt(at)
+	one.Assignment = ""
+	one.Rotation = nil
+	one.Exceptions = nil
+	return one
+}
+
+// SplitAt ends the series before the occurrence at the given time and
+// returns the rest of it as a new reminder with the given ID in the same
+// series, starting at that occurrence, to be edited apart from the
+// occurrences before it. Exceptions
+// go with the series their occurrence belongs to. Neither reminder is
+// saved.
+func (r *Reminder) SplitAt(at time.Time, id string) *Reminder {
+	r.SeriesID = r.Series()
+	rest := r.copyFrom(at, id)
+	// The member whose turn it is leads the new rotation
+	if r.Rotation != nil && len(r.Rotation.Members) > 0 {
+		n := r.occurrenceIndex(at) % len(r.Rotation.Members)
+		rest.Rotation = &Rotation{Members: slices.Concat(r.Rotation.Members[n:], r.Rotation.Members[:n])}
+	}
+	rest.Exceptions = slices.DeleteFunc(slices.Clone(r.Exceptions), at.After)
+	r.Exceptions = slices.DeleteFunc(r.Exceptions, func(ex time.Time) bool { return !at.After(ex) })
+	r.Recurrence.EndDate = at.Add(-time.Second).UTC().Format(time.RFC3339)
+	return rest
+}
+
+// copyFrom returns a fresh, uncompleted copy of the reminder due at the
+// given time, with the given ID
+func (r *Reminder) copyFrom(at time.Time, id string) *Reminder {
+	c := *r
+	c.ID = id
+	c.DueDate = &at
+	c.Version = 1
+	c.Completed = false
+	c.CompletedAt = nil
+	c.Status = ""
+	c.SnoozedUntil = nil
+	c.Tags = slices.Clone(r.Tags)
+	return &c
+}
@@ -0,0 +1,77 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on a reminder's tags
+const (
+	MaxTags      = 20
+	MaxTagLength = 32
+)
+
+// NormalizeTag returns the stored form of a tag: trimmed and lower-cased, so
+// "School" and "school " are the same tag
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// NormalizeTags returns the stored form of a reminder's tags, normalized,
+// without duplicates and in the order given. Nil and empty lists both come
+// back nil.
+func NormalizeTags(tags []string) ([]string, error) {
+	var result []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" {
+			return nil, errors.New("tags must not be empty")
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+		if !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	if len(result) > MaxTags {
+		return nil, fmt.Errorf("a reminder can have at most %d tags", MaxTags)
+	}
+	return result, nil
+}
+
+// HasTag reports whether the reminder is tagged with the given tag
+func (r *Reminder) HasTag(tag string) bool {
+	return slices.Contains(r.Tags, NormalizeTag(tag))
+}
+
+// TagCount is how many reminders carry a tag
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// CountTags tallies the tags of the given reminders, most used first and
+// alphabetically among equals
+func CountTags(list []*Reminder) []TagCount {
+	counts := make(map[string]int)
+	for _, r := range list {
+		for _, tag := range r.Tags {
+			counts[tag]++
+		}
+	}
+	result := make([]TagCount, 0, len(counts))
+	for tag, n := range counts {
+		result = append(result, TagCount{Tag: tag, Count: n})
+	}
+	slices.SortFunc(result, func(a, b TagCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return result
+}
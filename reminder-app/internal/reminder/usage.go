@@ -0,0 +1,86 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Usage makes a reminder fall due by a reported counter, such as a car's
+// odometer or the hours an HVAC filter has run, instead of by date. It falls
+// due once the counter has advanced Interval past Baseline, the reading when
+// it was last serviced; completing it starts the next interval from the
+// latest reading. "Change the oil every 8000 km" is {counter: odometer,
+// unit: km, interval: 8000, baseline: <odometer at the last oil change>}.
+type Usage struct {
+	Counter  string  `json:"counter"`        // e.g. "odometer" or "filter_hours"
+	Unit     string  `json:"unit,omitempty"` // e.g. "km" or "hours"
+	Interval float64 `json:"interval"`
+	Baseline float64 `json:"baseline"`
+	// Lead makes the reminder fall due that many units before the interval
+	// is up, so the service can be booked ahead
+	Lead float64 `json:"lead,omitempty"`
+	// Reading is the latest reported value of the counter, reported at
+	// ReadAt
+	Reading float64    `json:"reading"`
+	ReadAt  *time.Time `json:"read_at,omitempty" bson:"readat,omitempty"`
+}
+
+// UsageReadingKind is the storage record kind of usage readings
+const UsageReadingKind = "usage_reading"
+
+// UsageReading records a counter value reported for a usage-based reminder
+type UsageReading struct {
+	ID         string    `json:"id"`
+	ReminderID string    `json:"reminder_id"`
+	Counter    string    `json:"counter"`
+	Value      float64   `json:"value"`
+	ReportedBy string    `json:"reported_by,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+	// Triggered is set on the reading that made the reminder fall due
+	Triggered bool `json:"triggered,omitempty"`
+}
+
+// Validate checks that the usage trigger is complete
+func (u Usage) Validate() error {
+	if u.Counter == "" {
+		return errors.New("usage requires a counter")
+	}
+	if u.Interval <= 0 {
+		return fmt.Errorf("usage interval must be positive: %v", u.Interval)
+	}
+	if u.Lead < 0 || u.Lead >= u.Interval {
+		return fmt.Errorf("usage lead must be at least 0 and less than the interval: %v", u.Lead)
+	}
+	if u.Baseline < 0 || u.Reading < 0 {
+		return errors.New("usage baseline and reading must not be negative")
+	}
+	return nil
+}
+
+// Threshold returns the counter value at which the reminder falls due
+func (u Usage) Threshold() float64 {
+	return u.Baseline + u.Interval - u.Lead
+}
+
+// Due reports whether the latest reading has reached the threshold
+func (u Usage) Due() bool {
+	return u.Reading >= u.Threshold()
+}
+
+// RecordUsage sets the latest counter reading of a usage-based reminder.
+// When the reading is the first to reach the threshold, the reminder falls
+// due at the time of the reading, unless it was already due earlier, and
+// RecordUsage returns true.
+func (r *Reminder) RecordUsage(value float64, at time.Time) bool {
+	wasDue := r.Usage.Due()
+	r.Usage.Reading = value
+	r.Usage.ReadAt = &at
+	if wasDue || !r.Usage.Due() {
+		return false
+	}
+	if r.DueDate == nil || r.DueDate.After(at) {
+		r.DueDate = &at
+	}
+	return true
+}
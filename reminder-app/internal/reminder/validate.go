@@ -0,0 +1,70 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// maxIntervals bounds the interval of each recurring type, keeping one
+// period within the reach of Occurrences
+var maxIntervals = map[string]int{"hourly": 168, "daily": 365, "weekly": 52, "monthly": 24, "yearly": 10}
+
+// Validate checks that the pattern is complete for its type. Stored
+// reminders are validated on the way in, so an invalid pattern in storage
+// means the data was damaged or written by other means.
+func (p RecurrencePattern) Validate() error {
+	switch p.Type {
+	case "once", "hourly", "daily", "yearly":
+		// No additional validation needed
+	case "weekly":
+		if len(p.Days) == 0 {
+			return errors.New("weekly recurrence requires at least one day")
+		}
+		for _, day := range p.Days {
+			if !IsWeekday(day) {
+				return errors.New("invalid weekday in recurrence pattern")
+			}
+		}
+	case "monthly":
+		if p.Date < 1 || p.Date > 31 {
+			return errors.New("monthly recurrence requires a date between 1 and 31")
+		}
+	default:
+		return errors.New("invalid recurrence type")
+	}
+
+	if p.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+	if p.Interval > 1 {
+		max, ok := maxIntervals[p.Type]
+		if !ok {
+			return errors.New("interval requires a recurring type")
+		}
+		if p.Interval > max {
+			return fmt.Errorf("%s recurrence supports an interval of at most %d", p.Type, max)
+		}
+	}
+
+	if p.EndDate != "" {
+		if _, err := time.Parse(time.RFC3339, p.EndDate); err != nil {
+			return errors.New("invalid end_date format")
+		}
+	}
+	return nil
+}
+
+// IsWeekday reports whether day names a day of the week, ignoring case
+func IsWeekday(day string) bool {
+	day = strings.ToLower(day)
+	for _, valid := range weekdays {
+		if day == valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+// Package reply lets members act on a reminder by replying to its
+// notification email with "done" or "snooze 1h". Each notification gets its
+// own reply token, carried in the plus part of the Reply-To address, such as
+// reply+k3x9…@reminders.example.com, which the inbound email integration
+// posts back with the reply.
+package reply
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of reply tokens
+const Kind = "reply_token"
+
+// DefaultSnooze is how long a bare "snooze" postpones a reminder
+const DefaultSnooze = time.Hour
+
+var (
+	ErrNotFound       = errors.New("reply token not found")
+	ErrUnknownCommand = errors.New(`reply with "done" or "snooze" and a duration such as "snooze 1h"`)
+)
+
+// Token is what a reply token acts on. Only a hash of the token itself is
+// stored.
+type Token struct {
+	ID         string    `json:"id"`
+	ReminderID string    `json:"reminder_id"`
+	FamilyID   string    `json:"family_id"`
+	Member     string    `json:"member"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// encoding keeps tokens short and case-insensitive, as mail systems may
+// change the case of addresses
+var encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Issue stores a new token for replies from member about a reminder and
+// returns it. The token expires after ttl.
+func Issue(s storage.Storage, reminderID, familyID, member string, now time.Time, ttl time.Duration) (string, error) {
+	b := make([]byte, 15)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := encoding.EncodeToString(b)
+	t := &Token{
+		ID:         storage.NewRecordID("rpl"),
+		ReminderID: reminderID,
+		FamilyID:   familyID,
+		Member:     member,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	rec := storage.Record{Kind: Kind, ID: t.ID, FamilyID: familyID, Ref: hashToken(token), CreatedAt: now, ExpiresAt: &t.ExpiresAt}
+	if err := storage.PutJSON(s, rec, t); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Lookup returns the unexpired token a reply carries
+func Lookup(s storage.Storage, token string, now time.Time) (*Token, error) {
+	if token == "" {
+		return nil, ErrNotFound
+	}
+	list, err := storage.ListJSON[Token](s, storage.RecordQuery{Kind: Kind, Ref: hashToken(strings.ToLower(token))})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 || !now.Before(list[0].ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return list[0], nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Address returns the reply address for a token, adding it to the local
+// part of base: "reply@example.com" becomes "reply+token@example.com"
+func Address(base, token string) string {
+	local, domain, _ := strings.Cut(base, "@")
+	return local + "+" + token + "@" + domain
+}
+
+// TokenFrom returns the token in the first of a list of recipient addresses
+// that is a reply address for base, or "" if there is none
+func TokenFrom(base, recipients string) string {
+	local, domain, _ := strings.Cut(base, "@")
+	list, err := mail.ParseAddressList(recipients)
+	if err != nil {
+		return ""
+	}
+	for _, a := range list {
+		l, d, _ := strings.Cut(a.Address, "@")
+		prefix, token, ok := strings.Cut(l, "+")
+		if ok && token != "" && strings.EqualFold(prefix, local) && strings.EqualFold(d, domain) {
+			return token
+		}
+	}
+	return ""
+}
+
+// Command is the action a reply asks for
+type Command struct {
+	// Action is "done" or "snooze"
+	Action string
+	// Snooze is how long to postpone the reminder by
+	Snooze time.Duration
+}
+
+// Parse reads the command from the text of a reply: its first line that
+// isn't blank, stopping at the quoted original message. Durations are Go
+// durations such as "30m" or "1h30m", or whole days such as "2d".
+func Parse(text string) (Command, error) {
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, ">") {
+			break
+		}
+		fields := strings.Fields(strings.ToLower(strings.TrimRight(line, ".!")))
+		switch fields[0] {
+		case "done", "complete", "completed":
+			if len(fields) == 1 {
+				return Command{Action: "done"}, nil
+			}
+		case "snooze":
+			if len(fields) == 1 {
+				return Command{Action: "snooze", Snooze: DefaultSnooze}, nil
+			}
+			if len(fields) == 2 {
+				d, err := parseDuration(fields[1])
+				if err != nil {
+					return Command{}, err
+				}
+				return Command{Action: "snooze", Snooze: d}, nil
+			}
+		}
+		break
+	}
+	return Command{}, ErrUnknownCommand
+}
+
+// parseDuration parses a positive Go duration or a number of days
+func parseDuration(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid snooze duration %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid snooze duration %q", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("snooze duration must be positive: %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,74 @@
+package reply
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+func TestIssueLookup(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	now := time.Now()
+	token, err := Issue(store, "rem1", "fam1", "Alice", now, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if len(token) > 30 {
+		t.Errorf("token too long for an address: %q", token)
+	}
+	got, err := Lookup(store, token, now)
+	if err != nil || got.ReminderID != "rem1" || got.Member != "Alice" {
+		t.Fatalf("Lookup = %+v, %v", got, err)
+	}
+	if _, err := Lookup(store, token, now.Add(time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected an expired token not to be found, got %v", err)
+	}
+	if _, err := Lookup(store, "bogus", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	addr := Address("reply@example.com", "abc123")
+	if addr != "reply+abc123@example.com" {
+		t.Fatalf("Address = %q", addr)
+	}
+	for _, tt := range []struct{ to, want string }{
+		{addr, "abc123"},
+		{`"Reminders" <Reply+abc123@Example.com>, other@example.com`, "abc123"},
+		{"other@example.com, reply+abc123@example.com", "abc123"},
+		{"reply@example.com", ""},
+		{"reply+abc123@elsewhere.com", ""},
+		{"not an address", ""},
+	} {
+		if got := TokenFrom("reply@example.com", tt.to); got != tt.want {
+			t.Errorf("TokenFrom(%q) = %q, want %q", tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want Command
+		ok   bool
+	}{
+		{"done", Command{Action: "done"}, true},
+		{"\n  Done!\n\nSent from my phone", Command{Action: "done"}, true},
+		{"snooze 1h", Command{Action: "snooze", Snooze: time.Hour}, true},
+		{"Snooze 2d", Command{Action: "snooze", Snooze: 48 * time.Hour}, true},
+		{"snooze", Command{Action: "snooze", Snooze: DefaultSnooze}, true},
+		{"snooze -1h", Command{}, false},
+		{"snooze soon", Command{}, false},
+		{"done with this nonsense", Command{}, false},
+		{"> done\nthanks", Command{}, false},
+		{"", Command{}, false},
+	} {
+		got, err := Parse(tt.text)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v", tt.text, got, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of a rendered report
+const ContentType = "application/pdf"
+
+// A4 page size and margin, in points
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 50
+)
+
+// Bar chart layout, in points
+const (
+	labelWidth = 110
+	barWidth   = 280
+	barHeight  = 12
+	rowHeight  = 22
+)
+
+// maxRows is how many members fit in each chart
+const maxRows = 12
+
+// PDF renders the summary as a one-page PDF with a completion rate chart and
+// a completions chart per member. generated is printed in the footer.
+func PDF(s *Summary, generated time.Time) []byte {
+	var c canvas
+	y := float64(pageHeight - margin - 20)
+	c.text(margin, y, 20, true, s.Title())
+	y -= 28
+	overall := fmt.Sprintf("%d of %d reminders completed (%s)", s.Done, s.Due, percent(s.Rate()))
+	if s.Partial {
+		overall += ", month to date"
+	}
+	c.text(margin, y, 12, false, overall)
+	y -= 16
+	c.rect(margin, y, pageWidth-2*margin, 1, 0)
+
+	members := s.Members
+	if len(members) > maxRows {
+		members = members[:maxRows]
+	}
+	y -= 36
+	c.text(margin, y, 14, true, "Completion rate by member")
+	y -= 8
+	for _, m := range members {
+		y -= rowHeight
+		c.bar(y, m.Member, m.Rate(), fmt.Sprintf("%s (%d of %d)", percent(m.Rate()), m.Done, m.Due))
+	}
+
+	most := 0
+	for _, m := range members {
+		most = max(most, m.Completions)
+	}
+	y -= 40
+	c.text(margin, y, 14, true, "Completions by member")
+	y -= 8
+	for _, m := range members {
+		y -= rowHeight
+		c.bar(y, m.Member, rate(m.Completions, most), fmt.Sprint(m.Completions))
+	}
+	if len(s.Members) > maxRows {
+		y -= rowHeight
+		c.text(margin, y, 10, false, fmt.Sprintf("and %d more members", len(s.Members)-maxRows))
+	}
+
+	c.text(margin, margin, 9, false, "Generated "+generated.Format("January 2, 2006 15:04 MST"))
+	return document(c.Bytes())
+}
+
+// canvas accumulates the content stream of a page. Coordinates are in
+// points from the bottom left corner.
+type canvas struct {
+	bytes.Buffer
+}
+
+// text draws a line of text with its baseline at y
+func (c *canvas) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(c, "BT /%s %g Tf %g %g Td (%s) Tj ET\n", font, size, x, y, escape(s))
+}
+
+// rect fills a rectangle in a shade of gray from 0 (black) to 1 (white)
+func (c *canvas) rect(x, y, w, h, gray float64) {
+	fmt.Fprintf(c, "%g g %g %g %g %g re f 0 g\n", gray, x, y, w, h)
+}
+
+// bar draws a labelled horizontal bar filled to fraction of its length
+func (c *canvas) bar(y float64, label string, fraction float64, value string) {
+	c.text(margin, y+2, 11, false, label)
+	x := float64(margin + labelWidth)
+	c.rect(x, y, barWidth, barHeight, 0.9)
+	if fraction > 0 {
+		c.rect(x, y, barWidth*min(fraction, 1), barHeight, 0.25)
+	}
+	c.text(x+barWidth+10, y+2, 11, false, value)
+}
+
+// document wraps a page's content stream in a PDF 1.4 file using the
+// standard Helvetica fonts, which every reader has built in
+func document(content []byte) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>", pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// escape encodes s as the body of a PDF string in WinAnsiEncoding.
+// Characters outside Latin-1 are replaced with "?".
+func escape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			sb.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&sb, "\\%03o", r)
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+// Package report builds a family's monthly summary: how many reminder
+// occurrences fell due, how many of them were completed and who did the
+// work. Summaries render as a single-page PDF for printing and email.
+package report
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/stats"
+	"reminder-app/internal/storage"
+)
+
+// MemberStats are one member's figures for the month
+type MemberStats struct {
+	Member string `json:"member"`
+	// Due counts the occurrences assigned to the member, and Done those of
+	// them that were completed by anyone
+	Due  int `json:"due"`
+	Done int `json:"done"`
+	// Completions counts the completions the member made, whoever the
+	// reminders were assigned to
+	Completions int `json:"completions"`
+}
+
+// Rate is the share of the member's occurrences that were completed, or 0
+// if none were due
+func (m MemberStats) Rate() float64 {
+	return rate(m.Done, m.Due)
+}
+
+// Summary is a family's month
+type Summary struct {
+	FamilyID string `json:"family_id"`
+	Family   string `json:"family"`
+	// Month is the first instant of the month in the family's time zone
+	Month time.Time `json:"month"`
+	// Partial is set for the current month, which is summarized up to now
+	Partial bool          `json:"partial,omitempty"`
+	Due     int           `json:"due"`
+	Done    int           `json:"done"`
+	Members []MemberStats `json:"members"`
+}
+
+// Rate is the share of the month's occurrences that were completed
+func (s *Summary) Rate() float64 {
+	return rate(s.Done, s.Due)
+}
+
+// Title names the report, e.g. "Smith family report: March 2025"
+func (s *Summary) Title() string {
+	return fmt.Sprintf("%s family report: %s", s.Family, s.Month.Format("January 2006"))
+}
+
+// Text renders the summary as plain text for notification bodies
+func (s *Summary) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d of %d reminders completed (%s)\n", s.Done, s.Due, percent(s.Rate()))
+	for _, m := range s.Members {
+		fmt.Fprintf(&sb, "%s: %d of %d completed (%s), %d completions in total\n", m.Member, m.Done, m.Due, percent(m.Rate()), m.Completions)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// Build summarizes the month containing month, in month's location, for
+// family f. A month that hasn't ended by now is summarized up to now.
+// Completions awaiting a second sign-off don't count. Days with stored
+// daily stats are read from them, so the summary holds after their
+// completion events are purged.
+func Build(s storage.Storage, f *family.Family, month, now time.Time) (*Summary, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	end := start.AddDate(0, 1, 0)
+	sum := &Summary{FamilyID: f.ID, Family: f.Name, Month: start}
+	if now.Before(end) {
+		end, sum.Partial = now, true
+	}
+
+	days, err := stats.Range(s, f, start, end)
+	if err != nil {
+		return nil, err
+	}
+	total, members := stats.Sum(days)
+	sum.Due, sum.Done = total.Due, total.Done
+	var others []string
+	for name := range members {
+		if !slices.Contains(f.Members, name) {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range append(slices.Clone(f.Members), others...) {
+		m := MemberStats{Member: name}
+		if c := members[name]; c != nil {
+			m.Due, m.Done, m.Completions = c.Due, c.Done, c.Completions
+		}
+		sum.Members = append(sum.Members, m)
+	}
+	return sum, nil
+}
+
+func rate(done, due int) float64 {
+	if due == 0 {
+		return 0
+	}
+	return float64(done) / float64(due)
+}
+
+// percent formats a rate, e.g. "85%"
+func percent(r float64) string {
+	return fmt.Sprintf("%.0f%%", r*100)
+}
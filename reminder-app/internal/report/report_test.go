@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+func TestBuild(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	_ = store.CreateFamily(f)
+	// Every Monday in March 2025: the 3rd, 10th, 17th, 24th and 31st
+	monday := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &monday, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday"}}))
+	once := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem2", "Dentist", "", &once, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = store.CreateReminder(reminder.NewReminder("rem3", "Other family", "", &once, "fam2", "Carol", reminder.RecurrencePattern{Type: "once"}))
+	for i, e := range []*reminder.CompletionEvent{
+		{ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: monday.Add(time.Hour)},
+		{ReminderID: "rem1", CompletedBy: "Bob", CompletedAt: monday.AddDate(0, 0, 8)},
+		{ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: monday.AddDate(0, 0, 14), State: reminder.StateAwaitingConfirmation},
+		{ReminderID: "rem2", CompletedBy: "Bob", CompletedAt: once},
+	} {
+		e.ID = string(rune('a' + i))
+		_ = store.CreateCompletionEvent(e)
+	}
+
+	sum, err := Build(store, f, time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if sum.Partial || sum.Due != 6 || sum.Done != 3 || len(sum.Members) != 2 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+	alice, bob := sum.Members[0], sum.Members[1]
+	if alice.Member != "Alice" || alice.Due != 5 || alice.Done != 2 || alice.Completions != 1 {
+		t.Errorf("unexpected stats for Alice: %+v", alice)
+	}
+	if bob.Member != "Bob" || bob.Due != 1 || bob.Done != 1 || bob.Completions != 2 || bob.Rate() != 1 {
+		t.Errorf("unexpected stats for Bob: %+v", bob)
+	}
+
+	// The current month is summarized up to now
+	sum, _ = Build(store, f, monday, time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC))
+	if !sum.Partial || sum.Due != 2 || sum.Done != 2 {
+		t.Errorf("expected 2 of 2 done by March 12, got %+v", sum)
+	}
+}
+
+func TestPDF(t *testing.T) {
+	sum := &Summary{
+		Family: "Müller (Home)",
+		Month:  time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Due:    4,
+		Done:   3,
+		Members: []MemberStats{
+			{Member: "Alice", Due: 2, Done: 2, Completions: 2},
+			{Member: "Bob", Due: 2, Done: 1, Completions: 1},
+		},
+	}
+	pdf := PDF(sum, time.Date(2025, 4, 1, 8, 0, 0, 0, time.UTC))
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("not a PDF document:\n%s", pdf)
+	}
+	for _, want := range []string{`(M\374ller \(Home\) family report: March 2025)`, "(3 of 4 reminders completed \\(75%\\))", "(50% \\(1 of 2\\))"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF missing %s", want)
+		}
+	}
+	// The cross-reference table points at each object
+	start := bytes.LastIndex(pdf, []byte("startxref\n"))
+	if start < 0 || !bytes.Contains(pdf[start:], []byte("\n"+strconv.Itoa(bytes.Index(pdf, []byte("xref\n0 7")))+"\n")) {
+		t.Errorf("startxref doesn't point at the cross-reference table")
+	}
+}
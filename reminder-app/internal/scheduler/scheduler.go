@@ -0,0 +1,527 @@
+// Package scheduler runs time-based notifications, such as each member's
+// daily agenda and digest of upcoming and overdue reminders, the family's
+// monthly report and a message whenever a reminder falls due, unless its
+// condition on external data such as recent rain doesn't hold. Members who
+// rank their channels get a due reminder on the next one when it isn't
+// acknowledged in time; urgent reminders go to all their channels at once
+// and low priority ones only ever to the first that takes them. Reminders
+// with an escalation are sent again while they are overdue, and on to other
+// members after a while. A job fires when a tick crosses its scheduled
+// time, so restarting the server never repeats a notification that already
+// went out.
+// It also runs the nightly analysis that suggests recurrence changes, rolls
+// up each family's daily stats, purging completion events past the family's
+// retention, and purges expired records, such as share links past their
+// expiry.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/condition"
+	"reminder-app/internal/delivery"
+	"reminder-app/internal/digest"
+	"reminder-app/internal/family"
+	"reminder-app/internal/notify"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/report"
+	"reminder-app/internal/stats"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/suggest"
+	"reminder-app/internal/templates"
+)
+
+// suggestHour is the server-local hour at which suggestions are generated
+const suggestHour = 3
+
+// statsHour is the server-local hour at which daily stats are rolled up
+const statsHour = 2
+
+// Scheduler periodically checks every family for notifications that are due
+type Scheduler struct {
+	Store    storage.Storage
+	Notifier *notify.Dispatcher
+	Interval time.Duration
+	// Link returns an action URL, such as a signed completion link, to
+	// include in the notification for a due reminder. Optional.
+	Link func(r *reminder.Reminder) string
+	// ReplyTo returns the address replies to the notification for a due
+	// reminder go to, such as one that accepts "done". Optional.
+	ReplyTo func(r *reminder.Reminder) string
+	// Conditions evaluates the conditions of reminders that have one before
+	// they are notified. Optional; without it every occurrence is notified.
+	Conditions *condition.Registry
+
+	last time.Time
+}
+
+// New creates a scheduler that checks once a minute
+func New(store storage.Storage, notifier *notify.Dispatcher) *Scheduler {
+	return &Scheduler{Store: store, Notifier: notifier, Interval: time.Minute}
+}
+
+// Run ticks until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Tick(ctx, now)
+		}
+	}
+}
+
+// Tick sends every notification scheduled after the previous tick and at or
+// before now. The first tick covers the preceding interval.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	from := s.last
+	if from.IsZero() {
+		from = now.Add(-s.Interval)
+	}
+	s.last = now
+
+	if n, err := s.Store.PurgeExpiredRecords(now); err != nil {
+		log.Printf("scheduler: failed to purge expired records: %v", err)
+	} else if n > 0 {
+		log.Printf("scheduler: purged %d expired records", n)
+	}
+	if n, err := storage.PurgeTrash(s.Store, now); err != nil {
+		log.Printf("scheduler: failed to purge trash: %v", err)
+	} else if n > 0 {
+		log.Printf("scheduler: purged %d items from the trash", n)
+	}
+
+	families, err := s.Store.ListFamilies()
+	if err != nil {
+		log.Printf("scheduler: failed to list families: %v", err)
+		return
+	}
+	for _, f := range families {
+		for member, ms := range f.Settings.Members {
+			if ms.Agenda != nil {
+				if err := s.summary(ctx, f, member, *ms.Agenda, from, now); err != nil {
+					log.Printf("scheduler: agenda for %s in %s: %v", member, f.ID, err)
+				}
+			}
+			if ms.Digest != nil {
+				if err := s.digest(ctx, f, member, *ms.Digest, from, now); err != nil {
+					log.Printf("scheduler: digest for %s in %s: %v", member, f.ID, err)
+				}
+			}
+		}
+		if f.Settings.Nag != nil {
+			if err := s.summary(ctx, f, "", *f.Settings.Nag, from, now); err != nil {
+				log.Printf("scheduler: nag for %s: %v", f.ID, err)
+			}
+		}
+		if f.Settings.MonthlyReport != nil {
+			if err := s.monthlyReport(ctx, f, *f.Settings.MonthlyReport, from, now); err != nil {
+				log.Printf("scheduler: monthly report for %s: %v", f.ID, err)
+			}
+		}
+		if err := s.due(ctx, f, from, now); err != nil {
+			log.Printf("scheduler: due reminders for %s: %v", f.ID, err)
+		}
+		if err := s.escalate(ctx, f, from, now); err != nil {
+			log.Printf("scheduler: overdue reminders for %s: %v", f.ID, err)
+		}
+	}
+	if err := s.failover(ctx, now); err != nil {
+		log.Printf("scheduler: failover: %v", err)
+	}
+	if _, ok := crossed(from, now, statsHour, 0, time.Local); ok {
+		if err := stats.Run(s.Store, now); err != nil {
+			log.Printf("scheduler: stats: %v", err)
+		}
+	}
+	if _, ok := crossed(from, now, suggestHour, 0, time.Local); ok {
+		if _, err := suggest.Generate(s.Store, now); err != nil {
+			log.Printf("scheduler: suggestions: %v", err)
+		}
+	}
+}
+
+// summary sends the pending reminders of the day if the scheduled time falls
+// in (from, to]. A member's summary is their morning agenda and is sent even
+// when empty; the family-wide summary (member "") is the evening nag and is
+// suppressed when nothing is pending.
+func (s *Scheduler) summary(ctx context.Context, f *family.Family, member string, sched family.ScheduleSettings, from, to time.Time) error {
+	loc, err := f.LocationFor(member)
+	if err != nil {
+		return err
+	}
+	hour, min, err := sched.Clock()
+	if err != nil {
+		return err
+	}
+	at, ok := crossed(from, to, hour, min, loc)
+	if !ok {
+		return nil
+	}
+
+	list, err := s.Store.ListReminders()
+	if err != nil {
+		return err
+	}
+	items := agenda.Pending(agenda.ForDay(list, at, f.ID, member))
+	kind := templates.KindAgenda
+	if member == "" {
+		if len(items) == 0 {
+			return nil
+		}
+		kind = templates.KindNag
+	}
+	msg, err := templates.Render(f.Settings.Templates, kind, templates.ForAgenda(f, member, items, loc))
+	if err != nil {
+		return err
+	}
+	channels := f.ChannelsFor(member)
+	if sched.Channel != nil {
+		channels = []family.Channel{*sched.Channel}
+	}
+	return s.Notifier.SendAll(ctx, channels, msg)
+}
+
+// digest sends a member's digest if its scheduled time, on the scheduled
+// weekday for weekly digests, falls in (from, to]
+func (s *Scheduler) digest(ctx context.Context, f *family.Family, member string, sched family.DigestSettings, from, to time.Time) error {
+	loc, err := f.LocationFor(member)
+	if err != nil {
+		return err
+	}
+	hour, min, err := sched.Clock()
+	if err != nil {
+		return err
+	}
+	at, ok := crossed(from, to, hour, min, loc)
+	if !ok {
+		return nil
+	}
+	if sched.Period() == family.DigestWeekly {
+		day, err := sched.Day()
+		if err != nil {
+			return err
+		}
+		if at.Weekday() != day {
+			return nil
+		}
+	}
+	d, err := digest.Build(s.Store, f, member, sched.Period(), at)
+	if err != nil {
+		return err
+	}
+	msg, err := d.Message()
+	if err != nil {
+		return err
+	}
+	channels := f.ChannelsFor(member)
+	if sched.Channel != nil {
+		channels = []family.Channel{*sched.Channel}
+	}
+	return s.Notifier.SendAll(ctx, channels, msg)
+}
+
+// monthlyReport sends the report of the previous month if the scheduled
+// time on the first of the month, in the family's time zone, falls in
+// (from, to]
+func (s *Scheduler) monthlyReport(ctx context.Context, f *family.Family, sched family.ScheduleSettings, from, to time.Time) error {
+	loc, err := f.LocationFor("")
+	if err != nil {
+		return err
+	}
+	hour, min, err := sched.Clock()
+	if err != nil {
+		return err
+	}
+	at, ok := crossed(from, to, hour, min, loc)
+	if !ok || at.Day() != 1 {
+		return nil
+	}
+	sum, err := report.Build(s.Store, f, at.AddDate(0, -1, 0), at)
+	if err != nil {
+		return err
+	}
+	msg := notify.Message{
+		Subject: sum.Title(),
+		Body:    sum.Text(),
+		Attachments: []notify.Attachment{{
+			Name:        "report-" + sum.Month.Format("2006-01") + ".pdf",
+			ContentType: report.ContentType,
+			Data:        report.PDF(sum, at),
+		}},
+	}
+	channels := f.ChannelsFor("")
+	if sched.Channel != nil {
+		channels = []family.Channel{*sched.Channel}
+	}
+	return s.Notifier.SendAll(ctx, channels, msg)
+}
+
+// due notifies the assignee of every reminder occurrence in (from, to] that
+// isn't done yet, which for rotating reminders is whoever's turn it is, the
+// most pressing reminders first. Occurrences up to the end of a snooze are
+// postponed to it. Occurrences whose reminder has a condition that doesn't
+// hold are skipped.
+func (s *Scheduler) due(ctx context.Context, f *family.Family, from, to time.Time) error {
+	list, err := s.Store.ListReminders()
+	if err != nil {
+		return err
+	}
+	reminder.SortReminders(list, []reminder.SortKey{{Field: reminder.SortPriority}})
+	var errs []error
+	for _, r := range list {
+		if r.FamilyID != f.ID {
+			continue
+		}
+		loc, err := f.LocationFor(r.FamilyMember)
+		if err != nil {
+			return err
+		}
+		// Occurrences takes the time of day in from's location
+		times := r.Occurrences(from.In(loc).Add(time.Nanosecond), to.In(loc).Add(time.Nanosecond))
+		if until := r.SnoozedUntil; until != nil {
+			times = slices.DeleteFunc(times, func(at time.Time) bool { return !at.After(*until) })
+			if until.After(from) && !until.After(to) {
+				times = append([]time.Time{until.In(loc)}, times...)
+			}
+		}
+		for _, at := range times {
+			if agenda.IsDone(r, at) || !s.conditionHolds(ctx, r, at) {
+				continue
+			}
+			msg, err := s.message(f, r, templates.KindReminder, at, loc)
+			if err != nil {
+				return err
+			}
+			if err := s.notify(ctx, f, r, r.AssigneeAt(at), at, to, msg, 0); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// escalate notifies about the reminders of f that are overdue: their
+// assignee again every RepeatMinutes of their escalation, and the member of
+// each escalation step once its delay has passed, counting from when the
+// reminder became overdue, for every such time in (from, to]. Members who
+// have left the family are passed over, as are reminders whose condition
+// doesn't hold, which weren't notified in the first place.
+func (s *Scheduler) escalate(ctx context.Context, f *family.Family, from, to time.Time) error {
+	list, err := s.Store.QueryReminders(storage.ReminderFilter{FamilyID: f.ID})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, r := range list {
+		if r.Escalation == nil {
+			continue
+		}
+		loc, err := f.LocationFor(r.FamilyMember)
+		if err != nil {
+			return err
+		}
+		since := r.OverdueSince(to.In(loc))
+		if since == nil {
+			continue
+		}
+		var members []string
+		if n := r.Escalation.RepeatMinutes; n > 0 {
+			every := time.Duration(n) * time.Minute
+			at := since.Add(to.Sub(*since) / every * every)
+			if at.After(*since) && at.After(from) {
+				members = append(members, r.AssigneeAt(*since))
+			}
+		}
+		for _, step := range r.Escalation.Steps {
+			at := since.Add(time.Duration(step.AfterMinutes) * time.Minute)
+			if at.After(from) && !at.After(to) && slices.Contains(f.Members, step.Member) && !slices.Contains(members, step.Member) {
+				members = append(members, step.Member)
+			}
+		}
+		if len(members) == 0 || !s.conditionHolds(ctx, r, *since) {
+			continue
+		}
+		msg, err := s.message(f, r, templates.KindOverdue, since.In(loc), loc)
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			if err := s.Notifier.SendAll(ctx, f.ChannelsFor(m), msg); err != nil {
+				errs = append(errs, fmt.Errorf("%s to %s: %w", r.ID, m, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// message renders the notification of the given kind about the occurrence
+// of r at the given time
+func (s *Scheduler) message(f *family.Family, r *reminder.Reminder, kind string, at time.Time, loc *time.Location) (notify.Message, error) {
+	occurrence := *r
+	occurrence.DueDate = &at
+	occurrence.FamilyMember = r.AssigneeAt(at)
+	data := templates.ForReminder(f, &occurrence, loc)
+	if s.Link != nil {
+		data.Link = s.Link(r)
+	}
+	msg, err := templates.Render(f.Settings.Templates, kind, data)
+	if err != nil {
+		return notify.Message{}, err
+	}
+	if s.ReplyTo != nil {
+		msg.ReplyTo = s.ReplyTo(r)
+	}
+	return msg, nil
+}
+
+// notify sends msg about the occurrence of r at the given time to member's
+// channels from the given step on, logging each delivery as sent at now.
+// Members with failover are sent it on the first channel that takes it,
+// which is given a deadline for acknowledging it if they asked for one;
+// everyone else on all their channels. Urgent reminders go to all channels
+// regardless, and low priority ones only to the first that takes them,
+// without a deadline.
+func (s *Scheduler) notify(ctx context.Context, f *family.Family, r *reminder.Reminder, member string, at, now time.Time, msg notify.Message, step int) error {
+	channels := f.ChannelsFor(member)
+	failover := f.Settings.Members[member].Failover
+	switch r.Priority {
+	case reminder.PriorityUrgent:
+		failover = nil
+	case reminder.PriorityLow:
+		failover = &family.Failover{}
+	}
+	var errs []error
+	for i := step; i < len(channels); i++ {
+		d := &delivery.Delivery{
+			ID:         storage.NewRecordID("ntf"),
+			FamilyID:   f.ID,
+			ReminderID: r.ID,
+			Member:     member,
+			Occurrence: at,
+			Channel:    channels[i],
+			Step:       i,
+			Status:     delivery.StatusSent,
+			SentAt:     now,
+		}
+		err := s.Notifier.Send(ctx, channels[i], msg)
+		if err != nil {
+			d.Status, d.Error = delivery.StatusFailed, err.Error()
+			errs = append(errs, err)
+		} else if failover != nil && failover.AckMinutes > 0 && i+1 < len(channels) {
+			deadline := now.Add(time.Duration(failover.AckMinutes) * time.Minute)
+			d.FailoverAt = &deadline
+		}
+		if err := delivery.Save(s.Store, d); err != nil {
+			log.Printf("scheduler: failed to log delivery of %s: %v", r.ID, err)
+		}
+		if err == nil && failover != nil {
+			return nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// failover sends every occurrence that wasn't acknowledged by the deadline
+// of its delivery on to the member's next channel
+func (s *Scheduler) failover(ctx context.Context, now time.Time) error {
+	list, err := delivery.Due(s.Store, now)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, d := range list {
+		r, err := s.Store.GetReminder(d.ReminderID)
+		if err != nil {
+			// Deleted reminders need no acknowledging
+			d.Acknowledged = true
+		} else if d.Acknowledged, err = s.acknowledged(r, d); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.ID, err))
+			continue
+		}
+		d.FailedOver = !d.Acknowledged
+		if err := delivery.Save(s.Store, d); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.ID, err))
+			continue
+		}
+		if d.Acknowledged {
+			continue
+		}
+		f, err := s.Store.GetFamily(d.FamilyID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.ID, err))
+			continue
+		}
+		loc, err := f.LocationFor(r.FamilyMember)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.ID, err))
+			continue
+		}
+		msg, err := s.message(f, r, templates.KindReminder, d.Occurrence.In(loc), loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.ID, err))
+			continue
+		}
+		if err := s.notify(ctx, f, r, d.Member, d.Occurrence, now, msg, d.Step+1); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// acknowledged reports whether the assignee has reacted to delivery d of r:
+// by completing its occurrence, by snoozing it past then, or by
+// acknowledging the reminder since d was sent
+func (s *Scheduler) acknowledged(r *reminder.Reminder, d *delivery.Delivery) (bool, error) {
+	if agenda.IsDone(r, d.Occurrence) || (r.SnoozedUntil != nil && r.SnoozedUntil.After(d.Occurrence)) {
+		return true, nil
+	}
+	acks, err := storage.ListJSON[reminder.Ack](s.Store, storage.RecordQuery{Kind: reminder.AckKind, Ref: r.ID})
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(acks, func(a *reminder.Ack) bool { return !a.AckedAt.Before(d.SentAt) }), nil
+}
+
+// conditionHolds reports whether the occurrence of r at the given time
+// should be notified according to its condition. When the data can't be
+// fetched the occurrence is notified, as a reminder too many beats a missed
+// one.
+func (s *Scheduler) conditionHolds(ctx context.Context, r *reminder.Reminder, at time.Time) bool {
+	if r.Condition == nil || s.Conditions == nil {
+		return true
+	}
+	ok, err := s.Conditions.Evaluate(ctx, *r.Condition, at)
+	if err != nil {
+		log.Printf("scheduler: condition of %s: %v", r.ID, err)
+		return true
+	}
+	if !ok {
+		log.Printf("scheduler: skipped %s at %s as its condition doesn't hold", r.ID, at.Format(time.RFC3339))
+	}
+	return ok
+}
+
+// crossed returns the time of day hour:min in loc that lies in (from, to],
+// checking the local days of both ends so windows spanning midnight work.
+// A time that daylight saving skips or repeats is crossed once, as
+// reminder.LocalTime reads it.
+func crossed(from, to time.Time, hour, min int, loc *time.Location) (time.Time, bool) {
+	for _, day := range []time.Time{from.In(loc), to.In(loc)} {
+		y, m, d := day.Date()
+		t := reminder.LocalTime(y, m, d, hour, min, 0, loc)
+		if t.After(from) && !t.After(to) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,594 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-app/internal/condition"
+	"reminder-app/internal/delivery"
+	"reminder-app/internal/family"
+	"reminder-app/internal/notify"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/tztest"
+)
+
+type recordingNotifier struct {
+	targets []string
+	sent    []notify.Message
+}
+
+func (r *recordingNotifier) Send(ctx context.Context, target string, msg notify.Message) error {
+	r.targets = append(r.targets, target)
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func TestAgendaPush(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	f.Settings.Channels = []family.Channel{{Type: "test", Target: "family"}}
+	f.Settings.Members = map[string]family.MemberSettings{
+		"Alice": {Timezone: "Europe/Berlin", Agenda: &family.ScheduleSettings{Time: "07:00"}},
+		"Bob":   {Agenda: &family.ScheduleSettings{Time: "07:00", Channel: &family.Channel{Type: "test", Target: "bob"}}},
+	}
+	_ = store.CreateFamily(f)
+	due := time.Date(2024, 3, 4, 18, 0, 0, 0, berlin)
+	_ = store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+	tomorrow := due.AddDate(0, 0, 1)
+	_ = store.CreateReminder(reminder.NewReminder("rem2", "Dishes", "", &tomorrow, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}))
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+
+	// 05:59:30 UTC is 06:59:30 in Berlin: nothing yet
+	s.Tick(context.Background(), time.Date(2024, 3, 4, 5, 59, 30, 0, time.UTC))
+	if len(rec.sent) != 0 {
+		t.Fatalf("expected no agenda before 07:00, got %+v", rec.sent)
+	}
+	s.Tick(context.Background(), time.Date(2024, 3, 4, 6, 0, 30, 0, time.UTC))
+	if len(rec.sent) != 1 || rec.targets[0] != "family" {
+		t.Fatalf("expected Alice's agenda on the family channel, got %v", rec.targets)
+	}
+	msg := rec.sent[0]
+	if msg.Subject != "Today's reminders for Alice" || msg.Body != "- Trash at 6:00 PM" {
+		t.Errorf("unexpected agenda: %+v", msg)
+	}
+
+	// Ticking again in the same minute must not resend
+	s.Tick(context.Background(), time.Date(2024, 3, 4, 6, 0, 45, 0, time.UTC))
+	if len(rec.sent) != 1 {
+		t.Errorf("agenda sent twice: %v", rec.targets)
+	}
+}
+
+func TestAgendaOwnChannelAndEmpty(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Bob"}}
+	f.Settings.Members = map[string]family.MemberSettings{
+		"Bob": {Timezone: "UTC", Agenda: &family.ScheduleSettings{Time: "23:59", Channel: &family.Channel{Type: "test", Target: "bob"}}},
+	}
+	_ = store.CreateFamily(f)
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+	s.Tick(context.Background(), time.Date(2024, 3, 4, 23, 58, 30, 0, time.UTC))
+	// A gap spanning midnight still fires the agenda
+	s.Tick(context.Background(), time.Date(2024, 3, 5, 0, 3, 0, 0, time.UTC))
+	if len(rec.sent) != 1 || rec.targets[0] != "bob" {
+		t.Fatalf("expected one agenda on Bob's channel, got %v", rec.targets)
+	}
+	if !strings.Contains(rec.sent[0].Body, "Nothing due today") {
+		t.Errorf("unexpected empty agenda: %q", rec.sent[0].Body)
+	}
+}
+
+func TestNagSuppressedWhenNothingPending(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Channels = []family.Channel{{Type: "test", Target: "family"}}
+	f.Settings.Nag = &family.ScheduleSettings{Time: "19:00"}
+	_ = store.CreateFamily(f)
+	due := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	r := reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"})
+	_ = store.CreateReminder(r)
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+
+	s.Tick(context.Background(), time.Date(2024, 3, 4, 19, 0, 10, 0, time.UTC))
+	if len(rec.sent) != 1 || rec.sent[0].Subject != "Still to do today" || rec.sent[0].Body != "- Trash (Bob) at 6:00 PM" {
+		t.Fatalf("unexpected nag: %+v", rec.sent)
+	}
+
+	// The next evening nothing is due, so nothing is sent
+	s.Tick(context.Background(), time.Date(2024, 3, 5, 18, 59, 0, 0, time.UTC))
+	s.Tick(context.Background(), time.Date(2024, 3, 5, 19, 0, 10, 0, time.UTC))
+	if len(rec.sent) != 1 {
+		t.Errorf("expected nag to be suppressed, got %+v", rec.sent[1:])
+	}
+}
+
+func TestDueReminderNotifications(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Channels = []family.Channel{{Type: "test", Target: "family"}}
+	f.Settings.Members = map[string]family.MemberSettings{
+		"Bob": {Channels: []family.Channel{{Type: "test", Target: "bob"}}},
+	}
+	_ = store.CreateFamily(f)
+	due := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "daily"}))
+	done := reminder.NewReminder("rem2", "Dishes", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	done.Completed = true
+	_ = store.CreateReminder(done)
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+	s.Link = func(r *reminder.Reminder) string { return "https://example.com/c/" + r.ID }
+	s.ReplyTo = func(r *reminder.Reminder) string { return "reply+" + r.ID + "@example.com" }
+
+	s.Tick(context.Background(), time.Date(2024, 3, 4, 17, 59, 30, 0, time.UTC))
+	if len(rec.sent) != 0 {
+		t.Fatalf("expected nothing before the due time, got %+v", rec.sent)
+	}
+	s.Tick(context.Background(), time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC))
+	if len(rec.sent) != 1 || rec.targets[0] != "bob" {
+		t.Fatalf("expected one notification on Bob's channel, got %v %+v", rec.targets, rec.sent)
+	}
+	msg := rec.sent[0]
+	if msg.Subject != "Reminder: Trash" || msg.Link != "https://example.com/c/rem1" || msg.ReplyTo != "reply+rem1@example.com" || !strings.Contains(msg.Body, "Mon Mar 4 6:00 PM") {
+		t.Errorf("unexpected notification: %+v", msg)
+	}
+
+	// The next tick doesn't repeat it; the next day's occurrence is sent
+	s.Tick(context.Background(), time.Date(2024, 3, 4, 18, 1, 0, 0, time.UTC))
+	s.Tick(context.Background(), time.Date(2024, 3, 5, 18, 0, 30, 0, time.UTC))
+	if len(rec.sent) != 2 || !strings.Contains(rec.sent[1].Body, "Tue Mar 5 6:00 PM") {
+		t.Errorf("expected the next occurrence only, got %+v", rec.sent)
+	}
+}
+
+func TestSnoozedReminderNotifications(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Channels = []family.Channel{{Type: "test", Target: "family"}}
+	_ = store.CreateFamily(f)
+	due := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	until := due.Add(30 * time.Minute)
+	r := reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	r.SnoozedUntil = &until
+	_ = store.CreateReminder(r)
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+
+	s.Tick(context.Background(), due.Add(-time.Minute))
+	s.Tick(context.Background(), due)
+	if len(rec.sent) != 0 {
+		t.Fatalf("expected nothing while snoozed, got %+v", rec.sent)
+	}
+	s.Tick(context.Background(), until)
+	if len(rec.sent) != 1 || !strings.Contains(rec.sent[0].Body, "6:30 PM") {
+		t.Fatalf("expected one notification when the snooze ends, got %+v", rec.sent)
+	}
+	s.Tick(context.Background(), until.Add(time.Minute))
+	if len(rec.sent) != 1 {
+		t.Errorf("expected no repeat, got %+v", rec.sent)
+	}
+}
+
+type failingNotifier struct{}
+
+func (failingNotifier) Send(ctx context.Context, target string, msg notify.Message) error {
+	return errors.New("device not registered")
+}
+
+func TestChannelFailover(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Bob"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Members = map[string]family.MemberSettings{
+		"Bob": {
+			Channels: []family.Channel{{Type: "push", Target: "bob-phone"}, {Type: "test", Target: "bob@example.com"}, {Type: "test", Target: "+15550100"}},
+			Failover: &family.Failover{AckMinutes: 10},
+		},
+	}
+	_ = store.CreateFamily(f)
+	due := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = store.CreateReminder(reminder.NewReminder("rem2", "Dishes", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = store.CreateReminder(reminder.NewReminder("rem3", "Recycling", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	d.Register("push", failingNotifier{})
+	s := New(store, d)
+
+	// Push fails, so all go to email at once rather than to every channel
+	s.Tick(context.Background(), due.Add(-time.Minute))
+	s.Tick(context.Background(), due)
+	if len(rec.targets) != 3 || rec.targets[0] != "bob@example.com" || rec.targets[2] != "bob@example.com" {
+		t.Fatalf("expected all reminders on Bob's email, got %v", rec.targets)
+	}
+
+	// Dishes is done and Recycling seen in time; Trash isn't acknowledged
+	// and goes on to SMS
+	dishes, _ := store.GetReminder("rem2")
+	dishes.Completed = true
+	_ = store.CreateReminder(dishes)
+	ack := reminder.Ack{ID: "ack1", ReminderID: "rem3", AckedBy: "Bob", AckedAt: due.Add(2 * time.Minute)}
+	_ = storage.PutJSON(store, storage.Record{Kind: reminder.AckKind, ID: ack.ID, FamilyID: "fam1", Ref: "rem3", CreatedAt: ack.AckedAt}, ack)
+	s.Tick(context.Background(), due.Add(5*time.Minute))
+	if len(rec.targets) != 3 {
+		t.Fatalf("expected no failover before the deadline, got %v", rec.targets)
+	}
+	s.Tick(context.Background(), due.Add(10*time.Minute))
+	if len(rec.targets) != 4 || rec.targets[3] != "+15550100" || rec.sent[3].Subject != "Reminder: Trash" {
+		t.Fatalf("expected Trash alone to fail over to SMS, got %v", rec.targets)
+	}
+	s.Tick(context.Background(), due.Add(time.Hour))
+	if len(rec.targets) != 4 {
+		t.Errorf("expected the last channel not to fail over, got %v", rec.targets)
+	}
+
+	list, err := delivery.List(store, "fam1", "rem1")
+	if err != nil {
+		t.Fatalf("failed to list deliveries: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expected 3 deliveries of Trash, got %+v", list)
+	}
+	// Deliveries made in the same tick may be listed in either order
+	byStep := map[int]*delivery.Delivery{}
+	for _, d := range list {
+		byStep[d.Step] = d
+	}
+	if push := byStep[0]; push == nil || push.Status != delivery.StatusFailed || push.Error == "" {
+		t.Errorf("expected the push delivery to have failed, got %+v", push)
+	}
+	if email := byStep[1]; email == nil || !email.FailedOver {
+		t.Errorf("expected the email delivery to have failed over, got %+v", email)
+	}
+	if sms := byStep[2]; sms == nil || sms.FailoverAt != nil || list[0] != sms {
+		t.Errorf("expected the SMS delivery last, without a failover, got %+v", sms)
+	}
+	list, _ = delivery.List(store, "fam1", "rem2")
+	acknowledged := false
+	for _, d := range list {
+		acknowledged = acknowledged || (d.Step == 1 && d.Acknowledged && !d.FailedOver)
+	}
+	if len(list) != 2 || !acknowledged {
+		t.Errorf("expected Dishes to be acknowledged on email, got %+v", list)
+	}
+}
+
+func TestPriorityNotifications(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Bob"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Members = map[string]family.MemberSettings{
+		"Bob": {
+			Channels: []family.Channel{{Type: "test", Target: "bob@example.com"}, {Type: "test", Target: "+15550100"}},
+			Failover: &family.Failover{AckMinutes: 10},
+		},
+	}
+	_ = store.CreateFamily(f)
+	due := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	for id, p := range map[string]string{"rem1": reminder.PriorityLow, "rem2": reminder.PriorityUrgent} {
+		r := reminder.NewReminder(id, "Task "+p, "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"})
+		r.Priority = p
+		_ = store.CreateReminder(r)
+	}
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+	s.Tick(context.Background(), due.Add(-time.Minute))
+	s.Tick(context.Background(), due)
+
+	// The urgent reminder goes first, to both channels, and the low one to
+	// email alone
+	want := []string{"bob@example.com", "+15550100", "bob@example.com"}
+	if strings.Join(rec.targets, " ") != strings.Join(want, " ") || rec.sent[0].Subject != "Urgent: Task urgent" {
+		t.Fatalf("expected %v with the urgent reminder first, got %v %+v", want, rec.targets, rec.sent)
+	}
+	// Neither waits for an acknowledgement
+	s.Tick(context.Background(), due.Add(time.Hour))
+	if len(rec.targets) != 3 {
+		t.Errorf("expected no failover, got %v", rec.targets)
+	}
+}
+
+func TestDueReminderInOwnTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Channels = []family.Channel{{Type: "test", Target: "family"}}
+	_ = store.CreateFamily(f)
+	// 7am in Berlin before daylight saving starts on March 30
+	due := time.Date(2025, 3, 24, 7, 0, 0, 0, berlin).UTC()
+	r := reminder.NewReminder("rem1", "School run", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"})
+	r.Timezone = "Europe/Berlin"
+	_ = store.CreateReminder(r)
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+
+	// 7am summer time in Berlin is 5am UTC, an hour earlier than in winter
+	s.Tick(context.Background(), time.Date(2025, 3, 31, 4, 59, 30, 0, time.UTC))
+	s.Tick(context.Background(), time.Date(2025, 3, 31, 5, 0, 0, 0, time.UTC))
+	if len(rec.sent) != 1 {
+		t.Fatalf("expected a notification at 7am in Berlin, got %+v", rec.sent)
+	}
+	s.Tick(context.Background(), time.Date(2025, 3, 31, 6, 0, 0, 0, time.UTC))
+	if len(rec.sent) != 1 {
+		t.Errorf("expected no notification at the winter time, got %+v", rec.sent)
+	}
+}
+
+// rainProvider reports the rain on the day a window ends, failing on days
+// without data
+type rainProvider map[int]float64
+
+func (p rainProvider) Measure(ctx context.Context, lat, lon float64, metric string, from, to time.Time) (float64, error) {
+	mm, ok := p[to.Day()]
+	if !ok {
+		return 0, errors.New("no data")
+	}
+	return mm, nil
+}
+
+func TestConditionalReminderNotifications(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Channels = []family.Channel{{Type: "test", Target: "family"}}
+	_ = store.CreateFamily(f)
+	due := time.Date(2024, 6, 3, 7, 0, 0, 0, time.UTC)
+	r := reminder.NewReminder("rem1", "Water the lawn", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"})
+	r.Condition = &reminder.Condition{Provider: "weather", Location: "52.52,13.41", Metric: "precipitation", Operator: "lte", Value: 0, Window: "48h"}
+	_ = store.CreateReminder(r)
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+	s.Conditions = condition.NewRegistry()
+	s.Conditions.Register("weather", rainProvider{3: 4.5, 4: 0})
+
+	// It rained on the 3rd, so nothing is sent; the 4th was dry
+	s.Tick(context.Background(), time.Date(2024, 6, 3, 7, 0, 0, 0, time.UTC))
+	s.Tick(context.Background(), time.Date(2024, 6, 4, 7, 0, 0, 0, time.UTC))
+	if len(rec.sent) != 1 || !strings.Contains(rec.sent[0].Body, "Tue Jun 4") {
+		t.Fatalf("expected only the dry day's notification, got %+v", rec.sent)
+	}
+
+	// Without data the occurrence is notified
+	s.Tick(context.Background(), time.Date(2024, 6, 5, 7, 0, 0, 0, time.UTC))
+	if len(rec.sent) != 2 {
+		t.Errorf("expected a notification when the condition can't be evaluated, got %+v", rec.sent)
+	}
+}
+
+func TestMonthlyReport(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Channels = []family.Channel{{Type: "test", Target: "family"}}
+	f.Settings.MonthlyReport = &family.ScheduleSettings{Time: "08:00"}
+	_ = store.CreateFamily(f)
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+
+	// Only the first of the month
+	s.Tick(context.Background(), time.Date(2025, 3, 31, 7, 59, 0, 0, time.UTC))
+	s.Tick(context.Background(), time.Date(2025, 3, 31, 8, 0, 0, 0, time.UTC))
+	if len(rec.sent) != 0 {
+		t.Fatalf("expected no report on the 31st, got %+v", rec.sent)
+	}
+	s.Tick(context.Background(), time.Date(2025, 4, 1, 7, 59, 0, 0, time.UTC))
+	s.Tick(context.Background(), time.Date(2025, 4, 1, 8, 0, 0, 0, time.UTC))
+	if len(rec.sent) != 1 {
+		t.Fatalf("expected one report, got %+v", rec.sent)
+	}
+	msg := rec.sent[0]
+	if msg.Subject != "Smith family report: March 2025" || len(msg.Attachments) != 1 || msg.Attachments[0].Name != "report-2025-03.pdf" {
+		t.Errorf("unexpected report message: %+v", msg)
+	}
+}
+
+func TestOverdueEscalation(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Bob", "Carol"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Members = map[string]family.MemberSettings{
+		"Bob":   {Channels: []family.Channel{{Type: "test", Target: "bob"}}},
+		"Carol": {Channels: []family.Channel{{Type: "test", Target: "carol"}}},
+	}
+	_ = store.CreateFamily(f)
+	due := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	r := reminder.NewReminder("rem1", "Trash", "", &due, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"})
+	r.Escalation = &reminder.Escalation{RepeatMinutes: 30, Steps: []reminder.EscalationStep{{AfterMinutes: 60, Member: "Carol"}, {AfterMinutes: 60, Member: "Dave"}}}
+	_ = store.CreateReminder(r)
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+	s.Tick(context.Background(), due.Add(-30*time.Second))
+
+	// Each notification is the reminder falling due or, after that, its
+	// overdue notice
+	expect := func(at time.Time, targets ...string) {
+		t.Helper()
+		before := len(rec.targets)
+		s.Tick(context.Background(), at)
+		got := rec.targets[before:]
+		if strings.Join(got, ",") != strings.Join(targets, ",") {
+			t.Fatalf("at %s: expected notifications to %v, got %v", at.Format(time.Kitchen), targets, got)
+		}
+		for _, msg := range rec.sent[before:] {
+			if msg.Subject != "Reminder: Trash" && (msg.Subject != "Overdue: Trash" || !strings.Contains(msg.Body, "for Bob was due Mon Mar 4")) {
+				t.Errorf("unexpected notification: %+v", msg)
+			}
+		}
+	}
+	expect(due, "bob")
+	expect(due.Add(29 * time.Minute))
+	expect(due.Add(30*time.Minute), "bob")
+	expect(due.Add(45 * time.Minute))
+	// Dave has left the family, so only Carol hears of it
+	expect(due.Add(time.Hour), "bob", "carol")
+	if msg := rec.sent[len(rec.sent)-1]; msg.Subject != "Overdue: Trash" || msg.Body != "Trash for Bob was due Mon Mar 4 6:00 PM and isn't done yet." {
+		t.Errorf("unexpected overdue notification: %+v", msg)
+	}
+
+	// A tick spanning several repetitions sends one
+	expect(due.Add(3*time.Hour), "bob")
+
+	// Snoozing stops escalation until the snooze ends, when the reminder
+	// falls due again, and then counts from it
+	until := due.Add(4 * time.Hour)
+	r.SnoozedUntil = &until
+	_ = store.CreateReminder(r)
+	expect(due.Add(210 * time.Minute))
+	expect(until, "bob")
+	expect(until.Add(30*time.Minute), "bob")
+
+	r.Completed = true
+	_ = store.CreateReminder(r)
+	expect(until.Add(time.Hour))
+}
+
+func TestDigest(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}}
+	f.Settings.Timezone = "UTC"
+	f.Settings.Members = map[string]family.MemberSettings{
+		"Alice": {
+			Channels: []family.Channel{{Type: "test", Target: "alice"}},
+			Digest:   &family.DigestSettings{ScheduleSettings: family.ScheduleSettings{Time: "07:00", Channel: &family.Channel{Type: "test", Target: "alice-email"}}},
+		},
+		"Bob": {
+			Channels: []family.Channel{{Type: "test", Target: "bob"}},
+			Timezone: "Europe/Berlin",
+			Digest:   &family.DigestSettings{ScheduleSettings: family.ScheduleSettings{Time: "07:00"}, Frequency: family.DigestWeekly, Weekday: "sunday"},
+		},
+	}
+	_ = store.CreateFamily(f)
+	once := reminder.RecurrencePattern{Type: "once"}
+	overdue := time.Date(2025, 3, 7, 18, 0, 0, 0, berlin)
+	_ = store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &overdue, "fam1", "Bob", once))
+	upcoming := time.Date(2025, 3, 11, 9, 0, 0, 0, berlin)
+	_ = store.CreateReminder(reminder.NewReminder("rem2", "Dentist", "", &upcoming, "fam1", "Bob", once))
+
+	rec := &recordingNotifier{}
+	d := notify.NewDispatcher()
+	d.Register("test", rec)
+	s := New(store, d)
+
+	// Alice's daily digest goes to its own channel at 07:00 UTC; Bob's weekly
+	// one waits for Sunday 07:00 in Berlin
+	s.Tick(context.Background(), time.Date(2025, 3, 8, 5, 59, 0, 0, time.UTC))
+	s.Tick(context.Background(), time.Date(2025, 3, 8, 7, 0, 0, 0, time.UTC))
+	if strings.Join(rec.targets, ",") != "alice-email" || rec.sent[0].Subject != "Alice's daily digest" {
+		t.Fatalf("expected Alice's daily digest only, got %v %+v", rec.targets, rec.sent)
+	}
+	s.Tick(context.Background(), time.Date(2025, 3, 9, 5, 59, 0, 0, time.UTC))
+	s.Tick(context.Background(), time.Date(2025, 3, 9, 6, 0, 0, 0, time.UTC))
+	if len(rec.targets) != 2 || rec.targets[1] != "bob" {
+		t.Fatalf("expected Bob's weekly digest, got %v", rec.targets)
+	}
+	msg := rec.sent[1]
+	if msg.Subject != "Bob's weekly digest" || msg.Body != "Overdue:\n- Trash, due Fri Mar 7 6:00 PM\n\nComing up:\n- Dentist at Tue Mar 11 9:00 AM\n" {
+		t.Errorf("unexpected digest: %+v", msg)
+	}
+	if !strings.Contains(msg.HTML, "<b>Trash</b> &middot; due Fri Mar 7 6:00 PM") || !strings.Contains(msg.HTML, "Dentist &middot; Tue Mar 11 9:00 AM") {
+		t.Errorf("unexpected digest HTML:\n%s", msg.HTML)
+	}
+}
+
+func TestScheduleAcrossDST(t *testing.T) {
+	for _, tr := range tztest.All(t) {
+		wall := tr.Affected()
+		loc := tr.Zone
+		store := storage.NewMemoryStorage()
+		f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}}
+		f.Settings.Timezone = loc.String()
+		clock := fmt.Sprintf("%02d:%02d", wall.Hour(), wall.Minute())
+		f.Settings.Members = map[string]family.MemberSettings{
+			"Alice": {
+				Channels: []family.Channel{{Type: "test", Target: "due"}},
+				Agenda:   &family.ScheduleSettings{Time: clock, Channel: &family.Channel{Type: "test", Target: "agenda"}},
+			},
+		}
+		_ = store.CreateFamily(f)
+		// A weekly reminder at the time the change skips or repeats
+		due := time.Date(wall.Year(), wall.Month(), wall.Day()-14, wall.Hour(), wall.Minute(), 0, 0, loc)
+		weekly := reminder.RecurrencePattern{Type: "weekly", Days: []string{strings.ToLower(wall.Weekday().String())}}
+		_ = store.CreateReminder(reminder.NewReminder("rem1", "Water the plants", "", &due, "fam1", "Alice", weekly))
+
+		rec := &recordingNotifier{}
+		d := notify.NewDispatcher()
+		d.Register("test", rec)
+		s := New(store, d)
+
+		// Tick every minute for the three days around the change, so each
+		// notification is sent on the tick at its time
+		sent := map[string][]time.Time{}
+		start := time.Date(wall.Year(), wall.Month(), wall.Day()-1, 12, 0, 0, 0, loc)
+		for now := start; now.Before(start.AddDate(0, 0, 3)); now = now.Add(time.Minute) {
+			before := len(rec.targets)
+			s.Tick(context.Background(), now)
+			for _, target := range rec.targets[before:] {
+				sent[target] = append(sent[target], now)
+			}
+		}
+		name := loc.String() + " " + tr.At.Format(time.DateOnly)
+		if len(sent["agenda"]) != 3 {
+			t.Errorf("%s: expected three agendas at %s, got %v", name, clock, sent["agenda"])
+		}
+		tztest.CheckWallClock(t, sent["agenda"], loc, wall.Hour(), wall.Minute())
+		if len(sent["due"]) != 1 {
+			t.Errorf("%s: expected the weekly reminder once, got %v", name, sent["due"])
+		}
+		tztest.CheckWallClock(t, sent["due"], loc, wall.Hour(), wall.Minute())
+	}
+}
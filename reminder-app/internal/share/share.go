@@ -0,0 +1,81 @@
+// Package share implements read-only share links: a random token that lets
+// anyone holding it view a filtered list of a family's reminders, such as
+// the babysitter's evening checklist, until it expires or is revoked.
+package share
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of shares
+const Kind = "share"
+
+var (
+	ErrNotFound = errors.New("share not found")
+	ErrExpired  = errors.New("share expired")
+)
+
+// Share is a saved query exposed through a token. Only a hash of the token
+// is stored, so it can't be recovered from the database; revoking a share
+// deletes it.
+type Share struct {
+	ID        string          `json:"id"`
+	FamilyID  string          `json:"family_id"`
+	Name      string          `json:"name"`
+	Query     smartlist.Query `json:"query"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Expired reports whether the share can no longer be used at the given time
+func (sh *Share) Expired(now time.Time) bool {
+	return sh.ExpiresAt != nil && !now.Before(*sh.ExpiresAt)
+}
+
+// Create stores a new share and returns its token, which is only available
+// now
+func Create(s storage.Storage, sh *Share, now time.Time) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	sh.ID = storage.NewRecordID("shr")
+	sh.CreatedAt = now
+	rec := storage.Record{Kind: Kind, ID: sh.ID, FamilyID: sh.FamilyID, Ref: hashToken(token), CreatedAt: now, ExpiresAt: sh.ExpiresAt}
+	if err := storage.PutJSON(s, rec, sh); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Lookup returns the share a token grants access to
+func Lookup(s storage.Storage, token string, now time.Time) (*Share, error) {
+	if token == "" {
+		return nil, ErrNotFound
+	}
+	list, err := storage.ListJSON[Share](s, storage.RecordQuery{Kind: Kind, Ref: hashToken(token)})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	if list[0].Expired(now) {
+		return nil, ErrExpired
+	}
+	return list[0], nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,40 @@
+package share
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"reminder-app/internal/storage"
+)
+
+func TestLookup(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	now := time.Now()
+	expires := now.Add(time.Hour)
+	sh := &Share{FamilyID: "fam1", Name: "Babysitter", ExpiresAt: &expires}
+	token, err := Create(store, sh, now)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := Lookup(store, token, now)
+	if err != nil || got.ID != sh.ID {
+		t.Fatalf("Lookup = %+v, %v; want share %s", got, err, sh.ID)
+	}
+	rec, _ := store.GetRecord(Kind, sh.ID)
+	if rec.Ref == token {
+		t.Error("token stored in plain text")
+	}
+	if _, err := Lookup(store, token, expires); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+	if _, err := Lookup(store, "bogus", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	_ = store.DeleteRecord(Kind, sh.ID)
+	if _, err := Lookup(store, token, now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after revocation, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Package smartlist defines saved reminder filters ("smart lists") such as
+// "Alice's chores this week". A smart list stores a relative query that is
+// resolved into a storage.ReminderFilter at evaluation time, so "this week"
+// always means the current week.
+package smartlist
+
+import (
+	"errors"
+	"time"
+
+	"reminder-app/internal/query"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of smart lists
+const Kind = "smartlist"
+
+// SmartList is a named, saved query over a family's reminders
+type SmartList struct {
+	ID       string `json:"id"`
+	FamilyID string `json:"family_id"`
+	Name     string `json:"name"`
+	Query    Query  `json:"query"`
+}
+
+// Query is the saved form of a filter. Due window bounds are whole days
+// relative to the start of the evaluation day: {"due_from_days": 0,
+// "due_to_days": 7} is the coming week and {"due_to_days": 0} is everything
+// due before today. Q is a filter expression in the syntax of package
+// query, such as `tag:school AND due<+7d`, which narrows the other fields.
+type Query struct {
+	Assignee       string `json:"assignee,omitempty"`
+	Completed      *bool  `json:"completed,omitempty"`
+	DueFromDays    *int   `json:"due_from_days,omitempty"`
+	DueToDays      *int   `json:"due_to_days,omitempty"`
+	RecurrenceType string `json:"recurrence_type,omitempty"`
+	Q              string `json:"q,omitempty"`
+}
+
+// Validate checks the query for contradictions
+func (q Query) Validate() error {
+	if q.DueFromDays != nil && q.DueToDays != nil && *q.DueFromDays > *q.DueToDays {
+		return errors.New("due_from_days must not be after due_to_days")
+	}
+	_, err := q.Filter("", time.Now())
+	return err
+}
+
+// Filter resolves the query for a family at the given time. It fails when Q
+// doesn't parse or contradicts the other fields.
+func (q Query) Filter(familyID string, now time.Time) (storage.ReminderFilter, error) {
+	f := storage.ReminderFilter{
+		FamilyID:       familyID,
+		FamilyMember:   q.Assignee,
+		Completed:      q.Completed,
+		RecurrenceType: q.RecurrenceType,
+	}
+	today := reminder.StartOfDay(now)
+	if q.DueFromDays != nil {
+		t := today.AddDate(0, 0, *q.DueFromDays)
+		f.DueAfter = &t
+	}
+	if q.DueToDays != nil {
+		t := today.AddDate(0, 0, *q.DueToDays)
+		f.DueBefore = &t
+	}
+	expr, err := query.Parse(q.Q)
+	if err != nil {
+		return f, err
+	}
+	return f, expr.Apply(&f, now)
+}
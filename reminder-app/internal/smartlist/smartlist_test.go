@@ -0,0 +1,45 @@
+package smartlist
+
+import (
+	"testing"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+func TestQueryFilter(t *testing.T) {
+	now := time.Date(2025, 3, 4, 15, 0, 0, 0, time.UTC)
+	from, to := 0, 7
+	q := Query{Assignee: "Alice", DueFromDays: &from, DueToDays: &to}
+	f, err := q.Filter("fam1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due := func(days int) *time.Time {
+		t := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+		return &t
+	}
+	tests := []struct {
+		name string
+		r    *reminder.Reminder
+		want bool
+	}{
+		{"earlier today", reminder.NewReminder("r1", "T", "", due(0), "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}), true},
+		{"in six days", reminder.NewReminder("r2", "T", "", due(6), "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}), true},
+		{"in seven days", reminder.NewReminder("r3", "T", "", due(7), "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}), false},
+		{"yesterday", reminder.NewReminder("r4", "T", "", due(-1), "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}), false},
+		{"other member", reminder.NewReminder("r5", "T", "", due(1), "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}), false},
+		{"other family", reminder.NewReminder("r6", "T", "", due(1), "fam2", "Alice", reminder.RecurrencePattern{Type: "once"}), false},
+		{"no due date", reminder.NewReminder("r7", "T", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"}), false},
+	}
+	for _, tt := range tests {
+		if got := f.Matches(tt.r); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if err := (Query{DueFromDays: &to, DueToDays: &from}).Validate(); err == nil {
+		t.Error("expected inverted window to be rejected")
+	}
+}
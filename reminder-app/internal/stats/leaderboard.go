@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/storage"
+)
+
+// Leaderboard windows: the week from Monday, or the calendar month
+const (
+	WindowWeek  = "week"
+	WindowMonth = "month"
+)
+
+// Standing is a member's place on a leaderboard
+type Standing struct {
+	// Rank is shared by members with the same points
+	Rank        int    `json:"rank"`
+	Member      string `json:"member"`
+	Points      int    `json:"points"`
+	Completions int    `json:"completions"`
+}
+
+// Leaderboard ranks a family's members by the points their completions
+// earned over a window
+type Leaderboard struct {
+	FamilyID string     `json:"family_id"`
+	Window   string     `json:"window"`
+	From     time.Time  `json:"from"`
+	To       time.Time  `json:"to"`
+	Members  []Standing `json:"members"`
+}
+
+// Rank builds family f's leaderboard for the completions made in [from, to).
+// Every member has a standing, with no points if they earned none, as does
+// anyone else who earned points, such as a removed member. Points are those
+// recorded on each completion, so changing a reminder's points doesn't
+// rewrite the past, and completions awaiting a second sign-off don't count
+// yet.
+func Rank(s storage.Storage, f *family.Family, window string, from, to time.Time) (*Leaderboard, error) {
+	tallies, err := storage.TallyCompletions(s, f.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byMember := make(map[string]*Standing)
+	for _, m := range f.Members {
+		byMember[m] = &Standing{Member: m}
+	}
+	for _, t := range tallies {
+		if t.CompletedBy == "" {
+			continue
+		}
+		st := byMember[t.CompletedBy]
+		if st == nil {
+			st = &Standing{Member: t.CompletedBy}
+			byMember[t.CompletedBy] = st
+		}
+		st.Points += t.Points
+		st.Completions += len(t.Times)
+	}
+
+	board := &Leaderboard{FamilyID: f.ID, Window: window, From: from, To: to, Members: make([]Standing, 0, len(byMember))}
+	for name, st := range byMember {
+		if st.Points > 0 || slices.Contains(f.Members, name) {
+			board.Members = append(board.Members, *st)
+		}
+	}
+	slices.SortFunc(board.Members, func(a, b Standing) int {
+		return cmp.Or(cmp.Compare(b.Points, a.Points), cmp.Compare(b.Completions, a.Completions), strings.Compare(a.Member, b.Member))
+	})
+	for i := range board.Members {
+		if i > 0 && board.Members[i].Points == board.Members[i-1].Points {
+			board.Members[i].Rank = board.Members[i-1].Rank
+		} else {
+			board.Members[i].Rank = i + 1
+		}
+	}
+	return board, nil
+}
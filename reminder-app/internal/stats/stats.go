@@ -0,0 +1,367 @@
+// Package stats keeps daily aggregates of each family's reminders: how many
+// occurrences fell due and were completed, how many completions were made
+// and how many reminders were overdue at the end of the day. The aggregates
+// are stored as records, so reports stay fast and accurate after old
+// completion events are purged under a family's retention setting.
+package stats
+
+import (
+	"errors"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of daily aggregates
+const Kind = "daily-stats"
+
+// DateLayout formats the date of a day
+const DateLayout = "2006-01-02"
+
+// Window is how many of the most recent days Run aggregates again, so
+// completions made a few days late are still counted
+const Window = 7
+
+// MinRetentionDays is the shortest completion retention a family may set
+const MinRetentionDays = 30
+
+// Counts are the figures of a day
+type Counts struct {
+	// Due counts the occurrences due that day, and Done those of them that
+	// were completed within their occurrence period
+	Due  int `json:"due"`
+	Done int `json:"done"`
+	// Overdue counts the reminders still open past their due time at the
+	// end of the day
+	Overdue int `json:"overdue"`
+	// Completions counts the completions made that day
+	Completions int `json:"completions"`
+}
+
+func (c *Counts) add(o Counts) {
+	c.Due += o.Due
+	c.Done += o.Done
+	c.Overdue += o.Overdue
+	c.Completions += o.Completions
+}
+
+// Day is a family's aggregate for one day in its time zone. Members holds
+// the same figures per member: occurrences and overdue reminders by
+// assignee, completions by whoever made them.
+type Day struct {
+	FamilyID string `json:"family_id"`
+	Date     string `json:"date"`
+	Counts
+	Members map[string]*Counts `json:"members,omitempty"`
+}
+
+func (d *Day) member(name string) *Counts {
+	if d.Members == nil {
+		d.Members = make(map[string]*Counts)
+	}
+	if d.Members[name] == nil {
+		d.Members[name] = &Counts{}
+	}
+	return d.Members[name]
+}
+
+// Compute aggregates the days of family f from the day containing from up
+// to to from the reminders and completion events in storage. Days are
+// those of from's location; a to that isn't midnight leaves the last day
+// partial. Completions awaiting a second sign-off don't count.
+func Compute(s storage.Storage, f *family.Family, from, to time.Time) ([]*Day, error) {
+	loc := from.Location()
+	var days []*Day
+	byDate := make(map[string]*Day)
+	var ends []time.Time
+	for d := reminder.StartOfDay(from); d.Before(to); d = reminder.NextDay(d) {
+		day := &Day{FamilyID: f.ID, Date: d.Format(DateLayout)}
+		days = append(days, day)
+		byDate[day.Date] = day
+		ends = append(ends, minTime(reminder.NextDay(d), to))
+	}
+	dayOf := func(t time.Time) *Day {
+		return byDate[t.In(loc).Format(DateLayout)]
+	}
+
+	list, err := s.QueryReminders(storage.ReminderFilter{FamilyID: f.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		all, err := s.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: r.ID})
+		if err != nil {
+			return nil, err
+		}
+		var times []time.Time
+		for _, e := range all {
+			if e.Awaiting() {
+				continue
+			}
+			times = append(times, e.CompletedAt)
+			if day := dayOf(e.CompletedAt); day != nil && !e.CompletedAt.Before(from) && e.CompletedAt.Before(to) {
+				day.Completions++
+				if e.CompletedBy != "" {
+					day.member(e.CompletedBy).Completions++
+				}
+			}
+		}
+		for _, at := range r.Occurrences(reminder.StartOfDay(from), to) {
+			day := dayOf(at)
+			if day == nil {
+				continue
+			}
+			day.Due++
+			day.member(r.FamilyMember).Due++
+			if completed(r, at, times, time.Time{}) {
+				day.Done++
+				day.member(r.FamilyMember).Done++
+			}
+		}
+		for i, day := range days {
+			if overdue(r, ends[i], times) {
+				day.Overdue++
+				day.member(r.FamilyMember).Overdue++
+			}
+		}
+	}
+	return days, nil
+}
+
+// completed reports whether the occurrence of r at the given time was
+// completed, going by the times of its completions: within its occurrence
+// period for recurring reminders, or at all for one-off ones. A non-zero by
+// only counts completions before it.
+func completed(r *reminder.Reminder, at time.Time, times []time.Time, by time.Time) bool {
+	from, to := r.Period(at)
+	if !by.IsZero() && (to.IsZero() || by.Before(to)) {
+		to = by
+	}
+	if !r.IsRecurring() && by.IsZero() && r.Completed {
+		return true
+	}
+	for _, t := range times {
+		if !t.Before(from) && (to.IsZero() || t.Before(to)) {
+			return true
+		}
+	}
+	return false
+}
+
+// overdue reports whether r was open past its due time at the given time:
+// a one-off reminder not completed by then, or a recurring one whose latest
+// occurrence wasn't. Snoozed reminders aren't overdue until the snooze ends.
+func overdue(r *reminder.Reminder, at time.Time, times []time.Time) bool {
+	if r.DueDate == nil || (r.SnoozedUntil != nil && !r.SnoozedUntil.Before(at)) {
+		return false
+	}
+	if !r.IsRecurring() {
+		if !r.DueDate.Before(at) {
+			return false
+		}
+		if r.Completed && r.CompletedAt != nil && r.CompletedAt.Before(at) {
+			return false
+		}
+		return !completed(r, *r.DueDate, times, at)
+	}
+	start, _ := r.Period(at.Add(-time.Nanosecond))
+	occ := r.Occurrences(start, at)
+	if len(occ) == 0 {
+		return false
+	}
+	return !completed(r, occ[len(occ)-1], times, at)
+}
+
+// Save stores a day, replacing any previous aggregate of it
+func Save(s storage.Storage, d *Day) error {
+	created, err := time.Parse(DateLayout, d.Date)
+	if err != nil {
+		return err
+	}
+	rec := storage.Record{Kind: Kind, ID: d.FamilyID + ":" + d.Date, FamilyID: d.FamilyID, Ref: d.Date, CreatedAt: created}
+	return storage.PutJSON(s, rec, d)
+}
+
+// Get returns the stored aggregate of a family's day
+func Get(s storage.Storage, familyID, date string) (*Day, error) {
+	return storage.GetJSON[Day](s, Kind, familyID+":"+date)
+}
+
+// Range returns the aggregates of the days from the day containing from up
+// to to, preferring stored ones, which outlive the completion events they
+// were computed from. Days that haven't been stored are computed.
+func Range(s storage.Storage, f *family.Family, from, to time.Time) ([]*Day, error) {
+	stored, err := storage.ListJSON[Day](s, storage.RecordQuery{Kind: Kind, FamilyID: f.ID})
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]*Day, len(stored))
+	for _, d := range stored {
+		byDate[d.Date] = d
+	}
+	var days []*Day
+	complete := true
+	for d := reminder.StartOfDay(from); d.Before(to); d = reminder.NextDay(d) {
+		day, ok := byDate[d.Format(DateLayout)]
+		if !ok || reminder.NextDay(d).After(to) {
+			complete = false
+			break
+		}
+		days = append(days, day)
+	}
+	if complete {
+		return days, nil
+	}
+	days, err = Compute(s, f, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i, d := range days {
+		if st, ok := byDate[d.Date]; ok && !to.Before(dayEnd(d, from.Location())) {
+			days[i] = st
+		}
+	}
+	return days, nil
+}
+
+// Sum adds up days into a total and per-member totals
+func Sum(days []*Day) (Counts, map[string]*Counts) {
+	var total Counts
+	members := make(map[string]*Counts)
+	for _, d := range days {
+		total.add(d.Counts)
+		for name, c := range d.Members {
+			if members[name] == nil {
+				members[name] = &Counts{}
+			}
+			members[name].add(*c)
+		}
+	}
+	return total, members
+}
+
+// Run aggregates the last Window full days of every family into storage
+// and then purges completion events past each family's retention
+func Run(s storage.Storage, now time.Time) error {
+	families, err := s.ListFamilies()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range families {
+		loc, err := f.LocationFor("")
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		today := reminder.StartOfDay(now.In(loc))
+		days, err := Compute(s, f, today.AddDate(0, 0, -Window), today)
+		if err == nil {
+			for _, d := range days {
+				if err = Save(s, d); err != nil {
+					break
+				}
+			}
+		}
+		if err == nil && f.Settings.CompletionRetentionDays > 0 {
+			_, err = Purge(s, f, now)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Purge deletes the completion events of family f older than its retention
+// and returns how many were deleted. The days an event counts towards, from
+// the occurrence it completes to the end of its period or the day it was
+// made, are aggregated first if they haven't been. Events are kept while their occurrence period is
+// still running, so duplicate completions are still detected.
+func Purge(s storage.Storage, f *family.Family, now time.Time) (int, error) {
+	loc, err := f.LocationFor("")
+	if err != nil {
+		return 0, err
+	}
+	cutoff := reminder.StartOfDay(now.In(loc)).AddDate(0, 0, -f.Settings.CompletionRetentionDays)
+	list, err := s.QueryReminders(storage.ReminderFilter{FamilyID: f.ID})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range list {
+		events, err := s.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: r.ID, To: cutoff})
+		if err != nil {
+			return n, err
+		}
+		for _, e := range events {
+			first, end := r.Period(e.CompletedAt)
+			if !end.IsZero() && end.After(cutoff) {
+				continue
+			}
+			if first.IsZero() && r.DueDate != nil {
+				first = *r.DueDate
+			}
+			if first.IsZero() || first.After(e.CompletedAt) {
+				first = e.CompletedAt
+			}
+			last := e.CompletedAt
+			if end.After(last) {
+				last = end.Add(-time.Nanosecond)
+			}
+			if err := ensureDays(s, f, first.In(loc), last.In(loc)); err != nil {
+				return n, err
+			}
+			if err := s.DeleteCompletionEvent(e.ID); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ensureDays stores the aggregates of the days from the one containing from
+// to the one containing to that haven't been stored yet
+func ensureDays(s storage.Storage, f *family.Family, from, to time.Time) error {
+	start, end := reminder.StartOfDay(from), reminder.NextDay(to)
+	var missing bool
+	for d := start; d.Before(end) && !missing; d = reminder.NextDay(d) {
+		_, err := Get(s, f.ID, d.Format(DateLayout))
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			missing = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if !missing {
+		return nil
+	}
+	days, err := Compute(s, f, start, end)
+	if err != nil {
+		return err
+	}
+	for _, d := range days {
+		if _, err := Get(s, f.ID, d.Date); errors.Is(err, storage.ErrRecordNotFound) {
+			if err := Save(s, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dayEnd returns the midnight ending a day
+func dayEnd(d *Day, loc *time.Location) time.Time {
+	t, _ := time.Parse(DateLayout, d.Date)
+	return reminder.LocalTime(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, loc)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
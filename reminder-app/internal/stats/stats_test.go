@@ -0,0 +1,172 @@
+package stats
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+func TestCompute(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}, Settings: family.Settings{Timezone: "UTC"}}
+	_ = store.CreateFamily(f)
+	// Every Monday from March 3rd, done on the day, a day late and not at all
+	monday := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &monday, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday"}}))
+	once := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem2", "Dentist", "", &once, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	for i, e := range []*reminder.CompletionEvent{
+		{ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: monday.Add(time.Hour)},
+		{ReminderID: "rem1", CompletedBy: "Bob", CompletedAt: monday.AddDate(0, 0, 8)},
+		{ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: monday.AddDate(0, 0, 14), State: reminder.StateAwaitingConfirmation},
+	} {
+		e.ID = string(rune('a' + i))
+		_ = store.CreateCompletionEvent(e)
+	}
+
+	from, to := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 18, 0, 0, 0, 0, time.UTC)
+	days, err := Compute(store, f, from, to)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	if len(days) != 15 {
+		t.Fatalf("expected 15 days, got %d", len(days))
+	}
+	for _, tt := range []struct {
+		i    int
+		want Counts
+	}{
+		{0, Counts{Due: 1, Done: 1, Completions: 1}},
+		{2, Counts{Due: 1, Overdue: 1}},
+		{7, Counts{Due: 1, Done: 1, Overdue: 2}},
+		{8, Counts{Overdue: 1, Completions: 1}},
+		{14, Counts{Due: 1, Overdue: 2}},
+	} {
+		if days[tt.i].Counts != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", days[tt.i].Date, tt.want, days[tt.i].Counts)
+		}
+	}
+	total, members := Sum(days)
+	if total.Due != 4 || total.Done != 2 || total.Completions != 2 {
+		t.Errorf("unexpected total %+v", total)
+	}
+	if a := members["Alice"]; a == nil || a.Due != 3 || a.Done != 2 || a.Completions != 1 {
+		t.Errorf("unexpected figures for Alice: %+v", a)
+	}
+	if b := members["Bob"]; b == nil || b.Due != 1 || b.Done != 0 || b.Completions != 1 {
+		t.Errorf("unexpected figures for Bob: %+v", b)
+	}
+
+	// Purged events are kept in the stored days, so the figures don't change
+	f.Settings.CompletionRetentionDays = MinRetentionDays
+	_ = store.UpdateFamily(f)
+	if err := Run(store, time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if left, _ := store.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: "rem1"}); len(left) != 0 {
+		t.Errorf("expected every event purged, %d left", len(left))
+	}
+	if _, err := Get(store, "fam1", "2025-04-30"); err != nil {
+		t.Errorf("expected the last day stored: %v", err)
+	}
+	days, err = Range(store, f, from, to)
+	if err != nil {
+		t.Fatalf("Range failed: %v", err)
+	}
+	if got, _ := Sum(days); got != total {
+		t.Errorf("expected %+v after purging, got %+v", total, got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}, Settings: family.Settings{Timezone: "UTC"}}
+	_ = store.CreateFamily(f)
+	// Daily at 8am from March 1st, split on the 4th; done on the 1st, 2nd,
+	// 4th and 5th, late on the 6th and not at all on the 3rd
+	start := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	daily := reminder.NewReminder("rem1", "Dishes", "", &start, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"})
+	rest := daily.SplitAt(start.AddDate(0, 0, 3), "rem2")
+	rest.Title, rest.FamilyMember = "Dishes after dinner", "Bob"
+	_ = store.CreateReminder(daily)
+	_ = store.CreateReminder(rest)
+	once := time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem3", "Dentist", "", &once, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	for i, e := range []*reminder.CompletionEvent{
+		{ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: start.Add(-time.Hour)},
+		{ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: start.AddDate(0, 0, 1)},
+		{ReminderID: "rem2", CompletedBy: "Bob", CompletedAt: start.AddDate(0, 0, 3)},
+		{ReminderID: "rem2", CompletedBy: "Alice", CompletedAt: start.AddDate(0, 0, 4)},
+		{ReminderID: "rem2", CompletedBy: "Bob", CompletedAt: start.AddDate(0, 0, 5).Add(time.Hour)},
+		{ReminderID: "rem2", CompletedBy: "Bob", CompletedAt: start.AddDate(0, 0, 6), State: reminder.StateAwaitingConfirmation},
+	} {
+		e.ID = string(rune('a' + i))
+		_ = store.CreateCompletionEvent(e)
+	}
+
+	from, to := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	sum, err := Summarize(store, f, from, to)
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if sum.From != "2025-03-01" || sum.To != "2025-03-07" {
+		t.Errorf("expected March 1st through 7th, got %s through %s", sum.From, sum.To)
+	}
+	want := Totals{Due: 8, Done: 5, Overdue: 4, Completions: 5, CompletionRate: 5.0 / 8}
+	if sum.Totals != want {
+		t.Errorf("expected %+v, got %+v", want, sum.Totals)
+	}
+	if a := sum.Members["Alice"]; a == nil || *a != (Totals{Due: 3, Done: 2, Overdue: 1, Completions: 3, CompletionRate: 2.0 / 3}) {
+		t.Errorf("unexpected figures for Alice: %+v", a)
+	}
+	if b := sum.Members["Bob"]; b == nil || *b != (Totals{Due: 5, Done: 3, Overdue: 3, Completions: 2, CompletionRate: 3.0 / 5}) {
+		t.Errorf("unexpected figures for Bob: %+v", b)
+	}
+	// The 7th is awaiting its second sign-off but its day isn't over, so
+	// the streak since the 3rd continues
+	if len(sum.Streaks) != 1 || *sum.Streaks[0] != (Streak{SeriesID: "rem1", Title: "Dishes after dinner", Current: 3, Longest: 3}) {
+		t.Errorf("unexpected streaks %+v", sum.Streaks)
+	}
+}
+
+func TestRank(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	f := &family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob", "Carol", "Dave"}}
+	_ = store.CreateFamily(f)
+	due := time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem1", "Dishes", "", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	for i, e := range []*reminder.CompletionEvent{
+		{CompletedBy: "Alice", CompletedAt: due, Points: 5},
+		{CompletedBy: "Bob", CompletedAt: due.AddDate(0, 0, 1), Points: 3},
+		{CompletedBy: "Bob", CompletedAt: due.AddDate(0, 0, 2), Points: 2},
+		{CompletedBy: "Carol", CompletedAt: due.AddDate(0, 0, 3), Points: 2},
+		{CompletedBy: "Carol", CompletedAt: due.AddDate(0, 0, 4), Points: 4, State: reminder.StateAwaitingConfirmation},
+		{CompletedBy: "Eve", CompletedAt: due.AddDate(0, 0, 4), Points: 1},
+		{CompletedBy: "Alice", CompletedAt: due.AddDate(0, 0, 7), Points: 5},
+	} {
+		e.ID, e.ReminderID = string(rune('a'+i)), "rem1"
+		_ = store.CreateCompletionEvent(e)
+	}
+
+	from := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	board, err := Rank(store, f, WindowWeek, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Rank failed: %v", err)
+	}
+	// Bob ties with Alice on points but made more completions; Eve has left
+	// the family but keeps the points she earned
+	want := []Standing{
+		{Rank: 1, Member: "Bob", Points: 5, Completions: 2},
+		{Rank: 1, Member: "Alice", Points: 5, Completions: 1},
+		{Rank: 3, Member: "Carol", Points: 2, Completions: 1},
+		{Rank: 4, Member: "Eve", Points: 1, Completions: 1},
+		{Rank: 5, Member: "Dave"},
+	}
+	if !slices.Equal(board.Members, want) {
+		t.Errorf("expected %+v, got %+v", want, board.Members)
+	}
+}
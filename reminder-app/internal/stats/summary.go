@@ -0,0 +1,176 @@
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// Totals are the figures of a summary, for the family or one member
+type Totals struct {
+	// Due counts the occurrences that fell due, Done those of them that
+	// were completed within their occurrence period and Overdue those that
+	// weren't completed by their due time, whether done late or not at all
+	Due     int `json:"due"`
+	Done    int `json:"done"`
+	Overdue int `json:"overdue"`
+	// Completions counts the completions made
+	Completions int `json:"completions"`
+	// CompletionRate is Done out of Due, or 0 when nothing fell due
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+func (t *Totals) rate() {
+	if t.Due > 0 {
+		t.CompletionRate = float64(t.Done) / float64(t.Due)
+	}
+}
+
+// Streak is a recurring reminder's run of occurrences completed within
+// their periods. The parts of a series split by editing it count as one
+// reminder.
+type Streak struct {
+	SeriesID string `json:"series_id"`
+	Title    string `json:"title"`
+	// Current is the run up to the end of the window; an occurrence whose
+	// period is still running doesn't break it. Longest is the longest run
+	// in the window.
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+// Summary is what a family's reminders add up to over the days From through
+// To: totals for the family and per member, occurrences by assignee and
+// completions by whoever made them, and the streak of each recurring
+// reminder
+type Summary struct {
+	FamilyID string `json:"family_id"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Totals
+	Members map[string]*Totals `json:"members,omitempty"`
+	Streaks []*Streak          `json:"streaks"`
+}
+
+// Summarize sums up family f's reminders over [from, to). Completions are
+// tallied by the storage backend, in the database where it can, so only
+// their times are loaded. Completions made after to, including late ones of
+// occurrences in the window, don't count.
+func Summarize(s storage.Storage, f *family.Family, from, to time.Time) (*Summary, error) {
+	last := to.Add(-time.Nanosecond)
+	if last.Before(from) {
+		last = from
+	}
+	sum := &Summary{
+		FamilyID: f.ID,
+		From:     from.Format(DateLayout),
+		To:       last.Format(DateLayout),
+		Members:  make(map[string]*Totals),
+		Streaks:  []*Streak{},
+	}
+	member := func(name string) *Totals {
+		if sum.Members[name] == nil {
+			sum.Members[name] = &Totals{}
+		}
+		return sum.Members[name]
+	}
+
+	tallies, err := storage.TallyCompletions(s, f.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	times := make(map[string][]time.Time)
+	for _, t := range tallies {
+		times[t.ReminderID] = append(times[t.ReminderID], t.Times...)
+		sum.Completions += len(t.Times)
+		if t.CompletedBy != "" {
+			member(t.CompletedBy).Completions += len(t.Times)
+		}
+	}
+	for _, list := range times {
+		slices.SortFunc(list, func(a, b time.Time) int { return a.Compare(b) })
+	}
+
+	list, err := s.QueryReminders(storage.ReminderFilter{FamilyID: f.ID})
+	if err != nil {
+		return nil, err
+	}
+	series := make(map[string][]*reminder.Reminder)
+	for _, r := range list {
+		for _, at := range r.Occurrences(from, to) {
+			assignee := member(r.AssigneeAt(at))
+			sum.Due++
+			assignee.Due++
+			if completed(r, at, times[r.ID], time.Time{}) {
+				sum.Done++
+				assignee.Done++
+			}
+			if late(r, at, times[r.ID]) {
+				sum.Overdue++
+				assignee.Overdue++
+			}
+		}
+		if r.IsRecurring() && r.DueDate != nil {
+			series[r.Series()] = append(series[r.Series()], r)
+		}
+	}
+	sum.rate()
+	for _, t := range sum.Members {
+		t.rate()
+	}
+	delete(sum.Members, "")
+
+	for id, parts := range series {
+		sum.Streaks = append(sum.Streaks, streak(id, parts, times, from, to))
+	}
+	slices.SortFunc(sum.Streaks, func(a, b *Streak) int {
+		return cmp.Or(cmp.Compare(b.Current, a.Current), cmp.Compare(b.Longest, a.Longest),
+			strings.Compare(a.Title, b.Title), strings.Compare(a.SeriesID, b.SeriesID))
+	})
+	return sum, nil
+}
+
+// late reports whether the occurrence of r at the given time wasn't
+// completed by its due time
+func late(r *reminder.Reminder, at time.Time, times []time.Time) bool {
+	if !r.IsRecurring() && r.Completed && r.CompletedAt != nil && !r.CompletedAt.After(at) {
+		return false
+	}
+	return !completed(r, at, times, at.Add(time.Nanosecond))
+}
+
+// streak follows the occurrences in [from, to) of the parts of a series in
+// order, counting runs of completed ones
+func streak(id string, parts []*reminder.Reminder, times map[string][]time.Time, from, to time.Time) *Streak {
+	type occurrence struct {
+		r  *reminder.Reminder
+		at time.Time
+	}
+	var occ []occurrence
+	latest := parts[0]
+	for _, r := range parts {
+		for _, at := range r.Occurrences(from, to) {
+			occ = append(occ, occurrence{r, at})
+		}
+		if r.DueDate.After(*latest.DueDate) {
+			latest = r
+		}
+	}
+	slices.SortFunc(occ, func(a, b occurrence) int { return a.at.Compare(b.at) })
+
+	st := &Streak{SeriesID: id, Title: latest.Title}
+	for _, o := range occ {
+		if completed(o.r, o.at, times[o.r.ID], time.Time{}) {
+			st.Current++
+			st.Longest = max(st.Longest, st.Current)
+		} else if _, end := o.r.Period(o.at); !end.After(to) {
+			st.Current = 0
+		}
+	}
+	return st
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// CompletionTally is what one member's completions of one reminder over a
+// window add up to: when each was made, oldest first, and the points they
+// earned
+type CompletionTally struct {
+	ReminderID  string
+	CompletedBy string
+	Times       []time.Time
+	Points      int
+}
+
+// CompletionAggregator is implemented by backends that can tally a
+// family's completions in the database rather than returning every event
+type CompletionAggregator interface {
+	// TallyCompletions returns the completions made in [from, to) of the
+	// reminders of familyID, one tally per reminder and member. Completions
+	// awaiting a second sign-off aren't counted.
+	TallyCompletions(familyID string, from, to time.Time) ([]*CompletionTally, error)
+}
+
+// TallyCompletions tallies a family's completions made in [from, to), in
+// the database where the backend supports it and otherwise from the events
+// of each of the family's reminders. Tallies are ordered by reminder and
+// member.
+func TallyCompletions(s Storage, familyID string, from, to time.Time) ([]*CompletionTally, error) {
+	if a, ok := s.(CompletionAggregator); ok {
+		return a.TallyCompletions(familyID, from, to)
+	}
+	list, err := s.QueryReminders(ReminderFilter{FamilyID: familyID})
+	if err != nil {
+		return nil, err
+	}
+	var tallies []*CompletionTally
+	for _, r := range list {
+		events, err := s.QueryCompletionEvents(CompletionEventQuery{ReminderID: r.ID, From: from, To: to})
+		if err != nil {
+			return nil, err
+		}
+		byMember := make(map[string]*CompletionTally)
+		for _, e := range events {
+			if e.Awaiting() {
+				continue
+			}
+			t := byMember[e.CompletedBy]
+			if t == nil {
+				t = &CompletionTally{ReminderID: r.ID, CompletedBy: e.CompletedBy}
+				byMember[e.CompletedBy] = t
+				tallies = append(tallies, t)
+			}
+			t.Times = append(t.Times, e.CompletedAt)
+			t.Points += e.Points
+		}
+	}
+	sortTallies(tallies)
+	return tallies, nil
+}
+
+// sortTallies orders tallies by reminder and member, and each one's times
+// oldest first
+func sortTallies(tallies []*CompletionTally) {
+	for _, t := range tallies {
+		slices.SortFunc(t.Times, func(a, b time.Time) int { return a.Compare(b) })
+	}
+	slices.SortFunc(tallies, func(a, b *CompletionTally) int {
+		if a.ReminderID != b.ReminderID {
+			return strings.Compare(a.ReminderID, b.ReminderID)
+		}
+		return strings.Compare(a.CompletedBy, b.CompletedBy)
+	})
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"reminder-app/internal/reminder"
+)
+
+// ReminderBatcher is implemented by backends that can save many reminders
+// in one operation. The SQL, file and memory backends save all of them or
+// none; MongoDB saves them in order and stops at the first failure.
+type ReminderBatcher interface {
+	// SaveReminders creates or overwrites every reminder in rs and returns
+	// how many were saved
+	SaveReminders(rs []*reminder.Reminder) (int, error)
+}
+
+// SaveReminders creates or overwrites every reminder in rs, in one
+// operation where the backend supports it and otherwise one at a time,
+// stopping at the first failure. It returns how many were saved.
+func SaveReminders(s Storage, rs []*reminder.Reminder) (int, error) {
+	if b, ok := s.(ReminderBatcher); ok {
+		return b.SaveReminders(rs)
+	}
+	for i, r := range rs {
+		if err := s.CreateReminder(r); err != nil {
+			return i, err
+		}
+	}
+	return len(rs), nil
+}
+
+// ErrVersionConflict is returned by UpdateReminders when a reminder was
+// changed since the version it was edited from
+var ErrVersionConflict = errors.New("version conflict")
+
+// ReminderUpdater is implemented by backends that can save edited
+// reminders only if nobody else saved them in the meantime, checking the
+// stored version in the same operation as the write, so editors on other
+// replicas sharing the database can't overwrite each other
+type ReminderUpdater interface {
+	// UpdateReminders saves every reminder in rs that is stored at the
+	// version before its own, or isn't stored yet if it is at version 1,
+	// and returns how many were saved. It stops at the first other one
+	// with ErrVersionConflict.
+	UpdateReminders(rs []*reminder.Reminder) (int, error)
+}
+
+// UpdateReminders saves reminders edited from their stored versions, each
+// of which must have had its Version incremented, as ReminderUpdater does.
+// Backends that can't check versions as they write are checked first,
+// which is only safe while a single process writes to them.
+func UpdateReminders(s Storage, rs ...*reminder.Reminder) (int, error) {
+	if u, ok := s.(ReminderUpdater); ok {
+		return u.UpdateReminders(rs)
+	}
+	for _, r := range rs {
+		stored := 0
+		if old, err := s.GetReminder(r.ID); err == nil {
+			stored = old.Version
+		} else if r.Version != 1 {
+			return 0, err
+		}
+		if err := checkVersion(r, stored); err != nil {
+			return 0, err
+		}
+	}
+	return SaveReminders(s, rs)
+}
+
+// checkVersion returns ErrVersionConflict unless r was edited from the
+// stored version, where 0 means r isn't stored
+func checkVersion(r *reminder.Reminder, stored int) error {
+	if stored != r.Version-1 {
+		return fmt.Errorf("%w: reminder %s is at version %d", ErrVersionConflict, r.ID, stored)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// BlobKind is the record kind of blobs
+const BlobKind = "blob"
+
+// Blob is binary content, such as an avatar image, kept in a record so
+// every backend can store it. Blobs are meant to be small; callers bound
+// their size.
+type Blob struct {
+	ContentType string    `json:"content_type"`
+	Data        []byte    `json:"data"`
+	ETag        string    `json:"etag"` // quoted, for the ETag header
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PutBlob stores data under id, replacing any blob already there. familyID
+// ties the blob to a family so it is deleted along with it.
+func PutBlob(s Storage, id, familyID, contentType string, data []byte, now time.Time) (*Blob, error) {
+	sum := sha256.Sum256(data)
+	b := &Blob{
+		ContentType: contentType,
+		Data:        data,
+		ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		UpdatedAt:   now,
+	}
+	rec := Record{Kind: BlobKind, ID: id, FamilyID: familyID, CreatedAt: now}
+	if err := PutJSON(s, rec, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GetBlob loads the blob stored under id. It returns ErrRecordNotFound if
+// there is none.
+func GetBlob(s Storage, id string) (*Blob, error) {
+	return GetJSON[Blob](s, BlobKind, id)
+}
+
+// DeleteBlob removes the blob stored under id. It returns ErrRecordNotFound
+// if there is none.
+func DeleteBlob(s Storage, id string) error {
+	return s.DeleteRecord(BlobKind, id)
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"time"
+
+	"reminder-app/internal/breaker"
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BreakerStorage guards a backend with a circuit breaker, so while the
+// database is unreachable calls fail at once with breaker.ErrOpen instead
+// of each waiting for its own timeout. Only errors for which Unavailable
+// reports true count as failures; a missing entity is a normal answer, and
+// so is running out of the time a request bound with WithContext allowed.
+type BreakerStorage struct {
+	inner   Storage
+	breaker *breaker.Breaker
+	// ctx is the context the backend is bound to, if any
+	ctx context.Context
+}
+
+// WithBreaker wraps s with the circuit breaker b
+func WithBreaker(s Storage, b *breaker.Breaker) *BreakerStorage {
+	return &BreakerStorage{inner: s, breaker: b}
+}
+
+// Unwrap returns the backend under any BreakerStorage, or the primary one
+// of a DualWriteStorage or ReplicaStorage, for callers that need a
+// backend's own methods, such as SQLite backups
+func Unwrap(s Storage) Storage {
+	for {
+		switch w := s.(type) {
+		case *BreakerStorage:
+			s = w.inner
+		case *DualWriteStorage:
+			s = w.primary
+		case *ReplicaStorage:
+			s = w.primary
+		default:
+			return s
+		}
+	}
+}
+
+// Unavailable reports whether err means the backend couldn't be reached or
+// didn't answer in time, as opposed to refusing a particular request
+func Unavailable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr) ||
+		mongo.IsNetworkError(err) ||
+		mongo.IsTimeout(err)
+}
+
+// unavailable reports whether err counts as a failure of the backend. A
+// timeout or cancellation of the bound context is the caller's, not the
+// backend's, even though drivers report it like a slow database.
+func (s *BreakerStorage) unavailable(err error) bool {
+	if s.ctx != nil && s.ctx.Err() != nil {
+		return false
+	}
+	return Unavailable(err)
+}
+
+// guard runs fn through the breaker
+func guard[T any](s *BreakerStorage, fn func() (T, error)) (T, error) {
+	var v T
+	err := s.breaker.Do(func() error {
+		var err error
+		v, err = fn()
+		return err
+	}, s.unavailable)
+	return v, err
+}
+
+// WithContext binds the backend to ctx behind the same breaker
+func (s *BreakerStorage) WithContext(ctx context.Context) Storage {
+	return &BreakerStorage{inner: WithContext(s.inner, ctx), breaker: s.breaker, ctx: ctx}
+}
+
+// ReadPrimary reads from the backend's primary behind the same breaker
+func (s *BreakerStorage) ReadPrimary() Storage {
+	return &BreakerStorage{inner: ReadPrimary(s.inner), breaker: s.breaker, ctx: s.ctx}
+}
+
+// Ping pings the backend directly, so probes see the database's state even
+// while the breaker is open
+func (s *BreakerStorage) Ping(ctx context.Context) error {
+	return Ping(ctx, s.inner)
+}
+
+// VerifySchema verifies the backend's schema if it has one
+func (s *BreakerStorage) VerifySchema() error {
+	if v, ok := s.inner.(SchemaVerifier); ok {
+		return v.VerifySchema()
+	}
+	return nil
+}
+
+func (s *BreakerStorage) CreateFamily(f *family.Family) error {
+	return s.breaker.Do(func() error { return s.inner.CreateFamily(f) }, s.unavailable)
+}
+
+func (s *BreakerStorage) GetFamily(id string) (*family.Family, error) {
+	return guard(s, func() (*family.Family, error) { return s.inner.GetFamily(id) })
+}
+
+func (s *BreakerStorage) ListFamilies() ([]*family.Family, error) {
+	return guard(s, func() ([]*family.Family, error) { return s.inner.ListFamilies() })
+}
+
+func (s *BreakerStorage) UpdateFamily(f *family.Family) error {
+	return s.breaker.Do(func() error { return s.inner.UpdateFamily(f) }, s.unavailable)
+}
+
+func (s *BreakerStorage) DeleteFamily(id string) error {
+	return s.breaker.Do(func() error { return s.inner.DeleteFamily(id) }, s.unavailable)
+}
+
+func (s *BreakerStorage) CreateReminder(r *reminder.Reminder) error {
+	return s.breaker.Do(func() error { return s.inner.CreateReminder(r) }, s.unavailable)
+}
+
+func (s *BreakerStorage) SaveReminders(rs []*reminder.Reminder) (int, error) {
+	return guard(s, func() (int, error) { return SaveReminders(s.inner, rs) })
+}
+
+func (s *BreakerStorage) UpdateReminders(rs []*reminder.Reminder) (int, error) {
+	return guard(s, func() (int, error) { return UpdateReminders(s.inner, rs...) })
+}
+
+func (s *BreakerStorage) GetReminder(id string) (*reminder.Reminder, error) {
+	return guard(s, func() (*reminder.Reminder, error) { return s.inner.GetReminder(id) })
+}
+
+func (s *BreakerStorage) ListReminders() ([]*reminder.Reminder, error) {
+	return guard(s, func() ([]*reminder.Reminder, error) { return s.inner.ListReminders() })
+}
+
+func (s *BreakerStorage) QueryReminders(f ReminderFilter) ([]*reminder.Reminder, error) {
+	return guard(s, func() ([]*reminder.Reminder, error) { return s.inner.QueryReminders(f) })
+}
+
+func (s *BreakerStorage) DeleteReminder(id string) error {
+	return s.breaker.Do(func() error { return s.inner.DeleteReminder(id) }, s.unavailable)
+}
+
+func (s *BreakerStorage) CreateCompletionEvent(e *reminder.CompletionEvent) error {
+	return s.breaker.Do(func() error { return s.inner.CreateCompletionEvent(e) }, s.unavailable)
+}
+
+func (s *BreakerStorage) GetCompletionEvent(id string) (*reminder.CompletionEvent, error) {
+	return guard(s, func() (*reminder.CompletionEvent, error) { return s.inner.GetCompletionEvent(id) })
+}
+
+func (s *BreakerStorage) ListCompletionEvents(reminderID string) ([]*reminder.CompletionEvent, error) {
+	return guard(s, func() ([]*reminder.CompletionEvent, error) { return s.inner.ListCompletionEvents(reminderID) })
+}
+
+func (s *BreakerStorage) ListAllCompletionEvents() ([]*reminder.CompletionEvent, error) {
+	return guard(s, func() ([]*reminder.CompletionEvent, error) { return s.inner.ListAllCompletionEvents() })
+}
+
+func (s *BreakerStorage) QueryCompletionEvents(q CompletionEventQuery) ([]*reminder.CompletionEvent, error) {
+	return guard(s, func() ([]*reminder.CompletionEvent, error) { return s.inner.QueryCompletionEvents(q) })
+}
+
+func (s *BreakerStorage) TallyCompletions(familyID string, from, to time.Time) ([]*CompletionTally, error) {
+	return guard(s, func() ([]*CompletionTally, error) { return TallyCompletions(s.inner, familyID, from, to) })
+}
+
+func (s *BreakerStorage) GetLatestCompletionEvent(reminderID string) (*reminder.CompletionEvent, error) {
+	return guard(s, func() (*reminder.CompletionEvent, error) { return s.inner.GetLatestCompletionEvent(reminderID) })
+}
+
+func (s *BreakerStorage) DeleteCompletionEvent(id string) error {
+	return s.breaker.Do(func() error { return s.inner.DeleteCompletionEvent(id) }, s.unavailable)
+}
+
+func (s *BreakerStorage) PutRecord(rec *Record) error {
+	return s.breaker.Do(func() error { return s.inner.PutRecord(rec) }, s.unavailable)
+}
+
+func (s *BreakerStorage) CreateRecord(rec *Record, now time.Time) error {
+	return s.breaker.Do(func() error { return CreateRecord(s.inner, rec, now) }, s.unavailable)
+}
+
+func (s *BreakerStorage) GetRecord(kind, id string) (*Record, error) {
+	return guard(s, func() (*Record, error) { return s.inner.GetRecord(kind, id) })
+}
+
+func (s *BreakerStorage) ListRecords(q RecordQuery) ([]*Record, error) {
+	return guard(s, func() ([]*Record, error) { return s.inner.ListRecords(q) })
+}
+
+func (s *BreakerStorage) DeleteRecord(kind, id string) error {
+	return s.breaker.Do(func() error { return s.inner.DeleteRecord(kind, id) }, s.unavailable)
+}
+
+func (s *BreakerStorage) PurgeExpiredRecords(now time.Time) (int, error) {
+	return guard(s, func() (int, error) { return s.inner.PurgeExpiredRecords(now) })
+}
+
+func (s *BreakerStorage) NextID(kind IDKind) (int, error) {
+	return guard(s, func() (int, error) { return s.inner.NextID(kind) })
+}
+
+func (s *BreakerStorage) SetFamilyIDCounter(counter int) error {
+	return s.breaker.Do(func() error { return s.inner.SetFamilyIDCounter(counter) }, s.unavailable)
+}
+
+func (s *BreakerStorage) SetReminderIDCounter(counter int) error {
+	return s.breaker.Do(func() error { return s.inner.SetReminderIDCounter(counter) }, s.unavailable)
+}
+
+func (s *BreakerStorage) SetCompletionEventIDCounter(counter int) error {
+	return s.breaker.Do(func() error { return s.inner.SetCompletionEventIDCounter(counter) }, s.unavailable)
+}
+
+// The counter getters can't report errors, so they bypass the breaker
+
+func (s *BreakerStorage) GetFamilyIDCounter() int {
+	return s.inner.GetFamilyIDCounter()
+}
+
+func (s *BreakerStorage) GetReminderIDCounter() int {
+	return s.inner.GetReminderIDCounter()
+}
+
+func (s *BreakerStorage) GetCompletionEventIDCounter() int {
+	return s.inner.GetCompletionEventIDCounter()
+}
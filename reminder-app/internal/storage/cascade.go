@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"reminder-app/internal/reminder"
+)
+
+// DeleteReminderCascade deletes a reminder along with its completion and
+// status events and acknowledgements. The events go first, so a failure
+// part way through never leaves events behind without their reminder.
+func DeleteReminderCascade(s Storage, id string) error {
+	if err := deleteReminderEvents(s, id); err != nil {
+		return err
+	}
+	return s.DeleteReminder(id)
+}
+
+// deleteReminderEvents deletes the completion and status events and the
+// acknowledgements of a reminder
+func deleteReminderEvents(s Storage, id string) error {
+	events, err := s.ListCompletionEvents(id)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := s.DeleteCompletionEvent(e.ID); err != nil {
+			return err
+		}
+	}
+	for _, kind := range []string{reminder.StatusEventKind, reminder.AckKind} {
+		records, err := s.ListRecords(RecordQuery{Kind: kind, Ref: id})
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			if err := s.DeleteRecord(rec.Kind, rec.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteFamilyCascade deletes a family along with its reminders and their
+// events, and its blobs such as avatars, returning the reminders that were
+// deleted
+func DeleteFamilyCascade(s Storage, id string) ([]*reminder.Reminder, error) {
+	list, err := s.QueryReminders(ReminderFilter{FamilyID: id})
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range list {
+		if err := DeleteReminderCascade(s, r.ID); err != nil {
+			return list[:i], err
+		}
+	}
+	if err := deleteFamilyBlobs(s, id); err != nil {
+		return list, err
+	}
+	return list, s.DeleteFamily(id)
+}
+
+// deleteFamilyBlobs deletes the blobs of a family
+func deleteFamilyBlobs(s Storage, id string) error {
+	blobs, err := s.ListRecords(RecordQuery{Kind: BlobKind, FamilyID: id})
+	if err != nil {
+		return err
+	}
+	for _, rec := range blobs {
+		if err := s.DeleteRecord(rec.Kind, rec.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package storage
+
+import "context"
+
+// ContextBinder is implemented by backends whose queries can be cancelled,
+// so a request's deadline also bounds the database work done for it
+type ContextBinder interface {
+	WithContext(ctx context.Context) Storage
+}
+
+// WithContext returns s bound to ctx where the backend supports it, or s
+// itself. Calls through the result fail with ctx's error once it is done.
+func WithContext(s Storage, ctx context.Context) Storage {
+	if b, ok := s.(ContextBinder); ok {
+		return b.WithContext(ctx)
+	}
+	return s
+}
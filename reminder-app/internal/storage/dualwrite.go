@@ -0,0 +1,587 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+)
+
+// maxMirrorFailures caps how many failed mirror writes DualWriteStorage
+// remembers
+const maxMirrorFailures = 100
+
+// MirrorFailure is a write that reached the primary backend but failed on
+// the secondary one
+type MirrorFailure struct {
+	At    time.Time `json:"at"`
+	Op    string    `json:"op"` // e.g. "create reminder rem12"
+	Error string    `json:"error"`
+}
+
+// DualWriteStorage migrates between backends without downtime. Every write
+// goes to the primary backend and, once it succeeded there, to the
+// secondary one; reads are only served by the primary. A write that fails
+// on the secondary doesn't fail the request but is remembered, and Compare
+// reports where the two backends have diverged. Sync copies what the
+// secondary is missing, so once a sync and a clean comparison are done the
+// secondary can be promoted.
+type DualWriteStorage struct {
+	primary   Storage
+	secondary Storage
+	log       *mirrorLog
+}
+
+// mirrorLog is shared by a DualWriteStorage and its context-bound copies
+type mirrorLog struct {
+	mu       sync.Mutex
+	failures []MirrorFailure
+	// mirrored counts the writes that reached both backends
+	mirrored int
+}
+
+// NewDualWriteStorage writes to both primary and secondary, reading from
+// primary
+func NewDualWriteStorage(primary, secondary Storage) *DualWriteStorage {
+	return &DualWriteStorage{primary: primary, secondary: secondary, log: &mirrorLog{}}
+}
+
+// Secondary returns the backend writes are mirrored to
+func (s *DualWriteStorage) Secondary() Storage {
+	return s.secondary
+}
+
+// mirror applies a write that succeeded on the primary to the secondary
+func (s *DualWriteStorage) mirror(op string, write func() error) {
+	err := write()
+	l := s.log
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		l.mirrored++
+		return
+	}
+	log.Printf("Dual write: %s failed on the secondary backend: %v", op, err)
+	if len(l.failures) == maxMirrorFailures {
+		l.failures = l.failures[1:]
+	}
+	l.failures = append(l.failures, MirrorFailure{At: time.Now(), Op: op, Error: err.Error()})
+}
+
+// Failures returns the most recent writes that failed on the secondary,
+// oldest first, and how many writes reached both backends
+func (s *DualWriteStorage) Failures() ([]MirrorFailure, int) {
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+	return append([]MirrorFailure(nil), s.log.failures...), s.log.mirrored
+}
+
+// WithContext binds both backends to ctx, recording mirror failures
+// alongside s's own
+func (s *DualWriteStorage) WithContext(ctx context.Context) Storage {
+	return &DualWriteStorage{primary: WithContext(s.primary, ctx), secondary: WithContext(s.secondary, ctx), log: s.log}
+}
+
+// ReadPrimary reads from the primary backend's own primary
+func (s *DualWriteStorage) ReadPrimary() Storage {
+	return &DualWriteStorage{primary: ReadPrimary(s.primary), secondary: s.secondary, log: s.log}
+}
+
+// Ping pings the primary backend. The secondary being down fails no
+// requests, only their mirrored writes.
+func (s *DualWriteStorage) Ping(ctx context.Context) error {
+	return Ping(ctx, s.primary)
+}
+
+// VerifySchema verifies the schema of both backends
+func (s *DualWriteStorage) VerifySchema() error {
+	for _, b := range []struct {
+		name string
+		s    Storage
+	}{{"primary", s.primary}, {"secondary", s.secondary}} {
+		if v, ok := b.s.(SchemaVerifier); ok {
+			if err := v.VerifySchema(); err != nil {
+				return fmt.Errorf("%s backend: %w", b.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *DualWriteStorage) CreateFamily(f *family.Family) error {
+	return s.write(func(b Storage) error { return b.CreateFamily(f) }, "create family "+f.ID)
+}
+
+func (s *DualWriteStorage) GetFamily(id string) (*family.Family, error) {
+	return s.primary.GetFamily(id)
+}
+
+func (s *DualWriteStorage) ListFamilies() ([]*family.Family, error) {
+	return s.primary.ListFamilies()
+}
+
+func (s *DualWriteStorage) UpdateFamily(f *family.Family) error {
+	return s.write(func(b Storage) error { return b.UpdateFamily(f) }, "update family "+f.ID)
+}
+
+func (s *DualWriteStorage) DeleteFamily(id string) error {
+	return s.write(func(b Storage) error { return b.DeleteFamily(id) }, "delete family "+id)
+}
+
+func (s *DualWriteStorage) CreateReminder(r *reminder.Reminder) error {
+	return s.write(func(b Storage) error { return b.CreateReminder(r) }, "create reminder "+r.ID)
+}
+
+// SaveReminders saves the reminders on the primary and mirrors whatever
+// was saved there
+func (s *DualWriteStorage) SaveReminders(rs []*reminder.Reminder) (int, error) {
+	n, err := SaveReminders(s.primary, rs)
+	if n > 0 {
+		s.mirror(fmt.Sprintf("save %d reminders", n), func() error {
+			_, err := SaveReminders(s.secondary, rs[:n])
+			return err
+		})
+	}
+	return n, err
+}
+
+// UpdateReminders checks versions on the primary alone and mirrors
+// whatever was saved there
+func (s *DualWriteStorage) UpdateReminders(rs []*reminder.Reminder) (int, error) {
+	n, err := UpdateReminders(s.primary, rs...)
+	if n > 0 {
+		s.mirror(fmt.Sprintf("save %d reminders", n), func() error {
+			_, err := SaveReminders(s.secondary, rs[:n])
+			return err
+		})
+	}
+	return n, err
+}
+
+func (s *DualWriteStorage) GetReminder(id string) (*reminder.Reminder, error) {
+	return s.primary.GetReminder(id)
+}
+
+func (s *DualWriteStorage) ListReminders() ([]*reminder.Reminder, error) {
+	return s.primary.ListReminders()
+}
+
+func (s *DualWriteStorage) QueryReminders(f ReminderFilter) ([]*reminder.Reminder, error) {
+	return s.primary.QueryReminders(f)
+}
+
+func (s *DualWriteStorage) DeleteReminder(id string) error {
+	return s.write(func(b Storage) error { return b.DeleteReminder(id) }, "delete reminder "+id)
+}
+
+func (s *DualWriteStorage) CreateCompletionEvent(e *reminder.CompletionEvent) error {
+	return s.write(func(b Storage) error { return b.CreateCompletionEvent(e) }, "create completion event "+e.ID)
+}
+
+func (s *DualWriteStorage) GetCompletionEvent(id string) (*reminder.CompletionEvent, error) {
+	return s.primary.GetCompletionEvent(id)
+}
+
+func (s *DualWriteStorage) ListCompletionEvents(reminderID string) ([]*reminder.CompletionEvent, error) {
+	return s.primary.ListCompletionEvents(reminderID)
+}
+
+func (s *DualWriteStorage) ListAllCompletionEvents() ([]*reminder.CompletionEvent, error) {
+	return s.primary.ListAllCompletionEvents()
+}
+
+func (s *DualWriteStorage) QueryCompletionEvents(q CompletionEventQuery) ([]*reminder.CompletionEvent, error) {
+	return s.primary.QueryCompletionEvents(q)
+}
+
+func (s *DualWriteStorage) TallyCompletions(familyID string, from, to time.Time) ([]*CompletionTally, error) {
+	return TallyCompletions(s.primary, familyID, from, to)
+}
+
+func (s *DualWriteStorage) GetLatestCompletionEvent(reminderID string) (*reminder.CompletionEvent, error) {
+	return s.primary.GetLatestCompletionEvent(reminderID)
+}
+
+func (s *DualWriteStorage) DeleteCompletionEvent(id string) error {
+	return s.write(func(b Storage) error { return b.DeleteCompletionEvent(id) }, "delete completion event "+id)
+}
+
+func (s *DualWriteStorage) PutRecord(rec *Record) error {
+	return s.write(func(b Storage) error { return b.PutRecord(rec) }, "put record "+recordKey(rec.Kind, rec.ID))
+}
+
+// CreateRecord checks for an existing record on the primary alone and
+// mirrors the record if it was created there
+func (s *DualWriteStorage) CreateRecord(rec *Record, now time.Time) error {
+	if err := CreateRecord(s.primary, rec, now); err != nil {
+		return err
+	}
+	s.mirror("put record "+recordKey(rec.Kind, rec.ID), func() error { return s.secondary.PutRecord(rec) })
+	return nil
+}
+
+func (s *DualWriteStorage) GetRecord(kind, id string) (*Record, error) {
+	return s.primary.GetRecord(kind, id)
+}
+
+func (s *DualWriteStorage) ListRecords(q RecordQuery) ([]*Record, error) {
+	return s.primary.ListRecords(q)
+}
+
+func (s *DualWriteStorage) DeleteRecord(kind, id string) error {
+	return s.write(func(b Storage) error { return b.DeleteRecord(kind, id) }, "delete record "+recordKey(kind, id))
+}
+
+func (s *DualWriteStorage) PurgeExpiredRecords(now time.Time) (int, error) {
+	n, err := s.primary.PurgeExpiredRecords(now)
+	if err != nil {
+		return n, err
+	}
+	s.mirror("purge expired records", func() error {
+		_, err := s.secondary.PurgeExpiredRecords(now)
+		return err
+	})
+	return n, nil
+}
+
+// NextID takes the ID from the primary and advances the secondary's
+// counter to match, so the secondary hands out the same IDs once promoted
+func (s *DualWriteStorage) NextID(kind IDKind) (int, error) {
+	n, err := s.primary.NextID(kind)
+	if err != nil {
+		return n, err
+	}
+	s.mirror(fmt.Sprintf("advance %s ID counter to %d", kind, n), func() error {
+		return setCounter(s.secondary, kind, n)
+	})
+	return n, nil
+}
+
+func (s *DualWriteStorage) SetFamilyIDCounter(counter int) error {
+	return s.write(func(b Storage) error { return b.SetFamilyIDCounter(counter) }, "set family ID counter")
+}
+
+func (s *DualWriteStorage) SetReminderIDCounter(counter int) error {
+	return s.write(func(b Storage) error { return b.SetReminderIDCounter(counter) }, "set reminder ID counter")
+}
+
+func (s *DualWriteStorage) SetCompletionEventIDCounter(counter int) error {
+	return s.write(func(b Storage) error { return b.SetCompletionEventIDCounter(counter) }, "set completion event ID counter")
+}
+
+func (s *DualWriteStorage) GetFamilyIDCounter() int {
+	return s.primary.GetFamilyIDCounter()
+}
+
+func (s *DualWriteStorage) GetReminderIDCounter() int {
+	return s.primary.GetReminderIDCounter()
+}
+
+func (s *DualWriteStorage) GetCompletionEventIDCounter() int {
+	return s.primary.GetCompletionEventIDCounter()
+}
+
+// write applies fn to the primary and, if that succeeded, mirrors it
+func (s *DualWriteStorage) write(fn func(Storage) error, op string) error {
+	if err := fn(s.primary); err != nil {
+		return err
+	}
+	s.mirror(op, func() error { return fn(s.secondary) })
+	return nil
+}
+
+// setCounter sets the ID counter of kind
+func setCounter(s Storage, kind IDKind, n int) error {
+	switch kind {
+	case FamilyIDs:
+		return s.SetFamilyIDCounter(n)
+	case ReminderIDs:
+		return s.SetReminderIDCounter(n)
+	case CompletionEventIDs:
+		return s.SetCompletionEventIDCounter(n)
+	}
+	return fmt.Errorf("unknown ID kind: %s", kind)
+}
+
+// getCounter returns the ID counter of kind
+func getCounter(s Storage, kind IDKind) int {
+	switch kind {
+	case FamilyIDs:
+		return s.GetFamilyIDCounter()
+	case ReminderIDs:
+		return s.GetReminderIDCounter()
+	case CompletionEventIDs:
+		return s.GetCompletionEventIDCounter()
+	}
+	return 0
+}
+
+// Divergence problems
+const (
+	MissingFromSecondary = "missing from secondary"
+	OnlyInSecondary      = "only in secondary"
+	Differs              = "differs"
+)
+
+// Divergence is an entity that doesn't match between the backends
+type Divergence struct {
+	Entity  string `json:"entity"` // e.g. "reminder" or "record/audit"
+	ID      string `json:"id"`
+	Problem string `json:"problem"`
+	// Fields are the top-level fields that differ, for Differs
+	Fields []string `json:"fields,omitempty"`
+}
+
+// DualWriteReport is the outcome of comparing the two backends
+type DualWriteReport struct {
+	// Checked counts the entities compared, by entity
+	Checked     map[string]int `json:"checked"`
+	Divergences []Divergence   `json:"divergences"`
+}
+
+// comparison collects the entities of one kind from both backends
+type comparison struct {
+	entity    string
+	primary   map[string]any
+	secondary map[string]any
+}
+
+func newComparison(entity string) *comparison {
+	return &comparison{entity: entity, primary: make(map[string]any), secondary: make(map[string]any)}
+}
+
+// Compare reads everything from both backends and reports the entities
+// that differ. Records are compared for the given kinds only, since
+// storage doesn't know every kind in use. Times are compared as instants,
+// as backends differ in the zone they read them back in.
+func (s *DualWriteStorage) Compare(recordKinds []string) (*DualWriteReport, error) {
+	comparisons, err := s.collect(recordKinds)
+	if err != nil {
+		return nil, err
+	}
+	report := &DualWriteReport{Checked: make(map[string]int), Divergences: []Divergence{}}
+	for _, c := range comparisons {
+		report.Checked[c.entity] = len(c.primary)
+		for _, id := range sortedKeys(c.primary) {
+			other, ok := c.secondary[id]
+			if !ok {
+				report.Divergences = append(report.Divergences, Divergence{Entity: c.entity, ID: id, Problem: MissingFromSecondary})
+				continue
+			}
+			if fields := differingFields(c.primary[id], other); len(fields) > 0 {
+				report.Divergences = append(report.Divergences, Divergence{Entity: c.entity, ID: id, Problem: Differs, Fields: fields})
+			}
+		}
+		for _, id := range sortedKeys(c.secondary) {
+			if _, ok := c.primary[id]; !ok {
+				report.Divergences = append(report.Divergences, Divergence{Entity: c.entity, ID: id, Problem: OnlyInSecondary})
+			}
+		}
+	}
+	for _, kind := range []IDKind{FamilyIDs, ReminderIDs, CompletionEventIDs} {
+		if p, sec := getCounter(s.primary, kind), getCounter(s.secondary, kind); sec < p {
+			report.Divergences = append(report.Divergences, Divergence{Entity: "id_counter", ID: string(kind), Problem: Differs,
+				Fields: []string{fmt.Sprintf("primary %d, secondary %d", p, sec)}})
+		}
+	}
+	return report, nil
+}
+
+// collect lists every entity of both backends
+func (s *DualWriteStorage) collect(recordKinds []string) ([]*comparison, error) {
+	families := newComparison("family")
+	reminders := newComparison("reminder")
+	events := newComparison("completion_event")
+	comparisons := []*comparison{families, reminders, events}
+	for _, side := range []struct {
+		name  string
+		store Storage
+		pick  func(*comparison) map[string]any
+	}{
+		{"primary", s.primary, func(c *comparison) map[string]any { return c.primary }},
+		{"secondary", s.secondary, func(c *comparison) map[string]any { return c.secondary }},
+	} {
+		fams, err := side.store.ListFamilies()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s families: %w", side.name, err)
+		}
+		for _, f := range fams {
+			side.pick(families)[f.ID] = f
+		}
+		rems, err := side.store.ListReminders()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s reminders: %w", side.name, err)
+		}
+		for _, r := range rems {
+			side.pick(reminders)[r.ID] = r
+		}
+		evs, err := side.store.ListAllCompletionEvents()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s completion events: %w", side.name, err)
+		}
+		for _, e := range evs {
+			side.pick(events)[e.ID] = e
+		}
+	}
+	for _, kind := range recordKinds {
+		c := newComparison("record/" + kind)
+		for _, side := range []struct {
+			name  string
+			store Storage
+			into  map[string]any
+		}{{"primary", s.primary, c.primary}, {"secondary", s.secondary, c.secondary}} {
+			records, err := side.store.ListRecords(RecordQuery{Kind: kind})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s %s records: %w", side.name, kind, err)
+			}
+			for _, rec := range records {
+				side.into[rec.ID] = rec
+			}
+		}
+		comparisons = append(comparisons, c)
+	}
+	return comparisons, nil
+}
+
+// Sync copies every entity that is missing from the secondary or differs
+// there, deletes the ones only the secondary has and advances lagging ID
+// counters, then returns the divergences it repaired. Writes made while
+// it runs are mirrored as usual.
+func (s *DualWriteStorage) Sync(recordKinds []string) ([]Divergence, error) {
+	report, err := s.Compare(recordKinds)
+	if err != nil {
+		return nil, err
+	}
+	var repaired []Divergence
+	for _, d := range report.Divergences {
+		if err := s.repair(d); err != nil {
+			return repaired, fmt.Errorf("%s %s: %w", d.Entity, d.ID, err)
+		}
+		repaired = append(repaired, d)
+	}
+	return repaired, nil
+}
+
+// repair makes the secondary's copy of one entity match the primary's
+func (s *DualWriteStorage) repair(d Divergence) error {
+	remove := d.Problem == OnlyInSecondary
+	switch kind, isRecord := cutRecordEntity(d.Entity); {
+	case d.Entity == "id_counter":
+		return setCounter(s.secondary, IDKind(d.ID), getCounter(s.primary, IDKind(d.ID)))
+	case isRecord && remove:
+		return s.secondary.DeleteRecord(kind, d.ID)
+	case isRecord:
+		rec, err := s.primary.GetRecord(kind, d.ID)
+		if err != nil {
+			return err
+		}
+		return s.secondary.PutRecord(rec)
+	case d.Entity == "family" && remove:
+		return s.secondary.DeleteFamily(d.ID)
+	case d.Entity == "family":
+		f, err := s.primary.GetFamily(d.ID)
+		if err != nil {
+			return err
+		}
+		if d.Problem == Differs {
+			return s.secondary.UpdateFamily(f)
+		}
+		return s.secondary.CreateFamily(f)
+	case d.Entity == "reminder" && remove:
+		return s.secondary.DeleteReminder(d.ID)
+	case d.Entity == "reminder":
+		r, err := s.primary.GetReminder(d.ID)
+		if err != nil {
+			return err
+		}
+		return s.secondary.CreateReminder(r)
+	case d.Entity == "completion_event" && remove:
+		return s.secondary.DeleteCompletionEvent(d.ID)
+	case d.Entity == "completion_event":
+		e, err := s.primary.GetCompletionEvent(d.ID)
+		if err != nil {
+			return err
+		}
+		return s.secondary.CreateCompletionEvent(e)
+	}
+	return fmt.Errorf("unknown entity %s", d.Entity)
+}
+
+// cutRecordEntity returns the record kind of a Divergence entity such as
+// "record/audit"
+func cutRecordEntity(entity string) (string, bool) {
+	return strings.CutPrefix(entity, "record/")
+}
+
+// differingFields returns the top-level JSON fields in which a and b differ
+func differingFields(a, b any) []string {
+	fa, fb := comparableFields(a), comparableFields(b)
+	var fields []string
+	for name, va := range fa {
+		if vb, ok := fb[name]; !ok || va != vb {
+			fields = append(fields, name)
+		}
+	}
+	for name := range fb {
+		if _, ok := fa[name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// comparableFields returns the JSON of each top-level field of v, with
+// times converted to UTC
+func comparableFields(v any) map[string]string {
+	data, _ := json.Marshal(v)
+	var fields map[string]any
+	json.Unmarshal(data, &fields)
+	out := make(map[string]string, len(fields))
+	for name, value := range fields {
+		if value == nil {
+			// Absent and null are the same
+			continue
+		}
+		data, _ := json.Marshal(utcTimes(value))
+		out[name] = string(data)
+	}
+	return out
+}
+
+// utcTimes rewrites every string in a decoded JSON value that holds a time
+// as that time in UTC
+func utcTimes(v any) any {
+	switch v := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return NormalizeTime(t).UTC().Format(time.RFC3339Nano)
+		}
+	case map[string]any:
+		for k, e := range v {
+			v[k] = utcTimes(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = utcTimes(e)
+		}
+	}
+	return v
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
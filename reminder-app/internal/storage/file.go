@@ -1,13 +1,17 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"reminder-app/internal/family"
 	"reminder-app/internal/reminder"
@@ -17,6 +21,7 @@ type FileStorage struct {
 	familyFile               string
 	reminderFile             string
 	completionEventFile      string
+	recordFile               string
 	familyIDCounter          int
 	reminderIDCounter        int
 	completionEventIDCounter int
@@ -28,6 +33,8 @@ func NewFileStorage(familyFile, reminderFile, completionFile string) *FileStorag
 		familyFile:          familyFile,
 		reminderFile:        reminderFile,
 		completionEventFile: completionFile,
+		// Auxiliary records live next to the family file
+		recordFile: filepath.Join(filepath.Dir(familyFile), "records.json"),
 	}
 
 	// Initialize counters based on existing data
@@ -87,6 +94,25 @@ func (fs *FileStorage) recalculateCounters() {
 	}
 }
 
+// Ping checks that every data file that exists can be opened for reading
+// and writing, and that the directory of those not yet written exists
+func (fs *FileStorage) Ping(ctx context.Context) error {
+	for _, path := range []string{fs.familyFile, fs.reminderFile, fs.completionEventFile, fs.recordFile} {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if errors.Is(err, os.ErrNotExist) {
+			if _, err := os.Stat(filepath.Dir(path)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+	return ctx.Err()
+}
+
 // Unsafe versions (without mutex) for internal use
 func (fs *FileStorage) loadFamiliesUnsafe() (map[string]*family.Family, error) {
 	families := make(map[string]*family.Family)
@@ -218,6 +244,32 @@ func (fs *FileStorage) saveCompletionEvents(events map[string]*reminder.Completi
 	return nil
 }
 
+func (fs *FileStorage) loadRecords() (map[string]*Record, error) {
+	records := make(map[string]*Record)
+	data, err := os.ReadFile(fs.recordFile)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return records, nil
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (fs *FileStorage) saveRecords(records map[string]*Record) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fs.recordFile, data, 0644)
+}
+
 // Family operations
 func (fs *FileStorage) CreateFamily(f *family.Family) error {
 	fs.mu.Lock()
@@ -244,7 +296,7 @@ func (fs *FileStorage) CreateReminder(r *reminder.Reminder) error {
 	if err != nil {
 		return err
 	}
-	reminders[r.ID] = r
+	reminders[r.ID] = normalizedReminder(r)
 
 	// Update counter if this ID is greater than current
 	if numID := extractNumericID(r.ID, "rem"); numID > fs.reminderIDCounter {
@@ -254,6 +306,50 @@ func (fs *FileStorage) CreateReminder(r *reminder.Reminder) error {
 	return fs.saveReminders(reminders)
 }
 
+// SaveReminders saves every reminder in a single write of the file
+func (fs *FileStorage) SaveReminders(rs []*reminder.Reminder) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	reminders, err := fs.loadReminders()
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range rs {
+		reminders[r.ID] = normalizedReminder(r)
+	}
+	if err := fs.saveReminders(reminders); err != nil {
+		return 0, err
+	}
+	return len(rs), nil
+}
+
+// UpdateReminders checks and saves the reminders in a single write of the
+// file
+func (fs *FileStorage) UpdateReminders(rs []*reminder.Reminder) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	reminders, err := fs.loadReminders()
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range rs {
+		stored := 0
+		if old, ok := reminders[r.ID]; ok {
+			stored = old.Version
+		}
+		if err := checkVersion(r, stored); err != nil {
+			return 0, err
+		}
+	}
+	for _, r := range rs {
+		reminders[r.ID] = normalizedReminder(r)
+	}
+	if err := fs.saveReminders(reminders); err != nil {
+		return 0, err
+	}
+	return len(rs), nil
+}
+
 // CompletionEvent operations
 func (fs *FileStorage) CreateCompletionEvent(e *reminder.CompletionEvent) error {
 	fs.mu.Lock()
@@ -262,7 +358,7 @@ func (fs *FileStorage) CreateCompletionEvent(e *reminder.CompletionEvent) error
 	if err != nil {
 		return err
 	}
-	events[e.ID] = e
+	events[e.ID] = normalizedEvent(e)
 
 	// Update counter if this ID is greater than current
 	if numID := extractNumericID(e.ID, "cev"); numID > fs.completionEventIDCounter {
@@ -300,6 +396,20 @@ func (fs *FileStorage) ListFamilies() ([]*family.Family, error) {
 	return list, nil
 }
 
+func (fs *FileStorage) UpdateFamily(f *family.Family) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	families, err := fs.loadFamilies()
+	if err != nil {
+		return err
+	}
+	if _, ok := families[f.ID]; !ok {
+		return errors.New("family not found")
+	}
+	families[f.ID] = f
+	return fs.saveFamilies(families)
+}
+
 func (fs *FileStorage) DeleteFamily(id string) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -339,6 +449,14 @@ func (fs *FileStorage) ListReminders() ([]*reminder.Reminder, error) {
 	return list, nil
 }
 
+func (fs *FileStorage) QueryReminders(f ReminderFilter) ([]*reminder.Reminder, error) {
+	list, err := fs.ListReminders()
+	if err != nil {
+		return nil, err
+	}
+	return FilterReminders(list, f), nil
+}
+
 func (fs *FileStorage) DeleteReminder(id string) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -380,6 +498,53 @@ func (fs *FileStorage) ListCompletionEvents(reminderID string) ([]*reminder.Comp
 	return list, nil
 }
 
+func (fs *FileStorage) ListAllCompletionEvents() ([]*reminder.CompletionEvent, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	events, err := fs.loadCompletionEvents()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*reminder.CompletionEvent, 0, len(events))
+	for _, e := range events {
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+func (fs *FileStorage) QueryCompletionEvents(q CompletionEventQuery) ([]*reminder.CompletionEvent, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	events, err := fs.loadCompletionEvents()
+	if err != nil {
+		return nil, err
+	}
+	var list []*reminder.CompletionEvent
+	for _, e := range events {
+		if q.Matches(e) {
+			list = append(list, e)
+		}
+	}
+	sortCompletionEvents(list)
+	return q.limit(list), nil
+}
+
+func (fs *FileStorage) GetLatestCompletionEvent(reminderID string) (*reminder.CompletionEvent, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	events, err := fs.loadCompletionEvents()
+	if err != nil {
+		return nil, err
+	}
+	var latest *reminder.CompletionEvent
+	for _, e := range events {
+		if e.ReminderID == reminderID && (latest == nil || laterCompletionEvent(e, latest)) {
+			latest = e
+		}
+	}
+	return latest, nil
+}
+
 func (fs *FileStorage) DeleteCompletionEvent(id string) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -391,6 +556,117 @@ func (fs *FileStorage) DeleteCompletionEvent(id string) error {
 	return fs.saveCompletionEvents(events)
 }
 
+// Record operations
+func (fs *FileStorage) PutRecord(rec *Record) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	records, err := fs.loadRecords()
+	if err != nil {
+		return err
+	}
+	records[recordKey(rec.Kind, rec.ID)] = normalizedRecord(rec)
+	return fs.saveRecords(records)
+}
+
+func (fs *FileStorage) CreateRecord(rec *Record, now time.Time) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	records, err := fs.loadRecords()
+	if err != nil {
+		return err
+	}
+	key := recordKey(rec.Kind, rec.ID)
+	if old, ok := records[key]; ok && !old.Expired(now) {
+		return ErrRecordExists
+	}
+	records[key] = normalizedRecord(rec)
+	return fs.saveRecords(records)
+}
+
+func (fs *FileStorage) GetRecord(kind, id string) (*Record, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	records, err := fs.loadRecords()
+	if err != nil {
+		return nil, err
+	}
+	rec, ok := records[recordKey(kind, id)]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return rec, nil
+}
+
+func (fs *FileStorage) ListRecords(q RecordQuery) ([]*Record, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	records, err := fs.loadRecords()
+	if err != nil {
+		return nil, err
+	}
+	var list []*Record
+	for _, rec := range records {
+		if q.Matches(rec) {
+			list = append(list, rec)
+		}
+	}
+	sortRecords(list)
+	return list, nil
+}
+
+func (fs *FileStorage) DeleteRecord(kind, id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	records, err := fs.loadRecords()
+	if err != nil {
+		return err
+	}
+	key := recordKey(kind, id)
+	if _, ok := records[key]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(records, key)
+	return fs.saveRecords(records)
+}
+
+func (fs *FileStorage) PurgeExpiredRecords(now time.Time) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	records, err := fs.loadRecords()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for key, rec := range records {
+		if rec.Expired(now) {
+			delete(records, key)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, fs.saveRecords(records)
+}
+
+func (fs *FileStorage) NextID(kind IDKind) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var counter *int
+	switch kind {
+	case FamilyIDs:
+		counter = &fs.familyIDCounter
+	case ReminderIDs:
+		counter = &fs.reminderIDCounter
+	case CompletionEventIDs:
+		counter = &fs.completionEventIDCounter
+	default:
+		return 0, fmt.Errorf("unknown ID kind: %s", kind)
+	}
+	*counter++
+	return *counter, nil
+}
+
 func (fs *FileStorage) GetCompletionEventIDCounter() int {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// ReminderFilter selects reminders. Zero-valued fields match everything;
+// reminders without a due date never match a due date bound.
+type ReminderFilter struct {
+	FamilyID       string     `json:"family_id,omitempty"`
+	FamilyMember   string     `json:"family_member,omitempty"`
+	Completed      *bool      `json:"completed,omitempty"`
+	DueAfter       *time.Time `json:"due_after,omitempty"`  // inclusive
+	DueBefore      *time.Time `json:"due_before,omitempty"` // exclusive
+	RecurrenceType string     `json:"recurrence_type,omitempty"`
+	ProjectID      string     `json:"project_id,omitempty"`
+	Tag            string     `json:"tag,omitempty"`
+	// SeriesID selects the reminders of a series; see reminder.Series
+	SeriesID string `json:"series_id,omitempty"`
+}
+
+// Matches reports whether r is selected by the filter
+func (f ReminderFilter) Matches(r *reminder.Reminder) bool {
+	if f.FamilyID != "" && r.FamilyID != f.FamilyID {
+		return false
+	}
+	if f.FamilyMember != "" && r.FamilyMember != f.FamilyMember {
+		return false
+	}
+	if f.Completed != nil && r.Completed != *f.Completed {
+		return false
+	}
+	if f.RecurrenceType != "" && r.Recurrence.Type != f.RecurrenceType {
+		return false
+	}
+	if f.ProjectID != "" && r.ProjectID != f.ProjectID {
+		return false
+	}
+	if f.Tag != "" && !r.HasTag(f.Tag) {
+		return false
+	}
+	if f.SeriesID != "" && r.Series() != f.SeriesID {
+		return false
+	}
+	if f.DueAfter != nil || f.DueBefore != nil {
+		// Snoozed reminders are due when their snooze ends
+		due := r.EffectiveDueDate()
+		if due == nil {
+			return false
+		}
+		if f.DueAfter != nil && due.Before(*f.DueAfter) {
+			return false
+		}
+		if f.DueBefore != nil && !due.Before(*f.DueBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterReminders returns the reminders of list selected by f
+func FilterReminders(list []*reminder.Reminder, f ReminderFilter) []*reminder.Reminder {
+	var result []*reminder.Reminder
+	for _, r := range list {
+		if f.Matches(r) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"reminder-app/internal/family"
 	"reminder-app/internal/reminder"
@@ -12,6 +14,7 @@ type MemoryStorage struct {
 	families                 map[string]*family.Family
 	reminders                map[string]*reminder.Reminder
 	completionEvents         map[string]*reminder.CompletionEvent // new
+	records                  map[string]*Record
 	familyIDCounter          int
 	reminderIDCounter        int
 	completionEventIDCounter int
@@ -23,6 +26,7 @@ func NewMemoryStorage() *MemoryStorage {
 		families:         make(map[string]*family.Family),
 		reminders:        make(map[string]*reminder.Reminder),
 		completionEvents: make(map[string]*reminder.CompletionEvent),
+		records:          make(map[string]*Record),
 	}
 }
 
@@ -54,6 +58,16 @@ func (m *MemoryStorage) ListFamilies() ([]*family.Family, error) {
 	return list, nil
 }
 
+func (m *MemoryStorage) UpdateFamily(f *family.Family) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.families[f.ID]; !ok {
+		return errors.New("family not found")
+	}
+	m.families[f.ID] = f
+	return nil
+}
+
 func (m *MemoryStorage) DeleteFamily(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -69,6 +83,35 @@ func (m *MemoryStorage) CreateReminder(r *reminder.Reminder) error {
 	return nil
 }
 
+// SaveReminders saves every reminder under one lock
+func (m *MemoryStorage) SaveReminders(rs []*reminder.Reminder) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range rs {
+		m.reminders[r.ID] = r
+	}
+	return len(rs), nil
+}
+
+// UpdateReminders checks and saves the reminders under one lock
+func (m *MemoryStorage) UpdateReminders(rs []*reminder.Reminder) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range rs {
+		stored := 0
+		if old, ok := m.reminders[r.ID]; ok {
+			stored = old.Version
+		}
+		if err := checkVersion(r, stored); err != nil {
+			return 0, err
+		}
+	}
+	for _, r := range rs {
+		m.reminders[r.ID] = r
+	}
+	return len(rs), nil
+}
+
 func (m *MemoryStorage) GetReminder(id string) (*reminder.Reminder, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -89,6 +132,14 @@ func (m *MemoryStorage) ListReminders() ([]*reminder.Reminder, error) {
 	return list, nil
 }
 
+func (m *MemoryStorage) QueryReminders(f ReminderFilter) ([]*reminder.Reminder, error) {
+	list, err := m.ListReminders()
+	if err != nil {
+		return nil, err
+	}
+	return FilterReminders(list, f), nil
+}
+
 func (m *MemoryStorage) DeleteReminder(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -126,12 +177,132 @@ func (m *MemoryStorage) ListCompletionEvents(reminderID string) ([]*reminder.Com
 	return list, nil
 }
 
+func (m *MemoryStorage) ListAllCompletionEvents() ([]*reminder.CompletionEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]*reminder.CompletionEvent, 0, len(m.completionEvents))
+	for _, e := range m.completionEvents {
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+func (m *MemoryStorage) QueryCompletionEvents(q CompletionEventQuery) ([]*reminder.CompletionEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*reminder.CompletionEvent
+	for _, e := range m.completionEvents {
+		if q.Matches(e) {
+			list = append(list, e)
+		}
+	}
+	sortCompletionEvents(list)
+	return q.limit(list), nil
+}
+
+func (m *MemoryStorage) GetLatestCompletionEvent(reminderID string) (*reminder.CompletionEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest *reminder.CompletionEvent
+	for _, e := range m.completionEvents {
+		if e.ReminderID == reminderID && (latest == nil || laterCompletionEvent(e, latest)) {
+			latest = e
+		}
+	}
+	return latest, nil
+}
+
 func (m *MemoryStorage) DeleteCompletionEvent(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.completionEvents, id)
 	return nil
 }
+
+// Record operations
+func (m *MemoryStorage) PutRecord(rec *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[recordKey(rec.Kind, rec.ID)] = rec
+	return nil
+}
+
+func (m *MemoryStorage) CreateRecord(rec *Record, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := recordKey(rec.Kind, rec.ID)
+	if old, ok := m.records[key]; ok && !old.Expired(now) {
+		return ErrRecordExists
+	}
+	m.records[key] = rec
+	return nil
+}
+
+func (m *MemoryStorage) GetRecord(kind, id string) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[recordKey(kind, id)]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return rec, nil
+}
+
+func (m *MemoryStorage) ListRecords(q RecordQuery) ([]*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*Record
+	for _, rec := range m.records {
+		if q.Matches(rec) {
+			list = append(list, rec)
+		}
+	}
+	sortRecords(list)
+	return list, nil
+}
+
+func (m *MemoryStorage) DeleteRecord(kind, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := recordKey(kind, id)
+	if _, ok := m.records[key]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.records, key)
+	return nil
+}
+
+func (m *MemoryStorage) PurgeExpiredRecords(now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for key, rec := range m.records {
+		if rec.Expired(now) {
+			delete(m.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryStorage) NextID(kind IDKind) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var counter *int
+	switch kind {
+	case FamilyIDs:
+		counter = &m.familyIDCounter
+	case ReminderIDs:
+		counter = &m.reminderIDCounter
+	case CompletionEventIDs:
+		counter = &m.completionEventIDCounter
+	default:
+		return 0, fmt.Errorf("unknown ID kind: %s", kind)
+	}
+	*counter++
+	return *counter, nil
+}
+
 func (fs *MemoryStorage) GetCompletionEventIDCounter() int {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
@@ -12,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	"reminder-app/internal/family"
 	"reminder-app/internal/reminder"
@@ -25,7 +26,10 @@ type MongoStorage struct {
 	reminderCollection        *mongo.Collection
 	completionEventCollection *mongo.Collection
 	counterCollection         *mongo.Collection
+	recordCollection          *mongo.Collection
 	mu                        sync.Mutex
+	// bound is the context operations run under; see WithContext
+	bound context.Context
 }
 
 // Counter document structure for ID generation
@@ -36,18 +40,20 @@ type Counter struct {
 
 // NewMongoStorage creates a new MongoDB storage instance
 func NewMongoStorage(connectionString, databaseName string) (*MongoStorage, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
-	}
+	return NewMongoStorageWithRetry(connectionString, databaseName, ConnectRetry{})
+}
 
-	// Test the connection
-	err = client.Ping(ctx, nil)
+// NewMongoStorageWithRetry is NewMongoStorage trying to reach the server
+// as often as retry allows, with backoff in between
+func NewMongoStorageWithRetry(connectionString, databaseName string, retry ConnectRetry) (*MongoStorage, error) {
+	var client *mongo.Client
+	err := retry.do("MongoDB", func() error {
+		var err error
+		client, err = connectMongo(connectionString)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+		return nil, err
 	}
 
 	database := client.Database(databaseName)
@@ -59,6 +65,7 @@ func NewMongoStorage(connectionString, databaseName string) (*MongoStorage, erro
 		reminderCollection:        database.Collection("reminders"),
 		completionEventCollection: database.Collection("completion_events"),
 		counterCollection:         database.Collection("counters"),
+		recordCollection:          database.Collection("records"),
 	}
 
 	// Initialize counters if they don't exist
@@ -67,9 +74,86 @@ func NewMongoStorage(connectionString, databaseName string) (*MongoStorage, erro
 		return nil, fmt.Errorf("failed to initialize counters: %w", err)
 	}
 
+	if err := ms.createIndexes(); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+
 	return ms, nil
 }
 
+// connectMongo connects to the server and checks that it answers
+func connectMongo(connectionString string) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	// Test the connection
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+	return client, nil
+}
+
+// WithContext returns a view of the storage whose operations are cancelled
+// along with ctx
+func (ms *MongoStorage) WithContext(ctx context.Context) Storage {
+	return &MongoStorage{
+		client:                    ms.client,
+		database:                  ms.database,
+		familyCollection:          ms.familyCollection,
+		reminderCollection:        ms.reminderCollection,
+		completionEventCollection: ms.completionEventCollection,
+		counterCollection:         ms.counterCollection,
+		recordCollection:          ms.recordCollection,
+		bound:                     ctx,
+	}
+}
+
+func (ms *MongoStorage) ctx() context.Context {
+	if ms.bound == nil {
+		return context.Background()
+	}
+	return ms.bound
+}
+
+// Ping checks that the server answers
+func (ms *MongoStorage) Ping(ctx context.Context) error {
+	return ms.client.Ping(ctx, nil)
+}
+
+// minMongoStaleness is the smallest maxStaleness MongoDB accepts
+const minMongoStaleness = 90 * time.Second
+
+// SecondaryReads returns a view of the storage that reads from secondary
+// members of the replica set at most maxStaleness behind the primary,
+// falling back to the primary when none qualifies. Writes through the
+// view still go to the primary.
+func (ms *MongoStorage) SecondaryReads(maxStaleness time.Duration) (*MongoStorage, error) {
+	if maxStaleness < minMongoStaleness {
+		return nil, fmt.Errorf("MongoDB requires a staleness tolerance of at least %s", minMongoStaleness)
+	}
+	rp, err := readpref.New(readpref.SecondaryPreferredMode, readpref.WithMaxStaleness(maxStaleness))
+	if err != nil {
+		return nil, err
+	}
+	database := ms.client.Database(ms.database.Name(), options.Database().SetReadPreference(rp))
+	return &MongoStorage{
+		client:                    ms.client,
+		database:                  database,
+		familyCollection:          database.Collection("families"),
+		reminderCollection:        database.Collection("reminders"),
+		completionEventCollection: database.Collection("completion_events"),
+		counterCollection:         database.Collection("counters"),
+		recordCollection:          database.Collection("records"),
+		bound:                     ms.bound,
+	}, nil
+}
+
 // Close closes the MongoDB connection
 func (ms *MongoStorage) Close(ctx context.Context) error {
 	return ms.client.Disconnect(ctx)
@@ -77,7 +161,7 @@ func (ms *MongoStorage) Close(ctx context.Context) error {
 
 // initializeCounters initializes the counter documents if they don't exist
 func (ms *MongoStorage) initializeCounters() error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	counterTypes := []string{"family", "reminder", "completion_event"}
 
@@ -100,9 +184,81 @@ func (ms *MongoStorage) initializeCounters() error {
 	return nil
 }
 
+// createIndexes creates the indexes serving QueryReminders and the TTL index
+// that lets MongoDB remove expired records on its own. The TTL monitor runs
+// about once a minute, so readers must still check expiry themselves.
+func (ms *MongoStorage) createIndexes() error {
+	ctx := ms.ctx()
+
+	_, err := ms.reminderCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "familyid", Value: 1}, {Key: "duedate", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create reminders index: %w", err)
+	}
+	// A multikey index, with one entry per tag
+	_, err = ms.reminderCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "familyid", Value: 1}, {Key: "tags", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create reminder tags index: %w", err)
+	}
+	_, err = ms.reminderCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "seriesid", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create reminder series index: %w", err)
+	}
+	_, err = ms.recordCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresat", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create records TTL index: %w", err)
+	}
+	_, err = ms.recordCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create records index: %w", err)
+	}
+	return nil
+}
+
+// VerifySchema checks that the counters and the records TTL index exist.
+// MongoDB has no fixed schema, so these are the only shapes the code relies
+// on.
+func (ms *MongoStorage) VerifySchema() error {
+	ctx := ms.ctx()
+
+	for _, counterType := range []string{"family", "reminder", "completion_event"} {
+		if _, err := ms.getCounter(counterType); err != nil {
+			return fmt.Errorf("%s counter: %w", counterType, err)
+		}
+	}
+	cursor, err := ms.recordCollection.Indexes().List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list records indexes: %w", err)
+	}
+	var indexes []struct {
+		Key                bson.D `bson:"key"`
+		ExpireAfterSeconds any    `bson:"expireAfterSeconds"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return fmt.Errorf("failed to list records indexes: %w", err)
+	}
+	for _, idx := range indexes {
+		if len(idx.Key) == 1 && idx.Key[0].Key == "expiresat" && idx.ExpireAfterSeconds != nil {
+			return nil
+		}
+	}
+	return errors.New("records collection is missing its TTL index on expiresat")
+}
+
 // getNextCounter atomically increments and returns the next counter value
 func (ms *MongoStorage) getNextCounter(counterType string) (int, error) {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"_id": counterType}
 	update := bson.M{"$inc": bson.M{"value": 1}}
@@ -119,7 +275,7 @@ func (ms *MongoStorage) getNextCounter(counterType string) (int, error) {
 
 // setCounter sets the counter value
 func (ms *MongoStorage) setCounter(counterType string, value int) error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"_id": counterType}
 	update := bson.M{"$set": bson.M{"value": value}}
@@ -135,7 +291,7 @@ func (ms *MongoStorage) setCounter(counterType string, value int) error {
 
 // getCounter gets the current counter value
 func (ms *MongoStorage) getCounter(counterType string) (int, error) {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"_id": counterType}
 
@@ -154,7 +310,7 @@ func (ms *MongoStorage) getCounter(counterType string) (int, error) {
 // Family operations
 
 func (ms *MongoStorage) CreateFamily(f *family.Family) error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	_, err := ms.familyCollection.InsertOne(ctx, f)
 	if err != nil {
@@ -165,7 +321,7 @@ func (ms *MongoStorage) CreateFamily(f *family.Family) error {
 }
 
 func (ms *MongoStorage) GetFamily(id string) (*family.Family, error) {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"id": id}
 
@@ -182,7 +338,7 @@ func (ms *MongoStorage) GetFamily(id string) (*family.Family, error) {
 }
 
 func (ms *MongoStorage) ListFamilies() ([]*family.Family, error) {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	cursor, err := ms.familyCollection.Find(ctx, bson.M{})
 	if err != nil {
@@ -206,8 +362,25 @@ func (ms *MongoStorage) ListFamilies() ([]*family.Family, error) {
 	return families, nil
 }
 
+func (ms *MongoStorage) UpdateFamily(f *family.Family) error {
+	ctx := ms.ctx()
+
+	filter := bson.M{"id": f.ID}
+
+	result, err := ms.familyCollection.ReplaceOne(ctx, filter, f)
+	if err != nil {
+		return fmt.Errorf("failed to update family: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.New("family not found")
+	}
+
+	return nil
+}
+
 func (ms *MongoStorage) DeleteFamily(id string) error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"id": id}
 
@@ -226,13 +399,13 @@ func (ms *MongoStorage) DeleteFamily(id string) error {
 // Reminder operations
 
 func (ms *MongoStorage) CreateReminder(r *reminder.Reminder) error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	// Use upsert to replace existing reminder with same ID or create new one
 	filter := bson.M{"id": r.ID}
 	opts := options.Replace().SetUpsert(true)
 
-	_, err := ms.reminderCollection.ReplaceOne(ctx, filter, r, opts)
+	_, err := ms.reminderCollection.ReplaceOne(ctx, filter, normalizedReminder(r), opts)
 	if err != nil {
 		return fmt.Errorf("failed to create/update reminder: %w", err)
 	}
@@ -240,8 +413,63 @@ func (ms *MongoStorage) CreateReminder(r *reminder.Reminder) error {
 	return nil
 }
 
+// SaveReminders upserts the reminders in one ordered bulk write. Without a
+// transaction, which needs a replica set, the reminders before a failing
+// one stay saved.
+func (ms *MongoStorage) SaveReminders(rs []*reminder.Reminder) (int, error) {
+	if len(rs) == 0 {
+		return 0, nil
+	}
+	models := make([]mongo.WriteModel, len(rs))
+	for i, r := range rs {
+		models[i] = mongo.NewReplaceOneModel().SetFilter(bson.M{"id": r.ID}).SetReplacement(normalizedReminder(r)).SetUpsert(true)
+	}
+	res, err := ms.reminderCollection.BulkWrite(ms.ctx(), models, options.BulkWrite().SetOrdered(true))
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+			// The index of the first failure is how many went before it
+			return bulkErr.WriteErrors[0].Index, fmt.Errorf("failed to save reminders: %w", err)
+		}
+		return 0, fmt.Errorf("failed to save reminders: %w", err)
+	}
+	return int(res.MatchedCount + res.UpsertedCount), nil
+}
+
+// UpdateReminders replaces each reminder only if it is still at the
+// version before its own, and inserts one at version 1 only if it doesn't
+// exist yet. Like SaveReminders, the reminders before a conflicting one stay
+// saved.
+func (ms *MongoStorage) UpdateReminders(rs []*reminder.Reminder) (int, error) {
+	ctx := ms.ctx()
+	for i, r := range rs {
+		res, err := ms.reminderCollection.ReplaceOne(ctx, bson.M{"id": r.ID, "version": r.Version - 1}, normalizedReminder(r))
+		if err != nil {
+			return i, fmt.Errorf("failed to update reminder: %w", err)
+		}
+		if res.MatchedCount > 0 {
+			continue
+		}
+		if r.Version == 1 {
+			res, err := ms.reminderCollection.UpdateOne(ctx, bson.M{"id": r.ID}, bson.M{"$setOnInsert": normalizedReminder(r)}, options.Update().SetUpsert(true))
+			if err != nil {
+				return i, fmt.Errorf("failed to create reminder: %w", err)
+			}
+			if res.UpsertedCount > 0 {
+				continue
+			}
+		}
+		var stored reminder.Reminder
+		if err := ms.reminderCollection.FindOne(ctx, bson.M{"id": r.ID}).Decode(&stored); err != nil && err != mongo.ErrNoDocuments {
+			return i, fmt.Errorf("failed to read reminder version: %w", err)
+		}
+		return i, fmt.Errorf("%w: reminder %s is at version %d", ErrVersionConflict, r.ID, stored.Version)
+	}
+	return len(rs), nil
+}
+
 func (ms *MongoStorage) GetReminder(id string) (*reminder.Reminder, error) {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"id": id}
 
@@ -258,9 +486,56 @@ func (ms *MongoStorage) GetReminder(id string) (*reminder.Reminder, error) {
 }
 
 func (ms *MongoStorage) ListReminders() ([]*reminder.Reminder, error) {
-	ctx := context.Background()
+	return ms.findReminders(bson.M{})
+}
 
-	cursor, err := ms.reminderCollection.Find(ctx, bson.M{})
+func (ms *MongoStorage) QueryReminders(f ReminderFilter) ([]*reminder.Reminder, error) {
+	filter := bson.M{}
+	for field, value := range map[string]string{
+		"familyid":        f.FamilyID,
+		"familymember":    f.FamilyMember,
+		"recurrence.type": f.RecurrenceType,
+		"projectid":       f.ProjectID,
+		// Matches any element of the tags array
+		"tags": reminder.NormalizeTag(f.Tag),
+	} {
+		if value != "" {
+			filter[field] = value
+		}
+	}
+	if f.Completed != nil {
+		filter["completed"] = *f.Completed
+	}
+	// A series is named after its first reminder, which only gets a
+	// series ID once it is split
+	var bounds bson.A
+	if f.SeriesID != "" {
+		bounds = append(bounds, bson.M{"$or": bson.A{bson.M{"seriesid": f.SeriesID}, bson.M{"id": f.SeriesID}}})
+	}
+	// A snoozed reminder is due at the later of its due date and the end
+	// of its snooze
+	if f.DueAfter != nil {
+		bounds = append(bounds, bson.M{"$or": bson.A{
+			bson.M{"duedate": bson.M{"$gte": *f.DueAfter}},
+			bson.M{"snoozeduntil": bson.M{"$gte": *f.DueAfter}},
+		}})
+	}
+	if f.DueBefore != nil {
+		bounds = append(bounds,
+			bson.M{"duedate": bson.M{"$lt": *f.DueBefore}},
+			bson.M{"snoozeduntil": bson.M{"$not": bson.M{"$gte": *f.DueBefore}}})
+	}
+	if len(bounds) > 0 {
+		filter["$and"] = bounds
+	}
+	return ms.findReminders(filter)
+}
+
+// findReminders returns the reminders matching a MongoDB filter
+func (ms *MongoStorage) findReminders(filter bson.M) ([]*reminder.Reminder, error) {
+	ctx := ms.ctx()
+
+	cursor, err := ms.reminderCollection.Find(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list reminders: %w", err)
 	}
@@ -283,7 +558,7 @@ func (ms *MongoStorage) ListReminders() ([]*reminder.Reminder, error) {
 }
 
 func (ms *MongoStorage) DeleteReminder(id string) error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"id": id}
 
@@ -302,13 +577,13 @@ func (ms *MongoStorage) DeleteReminder(id string) error {
 // CompletionEvent operations
 
 func (ms *MongoStorage) CreateCompletionEvent(e *reminder.CompletionEvent) error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	// Use upsert to replace existing completion event with same ID or create new one
 	filter := bson.M{"id": e.ID}
 	opts := options.Replace().SetUpsert(true)
 
-	_, err := ms.completionEventCollection.ReplaceOne(ctx, filter, e, opts)
+	_, err := ms.completionEventCollection.ReplaceOne(ctx, filter, normalizedEvent(e), opts)
 	if err != nil {
 		return fmt.Errorf("failed to create/update completion event: %w", err)
 	}
@@ -317,7 +592,7 @@ func (ms *MongoStorage) CreateCompletionEvent(e *reminder.CompletionEvent) error
 }
 
 func (ms *MongoStorage) GetCompletionEvent(id string) (*reminder.CompletionEvent, error) {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"id": id}
 
@@ -334,9 +609,17 @@ func (ms *MongoStorage) GetCompletionEvent(id string) (*reminder.CompletionEvent
 }
 
 func (ms *MongoStorage) ListCompletionEvents(reminderID string) ([]*reminder.CompletionEvent, error) {
-	ctx := context.Background()
+	return ms.findCompletionEvents(bson.M{"reminderid": reminderID})
+}
 
-	filter := bson.M{"reminderid": reminderID}
+func (ms *MongoStorage) ListAllCompletionEvents() ([]*reminder.CompletionEvent, error) {
+	return ms.findCompletionEvents(bson.M{})
+}
+
+// findCompletionEvents returns the completion events matching a MongoDB
+// filter
+func (ms *MongoStorage) findCompletionEvents(filter bson.M) ([]*reminder.CompletionEvent, error) {
+	ctx := ms.ctx()
 
 	cursor, err := ms.completionEventCollection.Find(ctx, filter)
 	if err != nil {
@@ -360,8 +643,107 @@ func (ms *MongoStorage) ListCompletionEvents(reminderID string) ([]*reminder.Com
 	return events, nil
 }
 
+func (ms *MongoStorage) QueryCompletionEvents(q CompletionEventQuery) ([]*reminder.CompletionEvent, error) {
+	ctx := ms.ctx()
+
+	filter := bson.M{"reminderid": q.ReminderID}
+	completedAt := bson.M{}
+	if !q.From.IsZero() {
+		completedAt["$gte"] = q.From
+	}
+	if !q.To.IsZero() {
+		completedAt["$lt"] = q.To
+	}
+	if len(completedAt) > 0 {
+		filter["completedat"] = completedAt
+	}
+	if q.AfterID != "" {
+		filter["$or"] = bson.A{
+			bson.M{"completedat": bson.M{"$gt": q.AfterTime}},
+			bson.M{"completedat": q.AfterTime, "id": bson.M{"$gt": q.AfterID}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "completedat", Value: 1}, {Key: "id", Value: 1}})
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+
+	cursor, err := ms.completionEventCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completion events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []*reminder.CompletionEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode completion events: %w", err)
+	}
+	return events, nil
+}
+
+// TallyCompletions groups the family's completions in an aggregation
+// pipeline, joining each event to its reminder for the family
+func (ms *MongoStorage) TallyCompletions(familyID string, from, to time.Time) ([]*CompletionTally, error) {
+	ctx := ms.ctx()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"completedat": bson.M{"$gte": from, "$lt": to},
+			"state":       bson.M{"$ne": reminder.StateAwaitingConfirmation},
+		}}},
+		{{Key: "$lookup", Value: bson.M{"from": ms.reminderCollection.Name(), "localField": "reminderid", "foreignField": "id", "as": "reminder"}}},
+		{{Key: "$match", Value: bson.M{"reminder.familyid": familyID}}},
+		{{Key: "$sort", Value: bson.M{"completedat": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"reminderid": "$reminderid", "completedby": "$completedby"},
+			"times":  bson.M{"$push": "$completedat"},
+			"points": bson.M{"$sum": "$points"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.reminderid", Value: 1}, {Key: "_id.completedby", Value: 1}}}},
+	}
+	cursor, err := ms.completionEventCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tally completions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		ID struct {
+			ReminderID  string `bson:"reminderid"`
+			CompletedBy string `bson:"completedby"`
+		} `bson:"_id"`
+		Times  []time.Time `bson:"times"`
+		Points int         `bson:"points"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode completion tallies: %w", err)
+	}
+	tallies := make([]*CompletionTally, 0, len(groups))
+	for _, g := range groups {
+		tallies = append(tallies, &CompletionTally{ReminderID: g.ID.ReminderID, CompletedBy: g.ID.CompletedBy, Times: g.Times, Points: g.Points})
+	}
+	return tallies, nil
+}
+
+func (ms *MongoStorage) GetLatestCompletionEvent(reminderID string) (*reminder.CompletionEvent, error) {
+	ctx := ms.ctx()
+
+	filter := bson.M{"reminderid": reminderID}
+	opts := options.FindOne().SetSort(bson.D{{Key: "completedat", Value: -1}, {Key: "id", Value: -1}})
+
+	var e reminder.CompletionEvent
+	err := ms.completionEventCollection.FindOne(ctx, filter, opts).Decode(&e)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest completion event: %w", err)
+	}
+	return &e, nil
+}
+
 func (ms *MongoStorage) DeleteCompletionEvent(id string) error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	filter := bson.M{"id": id}
 
@@ -403,50 +785,120 @@ func (ms *MongoStorage) SetReminderIDCounter(counter int) error {
 	return ms.setCounter("reminder", counter)
 }
 
-func (ms *MongoStorage) GetCompletionEventIDCounter() int {
-	counter, err := ms.getCounter("completion_event")
+// Record operations
+func (ms *MongoStorage) PutRecord(rec *Record) error {
+	ctx := ms.ctx()
+
+	filter := bson.M{"kind": rec.Kind, "id": rec.ID}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := ms.recordCollection.ReplaceOne(ctx, filter, normalizedRecord(rec), opts); err != nil {
+		return fmt.Errorf("failed to put record: %w", err)
+	}
+	return nil
+}
+
+// CreateRecord inserts rec, replacing an expired record with its kind and
+// ID. A live record makes the insert violate the unique index on kind and
+// ID.
+func (ms *MongoStorage) CreateRecord(rec *Record, now time.Time) error {
+	ctx := ms.ctx()
+
+	filter := bson.M{"kind": rec.Kind, "id": rec.ID, "expiresat": bson.M{"$lte": now}}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := ms.recordCollection.ReplaceOne(ctx, filter, normalizedRecord(rec), opts); mongo.IsDuplicateKeyError(err) {
+		return ErrRecordExists
+	} else if err != nil {
+		return fmt.Errorf("failed to create record: %w", err)
+	}
+	return nil
+}
+
+func (ms *MongoStorage) GetRecord(kind, id string) (*Record, error) {
+	ctx := ms.ctx()
+
+	var rec Record
+	err := ms.recordCollection.FindOne(ctx, bson.M{"kind": kind, "id": id}).Decode(&rec)
 	if err != nil {
-		return 0
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to get record: %w", err)
 	}
-	return counter
+	return &rec, nil
 }
 
-func (ms *MongoStorage) SetCompletionEventIDCounter(counter int) error {
-	return ms.setCounter("completion_event", counter)
+func (ms *MongoStorage) ListRecords(q RecordQuery) ([]*Record, error) {
+	ctx := ms.ctx()
+
+	filter := bson.M{"kind": q.Kind}
+	if q.FamilyID != "" {
+		filter["familyid"] = q.FamilyID
+	}
+	if q.Ref != "" {
+		filter["ref"] = q.Ref
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}, {Key: "id", Value: 1}})
+	cursor, err := ms.recordCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []*Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode records: %w", err)
+	}
+	return records, nil
 }
 
-// Helper functions for MongoDB integration
+func (ms *MongoStorage) DeleteRecord(kind, id string) error {
+	ctx := ms.ctx()
 
-// GenerateMongoFamilyID generates a new family ID using MongoDB counter
-func GenerateMongoFamilyID(ms *MongoStorage) (string, error) {
-	counter, err := ms.getNextCounter("family")
+	result, err := ms.recordCollection.DeleteOne(ctx, bson.M{"kind": kind, "id": id})
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to delete record: %w", err)
 	}
-	return fmt.Sprintf("fam%d", counter), nil
+	if result.DeletedCount == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
 }
 
-// GenerateMongoReminderID generates a new reminder ID using MongoDB counter
-func GenerateMongoReminderID(ms *MongoStorage) (string, error) {
-	counter, err := ms.getNextCounter("reminder")
+// PurgeExpiredRecords removes expired records without waiting for the TTL
+// monitor
+func (ms *MongoStorage) PurgeExpiredRecords(now time.Time) (int, error) {
+	ctx := ms.ctx()
+
+	result, err := ms.recordCollection.DeleteMany(ctx, bson.M{"expiresat": bson.M{"$lte": now}})
 	if err != nil {
-		return "", err
+		return 0, fmt.Errorf("failed to purge expired records: %w", err)
 	}
-	return fmt.Sprintf("rem%d", counter), nil
+	return int(result.DeletedCount), nil
 }
 
-// GenerateMongoCompletionEventID generates a new completion event ID using MongoDB counter
-func GenerateMongoCompletionEventID(ms *MongoStorage) (string, error) {
-	counter, err := ms.getNextCounter("completion_event")
+func (ms *MongoStorage) GetCompletionEventIDCounter() int {
+	counter, err := ms.getCounter("completion_event")
 	if err != nil {
-		return "", err
+		return 0
+	}
+	return counter
+}
+
+func (ms *MongoStorage) SetCompletionEventIDCounter(counter int) error {
+	return ms.setCounter("completion_event", counter)
+}
+
+// NextID uses getNextCounter, which increments with a single $inc
+func (ms *MongoStorage) NextID(kind IDKind) (int, error) {
+	if _, ok := idPrefixes[kind]; !ok {
+		return 0, fmt.Errorf("unknown ID kind: %s", kind)
 	}
-	return fmt.Sprintf("cev%d", counter), nil
+	return ms.getNextCounter(string(kind))
 }
 
 // RecalculateCountersFromData recalculates counters based on existing data in MongoDB
 func (ms *MongoStorage) RecalculateCountersFromData() error {
-	ctx := context.Background()
+	ctx := ms.ctx()
 
 	// Recalculate family counter
 	familyCount, err := ms.getMaxIDFromCollection(ctx, ms.familyCollection, "id", "fam")
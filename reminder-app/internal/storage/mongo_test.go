@@ -64,6 +64,8 @@ func TestMongoStorage(t *testing.T) {
 
 	// Run the common storage tests
 	runStorageTests(t, mongoStorage)
+	runTimeRoundTripTests(t, mongoStorage)
+	runDSTTests(t, mongoStorage)
 }
 
 func TestMongoStorageIDGeneration(t *testing.T) {
@@ -74,55 +76,55 @@ func TestMongoStorageIDGeneration(t *testing.T) {
 	mongoStorage, cleanup := setupMongoTestContainer(t)
 	defer cleanup()
 
-	// Test MongoDB-specific ID generation functions
-	t.Run("GenerateMongoFamilyID", func(t *testing.T) {
-		id1, err := GenerateMongoFamilyID(mongoStorage)
+	// Test ID generation through the atomic MongoDB counters
+	t.Run("GenerateFamilyID", func(t *testing.T) {
+		id1, err := GenerateFamilyID(mongoStorage)
 		if err != nil {
-			t.Fatalf("GenerateMongoFamilyID failed: %v", err)
+			t.Fatalf("GenerateFamilyID failed: %v", err)
 		}
 		if id1 != "fam1" {
 			t.Errorf("Expected first family ID to be 'fam1', got '%s'", id1)
 		}
 
-		id2, err := GenerateMongoFamilyID(mongoStorage)
+		id2, err := GenerateFamilyID(mongoStorage)
 		if err != nil {
-			t.Fatalf("GenerateMongoFamilyID failed: %v", err)
+			t.Fatalf("GenerateFamilyID failed: %v", err)
 		}
 		if id2 != "fam2" {
 			t.Errorf("Expected second family ID to be 'fam2', got '%s'", id2)
 		}
 	})
 
-	t.Run("GenerateMongoReminderID", func(t *testing.T) {
-		id1, err := GenerateMongoReminderID(mongoStorage)
+	t.Run("GenerateReminderID", func(t *testing.T) {
+		id1, err := GenerateReminderID(mongoStorage)
 		if err != nil {
-			t.Fatalf("GenerateMongoReminderID failed: %v", err)
+			t.Fatalf("GenerateReminderID failed: %v", err)
 		}
 		if id1 != "rem1" {
 			t.Errorf("Expected first reminder ID to be 'rem1', got '%s'", id1)
 		}
 
-		id2, err := GenerateMongoReminderID(mongoStorage)
+		id2, err := GenerateReminderID(mongoStorage)
 		if err != nil {
-			t.Fatalf("GenerateMongoReminderID failed: %v", err)
+			t.Fatalf("GenerateReminderID failed: %v", err)
 		}
 		if id2 != "rem2" {
 			t.Errorf("Expected second reminder ID to be 'rem2', got '%s'", id2)
 		}
 	})
 
-	t.Run("GenerateMongoCompletionEventID", func(t *testing.T) {
-		id1, err := GenerateMongoCompletionEventID(mongoStorage)
+	t.Run("GenerateCompletionEventID", func(t *testing.T) {
+		id1, err := GenerateCompletionEventID(mongoStorage)
 		if err != nil {
-			t.Fatalf("GenerateMongoCompletionEventID failed: %v", err)
+			t.Fatalf("GenerateCompletionEventID failed: %v", err)
 		}
 		if id1 != "cev1" {
 			t.Errorf("Expected first completion event ID to be 'cev1', got '%s'", id1)
 		}
 
-		id2, err := GenerateMongoCompletionEventID(mongoStorage)
+		id2, err := GenerateCompletionEventID(mongoStorage)
 		if err != nil {
-			t.Fatalf("GenerateMongoCompletionEventID failed: %v", err)
+			t.Fatalf("GenerateCompletionEventID failed: %v", err)
 		}
 		if id2 != "cev2" {
 			t.Errorf("Expected second completion event ID to be 'cev2', got '%s'", id2)
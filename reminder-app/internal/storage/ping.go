@@ -0,0 +1,18 @@
+package storage
+
+import "context"
+
+// Pinger is implemented by backends that can check the database is
+// reachable without reading everything, for readiness probes
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that s can serve requests, giving up when ctx is done.
+// Backends without a database to reach, such as memory, always succeed.
+func Ping(ctx context.Context, s Storage) error {
+	if p, ok := s.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrRecordNotFound is returned by GetRecord and DeleteRecord for unknown
+// records
+var ErrRecordNotFound = errors.New("record not found")
+
+// ErrRecordExists is returned by CreateRecord when a record with the same
+// kind and ID is already stored
+var ErrRecordExists = errors.New("record already exists")
+
+// Record is a schemaless document for auxiliary data, such as audit entries,
+// that doesn't warrant its own table in every backend. Records are unique
+// by Kind and ID.
+type Record struct {
+	Kind     string `json:"kind" bson:"kind"`
+	ID       string `json:"id" bson:"id"`
+	FamilyID string `json:"family_id,omitempty" bson:"familyid,omitempty"`
+	// Ref is the entity the record is about, e.g. the reminder an audit
+	// entry describes
+	Ref       string          `json:"ref,omitempty" bson:"ref,omitempty"`
+	Data      json.RawMessage `json:"data" bson:"data"`
+	CreatedAt time.Time       `json:"created_at" bson:"createdat"`
+	// ExpiresAt marks ephemeral records, such as share links, for removal
+	// by PurgeExpiredRecords. Nil records are kept forever.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expiresat,omitempty"`
+}
+
+// Expired reports whether the record is due for removal at now
+func (rec *Record) Expired(now time.Time) bool {
+	return rec.ExpiresAt != nil && !now.Before(*rec.ExpiresAt)
+}
+
+// RecordQuery selects records of one kind. Empty FamilyID or Ref match
+// everything.
+type RecordQuery struct {
+	Kind     string
+	FamilyID string
+	Ref      string
+}
+
+// Matches reports whether rec is selected by the query
+func (q RecordQuery) Matches(rec *Record) bool {
+	return rec.Kind == q.Kind &&
+		(q.FamilyID == "" || rec.FamilyID == q.FamilyID) &&
+		(q.Ref == "" || rec.Ref == q.Ref)
+}
+
+// sortRecords orders records oldest first, breaking ties by ID
+func sortRecords(records []*Record) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].ID < records[j].ID
+		}
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+}
+
+// NewRecordID returns a random record ID with the given prefix. Records
+// don't use the sequential counters of families and reminders.
+func NewRecordID(prefix string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// recordKey identifies a record in the map-based backends
+func recordKey(kind, id string) string {
+	return kind + "/" + id
+}
+
+// PutJSON stores v as the data of a record
+func PutJSON(s Storage, rec Record, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	rec.Data = data
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	return s.PutRecord(&rec)
+}
+
+// RecordCreator is implemented by backends that can store a record only if
+// no other record has its kind and ID, checking and writing in one operation
+// so that of several processes creating the same record only one succeeds
+type RecordCreator interface {
+	// CreateRecord stores rec unless a record with its kind and ID that
+	// hasn't expired at now is stored, failing with ErrRecordExists. An
+	// expired record is replaced.
+	CreateRecord(rec *Record, now time.Time) error
+}
+
+// CreateRecord stores rec unless a record with its kind and ID that hasn't
+// expired at now is stored, failing with ErrRecordExists. Backends that
+// can't check as they write are checked first, which is only safe while a
+// single process writes to them.
+func CreateRecord(s Storage, rec *Record, now time.Time) error {
+	if c, ok := s.(RecordCreator); ok {
+		return c.CreateRecord(rec, now)
+	}
+	old, err := s.GetRecord(rec.Kind, rec.ID)
+	if err == nil && !old.Expired(now) {
+		return ErrRecordExists
+	} else if err != nil && !errors.Is(err, ErrRecordNotFound) {
+		return err
+	}
+	return s.PutRecord(rec)
+}
+
+// CreateJSON stores v as the data of a new record, as CreateRecord does
+func CreateJSON(s Storage, rec Record, v any, now time.Time) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	rec.Data = data
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = now
+	}
+	return CreateRecord(s, &rec, now)
+}
+
+// GetJSON loads the data of a record into a new T
+func GetJSON[T any](s Storage, kind, id string) (*T, error) {
+	rec, err := s.GetRecord(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	v := new(T)
+	if err := json.Unmarshal(rec.Data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ListJSON decodes the data of every record selected by q
+func ListJSON[T any](s Storage, q RecordQuery) ([]*T, error) {
+	records, err := s.ListRecords(q)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*T, 0, len(records))
+	for _, rec := range records {
+		v := new(T)
+		if err := json.Unmarshal(rec.Data, v); err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
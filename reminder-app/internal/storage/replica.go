@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+)
+
+// lagCheckInterval is how often ReplicaStorage asks a replica how far
+// behind it is
+const lagCheckInterval = 10 * time.Second
+
+// PrimaryReader is implemented by backends that may serve reads from a
+// replica, so reads that a write is based on can see the latest data
+type PrimaryReader interface {
+	ReadPrimary() Storage
+}
+
+// ReadPrimary returns s reading from the primary where s may read from a
+// replica, or s itself. Read-modify-write callers use it, since a version
+// read from a lagging replica could only ever conflict.
+func ReadPrimary(s Storage) Storage {
+	if p, ok := s.(PrimaryReader); ok {
+		return p.ReadPrimary()
+	}
+	return s
+}
+
+// LagReporter is implemented by replicas that can tell how far their data
+// is behind the primary's
+type LagReporter interface {
+	ReplicationLag(ctx context.Context) (time.Duration, error)
+}
+
+// ReplicaStorage sends reads to a read-only replica and everything else,
+// including ID counters, to the primary. Reads go to the primary instead
+// while the replica can't be trusted to be within MaxStaleness of it: for
+// MaxStaleness after each write made through this process, so callers read
+// back what they wrote, and while the replica reports a larger lag or
+// can't be reached.
+type ReplicaStorage struct {
+	primary Storage
+	replica Storage
+	// MaxStaleness is how far behind the primary replica reads may be
+	MaxStaleness time.Duration
+	state        *replicaState
+	// pinned sends every read to the primary; see ReadPrimary
+	pinned bool
+}
+
+// replicaState is shared by a ReplicaStorage and its context-bound copies
+type replicaState struct {
+	mu        sync.Mutex
+	lastWrite time.Time
+	// lagOK is whether the replica was within MaxStaleness at lagChecked
+	lagOK      bool
+	lagChecked time.Time
+}
+
+// NewReplicaStorage reads from replica when it is at most maxStaleness
+// behind primary
+func NewReplicaStorage(primary, replica Storage, maxStaleness time.Duration) *ReplicaStorage {
+	return &ReplicaStorage{primary: primary, replica: replica, MaxStaleness: maxStaleness, state: &replicaState{}}
+}
+
+// reader returns the backend to read from
+func (s *ReplicaStorage) reader() Storage {
+	if s.pinned {
+		return s.primary
+	}
+	st := s.state
+	now := time.Now()
+	lr, reports := s.replica.(LagReporter)
+	st.mu.Lock()
+	recentWrite := now.Sub(st.lastWrite) < s.MaxStaleness
+	check := reports && !recentWrite && now.Sub(st.lagChecked) >= lagCheckInterval
+	if check {
+		// Claim the check so concurrent reads don't repeat it
+		st.lagChecked = now
+	}
+	lagOK := st.lagOK || !reports
+	st.mu.Unlock()
+
+	if recentWrite {
+		return s.primary
+	}
+	if check {
+		lagOK = s.checkLag(lr)
+	}
+	if !lagOK {
+		return s.primary
+	}
+	return s.replica
+}
+
+// checkLag asks the replica how far behind it is and records whether
+// that is within MaxStaleness
+func (s *ReplicaStorage) checkLag(lr LagReporter) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	lag, err := lr.ReplicationLag(ctx)
+	ok := err == nil && lag <= s.MaxStaleness
+	st := s.state
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if ok != st.lagOK {
+		if ok {
+			log.Printf("Replica is %s behind; reading from it", lag)
+		} else if err != nil {
+			log.Printf("Failed to check replica lag, reading from the primary: %v", err)
+		} else {
+			log.Printf("Replica is %s behind; reading from the primary", lag)
+		}
+	}
+	st.lagOK = ok
+	return ok
+}
+
+// wrote notes a write, so reads go to the primary until the replica has
+// had time to catch up
+func (s *ReplicaStorage) wrote() {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	s.state.lastWrite = time.Now()
+}
+
+// read runs fn against the reader, retrying on the primary if the replica
+// couldn't be reached
+func read[T any](s *ReplicaStorage, fn func(Storage) (T, error)) (T, error) {
+	b := s.reader()
+	v, err := fn(b)
+	if err != nil && b == s.replica && Unavailable(err) {
+		return fn(s.primary)
+	}
+	return v, err
+}
+
+// write runs fn against the primary
+func (s *ReplicaStorage) write(fn func() error) error {
+	s.wrote()
+	return fn()
+}
+
+// WithContext binds both backends to ctx
+func (s *ReplicaStorage) WithContext(ctx context.Context) Storage {
+	return &ReplicaStorage{primary: WithContext(s.primary, ctx), replica: WithContext(s.replica, ctx), MaxStaleness: s.MaxStaleness, state: s.state, pinned: s.pinned}
+}
+
+// ReadPrimary sends every read to the primary, still recording writes so
+// other callers read them back
+func (s *ReplicaStorage) ReadPrimary() Storage {
+	return &ReplicaStorage{primary: s.primary, replica: s.replica, MaxStaleness: s.MaxStaleness, state: s.state, pinned: true}
+}
+
+// Ping pings the primary. Reads fall back to it, so a replica being down
+// doesn't make the server unready.
+func (s *ReplicaStorage) Ping(ctx context.Context) error {
+	return Ping(ctx, s.primary)
+}
+
+// VerifySchema verifies the primary's schema if it has one
+func (s *ReplicaStorage) VerifySchema() error {
+	if v, ok := s.primary.(SchemaVerifier); ok {
+		return v.VerifySchema()
+	}
+	return nil
+}
+
+func (s *ReplicaStorage) CreateFamily(f *family.Family) error {
+	return s.write(func() error { return s.primary.CreateFamily(f) })
+}
+
+func (s *ReplicaStorage) GetFamily(id string) (*family.Family, error) {
+	return read(s, func(b Storage) (*family.Family, error) { return b.GetFamily(id) })
+}
+
+func (s *ReplicaStorage) ListFamilies() ([]*family.Family, error) {
+	return read(s, func(b Storage) ([]*family.Family, error) { return b.ListFamilies() })
+}
+
+func (s *ReplicaStorage) UpdateFamily(f *family.Family) error {
+	return s.write(func() error { return s.primary.UpdateFamily(f) })
+}
+
+func (s *ReplicaStorage) DeleteFamily(id string) error {
+	return s.write(func() error { return s.primary.DeleteFamily(id) })
+}
+
+func (s *ReplicaStorage) CreateReminder(r *reminder.Reminder) error {
+	return s.write(func() error { return s.primary.CreateReminder(r) })
+}
+
+func (s *ReplicaStorage) SaveReminders(rs []*reminder.Reminder) (int, error) {
+	s.wrote()
+	return SaveReminders(s.primary, rs)
+}
+
+func (s *ReplicaStorage) UpdateReminders(rs []*reminder.Reminder) (int, error) {
+	s.wrote()
+	return UpdateReminders(s.primary, rs...)
+}
+
+func (s *ReplicaStorage) GetReminder(id string) (*reminder.Reminder, error) {
+	return read(s, func(b Storage) (*reminder.Reminder, error) { return b.GetReminder(id) })
+}
+
+func (s *ReplicaStorage) ListReminders() ([]*reminder.Reminder, error) {
+	return read(s, func(b Storage) ([]*reminder.Reminder, error) { return b.ListReminders() })
+}
+
+func (s *ReplicaStorage) QueryReminders(f ReminderFilter) ([]*reminder.Reminder, error) {
+	return read(s, func(b Storage) ([]*reminder.Reminder, error) { return b.QueryReminders(f) })
+}
+
+func (s *ReplicaStorage) DeleteReminder(id string) error {
+	return s.write(func() error { return s.primary.DeleteReminder(id) })
+}
+
+func (s *ReplicaStorage) CreateCompletionEvent(e *reminder.CompletionEvent) error {
+	return s.write(func() error { return s.primary.CreateCompletionEvent(e) })
+}
+
+func (s *ReplicaStorage) GetCompletionEvent(id string) (*reminder.CompletionEvent, error) {
+	return read(s, func(b Storage) (*reminder.CompletionEvent, error) { return b.GetCompletionEvent(id) })
+}
+
+func (s *ReplicaStorage) ListCompletionEvents(reminderID string) ([]*reminder.CompletionEvent, error) {
+	return read(s, func(b Storage) ([]*reminder.CompletionEvent, error) { return b.ListCompletionEvents(reminderID) })
+}
+
+func (s *ReplicaStorage) ListAllCompletionEvents() ([]*reminder.CompletionEvent, error) {
+	return read(s, func(b Storage) ([]*reminder.CompletionEvent, error) { return b.ListAllCompletionEvents() })
+}
+
+func (s *ReplicaStorage) QueryCompletionEvents(q CompletionEventQuery) ([]*reminder.CompletionEvent, error) {
+	return read(s, func(b Storage) ([]*reminder.CompletionEvent, error) { return b.QueryCompletionEvents(q) })
+}
+
+func (s *ReplicaStorage) TallyCompletions(familyID string, from, to time.Time) ([]*CompletionTally, error) {
+	return read(s, func(b Storage) ([]*CompletionTally, error) { return TallyCompletions(b, familyID, from, to) })
+}
+
+func (s *ReplicaStorage) GetLatestCompletionEvent(reminderID string) (*reminder.CompletionEvent, error) {
+	return read(s, func(b Storage) (*reminder.CompletionEvent, error) { return b.GetLatestCompletionEvent(reminderID) })
+}
+
+func (s *ReplicaStorage) DeleteCompletionEvent(id string) error {
+	return s.write(func() error { return s.primary.DeleteCompletionEvent(id) })
+}
+
+func (s *ReplicaStorage) PutRecord(rec *Record) error {
+	return s.write(func() error { return s.primary.PutRecord(rec) })
+}
+
+func (s *ReplicaStorage) CreateRecord(rec *Record, now time.Time) error {
+	return s.write(func() error { return CreateRecord(s.primary, rec, now) })
+}
+
+func (s *ReplicaStorage) GetRecord(kind, id string) (*Record, error) {
+	return read(s, func(b Storage) (*Record, error) { return b.GetRecord(kind, id) })
+}
+
+func (s *ReplicaStorage) ListRecords(q RecordQuery) ([]*Record, error) {
+	return read(s, func(b Storage) ([]*Record, error) { return b.ListRecords(q) })
+}
+
+func (s *ReplicaStorage) DeleteRecord(kind, id string) error {
+	return s.write(func() error { return s.primary.DeleteRecord(kind, id) })
+}
+
+func (s *ReplicaStorage) PurgeExpiredRecords(now time.Time) (int, error) {
+	s.wrote()
+	return s.primary.PurgeExpiredRecords(now)
+}
+
+// ID counters only live on the primary; a replica's could hand out IDs
+// that are already taken
+
+func (s *ReplicaStorage) NextID(kind IDKind) (int, error) {
+	return s.primary.NextID(kind)
+}
+
+func (s *ReplicaStorage) SetFamilyIDCounter(counter int) error {
+	return s.primary.SetFamilyIDCounter(counter)
+}
+
+func (s *ReplicaStorage) SetReminderIDCounter(counter int) error {
+	return s.primary.SetReminderIDCounter(counter)
+}
+
+func (s *ReplicaStorage) SetCompletionEventIDCounter(counter int) error {
+	return s.primary.SetCompletionEventIDCounter(counter)
+}
+
+func (s *ReplicaStorage) GetFamilyIDCounter() int {
+	return s.primary.GetFamilyIDCounter()
+}
+
+func (s *ReplicaStorage) GetReminderIDCounter() int {
+	return s.primary.GetReminderIDCounter()
+}
+
+func (s *ReplicaStorage) GetCompletionEventIDCounter() int {
+	return s.primary.GetCompletionEventIDCounter()
+}
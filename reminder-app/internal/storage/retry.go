@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"log"
+	"time"
+)
+
+// maxConnectWait caps the pause between connection attempts
+const maxConnectWait = 30 * time.Second
+
+// ConnectRetry is how often to try reaching a database server at startup
+// before giving up, for deployments such as docker-compose that start the
+// app alongside its database
+type ConnectRetry struct {
+	// Attempts is how many times to try; zero or one tries once
+	Attempts int
+	// Wait is the pause after the first failed attempt. It doubles after
+	// each further one, up to 30 seconds.
+	Wait time.Duration
+}
+
+// do calls connect until it succeeds or the attempts run out, returning the
+// last error
+func (r ConnectRetry) do(what string, connect func() error) error {
+	wait := r.Wait
+	for attempt := 1; ; attempt++ {
+		err := connect()
+		if err == nil || attempt >= r.Attempts {
+			return err
+		}
+		log.Printf("Failed to connect to %s (attempt %d of %d), retrying in %s: %v", what, attempt, r.Attempts, wait, err)
+		time.Sleep(wait)
+		wait = min(2*wait, maxConnectWait)
+	}
+}
@@ -1,10 +1,15 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,7 +21,10 @@ import (
 
 type SQLiteStorage struct {
 	db *sql.DB
-	mu sync.Mutex
+	// mu is shared with the views returned by WithContext
+	mu *sync.Mutex
+	// bound is the context queries run under; see WithContext
+	bound context.Context
 }
 
 // NewSQLiteStorage creates a new SQLite storage instance
@@ -26,7 +34,7 @@ func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
 	}
 
-	s := &SQLiteStorage{db: db}
+	s := &SQLiteStorage{db: db, mu: &sync.Mutex{}}
 
 	// Create tables if they don't exist
 	if err := s.createTables(); err != nil {
@@ -37,11 +45,67 @@ func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 	return s, nil
 }
 
+// WithContext returns a view of the storage whose queries are cancelled
+// along with ctx
+func (s *SQLiteStorage) WithContext(ctx context.Context) Storage {
+	bound := *s
+	bound.bound = ctx
+	return &bound
+}
+
+func (s *SQLiteStorage) ctx() context.Context {
+	if s.bound == nil {
+		return context.Background()
+	}
+	return s.bound
+}
+
+// Ping reads the schema, which fails if the database file has become
+// unreadable
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	var n int
+	return s.db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master").Scan(&n)
+}
+
 // Close closes the database connection
 func (s *SQLiteStorage) Close() error {
 	return s.db.Close()
 }
 
+// Backup writes a consistent copy of the database to w while the server
+// keeps serving. VACUUM INTO takes a snapshot in a read transaction, so
+// concurrent writes neither block nor tear the copy.
+func (s *SQLiteStorage) Backup(w io.Writer) error {
+	dir, err := os.MkdirTemp("", "reminder-backup-")
+	if err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.sqlite")
+	if err := s.Snapshot(path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// Snapshot writes a consistent copy of the database to a new file at path,
+// the same way Backup does
+func (s *SQLiteStorage) Snapshot(path string) error {
+	if _, err := s.db.ExecContext(s.ctx(), "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
 // createTables creates the necessary tables
 func (s *SQLiteStorage) createTables() error {
 	queries := []string{
@@ -79,15 +143,19 @@ func (s *SQLiteStorage) createTables() error {
 	}
 
 	for _, query := range queries {
-		if _, err := s.db.Exec(query); err != nil {
+		if _, err := s.db.ExecContext(s.ctx(), query); err != nil {
 			return fmt.Errorf("failed to execute query %q: %w", query, err)
 		}
 	}
 
+	if err := s.migrate(); err != nil {
+		return err
+	}
+
 	// Initialize counters if they don't exist
 	counterNames := []string{"family_id", "reminder_id", "completion_event_id"}
 	for _, name := range counterNames {
-		_, err := s.db.Exec("INSERT OR IGNORE INTO counters (name, value) VALUES (?, 0)", name)
+		_, err := s.db.ExecContext(s.ctx(), "INSERT OR IGNORE INTO counters (name, value) VALUES (?, 0)", name)
 		if err != nil {
 			return fmt.Errorf("failed to initialize counter %s: %w", name, err)
 		}
@@ -96,6 +164,156 @@ func (s *SQLiteStorage) createTables() error {
 	return nil
 }
 
+// sqliteMigrations are applied in order after the base tables exist. Each
+// entry runs exactly once and is recorded in schema_migrations, so new schema
+// changes must be appended rather than edited in place.
+var sqliteMigrations = []string{
+	`ALTER TABLE reminders ADD COLUMN version INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE families ADD COLUMN settings TEXT NOT NULL DEFAULT '{}'`,
+	// completed_at keeps the writer's UTC offset, so range queries use a
+	// separate Unix timestamp column
+	`ALTER TABLE completion_events ADD COLUMN completed_unix INTEGER NOT NULL DEFAULT 0`,
+	`UPDATE completion_events SET completed_unix = CAST(strftime('%s', completed_at) AS INTEGER)`,
+	`CREATE INDEX IF NOT EXISTS idx_completion_events_reminder ON completion_events (reminder_id, completed_unix)`,
+	`CREATE TABLE records (
+		kind TEXT NOT NULL,
+		id TEXT NOT NULL,
+		family_id TEXT NOT NULL DEFAULT '',
+		ref TEXT NOT NULL DEFAULT '',
+		data TEXT NOT NULL,
+		created_at TEXT NOT NULL, -- ISO 8601 format
+		created_unix INTEGER NOT NULL,
+		PRIMARY KEY (kind, id)
+	)`,
+	`CREATE INDEX idx_records_family ON records (kind, family_id, created_unix)`,
+	`CREATE INDEX idx_records_ref ON records (kind, ref, created_unix)`,
+	`ALTER TABLE reminders ADD COLUMN position INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE reminders ADD COLUMN status TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE reminders ADD COLUMN project_id TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE reminders ADD COLUMN effort INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE reminders ADD COLUMN assignment TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE records ADD COLUMN expires_at TEXT`, // ISO 8601 format, nullable
+	`ALTER TABLE records ADD COLUMN expires_unix INTEGER`,
+	`CREATE INDEX idx_records_expires ON records (expires_unix) WHERE expires_unix IS NOT NULL`,
+	// due_date keeps the writer's UTC offset, so range queries use a separate
+	// Unix timestamp column
+	`ALTER TABLE reminders ADD COLUMN due_unix INTEGER`,
+	`UPDATE reminders SET due_unix = CAST(strftime('%s', due_date) AS INTEGER) WHERE due_date IS NOT NULL`,
+	`CREATE INDEX idx_reminders_family ON reminders (family_id, due_unix)`,
+	`ALTER TABLE completion_events ADD COLUMN impersonator TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE reminders ADD COLUMN snoozed_until TEXT`, // ISO 8601 format, nullable
+	`ALTER TABLE reminders ADD COLUMN snoozed_unix INTEGER`,
+	`ALTER TABLE reminders ADD COLUMN recurrence_interval INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE reminders ADD COLUMN requires_confirmation BOOLEAN NOT NULL DEFAULT 0`,
+	`ALTER TABLE completion_events ADD COLUMN state TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE completion_events ADD COLUMN pair_id TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE reminders ADD COLUMN timezone TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE reminders ADD COLUMN notify_condition TEXT`, // JSON, nullable
+	`ALTER TABLE reminders ADD COLUMN usage_trigger TEXT`,    // JSON, nullable
+	`ALTER TABLE reminders ADD COLUMN rotation TEXT`,         // JSON, nullable
+	`ALTER TABLE reminders ADD COLUMN tags TEXT`,             // JSON array, nullable
+	`ALTER TABLE reminders ADD COLUMN priority TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE reminders ADD COLUMN exceptions TEXT`, // JSON array, nullable
+	`ALTER TABLE reminders ADD COLUMN series_id TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX idx_reminders_series ON reminders (series_id) WHERE series_id != ''`,
+	`ALTER TABLE reminders ADD COLUMN points INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE completion_events ADD COLUMN points INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE reminders ADD COLUMN escalation TEXT`, // JSON, nullable
+	// completed_unix only holds whole seconds, which ranges, cursors and
+	// ordering need to tell apart, so they use milliseconds, the precision
+	// times are stored at
+	`ALTER TABLE completion_events ADD COLUMN completed_ms INTEGER NOT NULL DEFAULT 0`,
+	`UPDATE completion_events SET completed_ms = CAST(strftime('%s', completed_at) AS INTEGER) * 1000 + CAST(ROUND(strftime('%f', completed_at) * 1000) AS INTEGER) % 1000`,
+	`DROP INDEX idx_completion_events_reminder`,
+	`CREATE INDEX idx_completion_events_reminder ON completion_events (reminder_id, completed_ms, id)`,
+}
+
+// migrate applies any pending entries from sqliteMigrations
+func (s *SQLiteStorage) migrate() error {
+	if _, err := s.db.ExecContext(s.ctx(), `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := s.db.QueryRowContext(s.ctx(), "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := current; i < len(sqliteMigrations); i++ {
+		tx, err := s.db.BeginTx(s.ctx(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(s.ctx(), sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(s.ctx(), "INSERT INTO schema_migrations (version) VALUES (?)", i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// VerifySchema checks that the database is at the schema version of this
+// build and that the tables have the columns the queries use
+func (s *SQLiteStorage) VerifySchema() error {
+	var version int
+	if err := s.db.QueryRowContext(s.ctx(), "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version != len(sqliteMigrations) {
+		return fmt.Errorf("database is at schema version %d but this build expects %d", version, len(sqliteMigrations))
+	}
+	for table, columns := range map[string]string{
+		"families":          familyColumns,
+		"reminders":         reminderColumns + ", due_unix, snoozed_unix",
+		"completion_events": completionEventColumns + ", completed_unix, completed_ms",
+		"records":           recordColumns + ", created_unix, expires_unix",
+		"counters":          "name, value",
+	} {
+		rows, err := s.db.QueryContext(s.ctx(), "SELECT name FROM pragma_table_info(?)", table)
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		have := make(map[string]bool)
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to inspect table %s: %w", table, err)
+			}
+			have[name] = true
+		}
+		rows.Close()
+		if err := missingColumns(table, columns, have); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingColumns reports the first column of a comma-separated list that
+// the table lacks
+func missingColumns(table, columns string, have map[string]bool) error {
+	if len(have) == 0 {
+		return fmt.Errorf("table %s is missing", table)
+	}
+	for _, c := range strings.Split(columns, ",") {
+		if c = strings.TrimSpace(c); !have[c] {
+			return fmt.Errorf("table %s is missing column %s", table, c)
+		}
+	}
+	return nil
+}
+
 // Family operations
 func (s *SQLiteStorage) CreateFamily(f *family.Family) error {
 	s.mu.Lock()
@@ -105,9 +323,13 @@ func (s *SQLiteStorage) CreateFamily(f *family.Family) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal family members: %w", err)
 	}
+	settingsJSON, err := json.Marshal(f.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal family settings: %w", err)
+	}
 
-	_, err = s.db.Exec("INSERT INTO families (id, name, members) VALUES (?, ?, ?)",
-		f.ID, f.Name, string(membersJSON))
+	_, err = s.db.ExecContext(s.ctx(), "INSERT INTO families (id, name, members, settings) VALUES (?, ?, ?, ?)",
+		f.ID, f.Name, string(membersJSON), string(settingsJSON))
 	if err != nil {
 		return fmt.Errorf("failed to create family: %w", err)
 	}
@@ -119,11 +341,7 @@ func (s *SQLiteStorage) GetFamily(id string) (*family.Family, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var f family.Family
-	var membersJSON string
-
-	err := s.db.QueryRow("SELECT id, name, members FROM families WHERE id = ?", id).
-		Scan(&f.ID, &f.Name, &membersJSON)
+	f, err := scanFamily(s.db.QueryRowContext(s.ctx(), "SELECT "+familyColumns+" FROM families WHERE id = ?", id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("family not found")
@@ -131,18 +349,14 @@ func (s *SQLiteStorage) GetFamily(id string) (*family.Family, error) {
 		return nil, fmt.Errorf("failed to get family: %w", err)
 	}
 
-	if err := json.Unmarshal([]byte(membersJSON), &f.Members); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal family members: %w", err)
-	}
-
-	return &f, nil
+	return f, nil
 }
 
 func (s *SQLiteStorage) ListFamilies() ([]*family.Family, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	rows, err := s.db.Query("SELECT id, name, members FROM families")
+	rows, err := s.db.QueryContext(s.ctx(), "SELECT "+familyColumns+" FROM families")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list families: %w", err)
 	}
@@ -150,28 +364,67 @@ func (s *SQLiteStorage) ListFamilies() ([]*family.Family, error) {
 
 	var families []*family.Family
 	for rows.Next() {
-		var f family.Family
-		var membersJSON string
-
-		if err := rows.Scan(&f.ID, &f.Name, &membersJSON); err != nil {
+		f, err := scanFamily(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan family: %w", err)
 		}
+		families = append(families, f)
+	}
 
-		if err := json.Unmarshal([]byte(membersJSON), &f.Members); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal family members: %w", err)
-		}
+	return families, nil
+}
+
+// familyColumns lists the family columns in the order scanFamily expects
+const familyColumns = "id, name, members, settings"
 
-		families = append(families, &f)
+// scanFamily reads a family selected with familyColumns
+func scanFamily(row rowScanner) (*family.Family, error) {
+	var f family.Family
+	var membersJSON, settingsJSON string
+
+	if err := row.Scan(&f.ID, &f.Name, &membersJSON, &settingsJSON); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(membersJSON), &f.Members); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal family members: %w", err)
+	}
+	if err := json.Unmarshal([]byte(settingsJSON), &f.Settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal family settings: %w", err)
 	}
 
-	return families, nil
+	return &f, nil
+}
+
+func (s *SQLiteStorage) UpdateFamily(f *family.Family) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	membersJSON, err := json.Marshal(f.Members)
+	if err != nil {
+		return fmt.Errorf("failed to marshal family members: %w", err)
+	}
+	settingsJSON, err := json.Marshal(f.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal family settings: %w", err)
+	}
+
+	result, err := s.db.ExecContext(s.ctx(), "UPDATE families SET name = ?, members = ?, settings = ? WHERE id = ?",
+		f.Name, string(membersJSON), string(settingsJSON), f.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update family: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("family not found")
+	}
+
+	return nil
 }
 
 func (s *SQLiteStorage) DeleteFamily(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec("DELETE FROM families WHERE id = ?", id)
+	_, err := s.db.ExecContext(s.ctx(), "DELETE FROM families WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete family: %w", err)
 	}
@@ -183,7 +436,65 @@ func (s *SQLiteStorage) DeleteFamily(id string) error {
 func (s *SQLiteStorage) CreateReminder(r *reminder.Reminder) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.saveReminder(s.db, r)
+}
+
+// SaveReminders saves every reminder in one transaction
+func (s *SQLiteStorage) SaveReminders(rs []*reminder.Reminder) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.BeginTx(s.ctx(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, r := range rs {
+		if err := s.saveReminder(tx, r); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit reminders: %w", err)
+	}
+	return len(rs), nil
+}
+
+// UpdateReminders checks the stored versions and saves the reminders in
+// one transaction
+func (s *SQLiteStorage) UpdateReminders(rs []*reminder.Reminder) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.BeginTx(s.ctx(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, r := range rs {
+		var stored int
+		err := tx.QueryRowContext(s.ctx(), "SELECT version FROM reminders WHERE id = ?", r.ID).Scan(&stored)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("failed to read reminder version: %w", err)
+		}
+		if err := checkVersion(r, stored); err != nil {
+			return 0, err
+		}
+		if err := s.saveReminder(tx, r); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit reminders: %w", err)
+	}
+	return len(rs), nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
 
+// saveReminder inserts or replaces r through db
+func (s *SQLiteStorage) saveReminder(db execer, r *reminder.Reminder) error {
 	recurrenceDaysJSON, err := json.Marshal(r.Recurrence.Days)
 	if err != nil {
 		return fmt.Errorf("failed to marshal recurrence days: %w", err)
@@ -191,14 +502,49 @@ func (s *SQLiteStorage) CreateReminder(r *reminder.Reminder) error {
 
 	var completedAtStr *string
 	if r.CompletedAt != nil {
-		str := r.CompletedAt.Format("2006-01-02T15:04:05Z07:00")
+		str := FormatTime(*r.CompletedAt)
 		completedAtStr = &str
 	}
 
 	var dueDateStr *string
+	var dueUnix *int64
 	if r.DueDate != nil {
-		str := r.DueDate.Format("2006-01-02T15:04:05Z07:00")
-		dueDateStr = &str
+		str := FormatTime(*r.DueDate)
+		unix := r.DueDate.Unix()
+		dueDateStr, dueUnix = &str, &unix
+	}
+
+	var snoozedUntilStr *string
+	var snoozedUnix *int64
+	if r.SnoozedUntil != nil {
+		str := FormatTime(*r.SnoozedUntil)
+		unix := r.SnoozedUntil.Unix()
+		snoozedUntilStr, snoozedUnix = &str, &unix
+	}
+
+	conditionJSON, err := marshalOptional(r.Condition, "condition")
+	if err != nil {
+		return err
+	}
+	usageJSON, err := marshalOptional(r.Usage, "usage")
+	if err != nil {
+		return err
+	}
+	rotationJSON, err := marshalOptional(r.Rotation, "rotation")
+	if err != nil {
+		return err
+	}
+	escalationJSON, err := marshalOptional(r.Escalation, "escalation")
+	if err != nil {
+		return err
+	}
+	tagsJSON, err := marshalList(r.Tags, "tags")
+	if err != nil {
+		return err
+	}
+	exceptionsJSON, err := marshalList(r.Exceptions, "exceptions")
+	if err != nil {
+		return err
 	}
 
 	// Handle empty end date by setting it to a very far future date
@@ -208,13 +554,11 @@ func (s *SQLiteStorage) CreateReminder(r *reminder.Reminder) error {
 		endDate = "2099-12-31T23:59:59Z"
 	}
 
-	_, err = s.db.Exec(`INSERT OR REPLACE INTO reminders 
-		(id, title, description, due_date, recurrence_type, recurrence_days, 
-		recurrence_date, recurrence_end_date, completed, completed_at, family_id, family_member) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	_, err = db.ExecContext(s.ctx(), `INSERT OR REPLACE INTO reminders (`+reminderColumns+`, due_unix, snoozed_unix)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		r.ID, r.Title, r.Description, dueDateStr,
 		r.Recurrence.Type, string(recurrenceDaysJSON), r.Recurrence.Date,
-		endDate, r.Completed, completedAtStr, r.FamilyID, r.FamilyMember)
+		endDate, r.Completed, completedAtStr, r.FamilyID, r.FamilyMember, r.Version, r.Position, r.Status, r.ProjectID, r.Effort, r.Assignment, snoozedUntilStr, r.Recurrence.Interval, r.RequiresConfirmation, r.Timezone, conditionJSON, usageJSON, rotationJSON, tagsJSON, r.Priority, exceptionsJSON, r.SeriesID, r.Points, escalationJSON, dueUnix, snoozedUnix)
 	if err != nil {
 		return fmt.Errorf("failed to create/update reminder: %w", err)
 	}
@@ -226,18 +570,7 @@ func (s *SQLiteStorage) GetReminder(id string) (*reminder.Reminder, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var r reminder.Reminder
-	var dueDateStr *string
-	var recurrenceDaysJSON string
-	var completedAtStr *string
-
-	err := s.db.QueryRow(`SELECT id, title, description, due_date, recurrence_type, 
-		recurrence_days, recurrence_date, recurrence_end_date, completed, completed_at, 
-		family_id, family_member FROM reminders WHERE id = ?`, id).
-		Scan(&r.ID, &r.Title, &r.Description, &dueDateStr, &r.Recurrence.Type,
-			&recurrenceDaysJSON, &r.Recurrence.Date, &r.Recurrence.EndDate,
-			&r.Completed, &completedAtStr, &r.FamilyID, &r.FamilyMember)
-
+	r, err := scanReminder(s.db.QueryRowContext(s.ctx(), `SELECT `+reminderColumns+` FROM reminders WHERE id = ?`, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("reminder not found")
@@ -245,9 +578,114 @@ func (s *SQLiteStorage) GetReminder(id string) (*reminder.Reminder, error) {
 		return nil, fmt.Errorf("failed to get reminder: %w", err)
 	}
 
+	return r, nil
+}
+
+func (s *SQLiteStorage) ListReminders() ([]*reminder.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.queryReminders(`SELECT ` + reminderColumns + ` FROM reminders`)
+}
+
+func (s *SQLiteStorage) QueryReminders(f ReminderFilter) ([]*reminder.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE 1 = 1`
+	var args []any
+	for _, c := range []struct{ column, value string }{
+		{"family_id", f.FamilyID},
+		{"family_member", f.FamilyMember},
+		{"recurrence_type", f.RecurrenceType},
+		{"project_id", f.ProjectID},
+	} {
+		if c.value != "" {
+			query += " AND " + c.column + " = ?"
+			args = append(args, c.value)
+		}
+	}
+	if f.Tag != "" {
+		query += " AND EXISTS (SELECT 1 FROM json_each(reminders.tags) WHERE value = ?)"
+		args = append(args, reminder.NormalizeTag(f.Tag))
+	}
+	// A series is named after its first reminder, which only gets a
+	// series_id once it is split
+	if f.SeriesID != "" {
+		query += " AND (series_id = ? OR id = ?)"
+		args = append(args, f.SeriesID, f.SeriesID)
+	}
+	if f.Completed != nil {
+		query += " AND completed = ?"
+		args = append(args, *f.Completed)
+	}
+	// due_unix only has second precision, so the bounds are widened to
+	// whole seconds here and applied exactly below. A snooze only ever
+	// makes a reminder due later.
+	if f.DueAfter != nil {
+		query += " AND (due_unix >= ? OR snoozed_unix >= ?)"
+		args = append(args, f.DueAfter.Unix(), f.DueAfter.Unix())
+	}
+	if f.DueBefore != nil {
+		query += " AND due_unix <= ?"
+		args = append(args, f.DueBefore.Unix())
+	}
+	list, err := s.queryReminders(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return FilterReminders(list, f), nil
+}
+
+// queryReminders runs a SELECT of reminderColumns
+func (s *SQLiteStorage) queryReminders(query string, args ...any) ([]*reminder.Reminder, error) {
+	rows, err := s.db.QueryContext(s.ctx(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var reminders []*reminder.Reminder
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminders = append(reminders, r)
+	}
+
+	return reminders, nil
+}
+
+// reminderColumns lists the reminder columns in the order scanReminder expects
+const reminderColumns = `id, title, description, due_date, recurrence_type,
+		recurrence_days, recurrence_date, recurrence_end_date, completed, completed_at,
+		family_id, family_member, version, position, status, project_id, effort, assignment, snoozed_until,
+		recurrence_interval, requires_confirmation, timezone, notify_condition, usage_trigger, rotation, tags, priority, exceptions, series_id, points, escalation`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanReminder reads a reminder selected with reminderColumns
+func scanReminder(row rowScanner) (*reminder.Reminder, error) {
+	var r reminder.Reminder
+	var dueDateStr *string
+	var recurrenceDaysJSON string
+	var completedAtStr *string
+	var snoozedUntilStr *string
+	var conditionJSON, usageJSON, rotationJSON, tagsJSON, exceptionsJSON, escalationJSON *string
+
+	if err := row.Scan(&r.ID, &r.Title, &r.Description, &dueDateStr, &r.Recurrence.Type,
+		&recurrenceDaysJSON, &r.Recurrence.Date, &r.Recurrence.EndDate,
+		&r.Completed, &completedAtStr, &r.FamilyID, &r.FamilyMember, &r.Version, &r.Position, &r.Status, &r.ProjectID, &r.Effort, &r.Assignment, &snoozedUntilStr, &r.Recurrence.Interval, &r.RequiresConfirmation, &r.Timezone, &conditionJSON, &usageJSON, &rotationJSON, &tagsJSON, &r.Priority, &exceptionsJSON, &r.SeriesID, &r.Points, &escalationJSON); err != nil {
+		return nil, err
+	}
+
 	// Parse due date if not null
 	if dueDateStr != nil {
-		dueDate, err := parseTimeString(*dueDateStr)
+		dueDate, err := ParseTime(*dueDateStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse due date: %w", err)
 		}
@@ -256,13 +694,21 @@ func (s *SQLiteStorage) GetReminder(id string) (*reminder.Reminder, error) {
 
 	// Parse completed at
 	if completedAtStr != nil {
-		completedAt, err := parseTimeString(*completedAtStr)
+		completedAt, err := ParseTime(*completedAtStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse completed at: %w", err)
 		}
 		r.CompletedAt = &completedAt
 	}
 
+	if snoozedUntilStr != nil {
+		snoozedUntil, err := ParseTime(*snoozedUntilStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse snoozed until: %w", err)
+		}
+		r.SnoozedUntil = &snoozedUntil
+	}
+
 	// Convert far future end date back to empty string for API consistency
 	if r.Recurrence.EndDate == "2099-12-31T23:59:59Z" {
 		r.Recurrence.EndDate = ""
@@ -272,74 +718,78 @@ func (s *SQLiteStorage) GetReminder(id string) (*reminder.Reminder, error) {
 	if err := json.Unmarshal([]byte(recurrenceDaysJSON), &r.Recurrence.Days); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal recurrence days: %w", err)
 	}
+	var err error
+	if r.Condition, err = unmarshalOptional[reminder.Condition](conditionJSON, "condition"); err != nil {
+		return nil, err
+	}
+	if r.Usage, err = unmarshalOptional[reminder.Usage](usageJSON, "usage"); err != nil {
+		return nil, err
+	}
+	if r.Rotation, err = unmarshalOptional[reminder.Rotation](rotationJSON, "rotation"); err != nil {
+		return nil, err
+	}
+	if r.Tags, err = unmarshalList[string](tagsJSON, "tags"); err != nil {
+		return nil, err
+	}
+	if r.Exceptions, err = unmarshalList[time.Time](exceptionsJSON, "exceptions"); err != nil {
+		return nil, err
+	}
+	if r.Escalation, err = unmarshalOptional[reminder.Escalation](escalationJSON, "escalation"); err != nil {
+		return nil, err
+	}
 
 	return &r, nil
 }
 
-func (s *SQLiteStorage) ListReminders() ([]*reminder.Reminder, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	rows, err := s.db.Query(`SELECT id, title, description, due_date, recurrence_type, 
-		recurrence_days, recurrence_date, recurrence_end_date, completed, completed_at, 
-		family_id, family_member FROM reminders`)
+// marshalOptional encodes an optional value, such as a reminder's
+// condition, for a nullable JSON column
+func marshalOptional[T any](v *T, what string) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list reminders: %w", err)
+		return nil, fmt.Errorf("failed to marshal %s: %w", what, err)
 	}
-	defer rows.Close()
-
-	var reminders []*reminder.Reminder
-	for rows.Next() {
-		var r reminder.Reminder
-		var dueDateStr *string
-		var recurrenceDaysJSON string
-		var completedAtStr *string
-
-		if err := rows.Scan(&r.ID, &r.Title, &r.Description, &dueDateStr, &r.Recurrence.Type,
-			&recurrenceDaysJSON, &r.Recurrence.Date, &r.Recurrence.EndDate,
-			&r.Completed, &completedAtStr, &r.FamilyID, &r.FamilyMember); err != nil {
-			return nil, fmt.Errorf("failed to scan reminder: %w", err)
-		}
-
-		// Parse due date if not null
-		if dueDateStr != nil {
-			dueDate, err := parseTimeString(*dueDateStr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse due date: %w", err)
-			}
-			r.DueDate = &dueDate
-		}
-
-		// Parse completed at
-		if completedAtStr != nil {
-			completedAt, err := parseTimeString(*completedAtStr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse completed at: %w", err)
-			}
-			r.CompletedAt = &completedAt
-		}
-
-		// Convert far future end date back to empty string for API consistency
-		if r.Recurrence.EndDate == "2099-12-31T23:59:59Z" {
-			r.Recurrence.EndDate = ""
-		}
+	str := string(data)
+	return &str, nil
+}
 
-		// Parse recurrence days
-		if err := json.Unmarshal([]byte(recurrenceDaysJSON), &r.Recurrence.Days); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal recurrence days: %w", err)
-		}
+// unmarshalOptional decodes a column written by marshalOptional
+func unmarshalOptional[T any](data *string, what string) (*T, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var v T
+	if err := json.Unmarshal([]byte(*data), &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", what, err)
+	}
+	return &v, nil
+}
 
-		reminders = append(reminders, &r)
+// marshalList encodes a list, such as a reminder's tags, for a nullable JSON
+// array column; empty lists store NULL
+func marshalList[T any](list []T, what string) (*string, error) {
+	if len(list) == 0 {
+		return nil, nil
 	}
+	return marshalOptional(&list, what)
+}
 
-	return reminders, nil
+// unmarshalList decodes a column written by marshalList
+func unmarshalList[T any](data *string, what string) ([]T, error) {
+	list, err := unmarshalOptional[[]T](data, what)
+	if list == nil {
+		return nil, err
+	}
+	return *list, nil
 }
 
 func (s *SQLiteStorage) DeleteReminder(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec("DELETE FROM reminders WHERE id = ?", id)
+	_, err := s.db.ExecContext(s.ctx(), "DELETE FROM reminders WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete reminder: %w", err)
 	}
@@ -347,13 +797,27 @@ func (s *SQLiteStorage) DeleteReminder(id string) error {
 	return nil
 }
 
+// completionEventColumns are the columns of completion_events shared by the
+// SQL backends, in the order their scans expect
+const completionEventColumns = "id, reminder_id, completed_at, completed_by, impersonator, state, pair_id, points"
+
+// ceilMilli returns the first whole millisecond since the Unix epoch that
+// isn't before t, so comparing completed_ms with it compares with t exactly
+func ceilMilli(t time.Time) int64 {
+	ms := t.UnixMilli()
+	if time.UnixMilli(ms).Before(t) {
+		ms++
+	}
+	return ms
+}
+
 // CompletionEvent operations
 func (s *SQLiteStorage) CreateCompletionEvent(e *reminder.CompletionEvent) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec("INSERT OR REPLACE INTO completion_events (id, reminder_id, completed_at, completed_by) VALUES (?, ?, ?, ?)",
-		e.ID, e.ReminderID, e.CompletedAt.Format("2006-01-02T15:04:05Z07:00"), e.CompletedBy)
+	_, err := s.db.ExecContext(s.ctx(), `INSERT OR REPLACE INTO completion_events (`+completionEventColumns+`, completed_unix, completed_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, e.ReminderID, FormatTime(e.CompletedAt), e.CompletedBy, e.Impersonator, e.State, e.PairID, e.Points, e.CompletedAt.Unix(), e.CompletedAt.UnixMilli())
 	if err != nil {
 		return fmt.Errorf("failed to create/update completion event: %w", err)
 	}
@@ -368,8 +832,8 @@ func (s *SQLiteStorage) GetCompletionEvent(id string) (*reminder.CompletionEvent
 	var e reminder.CompletionEvent
 	var completedAtStr string
 
-	err := s.db.QueryRow("SELECT id, reminder_id, completed_at, completed_by FROM completion_events WHERE id = ?", id).
-		Scan(&e.ID, &e.ReminderID, &completedAtStr, &e.CompletedBy)
+	err := s.db.QueryRowContext(s.ctx(), `SELECT `+completionEventColumns+` FROM completion_events WHERE id = ?`, id).
+		Scan(&e.ID, &e.ReminderID, &completedAtStr, &e.CompletedBy, &e.Impersonator, &e.State, &e.PairID, &e.Points)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("completion event not found")
@@ -378,7 +842,7 @@ func (s *SQLiteStorage) GetCompletionEvent(id string) (*reminder.CompletionEvent
 	}
 
 	// Parse completed at
-	if e.CompletedAt, err = parseTimeString(completedAtStr); err != nil {
+	if e.CompletedAt, err = ParseTime(completedAtStr); err != nil {
 		return nil, fmt.Errorf("failed to parse completed at: %w", err)
 	}
 
@@ -389,7 +853,97 @@ func (s *SQLiteStorage) ListCompletionEvents(reminderID string) ([]*reminder.Com
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	rows, err := s.db.Query("SELECT id, reminder_id, completed_at, completed_by FROM completion_events WHERE reminder_id = ?", reminderID)
+	return s.queryCompletionEvents(`SELECT `+completionEventColumns+` FROM completion_events WHERE reminder_id = ?`, reminderID)
+}
+
+func (s *SQLiteStorage) ListAllCompletionEvents() ([]*reminder.CompletionEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.queryCompletionEvents(`SELECT ` + completionEventColumns + ` FROM completion_events ORDER BY id`)
+}
+
+func (s *SQLiteStorage) QueryCompletionEvents(q CompletionEventQuery) ([]*reminder.CompletionEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := `SELECT ` + completionEventColumns + ` FROM completion_events WHERE reminder_id = ?`
+	args := []any{q.ReminderID}
+	// Stored times are whole milliseconds, so bounds finer than that are
+	// rounded the way comparing them exactly would
+	if !q.From.IsZero() {
+		query += " AND completed_ms >= ?"
+		args = append(args, ceilMilli(q.From))
+	}
+	if !q.To.IsZero() {
+		query += " AND completed_ms < ?"
+		args = append(args, ceilMilli(q.To))
+	}
+	if q.AfterID != "" {
+		query += " AND (completed_ms > ? OR (completed_ms = ? AND id > ?))"
+		args = append(args, q.AfterTime.UnixMilli(), q.AfterTime.UnixMilli(), q.AfterID)
+	}
+	query += " ORDER BY completed_ms, id"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+	return s.queryCompletionEvents(query, args...)
+}
+
+func (s *SQLiteStorage) GetLatestCompletionEvent(reminderID string) (*reminder.CompletionEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, err := s.queryCompletionEvents(`SELECT `+completionEventColumns+` FROM completion_events WHERE reminder_id = ? ORDER BY completed_ms DESC, id DESC LIMIT 1`, reminderID)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return events[0], nil
+}
+
+// TallyCompletions groups the family's completions in the database, each
+// group's times concatenated into one column
+func (s *SQLiteStorage) TallyCompletions(familyID string, from, to time.Time) ([]*CompletionTally, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.QueryContext(s.ctx(), `SELECT e.reminder_id, e.completed_by, group_concat(e.completed_at, '|'), SUM(e.points)
+		FROM completion_events e JOIN reminders r ON r.id = e.reminder_id
+		WHERE r.family_id = ? AND e.state != ? AND e.completed_ms >= ? AND e.completed_ms < ?
+		GROUP BY e.reminder_id, e.completed_by`,
+		familyID, reminder.StateAwaitingConfirmation, ceilMilli(from), ceilMilli(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to tally completions: %w", err)
+	}
+	defer rows.Close()
+
+	var tallies []*CompletionTally
+	for rows.Next() {
+		var t CompletionTally
+		var times string
+		if err := rows.Scan(&t.ReminderID, &t.CompletedBy, &times, &t.Points); err != nil {
+			return nil, fmt.Errorf("failed to scan completion tally: %w", err)
+		}
+		for _, v := range strings.Split(times, "|") {
+			at, err := ParseTime(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse completed at: %w", err)
+			}
+			t.Times = append(t.Times, at)
+		}
+		tallies = append(tallies, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sortTallies(tallies)
+	return tallies, nil
+}
+
+// queryCompletionEvents runs a SELECT of the completion event columns
+func (s *SQLiteStorage) queryCompletionEvents(query string, args ...any) ([]*reminder.CompletionEvent, error) {
+	rows, err := s.db.QueryContext(s.ctx(), query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list completion events: %w", err)
 	}
@@ -400,26 +954,26 @@ func (s *SQLiteStorage) ListCompletionEvents(reminderID string) ([]*reminder.Com
 		var e reminder.CompletionEvent
 		var completedAtStr string
 
-		if err := rows.Scan(&e.ID, &e.ReminderID, &completedAtStr, &e.CompletedBy); err != nil {
+		if err := rows.Scan(&e.ID, &e.ReminderID, &completedAtStr, &e.CompletedBy, &e.Impersonator, &e.State, &e.PairID, &e.Points); err != nil {
 			return nil, fmt.Errorf("failed to scan completion event: %w", err)
 		}
 
 		// Parse completed at
-		if e.CompletedAt, err = parseTimeString(completedAtStr); err != nil {
+		if e.CompletedAt, err = ParseTime(completedAtStr); err != nil {
 			return nil, fmt.Errorf("failed to parse completed at: %w", err)
 		}
 
 		events = append(events, &e)
 	}
 
-	return events, nil
+	return events, rows.Err()
 }
 
 func (s *SQLiteStorage) DeleteCompletionEvent(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec("DELETE FROM completion_events WHERE id = ?", id)
+	_, err := s.db.ExecContext(s.ctx(), "DELETE FROM completion_events WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete completion event: %w", err)
 	}
@@ -444,6 +998,148 @@ func (s *SQLiteStorage) SetReminderIDCounter(counter int) error {
 	return s.setCounter("reminder_id", counter)
 }
 
+// Record operations
+func (s *SQLiteStorage) PutRecord(rec *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expiresAt, expiresUnix any
+	if rec.ExpiresAt != nil {
+		expiresAt, expiresUnix = FormatTime(*rec.ExpiresAt), rec.ExpiresAt.Unix()
+	}
+	_, err := s.db.ExecContext(s.ctx(), "INSERT OR REPLACE INTO records (kind, id, family_id, ref, data, created_at, created_unix, expires_at, expires_unix) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		rec.Kind, rec.ID, rec.FamilyID, rec.Ref, string(rec.Data), FormatTime(rec.CreatedAt), rec.CreatedAt.Unix(), expiresAt, expiresUnix)
+	if err != nil {
+		return fmt.Errorf("failed to put record: %w", err)
+	}
+	return nil
+}
+
+// CreateRecord inserts rec, replacing an expired record with its kind and
+// ID but no other
+func (s *SQLiteStorage) CreateRecord(rec *Record, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expiresAt, expiresUnix any
+	if rec.ExpiresAt != nil {
+		expiresAt, expiresUnix = FormatTime(*rec.ExpiresAt), rec.ExpiresAt.Unix()
+	}
+	result, err := s.db.ExecContext(s.ctx(), `INSERT INTO records (kind, id, family_id, ref, data, created_at, created_unix, expires_at, expires_unix) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (kind, id) DO UPDATE SET family_id = excluded.family_id, ref = excluded.ref, data = excluded.data,
+			created_at = excluded.created_at, created_unix = excluded.created_unix, expires_at = excluded.expires_at, expires_unix = excluded.expires_unix
+		WHERE records.expires_unix <= ?`,
+		rec.Kind, rec.ID, rec.FamilyID, rec.Ref, string(rec.Data), FormatTime(rec.CreatedAt), rec.CreatedAt.Unix(), expiresAt, expiresUnix, now.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to create record: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to create record: %w", err)
+	} else if n == 0 {
+		return ErrRecordExists
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) GetRecord(kind, id string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.queryRecords("SELECT "+recordColumns+" FROM records WHERE kind = ? AND id = ?", kind, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	return records[0], nil
+}
+
+func (s *SQLiteStorage) ListRecords(q RecordQuery) ([]*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := "SELECT " + recordColumns + " FROM records WHERE kind = ?"
+	args := []any{q.Kind}
+	if q.FamilyID != "" {
+		query += " AND family_id = ?"
+		args = append(args, q.FamilyID)
+	}
+	if q.Ref != "" {
+		query += " AND ref = ?"
+		args = append(args, q.Ref)
+	}
+	records, err := s.queryRecords(query+" ORDER BY created_unix, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	// created_unix only has second precision
+	sortRecords(records)
+	return records, nil
+}
+
+func (s *SQLiteStorage) DeleteRecord(kind, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.ExecContext(s.ctx(), "DELETE FROM records WHERE kind = ? AND id = ?", kind, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete record: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeExpiredRecords deletes records whose expiry has passed. Records
+// expiring within the current second are left for the next purge.
+func (s *SQLiteStorage) PurgeExpiredRecords(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.ExecContext(s.ctx(), "DELETE FROM records WHERE expires_unix < ?", now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired records: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+const recordColumns = "kind, id, family_id, ref, data, created_at, expires_at"
+
+// queryRecords runs a SELECT of recordColumns
+func (s *SQLiteStorage) queryRecords(query string, args ...any) ([]*Record, error) {
+	rows, err := s.db.QueryContext(s.ctx(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		var rec Record
+		var data, createdAt string
+		var expiresAt sql.NullString
+		if err := rows.Scan(&rec.Kind, &rec.ID, &rec.FamilyID, &rec.Ref, &data, &createdAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan record: %w", err)
+		}
+		rec.Data = json.RawMessage(data)
+		if rec.CreatedAt, err = ParseTime(createdAt); err != nil {
+			return nil, fmt.Errorf("failed to parse record time: %w", err)
+		}
+		if expiresAt.Valid {
+			t, err := ParseTime(expiresAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse record expiry: %w", err)
+			}
+			rec.ExpiresAt = &t
+		}
+		records = append(records, &rec)
+	}
+	return records, rows.Err()
+}
+
 func (s *SQLiteStorage) GetCompletionEventIDCounter() int {
 	return s.getCounter("completion_event_id")
 }
@@ -452,13 +1148,27 @@ func (s *SQLiteStorage) SetCompletionEventIDCounter(counter int) error {
 	return s.setCounter("completion_event_id", counter)
 }
 
+// NextID increments the counter in a single statement, which SQLite runs
+// under its write lock, so other processes sharing the file are safe too
+func (s *SQLiteStorage) NextID(kind IDKind) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var value int
+	err := s.db.QueryRowContext(s.ctx(), "UPDATE counters SET value = value + 1 WHERE name = ? RETURNING value", string(kind)+"_id").Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("unknown ID kind: %s", kind)
+	}
+	return value, err
+}
+
 // Helper methods
 func (s *SQLiteStorage) getCounter(name string) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	var value int
-	err := s.db.QueryRow("SELECT value FROM counters WHERE name = ?", name).Scan(&value)
+	err := s.db.QueryRowContext(s.ctx(), "SELECT value FROM counters WHERE name = ?", name).Scan(&value)
 	if err != nil {
 		return 0
 	}
@@ -469,25 +1179,6 @@ func (s *SQLiteStorage) setCounter(name string, value int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec("UPDATE counters SET value = ? WHERE name = ?", value, name)
+	_, err := s.db.ExecContext(s.ctx(), "UPDATE counters SET value = ? WHERE name = ?", value, name)
 	return err
 }
-
-// parseTimeString parses a time string in ISO 8601 format
-func parseTimeString(timeStr string) (time.Time, error) {
-	// Try multiple time formats
-	formats := []string{
-		"2006-01-02T15:04:05Z07:00",
-		"2006-01-02T15:04:05Z",
-		"2006-01-02T15:04:05",
-		"2006-01-02 15:04:05",
-	}
-
-	for _, format := range formats {
-		if t, err := time.Parse(format, timeStr); err == nil {
-			return t, nil
-		}
-	}
-
-	return time.Time{}, fmt.Errorf("unable to parse time string: %s", timeStr)
-}
@@ -1,7 +1,12 @@
 package storage
 
 import (
+	"bytes"
+	"context"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,6 +28,16 @@ func TestSQLiteStorage(t *testing.T) {
 
 	// Use the shared test helper
 	runStorageTests(t, storage)
+	runTimeRoundTripTests(t, storage)
+	runDSTTests(t, storage)
+
+	if err := storage.Ping(context.Background()); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+	storage.Close()
+	if err := storage.Ping(context.Background()); err == nil {
+		t.Error("expected Ping to fail once the database is closed")
+	}
 }
 
 func TestSQLiteStorageIDGeneration(t *testing.T) {
@@ -38,22 +53,22 @@ func TestSQLiteStorageIDGeneration(t *testing.T) {
 	defer storage.Close()
 
 	// Test ID generation functions
-	familyID1 := GenerateFamilyID(storage)
-	familyID2 := GenerateFamilyID(storage)
+	familyID1 := mustID(GenerateFamilyID(storage))
+	familyID2 := mustID(GenerateFamilyID(storage))
 
 	if familyID1 == familyID2 {
 		t.Error("Generated family IDs should be unique")
 	}
 
-	reminderID1 := GenerateReminderID(storage)
-	reminderID2 := GenerateReminderID(storage)
+	reminderID1 := mustID(GenerateReminderID(storage))
+	reminderID2 := mustID(GenerateReminderID(storage))
 
 	if reminderID1 == reminderID2 {
 		t.Error("Generated reminder IDs should be unique")
 	}
 
-	eventID1 := GenerateCompletionEventID(storage)
-	eventID2 := GenerateCompletionEventID(storage)
+	eventID1 := mustID(GenerateCompletionEventID(storage))
+	eventID2 := mustID(GenerateCompletionEventID(storage))
 
 	if eventID1 == eventID2 {
 		t.Error("Generated completion event IDs should be unique")
@@ -72,8 +87,8 @@ func TestSQLiteStorageIDPersistence(t *testing.T) {
 	}
 
 	// Generate and create a few families, reminders, and completion events
-	fam1 := &family.Family{ID: GenerateFamilyID(storage), Name: "Fam1", Members: []string{"A"}}
-	fam2 := &family.Family{ID: GenerateFamilyID(storage), Name: "Fam2", Members: []string{"B"}}
+	fam1 := &family.Family{ID: mustID(GenerateFamilyID(storage)), Name: "Fam1", Members: []string{"A"}}
+	fam2 := &family.Family{ID: mustID(GenerateFamilyID(storage)), Name: "Fam2", Members: []string{"B"}}
 	if err := storage.CreateFamily(fam1); err != nil {
 		t.Fatalf("CreateFamily fam1 failed: %v", err)
 	}
@@ -83,7 +98,7 @@ func TestSQLiteStorageIDPersistence(t *testing.T) {
 
 	due := time.Now().Add(24 * time.Hour)
 	r1 := &reminder.Reminder{
-		ID:           GenerateReminderID(storage),
+		ID:           mustID(GenerateReminderID(storage)),
 		Title:        "R1",
 		FamilyID:     fam1.ID,
 		FamilyMember: "A",
@@ -91,7 +106,7 @@ func TestSQLiteStorageIDPersistence(t *testing.T) {
 		Recurrence:   reminder.RecurrencePattern{Type: "once"},
 	}
 	r2 := &reminder.Reminder{
-		ID:           GenerateReminderID(storage),
+		ID:           mustID(GenerateReminderID(storage)),
 		Title:        "R2",
 		FamilyID:     fam2.ID,
 		FamilyMember: "B",
@@ -105,8 +120,8 @@ func TestSQLiteStorageIDPersistence(t *testing.T) {
 		t.Fatalf("CreateReminder r2 failed: %v", err)
 	}
 
-	e1 := &reminder.CompletionEvent{ID: GenerateCompletionEventID(storage), ReminderID: r1.ID, CompletedBy: "A", CompletedAt: time.Now()}
-	e2 := &reminder.CompletionEvent{ID: GenerateCompletionEventID(storage), ReminderID: r2.ID, CompletedBy: "B", CompletedAt: time.Now()}
+	e1 := &reminder.CompletionEvent{ID: mustID(GenerateCompletionEventID(storage)), ReminderID: r1.ID, CompletedBy: "A", CompletedAt: time.Now()}
+	e2 := &reminder.CompletionEvent{ID: mustID(GenerateCompletionEventID(storage)), ReminderID: r2.ID, CompletedBy: "B", CompletedAt: time.Now()}
 	if err := storage.CreateCompletionEvent(e1); err != nil {
 		t.Fatalf("CreateCompletionEvent e1 failed: %v", err)
 	}
@@ -146,15 +161,15 @@ func TestSQLiteStorageIDPersistence(t *testing.T) {
 	}
 
 	// Generate new IDs and check they increment
-	newFamID := GenerateFamilyID(storage2)
+	newFamID := mustID(GenerateFamilyID(storage2))
 	if newFamID != "fam3" {
 		t.Errorf("Next family ID after reload: got %s, want fam3", newFamID)
 	}
-	newRemID := GenerateReminderID(storage2)
+	newRemID := mustID(GenerateReminderID(storage2))
 	if newRemID != "rem3" {
 		t.Errorf("Next reminder ID after reload: got %s, want rem3", newRemID)
 	}
-	newCevID := GenerateCompletionEventID(storage2)
+	newCevID := mustID(GenerateCompletionEventID(storage2))
 	if newCevID != "cev3" {
 		t.Errorf("Next completion event ID after reload: got %s, want cev3", newCevID)
 	}
@@ -330,3 +345,74 @@ func TestSQLiteStorageRecurrenceEndDateHandling(t *testing.T) {
 	storage.DeleteReminder("rem2")
 	storage.DeleteFamily("fam1")
 }
+
+func TestSQLiteStorageBackup(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSQLiteStorage(filepath.Join(dir, "live.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}}); err != nil {
+		t.Fatalf("CreateFamily failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Backup(&buf); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	copyPath := filepath.Join(dir, "copy.db")
+	if err := os.WriteFile(copyPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := NewSQLiteStorage(copyPath)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer restored.Close()
+	if f, err := restored.GetFamily("fam1"); err != nil || f.Name != "Smith" {
+		t.Errorf("backup is missing data: %+v, %v", f, err)
+	}
+}
+
+func TestSQLiteStorageVerifySchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "verify.db")
+	store, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	defer store.Close()
+	if err := store.VerifySchema(); err != nil {
+		t.Fatalf("VerifySchema of a fresh database: %v", err)
+	}
+
+	// A newer release has migrated the database further
+	if _, err := store.db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", len(sqliteMigrations)+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.VerifySchema(); err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Errorf("expected a schema version error, got %v", err)
+	}
+	if problems := Verify(store); len(problems) != 1 {
+		t.Errorf("Verify: got %v, want the schema problem only", problems)
+	}
+}
+
+func TestSQLiteStorageSaveRemindersAtomic(t *testing.T) {
+	store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "batch.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	defer store.Close()
+	good := testReminder()
+	// NaN can't be encoded as JSON, failing the second write
+	bad := testReminder()
+	bad.ID = "rem2"
+	bad.Condition = &reminder.Condition{Provider: "weather", Location: "0,0", Metric: "rain", Operator: "lt", Value: math.NaN()}
+	if n, err := store.SaveReminders([]*reminder.Reminder{good, bad}); err == nil || n != 0 {
+		t.Fatalf("SaveReminders: got %d, %v, want a failure", n, err)
+	}
+	if _, err := store.GetReminder(good.ID); err == nil {
+		t.Error("the reminder before the failing one was saved")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reminder-app/internal/family"
 	"reminder-app/internal/reminder"
+	"sort"
+	"time"
 )
 
 // Storage defines the interface for data persistence
@@ -13,20 +15,45 @@ type Storage interface {
 	CreateFamily(f *family.Family) error
 	GetFamily(id string) (*family.Family, error)
 	ListFamilies() ([]*family.Family, error)
+	UpdateFamily(f *family.Family) error
 	DeleteFamily(id string) error
 
 	// Reminder operations
 	CreateReminder(r *reminder.Reminder) error
 	GetReminder(id string) (*reminder.Reminder, error)
 	ListReminders() ([]*reminder.Reminder, error)
+	// QueryReminders returns the reminders selected by f, filtering in the
+	// database where the backend supports it
+	QueryReminders(f ReminderFilter) ([]*reminder.Reminder, error)
 	DeleteReminder(id string) error
 
 	// CompletionEvent operations
 	CreateCompletionEvent(e *reminder.CompletionEvent) error
 	GetCompletionEvent(id string) (*reminder.CompletionEvent, error)
 	ListCompletionEvents(reminderID string) ([]*reminder.CompletionEvent, error)
+	// ListAllCompletionEvents returns the events of every reminder,
+	// including any whose reminder no longer exists
+	ListAllCompletionEvents() ([]*reminder.CompletionEvent, error)
+	QueryCompletionEvents(q CompletionEventQuery) ([]*reminder.CompletionEvent, error)
+	// GetLatestCompletionEvent returns the most recent completion of a
+	// reminder, or nil if it has never been completed
+	GetLatestCompletionEvent(reminderID string) (*reminder.CompletionEvent, error)
 	DeleteCompletionEvent(id string) error
 
+	// Record operations for auxiliary data; see Record
+	PutRecord(rec *Record) error
+	GetRecord(kind, id string) (*Record, error)
+	ListRecords(q RecordQuery) ([]*Record, error)
+	DeleteRecord(kind, id string) error
+	// PurgeExpiredRecords deletes every record that has expired at now and
+	// returns how many were removed
+	PurgeExpiredRecords(now time.Time) (int, error)
+
+	// NextID atomically increments the ID counter of kind and returns its
+	// new value, so concurrent callers, even on other replicas sharing the
+	// database, never receive the same ID
+	NextID(kind IDKind) (int, error)
+
 	// ID counter operations
 	GetFamilyIDCounter() int
 	SetFamilyIDCounter(counter int) error
@@ -36,26 +63,104 @@ type Storage interface {
 	SetCompletionEventIDCounter(counter int) error
 }
 
-func GenerateFamilyID(s Storage) string {
-	// Generate a new family ID
-	counter := s.GetFamilyIDCounter()
-	counter++
-	s.SetFamilyIDCounter(counter)
-	return fmt.Sprintf("fam%d", counter)
+// IDKind names one of the sequential ID counters
+type IDKind string
+
+const (
+	FamilyIDs          IDKind = "family"
+	ReminderIDs        IDKind = "reminder"
+	CompletionEventIDs IDKind = "completion_event"
+)
+
+// idPrefixes are the prefixes of the IDs each counter generates
+var idPrefixes = map[IDKind]string{
+	FamilyIDs:          "fam",
+	ReminderIDs:        "rem",
+	CompletionEventIDs: "cev",
+}
+
+// GenerateID returns a new ID of the given kind, like "fam12"
+func GenerateID(s Storage, kind IDKind) (string, error) {
+	prefix, ok := idPrefixes[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown ID kind: %s", kind)
+	}
+	n, err := s.NextID(kind)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s ID: %w", kind, err)
+	}
+	return fmt.Sprintf("%s%d", prefix, n), nil
+}
+
+func GenerateFamilyID(s Storage) (string, error) {
+	return GenerateID(s, FamilyIDs)
+}
+
+func GenerateReminderID(s Storage) (string, error) {
+	return GenerateID(s, ReminderIDs)
+}
+
+func GenerateCompletionEventID(s Storage) (string, error) {
+	return GenerateID(s, CompletionEventIDs)
+}
+
+// CompletionEventQuery selects the completion events of a reminder that
+// happened in [From, To). A zero From or To leaves that end open. Results
+// are ordered oldest first; AfterTime and AfterID resume a previous page
+// after the event at that position, and Limit caps the page size.
+type CompletionEventQuery struct {
+	ReminderID string
+	From       time.Time
+	To         time.Time
+	AfterTime  time.Time
+	AfterID    string
+	// Limit is the maximum number of events returned; zero means no limit
+	Limit int
+}
+
+// Matches reports whether e is selected by the query
+func (q CompletionEventQuery) Matches(e *reminder.CompletionEvent) bool {
+	if e.ReminderID != q.ReminderID {
+		return false
+	}
+	if !q.From.IsZero() && e.CompletedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !e.CompletedAt.Before(q.To) {
+		return false
+	}
+	if q.AfterID != "" {
+		if e.CompletedAt.Before(q.AfterTime) {
+			return false
+		}
+		if e.CompletedAt.Equal(q.AfterTime) && e.ID <= q.AfterID {
+			return false
+		}
+	}
+	return true
+}
+
+// limit truncates sorted events to the query's limit
+func (q CompletionEventQuery) limit(events []*reminder.CompletionEvent) []*reminder.CompletionEvent {
+	if q.Limit > 0 && len(events) > q.Limit {
+		return events[:q.Limit]
+	}
+	return events
 }
 
-func GenerateReminderID(s Storage) string {
-	// Generate a new reminder ID
-	counter := s.GetReminderIDCounter()
-	counter++
-	s.SetReminderIDCounter(counter)
-	return fmt.Sprintf("rem%d", counter)
+// laterCompletionEvent reports whether a comes after b in the ordering used
+// by sortCompletionEvents
+func laterCompletionEvent(a, b *reminder.CompletionEvent) bool {
+	if a.CompletedAt.Equal(b.CompletedAt) {
+		return a.ID > b.ID
+	}
+	return a.CompletedAt.After(b.CompletedAt)
 }
 
-func GenerateCompletionEventID(s Storage) string {
-	// Generate a new completion event ID
-	counter := s.GetCompletionEventIDCounter()
-	counter++
-	s.SetCompletionEventIDCounter(counter)
-	return fmt.Sprintf("cev%d", counter)
+// sortCompletionEvents orders events oldest first, breaking ties by ID so
+// results are stable across backends
+func sortCompletionEvents(events []*reminder.CompletionEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		return laterCompletionEvent(events[j], events[i])
+	})
 }
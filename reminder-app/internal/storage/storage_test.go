@@ -1,10 +1,20 @@
 package storage
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"reflect"
+	"reminder-app/internal/breaker"
 	"reminder-app/internal/family"
 	"reminder-app/internal/reminder"
+	"reminder-app/internal/tztest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -65,6 +75,21 @@ func runStorageTests(t *testing.T, store Storage) {
 	if err != nil || len(fams) != 1 {
 		t.Errorf("ListFamilies: got %d, want 1", len(fams))
 	}
+	f.Name = "Renamed Family"
+	f.Settings.Channels = []family.Channel{{Type: "matrix", Target: "!room:example.org"}}
+	if err := store.UpdateFamily(f); err != nil {
+		t.Fatalf("UpdateFamily failed: %v", err)
+	}
+	gotFam, err = store.GetFamily(f.ID)
+	if err != nil {
+		t.Fatalf("GetFamily after update failed: %v", err)
+	}
+	if !reflect.DeepEqual(gotFam, f) {
+		t.Errorf("UpdateFamily: got %+v, want %+v", gotFam, f)
+	}
+	if err := store.UpdateFamily(&family.Family{ID: "missing"}); err == nil {
+		t.Error("expected error updating a missing family")
+	}
 	if err := store.DeleteFamily(f.ID); err != nil {
 		t.Errorf("DeleteFamily failed: %v", err)
 	}
@@ -97,6 +122,18 @@ func runStorageTests(t *testing.T, store Storage) {
 	r.CompletedAt = &completedTime
 	r.Recurrence.Type = "weekly"
 	r.Recurrence.Days = []string{"monday", "wednesday"}
+	r.Recurrence.Interval = 2
+	r.RequiresConfirmation = true
+	r.Timezone = "Europe/Berlin"
+	r.Condition = &reminder.Condition{Provider: "weather", Location: "52.52,13.41", Metric: "precipitation", Operator: "lte", Window: "48h"}
+	r.Usage = &reminder.Usage{Counter: "odometer", Unit: "km", Interval: 8000, Baseline: 41250.5, Reading: 44000}
+	r.Rotation = &reminder.Rotation{Members: []string{"Alice", "Bob"}}
+	r.Escalation = &reminder.Escalation{RepeatMinutes: 30, Steps: []reminder.EscalationStep{{AfterMinutes: 120, Member: "Bob"}}}
+	r.Tags = []string{"school", "car"}
+	r.Priority = reminder.PriorityUrgent
+	r.Points = 15
+	r.Exceptions = []time.Time{time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)}
+	r.Version = 2
 
 	if err := store.CreateReminder(r); err != nil {
 		t.Fatalf("UpdateReminder (via CreateReminder) failed: %v", err)
@@ -117,6 +154,36 @@ func runStorageTests(t *testing.T, store Storage) {
 	if !updatedRem.Completed {
 		t.Error("Update failed - Completed should be true")
 	}
+	if !updatedRem.RequiresConfirmation {
+		t.Error("Update failed - RequiresConfirmation should be true")
+	}
+	if updatedRem.Timezone != "Europe/Berlin" {
+		t.Errorf("Update failed - Timezone: got %q, want 'Europe/Berlin'", updatedRem.Timezone)
+	}
+	if updatedRem.Condition == nil || *updatedRem.Condition != *r.Condition {
+		t.Errorf("Update failed - Condition: got %+v, want %+v", updatedRem.Condition, r.Condition)
+	}
+	if updatedRem.Usage == nil || *updatedRem.Usage != *r.Usage {
+		t.Errorf("Update failed - Usage: got %+v, want %+v", updatedRem.Usage, r.Usage)
+	}
+	if updatedRem.Rotation == nil || !slices.Equal(updatedRem.Rotation.Members, r.Rotation.Members) {
+		t.Errorf("Update failed - Rotation: got %+v, want %+v", updatedRem.Rotation, r.Rotation)
+	}
+	if updatedRem.Escalation == nil || !reflect.DeepEqual(*updatedRem.Escalation, *r.Escalation) {
+		t.Errorf("Update failed - Escalation: got %+v, want %+v", updatedRem.Escalation, r.Escalation)
+	}
+	if !slices.Equal(updatedRem.Tags, r.Tags) {
+		t.Errorf("Update failed - Tags: got %v, want %v", updatedRem.Tags, r.Tags)
+	}
+	if !slices.EqualFunc(updatedRem.Exceptions, r.Exceptions, time.Time.Equal) {
+		t.Errorf("Update failed - Exceptions: got %v, want %v", updatedRem.Exceptions, r.Exceptions)
+	}
+	if updatedRem.Priority != reminder.PriorityUrgent {
+		t.Errorf("Update failed - Priority: got %q, want %q", updatedRem.Priority, reminder.PriorityUrgent)
+	}
+	if updatedRem.Points != 15 {
+		t.Errorf("Update failed - Points: got %d, want 15", updatedRem.Points)
+	}
 	if updatedRem.CompletedAt == nil {
 		t.Error("Update failed - CompletedAt should not be nil")
 	}
@@ -126,6 +193,12 @@ func runStorageTests(t *testing.T, store Storage) {
 	if len(updatedRem.Recurrence.Days) != 2 || updatedRem.Recurrence.Days[0] != "monday" || updatedRem.Recurrence.Days[1] != "wednesday" {
 		t.Errorf("Update failed - Recurrence days: got %v, want ['monday', 'wednesday']", updatedRem.Recurrence.Days)
 	}
+	if updatedRem.Recurrence.Interval != 2 {
+		t.Errorf("Update failed - Recurrence interval: got %d, want 2", updatedRem.Recurrence.Interval)
+	}
+	if updatedRem.Version != 2 {
+		t.Errorf("Update failed - Version: got %d, want 2", updatedRem.Version)
+	}
 
 	// Verify we still have only one reminder (not a duplicate)
 	rems, err := store.ListReminders()
@@ -171,6 +244,8 @@ func runStorageTests(t *testing.T, store Storage) {
 
 	// Test updating an existing completion event (upsert functionality)
 	e.CompletedBy = "Bob"
+	e.Impersonator = "Alice"
+	e.State, e.PairID = reminder.StateConfirmed, "ce-pair"
 	newCompletedTime := time.Now().Add(time.Hour)
 	e.CompletedAt = newCompletedTime
 
@@ -184,8 +259,11 @@ func runStorageTests(t *testing.T, store Storage) {
 		t.Fatalf("GetCompletionEvent after update failed: %v", err)
 	}
 
-	if updatedEv.CompletedBy != "Bob" {
-		t.Errorf("Update failed - CompletedBy: got %s, want 'Bob'", updatedEv.CompletedBy)
+	if updatedEv.CompletedBy != "Bob" || updatedEv.Impersonator != "Alice" {
+		t.Errorf("Update failed - CompletedBy: got %s by %q, want 'Bob' by 'Alice'", updatedEv.CompletedBy, updatedEv.Impersonator)
+	}
+	if updatedEv.State != reminder.StateConfirmed || updatedEv.PairID != "ce-pair" {
+		t.Errorf("Update failed - got state %q paired with %q", updatedEv.State, updatedEv.PairID)
 	}
 
 	// Allow for some time difference due to precision
@@ -200,6 +278,59 @@ func runStorageTests(t *testing.T, store Storage) {
 		t.Errorf("ListCompletionEvents after update: got %d, want 1", len(evs))
 	}
 
+	// Range queries are ordered and compare instants across time zones
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	berlin := time.FixedZone("CET", 3600)
+	for i, at := range []time.Time{base.Add(2 * time.Hour), base, base.Add(time.Hour).In(berlin)} {
+		ev := &reminder.CompletionEvent{ID: fmt.Sprintf("cev1%d", i), ReminderID: r.ID, CompletedAt: at, CompletedBy: "Alice", Points: i + 1}
+		if err := store.CreateCompletionEvent(ev); err != nil {
+			t.Fatalf("CreateCompletionEvent failed: %v", err)
+		}
+	}
+	ranged, err := store.QueryCompletionEvents(CompletionEventQuery{ReminderID: r.ID, From: base, To: base.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatalf("QueryCompletionEvents failed: %v", err)
+	}
+	if len(ranged) != 2 || ranged[0].ID != "cev11" || ranged[1].ID != "cev12" {
+		t.Errorf("QueryCompletionEvents: got %v, want [cev11 cev12]", ranged)
+	}
+	page, err := store.QueryCompletionEvents(CompletionEventQuery{ReminderID: r.ID, AfterTime: ranged[0].CompletedAt, AfterID: ranged[0].ID, Limit: 1})
+	if err != nil || len(page) != 1 || page[0].ID != "cev12" {
+		t.Errorf("QueryCompletionEvents after cursor: got %v, %v, want [cev12]", page, err)
+	}
+
+	// Tallies group a family's completions by reminder and member, leaving
+	// out first sign-offs
+	_ = store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "cev13", ReminderID: r.ID, CompletedAt: base.Add(30 * time.Minute), CompletedBy: "Bob", Points: 5})
+	_ = store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "cev14", ReminderID: r.ID, CompletedAt: base.Add(90 * time.Minute), CompletedBy: "Bob", State: reminder.StateAwaitingConfirmation, Points: 7})
+	tallies, err := TallyCompletions(store, r.FamilyID, base, base.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("TallyCompletions failed: %v", err)
+	}
+	if len(tallies) != 2 || tallies[0].CompletedBy != "Alice" || len(tallies[0].Times) != 3 || tallies[1].CompletedBy != "Bob" || len(tallies[1].Times) != 1 {
+		t.Fatalf("TallyCompletions: got %+v, want 3 by Alice and 1 by Bob", tallies)
+	}
+	if !tallies[0].Times[0].Equal(base) || !tallies[0].Times[2].Equal(base.Add(2*time.Hour)) || !tallies[1].Times[0].Equal(base.Add(30*time.Minute)) {
+		t.Errorf("TallyCompletions: got times %v and %v", tallies[0].Times, tallies[1].Times)
+	}
+	if tallies[0].Points != 6 || tallies[1].Points != 5 {
+		t.Errorf("TallyCompletions: got %d points for Alice and %d for Bob, want 6 and 5", tallies[0].Points, tallies[1].Points)
+	}
+	if tallies, err := TallyCompletions(store, "no-such-family", base, base.Add(3*time.Hour)); err != nil || len(tallies) != 0 {
+		t.Errorf("TallyCompletions of another family: got %+v, %v, want none", tallies, err)
+	}
+
+	latest, err := store.GetLatestCompletionEvent(r.ID)
+	if err != nil || latest == nil || latest.ID != e.ID {
+		t.Errorf("GetLatestCompletionEvent: got %v, %v, want %s", latest, err, e.ID)
+	}
+	for i := 0; i < 5; i++ {
+		store.DeleteCompletionEvent(fmt.Sprintf("cev1%d", i))
+	}
+	if none, err := store.GetLatestCompletionEvent("no-such-reminder"); none != nil || err != nil {
+		t.Errorf("GetLatestCompletionEvent without events: got %v, %v, want nil", none, err)
+	}
+
 	if err := store.DeleteCompletionEvent(e.ID); err != nil {
 		t.Errorf("DeleteCompletionEvent failed: %v", err)
 	}
@@ -208,6 +339,33 @@ func runStorageTests(t *testing.T, store Storage) {
 		t.Errorf("expected error after DeleteCompletionEvent, got nil")
 	}
 
+	// Events within one second are told apart, by time before ID
+	for id, ms := range map[string]int{"cev9": 100, "cev10": 900} {
+		ev := &reminder.CompletionEvent{ID: id, ReminderID: r.ID, CompletedAt: base.Add(time.Duration(ms) * time.Millisecond), CompletedBy: "Alice"}
+		if err := store.CreateCompletionEvent(ev); err != nil {
+			t.Fatalf("CreateCompletionEvent failed: %v", err)
+		}
+	}
+	mid := base.Add(500 * time.Millisecond)
+	for _, tt := range []struct {
+		q    CompletionEventQuery
+		want string
+	}{
+		{CompletionEventQuery{ReminderID: r.ID, From: mid}, "cev10"},
+		{CompletionEventQuery{ReminderID: r.ID, To: mid}, "cev9"},
+		{CompletionEventQuery{ReminderID: r.ID, Limit: 1}, "cev9"},
+		{CompletionEventQuery{ReminderID: r.ID, AfterTime: base.Add(100 * time.Millisecond), AfterID: "cev9"}, "cev10"},
+	} {
+		if got, err := store.QueryCompletionEvents(tt.q); err != nil || len(got) != 1 || got[0].ID != tt.want {
+			t.Errorf("QueryCompletionEvents(%+v): got %v, %v, want [%s]", tt.q, got, err, tt.want)
+		}
+	}
+	if latest, err := store.GetLatestCompletionEvent(r.ID); err != nil || latest == nil || latest.ID != "cev10" {
+		t.Errorf("GetLatestCompletionEvent within one second: got %v, %v, want cev10", latest, err)
+	}
+	store.DeleteCompletionEvent("cev9")
+	store.DeleteCompletionEvent("cev10")
+
 	// Test reminder with null due date
 	nullDueReminder := testReminderWithNullDueDate()
 	if err := store.CreateReminder(nullDueReminder); err != nil {
@@ -253,14 +411,320 @@ func runStorageTests(t *testing.T, store Storage) {
 		t.Errorf("DeleteReminder for null due date failed: %v", err)
 	}
 
+	// Records
+	type note struct{ Text string }
+	for i, ref := range []string{r.ID, "other", r.ID} {
+		rec := Record{Kind: "note", ID: fmt.Sprintf("n%d", i), FamilyID: f.ID, Ref: ref, CreatedAt: base.Add(time.Duration(2-i) * time.Minute)}
+		if err := PutJSON(store, rec, note{Text: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("PutJSON failed: %v", err)
+		}
+	}
+	got, err := GetJSON[note](store, "note", "n1")
+	if err != nil || got.Text != "1" {
+		t.Errorf("GetJSON: got %v, %v", got, err)
+	}
+	if _, err := store.GetRecord("other-kind", "n1"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetRecord of another kind: got %v, want ErrRecordNotFound", err)
+	}
+	notes, err := ListJSON[note](store, RecordQuery{Kind: "note", Ref: r.ID})
+	if err != nil || len(notes) != 2 || notes[0].Text != "2" || notes[1].Text != "0" {
+		t.Errorf("ListJSON: got %v, %v, want notes 2 and 0 oldest first", notes, err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := store.DeleteRecord("note", fmt.Sprintf("n%d", i)); err != nil {
+			t.Errorf("DeleteRecord failed: %v", err)
+		}
+	}
+	if err := store.DeleteRecord("note", "n0"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("DeleteRecord twice: got %v, want ErrRecordNotFound", err)
+	}
+
+	// Reminder queries
+	// Due dates carry an offset other than UTC, as the SQL backends store
+	// them as written
+	due := func(d time.Duration) *time.Time {
+		t := base.Add(d).In(time.FixedZone("", 2*3600))
+		return &t
+	}
+	queried := []*reminder.Reminder{
+		{ID: "rem101", Title: "Bins", FamilyID: "famq", FamilyMember: "Alice", DueDate: due(0), Recurrence: reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday"}}, Tags: []string{"chores"}},
+		{ID: "rem102", Title: "Vet", FamilyID: "famq", FamilyMember: "Bob", DueDate: due(48 * time.Hour), Recurrence: reminder.RecurrencePattern{Type: "once"}, Tags: []string{"pets", "chores-extra"}, SeriesID: "rem101"},
+		{ID: "rem103", Title: "Call", FamilyID: "famq", FamilyMember: "Alice", Completed: true, Recurrence: reminder.RecurrencePattern{Type: "once"}},
+		{ID: "rem105", Title: "Snoozed", FamilyID: "famq", FamilyMember: "Carol", DueDate: due(-24 * time.Hour), SnoozedUntil: due(72 * time.Hour), Recurrence: reminder.RecurrencePattern{Type: "once"}},
+		{ID: "rem104", Title: "Elsewhere", FamilyID: "fam2", FamilyMember: "Alice", DueDate: due(0), Recurrence: reminder.RecurrencePattern{Type: "once"}},
+	}
+	for _, q := range queried {
+		if err := store.CreateReminder(q); err != nil {
+			t.Fatalf("CreateReminder failed: %v", err)
+		}
+	}
+	yes, no := true, false
+	for _, tc := range []struct {
+		name   string
+		filter ReminderFilter
+		want   []string
+	}{
+		{"family", ReminderFilter{FamilyID: "famq"}, []string{"rem101", "rem102", "rem103", "rem105"}},
+		{"member", ReminderFilter{FamilyID: "famq", FamilyMember: "Alice"}, []string{"rem101", "rem103"}},
+		{"completed", ReminderFilter{FamilyID: "famq", Completed: &yes}, []string{"rem103"}},
+		{"not completed", ReminderFilter{FamilyID: "famq", Completed: &no}, []string{"rem101", "rem102", "rem105"}},
+		{"recurrence", ReminderFilter{FamilyID: "famq", RecurrenceType: "weekly"}, []string{"rem101"}},
+		{"tag", ReminderFilter{FamilyID: "famq", Tag: "chores"}, []string{"rem101"}},
+		{"second tag", ReminderFilter{Tag: "pets"}, []string{"rem102"}},
+		{"series", ReminderFilter{SeriesID: "rem101"}, []string{"rem101", "rem102"}},
+		{"due after is inclusive", ReminderFilter{FamilyID: "famq", DueAfter: due(48 * time.Hour)}, []string{"rem102", "rem105"}},
+		{"due after a snooze ends", ReminderFilter{FamilyID: "famq", DueAfter: due(72 * time.Hour)}, []string{"rem105"}},
+		{"due before is exclusive", ReminderFilter{FamilyID: "famq", DueBefore: due(48 * time.Hour)}, []string{"rem101"}},
+		{"due before within a second", ReminderFilter{FamilyID: "famq", DueBefore: due(time.Millisecond)}, []string{"rem101"}},
+		{"due after within a second", ReminderFilter{FamilyID: "famq", DueAfter: due(time.Millisecond), DueBefore: due(time.Hour)}, nil},
+	} {
+		list, err := store.QueryReminders(tc.filter)
+		if err != nil {
+			t.Errorf("QueryReminders %s failed: %v", tc.name, err)
+			continue
+		}
+		var got []string
+		for _, r := range list {
+			got = append(got, r.ID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("QueryReminders %s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	for _, q := range queried {
+		store.DeleteReminder(q.ID)
+	}
+
+	// Saving reminders in a batch
+	var batch []*reminder.Reminder
+	for i := 0; i < 3; i++ {
+		b := testReminder()
+		b.ID = fmt.Sprintf("rem%d", 201+i)
+		b.Title = fmt.Sprintf("Batch %d", i)
+		batch = append(batch, b)
+	}
+	if n, err := SaveReminders(store, batch); err != nil || n != len(batch) {
+		t.Errorf("SaveReminders: got %d, %v, want %d", n, err, len(batch))
+	}
+	for _, b := range batch {
+		if got, err := store.GetReminder(b.ID); err != nil || got.Title != b.Title {
+			t.Errorf("GetReminder of batch reminder %s: got %+v, %v", b.ID, got, err)
+		}
+		store.DeleteReminder(b.ID)
+	}
+
+	// Saving reminders only over the version they were edited from
+	u := testReminder()
+	u.ID, u.Version = "rem301", 1
+	if n, err := UpdateReminders(store, u); err != nil || n != 1 {
+		t.Errorf("UpdateReminders of a new reminder: got %d, %v", n, err)
+	}
+	current, stale := *u, *u
+	current.Title, current.Version = "Current", 2
+	stale.Title, stale.Version = "Stale", 2
+	if n, err := UpdateReminders(store, &current); err != nil || n != 1 {
+		t.Errorf("UpdateReminders: got %d, %v", n, err)
+	}
+	if n, err := UpdateReminders(store, &stale); !errors.Is(err, ErrVersionConflict) || n != 0 {
+		t.Errorf("UpdateReminders over a newer version: got %d, %v, want a conflict", n, err)
+	}
+	if got, err := store.GetReminder(u.ID); err != nil || got.Title != "Current" || got.Version != 2 {
+		t.Errorf("GetReminder after a conflict: got %+v, %v", got, err)
+	}
+	store.DeleteReminder(u.ID)
+
+	// Expiring records
+	now := time.Now()
+	past, future := now.Add(-time.Hour), NormalizeTime(now.Add(time.Hour))
+	for id, expires := range map[string]*time.Time{"e0": &past, "e1": &future, "e2": nil} {
+		rec := Record{Kind: "note", ID: id, FamilyID: f.ID, CreatedAt: base, ExpiresAt: expires}
+		if err := PutJSON(store, rec, note{Text: id}); err != nil {
+			t.Fatalf("PutJSON failed: %v", err)
+		}
+	}
+	if rec, err := store.GetRecord("note", "e1"); err != nil || rec.ExpiresAt == nil || !rec.ExpiresAt.Equal(future) {
+		t.Errorf("GetRecord expiry: got %+v, %v, want %s", rec, err, future)
+	}
+	if n, err := store.PurgeExpiredRecords(now); err != nil || n != 1 {
+		t.Errorf("PurgeExpiredRecords: got %d, %v, want 1", n, err)
+	}
+	if _, err := store.GetRecord("note", "e0"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expired record still present: %v", err)
+	}
+	// Creating a record only replaces one that has expired
+	if err := CreateJSON(store, Record{Kind: "note", ID: "e1", CreatedAt: base}, note{Text: "again"}, now); !errors.Is(err, ErrRecordExists) {
+		t.Errorf("CreateJSON over a live record: got %v, want ErrRecordExists", err)
+	}
+	if err := CreateJSON(store, Record{Kind: "note", ID: "e1", CreatedAt: base}, note{Text: "later"}, future); err != nil {
+		t.Errorf("CreateJSON over an expired record failed: %v", err)
+	}
+	if got, err := GetJSON[note](store, "note", "e1"); err != nil || got.Text != "later" {
+		t.Errorf("GetJSON after replacing an expired record: got %+v, %v", got, err)
+	}
+	if err := CreateJSON(store, Record{Kind: "note", ID: "e3", CreatedAt: base}, note{Text: "e3"}, now); err != nil {
+		t.Errorf("CreateJSON of a new record failed: %v", err)
+	}
+	for _, id := range []string{"e1", "e2", "e3"} {
+		if err := store.DeleteRecord("note", id); err != nil {
+			t.Errorf("DeleteRecord of unexpired record %s failed: %v", id, err)
+		}
+	}
+
+	// Concurrent ID generation never hands out the same ID twice
+	var wg sync.WaitGroup
+	ids := make(chan string, 50)
+	for i := 0; i < cap(ids); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := GenerateReminderID(store)
+			if err != nil {
+				t.Errorf("GenerateReminderID failed: %v", err)
+			}
+			ids <- id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	seen := make(map[string]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("GenerateReminderID returned %s twice", id)
+		}
+		seen[id] = true
+	}
+	if _, err := store.NextID("widget"); err == nil {
+		t.Error("NextID of an unknown kind succeeded")
+	}
+
 	// Clean up the reminder we recreated
 	store.DeleteReminder(r.ID)
 	store.DeleteFamily(f.ID)
 }
 
+// mustID returns a generated ID, panicking if generation failed
+func mustID(id string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// runTimeRoundTripTests checks that a backend which serializes times reads
+// back exactly NormalizeTime of what was written, whatever the zone and
+// precision of the original
+func runTimeRoundTripTests(t *testing.T, store Storage) {
+	cet := time.FixedZone("CET", 3600)
+	times := []time.Time{
+		time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		time.Date(2025, 7, 14, 23, 59, 59, 999999999, cet),
+		time.Now(),
+	}
+	check := func(what string, got *time.Time, want time.Time) {
+		t.Helper()
+		if got == nil {
+			t.Errorf("%s: got nil, want %s", what, NormalizeTime(want))
+			return
+		}
+		if !got.Equal(NormalizeTime(want)) {
+			t.Errorf("%s: got %s, want %s", what, got, NormalizeTime(want))
+		}
+	}
+
+	f := &family.Family{ID: "famt", Name: "Time", Members: []string{"Alice"}}
+	if err := store.CreateFamily(f); err != nil {
+		t.Fatalf("CreateFamily failed: %v", err)
+	}
+	defer store.DeleteFamily(f.ID)
+	for i, at := range times {
+		id := fmt.Sprintf("t%d", i)
+		r := &reminder.Reminder{ID: fmt.Sprintf("rem9%d", i), Title: id, DueDate: &at, CompletedAt: &at, SnoozedUntil: &at, Completed: true,
+			FamilyID: f.ID, FamilyMember: "Alice", Recurrence: reminder.RecurrencePattern{Type: "once"}}
+		if err := store.CreateReminder(r); err != nil {
+			t.Fatalf("CreateReminder failed: %v", err)
+		}
+		got, err := store.GetReminder(r.ID)
+		if err != nil {
+			t.Fatalf("GetReminder failed: %v", err)
+		}
+		check(id+" due date", got.DueDate, at)
+		check(id+" completed at", got.CompletedAt, at)
+		check(id+" snoozed until", got.SnoozedUntil, at)
+		store.DeleteReminder(r.ID)
+
+		e := &reminder.CompletionEvent{ID: fmt.Sprintf("cev9%d", i), ReminderID: r.ID, CompletedAt: at, CompletedBy: "Alice"}
+		if err := store.CreateCompletionEvent(e); err != nil {
+			t.Fatalf("CreateCompletionEvent failed: %v", err)
+		}
+		gotEvent, err := store.GetCompletionEvent(e.ID)
+		if err != nil {
+			t.Fatalf("GetCompletionEvent failed: %v", err)
+		}
+		check(id+" event completed at", &gotEvent.CompletedAt, at)
+		store.DeleteCompletionEvent(e.ID)
+
+		expires := at.Add(time.Hour)
+		if err := store.PutRecord(&Record{Kind: "note", ID: id, FamilyID: f.ID, Data: []byte("{}"), CreatedAt: at, ExpiresAt: &expires}); err != nil {
+			t.Fatalf("PutRecord failed: %v", err)
+		}
+		rec, err := store.GetRecord("note", id)
+		if err != nil {
+			t.Fatalf("GetRecord failed: %v", err)
+		}
+		check(id+" record created at", &rec.CreatedAt, at)
+		check(id+" record expires at", rec.ExpiresAt, expires)
+		store.DeleteRecord("note", id)
+	}
+}
+
+// runDSTTests checks that recurring reminders with a time zone read back
+// from a backend keep their occurrences across daylight saving changes,
+// including those at times the changes skip or repeat
+func runDSTTests(t *testing.T, store Storage) {
+	f := &family.Family{ID: "famdst", Name: "DST", Members: []string{"Alice"}}
+	if err := store.CreateFamily(f); err != nil {
+		t.Fatalf("CreateFamily failed: %v", err)
+	}
+	defer store.DeleteFamily(f.ID)
+	for i, tr := range tztest.All(t) {
+		wall := tr.Affected()
+		loc := tr.Zone
+		due := time.Date(wall.Year(), wall.Month(), wall.Day()-28, wall.Hour(), wall.Minute(), 0, 0, loc)
+		from := time.Date(wall.Year(), wall.Month()-1, wall.Day(), 12, 0, 0, 0, loc)
+		to := time.Date(wall.Year(), wall.Month()+1, wall.Day()+1, 12, 0, 0, 0, loc)
+		for j, pattern := range []reminder.RecurrencePattern{
+			{Type: "weekly", Days: []string{strings.ToLower(wall.Weekday().String())}},
+			{Type: "monthly", Date: wall.Day()},
+		} {
+			r := reminder.NewReminder(fmt.Sprintf("rem8%d%d", i, j), "Water the plants", "", &due, f.ID, "Alice", pattern)
+			r.Timezone = loc.String()
+			if err := store.CreateReminder(r); err != nil {
+				t.Fatalf("CreateReminder failed: %v", err)
+			}
+			got, err := store.GetReminder(r.ID)
+			if err != nil {
+				t.Fatalf("GetReminder failed: %v", err)
+			}
+			store.DeleteReminder(r.ID)
+			if got.Timezone != r.Timezone {
+				t.Errorf("%s: expected time zone %s, got %q", r.ID, r.Timezone, got.Timezone)
+			}
+			want := r.Occurrences(from, to)
+			occurrences := got.Occurrences(from, to)
+			if !slices.EqualFunc(occurrences, want, time.Time.Equal) {
+				t.Errorf("%s %s around %s: got occurrences %v, want %v", loc, pattern.Type, tr.At, occurrences, want)
+			}
+			tztest.CheckWallClock(t, occurrences, loc, wall.Hour(), wall.Minute())
+		}
+	}
+}
+
 func TestMemoryStorage(t *testing.T) {
 	store := NewMemoryStorage()
 	runStorageTests(t, store)
+	runDSTTests(t, store)
 }
 
 func TestFileStorage(t *testing.T) {
@@ -274,9 +738,36 @@ func TestFileStorage(t *testing.T) {
 	defer os.Remove(famFile)
 	defer os.Remove(remFile)
 	defer os.Remove(completeFile)
+	defer os.Remove("records.json")
 
 	store := NewFileStorage(famFile, remFile, completeFile)
 	runStorageTests(t, store)
+	runTimeRoundTripTests(t, store)
+	runDSTTests(t, store)
+}
+
+func TestFileStoragePing(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return dir + "/" + name }
+	store := NewFileStorage(path("families.json"), path("reminders.json"), path("events.json"))
+	if err := Ping(context.Background(), store); err != nil {
+		t.Errorf("before anything was written: %v", err)
+	}
+	store.CreateFamily(testFamily())
+	if err := Ping(context.Background(), store); err != nil {
+		t.Errorf("after writing: %v", err)
+	}
+	os.Mkdir(path("reminders.json"), 0755)
+	if err := Ping(context.Background(), store); err == nil {
+		t.Error("expected an error for an unwritable reminder file")
+	}
+	gone := NewFileStorage(path("missing/families.json"), path("missing/reminders.json"), path("missing/events.json"))
+	if err := Ping(context.Background(), gone); err == nil {
+		t.Error("expected an error for a missing data directory")
+	}
+	if err := Ping(context.Background(), NewMemoryStorage()); err != nil {
+		t.Errorf("memory storage: %v", err)
+	}
 }
 
 func TestFileStorageIDPersistence(t *testing.T) {
@@ -289,12 +780,13 @@ func TestFileStorageIDPersistence(t *testing.T) {
 	defer os.Remove(famFile)
 	defer os.Remove(remFile)
 	defer os.Remove(completeFile)
+	defer os.Remove("records.json")
 
 	store := NewFileStorage(famFile, remFile, completeFile)
 
 	// Generate and create a few families, reminders, and completion events
-	fam1 := &family.Family{ID: GenerateFamilyID(store), Name: "Fam1", Members: []string{"A"}}
-	fam2 := &family.Family{ID: GenerateFamilyID(store), Name: "Fam2", Members: []string{"B"}}
+	fam1 := &family.Family{ID: mustID(GenerateFamilyID(store)), Name: "Fam1", Members: []string{"A"}}
+	fam2 := &family.Family{ID: mustID(GenerateFamilyID(store)), Name: "Fam2", Members: []string{"B"}}
 	if err := store.CreateFamily(fam1); err != nil {
 		t.Fatalf("CreateFamily fam1 failed: %v", err)
 	}
@@ -303,8 +795,8 @@ func TestFileStorageIDPersistence(t *testing.T) {
 	}
 
 	due := time.Now().Add(24 * time.Hour)
-	r1 := &reminder.Reminder{ID: GenerateReminderID(store), Title: "R1", FamilyID: fam1.ID, FamilyMember: "A", DueDate: &due}
-	r2 := &reminder.Reminder{ID: GenerateReminderID(store), Title: "R2", FamilyID: fam2.ID, FamilyMember: "B", DueDate: &due}
+	r1 := &reminder.Reminder{ID: mustID(GenerateReminderID(store)), Title: "R1", FamilyID: fam1.ID, FamilyMember: "A", DueDate: &due}
+	r2 := &reminder.Reminder{ID: mustID(GenerateReminderID(store)), Title: "R2", FamilyID: fam2.ID, FamilyMember: "B", DueDate: &due}
 	if err := store.CreateReminder(r1); err != nil {
 		t.Fatalf("CreateReminder r1 failed: %v", err)
 	}
@@ -312,8 +804,8 @@ func TestFileStorageIDPersistence(t *testing.T) {
 		t.Fatalf("CreateReminder r2 failed: %v", err)
 	}
 
-	e1 := &reminder.CompletionEvent{ID: GenerateCompletionEventID(store), ReminderID: r1.ID, CompletedBy: "A", CompletedAt: time.Now()}
-	e2 := &reminder.CompletionEvent{ID: GenerateCompletionEventID(store), ReminderID: r2.ID, CompletedBy: "B", CompletedAt: time.Now()}
+	e1 := &reminder.CompletionEvent{ID: mustID(GenerateCompletionEventID(store)), ReminderID: r1.ID, CompletedBy: "A", CompletedAt: time.Now()}
+	e2 := &reminder.CompletionEvent{ID: mustID(GenerateCompletionEventID(store)), ReminderID: r2.ID, CompletedBy: "B", CompletedAt: time.Now()}
 	if err := store.CreateCompletionEvent(e1); err != nil {
 		t.Fatalf("CreateCompletionEvent e1 failed: %v", err)
 	}
@@ -345,16 +837,310 @@ func TestFileStorageIDPersistence(t *testing.T) {
 	}
 
 	// Generate new IDs and check they increment
-	newFamID := GenerateFamilyID(store2)
+	newFamID := mustID(GenerateFamilyID(store2))
 	if newFamID != "fam3" {
 		t.Errorf("Next family ID after reload: got %s, want fam3", newFamID)
 	}
-	newRemID := GenerateReminderID(store2)
+	newRemID := mustID(GenerateReminderID(store2))
 	if newRemID != "rem3" {
 		t.Errorf("Next reminder ID after reload: got %s, want rem3", newRemID)
 	}
-	newCevID := GenerateCompletionEventID(store2)
+	newCevID := mustID(GenerateCompletionEventID(store2))
 	if newCevID != "cev3" {
 		t.Errorf("Next completion event ID after reload: got %s, want cev3", newCevID)
 	}
 }
+
+func TestVerify(t *testing.T) {
+	store := NewMemoryStorage()
+	if problems := Verify(store); len(problems) != 0 {
+		t.Errorf("empty storage: got %v", problems)
+	}
+	store.CreateFamily(testFamily())
+	r := testReminder()
+	r.ID = "rem7"
+	store.CreateReminder(r)
+	store.SetFamilyIDCounter(1)
+	store.SetReminderIDCounter(3)
+	problems := Verify(store)
+	if len(problems) != 1 || !strings.Contains(problems[0], "rem7") {
+		t.Errorf("lagging reminder counter: got %v", problems)
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	at := time.Date(2025, 7, 14, 23, 59, 59, 999999999, time.FixedZone("", 2*3600))
+	if got := FormatTime(at); got != "2025-07-14T23:59:59.999+02:00" {
+		t.Errorf("FormatTime: got %s", got)
+	}
+	if got := FormatTime(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)); got != "2025-01-02T03:04:05.000Z" {
+		t.Errorf("FormatTime of a whole second: got %s", got)
+	}
+
+	for _, tc := range []struct {
+		in   string
+		want time.Time
+	}{
+		{"2025-07-14T23:59:59.999+02:00", time.Date(2025, 7, 14, 21, 59, 59, 999000000, time.UTC)},
+		// Written before sub-second precision was kept
+		{"2025-07-14T23:59:59+02:00", time.Date(2025, 7, 14, 21, 59, 59, 0, time.UTC)},
+		{"2025-07-14T23:59:59Z", time.Date(2025, 7, 14, 23, 59, 59, 0, time.UTC)},
+		{"2025-07-14 23:59:59", time.Date(2025, 7, 14, 23, 59, 59, 0, time.UTC)},
+		// Finer precision than TimePrecision is dropped
+		{"2025-07-14T23:59:59.123456789Z", time.Date(2025, 7, 14, 23, 59, 59, 123000000, time.UTC)},
+	} {
+		got, err := ParseTime(tc.in)
+		if err != nil || !got.Equal(tc.want) {
+			t.Errorf("ParseTime(%q): got %s, %v, want %s", tc.in, got, err, tc.want)
+		}
+	}
+	if _, err := ParseTime("tomorrow"); err == nil {
+		t.Error("ParseTime accepted an invalid time")
+	}
+}
+
+// unreachableStorage fails family lookups as if the database were down
+type unreachableStorage struct {
+	Storage
+	calls int
+}
+
+func (s *unreachableStorage) GetFamily(id string) (*family.Family, error) {
+	s.calls++
+	return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+}
+
+func TestBreakerStorage(t *testing.T) {
+	// Missing entities don't count as failures
+	b := breaker.New("storage")
+	s := WithBreaker(NewMemoryStorage(), b)
+	for i := 0; i < breaker.DefaultThreshold; i++ {
+		if _, err := s.GetFamily("nope"); err == nil {
+			t.Fatal("expected an error for a missing family")
+		}
+	}
+	if b.State() != breaker.Closed {
+		t.Fatalf("breaker is %s after not-found errors, want closed", b.State())
+	}
+
+	down := &unreachableStorage{Storage: NewMemoryStorage()}
+	b = breaker.New("storage")
+	s = WithBreaker(down, b)
+	for i := 0; i < breaker.DefaultThreshold; i++ {
+		s.GetFamily("fam1")
+	}
+	if _, err := s.GetFamily("fam1"); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("expected ErrOpen, got %v", err)
+	}
+	if down.calls != breaker.DefaultThreshold {
+		t.Errorf("open breaker still called the backend: %d calls", down.calls)
+	}
+	if Unwrap(s) != Storage(down) {
+		t.Error("Unwrap didn't return the backend")
+	}
+
+	// Requests running out of their own time don't count either
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	b = breaker.New("storage")
+	bound := WithBreaker(&timingOutStorage{Storage: NewMemoryStorage()}, b).WithContext(ctx)
+	for i := 0; i < breaker.DefaultThreshold; i++ {
+		if _, err := bound.GetFamily("fam1"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the request's deadline, got %v", err)
+		}
+	}
+	if b.State() != breaker.Closed {
+		t.Errorf("breaker is %s after requests timed out, want closed", b.State())
+	}
+}
+
+// timingOutStorage fails family lookups with the error of its bound context
+type timingOutStorage struct {
+	Storage
+	ctx context.Context
+}
+
+func (s *timingOutStorage) WithContext(ctx context.Context) Storage {
+	return &timingOutStorage{Storage: s.Storage, ctx: ctx}
+}
+
+func (s *timingOutStorage) GetFamily(id string) (*family.Family, error) {
+	<-s.ctx.Done()
+	return nil, fmt.Errorf("failed to get family: %w", s.ctx.Err())
+}
+
+func TestConnectRetry(t *testing.T) {
+	down := errors.New("connection refused")
+	for _, tt := range []struct {
+		retry     ConnectRetry
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{ConnectRetry{}, 0, 1, false},
+		{ConnectRetry{}, 1, 1, true},
+		{ConnectRetry{Attempts: 3, Wait: time.Millisecond}, 2, 3, false},
+		{ConnectRetry{Attempts: 3, Wait: time.Millisecond}, 5, 3, true},
+	} {
+		calls := 0
+		err := tt.retry.do("test", func() error {
+			calls++
+			if calls <= tt.failures {
+				return down
+			}
+			return nil
+		})
+		if calls != tt.wantCalls || (err != nil) != tt.wantErr {
+			t.Errorf("%+v with %d failures: %d calls, err %v", tt.retry, tt.failures, calls, err)
+		}
+	}
+}
+
+// refusingStorage fails every reminder write
+type refusingStorage struct {
+	Storage
+}
+
+func (s *refusingStorage) CreateReminder(r *reminder.Reminder) error {
+	return errors.New("disk full")
+}
+
+func TestSaveRemindersFallback(t *testing.T) {
+	// refusingStorage hides MemoryStorage's batch support
+	mem := NewMemoryStorage()
+	n, err := SaveReminders(&refusingStorage{mem}, []*reminder.Reminder{testReminder()})
+	if err == nil || n != 0 {
+		t.Errorf("SaveReminders: got %d, %v, want a failure", n, err)
+	}
+	r := testReminder()
+	if n, err := SaveReminders(struct{ Storage }{mem}, []*reminder.Reminder{r}); err != nil || n != 1 {
+		t.Errorf("SaveReminders one at a time: got %d, %v", n, err)
+	}
+	if _, err := mem.GetReminder(r.ID); err != nil {
+		t.Errorf("reminder saved one at a time is missing: %v", err)
+	}
+	r.Version = 5
+	if n, err := UpdateReminders(struct{ Storage }{mem}, r); !errors.Is(err, ErrVersionConflict) || n != 0 {
+		t.Errorf("UpdateReminders one at a time over a stale version: got %d, %v", n, err)
+	}
+	rec := &Record{Kind: "note", ID: "n1", Data: []byte("{}")}
+	if err := CreateRecord(struct{ Storage }{mem}, rec, time.Now()); err != nil {
+		t.Errorf("CreateRecord without backend support failed: %v", err)
+	}
+	if err := CreateRecord(struct{ Storage }{mem}, rec, time.Now()); !errors.Is(err, ErrRecordExists) {
+		t.Errorf("CreateRecord without backend support over a live record: got %v", err)
+	}
+}
+
+func TestDualWriteStorage(t *testing.T) {
+	primary, secondary := NewMemoryStorage(), NewMemoryStorage()
+	// Written before the migration started
+	primary.CreateFamily(testFamily())
+	primary.SetFamilyIDCounter(1)
+	s := NewDualWriteStorage(primary, &refusingStorage{secondary})
+
+	r := testReminder()
+	r.ID = mustID(GenerateReminderID(s))
+	if err := s.CreateReminder(r); err != nil {
+		t.Fatalf("CreateReminder failed despite succeeding on the primary: %v", err)
+	}
+	if secondary.GetReminderIDCounter() != 1 {
+		t.Errorf("secondary reminder counter: got %d, want 1", secondary.GetReminderIDCounter())
+	}
+	e := &reminder.CompletionEvent{ID: "cev1", ReminderID: r.ID, CompletedAt: time.Now(), CompletedBy: "Alice"}
+	s.CreateCompletionEvent(e)
+	// Backends may read times back in another zone
+	utc := *e
+	utc.CompletedAt = e.CompletedAt.UTC()
+	secondary.CreateCompletionEvent(&utc)
+	secondary.PutRecord(&Record{Kind: "note", ID: "stale", Data: []byte("{}")})
+
+	failures, mirrored := s.Failures()
+	if len(failures) != 1 || !strings.Contains(failures[0].Op, r.ID) || mirrored != 2 {
+		t.Errorf("failures: got %+v with %d mirrored", failures, mirrored)
+	}
+
+	report, err := s.Compare([]string{"note"})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	want := []Divergence{
+		{Entity: "family", ID: "fam1", Problem: MissingFromSecondary},
+		{Entity: "reminder", ID: r.ID, Problem: MissingFromSecondary},
+		{Entity: "record/note", ID: "stale", Problem: OnlyInSecondary},
+		{Entity: "id_counter", ID: "family", Problem: Differs, Fields: []string{"primary 1, secondary 0"}},
+	}
+	if !reflect.DeepEqual(report.Divergences, want) {
+		t.Errorf("divergences: got %+v, want %+v", report.Divergences, want)
+	}
+
+	s = NewDualWriteStorage(primary, secondary)
+	if repaired, err := s.Sync([]string{"note"}); err != nil || len(repaired) != len(want) {
+		t.Fatalf("Sync: repaired %+v, %v", repaired, err)
+	}
+	if report, _ := s.Compare([]string{"note"}); len(report.Divergences) != 0 {
+		t.Errorf("divergences after sync: %+v", report.Divergences)
+	}
+	if Unwrap(WithBreaker(s, breaker.New("storage"))) != Storage(primary) {
+		t.Error("Unwrap didn't return the primary backend")
+	}
+}
+
+// laggingStorage reports a fixed replication lag
+type laggingStorage struct {
+	Storage
+	lag time.Duration
+}
+
+func (s *laggingStorage) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	return s.lag, nil
+}
+
+func TestReplicaStorage(t *testing.T) {
+	primary, replica := NewMemoryStorage(), NewMemoryStorage()
+	primary.CreateFamily(testFamily())
+	replica.CreateFamily(&family.Family{ID: "fam1", Name: "Replicated"})
+	s := NewReplicaStorage(primary, replica, time.Minute)
+	name := func() string {
+		t.Helper()
+		f, err := s.GetFamily("fam1")
+		if err != nil {
+			t.Fatalf("GetFamily failed: %v", err)
+		}
+		return f.Name
+	}
+
+	if got := name(); got != "Replicated" {
+		t.Errorf("read went to %q, want the replica", got)
+	}
+	s.CreateReminder(testReminder())
+	if _, err := replica.GetReminder("rem1"); err == nil {
+		t.Error("write reached the replica")
+	}
+	if got := name(); got != "Test Family" {
+		t.Errorf("read after a write went to %q, want the primary", got)
+	}
+
+	lagging := &laggingStorage{Storage: replica, lag: 5 * time.Minute}
+	s = NewReplicaStorage(primary, lagging, time.Minute)
+	if got := name(); got != "Test Family" {
+		t.Errorf("read from a lagging replica went to %q, want the primary", got)
+	}
+	s = NewReplicaStorage(primary, &laggingStorage{Storage: replica, lag: time.Second}, time.Minute)
+	if got := name(); got != "Replicated" {
+		t.Errorf("read from a current replica went to %q, want the replica", got)
+	}
+
+	s = NewReplicaStorage(primary, &unreachableStorage{Storage: replica}, time.Minute)
+	if got := name(); got != "Test Family" {
+		t.Errorf("read with the replica down went to %q, want the primary", got)
+	}
+	s = NewReplicaStorage(primary, replica, time.Minute)
+	if f, err := ReadPrimary(s).GetFamily("fam1"); err != nil || f.Name != "Test Family" {
+		t.Errorf("ReadPrimary read %+v, %v, want the primary", f, err)
+	}
+	if Unwrap(s) != Storage(primary) {
+		t.Error("Unwrap didn't return the primary backend")
+	}
+}
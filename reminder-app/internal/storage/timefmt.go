@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"reminder-app/internal/reminder"
+)
+
+// TimePrecision is the precision every backend stores times at. MongoDB
+// dates only hold milliseconds, so anything finer would read back
+// differently depending on the backend.
+const TimePrecision = time.Millisecond
+
+// timeLayout is RFC 3339 with fixed-width milliseconds, used by backends
+// that store times as text
+const timeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// NormalizeTime truncates t to TimePrecision and drops its monotonic clock
+// reading, giving the instant every backend reads back. Backends that store
+// text keep t's UTC offset; the others return UTC or the server's zone.
+func NormalizeTime(t time.Time) time.Time {
+	return t.Truncate(TimePrecision)
+}
+
+// FormatTime serializes t for backends that store times as text
+func FormatTime(t time.Time) string {
+	return NormalizeTime(t).Format(timeLayout)
+}
+
+// ParseTime parses a time written by FormatTime, or by older releases that
+// stored whole seconds with or without an offset
+func ParseTime(s string) (time.Time, error) {
+	// time.Parse accepts fractional seconds after the seconds field even
+	// when the layout has none
+	for _, layout := range []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return NormalizeTime(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time string: %s", s)
+}
+
+// normalizeTimePtr is NormalizeTime for optional times
+func normalizeTimePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	n := NormalizeTime(*t)
+	return &n
+}
+
+// normalizedReminder returns a copy of r with its times normalized
+func normalizedReminder(r *reminder.Reminder) *reminder.Reminder {
+	c := *r
+	c.DueDate = normalizeTimePtr(r.DueDate)
+	c.CompletedAt = normalizeTimePtr(r.CompletedAt)
+	c.SnoozedUntil = normalizeTimePtr(r.SnoozedUntil)
+	return &c
+}
+
+// normalizedEvent returns a copy of e with its time normalized
+func normalizedEvent(e *reminder.CompletionEvent) *reminder.CompletionEvent {
+	c := *e
+	c.CompletedAt = NormalizeTime(e.CompletedAt)
+	return &c
+}
+
+// normalizedRecord returns a copy of rec with its times normalized
+func normalizedRecord(rec *Record) *Record {
+	c := *rec
+	c.CreatedAt = NormalizeTime(rec.CreatedAt)
+	c.ExpiresAt = normalizeTimePtr(rec.ExpiresAt)
+	return &c
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+)
+
+// TrashKind is the record kind of deleted reminders and families kept for
+// restoring. A trashed item's record has its ID, so restoring puts it back
+// under the same ID, where its completion and status events still point.
+const TrashKind = "trash"
+
+// ErrTrashedFamily is returned when restoring a reminder whose family is
+// itself in the trash
+var ErrTrashedFamily = errors.New("family is in the trash")
+
+// Trashed is a deleted reminder or family, with the reminders that were
+// deleted along with a family. DeletedAt is also set on the items
+// themselves.
+type Trashed struct {
+	Reminder  *reminder.Reminder   `json:"reminder,omitempty"`
+	Family    *family.Family       `json:"family,omitempty"`
+	Reminders []*reminder.Reminder `json:"reminders,omitempty"`
+	DeletedAt time.Time            `json:"deleted_at"`
+	DeletedBy string               `json:"deleted_by,omitempty"`
+	// PurgeAt is when the item is deleted for good
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// TrashReminder moves a reminder to the trash until purgeAt. Its events are
+// kept. It returns the reminder as trashed.
+func TrashReminder(s Storage, id, by string, now, purgeAt time.Time) (*reminder.Reminder, error) {
+	r, err := s.GetReminder(id)
+	if err != nil {
+		return nil, err
+	}
+	r.DeletedAt = &now
+	t := Trashed{Reminder: r, DeletedAt: now, DeletedBy: by, PurgeAt: purgeAt}
+	if err := PutJSON(s, Record{Kind: TrashKind, ID: r.ID, FamilyID: r.FamilyID, CreatedAt: now}, t); err != nil {
+		return nil, err
+	}
+	return r, s.DeleteReminder(id)
+}
+
+// TrashFamily moves a family and all its reminders to the trash until
+// purgeAt, returning the reminders. Reminders trashed earlier stay in the
+// trash on their own.
+func TrashFamily(s Storage, id, by string, now, purgeAt time.Time) ([]*reminder.Reminder, error) {
+	f, err := s.GetFamily(id)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.QueryReminders(ReminderFilter{FamilyID: id})
+	if err != nil {
+		return nil, err
+	}
+	f.DeletedAt = &now
+	for _, r := range list {
+		r.DeletedAt = &now
+	}
+	t := Trashed{Family: f, Reminders: list, DeletedAt: now, DeletedBy: by, PurgeAt: purgeAt}
+	if err := PutJSON(s, Record{Kind: TrashKind, ID: f.ID, FamilyID: f.ID, CreatedAt: now}, t); err != nil {
+		return nil, err
+	}
+	for i, r := range list {
+		if err := s.DeleteReminder(r.ID); err != nil {
+			return list[:i], err
+		}
+	}
+	return list, s.DeleteFamily(id)
+}
+
+// ListTrash returns the trashed items of a family, oldest first
+func ListTrash(s Storage, familyID string) ([]*Trashed, error) {
+	return ListJSON[Trashed](s, RecordQuery{Kind: TrashKind, FamilyID: familyID})
+}
+
+// Restore puts the trashed reminder or family with the given ID back and
+// removes it from the trash. It returns ErrRecordNotFound if there is no
+// such item.
+func Restore(s Storage, id string) (*Trashed, error) {
+	t, err := GetJSON[Trashed](s, TrashKind, id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.Reminder != nil:
+		if _, err := s.GetRecord(TrashKind, t.Reminder.FamilyID); err == nil {
+			return nil, ErrTrashedFamily
+		}
+		if _, err := s.GetFamily(t.Reminder.FamilyID); err != nil {
+			return nil, err
+		}
+		t.Reminder.DeletedAt = nil
+		if err := s.CreateReminder(t.Reminder); err != nil {
+			return nil, err
+		}
+	case t.Family != nil:
+		t.Family.DeletedAt = nil
+		if err := s.CreateFamily(t.Family); err != nil {
+			return nil, err
+		}
+		for _, r := range t.Reminders {
+			r.DeletedAt = nil
+		}
+		if _, err := SaveReminders(s, t.Reminders); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("trash item %s is empty", id)
+	}
+	return t, s.DeleteRecord(TrashKind, id)
+}
+
+// PurgeTrash deletes the trashed items due for purging at now for good,
+// along with the events of their reminders and the blobs of families, and
+// returns how many there were
+func PurgeTrash(s Storage, now time.Time) (int, error) {
+	records, err := s.ListRecords(RecordQuery{Kind: TrashKind})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, rec := range records {
+		var t Trashed
+		if err := json.Unmarshal(rec.Data, &t); err != nil {
+			return n, err
+		}
+		if now.Before(t.PurgeAt) {
+			continue
+		}
+		list := t.Reminders
+		if t.Reminder != nil {
+			list = append(list, t.Reminder)
+		}
+		for _, r := range list {
+			if err := deleteReminderEvents(s, r.ID); err != nil {
+				return n, err
+			}
+		}
+		if t.Family != nil {
+			if err := deleteFamilyBlobs(s, t.Family.ID); err != nil {
+				return n, err
+			}
+		}
+		if err := s.DeleteRecord(rec.Kind, rec.ID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
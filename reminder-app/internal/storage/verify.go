@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SchemaVerifier is implemented by backends whose schema can differ from
+// what this build expects, for example a database last migrated by a newer
+// release or altered by hand
+type SchemaVerifier interface {
+	VerifySchema() error
+}
+
+// Verify checks that s is safe to serve from. It verifies the schema of
+// backends that have one and that every ID counter is ahead of the IDs
+// already stored, since a lagging counter would make new entities overwrite
+// existing ones. Reading every family and reminder also warms up
+// connections and caches. It returns one diagnostic per problem found.
+func Verify(s Storage) []string {
+	var problems []string
+	if v, ok := s.(SchemaVerifier); ok {
+		if err := v.VerifySchema(); err != nil {
+			// Counters can't be trusted against an unexpected schema
+			return []string{fmt.Sprintf("schema: %v", err)}
+		}
+	}
+
+	families, err := s.ListFamilies()
+	if err != nil {
+		return append(problems, fmt.Sprintf("failed to list families: %v", err))
+	}
+	maxFamily := 0
+	for _, f := range families {
+		maxFamily = max(maxFamily, idNumber(f.ID, "fam"))
+	}
+
+	reminders, err := s.ListReminders()
+	if err != nil {
+		return append(problems, fmt.Sprintf("failed to list reminders: %v", err))
+	}
+	maxReminder := 0
+	for _, r := range reminders {
+		maxReminder = max(maxReminder, idNumber(r.ID, "rem"))
+	}
+
+	events, err := s.ListAllCompletionEvents()
+	if err != nil {
+		return append(problems, fmt.Sprintf("failed to list completion events: %v", err))
+	}
+	maxEvent := 0
+	for _, e := range events {
+		maxEvent = max(maxEvent, idNumber(e.ID, "cev"))
+	}
+
+	for _, c := range []struct {
+		name    string
+		counter int
+		maxID   int
+		prefix  string
+	}{
+		{"family", s.GetFamilyIDCounter(), maxFamily, "fam"},
+		{"reminder", s.GetReminderIDCounter(), maxReminder, "rem"},
+		{"completion event", s.GetCompletionEventIDCounter(), maxEvent, "cev"},
+	} {
+		if c.counter < c.maxID {
+			problems = append(problems, fmt.Sprintf("%s ID counter is %d but %s%d exists; new %ss would overwrite existing ones",
+				c.name, c.counter, c.prefix, c.maxID, c.name))
+		}
+	}
+	return problems
+}
+
+// idNumber returns the counter value of a generated ID such as "rem12", or
+// zero for IDs that weren't generated from a counter
+func idNumber(id, prefix string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, prefix))
+	if err != nil || !strings.HasPrefix(id, prefix) {
+		return 0
+	}
+	return n
+}
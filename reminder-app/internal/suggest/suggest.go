@@ -0,0 +1,266 @@
+// Package suggest looks for recurring reminders whose schedule doesn't match
+// how they are actually completed, such as a weekly "water plants" reminder
+// that is always done every other week, and proposes a better recurrence.
+package suggest
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// Kind is the storage record kind of suggestions
+const Kind = "suggestion"
+
+// Suggestion statuses
+const (
+	StatusOpen      = "open"
+	StatusAccepted  = "accepted"
+	StatusDismissed = "dismissed"
+)
+
+// MinCompletions is how many completions a reminder needs before its
+// pattern is analyzed
+const MinCompletions = 4
+
+// window is how many of the most recent completions are analyzed
+const window = 8
+
+// tolerance is how far the observed interval may stray from the scheduled
+// one, as a ratio, before a change is suggested
+const tolerance = 1.5
+
+// Suggestion proposes a new recurrence for a reminder
+type Suggestion struct {
+	ID           string                     `json:"id"`
+	FamilyID     string                     `json:"family_id"`
+	ReminderID   string                     `json:"reminder_id"`
+	Title        string                     `json:"title"`
+	Current      reminder.RecurrencePattern `json:"current"`
+	Proposed     reminder.RecurrencePattern `json:"proposed"`
+	IntervalDays float64                    `json:"interval_days"` // median days between completions
+	Reason       string                     `json:"reason"`
+	Status       string                     `json:"status"`
+	CreatedAt    time.Time                  `json:"created_at"`
+}
+
+// candidate is a recurrence pattern and its average interval in days
+type candidate struct {
+	pattern reminder.RecurrencePattern
+	days    float64
+}
+
+// Analyze compares the completions of r with its schedule and returns a
+// suggestion if they disagree, or nil if the schedule fits or there isn't
+// enough history
+func Analyze(r *reminder.Reminder, events []*reminder.CompletionEvent) *Suggestion {
+	current, ok := interval(r.Recurrence)
+	if !ok || len(events) < MinCompletions {
+		return nil
+	}
+	events = append([]*reminder.CompletionEvent(nil), events...)
+	sort.Slice(events, func(i, j int) bool { return events[i].CompletedAt.Before(events[j].CompletedAt) })
+	if len(events) > window {
+		events = events[len(events)-window:]
+	}
+	gaps := make([]float64, 0, len(events)-1)
+	for i := 1; i < len(events); i++ {
+		gaps = append(gaps, events[i].CompletedAt.Sub(events[i-1].CompletedAt).Hours()/24)
+	}
+	observed := median(gaps)
+	if observed <= 0 || (observed < current*tolerance && observed > current/tolerance) {
+		return nil
+	}
+
+	cands := candidates(r.Recurrence, events)
+	best := cands[0]
+	for _, c := range cands[1:] {
+		if distance(c.days, observed) < distance(best.days, observed) {
+			best = c
+		}
+	}
+	if best.days == current {
+		return nil
+	}
+	return &Suggestion{
+		FamilyID:     r.FamilyID,
+		ReminderID:   r.ID,
+		Title:        r.Title,
+		Current:      r.Recurrence,
+		Proposed:     best.pattern,
+		IntervalDays: math.Round(observed*10) / 10,
+		Reason: fmt.Sprintf("%q is usually completed about every %s, but it is scheduled %s",
+			r.Title, days(observed), describe(r.Recurrence)),
+		Status: StatusOpen,
+	}
+}
+
+// Generate analyzes every recurring reminder and stores new suggestions. A
+// reminder gets no new suggestion while one is open, nor one that was
+// already dismissed.
+func Generate(s storage.Storage, now time.Time) ([]*Suggestion, error) {
+	list, err := s.ListReminders()
+	if err != nil {
+		return nil, err
+	}
+	var created []*Suggestion
+	for _, r := range list {
+		if _, ok := interval(r.Recurrence); !ok {
+			continue
+		}
+		events, err := s.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: r.ID})
+		if err != nil {
+			return created, err
+		}
+		sug := Analyze(r, events)
+		if sug == nil {
+			continue
+		}
+		existing, err := storage.ListJSON[Suggestion](s, storage.RecordQuery{Kind: Kind, Ref: r.ID})
+		if err != nil {
+			return created, err
+		}
+		if superseded(sug, existing) {
+			continue
+		}
+		sug.ID = storage.NewRecordID("sug")
+		sug.CreatedAt = now
+		if err := Put(s, sug); err != nil {
+			return created, err
+		}
+		created = append(created, sug)
+	}
+	return created, nil
+}
+
+// Put stores a suggestion
+func Put(s storage.Storage, sug *Suggestion) error {
+	rec := storage.Record{Kind: Kind, ID: sug.ID, FamilyID: sug.FamilyID, Ref: sug.ReminderID, CreatedAt: sug.CreatedAt}
+	return storage.PutJSON(s, rec, sug)
+}
+
+// superseded reports whether an existing suggestion makes sug redundant
+func superseded(sug *Suggestion, existing []*Suggestion) bool {
+	for _, e := range existing {
+		if e.Status == StatusOpen {
+			return true
+		}
+		if e.Status == StatusDismissed && reflect.DeepEqual(e.Proposed, sug.Proposed) {
+			return true
+		}
+	}
+	return false
+}
+
+// interval returns the average days between occurrences of a pattern.
+// Only regularly recurring patterns qualify.
+func interval(p reminder.RecurrencePattern) (float64, bool) {
+	n := float64(max(p.Interval, 1))
+	switch p.Type {
+	case "daily":
+		return n, true
+	case "weekly":
+		if len(p.Days) == 0 {
+			return 0, false
+		}
+		return n * 7 / float64(len(p.Days)), true
+	case "monthly":
+		return n * 30.4, true
+	case "yearly":
+		return n * 365.25, true
+	}
+	return 0, false
+}
+
+// candidates lists the patterns a reminder could move to. Weekdays and the
+// day of the month are taken from when the reminder is actually completed.
+func candidates(current reminder.RecurrencePattern, events []*reminder.CompletionEvent) []candidate {
+	byWeekday := make(map[time.Weekday]int)
+	byDate := make(map[int]int)
+	for _, e := range events {
+		byWeekday[e.CompletedAt.Weekday()]++
+		byDate[e.CompletedAt.Day()]++
+	}
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+	sort.SliceStable(weekdays, func(i, j int) bool { return byWeekday[weekdays[i]] > byWeekday[weekdays[j]] })
+	date := events[len(events)-1].CompletedAt.Day()
+	for d, n := range byDate {
+		if n > byDate[date] || (n == byDate[date] && d < date) {
+			date = d
+		}
+	}
+
+	result := []candidate{{pattern: reminder.RecurrencePattern{Type: "daily", EndDate: current.EndDate}, days: 1}}
+	for n := 1; n < 7; n++ {
+		days := make([]string, 0, n)
+		for _, wd := range weekdays[:n] {
+			days = append(days, strings.ToLower(wd.String()))
+		}
+		sort.Slice(days, func(i, j int) bool { return weekdayIndex(days[i]) < weekdayIndex(days[j]) })
+		p := reminder.RecurrencePattern{Type: "weekly", Days: days, EndDate: current.EndDate}
+		if current.Type == "weekly" && len(current.Days) == n {
+			p.Days = current.Days // same frequency; keep the days the family chose
+		}
+		result = append(result, candidate{pattern: p, days: 7 / float64(n)})
+	}
+	if date > 28 {
+		date = 28 // every month has it
+	}
+	if current.Type == "monthly" {
+		date = current.Date
+	}
+	result = append(result, candidate{pattern: reminder.RecurrencePattern{Type: "monthly", Date: date, EndDate: current.EndDate}, days: 30.4})
+	return result
+}
+
+// weekdayIndex orders lower-case weekday names from Sunday
+func weekdayIndex(day string) int {
+	for i := time.Sunday; i <= time.Saturday; i++ {
+		if strings.ToLower(i.String()) == day {
+			return int(i)
+		}
+	}
+	return 7
+}
+
+// distance compares intervals by ratio, so 2 vs 4 days is as far apart as
+// 7 vs 14
+func distance(a, b float64) float64 {
+	return math.Abs(math.Log(a / b))
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// days formats an interval for a reason, e.g. "10 days"
+func days(d float64) string {
+	n := int(math.Round(d))
+	if n <= 1 {
+		return "day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+// describe formats a pattern for a reason, e.g. "weekly on monday"
+func describe(p reminder.RecurrencePattern) string {
+	switch p.Type {
+	case "weekly":
+		return "weekly on " + strings.Join(p.Days, ", ")
+	case "monthly":
+		return fmt.Sprintf("monthly on day %d", p.Date)
+	}
+	return p.Type
+}
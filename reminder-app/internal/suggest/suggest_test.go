@@ -0,0 +1,80 @@
+package suggest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+// completions returns n completion events every interval starting at start
+func completions(id string, start time.Time, interval time.Duration, n int) []*reminder.CompletionEvent {
+	var events []*reminder.CompletionEvent
+	for i := 0; i < n; i++ {
+		events = append(events, &reminder.CompletionEvent{
+			ID:          fmt.Sprintf("%s-%d", id, i),
+			ReminderID:  id,
+			CompletedAt: start.Add(time.Duration(i) * interval),
+		})
+	}
+	return events
+}
+
+func TestAnalyze(t *testing.T) {
+	day := 24 * time.Hour
+	monday := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	weekly := reminder.NewReminder("r1", "Water plants", "", &monday, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday", "thursday"}})
+
+	if s := Analyze(weekly, completions("r1", monday, 3*day+12*time.Hour, 6)); s != nil {
+		t.Errorf("expected no suggestion when the schedule fits, got %+v", s)
+	}
+	if s := Analyze(weekly, completions("r1", monday, 7*day, MinCompletions-1)); s != nil {
+		t.Errorf("expected no suggestion with too little history, got %+v", s)
+	}
+
+	s := Analyze(weekly, completions("r1", monday, 7*day, 6))
+	if s == nil {
+		t.Fatal("expected a suggestion")
+	}
+	if s.Proposed.Type != "weekly" || len(s.Proposed.Days) != 1 || s.Proposed.Days[0] != "monday" {
+		t.Errorf("expected weekly on monday, got %+v", s.Proposed)
+	}
+	if s.IntervalDays != 7 || s.Status != StatusOpen {
+		t.Errorf("unexpected suggestion: %+v", s)
+	}
+
+	daily := reminder.NewReminder("r2", "Feed fish", "", &monday, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"})
+	var monthly []*reminder.CompletionEvent
+	for i := 0; i < 5; i++ {
+		monthly = append(monthly, &reminder.CompletionEvent{ID: fmt.Sprintf("m%d", i), ReminderID: "r2", CompletedAt: monday.AddDate(0, i, 0)})
+	}
+	s = Analyze(daily, monthly)
+	if s == nil || s.Proposed.Type != "monthly" || s.Proposed.Date != 3 {
+		t.Errorf("expected monthly on day 3, got %+v", s)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	monday := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("r1", "Water plants", "", &monday, "fam1", "Alice", reminder.RecurrencePattern{Type: "daily"}))
+	for _, e := range completions("r1", monday, 7*24*time.Hour, 5) {
+		_ = store.CreateCompletionEvent(e)
+	}
+
+	created, err := Generate(store, monday)
+	if err != nil || len(created) != 1 {
+		t.Fatalf("expected one suggestion, got %v, %v", created, err)
+	}
+	if again, _ := Generate(store, monday); len(again) != 0 {
+		t.Errorf("expected no duplicate while a suggestion is open, got %d", len(again))
+	}
+
+	created[0].Status = StatusDismissed
+	_ = Put(store, created[0])
+	if again, _ := Generate(store, monday); len(again) != 0 {
+		t.Errorf("expected a dismissed suggestion not to be repeated, got %d", len(again))
+	}
+}
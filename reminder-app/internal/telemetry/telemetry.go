@@ -0,0 +1,240 @@
+// Package telemetry reports anonymous usage counts to help prioritize
+// development. It is off unless the operator enables it. A report holds only
+// aggregate numbers, such as how many reminders exist and how many families
+// use each feature, along with the storage backend and a random instance ID
+// that tells reports of the same server apart. No names, titles or other
+// content ever leave the server.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"reminder-app/internal/guest"
+	"reminder-app/internal/hook"
+	"reminder-app/internal/project"
+	"reminder-app/internal/share"
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/webhook"
+)
+
+// Kind is the storage record kind holding the instance ID
+const Kind = "telemetry"
+
+// DefaultInterval is how often reports are sent
+const DefaultInterval = 24 * time.Hour
+
+// recentCompletions is how far back Completions counts
+const recentCompletions = 7 * 24 * time.Hour
+
+// Report is what is sent
+type Report struct {
+	InstanceID string    `json:"instance_id"`
+	Backend    string    `json:"backend"`
+	Time       time.Time `json:"time"`
+	Families   int       `json:"families"`
+	Members    int       `json:"members"`
+	Reminders  int       `json:"reminders"`
+	// Recurrence counts reminders by recurrence type
+	Recurrence map[string]int `json:"recurrence"`
+	// Completions counts the completions made in the last week
+	Completions int `json:"completions"`
+	// Channels counts notification channels by type
+	Channels map[string]int `json:"channels"`
+	// Features counts the families, reminders or records using each
+	// optional feature
+	Features map[string]int `json:"features"`
+}
+
+// instance is the stored instance ID
+type instance struct {
+	ID string `json:"id"`
+}
+
+// Reporter collects reports and sends them to Endpoint
+type Reporter struct {
+	Store storage.Storage
+	// Backend names the storage backend, e.g. "sqlite"
+	Backend  string
+	Endpoint string
+	Interval time.Duration
+	Client   *http.Client
+}
+
+// New creates a reporter sending daily with a 10 second timeout
+func New(s storage.Storage, backend, endpoint string) *Reporter {
+	return &Reporter{
+		Store:    s,
+		Backend:  backend,
+		Endpoint: endpoint,
+		Interval: DefaultInterval,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Collect builds the report as of now
+func (t *Reporter) Collect(now time.Time) (*Report, error) {
+	id, err := t.instanceID()
+	if err != nil {
+		return nil, err
+	}
+	rep := &Report{
+		InstanceID: id,
+		Backend:    t.Backend,
+		Time:       now.UTC().Truncate(time.Hour),
+		Recurrence: make(map[string]int),
+		Channels:   make(map[string]int),
+		Features:   make(map[string]int),
+	}
+
+	families, err := t.Store.ListFamilies()
+	if err != nil {
+		return nil, err
+	}
+	rep.Families = len(families)
+	for _, f := range families {
+		rep.Members += len(f.Members)
+		for _, c := range f.Settings.Channels {
+			rep.Channels[c.Type]++
+		}
+		for _, ms := range f.Settings.Members {
+			for _, c := range ms.Channels {
+				rep.Channels[c.Type]++
+			}
+		}
+		s := f.Settings
+		for feature, used := range map[string]bool{
+			"roles":                f.HasRoles(),
+			"timezone":             s.Timezone != "",
+			"nag":                  s.Nag != nil,
+			"monthly_report":       s.MonthlyReport != nil,
+			"completion_notices":   s.CompletionNotices,
+			"completion_retention": s.CompletionRetentionDays > 0,
+			"workflow":             s.Workflow != nil,
+			"templates":            len(s.Templates) > 0,
+		} {
+			if used {
+				rep.Features[feature]++
+			}
+		}
+	}
+
+	reminders, err := t.Store.ListReminders()
+	if err != nil {
+		return nil, err
+	}
+	rep.Reminders = len(reminders)
+	for _, r := range reminders {
+		typ := r.Recurrence.Type
+		if typ == "" {
+			typ = "once"
+		}
+		rep.Recurrence[typ]++
+		if r.RequiresConfirmation {
+			rep.Features["requires_confirmation"]++
+		}
+		if r.Timezone != "" {
+			rep.Features["reminder_timezone"]++
+		}
+		if r.Condition != nil {
+			rep.Features["condition_"+r.Condition.Provider]++
+		}
+		if r.Usage != nil {
+			rep.Features["reminder_usage"]++
+		}
+		events, err := t.Store.QueryCompletionEvents(storage.CompletionEventQuery{ReminderID: r.ID, From: now.Add(-recentCompletions), To: now})
+		if err != nil {
+			return nil, err
+		}
+		rep.Completions += len(events)
+	}
+
+	for feature, kind := range map[string]string{
+		"projects":    project.Kind,
+		"smart_lists": smartlist.Kind,
+		"shares":      share.Kind,
+		"hooks":       hook.Kind,
+		"webhooks":    webhook.Kind,
+		"guests":      guest.Kind,
+	} {
+		records, err := t.Store.ListRecords(storage.RecordQuery{Kind: kind})
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 {
+			rep.Features[feature] = len(records)
+		}
+	}
+	return rep, nil
+}
+
+// instanceID returns the random ID of this server, creating it on first use
+func (t *Reporter) instanceID() (string, error) {
+	inst, err := storage.GetJSON[instance](t.Store, Kind, "instance")
+	if err == nil {
+		return inst.ID, nil
+	}
+	if !errors.Is(err, storage.ErrRecordNotFound) {
+		return "", err
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	inst = &instance{ID: hex.EncodeToString(b)}
+	rec := storage.Record{Kind: Kind, ID: "instance", CreatedAt: time.Now()}
+	return inst.ID, storage.PutJSON(t.Store, rec, inst)
+}
+
+// Send collects a report and POSTs it to Endpoint
+func (t *Reporter) Send(ctx context.Context, now time.Time) error {
+	rep, err := t.Collect(now)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "reminder-app-telemetry")
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("telemetry endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Run sends a report now and then every Interval until ctx is cancelled.
+// Failures are logged and not retried until the next report is due.
+func (t *Reporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	for {
+		if err := t.Send(ctx, time.Now()); err != nil {
+			log.Printf("telemetry: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
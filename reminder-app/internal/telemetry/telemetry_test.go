@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/project"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+func TestReporter(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	_ = store.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice", "Bob"}, Settings: family.Settings{
+		Timezone: "Europe/Berlin",
+		Channels: []family.Channel{{Type: "ntfy", Target: "smith-secret-topic"}},
+	}})
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	_ = store.CreateReminder(reminder.NewReminder("rem1", "Trash", "", &now, "fam1", "Alice", reminder.RecurrencePattern{Type: "weekly", Days: []string{"monday"}}))
+	_ = store.CreateReminder(reminder.NewReminder("rem2", "Dentist", "", &now, "fam1", "Bob", reminder.RecurrencePattern{Type: "once"}))
+	_ = store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "e1", ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: now.Add(-time.Hour)})
+	_ = store.CreateCompletionEvent(&reminder.CompletionEvent{ID: "e2", ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: now.AddDate(0, -1, 0)})
+	_ = storage.PutJSON(store, storage.Record{Kind: project.Kind, ID: "p1", FamilyID: "fam1", CreatedAt: now}, map[string]string{"name": "Garden"})
+
+	var got []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	t.Run("Collect", func(t *testing.T) {
+		rep, err := New(store, "memory", srv.URL).Collect(now)
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if rep.Backend != "memory" || rep.Families != 1 || rep.Members != 2 || rep.Reminders != 2 || rep.Completions != 1 {
+			t.Errorf("unexpected counts %+v", rep)
+		}
+		if rep.Recurrence["weekly"] != 1 || rep.Recurrence["once"] != 1 || rep.Channels["ntfy"] != 1 {
+			t.Errorf("unexpected breakdowns %+v %+v", rep.Recurrence, rep.Channels)
+		}
+		if rep.Features["timezone"] != 1 || rep.Features["projects"] != 1 || rep.Features["nag"] != 0 {
+			t.Errorf("unexpected features %+v", rep.Features)
+		}
+		again, _ := New(store, "memory", srv.URL).Collect(now)
+		if rep.InstanceID == "" || again.InstanceID != rep.InstanceID {
+			t.Errorf("expected a stable instance ID, got %q and %q", rep.InstanceID, again.InstanceID)
+		}
+	})
+
+	t.Run("Send", func(t *testing.T) {
+		if err := New(store, "memory", srv.URL).Send(context.Background(), now); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		var rep Report
+		if err := json.Unmarshal(got, &rep); err != nil || rep.Reminders != 2 {
+			t.Fatalf("unexpected report %s: %v", got, err)
+		}
+		// Nothing identifying is sent
+		for _, s := range []string{"Smith", "Alice", "Trash", "Garden", "smith-secret-topic", "fam1"} {
+			if bytes.Contains(got, []byte(s)) {
+				t.Errorf("report leaks %q: %s", s, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,186 @@
+// Package templates renders notification text. Every kind of notification
+// has a built-in default which a family can override in its settings; the
+// placeholders available to a template are the fields of Data.
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"reminder-app/internal/agenda"
+	"reminder-app/internal/family"
+	"reminder-app/internal/notify"
+	"reminder-app/internal/reminder"
+)
+
+// Notification kinds
+const (
+	// KindReminder is sent when a single reminder is due
+	KindReminder = "reminder"
+	// KindAgenda is the daily summary of a member's reminders
+	KindAgenda = "agenda"
+	// KindNag is the evening summary of the family's unfinished reminders
+	KindNag = "nag"
+	// KindCompleted tells the family a reminder was done
+	KindCompleted = "completed"
+	// KindOverdue is sent again, and to other members, while a reminder
+	// is overdue
+	KindOverdue = "overdue"
+)
+
+// Defaults are used for any kind, or part of a kind, a family does not
+// override
+var Defaults = map[string]family.Template{
+	KindReminder: {
+		Subject: `{{if eq .Priority "urgent"}}Urgent{{else if eq .Priority "high"}}Important{{else}}Reminder{{end}}: {{.Title}}`,
+		Body:    "{{.Title}}{{if .Assignee}} for {{.Assignee}}{{end}}{{if .Due}} is due {{.Due}}{{end}}.{{if .Description}}\n{{.Description}}{{end}}",
+	},
+	KindAgenda: {
+		Subject: "Today's reminders for {{.Assignee}}",
+		Body:    "{{range $i, $it := .Items}}{{if $i}}\n{{end}}- {{$it.Title}} at {{$it.Due}}{{else}}Nothing due today.{{end}}",
+	},
+	KindNag: {
+		Subject: "Still to do today",
+		Body:    "{{range $i, $it := .Items}}{{if $i}}\n{{end}}- {{$it.Title}}{{if $it.Assignee}} ({{$it.Assignee}}){{end}} at {{$it.Due}}{{end}}",
+	},
+	KindCompleted: {
+		Subject: "Done: {{.Title}}",
+		Body:    "{{if .CompletedBy}}{{.CompletedBy}}{{else}}Someone{{end}} completed {{.Title}}.",
+	},
+	KindOverdue: {
+		Subject: "Overdue: {{.Title}}",
+		Body:    "{{.Title}}{{if .Assignee}} for {{.Assignee}}{{end}} was due {{.Due}} and isn't done yet.{{if .Description}}\n{{.Description}}{{end}}",
+	},
+}
+
+// DueLayout is the format of the Due placeholder
+const DueLayout = "Mon Jan 2 3:04 PM"
+
+// ItemLayout is the format of an agenda item's Due placeholder
+const ItemLayout = "3:04 PM"
+
+// Item is one entry of an agenda
+type Item struct {
+	Title    string
+	Assignee string
+	Due      string
+}
+
+// Data holds the values a template can refer to
+type Data struct {
+	Family      string
+	Assignee    string
+	Title       string
+	Description string
+	// Priority is the reminder's priority, empty meaning normal
+	Priority string
+	// Due is the formatted due time, empty when the reminder has none
+	Due string
+	// DueTime is the raw due time for templates wanting their own format
+	DueTime *time.Time
+	// Link is an optional action URL such as a signed completion link
+	Link string
+	// CompletedBy is the member who completed the reminder
+	CompletedBy string
+	// Items lists the entries of an agenda
+	Items []Item
+}
+
+// ForReminder builds template data for a reminder, formatting times in loc
+func ForReminder(f *family.Family, r *reminder.Reminder, loc *time.Location) Data {
+	d := Data{
+		Assignee:    r.FamilyMember,
+		Title:       r.Title,
+		Description: r.Description,
+		Priority:    r.Priority,
+	}
+	if f != nil {
+		d.Family = f.Name
+	}
+	if r.DueDate != nil {
+		due := r.DueDate.In(loc)
+		d.Due = due.Format(DueLayout)
+		d.DueTime = &due
+	}
+	return d
+}
+
+// ForAgenda builds template data for an agenda, formatting times in loc.
+// The member is empty for family-wide summaries.
+func ForAgenda(f *family.Family, member string, items []agenda.Item, loc *time.Location) Data {
+	d := Data{Assignee: member}
+	if f != nil {
+		d.Family = f.Name
+	}
+	for _, it := range items {
+		d.Items = append(d.Items, Item{
+			Title:    it.Reminder.Title,
+			Assignee: it.Reminder.FamilyMember,
+			Due:      it.At.In(loc).Format(ItemLayout),
+		})
+	}
+	return d
+}
+
+// Render produces the message of the given kind, using the family's
+// template where it has one and the default otherwise
+func Render(custom map[string]family.Template, kind string, data Data) (notify.Message, error) {
+	t, ok := Defaults[kind]
+	if !ok {
+		return notify.Message{}, fmt.Errorf("unknown notification kind: %s", kind)
+	}
+	if c, ok := custom[kind]; ok {
+		if c.Subject != "" {
+			t.Subject = c.Subject
+		}
+		if c.Body != "" {
+			t.Body = c.Body
+		}
+	}
+	subject, err := execute(kind+".subject", t.Subject, data)
+	if err != nil {
+		return notify.Message{}, err
+	}
+	body, err := execute(kind+".body", t.Body, data)
+	if err != nil {
+		return notify.Message{}, err
+	}
+	return notify.Message{Subject: subject, Body: body, Link: data.Link}, nil
+}
+
+// Validate checks a family's templates by rendering each against sample
+// data, which catches both syntax errors and unknown placeholders
+func Validate(custom map[string]family.Template) error {
+	due := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	sample := Data{
+		Family:      "Smith",
+		Assignee:    "Alice",
+		Title:       "Take out the trash",
+		Description: "Bins go out Monday",
+		Due:         due.Format(DueLayout),
+		DueTime:     &due,
+		Link:        "https://example.com/c/token",
+		CompletedBy: "Bob",
+		Items:       []Item{{Title: "Take out the trash", Assignee: "Alice", Due: due.Format(ItemLayout)}},
+	}
+	for kind := range custom {
+		if _, err := Render(custom, kind, sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func execute(name, text string, data Data) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", name, err)
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("template %s: %w", name, err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,67 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"reminder-app/internal/family"
+	"reminder-app/internal/reminder"
+)
+
+func TestRenderDefault(t *testing.T) {
+	due := time.Date(2024, 3, 4, 18, 30, 0, 0, time.UTC)
+	r := reminder.NewReminder("rem1", "Trash", "Bins out", &due, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	msg, err := Render(nil, KindReminder, ForReminder(&family.Family{Name: "Smith"}, r, time.UTC))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if msg.Subject != "Reminder: Trash" {
+		t.Errorf("unexpected subject: %q", msg.Subject)
+	}
+	if msg.Body != "Trash for Alice is due Mon Mar 4 6:30 PM.\nBins out" {
+		t.Errorf("unexpected body: %q", msg.Body)
+	}
+	r.Priority = reminder.PriorityUrgent
+	msg, _ = Render(nil, KindReminder, ForReminder(nil, r, time.UTC))
+	if msg.Subject != "Urgent: Trash" {
+		t.Errorf("unexpected subject of an urgent reminder: %q", msg.Subject)
+	}
+}
+
+func TestRenderCustom(t *testing.T) {
+	custom := map[string]family.Template{
+		KindReminder: {Body: "Hey {{.Assignee}}, {{.Title}}! {{.DueTime.Format \"15:04\"}}"},
+	}
+	due := time.Date(2024, 3, 4, 18, 30, 0, 0, time.UTC)
+	msg, err := Render(custom, KindReminder, Data{Assignee: "Bob", Title: "Dishes", DueTime: &due})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	// The subject falls back to the default
+	if msg.Subject != "Reminder: Dishes" || msg.Body != "Hey Bob, Dishes! 18:30" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		custom  map[string]family.Template
+		wantErr string
+	}{
+		{"valid", map[string]family.Template{KindReminder: {Subject: "{{.Title}}"}}, ""},
+		{"syntax", map[string]family.Template{KindReminder: {Subject: "{{.Title"}}, "unclosed action"},
+		{"unknown field", map[string]family.Template{KindReminder: {Body: "{{.Nope}}"}}, "Nope"},
+		{"unknown kind", map[string]family.Template{"birthday": {Body: "x"}}, "unknown notification kind"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.custom)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
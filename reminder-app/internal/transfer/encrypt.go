@@ -0,0 +1,114 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// An encrypted package is the signed archive sealed with AES-256-GCM under
+// a key derived from the passphrase with scrypt:
+//
+//	magic | salt | nonce | ciphertext
+//
+// The magic header is authenticated along with the archive.
+const encryptedMagic = "RMDPKG\x00\x01"
+
+// MinPassphraseLength is the shortest passphrase a package is encrypted with
+const MinPassphraseLength = 8
+
+const (
+	saltSize = 16
+	keySize  = 32
+	// scrypt costs, as the scrypt package recommends for interactive logins
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ValidatePassphrase checks that a passphrase is long enough to encrypt a
+// package with
+func ValidatePassphrase(passphrase string) error {
+	if len(passphrase) < MinPassphraseLength {
+		return fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+	}
+	return nil
+}
+
+// encrypt writes data sealed with passphrase
+func encrypt(w io.Writer, data []byte, passphrase string) error {
+	if err := ValidatePassphrase(passphrase); err != nil {
+		return err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := packageCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	out := make([]byte, 0, len(encryptedMagic)+saltSize+len(nonce)+len(data)+aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, data, []byte(encryptedMagic))
+	_, err = w.Write(out)
+	return err
+}
+
+// decrypt opens data sealed by encrypt, failing with ErrPassphrase if the
+// passphrase is missing or wrong
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrPassphrase
+	}
+	data = bytes.TrimPrefix(data, []byte(encryptedMagic))
+	if len(data) < saltSize {
+		return nil, errors.New("truncated encrypted package")
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+	aead, err := packageCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("truncated encrypted package")
+	}
+	nonce, data := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data, []byte(encryptedMagic))
+	if err != nil {
+		// GCM can't tell a wrong key from a damaged package
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
+
+func packageCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isEncrypted reports whether the archive r starts with is encrypted
+func isEncrypted(r *bufio.Reader) bool {
+	magic, _ := r.Peek(len(encryptedMagic))
+	return string(magic) == encryptedMagic
+}
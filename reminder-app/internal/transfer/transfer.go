@@ -0,0 +1,333 @@
+// Package transfer moves a family between self-hosted instances. A package
+// holds the family with its reminders, completion events and records, and
+// is written as a gzipped tar archive signed with a secret shared by the
+// exporting and importing instances. An archive can also be encrypted with
+// a passphrase, so that a family's backup is safe wherever it is stored;
+// see encrypt.go.
+package transfer
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/family"
+	"reminder-app/internal/project"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/smartlist"
+	"reminder-app/internal/storage"
+	"reminder-app/internal/suggest"
+)
+
+// Format is the version of the package layout
+const Format = 1
+
+// Archive entry names
+const (
+	packageEntry   = "package.json"
+	signatureEntry = "package.sig"
+)
+
+// maxPackageSize bounds how much of an archive entry is read
+const maxPackageSize = 64 << 20
+
+var (
+	ErrSignature  = errors.New("invalid package signature")
+	ErrFormat     = errors.New("unsupported package format")
+	ErrPassphrase = errors.New("package is encrypted and the passphrase is missing or wrong")
+)
+
+// Kinds are the record kinds that belong to a family and travel with it
+var Kinds = []string{audit.Kind, project.Kind, smartlist.Kind, suggest.Kind, reminder.StatusEventKind, reminder.AckKind}
+
+// Package is everything stored about one family
+type Package struct {
+	Format           int                         `json:"format"`
+	ExportedAt       time.Time                   `json:"exported_at"`
+	Family           *family.Family              `json:"family"`
+	Reminders        []*reminder.Reminder        `json:"reminders"`
+	CompletionEvents []*reminder.CompletionEvent `json:"completion_events"`
+	Records          []*storage.Record           `json:"records"`
+}
+
+// Build collects a family's data into a package
+func Build(s storage.Storage, familyID string, now time.Time) (*Package, error) {
+	f, err := s.GetFamily(familyID)
+	if err != nil {
+		return nil, err
+	}
+	p := &Package{Format: Format, ExportedAt: now, Family: f}
+	list, err := s.ListReminders()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		if r.FamilyID != familyID {
+			continue
+		}
+		p.Reminders = append(p.Reminders, r)
+		events, err := s.ListCompletionEvents(r.ID)
+		if err != nil {
+			return nil, err
+		}
+		p.CompletionEvents = append(p.CompletionEvents, events...)
+	}
+	for _, kind := range Kinds {
+		records, err := s.ListRecords(storage.RecordQuery{Kind: kind, FamilyID: familyID})
+		if err != nil {
+			return nil, err
+		}
+		p.Records = append(p.Records, records...)
+	}
+	return p, nil
+}
+
+// Import stores a package as a new family. Every family, reminder, event
+// and record gets a fresh ID so the package can't collide with data
+// already on this instance, and references between them are rewritten to
+// match. It returns the new family.
+func Import(s storage.Storage, p *Package) (*family.Family, error) {
+	if p.Format != Format {
+		return nil, ErrFormat
+	}
+	if p.Family == nil {
+		return nil, errors.New("package has no family")
+	}
+	ids := make(map[string]string)
+	var err error
+	if ids[p.Family.ID], err = unusedID(s, storage.FamilyIDs, func(id string) error {
+		_, err := s.GetFamily(id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	for _, r := range p.Reminders {
+		if ids[r.ID], err = unusedID(s, storage.ReminderIDs, func(id string) error {
+			_, err := s.GetReminder(id)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range p.CompletionEvents {
+		if ids[e.ID], err = unusedID(s, storage.CompletionEventIDs, func(id string) error {
+			_, err := s.GetCompletionEvent(id)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for _, rec := range p.Records {
+		ids[rec.ID] = storage.NewRecordID(recordPrefix(rec.ID))
+	}
+
+	f := *p.Family
+	f.ID = ids[f.ID]
+	if err := s.CreateFamily(&f); err != nil {
+		return nil, err
+	}
+	for _, r := range p.Reminders {
+		r.ID, r.FamilyID = ids[r.ID], f.ID
+		if r.ProjectID != "" {
+			r.ProjectID = remap(ids, r.ProjectID)
+		}
+		if r.SeriesID != "" {
+			r.SeriesID = remap(ids, r.SeriesID)
+		}
+		if err := s.CreateReminder(r); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range p.CompletionEvents {
+		e.ID, e.ReminderID = ids[e.ID], remap(ids, e.ReminderID)
+		if err := s.CreateCompletionEvent(e); err != nil {
+			return nil, err
+		}
+	}
+	for _, rec := range p.Records {
+		data, err := remapJSON(ids, rec.Data)
+		if err != nil {
+			return nil, fmt.Errorf("record %s/%s: %w", rec.Kind, rec.ID, err)
+		}
+		rec.ID, rec.FamilyID, rec.Ref, rec.Data = ids[rec.ID], f.ID, remap(ids, rec.Ref), data
+		if err := s.PutRecord(rec); err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// unusedID draws IDs from generate until get fails to find one. Counters can
+// lag behind the data when entities were created with explicit IDs, and an
+// import must never overwrite existing data.
+func unusedID(s storage.Storage, kind storage.IDKind, get func(string) error) (string, error) {
+	for {
+		id, err := storage.GenerateID(s, kind)
+		if err != nil {
+			return "", err
+		}
+		if get(id) != nil {
+			return id, nil
+		}
+	}
+}
+
+// remap returns the new ID for id, or id itself if it isn't being replaced
+func remap(ids map[string]string, id string) string {
+	if n, ok := ids[id]; ok {
+		return n
+	}
+	return id
+}
+
+// remapJSON rewrites the ID fields of a record's data: every "id" or
+// "..._id" string value that names an imported entity
+func remapJSON(ids map[string]string, data json.RawMessage) (json.RawMessage, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(remapValue(ids, "", v))
+}
+
+func remapValue(ids map[string]string, key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = remapValue(ids, k, child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = remapValue(ids, key, child)
+		}
+	case string:
+		if key == "id" || strings.HasSuffix(key, "_id") {
+			return remap(ids, v)
+		}
+	}
+	return v
+}
+
+// recordPrefix returns the prefix a record ID was created with by
+// storage.NewRecordID, which appends 16 hex digits
+func recordPrefix(id string) string {
+	if len(id) <= 16 {
+		return ""
+	}
+	return id[:len(id)-16]
+}
+
+// Write encodes a package as a signed archive, encrypted with passphrase
+// unless it is empty
+func Write(w io.Writer, p *Package, key []byte, passphrase string) error {
+	if passphrase == "" {
+		return writeArchive(w, p, key)
+	}
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, p, key); err != nil {
+		return err
+	}
+	return encrypt(w, buf.Bytes(), passphrase)
+}
+
+func writeArchive(w io.Writer, p *Package, key []byte) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{packageEntry, data},
+		{signatureEntry, []byte(hex.EncodeToString(sign(key, data)) + "\n")},
+	} {
+		hdr := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.data)), ModTime: p.ExportedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read decodes a signed archive, rejecting it unless it was signed with
+// key. An encrypted archive is rejected with ErrPassphrase unless it was
+// encrypted with passphrase; the passphrase is ignored for plain ones.
+func Read(r io.Reader, key []byte, passphrase string) (*Package, error) {
+	br := bufio.NewReader(r)
+	if isEncrypted(br) {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = decrypt(data, passphrase); err != nil {
+			return nil, err
+		}
+		return readArchive(bytes.NewReader(data), key)
+	}
+	return readArchive(br, key)
+}
+
+func readArchive(r io.Reader, key []byte) (*Package, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a package archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	entries := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a package archive: %w", err)
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, io.LimitReader(tr, maxPackageSize)); err != nil {
+			return nil, err
+		}
+		entries[hdr.Name] = buf.Bytes()
+	}
+	data, ok := entries[packageEntry]
+	if !ok {
+		return nil, fmt.Errorf("archive has no %s", packageEntry)
+	}
+	sig, err := hex.DecodeString(strings.TrimSpace(string(entries[signatureEntry])))
+	if err != nil || !hmac.Equal(sig, sign(key, data)) {
+		return nil, ErrSignature
+	}
+	var p Package
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if p.Format != Format {
+		return nil, ErrFormat
+	}
+	return &p, nil
+}
+
+func sign(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,118 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"reminder-app/internal/audit"
+	"reminder-app/internal/family"
+	"reminder-app/internal/project"
+	"reminder-app/internal/reminder"
+	"reminder-app/internal/storage"
+)
+
+func TestRoundTrip(t *testing.T) {
+	src := storage.NewMemoryStorage()
+	_ = src.CreateFamily(&family.Family{ID: "fam1", Name: "Smith", Members: []string{"Alice"}})
+	_ = src.CreateFamily(&family.Family{ID: "fam2", Name: "Jones", Members: []string{"Carol"}})
+	p := &project.Project{ID: storage.NewRecordID("prj"), FamilyID: "fam1", Name: "Party"}
+	_ = storage.PutJSON(src, storage.Record{Kind: project.Kind, ID: p.ID, FamilyID: "fam1"}, p)
+	r := reminder.NewReminder("rem1", "Cake", "", nil, "fam1", "Alice", reminder.RecurrencePattern{Type: "once"})
+	r.ProjectID = p.ID
+	_ = src.CreateReminder(r)
+	_ = src.CreateReminder(reminder.NewReminder("rem2", "Other", "", nil, "fam2", "Carol", reminder.RecurrencePattern{Type: "once"}))
+	_ = src.CreateCompletionEvent(&reminder.CompletionEvent{ID: "cev1", ReminderID: "rem1", CompletedBy: "Alice", CompletedAt: time.Now()})
+	_ = audit.Record(src, audit.Entry{Entity: "reminder", EntityID: "rem1", FamilyID: "fam1", Action: audit.ActionCreate})
+
+	pkg, err := Build(src, "fam1", time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(pkg.Reminders) != 1 || len(pkg.CompletionEvents) != 1 || len(pkg.Records) != 2 {
+		t.Fatalf("unexpected package contents: %d reminders, %d events, %d records", len(pkg.Reminders), len(pkg.CompletionEvents), len(pkg.Records))
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, pkg, []byte("secret"), ""); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := Read(bytes.NewReader(buf.Bytes()), []byte("wrong"), ""); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected ErrSignature with the wrong key, got %v", err)
+	}
+	read, err := Read(bytes.NewReader(buf.Bytes()), []byte("secret"), "")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	// Import into an instance whose IDs overlap with the source
+	dst := storage.NewMemoryStorage()
+	_ = dst.CreateFamily(&family.Family{ID: "fam1", Name: "Existing"})
+	dst.SetFamilyIDCounter(1)
+	_ = dst.CreateReminder(reminder.NewReminder("rem1", "Existing", "", nil, "fam1", "", reminder.RecurrencePattern{Type: "once"}))
+	dst.SetReminderIDCounter(1)
+
+	f, err := Import(dst, read)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if f.ID == "fam1" || f.Name != "Smith" {
+		t.Fatalf("expected a new family, got %+v", f)
+	}
+	existing, _ := dst.GetReminder("rem1")
+	if existing.Title != "Existing" {
+		t.Errorf("import overwrote an existing reminder: %+v", existing)
+	}
+	list, _ := dst.ListReminders()
+	var imported *reminder.Reminder
+	for _, r := range list {
+		if r.FamilyID == f.ID {
+			imported = r
+		}
+	}
+	if imported == nil || imported.Title != "Cake" {
+		t.Fatalf("imported reminder not found in %+v", list)
+	}
+	projects, _ := storage.ListJSON[project.Project](dst, storage.RecordQuery{Kind: project.Kind, FamilyID: f.ID})
+	if len(projects) != 1 || projects[0].FamilyID != f.ID || imported.ProjectID != projects[0].ID {
+		t.Errorf("project not remapped: %+v, reminder project %q", projects, imported.ProjectID)
+	}
+	events, _ := dst.ListCompletionEvents(imported.ID)
+	if len(events) != 1 || events[0].CompletedBy != "Alice" {
+		t.Errorf("completion events not imported: %+v", events)
+	}
+	history, _ := audit.History(dst, "reminder", imported.ID)
+	if len(history) != 1 || history[0].FamilyID != f.ID {
+		t.Errorf("audit history not remapped: %+v", history)
+	}
+}
+
+func TestEncryptedPackage(t *testing.T) {
+	pkg := &Package{Format: Format, ExportedAt: time.Now(), Family: &family.Family{ID: "fam1", Name: "Smith"}}
+	var buf bytes.Buffer
+	if err := Write(&buf, pkg, []byte("secret"), "short"); err == nil {
+		t.Error("expected a short passphrase to be rejected")
+	}
+	buf.Reset()
+	if err := Write(&buf, pkg, []byte("secret"), "correct horse"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("Smith")) {
+		t.Error("expected the package to be encrypted")
+	}
+	for _, passphrase := range []string{"", "wrong horse"} {
+		if _, err := Read(bytes.NewReader(buf.Bytes()), []byte("secret"), passphrase); !errors.Is(err, ErrPassphrase) {
+			t.Errorf("passphrase %q: expected ErrPassphrase, got %v", passphrase, err)
+		}
+	}
+	if _, err := Read(bytes.NewReader(buf.Bytes()), []byte("wrong"), "correct horse"); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected the signature to be checked after decrypting, got %v", err)
+	}
+	read, err := Read(bytes.NewReader(buf.Bytes()), []byte("secret"), "correct horse")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if read.Family.Name != "Smith" {
+		t.Errorf("expected the family back, got %+v", read.Family)
+	}
+}
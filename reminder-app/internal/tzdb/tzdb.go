@@ -0,0 +1,123 @@
+// Package tzdb lists the IANA time zones the server can load, for clients
+// offering a choice of time zone. Go loads zones by name but can't list
+// them, so the names are read from the time zone database Go loads them
+// from: the directory or zip file named by $ZONEINFO, the system's
+// database, or the copy that comes with Go.
+package tzdb
+
+import (
+	"archive/zip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned when no time zone database can be found
+var ErrUnavailable = errors.New("no time zone database found")
+
+// systemSources are where Go looks for the system's database on Unix
+var systemSources = []string{
+	"/usr/share/zoneinfo/",
+	"/usr/share/lib/zoneinfo/",
+	"/usr/lib/locale/TZ/",
+	"/etc/zoneinfo/",
+}
+
+// Zone is a time zone as it is at a given time
+type Zone struct {
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+	// Offset is the offset from UTC, e.g. "+05:30"
+	Offset string `json:"offset"`
+	DST    bool   `json:"dst"`
+}
+
+// Load reads the first time zone database found, in the order Go looks for
+// one. It is only read once.
+var Load = sync.OnceValues(func() (*Database, error) {
+	var sources []string
+	if env := os.Getenv("ZONEINFO"); env != "" {
+		sources = append(sources, env)
+	}
+	sources = append(sources, systemSources...)
+	sources = append(sources, filepath.Join(runtime.GOROOT(), "lib", "time", "zoneinfo.zip"))
+	for _, source := range sources {
+		names, err := Read(source)
+		if err != nil || len(names) == 0 {
+			continue
+		}
+		return &Database{Source: source, Names: names}, nil
+	}
+	return nil, ErrUnavailable
+})
+
+// Database is the list of zones read from a time zone database
+type Database struct {
+	// Source is the directory or zip file the zones were read from
+	Source string
+	// Names are the names of the zones that load, sorted
+	Names []string
+}
+
+// Zones returns every zone of the database as it is at the given time
+func (db *Database) Zones(at time.Time) []Zone {
+	zones := make([]Zone, 0, len(db.Names))
+	for _, name := range db.Names {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			continue
+		}
+		local := at.In(loc)
+		abbr, _ := local.Zone()
+		zones = append(zones, Zone{Name: name, Abbreviation: abbr, Offset: local.Format("-07:00"), DST: local.IsDST()})
+	}
+	return zones
+}
+
+// Read returns the sorted names of the zones in a database directory or
+// zip file that time.LoadLocation can load. Files that aren't zones, and
+// the posix/ and right/ copies of the database, are left out.
+func Read(source string) ([]string, error) {
+	var names []string
+	if strings.HasSuffix(source, ".zip") {
+		z, err := zip.OpenReader(source)
+		if err != nil {
+			return nil, err
+		}
+		defer z.Close()
+		for _, f := range z.File {
+			if !f.FileInfo().IsDir() {
+				names = append(names, f.Name)
+			}
+		}
+	} else {
+		err := filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				name, _ := filepath.Rel(source, path)
+				names = append(names, filepath.ToSlash(name))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	names = slices.DeleteFunc(names, func(name string) bool {
+		if strings.HasPrefix(name, "posix/") || strings.HasPrefix(name, "right/") || name == "posixrules" || name == "localtime" {
+			return true
+		}
+		_, err := time.LoadLocation(name)
+		return err != nil
+	})
+	slices.Sort(names)
+	return names, nil
+}
@@ -0,0 +1,55 @@
+package tzdb
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRead(t *testing.T) {
+	// Go's own copy of the database, as a zip file, and the system's as a
+	// directory where there is one
+	sources := []string{filepath.Join(runtime.GOROOT(), "lib", "time", "zoneinfo.zip")}
+	if _, err := os.Stat(systemSources[0]); err == nil {
+		sources = append(sources, systemSources[0])
+	}
+	for _, source := range sources {
+		names, err := Read(source)
+		if err != nil {
+			t.Skipf("tzdata not available: %v", err)
+		}
+		for _, want := range []string{"Europe/Berlin", "America/Argentina/Buenos_Aires", "UTC"} {
+			if !slices.Contains(names, want) {
+				t.Errorf("%s: expected %s among %d zones", source, want, len(names))
+			}
+		}
+		for _, name := range names {
+			if name == "zone.tab" || strings.HasPrefix(name, "posix/") || strings.HasPrefix(name, "right/") {
+				t.Errorf("%s: unexpected zone %s", source, name)
+			}
+		}
+		if !slices.IsSorted(names) {
+			t.Errorf("%s: expected sorted names", source)
+		}
+	}
+	if _, err := Read(t.TempDir() + "/missing.zip"); err == nil {
+		t.Error("expected a missing database to fail")
+	}
+}
+
+func TestZones(t *testing.T) {
+	db := &Database{Names: []string{"Europe/Berlin", "Nowhere/Special"}}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	zones := db.Zones(time.Date(2025, 7, 1, 12, 0, 0, 0, berlin))
+	want := []Zone{{Name: "Europe/Berlin", Abbreviation: "CEST", Offset: "+02:00", DST: true}}
+	if !slices.Equal(zones, want) {
+		t.Errorf("got %+v, want %+v", zones, want)
+	}
+}
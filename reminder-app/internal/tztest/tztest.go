@@ -0,0 +1,127 @@
+// Package tztest is a harness for testing time zone handling across daylight
+// saving time changes. It finds the changes of a set of zones chosen for the
+// ways they have broken schedules before, and checks that recurring times
+// keep their wall-clock time through them, once a day at most and in order,
+// resolving the times a change skips or repeats the way iCalendar does.
+package tztest
+
+import (
+	"testing"
+	"time"
+)
+
+// Zones observe daylight saving time in different ways: in both
+// hemispheres, at midnight so that a day starts at 01:00 (Santiago), and by
+// half an hour (Lord Howe Island)
+var Zones = []string{
+	"Europe/Berlin",
+	"America/New_York",
+	"Australia/Sydney",
+	"America/Santiago",
+	"Australia/Lord_Howe",
+}
+
+// Year is the year whose changes the harness tests by default
+const Year = 2025
+
+// Load returns the named zone, skipping the test when the time zone
+// database isn't available
+func Load(t testing.TB, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	return loc
+}
+
+// Transition is a change of a zone's offset from UTC
+type Transition struct {
+	Zone *time.Location
+	// At is the first instant of the new offset
+	At time.Time
+	// Before and After are the offsets, in seconds east of UTC
+	Before, After int
+}
+
+// Affected returns the local wall-clock time in the middle of the times the
+// change skips or repeats, such as 02:30 when clocks go from 02:00 to 03:00.
+// The time is in UTC, since it may not exist in the zone, with the date and
+// time of day of the wall clock.
+func (tr Transition) Affected() time.Time {
+	from, to := tr.Before, tr.After
+	if from > to {
+		from, to = to, from
+	}
+	// The local times the change skips or repeats start at the wall-clock
+	// time of At in the smaller offset
+	return tr.At.UTC().Add(time.Duration(from+(to-from)/2) * time.Second)
+}
+
+// Transitions returns the offset changes of loc during year
+func Transitions(loc *time.Location, year int) []Transition {
+	end := time.Date(year+1, 1, 1, 0, 0, 0, 0, loc)
+	var result []Transition
+	for t := time.Date(year, 1, 1, 0, 0, 0, 0, loc); ; {
+		_, next := t.ZoneBounds()
+		if next.IsZero() || !next.Before(end) {
+			return result
+		}
+		_, before := t.Zone()
+		_, after := next.Zone()
+		if before != after {
+			result = append(result, Transition{Zone: loc, At: next, Before: before, After: after})
+		}
+		t = next
+	}
+}
+
+// All returns the changes of every zone in Zones during Year, skipping the
+// test when the time zone database isn't available
+func All(t testing.TB) []Transition {
+	t.Helper()
+	var result []Transition
+	for _, name := range Zones {
+		trs := Transitions(Load(t, name), Year)
+		if len(trs) == 0 {
+			t.Fatalf("no daylight saving time changes in %s during %d", name, Year)
+		}
+		result = append(result, trs...)
+	}
+	return result
+}
+
+// CheckWallClock fails the test unless times are in order, on different
+// days of loc, and each at hour:min in loc. Where daylight saving time skips
+// hour:min, it must be read in the offset before the change, so that 02:30
+// is 03:30 when clocks go from 02:00 to 03:00, and where it repeats hour:min,
+// it must be the first of the two, as iCalendar has it.
+func CheckWallClock(t testing.TB, times []time.Time, loc *time.Location, hour, min int) {
+	t.Helper()
+	for i, at := range times {
+		local := at.In(loc)
+		if i > 0 {
+			prev := times[i-1].In(loc)
+			if !prev.Before(local) {
+				t.Errorf("%s: %s is not after %s", loc, local, prev)
+			}
+			if y, m, d := prev.Date(); local.Year() == y && local.Month() == m && local.Day() == d {
+				t.Errorf("%s: %s and %s fall on the same day", loc, prev, local)
+			}
+		}
+		_, before := local.Add(-12 * time.Hour).Zone()
+		_, offset := local.Zone()
+		if local.Hour() == hour && local.Minute() == min {
+			if repeat := time.Duration(before-offset) * time.Second; repeat > 0 {
+				if earlier := local.Add(-repeat); earlier.Hour() == hour && earlier.Minute() == min {
+					t.Errorf("%s: %s is the second %02d:%02d that day, not the first", loc, local, hour, min)
+				}
+			}
+			continue
+		}
+		wall := time.Date(local.Year(), local.Month(), local.Day(), hour, min, 0, 0, time.UTC)
+		if want := wall.Add(-time.Duration(before) * time.Second); before == offset || !local.Equal(want) {
+			t.Errorf("%s: %s is not at %02d:%02d", loc, local, hour, min)
+		}
+	}
+}
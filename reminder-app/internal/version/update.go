@@ -0,0 +1,143 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// DefaultRepo is the GitHub repository releases are published in
+const DefaultRepo = "brendandburns/reminder-app"
+
+// DefaultCheckInterval is how often the checker looks for a new release
+const DefaultCheckInterval = 12 * time.Hour
+
+// securityPattern spots release notes that mention security fixes
+var securityPattern = regexp.MustCompile(`(?i)\b(security|CVE-\d{4}-\d+|vulnerabilit(y|ies))\b`)
+
+// Status is the outcome of the latest check
+type Status struct {
+	Current string `json:"current"`
+	Latest  string `json:"latest,omitempty"`
+	// UpdateAvailable is set when Latest is newer than Current
+	UpdateAvailable bool `json:"update_available"`
+	// Security is set when the newer release's notes mention security fixes
+	Security    bool       `json:"security,omitempty"`
+	URL         string     `json:"url,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// release is the part of a GitHub release the checker reads
+type release struct {
+	TagName     string    `json:"tag_name"`
+	HTMLURL     string    `json:"html_url"`
+	Body        string    `json:"body"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Checker periodically looks up the latest release on GitHub
+type Checker struct {
+	Repo string
+	// API is the GitHub API base URL
+	API      string
+	Interval time.Duration
+	Client   *http.Client
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewChecker creates a checker for repo with a 10 second timeout
+func NewChecker(repo string) *Checker {
+	return &Checker{
+		Repo:     repo,
+		API:      "https://api.github.com",
+		Interval: DefaultCheckInterval,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		status:   Status{Current: Version},
+	}
+}
+
+// Status returns the outcome of the latest check
+func (c *Checker) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Check looks up the latest release now and records the outcome
+func (c *Checker) Check(ctx context.Context) error {
+	now := time.Now()
+	rel, err := c.latest(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Current = Version
+	c.status.CheckedAt = &now
+	if err != nil {
+		// The previous result stays, along with why it couldn't be refreshed
+		c.status.Error = err.Error()
+		return err
+	}
+	c.status = Status{
+		Current:         Version,
+		Latest:          rel.TagName,
+		UpdateAvailable: Newer(rel.TagName, Version),
+		URL:             rel.HTMLURL,
+		PublishedAt:     &rel.PublishedAt,
+		CheckedAt:       &now,
+	}
+	c.status.Security = c.status.UpdateAvailable && securityPattern.MatchString(rel.Body)
+	return nil
+}
+
+func (c *Checker) latest(ctx context.Context) (*release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/releases/latest", c.API, c.Repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "reminder-app/"+Version)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub answered %s", resp.Status)
+	}
+	var rel release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("invalid release: %w", err)
+	}
+	return &rel, nil
+}
+
+// Run checks now and then every Interval until ctx is cancelled, logging
+// when a newer release is found
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Check(ctx); err != nil {
+			log.Printf("update check: %v", err)
+		} else if s := c.Status(); s.UpdateAvailable {
+			note := ""
+			if s.Security {
+				note = " with security fixes"
+			}
+			log.Printf("A new release%s is available: %s (running %s) %s", note, s.Latest, s.Current, s.URL)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
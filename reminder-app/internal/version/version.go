@@ -0,0 +1,143 @@
+// Package version identifies the running build and checks GitHub for newer
+// releases, so self-hosters learn when an update, especially one with
+// security fixes, is available.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the release the binary was built from. It and the other build
+// metadata are set at build time, e.g.
+//
+//	go build -ldflags "-X reminder-app/internal/version.Version=v1.2.3 \
+//		-X reminder-app/internal/version.Commit=$(git rev-parse HEAD) \
+//		-X reminder-app/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Development builds report "dev". Commit and BuildDate default to the VCS
+// information the Go toolchain embeds when building from a checkout.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Storage is the storage backend in use and Features the optional features
+// the server was started with, both set by main at startup. Features
+// changed while serving are set with SetFeatures.
+var (
+	Storage  string
+	Features map[string]bool
+
+	featuresMu sync.RWMutex
+)
+
+// SetFeatures replaces Features while requests may be reading it
+func SetFeatures(f map[string]bool) {
+	featuresMu.Lock()
+	defer featuresMu.Unlock()
+	Features = f
+}
+
+// Info is what GET /version reports
+type Info struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	// Modified is set for builds from a checkout with uncommitted changes
+	Modified  bool   `json:"modified,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	// Backends lists the storage backends compiled in and Storage the one
+	// in use
+	Backends []string        `json:"backends"`
+	Storage  string          `json:"storage,omitempty"`
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// allBackends are the storage backends; sqlite also needs cgo
+var allBackends = []string{"memory", "file", "sqlite", "mongo", "postgres"}
+
+// Current describes the running build
+func Current() Info {
+	featuresMu.RLock()
+	defer featuresMu.RUnlock()
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Storage:   Storage,
+		Features:  Features,
+	}
+	cgo := true
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			case "CGO_ENABLED":
+				cgo = s.Value == "1"
+			}
+		}
+	}
+	for _, b := range allBackends {
+		if b != "sqlite" || cgo {
+			info.Backends = append(info.Backends, b)
+		}
+	}
+	return info
+}
+
+// Newer reports whether release a is newer than release b. Both are
+// versions like "v1.2.3"; a build that isn't a release, such as "dev", is
+// never newer and anything is newer than it.
+func Newer(a, b string) bool {
+	va, okA := parse(a)
+	vb, okB := parse(b)
+	if !okA {
+		return false
+	}
+	if !okB {
+		return true
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+// parse splits "v1.2.3" into its numbers. Pre-release and build suffixes
+// such as "-rc.1" are ignored.
+func parse(v string) ([3]int, bool) {
+	var n [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return n, false
+	}
+	for i, p := range parts {
+		x, err := strconv.Atoi(p)
+		if err != nil || x < 0 {
+			return n, false
+		}
+		n[i] = x
+	}
+	return n, true
+}
@@ -0,0 +1,93 @@
+package version
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{"v1.2.4", "v1.2.3", true},
+		{"v1.10.0", "v1.9.9", true},
+		{"v2.0.0", "v1.99.0", true},
+		{"v1.2.3", "v1.2.3", false},
+		{"v1.2.2", "v1.2.3", false},
+		{"v1.3", "v1.2.9", true},
+		{"v1.2.3-rc.1", "v1.2.2", true},
+		{"v1.2.3", "dev", true},
+		{"nightly", "v1.2.3", false},
+	} {
+		if got := Newer(tt.a, tt.b); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestChecker(t *testing.T) {
+	defer func(v string) { Version = v }(Version)
+	Version = "v1.2.0"
+	notes := "Fixes CVE-2025-1234 in share links"
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/app/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		if fail {
+			http.Error(w, "rate limited", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"tag_name": "v1.3.0", "html_url": "https://example.com/v1.3.0", "body": "` + notes + `", "published_at": "2025-03-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	c := NewChecker("owner/app")
+	c.API = srv.URL
+	if s := c.Status(); s.Current != "v1.2.0" || s.CheckedAt != nil {
+		t.Errorf("unexpected status before checking: %+v", s)
+	}
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	s := c.Status()
+	if !s.UpdateAvailable || !s.Security || s.Latest != "v1.3.0" || s.URL != "https://example.com/v1.3.0" || s.CheckedAt == nil {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	// A failed check keeps the last result
+	fail = true
+	if err := c.Check(context.Background()); err == nil {
+		t.Fatalf("expected an error when GitHub refuses")
+	}
+	if s := c.Status(); !s.UpdateAvailable || s.Error == "" {
+		t.Errorf("expected the previous result with an error, got %+v", s)
+	}
+
+	fail, notes = false, "Small fixes"
+	Version = "v1.3.0"
+	c.Check(context.Background())
+	if s := c.Status(); s.UpdateAvailable || s.Security || s.Error != "" {
+		t.Errorf("expected no update when running the latest release, got %+v", s)
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	defer func(c string, f map[string]bool) { Commit, Features = c, f }(Commit, Features)
+	Commit = "abc123"
+	Features = map[string]bool{"telemetry": false, "admin": true}
+	info := Current()
+	if info.Version != Version || info.Commit != "abc123" || info.GoVersion == "" {
+		t.Errorf("unexpected build info %+v", info)
+	}
+	if len(info.Backends) < 4 || info.Backends[0] != "memory" {
+		t.Errorf("unexpected backends %v", info.Backends)
+	}
+	if !info.Features["admin"] || info.Features["telemetry"] {
+		t.Errorf("unexpected features %v", info.Features)
+	}
+}
@@ -0,0 +1,350 @@
+// Package webhook implements outbound webhooks: URLs a family registers to
+// receive its reminder events. Every delivery is signed with the webhook's
+// own secret, as described in package client, and failed deliveries are
+// retried with exponential backoff. Registrations, deliveries and the retry
+// queue are storage records, so pending retries survive a restart and
+// recent deliveries can be inspected.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"reminder-app/internal/storage"
+	"reminder-app/pkg/client"
+)
+
+// Storage record kinds
+const (
+	Kind         = "webhook"
+	DeliveryKind = "webhook_delivery"
+	// QueueKind holds a copy of each delivery until it succeeds or gives up
+	QueueKind = "webhook_queue"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before giving up
+	MaxAttempts = 8
+	// BaseDelay is the wait before the first retry; each later retry waits
+	// twice as long as the one before, up to MaxDelay
+	BaseDelay = 30 * time.Second
+	MaxDelay  = 6 * time.Hour
+	// DeliveryRetention is how long deliveries stay in the log
+	DeliveryRetention = 30 * 24 * time.Hour
+	// pollInterval is how often the dispatcher looks for retries that are
+	// due when nothing new has been enqueued
+	pollInterval = 15 * time.Second
+)
+
+var ErrNotFound = errors.New("webhook not found")
+
+// Webhook is a URL registered to receive a family's events
+type Webhook struct {
+	ID       string `json:"id"`
+	FamilyID string `json:"family_id"`
+	URL      string `json:"url"`
+	// Events lists the event types delivered; empty means all of them
+	Events    []string  `json:"events,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether w receives events of type typ
+func (w *Webhook) Subscribes(typ string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, typ)
+}
+
+// stored is a webhook as stored, with the secret its deliveries are signed
+// with. The secret is only shown when the webhook is created.
+type stored struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// Delivery is one event sent, or being sent, to a webhook
+type Delivery struct {
+	ID        string `json:"id"`
+	WebhookID string `json:"webhook_id"`
+	FamilyID  string `json:"family_id"`
+	// EventID identifies the event; it is the same for every webhook and
+	// attempt, so receivers can drop repeats
+	EventID   string          `json:"event_id"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"created_at"`
+	// ResponseStatus is the HTTP status of the last attempt, if it got one
+	ResponseStatus int `json:"response_status,omitempty"`
+	// Error describes why the last attempt failed
+	Error         string     `json:"error,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	// NextAttemptAt is when a pending delivery is tried next
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// ValidateURL checks that target is an absolute http(s) URL
+func ValidateURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook url must be an http or https URL")
+	}
+	return nil
+}
+
+// Create stores a new webhook and returns its signing secret, which is only
+// available now
+func Create(s storage.Storage, w *Webhook, now time.Time) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	w.ID = storage.NewRecordID("whk")
+	w.CreatedAt = now
+	rec := storage.Record{Kind: Kind, ID: w.ID, FamilyID: w.FamilyID, CreatedAt: now}
+	if err := storage.PutJSON(s, rec, stored{Webhook: *w, Secret: secret}); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// List returns the webhooks of a family, oldest first
+func List(s storage.Storage, familyID string) ([]*Webhook, error) {
+	list, err := storage.ListJSON[Webhook](s, storage.RecordQuery{Kind: Kind, FamilyID: familyID})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Get returns a webhook by ID
+func Get(s storage.Storage, id string) (*Webhook, error) {
+	w, err := storage.GetJSON[stored](s, Kind, id)
+	if errors.Is(err, storage.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w.Webhook, nil
+}
+
+// Delete removes a webhook. Its pending deliveries fail on their next
+// attempt and its log expires with them.
+func Delete(s storage.Storage, id string) error {
+	err := s.DeleteRecord(Kind, id)
+	if errors.Is(err, storage.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Deliveries returns the logged deliveries of a webhook, newest first
+func Deliveries(s storage.Storage, webhookID string) ([]*Delivery, error) {
+	list, err := storage.ListJSON[Delivery](s, storage.RecordQuery{Kind: DeliveryKind, Ref: webhookID})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(list)
+	return list, nil
+}
+
+// Backoff returns how long to wait after a delivery's nth failed attempt
+func Backoff(attempts int) time.Duration {
+	d := BaseDelay
+	for i := 1; i < attempts && d < MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, MaxDelay)
+}
+
+// Dispatcher enqueues events for a family's webhooks and delivers them
+type Dispatcher struct {
+	Store  storage.Storage
+	Client *http.Client
+	wake   chan struct{}
+}
+
+// NewDispatcher creates a dispatcher delivering with a 10 second timeout
+func NewDispatcher(s storage.Storage) *Dispatcher {
+	return &Dispatcher{
+		Store:  s,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Enqueue queues an event for every webhook of the family subscribed to its
+// type. data becomes the event's data. Deliveries are made by Run.
+func (d *Dispatcher) Enqueue(familyID, typ string, data any, now time.Time) error {
+	hooks, err := List(d.Store, familyID)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	eventID := storage.NewRecordID("evt")
+	for _, w := range hooks {
+		if !w.Subscribes(typ) {
+			continue
+		}
+		if payload == nil {
+			raw, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			payload, err = json.Marshal(client.Event{ID: eventID, Type: typ, FamilyID: familyID, OccurredAt: now.UTC(), Data: raw})
+			if err != nil {
+				return err
+			}
+		}
+		del := &Delivery{
+			ID:            storage.NewRecordID("dlv"),
+			WebhookID:     w.ID,
+			FamilyID:      familyID,
+			EventID:       eventID,
+			Event:         typ,
+			Payload:       payload,
+			Status:        StatusPending,
+			CreatedAt:     now,
+			NextAttemptAt: &now,
+		}
+		if err := d.save(del); err != nil {
+			return err
+		}
+	}
+	if payload != nil && d.wake != nil {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// save writes a delivery to the log and, while it is pending, the queue
+func (d *Dispatcher) save(del *Delivery) error {
+	expires := del.CreatedAt.Add(DeliveryRetention)
+	rec := storage.Record{Kind: DeliveryKind, ID: del.ID, FamilyID: del.FamilyID, Ref: del.WebhookID, CreatedAt: del.CreatedAt, ExpiresAt: &expires}
+	if err := storage.PutJSON(d.Store, rec, del); err != nil {
+		return err
+	}
+	if del.Status != StatusPending {
+		if err := d.Store.DeleteRecord(QueueKind, del.ID); err != nil && !errors.Is(err, storage.ErrRecordNotFound) {
+			return err
+		}
+		return nil
+	}
+	rec.Kind = QueueKind
+	return storage.PutJSON(d.Store, rec, del)
+}
+
+// Run delivers queued events until ctx is cancelled, as soon as they are
+// enqueued and again whenever retries fall due
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.Process(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process attempts every queued delivery that is due at now, returning how
+// many it attempted
+func (d *Dispatcher) Process(ctx context.Context, now time.Time) int {
+	queue, err := storage.ListJSON[Delivery](d.Store, storage.RecordQuery{Kind: QueueKind})
+	if err != nil {
+		log.Printf("webhook: failed to list queued deliveries: %v", err)
+		return 0
+	}
+	n := 0
+	for _, del := range queue {
+		if del.NextAttemptAt != nil && del.NextAttemptAt.After(now) {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		d.attempt(ctx, del, now)
+		n++
+		if err := d.save(del); err != nil {
+			log.Printf("webhook: failed to save delivery %s: %v", del.ID, err)
+		}
+	}
+	return n
+}
+
+// attempt sends a delivery once and updates it with the outcome
+func (d *Dispatcher) attempt(ctx context.Context, del *Delivery, now time.Time) {
+	del.Attempts++
+	del.LastAttemptAt = &now
+	del.NextAttemptAt = nil
+	status, err := d.send(ctx, del)
+	del.ResponseStatus = status
+	if err == nil {
+		del.Status, del.Error = StatusDelivered, ""
+		return
+	}
+	del.Error = err.Error()
+	if errors.Is(err, ErrNotFound) || del.Attempts >= MaxAttempts {
+		del.Status = StatusFailed
+		return
+	}
+	next := now.Add(Backoff(del.Attempts))
+	del.NextAttemptAt = &next
+}
+
+// send POSTs the payload to the webhook, returning the response status
+func (d *Dispatcher) send(ctx context.Context, del *Delivery) (int, error) {
+	w, err := storage.GetJSON[stored](d.Store, Kind, del.WebhookID)
+	if errors.Is(err, storage.ErrRecordNotFound) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "reminder-app-webhook")
+	req.Header.Set(client.HeaderEvent, del.Event)
+	client.SignRequest(req, []byte(w.Secret), del.Payload)
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
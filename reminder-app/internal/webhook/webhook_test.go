@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"reminder-app/internal/storage"
+	"reminder-app/pkg/client"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		20: MaxDelay,
+	} {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %s; want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	fail := true
+	var got []client.Event
+	var verifyErr error
+	var verifier *client.Verifier
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := verifier.Verify(r.Header, body); err != nil {
+			verifyErr = err
+		}
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var e client.Event
+		json.Unmarshal(body, &e)
+		got = append(got, e)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2025, 3, 4, 8, 0, 0, 0, time.UTC)
+	w := &Webhook{FamilyID: "fam1", URL: srv.URL, Events: []string{"reminder.completed"}}
+	secret, err := Create(store, w, now)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	verifier = client.NewVerifier([]byte(secret))
+
+	d := NewDispatcher(store)
+	d.Enqueue("fam1", "reminder.created", map[string]string{"id": "rem1"}, now)
+	d.Enqueue("fam1", "reminder.completed", map[string]string{"id": "rem1"}, now)
+	if n := d.Process(context.Background(), now); n != 1 {
+		t.Fatalf("expected 1 attempt for the subscribed event, got %d", n)
+	}
+	list, _ := Deliveries(store, w.ID)
+	if len(list) != 1 || list[0].Status != StatusPending || list[0].ResponseStatus != 503 ||
+		list[0].NextAttemptAt == nil || !list[0].NextAttemptAt.Equal(now.Add(BaseDelay)) {
+		t.Fatalf("expected a pending delivery to retry after %s, got %+v", BaseDelay, list)
+	}
+	if n := d.Process(context.Background(), now.Add(time.Second)); n != 0 {
+		t.Errorf("expected no attempt before the retry is due, got %d", n)
+	}
+
+	fail = false
+	d.Process(context.Background(), now.Add(BaseDelay))
+	if verifyErr != nil {
+		t.Errorf("delivery failed verification: %v", verifyErr)
+	}
+	if len(got) != 1 || got[0].Type != "reminder.completed" || got[0].ID != list[0].EventID || string(got[0].Data) != `{"id":"rem1"}` {
+		t.Fatalf("unexpected events received: %+v", got)
+	}
+	list, _ = Deliveries(store, w.ID)
+	if list[0].Status != StatusDelivered || list[0].Attempts != 2 || list[0].NextAttemptAt != nil {
+		t.Errorf("expected delivered after 2 attempts, got %+v", list[0])
+	}
+	if n := d.Process(context.Background(), now.Add(time.Hour)); n != 0 {
+		t.Errorf("expected the queue to be empty, got %d attempts", n)
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	w := &Webhook{FamilyID: "fam1", URL: srv.URL}
+	Create(store, w, now)
+	d := NewDispatcher(store)
+	d.Enqueue("fam1", "reminder.created", nil, now)
+	for i := 0; i < MaxAttempts; i++ {
+		d.Process(context.Background(), now)
+		now = now.Add(MaxDelay)
+	}
+	list, _ := Deliveries(store, w.ID)
+	if len(list) != 1 || list[0].Status != StatusFailed || list[0].Attempts != MaxAttempts {
+		t.Fatalf("expected a failed delivery after %d attempts, got %+v", MaxAttempts, list)
+	}
+
+	// Deliveries to deleted webhooks fail without a request
+	d.Enqueue("fam1", "reminder.created", nil, now)
+	Delete(store, w.ID)
+	d.Process(context.Background(), now)
+	list, _ = Deliveries(store, w.ID)
+	if list[0].Status != StatusFailed || list[0].Attempts != 1 || list[0].Error != ErrNotFound.Error() {
+		t.Errorf("expected the delivery to fail once the webhook is deleted, got %+v", list[0])
+	}
+}
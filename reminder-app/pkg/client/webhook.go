@@ -0,0 +1,197 @@
+// Package client holds helpers for programs that integrate with the reminder
+// server, starting with verification of its outbound webhooks.
+//
+// Every webhook delivery carries three headers: a Unix timestamp, a random
+// nonce and an HMAC-SHA256 signature over "timestamp.nonce.body" keyed with
+// the secret shared with the server. A receiver checks the signature,
+// rejects timestamps outside a tolerance window and remembers nonces for
+// that window so a captured delivery can't be replayed:
+//
+//	v := client.NewVerifier([]byte(secret))
+//	http.Handle("/hooks/reminders", v.Handler(myHandler))
+package client
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Webhook delivery headers
+const (
+	HeaderTimestamp = "X-Reminder-Timestamp"
+	HeaderNonce     = "X-Reminder-Nonce"
+	HeaderSignature = "X-Reminder-Signature"
+	// HeaderEvent carries the event type of family webhook deliveries
+	HeaderEvent = "X-Reminder-Event"
+)
+
+// signatureVersion prefixes signatures so the scheme can change later
+const signatureVersion = "v1="
+
+// DefaultTolerance is how far a delivery's timestamp may be from the
+// receiver's clock
+const DefaultTolerance = 5 * time.Minute
+
+// MaxBodySize is the largest webhook body Handler reads
+const MaxBodySize = 1 << 20
+
+var (
+	ErrMissingSignature = errors.New("webhook signature headers missing")
+	ErrInvalidSignature = errors.New("webhook signature mismatch")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside allowed window")
+	ErrReplayed         = errors.New("webhook delivery already received")
+)
+
+// Notification is the JSON body of a webhook delivery
+type Notification struct {
+	Subject string    `json:"subject,omitempty"`
+	Body    string    `json:"body"`
+	Link    string    `json:"link,omitempty"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Event is the JSON body of a delivery to a webhook registered with
+// POST /families/{id}/webhooks. ID is the same on every retry, so
+// receivers can drop deliveries they have already handled.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	FamilyID   string          `json:"family_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Sign returns the signature header value for a delivery
+func Sign(secret []byte, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest stamps req with a fresh timestamp, nonce and signature of
+// body, which must be the request's body
+func SignRequest(req *http.Request, secret, body []byte) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	nonce := hex.EncodeToString(b)
+	ts := time.Now().Unix()
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(secret, ts, nonce, body))
+}
+
+// NonceCache remembers nonces until they expire. It is safe for concurrent
+// use.
+type NonceCache struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewNonceCache creates an empty cache
+func NewNonceCache() *NonceCache {
+	return &NonceCache{nonces: make(map[string]time.Time)}
+}
+
+// Seen records nonce until expires and reports whether it was already
+// recorded and unexpired at now
+func (c *NonceCache) Seen(nonce string, now, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, exp := range c.nonces {
+		if !exp.After(now) {
+			delete(c.nonces, n)
+		}
+	}
+	if _, ok := c.nonces[nonce]; ok {
+		return true
+	}
+	c.nonces[nonce] = expires
+	return false
+}
+
+// Verifier checks signed webhook deliveries
+type Verifier struct {
+	Secret []byte
+	// Tolerance is the allowed clock difference; DefaultTolerance if zero
+	Tolerance time.Duration
+	// Nonces records accepted deliveries; replay checks are skipped if nil
+	Nonces *NonceCache
+	// Now returns the current time; defaults to time.Now
+	Now func() time.Time
+}
+
+// NewVerifier creates a verifier with the default tolerance and replay
+// protection
+func NewVerifier(secret []byte) *Verifier {
+	return &Verifier{Secret: secret, Nonces: NewNonceCache()}
+}
+
+// Verify checks the signature headers in h against the raw body. A
+// delivery that verifies is remembered, so verifying it again fails with
+// ErrReplayed.
+func (v *Verifier) Verify(h http.Header, body []byte) error {
+	tsHeader, nonce, sig := h.Get(HeaderTimestamp), h.Get(HeaderNonce), h.Get(HeaderSignature)
+	if tsHeader == "" || nonce == "" || sig == "" {
+		return ErrMissingSignature
+	}
+	ts, err := strconv.ParseInt(tsHeader, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	if !strings.HasPrefix(sig, signatureVersion) ||
+		!hmac.Equal([]byte(sig), []byte(Sign(v.Secret, ts, nonce, body))) {
+		return ErrInvalidSignature
+	}
+
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	tolerance := v.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	sent := time.Unix(ts, 0)
+	if d := now.Sub(sent); d > tolerance || d < -tolerance {
+		return ErrStaleTimestamp
+	}
+	// Once the timestamp leaves the window the delivery is rejected as
+	// stale, so the nonce only needs to be kept until then
+	if v.Nonces != nil && v.Nonces.Seen(nonce, now, sent.Add(tolerance)) {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// Handler wraps next so it only sees verified deliveries. Rejected
+// deliveries get 401 Unauthorized; next can read the body as usual.
+func (v *Verifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if err := v.Verify(r.Header, body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
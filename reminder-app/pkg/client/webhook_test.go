@@ -0,0 +1,79 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"body":"Take out the trash"}`)
+	now := time.Unix(1741000000, 0)
+	v := NewVerifier(secret)
+	v.Now = func() time.Time { return now }
+
+	header := func(ts time.Time, nonce string, b []byte) http.Header {
+		h := http.Header{}
+		h.Set(HeaderTimestamp, strconv.FormatInt(ts.Unix(), 10))
+		h.Set(HeaderNonce, nonce)
+		h.Set(HeaderSignature, Sign(secret, ts.Unix(), nonce, b))
+		return h
+	}
+
+	if err := v.Verify(header(now, "n1", body), body); err != nil {
+		t.Fatalf("expected valid delivery, got %v", err)
+	}
+	if err := v.Verify(header(now, "n1", body), body); !errors.Is(err, ErrReplayed) {
+		t.Errorf("replay: got %v, want ErrReplayed", err)
+	}
+	if err := v.Verify(header(now, "n2", body), []byte(`{"body":"tampered"}`)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered body: got %v, want ErrInvalidSignature", err)
+	}
+	if err := v.Verify(header(now.Add(-10*time.Minute), "n3", body), body); !errors.Is(err, ErrStaleTimestamp) {
+		t.Errorf("stale timestamp: got %v, want ErrStaleTimestamp", err)
+	}
+	if err := v.Verify(http.Header{}, body); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("unsigned: got %v, want ErrMissingSignature", err)
+	}
+
+	// Nonces are forgotten once their timestamp is out of the window
+	now = now.Add(DefaultTolerance + time.Second)
+	if err := v.Verify(header(now, "n4", body), body); err != nil {
+		t.Fatalf("expected valid delivery, got %v", err)
+	}
+	if n := len(v.Nonces.nonces); n != 1 {
+		t.Errorf("expected expired nonces to be pruned, %d remain", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	secret := []byte("s3cret")
+	var got string
+	h := NewVerifier(secret).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+
+	body := `{"body":"hi"}`
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	SignRequest(req, secret, []byte(body))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || got != body {
+		t.Errorf("expected delivery to reach handler, got %d %q", rr.Code, got)
+	}
+
+	replay := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	replay.Header = req.Header.Clone()
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, replay)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected replay to be rejected, got %d", rr.Code)
+	}
+}
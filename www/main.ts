@@ -392,10 +392,11 @@ $(document).ready(function () {
     bsModal.show();
   }
 
-  // Perform the actual delete operation
+  // Perform the actual delete operation. The user has confirmed, so the
+  // reminder's completion history goes with it.
   function performDelete(reminderId: string) {
     $.ajax({
-      url: `/reminders/${reminderId}`,
+      url: `/reminders/${reminderId}?cascade=true`,
       method: 'DELETE',
       success: function() {
         showDialog('Reminder deleted successfully!');
@@ -127,8 +127,51 @@ $(document).ready(function() {
     );
   }
 
+  // Live updates: reload the reminders whenever one in the family changes
+  let liveSocket: WebSocket | null = null;
+  let liveFamilyId = '';
+  let liveRetry = 1000;
+  let liveReload: number | undefined;
+
+  function watchFamily(familyId: string) {
+    if (liveFamilyId === familyId && liveSocket) {
+      return;
+    }
+    liveFamilyId = familyId;
+    if (liveSocket) {
+      liveSocket.onclose = null;
+      liveSocket.close();
+    }
+    const scheme = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+    const socket = new WebSocket(`${scheme}//${window.location.host}/ws?family=${encodeURIComponent(familyId)}`);
+    liveSocket = socket;
+    socket.onopen = () => { liveRetry = 1000; };
+    socket.onmessage = () => {
+      // Coalesce bursts, such as a completion followed by its update
+      window.clearTimeout(liveReload);
+      liveReload = window.setTimeout(() => {
+        const [currentFamily, memberName] = memberSelector.val()?.toString().split(':') || [];
+        if (currentFamily === familyId && memberName) {
+          loadMemberReminders(currentFamily, memberName);
+        }
+      }, 250);
+    };
+    socket.onclose = () => {
+      // Reconnect with backoff; changes missed meanwhile are picked up by
+      // the reload that follows the next event
+      liveSocket = null;
+      window.setTimeout(() => {
+        if (liveFamilyId === familyId && !liveSocket) {
+          watchFamily(familyId);
+        }
+      }, liveRetry);
+      liveRetry = Math.min(liveRetry * 2, 30000);
+    };
+  }
+
   // Load and filter reminders
   function loadMemberReminders(familyId: string, memberName: string) {
+    watchFamily(familyId);
     $('#member-reminders').html(`
       <div class="text-center">
         <div class="spinner-border text-primary" role="status">
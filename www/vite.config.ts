@@ -1,7 +1,43 @@
-import { defineConfig } from 'vite';
+import { defineConfig, type Plugin } from 'vite';
+import { readdirSync, readFileSync, statSync, writeFileSync } from 'node:fs';
+import { extname, join } from 'node:path';
+import { brotliCompressSync, constants, gzipSync } from 'node:zlib';
+
+// Text assets worth compressing; keep in sync with the server's list
+const compressible = new Set(['.html', '.css', '.js', '.mjs', '.json', '.map', '.svg', '.txt', '.xml', '.webmanifest', '.wasm']);
+
+// precompress writes .br and .gz variants of the built assets, which the
+// server sends to clients that accept them
+function precompress(): Plugin {
+  let outDir = 'dist';
+  const walk = (dir: string): string[] =>
+    readdirSync(dir).flatMap((name) => {
+      const path = join(dir, name);
+      return statSync(path).isDirectory() ? walk(path) : [path];
+    });
+  return {
+    name: 'precompress',
+    apply: 'build',
+    configResolved(config) {
+      outDir = config.build.outDir;
+    },
+    closeBundle() {
+      for (const file of walk(outDir)) {
+        if (!compressible.has(extname(file))) continue;
+        const data = readFileSync(file);
+        if (data.length < 1024) continue;
+        const br = brotliCompressSync(data, { params: { [constants.BROTLI_PARAM_QUALITY]: constants.BROTLI_MAX_QUALITY } });
+        if (br.length < data.length) writeFileSync(file + '.br', br);
+        const gz = gzipSync(data, { level: 9 });
+        if (gz.length < data.length) writeFileSync(file + '.gz', gz);
+      }
+    },
+  };
+}
 
 export default defineConfig({
   root: '.',
+  plugins: [precompress()],
   build: {
     outDir: 'dist',
     rollupOptions: {
@@ -18,6 +54,8 @@ export default defineConfig({
     proxy: {
       '/families': 'http://localhost:8080',
       '/reminders': 'http://localhost:8080',
+      '/locales': 'http://localhost:8080',
+      '/ws': { target: 'ws://localhost:8080', ws: true },
     },
   },
 });